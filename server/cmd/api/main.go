@@ -8,8 +8,10 @@ import (
 
 	"github.com/joho/godotenv"
 
+	"server/internal/domain/jsoncfg"
 	"server/internal/http/handlers"
 	httpapi "server/internal/http/httpapi"
+	"server/internal/i18n"
 	"server/internal/infra"
 )
 
@@ -21,6 +23,11 @@ func main() {
 		panic(err)
 	}
 	logger := infra.NewLogger(cfg.AppEnv)
+	jsoncfg.SetQuantityLimits(cfg.DefaultImageQuantity, cfg.PlanMaxQuantity)
+	jsoncfg.SetTextLimits(cfg.TitleMaxChars, cfg.InstructionsMaxChars)
+	if err := i18n.Configure(cfg.SupportedLocales, cfg.LocaleFallbacks); err != nil {
+		logger.Fatal().Err(err).Msg("invalid locale configuration")
+	}
 
 	ctx := context.Background()
 	dbpool, err := infra.NewDBPool(ctx, cfg)
@@ -33,6 +40,7 @@ func main() {
 
 	router := httpapi.NewRouter(app)
 	server := infra.NewHTTPServer(cfg, router)
+	server.RegisterDrain(app.DrainImageSlots)
 
 	go func() {
 		logger.Info().Msgf("API listening on :%s", cfg.Port)
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -18,11 +19,28 @@ func main() {
 	var (
 		keyFlag      string
 		providerFlag string
+		listFlag     bool
+		rotateFlag   bool
+		deleteFlag   bool
 	)
 	flag.StringVar(&keyFlag, "key", "", "API key for the selected provider (fallbacks to environment)")
 	flag.StringVar(&providerFlag, "provider", credentials.ProviderGemini, "Prompt provider to configure (gemini or openai)")
+	flag.BoolVar(&listFlag, "list", false, "list providers with stored keys (masked) and when they were last updated")
+	flag.BoolVar(&rotateFlag, "rotate", false, "replace an existing key and record the previous key's fingerprint")
+	flag.BoolVar(&deleteFlag, "delete", false, "remove the stored key for the selected provider")
 	flag.Parse()
 
+	modes := 0
+	for _, set := range []bool{listFlag, rotateFlag, deleteFlag} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		fmt.Fprintln(os.Stderr, "-list, -rotate and -delete cannot be combined")
+		os.Exit(1)
+	}
+
 	provider := strings.TrimSpace(strings.ToLower(providerFlag))
 	switch provider {
 	case credentials.ProviderGemini, credentials.ProviderOpenAI:
@@ -34,17 +52,17 @@ func main() {
 	}
 
 	key := strings.TrimSpace(keyFlag)
-	if key == "" {
+	if key == "" && !listFlag && !deleteFlag {
 		switch provider {
 		case credentials.ProviderOpenAI:
 			key = strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
 		default:
 			key = strings.TrimSpace(os.Getenv("GEMINI_API_KEY"))
 		}
-	}
-	if key == "" {
-		fmt.Fprintf(os.Stderr, "%s API key is required via -key or environment\n", strings.ToUpper(provider))
-		os.Exit(1)
+		if key == "" {
+			fmt.Fprintf(os.Stderr, "%s API key is required via -key or environment\n", strings.ToUpper(provider))
+			os.Exit(1)
+		}
 	}
 
 	dbURL := strings.TrimSpace(os.Getenv("DATABASE_URL"))
@@ -68,6 +86,51 @@ func main() {
 
 	ctxExec, cancelExec := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelExec()
+
+	switch {
+	case listFlag:
+		keys, err := store.ListAPIKeys(ctxExec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list api keys: %v\n", err)
+			os.Exit(1)
+		}
+		if len(keys) == 0 {
+			fmt.Println("no API keys stored")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROVIDER\tKEY\tUPDATED AT\tPREVIOUS FINGERPRINT")
+		for _, k := range keys {
+			previous := k.PreviousKeyFingerprint
+			if previous == "" {
+				previous = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.Provider, k.MaskedKey, k.UpdatedAt.UTC().Format(time.RFC3339), previous)
+		}
+		_ = w.Flush()
+		return
+	case rotateFlag:
+		previous, err := store.RotateAPIKey(ctxExec, provider, key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to rotate %s api key: %v\n", provider, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s API key rotated (previous key %s)\n", strings.ToUpper(provider), previous)
+		return
+	case deleteFlag:
+		deleted, err := store.DeleteAPIKey(ctxExec, provider)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to delete %s api key: %v\n", provider, err)
+			os.Exit(1)
+		}
+		if !deleted {
+			fmt.Printf("no %s API key stored\n", strings.ToUpper(provider))
+			return
+		}
+		fmt.Printf("%s API key deleted; restart the api and worker to fall back to static prompts and synthetic assets\n", strings.ToUpper(provider))
+		return
+	}
+
 	var persistErr error
 	switch provider {
 	case credentials.ProviderOpenAI:
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"server/internal/infra"
+	"server/internal/sqlinline"
+	"server/internal/storage"
+)
+
+type gcOptions struct {
+	grace time.Duration
+	apply bool
+	now   time.Time
+}
+
+type gcSummary struct {
+	scanned    int
+	referenced int
+	recent     int
+	orphaned   int
+	deleted    int
+	failed     int
+	bytes      int64
+}
+
+// collectOrphans lists every stored file, skips those referenced by an asset
+// row (as the file or its thumbnail) or modified within the grace period, and
+// reports the rest. Files are only deleted when opts.apply is set.
+func collectOrphans(ctx context.Context, sql infra.SQLExecutor, lister storage.Lister, store storage.Storage, opts gcOptions, out io.Writer) (gcSummary, error) {
+	var summary gcSummary
+	referenced, err := referencedKeys(ctx, sql)
+	if err != nil {
+		return summary, err
+	}
+	cutoff := opts.now.Add(-opts.grace)
+	var orphans []storage.Object
+	err = lister.List(ctx, func(obj storage.Object) error {
+		summary.scanned++
+		switch {
+		case referenced[obj.Key]:
+			summary.referenced++
+		case obj.ModTime.After(cutoff):
+			summary.recent++
+		default:
+			orphans = append(orphans, obj)
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, fmt.Errorf("list storage: %w", err)
+	}
+
+	for _, obj := range orphans {
+		summary.orphaned++
+		summary.bytes += obj.Size
+		if !opts.apply {
+			fmt.Fprintf(out, "would delete %s (%d bytes, modified %s)\n", obj.Key, obj.Size, obj.ModTime.UTC().Format(time.RFC3339))
+			continue
+		}
+		if err := store.Delete(ctx, obj.Key); err != nil {
+			summary.failed++
+			summary.bytes -= obj.Size
+			fmt.Fprintf(out, "failed %s: %v\n", obj.Key, err)
+			continue
+		}
+		summary.deleted++
+		fmt.Fprintf(out, "deleted %s (%d bytes)\n", obj.Key, obj.Size)
+	}
+	return summary, nil
+}
+
+// referencedKeys loads the storage keys still owned by asset rows, including
+// soft-deleted ones the worker purges on its own schedule.
+func referencedKeys(ctx context.Context, sql infra.SQLExecutor) (map[string]bool, error) {
+	rows, err := sql.Query(ctx, sqlinline.QListReferencedStorageKeys)
+	if err != nil {
+		return nil, fmt.Errorf("load asset keys: %w", err)
+	}
+	defer rows.Close()
+	keys := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("scan asset key: %w", err)
+		}
+		keys[normalizeKey(key)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load asset keys: %w", err)
+	}
+	return keys, nil
+}
+
+// normalizeKey matches keys recorded with a leading slash or "./" against
+// the slash-separated keys reported by the store.
+func normalizeKey(key string) string {
+	key = strings.TrimSpace(strings.ReplaceAll(key, "\\", "/"))
+	key = strings.TrimPrefix(key, "./")
+	return strings.TrimLeft(key, "/")
+}
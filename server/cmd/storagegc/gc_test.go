@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"server/internal/sqlinline"
+	"server/internal/storage"
+)
+
+type assetKeysSQL struct {
+	keys []string
+}
+
+func (s *assetKeysSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("unexpected exec")
+}
+
+func (s *assetKeysSQL) QueryRow(context.Context, string, ...any) pgx.Row {
+	return nil
+}
+
+func (s *assetKeysSQL) Query(_ context.Context, query string, _ ...any) (pgx.Rows, error) {
+	if query != sqlinline.QListReferencedStorageKeys {
+		return nil, errors.New("unexpected query")
+	}
+	return &keyRows{keys: s.keys, idx: -1}, nil
+}
+
+type keyRows struct {
+	pgx.Rows
+	keys []string
+	idx  int
+}
+
+func (r *keyRows) Next() bool {
+	r.idx++
+	return r.idx < len(r.keys)
+}
+
+func (r *keyRows) Scan(dest ...any) error {
+	*dest[0].(*string) = r.keys[r.idx]
+	return nil
+}
+
+func (r *keyRows) Err() error { return nil }
+
+func (r *keyRows) Close() {}
+
+func seedStore(t *testing.T, now time.Time) *storage.FileStore {
+	t.Helper()
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	files := map[string]time.Duration{
+		"uploads/u1/referenced.png":    72 * time.Hour,
+		"generated/j1/0.png":           72 * time.Hour,
+		"generated/j1/0_thumb.jpg":     72 * time.Hour,
+		"generated/j2/orphan.png":      72 * time.Hour,
+		"uploads/u2/orphan-upload.jpg": 48 * time.Hour,
+		"generated/j3/in-progress.png": time.Hour,
+	}
+	for key, age := range files {
+		if _, err := store.Write(context.Background(), key, []byte("data-"+key)); err != nil {
+			t.Fatalf("write %s: %v", key, err)
+		}
+		full := filepath.Join(store.BasePath(), filepath.FromSlash(key))
+		mtime := now.Add(-age)
+		if err := os.Chtimes(full, mtime, mtime); err != nil {
+			t.Fatalf("chtimes %s: %v", key, err)
+		}
+	}
+	return store
+}
+
+func fileExists(store *storage.FileStore, key string) bool {
+	_, err := os.Stat(filepath.Join(store.BasePath(), filepath.FromSlash(key)))
+	return err == nil
+}
+
+func TestCollectOrphansDryRunKeepsFiles(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := seedStore(t, now)
+	sql := &assetKeysSQL{keys: []string{"/uploads/u1/referenced.png", "generated/j1/0.png", "generated/j1/0_thumb.jpg"}}
+	var out bytes.Buffer
+
+	summary, err := collectOrphans(context.Background(), sql, store, store, gcOptions{grace: 24 * time.Hour, now: now}, &out)
+	if err != nil {
+		t.Fatalf("collectOrphans: %v", err)
+	}
+	if summary.scanned != 6 || summary.referenced != 3 || summary.recent != 1 || summary.orphaned != 2 || summary.deleted != 0 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	for _, want := range []string{"would delete generated/j2/orphan.png", "would delete uploads/u2/orphan-upload.jpg"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if !fileExists(store, "generated/j2/orphan.png") || !fileExists(store, "uploads/u2/orphan-upload.jpg") {
+		t.Fatal("dry run deleted files")
+	}
+}
+
+func TestCollectOrphansApplyDeletesOnlyOldOrphans(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := seedStore(t, now)
+	sql := &assetKeysSQL{keys: []string{"uploads/u1/referenced.png", "generated/j1/0.png", "generated/j1/0_thumb.jpg"}}
+	var out bytes.Buffer
+
+	summary, err := collectOrphans(context.Background(), sql, store, store, gcOptions{grace: 24 * time.Hour, apply: true, now: now}, &out)
+	if err != nil {
+		t.Fatalf("collectOrphans: %v", err)
+	}
+	if summary.deleted != 2 || summary.failed != 0 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	for key, want := range map[string]bool{
+		"uploads/u1/referenced.png":    true,
+		"generated/j1/0.png":           true,
+		"generated/j1/0_thumb.jpg":     true,
+		"generated/j3/in-progress.png": true,
+		"generated/j2/orphan.png":      false,
+		"uploads/u2/orphan-upload.jpg": false,
+	} {
+		if got := fileExists(store, key); got != want {
+			t.Fatalf("%s exists = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"server/internal/infra"
+	"server/internal/storage"
+)
+
+func main() {
+	var (
+		graceFlag time.Duration
+		applyFlag bool
+	)
+	flag.DurationVar(&graceFlag, "grace", 24*time.Hour, "only remove orphaned files last modified longer ago than this")
+	flag.BoolVar(&applyFlag, "apply", false, "delete orphaned files (default is a dry run that only lists them)")
+	flag.Parse()
+
+	if graceFlag < 0 {
+		exitWithError(errors.New("-grace must not be negative"))
+	}
+
+	cfg, err := infra.LoadConfig()
+	if err != nil {
+		exitWithError(err)
+	}
+	store, err := storage.New(cfg)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to configure storage: %w", err))
+	}
+	lister, ok := store.(storage.Lister)
+	if !ok {
+		exitWithError(fmt.Errorf("storage driver %q cannot list keys", cfg.StorageDriver))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, strings.TrimSpace(cfg.DatabaseURL))
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to connect database: %w", err))
+	}
+	defer pool.Close()
+
+	logger := infra.NewLogger("cli").With().Str("cmd", "storagegc").Logger()
+	runner := infra.NewSQLRunner(pool, logger)
+
+	opts := gcOptions{grace: graceFlag, apply: applyFlag, now: time.Now()}
+	summary, err := collectOrphans(ctx, runner, lister, store, opts, os.Stdout)
+	if err != nil {
+		exitWithError(err)
+	}
+	if !opts.apply {
+		fmt.Printf("dry run: scanned=%d referenced=%d recent=%d would_delete=%d bytes=%d\n",
+			summary.scanned, summary.referenced, summary.recent, summary.orphaned, summary.bytes)
+		return
+	}
+	fmt.Printf("scanned=%d referenced=%d recent=%d deleted=%d bytes=%d failed=%d\n",
+		summary.scanned, summary.referenced, summary.recent, summary.deleted, summary.bytes, summary.failed)
+}
+
+func exitWithError(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/infra"
+)
+
+// csvPlanRow is one `email,plan,quota` line of a bulk assignment file.
+type csvPlanRow struct {
+	line  int
+	email string
+	plan  string
+	quota int
+}
+
+type csvRowError struct {
+	line int
+	err  error
+}
+
+type bulkOptions struct {
+	strict    bool
+	keepUsage bool
+	dryRun    bool
+	now       time.Time
+}
+
+type bulkSummary struct {
+	applied int
+	failed  int
+}
+
+// parsePlanCSV reads `email,plan,quota` rows. A leading header row is
+// skipped. Malformed rows are returned separately so the caller can decide
+// whether to continue.
+func parsePlanCSV(r io.Reader) ([]csvPlanRow, []csvRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var (
+		rows      []csvPlanRow
+		malformed []csvRowError
+	)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				malformed = append(malformed, csvRowError{line: parseErr.StartLine, err: parseErr.Err})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows) == 0 && len(malformed) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "email") {
+			continue
+		}
+		row, err := parsePlanRecord(line, record)
+		if err != nil {
+			malformed = append(malformed, csvRowError{line: line, err: err})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, malformed, nil
+}
+
+func parsePlanRecord(line int, record []string) (csvPlanRow, error) {
+	if len(record) != 3 {
+		return csvPlanRow{}, fmt.Errorf("expected 3 columns (email,plan,quota), got %d", len(record))
+	}
+	email := strings.TrimSpace(record[0])
+	if !strings.Contains(email, "@") {
+		return csvPlanRow{}, fmt.Errorf("invalid email %q", email)
+	}
+	plan := strings.ToLower(strings.TrimSpace(record[1]))
+	if err := validatePlan(plan); err != nil {
+		return csvPlanRow{}, err
+	}
+	quota, err := strconv.Atoi(strings.TrimSpace(record[2]))
+	if err != nil {
+		return csvPlanRow{}, fmt.Errorf("invalid quota %q", record[2])
+	}
+	return csvPlanRow{line: line, email: email, plan: plan, quota: quota}, nil
+}
+
+// applyPlanCSV assigns plans for every valid row and writes a per-row result
+// to out. In strict mode any malformed row or missing user aborts the run
+// with an error so the surrounding transaction is rolled back.
+func applyPlanCSV(ctx context.Context, sql infra.SQLExecutor, r io.Reader, opts bulkOptions, out io.Writer) (bulkSummary, error) {
+	rows, malformed, err := parsePlanCSV(r)
+	if err != nil {
+		return bulkSummary{}, fmt.Errorf("failed to read csv: %w", err)
+	}
+	var summary bulkSummary
+	for _, m := range malformed {
+		fmt.Fprintf(out, "line %d: malformed: %v\n", m.line, m.err)
+		summary.failed++
+	}
+	if opts.strict && len(malformed) > 0 {
+		return summary, fmt.Errorf("%d malformed row(s); nothing applied", len(malformed))
+	}
+	for _, row := range rows {
+		user, err := loadUser(ctx, sql, "", row.email)
+		if err != nil {
+			if !infra.IsNoRows(err) {
+				return summary, fmt.Errorf("line %d: failed to load user: %w", row.line, err)
+			}
+			fmt.Fprintf(out, "line %d %s: user not found\n", row.line, row.email)
+			summary.failed++
+			if opts.strict {
+				return summary, fmt.Errorf("line %d: user %s not found; nothing applied", row.line, row.email)
+			}
+			continue
+		}
+		props := planProperties(user.Props, row.quota, opts.keepUsage, opts.now)
+		if opts.dryRun {
+			fmt.Fprintf(out, "line %d %s: dry run\n", row.line, user.Email)
+			writePlanDiff(out, user, row.plan, props)
+			summary.applied++
+			continue
+		}
+		updated, err := updatePlan(ctx, sql, user.ID, row.plan, props)
+		if err != nil {
+			return summary, fmt.Errorf("line %d: %w", row.line, err)
+		}
+		fmt.Fprintf(out, "line %d %s: %s -> %s (quota_daily=%v)\n", row.line, updated.Email, user.Plan, updated.Plan, updated.Props["quota_daily"])
+		summary.applied++
+	}
+	return summary, nil
+}
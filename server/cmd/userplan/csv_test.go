@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"server/internal/sqlinline"
+)
+
+type stubUser struct {
+	id    string
+	plan  string
+	props map[string]any
+}
+
+// planSQL serves users by email and records plan updates.
+type planSQL struct {
+	users   map[string]stubUser
+	updates []string
+}
+
+type rowFunc func(dest ...any) error
+
+func (f rowFunc) Scan(dest ...any) error { return f(dest...) }
+
+func (s *planSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("unexpected exec")
+}
+
+func (s *planSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	switch query {
+	case sqlinline.QSelectUserPlanByEmail:
+		email := args[0].(string)
+		user, ok := s.users[email]
+		if !ok {
+			return rowFunc(func(...any) error { return pgx.ErrNoRows })
+		}
+		raw, _ := json.Marshal(user.props)
+		return rowFunc(func(dest ...any) error {
+			*dest[0].(*string) = user.id
+			*dest[1].(*string) = email
+			*dest[2].(*string) = user.plan
+			*dest[3].(*[]byte) = raw
+			return nil
+		})
+	case sqlinline.QUpdateUserPlan:
+		id, plan, raw := args[0].(string), args[1].(string), args[2].([]byte)
+		s.updates = append(s.updates, id+":"+plan)
+		return rowFunc(func(dest ...any) error {
+			*dest[0].(*string) = id
+			*dest[1].(*string) = id + "@example.com"
+			*dest[2].(*string) = plan
+			*dest[3].(*[]byte) = raw
+			return nil
+		})
+	default:
+		return rowFunc(func(...any) error { return errors.New("unexpected query") })
+	}
+}
+
+func (s *planSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
+
+const mixedPlanCSV = `email,plan,quota
+alice@example.com,supporter,100
+not-an-email,pro,50
+bob@example.com,gold,50
+carol@example.com,pro,lots
+dave@example.com,pro
+erin@example.com,PRO,0
+`
+
+func newPlanSQL() *planSQL {
+	return &planSQL{users: map[string]stubUser{
+		"alice@example.com": {id: "alice", plan: "free", props: map[string]any{"quota_daily": 2, "quota_used_today": 1}},
+		"erin@example.com":  {id: "erin", plan: "free", props: map[string]any{"quota_daily": 2}},
+	}}
+}
+
+func TestApplyPlanCSVLenientSkipsMalformedRows(t *testing.T) {
+	sql := newPlanSQL()
+	var out bytes.Buffer
+	summary, err := applyPlanCSV(context.Background(), sql, strings.NewReader(mixedPlanCSV), bulkOptions{now: time.Now()}, &out)
+	if err != nil {
+		t.Fatalf("applyPlanCSV error: %v", err)
+	}
+	if summary.applied != 2 || summary.failed != 4 {
+		t.Fatalf("summary = %+v, want 2 applied and 4 failed; output:\n%s", summary, out.String())
+	}
+	if got := strings.Join(sql.updates, ","); got != "alice:supporter,erin:pro" {
+		t.Fatalf("updates = %q", got)
+	}
+	for _, want := range []string{
+		"line 2 alice@example.com: free -> supporter (quota_daily=100)",
+		"line 3: malformed: invalid email",
+		`line 4: malformed: unsupported plan "gold"`,
+		`line 5: malformed: invalid quota "lots"`,
+		"line 6: malformed: expected 3 columns",
+		"line 7 erin@example.com: free -> pro (quota_daily=2)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestApplyPlanCSVStrictAbortsOnMalformedRows(t *testing.T) {
+	sql := newPlanSQL()
+	var out bytes.Buffer
+	_, err := applyPlanCSV(context.Background(), sql, strings.NewReader(mixedPlanCSV), bulkOptions{strict: true, now: time.Now()}, &out)
+	if err == nil {
+		t.Fatal("expected strict mode to fail on malformed rows")
+	}
+	if len(sql.updates) != 0 {
+		t.Fatalf("strict mode applied updates: %v", sql.updates)
+	}
+	if !strings.Contains(out.String(), "line 3: malformed") {
+		t.Fatalf("malformed rows were not reported:\n%s", out.String())
+	}
+}
+
+func TestApplyPlanCSVStrictAbortsOnUnknownUser(t *testing.T) {
+	sql := newPlanSQL()
+	input := "alice@example.com,pro,50\nnobody@example.com,pro,50\n"
+	var out bytes.Buffer
+	_, err := applyPlanCSV(context.Background(), sql, strings.NewReader(input), bulkOptions{strict: true, now: time.Now()}, &out)
+	if err == nil || !strings.Contains(err.Error(), "nobody@example.com") {
+		t.Fatalf("expected unknown user error, got %v", err)
+	}
+
+	lenient := newPlanSQL()
+	out.Reset()
+	summary, err := applyPlanCSV(context.Background(), lenient, strings.NewReader(input), bulkOptions{now: time.Now()}, &out)
+	if err != nil {
+		t.Fatalf("lenient error: %v", err)
+	}
+	if summary.applied != 1 || summary.failed != 1 || !strings.Contains(out.String(), "line 2 nobody@example.com: user not found") {
+		t.Fatalf("summary = %+v, output:\n%s", summary, out.String())
+	}
+}
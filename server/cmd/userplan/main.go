@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -13,7 +12,6 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"server/internal/infra"
-	"server/internal/sqlinline"
 )
 
 func main() {
@@ -23,6 +21,9 @@ func main() {
 		planFlag      string
 		quotaFlag     int
 		keepUsageFlag bool
+		csvFlag       string
+		strictFlag    bool
+		dryRunFlag    bool
 	)
 
 	flag.StringVar(&idFlag, "id", "", "user ID to update (UUID)")
@@ -30,22 +31,28 @@ func main() {
 	flag.StringVar(&planFlag, "plan", "pro", "plan to assign (free, pro, supporter)")
 	flag.IntVar(&quotaFlag, "quota", 50, "daily quota to enforce for the plan (set <=0 to keep current value)")
 	flag.BoolVar(&keepUsageFlag, "keep-usage", false, "preserve current quota_used_today instead of resetting to 0")
+	flag.StringVar(&csvFlag, "csv", "", "CSV file of email,plan,quota rows to apply in a single transaction")
+	flag.BoolVar(&strictFlag, "strict", false, "with -csv, abort without changes when any row is malformed or unknown")
+	flag.BoolVar(&dryRunFlag, "dry-run", false, "print the before/after plan and properties diff without updating")
 	flag.Parse()
 
 	userID := strings.TrimSpace(idFlag)
 	email := strings.TrimSpace(emailFlag)
 	plan := strings.TrimSpace(strings.ToLower(planFlag))
+	csvPath := strings.TrimSpace(csvFlag)
 
-	if userID == "" && email == "" {
-		exitWithError(errors.New("either -id or -email must be provided"))
-	}
-	if plan == "" {
-		exitWithError(errors.New("-plan is required"))
-	}
-	switch plan {
-	case "free", "pro", "supporter":
-	default:
-		exitWithError(fmt.Errorf("unsupported plan %q", plan))
+	if csvPath == "" {
+		if userID == "" && email == "" {
+			exitWithError(errors.New("either -id, -email or -csv must be provided"))
+		}
+		if plan == "" {
+			exitWithError(errors.New("-plan is required"))
+		}
+		if err := validatePlan(plan); err != nil {
+			exitWithError(err)
+		}
+	} else if userID != "" || email != "" {
+		exitWithError(errors.New("-csv cannot be combined with -id or -email"))
 	}
 
 	dbURL := strings.TrimSpace(os.Getenv("DATABASE_URL"))
@@ -65,75 +72,52 @@ func main() {
 	logger := infra.NewLogger("cli").With().Str("cmd", "userplan").Logger()
 	runner := infra.NewSQLRunner(pool, logger)
 
-	lookupCtx, cancelLookup := context.WithTimeout(context.Background(), 5*time.Second)
-	var rowData struct {
-		ID    string
-		Email string
-		Plan  string
-		Props []byte
-	}
-	var scanErr error
-	if userID != "" {
-		row := runner.QueryRow(lookupCtx, sqlinline.QSelectUserPlanByID, userID)
-		scanErr = row.Scan(&rowData.ID, &rowData.Email, &rowData.Plan, &rowData.Props)
-	} else {
-		row := runner.QueryRow(lookupCtx, sqlinline.QSelectUserPlanByEmail, email)
-		scanErr = row.Scan(&rowData.ID, &rowData.Email, &rowData.Plan, &rowData.Props)
-	}
-	cancelLookup()
-	if scanErr != nil {
-		exitWithError(fmt.Errorf("failed to load user: %w", scanErr))
+	if csvPath != "" {
+		runCSV(runner, csvPath, bulkOptions{strict: strictFlag, keepUsage: keepUsageFlag, dryRun: dryRunFlag, now: time.Now()})
+		return
 	}
 
-	props := map[string]any{}
-	if len(rowData.Props) > 0 {
-		if err := json.Unmarshal(rowData.Props, &props); err != nil {
-			exitWithError(fmt.Errorf("failed to decode user properties: %w", err))
-		}
+	planCtx, cancelPlan := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelPlan()
+	req := planRequest{
+		userID:    userID,
+		email:     email,
+		plan:      plan,
+		quota:     quotaFlag,
+		keepUsage: keepUsageFlag,
+		dryRun:    dryRunFlag,
+		now:       time.Now(),
 	}
-
-	if quotaFlag > 0 {
-		props["quota_daily"] = quotaFlag
+	if err := assignPlan(planCtx, runner, req, os.Stdout); err != nil {
+		exitWithError(err)
 	}
-	if !keepUsageFlag {
-		props["quota_used_today"] = 0
-	}
-	props["quota_refreshed_at"] = time.Now().UTC().Format(time.RFC3339Nano)
+}
 
-	updatedProps, err := json.Marshal(props)
+func runCSV(runner *infra.SQLRunner, path string, opts bulkOptions) {
+	f, err := os.Open(path)
 	if err != nil {
-		exitWithError(fmt.Errorf("failed to encode user properties: %w", err))
+		exitWithError(fmt.Errorf("failed to open csv: %w", err))
 	}
+	defer func() {
+		_ = f.Close()
+	}()
 
-	updateCtx, cancelUpdate := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancelUpdate()
-	row := runner.QueryRow(updateCtx, sqlinline.QUpdateUserPlan, rowData.ID, plan, updatedProps)
-
-	var (
-		updatedID         string
-		updatedEmail      string
-		updatedPlan       string
-		updatedPropsBytes []byte
-	)
-	if err := row.Scan(&updatedID, &updatedEmail, &updatedPlan, &updatedPropsBytes); err != nil {
-		exitWithError(fmt.Errorf("failed to update user plan: %w", err))
-	}
-
-	resultProps := map[string]any{}
-	if len(updatedPropsBytes) > 0 {
-		_ = json.Unmarshal(updatedPropsBytes, &resultProps)
-	}
-
-	fmt.Printf("User %s (%s) updated to plan %s\n", updatedID, updatedEmail, updatedPlan)
-	if quota, ok := resultProps["quota_daily"]; ok {
-		fmt.Printf("quota_daily=%v\n", quota)
-	}
-	if used, ok := resultProps["quota_used_today"]; ok {
-		fmt.Printf("quota_used_today=%v\n", used)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	var summary bulkSummary
+	err = runner.InTx(ctx, func(sql infra.SQLExecutor) error {
+		var applyErr error
+		summary, applyErr = applyPlanCSV(ctx, sql, f, opts, os.Stdout)
+		return applyErr
+	})
+	if err != nil {
+		exitWithError(fmt.Errorf("bulk plan assignment rolled back: %w", err))
 	}
-	if refreshed, ok := resultProps["quota_refreshed_at"]; ok {
-		fmt.Printf("quota_refreshed_at=%v\n", refreshed)
+	if opts.dryRun {
+		fmt.Printf("dry run: would_apply=%d failed=%d\n", summary.applied, summary.failed)
+		return
 	}
+	fmt.Printf("applied=%d failed=%d\n", summary.applied, summary.failed)
 }
 
 func exitWithError(err error) {
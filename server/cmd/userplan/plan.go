@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"server/internal/infra"
+	"server/internal/sqlinline"
+)
+
+type planRequest struct {
+	userID    string
+	email     string
+	plan      string
+	quota     int
+	keepUsage bool
+	dryRun    bool
+	now       time.Time
+}
+
+type planUser struct {
+	ID    string
+	Email string
+	Plan  string
+	Props map[string]any
+}
+
+func validatePlan(plan string) error {
+	switch plan {
+	case "free", "pro", "supporter":
+		return nil
+	case "":
+		return fmt.Errorf("plan is required")
+	default:
+		return fmt.Errorf("unsupported plan %q", plan)
+	}
+}
+
+// loadUser looks the user up by ID when given, otherwise by email.
+func loadUser(ctx context.Context, sql infra.SQLExecutor, userID, email string) (planUser, error) {
+	query, arg := sqlinline.QSelectUserPlanByEmail, email
+	if userID != "" {
+		query, arg = sqlinline.QSelectUserPlanByID, userID
+	}
+	var (
+		user planUser
+		raw  []byte
+	)
+	if err := sql.QueryRow(ctx, query, arg).Scan(&user.ID, &user.Email, &user.Plan, &raw); err != nil {
+		return planUser{}, err
+	}
+	user.Props = map[string]any{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &user.Props); err != nil {
+			return planUser{}, fmt.Errorf("failed to decode user properties: %w", err)
+		}
+	}
+	return user, nil
+}
+
+// planProperties returns a copy of props carrying the quota settings for a
+// plan change. A quota <= 0 keeps the current daily quota.
+func planProperties(props map[string]any, quota int, keepUsage bool, now time.Time) map[string]any {
+	next := make(map[string]any, len(props)+3)
+	for k, v := range props {
+		next[k] = v
+	}
+	if quota > 0 {
+		next["quota_daily"] = quota
+	}
+	if !keepUsage {
+		next["quota_used_today"] = 0
+	}
+	next["quota_refreshed_at"] = now.UTC().Format(time.RFC3339Nano)
+	return next
+}
+
+func updatePlan(ctx context.Context, sql infra.SQLExecutor, userID, plan string, props map[string]any) (planUser, error) {
+	raw, err := json.Marshal(props)
+	if err != nil {
+		return planUser{}, fmt.Errorf("failed to encode user properties: %w", err)
+	}
+	var (
+		updated    planUser
+		updatedRaw []byte
+	)
+	row := sql.QueryRow(ctx, sqlinline.QUpdateUserPlan, userID, plan, raw)
+	if err := row.Scan(&updated.ID, &updated.Email, &updated.Plan, &updatedRaw); err != nil {
+		return planUser{}, fmt.Errorf("failed to update user plan: %w", err)
+	}
+	updated.Props = map[string]any{}
+	if len(updatedRaw) > 0 {
+		_ = json.Unmarshal(updatedRaw, &updated.Props)
+	}
+	return updated, nil
+}
+
+// assignPlan updates a single user's plan and reports the result to out. In
+// dry-run mode it prints the before/after diff and skips the update.
+func assignPlan(ctx context.Context, sql infra.SQLExecutor, req planRequest, out io.Writer) error {
+	user, err := loadUser(ctx, sql, req.userID, req.email)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	props := planProperties(user.Props, req.quota, req.keepUsage, req.now)
+	if req.dryRun {
+		fmt.Fprintf(out, "User %s (%s) dry run, no changes applied\n", user.ID, user.Email)
+		writePlanDiff(out, user, req.plan, props)
+		return nil
+	}
+
+	updated, err := updatePlan(ctx, sql, user.ID, req.plan, props)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "User %s (%s) updated to plan %s\n", updated.ID, updated.Email, updated.Plan)
+	for _, key := range []string{"quota_daily", "quota_used_today", "quota_refreshed_at"} {
+		if value, ok := updated.Props[key]; ok {
+			fmt.Fprintf(out, "%s=%v\n", key, value)
+		}
+	}
+	return nil
+}
+
+// writePlanDiff prints the plan change and every properties key whose JSON
+// value differs, "-" lines showing the current value and "+" the new one.
+func writePlanDiff(out io.Writer, user planUser, plan string, props map[string]any) {
+	if user.Plan == plan {
+		fmt.Fprintf(out, "  plan: %s (unchanged)\n", plan)
+	} else {
+		fmt.Fprintf(out, "  plan: %s -> %s\n", user.Plan, plan)
+	}
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	for key := range user.Props {
+		if _, ok := props[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	changed := false
+	for _, key := range keys {
+		before, hadBefore := user.Props[key]
+		after, hasAfter := props[key]
+		beforeJSON, afterJSON := diffValue(before), diffValue(after)
+		if hadBefore == hasAfter && beforeJSON == afterJSON {
+			continue
+		}
+		changed = true
+		if hadBefore {
+			fmt.Fprintf(out, "- %q: %s\n", key, beforeJSON)
+		}
+		if hasAfter {
+			fmt.Fprintf(out, "+ %q: %s\n", key, afterJSON)
+		}
+	}
+	if !changed {
+		fmt.Fprintln(out, "  properties unchanged")
+	}
+}
+
+func diffValue(v any) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(raw)
+}
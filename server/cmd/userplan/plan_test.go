@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAssignPlanDryRunSkipsUpdate(t *testing.T) {
+	sql := newPlanSQL()
+	var out bytes.Buffer
+	req := planRequest{
+		email:  "alice@example.com",
+		plan:   "pro",
+		quota:  50,
+		dryRun: true,
+		now:    time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := assignPlan(context.Background(), sql, req, &out); err != nil {
+		t.Fatalf("assignPlan error: %v", err)
+	}
+	if len(sql.updates) != 0 {
+		t.Fatalf("dry run executed updates: %v", sql.updates)
+	}
+	for _, want := range []string{
+		"dry run, no changes applied",
+		"plan: free -> pro",
+		`- "quota_daily": 2`,
+		`+ "quota_daily": 50`,
+		`- "quota_used_today": 1`,
+		`+ "quota_used_today": 0`,
+		`+ "quota_refreshed_at": "2026-03-01T00:00:00Z"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("diff missing %q:\n%s", want, out.String())
+		}
+	}
+
+	req.dryRun = false
+	out.Reset()
+	if err := assignPlan(context.Background(), sql, req, &out); err != nil {
+		t.Fatalf("assignPlan error: %v", err)
+	}
+	if strings.Join(sql.updates, ",") != "alice:pro" {
+		t.Fatalf("updates = %v, want alice:pro", sql.updates)
+	}
+}
+
+func TestApplyPlanCSVDryRunSkipsUpdates(t *testing.T) {
+	sql := newPlanSQL()
+	var out bytes.Buffer
+	summary, err := applyPlanCSV(context.Background(), sql, strings.NewReader("alice@example.com,supporter,100\n"), bulkOptions{dryRun: true, now: time.Now()}, &out)
+	if err != nil {
+		t.Fatalf("applyPlanCSV error: %v", err)
+	}
+	if summary.applied != 1 || len(sql.updates) != 0 {
+		t.Fatalf("summary = %+v, updates = %v", summary, sql.updates)
+	}
+	if !strings.Contains(out.String(), "plan: free -> supporter") {
+		t.Fatalf("missing diff:\n%s", out.String())
+	}
+}
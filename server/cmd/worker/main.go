@@ -1,29 +1,44 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	stdimage "image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
 	"io"
 	"net/http"
 	neturl "net/url"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"server/internal/domain/jsoncfg"
 	"server/internal/infra"
 	"server/internal/infra/credentials"
+	"server/internal/metrics"
+	"server/internal/netguard"
+	"server/internal/providers/breaker"
 	"server/internal/providers/genai"
 	"server/internal/providers/image"
 	"server/internal/providers/qwen"
 	videoprovider "server/internal/providers/video"
 	"server/internal/sqlinline"
 	"server/internal/storage"
+	"server/internal/thumbnail"
+	"server/internal/watermark"
+	"server/internal/webhook"
+	"server/pkg/webp"
 )
 
 const (
@@ -38,34 +53,84 @@ const (
 
 	jobPollInterval = 2 * time.Second
 
+	defaultMaxAttempts = 3
+	retryBaseDelay     = 2 * time.Second
+	retryBackoffFactor = 4
+
+	defaultShutdownGrace = 10 * time.Second
+
+	heartbeatInterval = jobPollInterval
+
+	defaultImageGenTimeout = 90 * time.Second
+	defaultVideoGenTimeout = 180 * time.Second
+
 	sourceAssetDownloadTimeout = 30 * time.Second
+	maxSourceRedirects         = 10
+
+	assetPurgeInterval  = 10 * time.Minute
+	assetPurgeBatchSize = 100
+
+	revokedTokenPruneInterval = time.Hour
 )
 
 const (
-	maxSourceImageBytes int64 = 20 * 1024 * 1024
+	// defaultSourceImageBytes caps source downloads when SOURCE_IMAGE_MAX_MB
+	// is unset.
+	defaultSourceImageBytes int64 = 20 * 1024 * 1024
 )
 
 type job struct {
-	ID       string
-	UserID   string
-	TaskType string
-	Provider string
-	Quantity int
-	Aspect   string
-	Prompt   json.RawMessage
+	ID        string
+	UserID    string
+	TaskType  string
+	Provider  string
+	Quantity  int
+	Aspect    string
+	Prompt    json.RawMessage
+	Attempts  int
+	Callback  string
+	RequestID string
+	Plan      string
+	BrandName string
 }
 
 type jobWorker struct {
 	ctx            context.Context
-	runner         *infra.SQLRunner
+	runner         infra.SQLExecutor
 	logger         infra.Logger
+	maxAttempts    int
+	shutdownGrace  time.Duration
 	imageProviders map[string]image.Generator
 	videoProviders map[string]videoprovider.Generator
-	store          *storage.FileStore
-	httpClient     *http.Client
+	store          storage.Storage
+	// sourceClient downloads user-supplied source asset URLs through a
+	// netguard client, so a host that resolves or redirects to a private
+	// address is refused at fetch time, not only when the job was queued.
+	sourceClient *http.Client
+
+	concurrency  int
+	maxPoll      time.Duration
+	purgeGrace   time.Duration
+	retention    map[string]time.Duration
+	imageTimeout time.Duration
+	videoTimeout time.Duration
+	sourceMaxMB  int
+	planSourceMB map[string]int
+	negatives    map[string][]string
+	notifier     *webhook.Notifier
+	assetBaseURL string
+	urlSigner    *storage.URLSigner
+	signedTTL    time.Duration
+	workerID     string
+
+	mu         sync.Mutex
+	activeJobs map[string]struct{}
 }
 
-var errNoJobAvailable = errors.New("no job available")
+var (
+	errNoJobAvailable     = errors.New("no job available")
+	errGenerationTimedOut = errors.New("generation timed out")
+)
 
 func main() {
 	cfg, err := infra.LoadConfig()
@@ -85,16 +150,20 @@ func main() {
 
 	runner := infra.NewSQLRunner(pool, logger)
 
-	storagePath := cfg.StoragePath
-	if storagePath == "" {
-		storagePath = "./storage"
-	}
-	if !filepath.IsAbs(storagePath) {
-		if abs, err := filepath.Abs(storagePath); err == nil {
-			storagePath = abs
-		}
+	if addr := strings.TrimSpace(cfg.WorkerMetricsAddr); addr != "" {
+		metricsServer := &http.Server{Addr: addr, Handler: metrics.Default, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error().Err(err).Str("addr", addr).Msg("worker: metrics server stopped")
+			}
+		}()
+		defer func() {
+			_ = metricsServer.Close()
+		}()
+		logger.Info().Str("addr", addr).Msg("worker: serving metrics")
 	}
-	fileStore, err := storage.NewFileStore(storagePath)
+
+	store, err := storage.New(cfg)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("worker: failed to configure storage")
 	}
@@ -121,48 +190,97 @@ func main() {
 		}
 	}
 
+	openaiAPIKey := strings.TrimSpace(cfg.OpenAIAPIKey)
+	if openaiAPIKey == "" {
+		keyFromStore, err := credStore.OpenAIAPIKey(ctx)
+		if err != nil {
+			logger.Warn().Err(err).Msg("worker: failed to load openai api key from store")
+		} else {
+			openaiAPIKey = keyFromStore
+		}
+	}
+
 	httpClient := &http.Client{Timeout: 60 * time.Second}
 	geminiClient, err := genai.NewClient(genai.Options{
-		APIKey:     geminiAPIKey,
-		BaseURL:    cfg.GeminiBaseURL,
-		Model:      cfg.GeminiModel,
-		HTTPClient: httpClient,
-		Logger:     &logger,
+		APIKey:                   geminiAPIKey,
+		BaseURL:                  cfg.GeminiBaseURL,
+		Model:                    cfg.GeminiModel,
+		HTTPClient:               httpClient,
+		Logger:                   &logger,
+		DisableSyntheticFallback: !cfg.SyntheticFallback,
+		SyntheticVideoSeconds:    int(cfg.SyntheticVideoLength / time.Second),
 	})
 	if err != nil {
 		logger.Fatal().Err(err).Msg("worker: failed to configure gemini client")
 	}
 
-	if geminiAPIKey == "" {
+	if geminiAPIKey == "" && !cfg.SyntheticFallback {
+		logger.Warn().Str("model", geminiClient.Model()).Msg("worker: gemini api key missing and synthetic fallback disabled; gemini jobs will fail")
+	} else if geminiAPIKey == "" {
 		logger.Warn().Str("model", geminiClient.Model()).Msg("worker: gemini api key missing, using synthetic asset generation")
 	}
 
 	qwenClient, err := qwen.NewClient(qwen.Options{
-		APIKey:         qwenAPIKey,
-		BaseURL:        cfg.QwenBaseURL,
-		Model:          cfg.QwenModel,
-		DefaultSize:    cfg.QwenDefaultSize,
-		PromptExtend:   true,
-		Watermark:      false,
-		HTTPClient:     httpClient,
-		Logger:         &logger,
-		RequestTimeout: 45 * time.Second,
+		APIKey:                   qwenAPIKey,
+		BaseURL:                  cfg.QwenBaseURL,
+		RegionBaseURLs:           cfg.QwenRegionBaseURLs,
+		Model:                    cfg.QwenModel,
+		VideoModel:               cfg.QwenVideoModel,
+		DefaultSize:              cfg.QwenDefaultSize,
+		PromptExtend:             true,
+		Watermark:                false,
+		HTTPClient:               httpClient,
+		Logger:                   &logger,
+		RequestTimeout:           45 * time.Second,
+		DisableSyntheticFallback: !cfg.SyntheticFallback,
 	})
 	if err != nil {
 		logger.Fatal().Err(err).Msg("worker: failed to configure qwen client")
 	}
-	if !qwenClient.HasCredentials() {
+	if !qwenClient.HasCredentials() && !cfg.SyntheticFallback {
+		logger.Warn().Str("model", qwenClient.Model()).Msg("worker: qwen api key missing and synthetic fallback disabled; qwen jobs will fail")
+	} else if !qwenClient.HasCredentials() {
 		logger.Warn().Str("model", qwenClient.Model()).Msg("worker: qwen api key missing, falling back to synthetic assets")
 	}
 
+	openaiImageOpts := image.OpenAIOptions{
+		APIKey:       openaiAPIKey,
+		BaseURL:      cfg.OpenAIBaseURL,
+		Organization: cfg.OpenAIOrg,
+		HTTPClient:   &http.Client{Timeout: 120 * time.Second},
+	}
+
+	qwenBreaker := breaker.New(breaker.Options{
+		Threshold:     cfg.QwenBreakerThreshold,
+		Cooldown:      cfg.QwenBreakerCooldown,
+		OnStateChange: breaker.LogStateChanges(logger, "qwen"),
+	})
+
+	trustedHosts := netguard.Allowlist(cfg.TrustedHosts)
 	worker := &jobWorker{
 		ctx:            ctx,
 		runner:         runner,
 		logger:         logger,
-		imageProviders: initImageProviders(qwenClient, geminiClient),
-		videoProviders: initVideoProviders(geminiClient),
-		store:          fileStore,
-		httpClient:     httpClient,
+		maxAttempts:    cfg.WorkerMaxAttempts,
+		shutdownGrace:  cfg.WorkerShutdownGrace,
+		concurrency:    cfg.WorkerConcurrency,
+		maxPoll:        cfg.WorkerMaxPoll,
+		purgeGrace:     cfg.AssetPurgeGrace,
+		retention:      planRetention(cfg.AssetRetentionDays),
+		imageTimeout:   cfg.ImageGenTimeout,
+		videoTimeout:   cfg.VideoGenTimeout,
+		sourceMaxMB:    cfg.SourceImageMaxMB,
+		planSourceMB:   cfg.PlanSourceImageMB,
+		negatives:      cfg.NegativePrompts,
+		notifier:       webhook.NewNotifier(trustedHosts),
+		assetBaseURL:   cfg.StorageBaseURL,
+		urlSigner:      storage.NewURLSigner(cfg.StorageSignedBaseURL, cfg.StorageSigningSecret),
+		signedTTL:      cfg.StorageSignedURLTTL,
+		workerID:       workerIdentity(),
+		imageProviders: initImageProviders(qwenClient, geminiClient, openaiImageOpts, qwenBreaker),
+		videoProviders: initVideoProviders(qwenClient, geminiClient),
+		store:          store,
+		sourceClient:   newSourceClient(trustedHosts, nil),
 	}
 
 	if err := worker.Run(); err != nil && !errors.Is(err, context.Canceled) {
@@ -171,9 +289,13 @@ func main() {
 	logger.Info().Msg("worker: stopped")
 }
 
-func initImageProviders(qwenClient *qwen.Client, geminiClient *genai.Client) map[string]image.Generator {
+func initImageProviders(qwenClient *qwen.Client, geminiClient *genai.Client, openaiOpts image.OpenAIOptions, qwenBreaker *breaker.Breaker) map[string]image.Generator {
 	gemini := image.NewGeminiGenerator(geminiClient)
-	qwen := image.NewQwenGenerator(qwenClient, gemini)
+	qwen := image.NewQwenGenerator(qwenClient, gemini).WithBreaker(qwenBreaker)
+	openaiOpts.Model = "gpt-image-1"
+	gptImage := image.NewOpenAIGenerator(openaiOpts, gemini)
+	openaiOpts.Model = "dall-e-3"
+	dallE := image.NewOpenAIGenerator(openaiOpts, gemini)
 	providers := map[string]image.Generator{
 		"qwen":             qwen,
 		"qwen-image":       qwen,
@@ -182,6 +304,9 @@ func initImageProviders(qwenClient *qwen.Client, geminiClient *genai.Client) map
 		"gemini-1.5-flash": gemini,
 		"gemini-2.0-flash": gemini,
 		"gemini-2.5-flash": gemini,
+		"openai":           gptImage,
+		"gpt-image-1":      gptImage,
+		"dall-e-3":         dallE,
 	}
 	if qwenClient != nil {
 		providers[strings.ToLower(qwenClient.Model())] = qwen
@@ -192,51 +317,529 @@ func initImageProviders(qwenClient *qwen.Client, geminiClient *genai.Client) map
 	return providers
 }
 
-func initVideoProviders(client *genai.Client) map[string]videoprovider.Generator {
-	gemini := videoprovider.NewGeminiGenerator(client)
-	return map[string]videoprovider.Generator{
+func initVideoProviders(qwenClient *qwen.Client, geminiClient *genai.Client) map[string]videoprovider.Generator {
+	gemini := videoprovider.NewGeminiGenerator(geminiClient)
+	qwen := videoprovider.NewQwenGenerator(qwenClient, gemini)
+	providers := map[string]videoprovider.Generator{
+		"qwen":             qwen,
+		"wan":              qwen,
 		"gemini":           gemini,
 		"gemini-1.5-flash": gemini,
 		"gemini-2.0-flash": gemini,
 		"gemini-2.5-flash": gemini,
 	}
+	if qwenClient != nil {
+		providers[strings.ToLower(qwenClient.VideoModel())] = qwen
+	}
+	return providers
 }
 
 func (w *jobWorker) Run() error {
-	w.logger.Info().Msg("worker: started")
+	workers := w.workerCount()
+	w.logger.Info().Int("concurrency", workers).Msg("worker: started")
+	idle := newIdleTracker(workers, newPollBackoff(jobPollInterval, w.maxPoll))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(idle)
+		}()
+	}
+	if w.store != nil && (w.purgeGrace > 0 || len(w.retention) > 0) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.purgeLoop()
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.pruneRevokedTokensLoop()
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.heartbeatLoop()
+	}()
+	<-w.ctx.Done()
+	for _, active := range w.currentJobs() {
+		w.logger.Warn().Str("job_id", active).Msg("worker: waiting for in-flight job before shutdown")
+	}
+	wg.Wait()
+	return w.ctx.Err()
+}
+
+// loop claims and handles jobs until the worker context is cancelled. Workers
+// that find no job park on the idle tracker; the poll delay only elapses once
+// every worker is idle and grows while the queue stays empty.
+func (w *jobWorker) loop(idle *idleTracker) {
+	for {
+		if w.ctx.Err() != nil {
+			return
+		}
+
+		j, err := w.claimJob()
+		if err != nil {
+			if !errors.Is(err, errNoJobAvailable) && w.ctx.Err() == nil {
+				w.logger.Error().Err(err).Msg("worker: failed to claim job")
+			}
+			idle.wait(w.ctx)
+			continue
+		}
+
+		idle.reset()
+		w.handleJob(j)
+		idle.release()
+	}
+}
+
+// purgeLoop periodically removes soft-deleted assets whose grace period has
+// expired and assets that outlived their owner's plan retention.
+func (w *jobWorker) purgeLoop() {
+	ticker := time.NewTicker(assetPurgeInterval)
+	defer ticker.Stop()
+	for {
+		if w.purgeGrace > 0 {
+			if n, err := w.purgeDeletedAssets(); err != nil && w.ctx.Err() == nil {
+				w.logger.Warn().Err(err).Msg("worker: asset purge sweep failed")
+			} else if n > 0 {
+				w.logger.Info().Int("purged", n).Msg("worker: purged deleted assets")
+			}
+		}
+		if len(w.retention) > 0 {
+			if n, err := w.expireRetainedAssets(); err != nil && w.ctx.Err() == nil {
+				w.logger.Warn().Err(err).Msg("worker: asset retention sweep failed")
+			} else if n > 0 {
+				w.logger.Info().Int("expired", n).Msg("worker: removed assets past plan retention")
+			}
+		}
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneRevokedTokensLoop periodically drops revocation records for tokens
+// that have expired anyway, keeping the per-request revocation lookup small.
+func (w *jobWorker) pruneRevokedTokensLoop() {
+	ticker := time.NewTicker(revokedTokenPruneInterval)
+	defer ticker.Stop()
 	for {
+		if tag, err := w.runner.Exec(w.ctx, sqlinline.QDeleteExpiredRevokedTokens); err != nil && w.ctx.Err() == nil {
+			w.logger.Warn().Err(err).Msg("worker: revoked token prune failed")
+		} else if n := tag.RowsAffected(); n > 0 {
+			w.logger.Info().Int64("pruned", n).Msg("worker: pruned expired token revocations")
+		}
 		select {
 		case <-w.ctx.Done():
-			return w.ctx.Err()
-		default:
+			return
+		case <-ticker.C:
 		}
+	}
+}
 
-		j, err := w.claimJob()
+// purgeDeletedAssets hard-deletes one batch of expired soft-deleted assets:
+// the stored bytes first, then the row. Assets whose bytes cannot be removed
+// are left for the next sweep.
+func (w *jobWorker) purgeDeletedAssets() (int, error) {
+	assets, err := w.selectRemovableAssets(sqlinline.QSelectPurgeableAssets, int(w.purgeGrace.Seconds()), assetPurgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return w.removeAssets(assets), nil
+}
+
+// expireRetainedAssets removes one batch per plan of assets older than that
+// plan's retention. Plans without a TTL keep their assets, and assets still
+// used by a queued or running job are skipped until it finishes.
+func (w *jobWorker) expireRetainedAssets() (int, error) {
+	plans := make([]string, 0, len(w.retention))
+	for plan := range w.retention {
+		plans = append(plans, plan)
+	}
+	sort.Strings(plans)
+	expired := 0
+	for _, plan := range plans {
+		assets, err := w.selectRemovableAssets(sqlinline.QSelectExpiredPlanAssets, plan, int(w.retention[plan].Seconds()), assetPurgeBatchSize)
 		if err != nil {
-			if errors.Is(err, errNoJobAvailable) {
-				time.Sleep(jobPollInterval)
+			return expired, fmt.Errorf("plan %s: %w", plan, err)
+		}
+		expired += w.removeAssets(assets)
+	}
+	return expired, nil
+}
+
+type removableAsset struct{ id, storageKey, thumbnailKey string }
+
+func (w *jobWorker) selectRemovableAssets(query string, args ...any) ([]removableAsset, error) {
+	rows, err := w.runner.Query(w.ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var assets []removableAsset
+	for rows.Next() {
+		var item removableAsset
+		if err := rows.Scan(&item.id, &item.storageKey, &item.thumbnailKey); err != nil {
+			return nil, err
+		}
+		assets = append(assets, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// removeAssets deletes the stored bytes and then the row of each asset,
+// returning how many rows were removed.
+func (w *jobWorker) removeAssets(assets []removableAsset) int {
+	removed := 0
+	for _, asset := range assets {
+		if !isRemotePath(asset.storageKey) {
+			if err := w.store.Delete(w.ctx, asset.storageKey); err != nil {
+				w.logger.Warn().Err(err).Str("asset_id", asset.id).Msg("worker: delete asset bytes failed")
 				continue
 			}
-			w.logger.Error().Err(err).Msg("worker: failed to claim job")
-			time.Sleep(jobPollInterval)
+		}
+		if asset.thumbnailKey != "" {
+			if err := w.store.Delete(w.ctx, asset.thumbnailKey); err != nil {
+				w.logger.Warn().Err(err).Str("asset_id", asset.id).Msg("worker: delete asset thumbnail failed")
+			}
+		}
+		if _, err := w.runner.Exec(w.ctx, sqlinline.QDeleteAsset, asset.id); err != nil {
+			w.logger.Warn().Err(err).Str("asset_id", asset.id).Msg("worker: delete asset row failed")
 			continue
 		}
+		removed++
+	}
+	return removed
+}
 
-		w.handleJob(j)
+// planRetention converts ASSET_RETENTION_DAYS_BY_PLAN into per-plan TTLs.
+func planRetention(days map[string]int) map[string]time.Duration {
+	out := make(map[string]time.Duration, len(days))
+	for plan, n := range days {
+		if n > 0 {
+			out[plan] = 24 * time.Hour * time.Duration(n)
+		}
+	}
+	return out
+}
+
+// heartbeatLoop records that the worker process is alive every
+// heartbeatInterval. It runs on its own goroutine so the beat continues while
+// every job goroutine is busy with a long generation or parked idle.
+func (w *jobWorker) heartbeatLoop() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		w.heartbeat()
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *jobWorker) heartbeat() {
+	if _, err := w.runner.Exec(w.ctx, sqlinline.QWorkerHeartbeat, w.workerID); err != nil && w.ctx.Err() == nil {
+		w.logger.Warn().Err(err).Msg("worker: heartbeat failed")
+	}
+}
+
+func workerIdentity() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+func (w *jobWorker) workerCount() int {
+	if w.concurrency <= 0 {
+		return 1
+	}
+	return w.concurrency
+}
+
+// idleTracker coordinates polling across worker goroutines so the queue is
+// only re-polled after a sleep when all of them came up empty.
+type idleTracker struct {
+	mu      sync.Mutex
+	total   int
+	idle    int
+	wake    chan struct{}
+	backoff *pollBackoff
+}
+
+func newIdleTracker(total int, backoff *pollBackoff) *idleTracker {
+	return &idleTracker{total: total, wake: make(chan struct{}), backoff: backoff}
+}
+
+// wait parks the caller until another worker releases the tracker or the
+// current backoff delay passes, so jobs queued while a long job keeps its
+// worker busy are still claimed. The last worker to go idle sleeps for the
+// delay and advances the backoff.
+func (t *idleTracker) wait(ctx context.Context) {
+	t.mu.Lock()
+	t.idle++
+	if t.idle < t.total {
+		wake := t.wake
+		delay := t.backoff.current
+		t.mu.Unlock()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-wake:
+		case <-timer.C:
+			t.mu.Lock()
+			if t.wake == wake && t.idle > 0 {
+				t.idle--
+			}
+			t.mu.Unlock()
+		case <-ctx.Done():
+		}
+		return
+	}
+	delay := t.backoff.next()
+	t.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	t.release()
+}
+
+// reset drops the poll delay back to its floor after a successful claim.
+func (t *idleTracker) reset() {
+	t.mu.Lock()
+	t.backoff.reset()
+	t.mu.Unlock()
+}
+
+// release wakes idle workers, typically because a job just completed and more
+// may be waiting in the queue.
+func (t *idleTracker) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.idle == 0 {
+		return
+	}
+	t.idle = 0
+	close(t.wake)
+	t.wake = make(chan struct{})
+}
+
+// pollBackoff doubles the idle poll delay from floor up to ceiling across
+// consecutive empty claims. It is not safe for concurrent use on its own.
+type pollBackoff struct {
+	floor   time.Duration
+	ceiling time.Duration
+	current time.Duration
+}
+
+func newPollBackoff(floor, ceiling time.Duration) *pollBackoff {
+	if floor <= 0 {
+		floor = jobPollInterval
 	}
+	if ceiling < floor {
+		ceiling = floor
+	}
+	return &pollBackoff{floor: floor, ceiling: ceiling, current: floor}
+}
+
+// next returns the delay to sleep after an empty claim and advances the
+// backoff for the following one.
+func (b *pollBackoff) next() time.Duration {
+	delay := b.current
+	b.current *= 2
+	if b.current > b.ceiling {
+		b.current = b.ceiling
+	}
+	return delay
+}
+
+func (b *pollBackoff) reset() {
+	b.current = b.floor
+}
+
+// jobLogger returns the worker logger annotated with the job id and, when the
+// job was enqueued through the API, the originating request id.
+func (w *jobWorker) jobLogger(j job) infra.Logger {
+	ctx := w.logger.With().Str("job_id", j.ID)
+	if j.RequestID != "" {
+		ctx = ctx.Str("request_id", j.RequestID)
+	}
+	return ctx.Logger()
 }
 
 func (w *jobWorker) handleJob(j job) {
-	w.logger.Info().Str("job_id", j.ID).Str("task_type", j.TaskType).Msg("worker: picked job")
-	status := statusFailed
-	if err := w.dispatch(j); err != nil {
-		w.logger.Error().Err(err).Str("job_id", j.ID).Msg("worker: job failed")
-	} else {
-		status = statusSucceeded
+	log := w.jobLogger(j)
+	log.Info().Str("task_type", j.TaskType).Int("attempt", j.Attempts).Msg("worker: picked job")
+	w.trackJob(j.ID)
+	defer w.untrackJob(j.ID)
+	started := time.Now()
+	err := w.dispatch(j)
+	metrics.ObserveGeneration(j.TaskType, j.Provider, time.Since(started), err, err == nil || j.Attempts >= w.attemptLimit())
+	if err != nil {
+		if j.Attempts < w.attemptLimit() {
+			delay := retryDelay(j.Attempts)
+			log.Warn().Err(err).Int("attempt", j.Attempts).Dur("retry_in", delay).Msg("worker: job failed, scheduling retry")
+			if err := w.requeue(j.ID, delay); err != nil {
+				log.Error().Err(err).Msg("worker: requeue failed")
+			}
+			return
+		}
+		log.Error().Err(err).Int("attempt", j.Attempts).Msg("worker: job failed")
+		if err := w.updateStatusWithError(j.ID, statusFailed, err.Error()); err != nil {
+			log.Error().Err(err).Msg("worker: update status failed")
+		}
+		w.notifyCallback(j, statusFailed, err.Error())
+		return
+	}
+	if err := w.updateStatus(j.ID, statusSucceeded); err != nil {
+		log.Error().Err(err).Msg("worker: update status failed")
+	}
+	w.notifyCallback(j, statusSucceeded, "")
+}
+
+// notifyCallback posts the job outcome to the callback URL supplied at
+// enqueue time, if any. Delivery failures are logged and otherwise ignored.
+func (w *jobWorker) notifyCallback(j job, status, message string) {
+	log := w.jobLogger(j)
+	if strings.TrimSpace(j.Callback) == "" || w.notifier == nil {
+		return
+	}
+	ctx, cancel := w.persistContext()
+	defer cancel()
+	payload := webhook.Payload{
+		JobID:     j.ID,
+		Status:    status,
+		Error:     message,
+		AssetURLs: w.jobAssetURLs(ctx, j),
+	}
+	if err := w.notifier.Deliver(ctx, j.Callback, payload); err != nil {
+		log.Warn().Err(err).Msg("worker: callback delivery failed")
+	}
+}
+
+func (w *jobWorker) jobAssetURLs(ctx context.Context, j job) []string {
+	log := w.jobLogger(j)
+	rows, err := w.runner.Query(ctx, sqlinline.QSelectJobAssets, j.ID, j.UserID)
+	if err != nil {
+		log.Warn().Err(err).Msg("worker: load job assets for callback failed")
+		return nil
+	}
+	defer rows.Close()
+	var urls []string
+	for rows.Next() {
+		var (
+			id, storageKey, mime, aspect string
+			bytes                        int64
+			width, height                int
+			props                        []byte
+			createdAt                    time.Time
+		)
+		if err := rows.Scan(&id, &storageKey, &mime, &bytes, &width, &height, &aspect, &props, &createdAt); err != nil {
+			log.Warn().Err(err).Msg("worker: scan job asset for callback failed")
+			continue
+		}
+		urls = append(urls, w.publicAssetURL(storageKey))
+	}
+	if err := rows.Err(); err != nil {
+		log.Warn().Err(err).Msg("worker: iterate job assets for callback failed")
+	}
+	return urls
+}
+
+func (w *jobWorker) publicAssetURL(storageKey string) string {
+	storageKey = strings.TrimSpace(storageKey)
+	if isRemotePath(storageKey) {
+		return storageKey
+	}
+	// With signing enabled the API no longer mounts /static, so webhook links
+	// must be signed the same way App.assetURL signs API responses.
+	if w.urlSigner != nil {
+		signed, err := w.urlSigner.SignedURL(storageKey, w.signedTTL)
+		if err == nil {
+			return signed
+		}
+		w.logger.Warn().Err(err).Str("storage_key", storageKey).Msg("worker: sign asset url failed")
+	}
+	if strings.TrimSpace(w.assetBaseURL) == "" {
+		return storageKey
+	}
+	return strings.TrimRight(w.assetBaseURL, "/") + "/" + strings.TrimLeft(storageKey, "/")
+}
+
+func (w *jobWorker) trackJob(jobID string) {
+	w.mu.Lock()
+	if w.activeJobs == nil {
+		w.activeJobs = make(map[string]struct{})
+	}
+	w.activeJobs[jobID] = struct{}{}
+	w.mu.Unlock()
+}
+
+func (w *jobWorker) untrackJob(jobID string) {
+	w.mu.Lock()
+	delete(w.activeJobs, jobID)
+	w.mu.Unlock()
+}
+
+func (w *jobWorker) currentJobs() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	jobs := make([]string, 0, len(w.activeJobs))
+	for id := range w.activeJobs {
+		jobs = append(jobs, id)
+	}
+	sort.Strings(jobs)
+	return jobs
+}
+
+// persistContext returns the context used to store job output and record the
+// outcome. Once the worker context is cancelled, a fresh context bounded by the
+// shutdown grace period is used so in-flight jobs still keep their assets and
+// final status.
+func (w *jobWorker) persistContext() (context.Context, context.CancelFunc) {
+	if w.ctx.Err() == nil {
+		return context.WithCancel(w.ctx)
+	}
+	grace := w.shutdownGrace
+	if grace <= 0 {
+		grace = defaultShutdownGrace
+	}
+	return context.WithTimeout(context.Background(), grace)
+}
+
+func (w *jobWorker) attemptLimit() int {
+	if w.maxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+	return w.maxAttempts
+}
+
+// retryDelay returns the backoff before the next attempt, growing 2s, 8s, 32s
+// for the first, second and third failure respectively.
+func retryDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
 	}
-	if err := w.updateStatus(j.ID, status); err != nil {
-		w.logger.Error().Err(err).Str("job_id", j.ID).Msg("worker: update status failed")
+	delay := retryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= retryBackoffFactor
 	}
+	return delay
 }
 
 func (w *jobWorker) dispatch(j job) error {
@@ -253,7 +856,7 @@ func (w *jobWorker) dispatch(j job) error {
 func (w *jobWorker) claimJob() (job, error) {
 	row := w.runner.QueryRow(w.ctx, sqlinline.QWorkerClaimJob)
 	var j job
-	if err := row.Scan(&j.ID, &j.UserID, &j.TaskType, &j.Provider, &j.Quantity, &j.Aspect, &j.Prompt); err != nil {
+	if err := row.Scan(&j.ID, &j.UserID, &j.TaskType, &j.Provider, &j.Quantity, &j.Aspect, &j.Prompt, &j.Attempts, &j.Callback, &j.RequestID, &j.Plan, &j.BrandName); err != nil {
 		if infra.IsNoRows(err) {
 			return job{}, errNoJobAvailable
 		}
@@ -265,20 +868,38 @@ func (w *jobWorker) claimJob() (job, error) {
 }
 
 func (w *jobWorker) updateStatus(jobID, status string) error {
-	_, err := w.runner.Exec(w.ctx, sqlinline.QUpdateJobStatus, jobID, status)
+	ctx, cancel := w.persistContext()
+	defer cancel()
+	_, err := w.runner.Exec(ctx, sqlinline.QUpdateJobStatus, jobID, status)
+	return err
+}
+
+func (w *jobWorker) updateStatusWithError(jobID, status, message string) error {
+	ctx, cancel := w.persistContext()
+	defer cancel()
+	_, err := w.runner.Exec(ctx, sqlinline.QUpdateJobStatusWithError, jobID, status, message)
+	return err
+}
+
+func (w *jobWorker) requeue(jobID string, delay time.Duration) error {
+	ctx, cancel := w.persistContext()
+	defer cancel()
+	_, err := w.runner.Exec(ctx, sqlinline.QRequeueJob, jobID, int(delay/time.Second))
 	return err
 }
 
 func (w *jobWorker) processImageJob(j job) error {
+	log := w.jobLogger(j)
 	var prompt jsoncfg.PromptJSON
 	if err := json.Unmarshal(j.Prompt, &prompt); err != nil {
 		return fmt.Errorf("decode image prompt: %w", err)
 	}
+	applyBrandWatermark(&prompt.Watermark, j.BrandName)
 	generator, provider := w.selectImageProvider(j.Provider)
 	if generator == nil {
 		return fmt.Errorf("image provider %q not configured", provider)
 	}
-	sourceImage, err := w.resolveSourceImage(j.UserID, prompt.SourceAsset)
+	sourceImage, err := w.resolveSourceImage(j, prompt.SourceAsset)
 	if err != nil {
 		return fmt.Errorf("load source asset: %w", err)
 	}
@@ -290,7 +911,9 @@ func (w *jobWorker) processImageJob(j job) error {
 		RetouchStrength: prompt.Workflow.RetouchStrength,
 		Notes:           prompt.Workflow.Notes,
 	}
-	assets, err := generator.Generate(w.ctx, image.GenerateRequest{
+	genCtx, cancel := context.WithTimeout(w.ctx, durationOrDefault(w.imageTimeout, defaultImageGenTimeout))
+	defer cancel()
+	assets, err := generator.Generate(genCtx, image.GenerateRequest{
 		Prompt:         image.BuildMarketingPrompt(prompt),
 		Quantity:       j.Quantity,
 		AspectRatio:    j.Aspect,
@@ -299,29 +922,68 @@ func (w *jobWorker) processImageJob(j job) error {
 		Locale:         prompt.Extras.Locale,
 		WatermarkTag:   prompt.Watermark.Text,
 		Quality:        prompt.Extras.Quality,
-		NegativePrompt: image.DefaultNegativePrompt,
+		NegativePrompt: image.MergeNegativePrompt(image.NegativePromptFor(prompt.ProductType, w.negatives), prompt.Extras.NegativePrompt),
 		Workflow:       workflow,
 		SourceImage:    sourceImage,
+		Seed:           promptSeed(prompt),
+		Region:         prompt.Extras.Region,
 	})
 	if err != nil {
+		if errors.Is(genCtx.Err(), context.DeadlineExceeded) {
+			return errGenerationTimedOut
+		}
 		return fmt.Errorf("image generation: %w", err)
 	}
+	outputFormat := jsoncfg.NormalizeOutputFormat(prompt.OutputFormat)
 	for idx, asset := range assets {
-		storageKey, size := w.persistAsset(j.ID, provider, asset.Format, asset.StorageKey, asset.URL, asset.Data, idx)
+		if prompt.Watermark.Enabled && prompt.Watermark.Text != "" && len(asset.Data) > 0 {
+			data, format, markErr := applyWatermark(asset.Data, prompt.Watermark)
+			if markErr != nil {
+				log.Warn().Err(markErr).Msg("worker: watermark image asset failed; keeping original")
+			} else {
+				asset.Data, asset.Format = data, format
+				asset.StorageKey = replaceExtension(asset.StorageKey, format)
+			}
+		}
+		if outputFormat != "" {
+			data, format, convErr := transcodeImage(asset.Data, asset.Format, outputFormat)
+			if convErr != nil {
+				log.Warn().Err(convErr).
+					Str("output_format", outputFormat).
+					Msg("worker: transcode image asset failed; keeping provider format")
+			} else if format != asset.Format {
+				asset.Data, asset.Format = data, format
+				asset.StorageKey = replaceExtension(asset.StorageKey, format)
+			}
+		}
+		storageKey, size := w.persistAsset(j, provider, asset.Format, asset.StorageKey, asset.URL, asset.Data, idx)
 		if storageKey == "" {
-			w.logger.Error().Str("job_id", j.ID).Msg("worker: image asset missing storage key")
+			log.Error().Msg("worker: image asset missing storage key")
 			continue
 		}
 		metadata := map[string]any{"provider": provider}
+		if outputFormat != "" {
+			metadata["output_format"] = outputFormat
+		}
 		if asset.URL != "" && asset.URL != storageKey {
 			metadata["source_url"] = asset.URL
 		}
+		if j.RequestID != "" {
+			metadata["request_id"] = j.RequestID
+		}
+		if asset.Seed > 0 {
+			metadata["seed"] = asset.Seed
+		}
+		if asset.FallbackReason != "" {
+			metadata["fallback_reason"] = asset.FallbackReason
+		}
+		if thumbKey := w.persistThumbnail(j, storageKey, asset.Data); thumbKey != "" {
+			metadata["thumbnail_key"] = thumbKey
+		}
 		if len(asset.Data) == 0 && size == 0 {
 			size = 1024 * 1024
 		}
-		if _, execErr := w.runner.Exec(
-			w.ctx,
-			sqlinline.QInsertAsset,
+		if execErr := w.insertAsset(
 			j.UserID,
 			"GENERATED",
 			j.ID,
@@ -333,13 +995,14 @@ func (w *jobWorker) processImageJob(j job) error {
 			j.Aspect,
 			jsoncfg.MustMarshal(metadata),
 		); execErr != nil {
-			w.logger.Error().Err(execErr).Str("job_id", j.ID).Msg("worker: insert image asset failed")
+			log.Error().Err(execErr).Msg("worker: insert image asset failed")
 		}
 	}
 	return nil
 }
 
 func (w *jobWorker) processVideoJob(j job) error {
+	log := w.jobLogger(j)
 	payload := map[string]any{}
 	if len(j.Prompt) > 0 {
 		if err := json.Unmarshal(j.Prompt, &payload); err != nil {
@@ -354,16 +1017,34 @@ func (w *jobWorker) processVideoJob(j job) error {
 	if v, ok := payload["locale"].(string); ok {
 		locale = v
 	}
-	asset, err := generator.Generate(w.ctx, videoprovider.GenerateRequest{
-		Prompt:    extractPromptText(payload),
-		Provider:  provider,
-		RequestID: j.ID,
-		Locale:    locale,
+	var source struct {
+		SourceAsset jsoncfg.SourceAssetConfig `json:"source_asset"`
+	}
+	if len(j.Prompt) > 0 {
+		if err := json.Unmarshal(j.Prompt, &source); err != nil {
+			return fmt.Errorf("decode video source asset: %w", err)
+		}
+	}
+	sourceImage, err := w.resolveSourceImage(j, source.SourceAsset)
+	if err != nil {
+		return fmt.Errorf("load source asset: %w", err)
+	}
+	genCtx, cancel := context.WithTimeout(w.ctx, durationOrDefault(w.videoTimeout, defaultVideoGenTimeout))
+	defer cancel()
+	asset, err := generator.Generate(genCtx, videoprovider.GenerateRequest{
+		Prompt:      extractPromptText(payload),
+		Provider:    provider,
+		RequestID:   j.ID,
+		Locale:      locale,
+		SourceImage: videoSourceImage(sourceImage),
 	})
 	if err != nil {
+		if errors.Is(genCtx.Err(), context.DeadlineExceeded) {
+			return errGenerationTimedOut
+		}
 		return fmt.Errorf("video generation: %w", err)
 	}
-	storageKey, size := w.persistAsset(j.ID, provider, asset.Format, asset.StorageKey, asset.URL, asset.Data, 0)
+	storageKey, size := w.persistAsset(j, provider, asset.Format, asset.StorageKey, asset.URL, asset.Data, 0)
 	if storageKey == "" {
 		return fmt.Errorf("video asset missing storage key")
 	}
@@ -377,9 +1058,10 @@ func (w *jobWorker) processVideoJob(j job) error {
 	if asset.URL != "" && asset.URL != storageKey {
 		metadata["source_url"] = asset.URL
 	}
-	if _, execErr := w.runner.Exec(
-		w.ctx,
-		sqlinline.QInsertAsset,
+	if j.RequestID != "" {
+		metadata["request_id"] = j.RequestID
+	}
+	if execErr := w.insertAsset(
 		j.UserID,
 		"GENERATED",
 		j.ID,
@@ -391,11 +1073,27 @@ func (w *jobWorker) processVideoJob(j job) error {
 		j.Aspect,
 		jsoncfg.MustMarshal(metadata),
 	); execErr != nil {
-		w.logger.Error().Err(execErr).Str("job_id", j.ID).Msg("worker: insert video asset failed")
+		log.Error().Err(execErr).Msg("worker: insert video asset failed")
 	}
 	return nil
 }
 
+// insertAsset records a generated asset row. Like the final status update it
+// runs on the persist context, so output finished during shutdown is kept.
+func (w *jobWorker) insertAsset(args ...any) error {
+	ctx, cancel := w.persistContext()
+	defer cancel()
+	_, err := w.runner.Exec(ctx, sqlinline.QInsertAsset, args...)
+	return err
+}
+
+func durationOrDefault(value, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
 func (w *jobWorker) selectImageProvider(requested string) (image.Generator, string) {
 	if generator, ok := w.imageProviders[requested]; ok {
 		return generator, requested
@@ -436,7 +1134,8 @@ func extractPromptText(payload map[string]any) string {
 	return ""
 }
 
-func (w *jobWorker) persistAsset(jobID, provider, mime, storageKey, sourceURL string, data []byte, index int) (string, int64) {
+func (w *jobWorker) persistAsset(j job, provider, mime, storageKey, sourceURL string, data []byte, index int) (string, int64) {
+	log := w.jobLogger(j)
 	key := strings.TrimSpace(storageKey)
 	if key == "" {
 		key = strings.TrimSpace(sourceURL)
@@ -448,13 +1147,14 @@ func (w *jobWorker) persistAsset(jobID, provider, mime, storageKey, sourceURL st
 	if w.store != nil && len(data) > 0 {
 		targetKey := key
 		if targetKey == "" || strings.HasPrefix(targetKey, "http://") || strings.HasPrefix(targetKey, "https://") {
-			targetKey = defaultStorageKey(jobID, mime, index)
+			targetKey = defaultStorageKey(j.ID, mime, index)
 		}
 		targetKey = ensureExtension(targetKey, mime)
-		savedKey, err := w.store.Write(w.ctx, targetKey, data)
+		ctx, cancel := w.persistContext()
+		savedKey, err := w.store.Write(ctx, targetKey, data)
+		cancel()
 		if err != nil {
-			w.logger.Warn().Err(err).
-				Str("job_id", jobID).
+			log.Warn().Err(err).
 				Str("provider", provider).
 				Msg("worker: persist asset to storage failed")
 		} else {
@@ -464,6 +1164,30 @@ func (w *jobWorker) persistAsset(jobID, provider, mime, storageKey, sourceURL st
 	return key, size
 }
 
+// persistThumbnail stores a gallery preview next to a locally persisted image
+// under thumbnails/ and returns its key. Formats the decoder cannot read, such
+// as WebP, are skipped.
+func (w *jobWorker) persistThumbnail(j job, storageKey string, data []byte) string {
+	log := w.jobLogger(j)
+	if w.store == nil || len(data) == 0 || isRemotePath(storageKey) {
+		return ""
+	}
+	thumb, mime, _, _, err := thumbnail.Generate(data, thumbnail.DefaultMaxSide)
+	if err != nil {
+		log.Debug().Err(err).Msg("worker: thumbnail skipped")
+		return ""
+	}
+	key := replaceExtension(path.Join("thumbnails", storageKey), mime)
+	ctx, cancel := w.persistContext()
+	defer cancel()
+	savedKey, err := w.store.Write(ctx, key, thumb)
+	if err != nil {
+		log.Warn().Err(err).Msg("worker: persist thumbnail failed")
+		return ""
+	}
+	return savedKey
+}
+
 func defaultStorageKey(jobID, mime string, index int) string {
 	category := "images"
 	prefix := "image"
@@ -508,6 +1232,8 @@ func extensionForMIME(mime string) string {
 		return ".png"
 	case "image/jpeg", "image/jpg":
 		return ".jpg"
+	case "image/webp":
+		return ".webp"
 	case "video/mp4":
 		return ".mp4"
 	case "text/plain":
@@ -517,7 +1243,104 @@ func extensionForMIME(mime string) string {
 	}
 }
 
-func (w *jobWorker) resolveSourceImage(userID string, cfg jsoncfg.SourceAssetConfig) (*image.SourceImage, error) {
+// applyBrandWatermark fills in the user's brand name when a watermark is
+// enabled without text, and a bottom-right position when none was given, so
+// prompts that only set enabled still get a mark.
+func applyBrandWatermark(cfg *jsoncfg.WatermarkConfig, brandName string) {
+	if !cfg.Enabled {
+		return
+	}
+	if strings.TrimSpace(cfg.Text) == "" {
+		cfg.Text = strings.TrimSpace(brandName)
+	}
+	if strings.TrimSpace(cfg.Position) == "" {
+		cfg.Position = string(watermark.BottomRight)
+	}
+}
+
+// applyWatermark composites the configured watermark text onto the image.
+func applyWatermark(data []byte, cfg jsoncfg.WatermarkConfig) ([]byte, string, error) {
+	position, err := watermark.ParsePosition(cfg.Position)
+	if err != nil {
+		return nil, "", err
+	}
+	return watermark.Apply(data, watermark.Options{
+		Text:     cfg.Text,
+		Position: position,
+		Opacity:  cfg.Opacity,
+		OffsetX:  cfg.OffsetX,
+		OffsetY:  cfg.OffsetY,
+	})
+}
+
+// promptSeed returns the caller-supplied seed, or zero to let the provider
+// derive one.
+func promptSeed(prompt jsoncfg.PromptJSON) int {
+	if prompt.Seed == nil {
+		return 0
+	}
+	return *prompt.Seed
+}
+
+// replaceExtension swaps the extension of key to match mime. Keys without an
+// extension are left for persistAsset to complete.
+func replaceExtension(key, mime string) string {
+	ext := filepath.Ext(key)
+	want := extensionForMIME(mime)
+	if ext == "" || want == "" {
+		return key
+	}
+	return strings.TrimSuffix(key, ext) + want
+}
+
+// transcodeImage re-encodes data into the requested output format. Data that
+// already matches the format is returned unchanged.
+func transcodeImage(data []byte, mime, format string) ([]byte, string, error) {
+	target := mimeForOutputFormat(format)
+	if target == "" {
+		return nil, "", fmt.Errorf("unsupported output format %q", format)
+	}
+	current := strings.ToLower(strings.TrimSpace(mime))
+	if current == "image/jpg" {
+		current = "image/jpeg"
+	}
+	if current == target || len(data) == 0 {
+		return data, mime, nil
+	}
+	img, _, err := stdimage.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image: %w", err)
+	}
+	var buf bytes.Buffer
+	switch target {
+	case "image/png":
+		err = png.Encode(&buf, img)
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	case "image/webp":
+		err = webp.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("encode %s: %w", format, err)
+	}
+	return buf.Bytes(), target, nil
+}
+
+func mimeForOutputFormat(format string) string {
+	switch jsoncfg.NormalizeOutputFormat(format) {
+	case jsoncfg.OutputFormatPNG:
+		return "image/png"
+	case jsoncfg.OutputFormatJPEG:
+		return "image/jpeg"
+	case jsoncfg.OutputFormatWebP:
+		return "image/webp"
+	default:
+		return ""
+	}
+}
+
+func (w *jobWorker) resolveSourceImage(j job, cfg jsoncfg.SourceAssetConfig) (*image.SourceImage, error) {
+	log := w.jobLogger(j)
 	if cfg.IsZero() {
 		return nil, nil
 	}
@@ -545,7 +1368,7 @@ func (w *jobWorker) resolveSourceImage(userID string, cfg jsoncfg.SourceAssetCon
 		if err := row.Scan(&assetID, &ownerID, &storedKey, &storedMIME, &bytes, &storedW, &storedH, &aspect, &props); err != nil {
 			return nil, err
 		}
-		if ownerID != userID {
+		if ownerID != j.UserID {
 			return nil, fmt.Errorf("source asset %s does not belong to user", cfg.AssetID)
 		}
 		if storageKey == "" {
@@ -582,7 +1405,7 @@ func (w *jobWorker) resolveSourceImage(userID string, cfg jsoncfg.SourceAssetCon
 		if err == nil {
 			data = saved
 		} else {
-			w.logger.Warn().Err(err).Str("storage_key", storageKey).Msg("worker: failed to read source asset from storage")
+			log.Warn().Err(err).Str("storage_key", storageKey).Msg("worker: failed to read source asset from storage")
 		}
 	}
 	if filename == "" && storageKey != "" {
@@ -592,7 +1415,7 @@ func (w *jobWorker) resolveSourceImage(userID string, cfg jsoncfg.SourceAssetCon
 		sourceURL = strings.TrimSpace(cfg.URL)
 	}
 	if len(data) == 0 && sourceURL != "" {
-		if fetched, fetchedMIME := w.fetchSourceAsset(sourceURL); len(fetched) > 0 {
+		if fetched, fetchedMIME := w.fetchSourceAsset(j, sourceURL); len(fetched) > 0 {
 			data = fetched
 			if mime == "" {
 				mime = fetchedMIME
@@ -622,8 +1445,25 @@ func (w *jobWorker) resolveSourceImage(userID string, cfg jsoncfg.SourceAssetCon
 	}, nil
 }
 
-func (w *jobWorker) fetchSourceAsset(sourceURL string) ([]byte, string) {
-	if w.httpClient == nil {
+// videoSourceImage narrows a resolved source asset to what video generators
+// consume.
+func videoSourceImage(src *image.SourceImage) *videoprovider.SourceImage {
+	if src == nil || (len(src.Data) == 0 && src.URL == "") {
+		return nil
+	}
+	return &videoprovider.SourceImage{Data: src.Data, MIME: src.MIME, URL: src.URL}
+}
+
+// newSourceClient returns the client used to download source assets. The
+// request-time URL check cannot see later DNS answers or redirects, so every
+// connection and redirect hop is validated again here.
+func newSourceClient(trusted map[string]struct{}, resolver netguard.Resolver) *http.Client {
+	return netguard.NewClient(sourceAssetDownloadTimeout, maxSourceRedirects, trusted, resolver)
+}
+
+func (w *jobWorker) fetchSourceAsset(j job, sourceURL string) ([]byte, string) {
+	log := w.jobLogger(j)
+	if w.sourceClient == nil {
 		return nil, ""
 	}
 	trimmed := strings.TrimSpace(sourceURL)
@@ -638,33 +1478,47 @@ func (w *jobWorker) fetchSourceAsset(sourceURL string) ([]byte, string) {
 	defer cancel()
 	req, err := http.NewRequestWithContext(downloadCtx, http.MethodGet, trimmed, nil)
 	if err != nil {
-		w.logger.Warn().Err(err).Str("url", trimmed).Msg("worker: build source asset request failed")
+		log.Warn().Err(err).Str("url", trimmed).Msg("worker: build source asset request failed")
 		return nil, ""
 	}
-	resp, err := w.httpClient.Do(req)
+	resp, err := w.sourceClient.Do(req)
 	if err != nil {
-		w.logger.Warn().Err(err).Str("url", trimmed).Msg("worker: download source asset failed")
+		log.Warn().Err(err).Str("url", trimmed).Msg("worker: download source asset failed")
 		return nil, ""
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= http.StatusMultipleChoices {
-		w.logger.Warn().Int("status", resp.StatusCode).Str("url", trimmed).Msg("worker: source asset responded with non-success status")
+		log.Warn().Int("status", resp.StatusCode).Str("url", trimmed).Msg("worker: source asset responded with non-success status")
 		return nil, ""
 	}
-	limited := io.LimitReader(resp.Body, maxSourceImageBytes+1)
+	maxBytes := w.sourceLimitBytes(j.Plan)
+	limited := io.LimitReader(resp.Body, maxBytes+1)
 	data, err := io.ReadAll(limited)
 	if err != nil {
-		w.logger.Warn().Err(err).Str("url", trimmed).Msg("worker: read source asset failed")
+		log.Warn().Err(err).Str("url", trimmed).Msg("worker: read source asset failed")
 		return nil, ""
 	}
-	if int64(len(data)) > maxSourceImageBytes {
-		w.logger.Warn().Int64("bytes", int64(len(data))).Str("url", trimmed).Msg("worker: source asset exceeds max size, falling back to url")
+	if int64(len(data)) > maxBytes {
+		log.Warn().Int64("bytes", int64(len(data))).Str("url", trimmed).Msg("worker: source asset exceeds max size, falling back to url")
 		return nil, ""
 	}
 	mime := strings.TrimSpace(resp.Header.Get("Content-Type"))
 	return data, mime
 }
 
+// sourceLimitBytes resolves the source download cap for plan: the plan
+// override when one is configured, otherwise SOURCE_IMAGE_MAX_MB.
+func (w *jobWorker) sourceLimitBytes(plan string) int64 {
+	limit := defaultSourceImageBytes
+	if w.sourceMaxMB > 0 {
+		limit = int64(w.sourceMaxMB) << 20
+	}
+	if override := w.planSourceMB[plan]; override > 0 {
+		limit = int64(override) << 20
+	}
+	return limit
+}
+
 func jsonPropertyString(raw []byte, key string) (string, bool) {
 	if len(raw) == 0 {
 		return "", false
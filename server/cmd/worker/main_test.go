@@ -0,0 +1,1429 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdimage "image"
+	"image/color"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+
+	"server/internal/domain/jsoncfg"
+	"server/internal/metrics"
+	"server/internal/providers/image"
+	videoprovider "server/internal/providers/video"
+	"server/internal/sqlinline"
+	"server/internal/storage"
+	"server/internal/webhook"
+)
+
+type fakeJob struct {
+	job
+	Status   string
+	Error    string
+	Delays   []int
+	Statuses []string
+}
+
+// fakeRunner emulates the generation_requests queue in memory so worker
+// behaviour can be exercised without a database.
+type fakeRunner struct {
+	mu         sync.Mutex
+	jobs       []*fakeJob
+	heartbeats int
+	assets     map[string][]string
+	deleted    []fakeDeletedAsset
+	retained   []fakeRetainedAsset
+	purged     []string
+	inserted   []json.RawMessage
+	uploads    map[string]fakeUpload
+}
+
+// fakeUpload is a user asset returned by QSelectAssetByID.
+type fakeUpload struct {
+	ownerID    string
+	storageKey string
+	mime       string
+}
+
+type fakeDeletedAsset struct {
+	id         string
+	storageKey string
+	deletedAt  time.Time
+}
+
+// fakeRetainedAsset is a live asset considered by the plan retention sweep.
+type fakeRetainedAsset struct {
+	id         string
+	storageKey string
+	plan       string
+	createdAt  time.Time
+	activeJob  bool
+}
+
+func (f *fakeRunner) add(j job) *fakeJob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fj := &fakeJob{job: j, Status: "QUEUED"}
+	f.jobs = append(f.jobs, fj)
+	return fj
+}
+
+func (f *fakeRunner) find(id string) *fakeJob {
+	for _, fj := range f.jobs {
+		if fj.ID == id {
+			return fj
+		}
+	}
+	return nil
+}
+
+func (f *fakeRunner) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	switch query {
+	case sqlinline.QUpdateJobStatus:
+		fj := f.find(args[0].(string))
+		if fj == nil {
+			return pgconn.CommandTag{}, errors.New("job not found")
+		}
+		fj.Status = args[1].(string)
+		fj.Statuses = append(fj.Statuses, fj.Status)
+	case sqlinline.QUpdateJobStatusWithError:
+		fj := f.find(args[0].(string))
+		if fj == nil {
+			return pgconn.CommandTag{}, errors.New("job not found")
+		}
+		fj.Status = args[1].(string)
+		fj.Error = args[2].(string)
+		fj.Statuses = append(fj.Statuses, fj.Status)
+	case sqlinline.QWorkerHeartbeat:
+		f.heartbeats++
+	case sqlinline.QInsertAsset:
+		f.inserted = append(f.inserted, args[9].(json.RawMessage))
+	case sqlinline.QDeleteAsset:
+		for i, asset := range f.deleted {
+			if asset.id == args[0].(string) {
+				f.deleted = append(f.deleted[:i], f.deleted[i+1:]...)
+				f.purged = append(f.purged, asset.id)
+				break
+			}
+		}
+		for i, asset := range f.retained {
+			if asset.id == args[0].(string) {
+				f.retained = append(f.retained[:i], f.retained[i+1:]...)
+				f.purged = append(f.purged, asset.id)
+				break
+			}
+		}
+	case sqlinline.QRequeueJob:
+		fj := f.find(args[0].(string))
+		if fj == nil {
+			return pgconn.CommandTag{}, errors.New("job not found")
+		}
+		fj.Status = "QUEUED"
+		fj.Statuses = append(fj.Statuses, fj.Status)
+		fj.Delays = append(fj.Delays, args[1].(int))
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (f *fakeRunner) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if query == sqlinline.QSelectAssetByID {
+		upload, ok := f.uploads[args[0].(string)]
+		if !ok {
+			return fakeRow{err: pgx.ErrNoRows}
+		}
+		return fakeRow{values: []any{args[0].(string), upload.ownerID, upload.storageKey, upload.mime, int64(0), 0, 0, "", []byte(nil)}}
+	}
+	if query != sqlinline.QWorkerClaimJob {
+		return fakeRow{err: errors.New("unexpected query")}
+	}
+	for _, fj := range f.jobs {
+		if fj.Status != "QUEUED" {
+			continue
+		}
+		fj.Status = "RUNNING"
+		fj.Attempts++
+		return fakeRow{values: []any{fj.ID, fj.UserID, fj.TaskType, fj.Provider, fj.Quantity, fj.Aspect, []byte(fj.Prompt), fj.Attempts, fj.Callback, fj.RequestID, fj.Plan, fj.BrandName}}
+	}
+	return fakeRow{err: pgx.ErrNoRows}
+}
+
+func (f *fakeRunner) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch query {
+	case sqlinline.QSelectJobAssets:
+		return &fakeAssetRows{keys: f.assets[args[0].(string)]}, nil
+	case sqlinline.QSelectPurgeableAssets:
+		cutoff := time.Now().Add(-time.Duration(args[0].(int)) * time.Second)
+		rows := &fakeAssetRows{}
+		for _, asset := range f.deleted {
+			if asset.deletedAt.Before(cutoff) && len(rows.keys) < args[1].(int) {
+				rows.ids = append(rows.ids, asset.id)
+				rows.keys = append(rows.keys, asset.storageKey)
+			}
+		}
+		return rows, nil
+	case sqlinline.QSelectExpiredPlanAssets:
+		cutoff := time.Now().Add(-time.Duration(args[1].(int)) * time.Second)
+		rows := &fakeAssetRows{}
+		for _, asset := range f.retained {
+			if asset.plan == args[0].(string) && asset.createdAt.Before(cutoff) && !asset.activeJob && len(rows.keys) < args[2].(int) {
+				rows.ids = append(rows.ids, asset.id)
+				rows.keys = append(rows.keys, asset.storageKey)
+			}
+		}
+		return rows, nil
+	}
+	return nil, errors.New("not implemented")
+}
+
+type fakeAssetRows struct {
+	ids     []string
+	keys    []string
+	idx     int
+	scanErr map[int]error
+	err     error
+}
+
+func (r *fakeAssetRows) Close()                                       {}
+func (r *fakeAssetRows) Err() error                                   { return r.err }
+func (r *fakeAssetRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeAssetRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeAssetRows) Values() ([]any, error)                       { return nil, errors.New("not supported") }
+func (r *fakeAssetRows) RawValues() [][]byte                          { return nil }
+func (r *fakeAssetRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeAssetRows) Next() bool {
+	if r.idx >= len(r.keys) {
+		return false
+	}
+	r.idx++
+	return true
+}
+
+func (r *fakeAssetRows) Scan(dest ...any) error {
+	if err := r.scanErr[r.idx-1]; err != nil {
+		return err
+	}
+	if len(r.ids) > 0 {
+		*dest[0].(*string) = r.ids[r.idx-1]
+	}
+	*dest[1].(*string) = r.keys[r.idx-1]
+	return nil
+}
+
+type fakeRow struct {
+	values []any
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(dest) != len(r.values) {
+		return errors.New("scan arity mismatch")
+	}
+	for i, d := range dest {
+		switch ptr := d.(type) {
+		case *string:
+			*ptr = r.values[i].(string)
+		case *int:
+			*ptr = r.values[i].(int)
+		case *int64:
+			*ptr = r.values[i].(int64)
+		case *[]byte:
+			*ptr = r.values[i].([]byte)
+		case *json.RawMessage:
+			*ptr = json.RawMessage(r.values[i].([]byte))
+		default:
+			return errors.New("unsupported scan destination")
+		}
+	}
+	return nil
+}
+
+type flakyVideoGenerator struct {
+	failures int
+	calls    int
+}
+
+func (g *flakyVideoGenerator) Generate(ctx context.Context, req videoprovider.GenerateRequest) (*videoprovider.Asset, error) {
+	g.calls++
+	if g.calls <= g.failures {
+		return nil, errors.New("provider unavailable")
+	}
+	return &videoprovider.Asset{StorageKey: "https://cdn.example.com/video.mp4", Format: "video/mp4"}, nil
+}
+
+func newTestWorker(runner *fakeRunner, generator videoprovider.Generator) *jobWorker {
+	return &jobWorker{
+		ctx:            context.Background(),
+		runner:         runner,
+		logger:         zerolog.Nop(),
+		maxAttempts:    3,
+		videoProviders: map[string]videoprovider.Generator{defaultVideoProvider: generator},
+	}
+}
+
+func testVideoJob(id string) job {
+	return job{
+		ID:       id,
+		UserID:   "user-1",
+		TaskType: taskTypeVideo,
+		Provider: defaultVideoProvider,
+		Quantity: 1,
+		Aspect:   "16:9",
+		Prompt:   json.RawMessage(`{"prompt":"promo video"}`),
+	}
+}
+
+func runQueue(t *testing.T, w *jobWorker, rounds int) {
+	t.Helper()
+	for i := 0; i < rounds; i++ {
+		j, err := w.claimJob()
+		if errors.Is(err, errNoJobAvailable) {
+			return
+		}
+		if err != nil {
+			t.Fatalf("claimJob error: %v", err)
+		}
+		w.handleJob(j)
+	}
+}
+
+func TestHandleJobRetriesUntilSuccess(t *testing.T) {
+	runner := &fakeRunner{}
+	fj := runner.add(testVideoJob("job-1"))
+	w := newTestWorker(runner, &flakyVideoGenerator{failures: 2})
+
+	runQueue(t, w, 10)
+
+	if fj.Status != statusSucceeded {
+		t.Fatalf("expected status %s, got %s (history %v)", statusSucceeded, fj.Status, fj.Statuses)
+	}
+	if fj.Attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", fj.Attempts)
+	}
+	want := []int{2, 8}
+	if len(fj.Delays) != len(want) {
+		t.Fatalf("expected delays %v, got %v", want, fj.Delays)
+	}
+	for i := range want {
+		if fj.Delays[i] != want[i] {
+			t.Fatalf("expected delays %v, got %v", want, fj.Delays)
+		}
+	}
+}
+
+func TestHandleJobFailsAfterMaxAttempts(t *testing.T) {
+	runner := &fakeRunner{}
+	fj := runner.add(testVideoJob("job-1"))
+	w := newTestWorker(runner, &flakyVideoGenerator{failures: 10})
+
+	runQueue(t, w, 10)
+
+	if fj.Status != statusFailed {
+		t.Fatalf("expected status %s, got %s", statusFailed, fj.Status)
+	}
+	if fj.Attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", fj.Attempts)
+	}
+	if want := "video generation: provider unavailable"; fj.Error != want {
+		t.Fatalf("expected error %q, got %q", want, fj.Error)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := map[int]time.Duration{
+		0: 2 * time.Second,
+		1: 2 * time.Second,
+		2: 8 * time.Second,
+		3: 32 * time.Second,
+	}
+	for attempt, want := range cases {
+		if got := retryDelay(attempt); got != want {
+			t.Fatalf("retryDelay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+type cancelingVideoGenerator struct {
+	cancel context.CancelFunc
+}
+
+func (g *cancelingVideoGenerator) Generate(ctx context.Context, req videoprovider.GenerateRequest) (*videoprovider.Asset, error) {
+	g.cancel()
+	return &videoprovider.Asset{StorageKey: "https://cdn.example.com/video.mp4", Format: "video/mp4", Data: []byte("video")}, nil
+}
+
+// ctxStore refuses writes on a cancelled context, as remote stores do.
+type ctxStore struct {
+	*storage.FileStore
+}
+
+func (s ctxStore) Write(ctx context.Context, key string, data []byte) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return s.FileStore.Write(ctx, key, data)
+}
+
+func TestHandleJobPersistsStatusAfterShutdown(t *testing.T) {
+	runner := &fakeRunner{}
+	fj := runner.add(testVideoJob("job-1"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	w := newTestWorker(runner, &cancelingVideoGenerator{cancel: cancel})
+	w.ctx = ctx
+	w.shutdownGrace = time.Second
+	w.store = ctxStore{store}
+
+	j, err := w.claimJob()
+	if err != nil {
+		t.Fatalf("claimJob error: %v", err)
+	}
+	w.handleJob(j)
+
+	if ctx.Err() == nil {
+		t.Fatal("expected worker context to be cancelled")
+	}
+	if fj.Status != statusSucceeded {
+		t.Fatalf("expected status %s after shutdown, got %s", statusSucceeded, fj.Status)
+	}
+	if len(runner.inserted) != 1 {
+		t.Fatalf("asset inserts = %d, want 1 after shutdown", len(runner.inserted))
+	}
+	if _, err := store.Read(context.Background(), defaultStorageKey("job-1", "video/mp4", 0)); err != nil {
+		t.Fatalf("video not stored after shutdown: %v", err)
+	}
+	if active := w.currentJobs(); len(active) != 0 {
+		t.Fatalf("expected no active jobs, got %v", active)
+	}
+	if err := w.Run(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Run to return context.Canceled, got %v", err)
+	}
+}
+
+type countingVideoGenerator struct {
+	calls atomic.Int32
+}
+
+func (g *countingVideoGenerator) Generate(ctx context.Context, req videoprovider.GenerateRequest) (*videoprovider.Asset, error) {
+	g.calls.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	return &videoprovider.Asset{StorageKey: "https://cdn.example.com/" + req.RequestID + ".mp4", Format: "video/mp4"}, nil
+}
+
+func (f *fakeRunner) terminal() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, fj := range f.jobs {
+		if fj.Status != statusSucceeded && fj.Status != statusFailed {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRunProcessesJobsConcurrently(t *testing.T) {
+	runner := &fakeRunner{}
+	for i := 0; i < 5; i++ {
+		runner.add(testVideoJob(fmt.Sprintf("job-%d", i+1)))
+	}
+	generator := &countingVideoGenerator{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := newTestWorker(runner, generator)
+	w.ctx = ctx
+	w.concurrency = 3
+
+	done := make(chan error, 1)
+	go func() { done <- w.Run() }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !runner.terminal() {
+		if time.Now().After(deadline) {
+			t.Fatal("jobs did not reach a terminal state in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after cancellation")
+	}
+	for _, fj := range runner.jobs {
+		if fj.Status != statusSucceeded {
+			t.Fatalf("job %s ended in %s", fj.ID, fj.Status)
+		}
+	}
+	if got := generator.calls.Load(); got != 5 {
+		t.Fatalf("expected 5 generator calls, got %d", got)
+	}
+	if runner.heartbeats != 1 {
+		t.Fatalf("expected a single heartbeat, got %d", runner.heartbeats)
+	}
+}
+
+func TestPollBackoffDoublesUntilCeiling(t *testing.T) {
+	b := newPollBackoff(2*time.Second, 30*time.Second)
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, expected := range want {
+		if got := b.next(); got != expected {
+			t.Fatalf("empty cycle %d: delay = %s, want %s", i+1, got, expected)
+		}
+	}
+}
+
+func TestPollBackoffResetsAfterClaim(t *testing.T) {
+	b := newPollBackoff(2*time.Second, 30*time.Second)
+	b.next()
+	b.next()
+	b.next()
+	b.reset()
+	if got := b.next(); got != 2*time.Second {
+		t.Fatalf("delay after reset = %s, want 2s", got)
+	}
+	if got := b.next(); got != 4*time.Second {
+		t.Fatalf("second delay after reset = %s, want 4s", got)
+	}
+}
+
+func TestPollBackoffClampsInvalidBounds(t *testing.T) {
+	b := newPollBackoff(0, time.Second)
+	if got := b.next(); got != jobPollInterval {
+		t.Fatalf("delay = %s, want floor %s", got, jobPollInterval)
+	}
+	if got := b.next(); got != jobPollInterval {
+		t.Fatalf("ceiling below floor should pin delay to floor, got %s", got)
+	}
+}
+
+func TestIdleTrackerResetAfterClaim(t *testing.T) {
+	tracker := newIdleTracker(1, newPollBackoff(time.Millisecond, 8*time.Millisecond))
+	ctx := context.Background()
+	tracker.wait(ctx)
+	tracker.wait(ctx)
+	if tracker.backoff.current != 4*time.Millisecond {
+		t.Fatalf("backoff after two empty polls = %s, want 4ms", tracker.backoff.current)
+	}
+	tracker.reset()
+	if tracker.backoff.current != time.Millisecond {
+		t.Fatalf("backoff after claim = %s, want 1ms", tracker.backoff.current)
+	}
+}
+
+func TestIdleTrackerRepollsWhileAnotherWorkerIsBusy(t *testing.T) {
+	// Two workers: one is busy with a long job, the other found no job. It
+	// must re-poll after the backoff delay instead of waiting for the release.
+	tracker := newIdleTracker(2, newPollBackoff(time.Millisecond, 8*time.Millisecond))
+	done := make(chan struct{})
+	go func() {
+		tracker.wait(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("idle worker stayed parked while the other worker was busy")
+	}
+	tracker.mu.Lock()
+	idle := tracker.idle
+	tracker.mu.Unlock()
+	if idle != 0 {
+		t.Fatalf("idle count after timed-out wait = %d, want 0", idle)
+	}
+}
+
+type blockingVideoGenerator struct{}
+
+func (blockingVideoGenerator) Generate(ctx context.Context, req videoprovider.GenerateRequest) (*videoprovider.Asset, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type blockingImageGenerator struct{}
+
+func (blockingImageGenerator) Generate(ctx context.Context, req image.GenerateRequest) ([]image.Asset, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestVideoJobTimesOut(t *testing.T) {
+	runner := &fakeRunner{}
+	fj := runner.add(testVideoJob("job-1"))
+	w := newTestWorker(runner, blockingVideoGenerator{})
+	w.maxAttempts = 1
+	w.videoTimeout = 20 * time.Millisecond
+
+	runQueue(t, w, 1)
+
+	if fj.Status != statusFailed {
+		t.Fatalf("expected status %s, got %s", statusFailed, fj.Status)
+	}
+	if fj.Error != errGenerationTimedOut.Error() {
+		t.Fatalf("expected error %q, got %q", errGenerationTimedOut, fj.Error)
+	}
+}
+
+func TestImageJobTimesOut(t *testing.T) {
+	runner := &fakeRunner{}
+	fj := runner.add(job{
+		ID:       "job-1",
+		UserID:   "user-1",
+		TaskType: taskTypeImage,
+		Provider: defaultImageProvider,
+		Quantity: 1,
+		Aspect:   "1:1",
+		Prompt:   json.RawMessage(`{"version":"1","title":"Sample"}`),
+	})
+	w := newTestWorker(runner, nil)
+	w.imageProviders = map[string]image.Generator{defaultImageProvider: blockingImageGenerator{}}
+	w.maxAttempts = 1
+	w.imageTimeout = 20 * time.Millisecond
+
+	start := time.Now()
+	runQueue(t, w, 1)
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("deadline did not fire, took %s", elapsed)
+	}
+	if fj.Status != statusFailed || fj.Error != errGenerationTimedOut.Error() {
+		t.Fatalf("expected timed out failure, got status=%s error=%q", fj.Status, fj.Error)
+	}
+}
+
+func TestHandleJobPostsCallback(t *testing.T) {
+	received := make(chan webhook.Payload, 4)
+	calls := atomic.Int32{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhook.Payload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("decode callback: %v", err)
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		received <- p
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name       string
+		generator  videoprovider.Generator
+		wantStatus string
+		wantError  string
+		wantAssets []string
+	}{
+		{
+			name:       "success",
+			generator:  &flakyVideoGenerator{},
+			wantStatus: statusSucceeded,
+			wantAssets: []string{"https://cdn.example.com/static/generated/videos/job-1/video.mp4"},
+		},
+		{
+			name:       "failure",
+			generator:  &flakyVideoGenerator{failures: 1},
+			wantStatus: statusFailed,
+			wantError:  "video generation: provider unavailable",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls.Store(0)
+			runner := &fakeRunner{assets: map[string][]string{"job-1": {"generated/videos/job-1/video.mp4"}}}
+			j := testVideoJob("job-1")
+			j.Callback = srv.URL
+			if tc.wantStatus == statusFailed {
+				runner.assets = nil
+			}
+			runner.add(j)
+			w := newTestWorker(runner, tc.generator)
+			w.maxAttempts = 1
+			w.assetBaseURL = "https://cdn.example.com/static/"
+			w.notifier = &webhook.Notifier{Client: srv.Client(), MaxAttempts: 3}
+
+			runQueue(t, w, 1)
+
+			select {
+			case p := <-received:
+				if p.JobID != "job-1" || p.Status != tc.wantStatus || p.Error != tc.wantError {
+					t.Fatalf("unexpected payload: %+v", p)
+				}
+				if fmt.Sprint(p.AssetURLs) != fmt.Sprint(tc.wantAssets) && !(len(p.AssetURLs) == 0 && len(tc.wantAssets) == 0) {
+					t.Fatalf("asset urls = %v, want %v", p.AssetURLs, tc.wantAssets)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("callback not received")
+			}
+			if got := calls.Load(); got != 2 {
+				t.Fatalf("expected retry after non-2xx, got %d calls", got)
+			}
+		})
+	}
+}
+
+func TestJobAssetURLsLogsRowErrors(t *testing.T) {
+	rows := &fakeAssetRows{
+		keys:    []string{"generated/a.png", "generated/b.png"},
+		scanErr: map[int]error{0: errors.New("cannot scan NULL into *string")},
+		err:     errors.New("conn reset"),
+	}
+	var logs bytes.Buffer
+	w := &jobWorker{
+		runner:       assetRowsRunner{fakeRunner: &fakeRunner{}, rows: rows},
+		logger:       zerolog.New(&logs),
+		assetBaseURL: "https://cdn.example.com/static",
+	}
+
+	urls := w.jobAssetURLs(context.Background(), testVideoJob("job-1"))
+
+	if fmt.Sprint(urls) != "[https://cdn.example.com/static/generated/b.png]" {
+		t.Fatalf("urls = %v", urls)
+	}
+	for _, want := range []string{"scan job asset for callback failed", "iterate job assets for callback failed", `"job_id":"job-1"`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Fatalf("logs missing %q: %s", want, logs.String())
+		}
+	}
+}
+
+// assetRowsRunner serves fixed rows for QSelectJobAssets.
+type assetRowsRunner struct {
+	*fakeRunner
+	rows *fakeAssetRows
+}
+
+func (r assetRowsRunner) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	if query == sqlinline.QSelectJobAssets {
+		return r.rows, nil
+	}
+	return r.fakeRunner.Query(ctx, query, args...)
+}
+
+func TestPublicAssetURLSignsWhenConfigured(t *testing.T) {
+	w := &jobWorker{logger: zerolog.Nop(), assetBaseURL: "https://cdn.example.com/static"}
+	if got := w.publicAssetURL("generated/a.png"); got != "https://cdn.example.com/static/generated/a.png" {
+		t.Fatalf("unsigned url = %q", got)
+	}
+	w.urlSigner = storage.NewURLSigner("https://api.example.com/v1/files", "secret")
+	w.signedTTL = time.Hour
+	got := w.publicAssetURL("generated/a.png")
+	parsed, err := url.Parse(got)
+	if err != nil {
+		t.Fatalf("parse signed url %q: %v", got, err)
+	}
+	if parsed.Host != "api.example.com" || parsed.Path != "/v1/files/generated/a.png" {
+		t.Fatalf("signed url = %q, want link under /v1/files", got)
+	}
+	query := parsed.Query()
+	if err := w.urlSigner.Verify("generated/a.png", query.Get("expires"), query.Get("sig")); err != nil {
+		t.Fatalf("Verify() signed webhook url: %v", err)
+	}
+	if got := w.publicAssetURL("https://cdn.example.com/remote.png"); got != "https://cdn.example.com/remote.png" {
+		t.Fatalf("remote url = %q, want unchanged", got)
+	}
+}
+
+func TestTranscodeImagePNGToWebP(t *testing.T) {
+	src := stdimage.NewNRGBA(stdimage.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 60), G: uint8(y * 80), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	data, mime, err := transcodeImage(buf.Bytes(), "image/png", "webp")
+	if err != nil {
+		t.Fatalf("transcode: %v", err)
+	}
+	if mime != "image/webp" {
+		t.Fatalf("mime = %q, want image/webp", mime)
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		t.Fatalf("output is not a webp container")
+	}
+	if ext := extensionForMIME(mime); ext != ".webp" {
+		t.Fatalf("extension = %q, want .webp", ext)
+	}
+	if key := defaultStorageKey("job-1", mime, 0); !strings.HasSuffix(key, ".webp") {
+		t.Fatalf("storage key = %q, want .webp suffix", key)
+	}
+
+	same, mime, err := transcodeImage(buf.Bytes(), "image/png", "png")
+	if err != nil || mime != "image/png" || !bytes.Equal(same, buf.Bytes()) {
+		t.Fatalf("matching format should be returned unchanged")
+	}
+}
+
+type recordingImageGenerator struct {
+	mu       sync.Mutex
+	requests []image.GenerateRequest
+}
+
+func (g *recordingImageGenerator) Generate(ctx context.Context, req image.GenerateRequest) ([]image.Asset, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.requests = append(g.requests, req)
+	return []image.Asset{{URL: "https://cdn.example.com/out.png", Format: "image/png"}}, nil
+}
+
+type recordingVideoGenerator struct {
+	mu       sync.Mutex
+	requests []videoprovider.GenerateRequest
+}
+
+func (g *recordingVideoGenerator) Generate(ctx context.Context, req videoprovider.GenerateRequest) (*videoprovider.Asset, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.requests = append(g.requests, req)
+	return &videoprovider.Asset{StorageKey: "https://cdn.example.com/video.mp4", Format: "video/mp4"}, nil
+}
+
+func TestVideoJobPassesSourceImage(t *testing.T) {
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	photo := []byte("\x89PNG product photo")
+	if _, err := store.Write(context.Background(), "uploads/user-1/photo.png", photo); err != nil {
+		t.Fatalf("save source: %v", err)
+	}
+	runner := &fakeRunner{uploads: map[string]fakeUpload{
+		"asset-1": {ownerID: "user-1", storageKey: "uploads/user-1/photo.png", mime: "image/png"},
+		"asset-2": {ownerID: "user-2", storageKey: "uploads/user-2/photo.png", mime: "image/png"},
+	}}
+	owned := testVideoJob("job-1")
+	owned.Prompt = json.RawMessage(`{"prompt":"animate the product","source_asset":{"asset_id":"asset-1"}}`)
+	foreign := testVideoJob("job-2")
+	foreign.Prompt = json.RawMessage(`{"prompt":"animate the product","source_asset":{"asset_id":"asset-2"}}`)
+	ownedJob := runner.add(owned)
+	foreignJob := runner.add(foreign)
+	gen := &recordingVideoGenerator{}
+	w := newTestWorker(runner, gen)
+	w.store = store
+	w.maxAttempts = 1
+
+	runQueue(t, w, 2)
+
+	if ownedJob.Status != statusSucceeded {
+		t.Fatalf("expected status %s, got %s (%s)", statusSucceeded, ownedJob.Status, ownedJob.Error)
+	}
+	if len(gen.requests) != 1 {
+		t.Fatalf("expected 1 generate call, got %d", len(gen.requests))
+	}
+	src := gen.requests[0].SourceImage
+	if src == nil || !bytes.Equal(src.Data, photo) || src.MIME != "image/png" {
+		t.Fatalf("source image = %+v, want stored photo bytes", src)
+	}
+	if foreignJob.Status != statusFailed || !strings.Contains(foreignJob.Error, "does not belong to user") {
+		t.Fatalf("foreign source: status=%s error=%q", foreignJob.Status, foreignJob.Error)
+	}
+}
+
+func TestSourceLimitBytesFollowsPlan(t *testing.T) {
+	w := &jobWorker{}
+	if got := w.sourceLimitBytes("free"); got != defaultSourceImageBytes {
+		t.Fatalf("unconfigured limit = %d, want %d", got, defaultSourceImageBytes)
+	}
+	w.sourceMaxMB = 20
+	w.planSourceMB = map[string]int{"free": 5, "pro": 40}
+	cases := map[string]int64{"free": 5 << 20, "pro": 40 << 20, "team": 20 << 20}
+	for plan, want := range cases {
+		if got := w.sourceLimitBytes(plan); got != want {
+			t.Fatalf("sourceLimitBytes(%q) = %d, want %d", plan, got, want)
+		}
+	}
+}
+
+func TestImageJobMergesNegativePrompt(t *testing.T) {
+	runner := &fakeRunner{}
+	fj := runner.add(job{
+		ID:       "job-1",
+		UserID:   "user-1",
+		TaskType: taskTypeImage,
+		Provider: defaultImageProvider,
+		Quantity: 1,
+		Aspect:   "1:1",
+		Prompt:   json.RawMessage(`{"title":"Sample","extras":{"negative_prompt":"Blurry, cartoon,  busy background"}}`),
+	})
+	gen := &recordingImageGenerator{}
+	w := newTestWorker(runner, nil)
+	w.imageProviders = map[string]image.Generator{defaultImageProvider: gen}
+
+	runQueue(t, w, 1)
+
+	if fj.Status != statusSucceeded {
+		t.Fatalf("expected status %s, got %s (%s)", statusSucceeded, fj.Status, fj.Error)
+	}
+	if len(gen.requests) != 1 {
+		t.Fatalf("expected 1 generate call, got %d", len(gen.requests))
+	}
+	want := image.DefaultNegativePrompt + ", cartoon, busy background"
+	if got := gen.requests[0].NegativePrompt; got != want {
+		t.Fatalf("negative prompt = %q, want %q", got, want)
+	}
+}
+
+func TestImageJobPicksCategoryNegativePrompt(t *testing.T) {
+	negatives := map[string][]string{"food": {"burnt", "unappetizing"}}
+	for _, tc := range []struct {
+		name    string
+		product string
+		want    string
+	}{
+		{name: "known category", product: "Food", want: "burnt, unappetizing, cartoon"},
+		{name: "unknown category", product: "electronics", want: image.DefaultNegativePrompt + ", cartoon"},
+		{name: "no category", want: image.DefaultNegativePrompt + ", cartoon"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &fakeRunner{}
+			prompt, err := json.Marshal(map[string]any{
+				"title":        "Sample",
+				"product_type": tc.product,
+				"extras":       map[string]any{"negative_prompt": "cartoon"},
+			})
+			if err != nil {
+				t.Fatalf("marshal prompt: %v", err)
+			}
+			fj := runner.add(job{
+				ID:       "job-1",
+				UserID:   "user-1",
+				TaskType: taskTypeImage,
+				Provider: defaultImageProvider,
+				Quantity: 1,
+				Aspect:   "1:1",
+				Prompt:   prompt,
+			})
+			gen := &recordingImageGenerator{}
+			w := newTestWorker(runner, nil)
+			w.imageProviders = map[string]image.Generator{defaultImageProvider: gen}
+			w.negatives = negatives
+
+			runQueue(t, w, 1)
+
+			if fj.Status != statusSucceeded {
+				t.Fatalf("expected status %s, got %s (%s)", statusSucceeded, fj.Status, fj.Error)
+			}
+			if got := gen.requests[0].NegativePrompt; got != tc.want {
+				t.Fatalf("negative prompt = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestImageJobDefaultsWatermarkToBrandName(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		watermark map[string]any
+		brand     string
+		want      string
+	}{
+		{name: "empty text uses brand", watermark: map[string]any{"enabled": true}, brand: "Toko Sari", want: "Toko Sari"},
+		{name: "explicit text wins", watermark: map[string]any{"enabled": true, "text": "Promo"}, brand: "Toko Sari", want: "Promo"},
+		{name: "disabled ignores brand", watermark: map[string]any{"enabled": false}, brand: "Toko Sari", want: ""},
+		{name: "no brand", watermark: map[string]any{"enabled": true}, want: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &fakeRunner{}
+			prompt, err := json.Marshal(map[string]any{"title": "Sample", "watermark": tc.watermark})
+			if err != nil {
+				t.Fatalf("marshal prompt: %v", err)
+			}
+			fj := runner.add(job{
+				ID:        "job-1",
+				UserID:    "user-1",
+				TaskType:  taskTypeImage,
+				Provider:  defaultImageProvider,
+				Quantity:  1,
+				Aspect:    "1:1",
+				Prompt:    prompt,
+				BrandName: tc.brand,
+			})
+			gen := &recordingImageGenerator{}
+			w := newTestWorker(runner, nil)
+			w.imageProviders = map[string]image.Generator{defaultImageProvider: gen}
+
+			runQueue(t, w, 1)
+
+			if fj.Status != statusSucceeded {
+				t.Fatalf("expected status %s, got %s (%s)", statusSucceeded, fj.Status, fj.Error)
+			}
+			if got := gen.requests[0].WatermarkTag; got != tc.want {
+				t.Fatalf("watermark tag = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyBrandWatermarkDefaultsPosition(t *testing.T) {
+	cfg := jsoncfg.WatermarkConfig{Enabled: true}
+	applyBrandWatermark(&cfg, "  Toko Sari ")
+	if cfg.Text != "Toko Sari" || cfg.Position != "bottom-right" {
+		t.Fatalf("watermark = %+v, want brand text at bottom-right", cfg)
+	}
+	cfg = jsoncfg.WatermarkConfig{Enabled: true, Position: "top-left"}
+	applyBrandWatermark(&cfg, "Toko Sari")
+	if cfg.Position != "top-left" {
+		t.Fatalf("position = %q, want caller's top-left kept", cfg.Position)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, stdimage.NewNRGBA(stdimage.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	if _, _, err := applyWatermark(buf.Bytes(), cfg); err != nil {
+		t.Fatalf("applyWatermark() with brand default: %v", err)
+	}
+}
+
+// TestImageJobWatermarksValidatedBrandDefault follows a prompt that only
+// enables the watermark from API validation through to the stored asset.
+func TestImageJobWatermarksValidatedBrandDefault(t *testing.T) {
+	var req struct {
+		Prompt jsoncfg.PromptJSON `json:"prompt"`
+	}
+	body := `{"prompt":{"title":"Kopi Susu","product_type":"beverage","style":"minimal","background":"wood","quantity":1,"aspect_ratio":"1:1","watermark":{"enabled":true}}}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+	req.Prompt.NormalizeForPlan("id", jsoncfg.DefaultPlan)
+	if err := req.Prompt.ValidateForPlan(jsoncfg.DefaultPlan); err != nil {
+		t.Fatalf("ValidateForPlan() rejected enabled-only watermark: %v", err)
+	}
+	prompt, err := json.Marshal(req.Prompt)
+	if err != nil {
+		t.Fatalf("marshal prompt: %v", err)
+	}
+
+	src := stdimage.NewNRGBA(stdimage.Rect(0, 0, 400, 200))
+	for i := range src.Pix {
+		src.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	runner := &fakeRunner{}
+	fj := runner.add(job{
+		ID:        "job-brand",
+		UserID:    "user-1",
+		TaskType:  taskTypeImage,
+		Provider:  defaultImageProvider,
+		Quantity:  1,
+		Aspect:    "1:1",
+		Prompt:    prompt,
+		BrandName: "Toko Sari",
+	})
+	w := newTestWorker(runner, nil)
+	w.store = store
+	w.imageProviders = map[string]image.Generator{defaultImageProvider: pngImageGenerator{data: buf.Bytes()}}
+
+	runQueue(t, w, 1)
+
+	if fj.Status != statusSucceeded {
+		t.Fatalf("expected status %s, got %s (%s)", statusSucceeded, fj.Status, fj.Error)
+	}
+	stored, err := store.Read(context.Background(), defaultStorageKey("job-brand", "image/png", 0))
+	if err != nil {
+		t.Fatalf("read stored asset: %v", err)
+	}
+	out, _, err := stdimage.Decode(bytes.NewReader(stored))
+	if err != nil {
+		t.Fatalf("decode stored asset: %v", err)
+	}
+	marked := func(r stdimage.Rectangle) bool {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if cr, cg, cb, _ := out.At(x, y).RGBA(); cr != 0xffff || cg != 0xffff || cb != 0xffff {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if !marked(stdimage.Rect(200, 100, 400, 200)) || marked(stdimage.Rect(0, 0, 200, 100)) {
+		t.Fatal("expected the brand watermark in the bottom-right corner only")
+	}
+}
+
+func TestPurgeDeletedAssetsRemovesExpiredAssets(t *testing.T) {
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	for _, key := range []string{"images/old.png", "images/recent.png"} {
+		if _, err := store.Write(context.Background(), key, []byte("png")); err != nil {
+			t.Fatalf("write %s: %v", key, err)
+		}
+	}
+	now := time.Now()
+	runner := &fakeRunner{deleted: []fakeDeletedAsset{
+		{id: "old", storageKey: "images/old.png", deletedAt: now.Add(-4 * 24 * time.Hour)},
+		{id: "remote", storageKey: "https://cdn.example.com/old.png", deletedAt: now.Add(-4 * 24 * time.Hour)},
+		{id: "recent", storageKey: "images/recent.png", deletedAt: now.Add(-time.Hour)},
+	}}
+	w := &jobWorker{
+		ctx:        context.Background(),
+		runner:     runner,
+		logger:     zerolog.Nop(),
+		store:      store,
+		purgeGrace: 72 * time.Hour,
+	}
+
+	purged, err := w.purgeDeletedAssets()
+	if err != nil {
+		t.Fatalf("purgeDeletedAssets: %v", err)
+	}
+	if purged != 2 || strings.Join(runner.purged, ",") != "old,remote" {
+		t.Fatalf("purged %d (%v), want old and remote", purged, runner.purged)
+	}
+	if _, err := store.Read(context.Background(), "images/old.png"); err == nil {
+		t.Fatalf("expired asset bytes still present")
+	}
+	if _, err := store.Read(context.Background(), "images/recent.png"); err != nil {
+		t.Fatalf("asset inside grace period was removed: %v", err)
+	}
+	if len(runner.deleted) != 1 || runner.deleted[0].id != "recent" {
+		t.Fatalf("remaining deleted assets = %+v", runner.deleted)
+	}
+}
+
+func TestExpireRetainedAssetsRemovesOnlyExpiredFreeAssets(t *testing.T) {
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	now := time.Now()
+	assets := []fakeRetainedAsset{
+		{id: "free-old", storageKey: "images/free-old.png", plan: "free", createdAt: now.Add(-40 * 24 * time.Hour)},
+		{id: "free-new", storageKey: "images/free-new.png", plan: "free", createdAt: now.Add(-5 * 24 * time.Hour)},
+		{id: "free-busy", storageKey: "images/free-busy.png", plan: "free", createdAt: now.Add(-40 * 24 * time.Hour), activeJob: true},
+		{id: "supporter-old", storageKey: "images/supporter-old.png", plan: "supporter", createdAt: now.Add(-40 * 24 * time.Hour)},
+		{id: "pro-old", storageKey: "images/pro-old.png", plan: "pro", createdAt: now.Add(-400 * 24 * time.Hour)},
+	}
+	for _, asset := range assets {
+		if _, err := store.Write(context.Background(), asset.storageKey, []byte("png")); err != nil {
+			t.Fatalf("write %s: %v", asset.storageKey, err)
+		}
+	}
+	runner := &fakeRunner{retained: assets}
+	w := &jobWorker{
+		ctx:       context.Background(),
+		runner:    runner,
+		logger:    zerolog.Nop(),
+		store:     store,
+		retention: planRetention(map[string]int{"free": 30, "supporter": 365}),
+	}
+
+	expired, err := w.expireRetainedAssets()
+	if err != nil {
+		t.Fatalf("expireRetainedAssets: %v", err)
+	}
+	if expired != 1 || strings.Join(runner.purged, ",") != "free-old" {
+		t.Fatalf("expired %d (%v), want only free-old", expired, runner.purged)
+	}
+	for _, asset := range assets {
+		_, readErr := store.Read(context.Background(), asset.storageKey)
+		if removed := readErr != nil; removed != (asset.id == "free-old") {
+			t.Fatalf("%s bytes removed = %v", asset.id, removed)
+		}
+	}
+}
+
+type pngImageGenerator struct {
+	data   []byte
+	reason string
+}
+
+func (g pngImageGenerator) Generate(ctx context.Context, req image.GenerateRequest) ([]image.Asset, error) {
+	return []image.Asset{{Data: g.data, Format: "image/png", Width: 800, Height: 600, FallbackReason: g.reason}}, nil
+}
+
+func TestImageJobStoresThumbnail(t *testing.T) {
+	src := stdimage.NewNRGBA(stdimage.Rect(0, 0, 800, 600))
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 800; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: uint8(x * y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	runner := &fakeRunner{}
+	fj := runner.add(job{
+		ID:       "job-thumb",
+		UserID:   "user-1",
+		TaskType: taskTypeImage,
+		Provider: defaultImageProvider,
+		Quantity: 1,
+		Aspect:   "4:3",
+		Prompt:   json.RawMessage(`{"title":"Sample"}`),
+	})
+	w := newTestWorker(runner, nil)
+	w.store = store
+	w.imageProviders = map[string]image.Generator{defaultImageProvider: pngImageGenerator{data: buf.Bytes()}}
+
+	runQueue(t, w, 1)
+
+	if fj.Status != statusSucceeded {
+		t.Fatalf("expected status %s, got %s (%s)", statusSucceeded, fj.Status, fj.Error)
+	}
+	if len(runner.inserted) != 1 {
+		t.Fatalf("inserted %d assets, want 1", len(runner.inserted))
+	}
+	var meta struct {
+		ThumbnailKey string `json:"thumbnail_key"`
+	}
+	if err := json.Unmarshal(runner.inserted[0], &meta); err != nil {
+		t.Fatalf("decode metadata: %v", err)
+	}
+	if !strings.HasPrefix(meta.ThumbnailKey, "thumbnails/") {
+		t.Fatalf("thumbnail_key = %q, want thumbnails/ prefix", meta.ThumbnailKey)
+	}
+	thumb, err := store.Read(context.Background(), meta.ThumbnailKey)
+	if err != nil {
+		t.Fatalf("read thumbnail: %v", err)
+	}
+	if len(thumb) >= buf.Len() {
+		t.Fatalf("thumbnail (%d bytes) not smaller than source (%d bytes)", len(thumb), buf.Len())
+	}
+	cfg, _, err := stdimage.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("decode thumbnail: %v", err)
+	}
+	if cfg.Width != 256 || cfg.Height != 192 {
+		t.Fatalf("thumbnail size = %dx%d, want 256x192", cfg.Width, cfg.Height)
+	}
+}
+
+func TestImageJobCarriesRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	runner := &fakeRunner{}
+	fj := runner.add(job{
+		ID:        "job-traced",
+		UserID:    "user-1",
+		TaskType:  taskTypeImage,
+		Provider:  defaultImageProvider,
+		Quantity:  1,
+		Aspect:    "1:1",
+		Prompt:    json.RawMessage(`{"title":"Sample"}`),
+		RequestID: "req-abc-123",
+	})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, stdimage.NewNRGBA(stdimage.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	w := newTestWorker(runner, nil)
+	w.logger = zerolog.New(&logs)
+	w.store = store
+	w.imageProviders = map[string]image.Generator{defaultImageProvider: pngImageGenerator{data: buf.Bytes()}}
+
+	runQueue(t, w, 1)
+
+	if fj.Status != statusSucceeded {
+		t.Fatalf("expected status %s, got %s (%s)", statusSucceeded, fj.Status, fj.Error)
+	}
+	if len(runner.inserted) != 1 {
+		t.Fatalf("inserted %d assets, want 1", len(runner.inserted))
+	}
+	var meta struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(runner.inserted[0], &meta); err != nil {
+		t.Fatalf("decode metadata: %v", err)
+	}
+	if meta.RequestID != "req-abc-123" {
+		t.Fatalf("asset request_id = %q, want req-abc-123", meta.RequestID)
+	}
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		t.Fatalf("expected job log lines")
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, `"request_id":"req-abc-123"`) {
+			t.Fatalf("log line missing request_id: %s", line)
+		}
+	}
+}
+
+func TestImageJobRecordsFallbackReason(t *testing.T) {
+	runner := &fakeRunner{}
+	fj := runner.add(job{
+		ID:       "job-degraded",
+		UserID:   "user-1",
+		TaskType: taskTypeImage,
+		Provider: defaultImageProvider,
+		Quantity: 1,
+		Aspect:   "1:1",
+		Prompt:   json.RawMessage(`{"title":"Sample"}`),
+	})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, stdimage.NewNRGBA(stdimage.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	w := newTestWorker(runner, nil)
+	w.store = store
+	w.imageProviders = map[string]image.Generator{
+		defaultImageProvider: pngImageGenerator{data: buf.Bytes(), reason: image.FallbackCircuitOpen},
+	}
+
+	runQueue(t, w, 1)
+
+	if fj.Status != statusSucceeded {
+		t.Fatalf("expected status %s, got %s (%s)", statusSucceeded, fj.Status, fj.Error)
+	}
+	if len(runner.inserted) != 1 {
+		t.Fatalf("inserted %d assets, want 1", len(runner.inserted))
+	}
+	var meta struct {
+		FallbackReason string `json:"fallback_reason"`
+	}
+	if err := json.Unmarshal(runner.inserted[0], &meta); err != nil {
+		t.Fatalf("decode metadata: %v", err)
+	}
+	if meta.FallbackReason != image.FallbackCircuitOpen {
+		t.Fatalf("asset fallback_reason = %q, want %q", meta.FallbackReason, image.FallbackCircuitOpen)
+	}
+}
+
+func TestHandleJobRecordsMetrics(t *testing.T) {
+	succeededBefore := metrics.JobsSucceeded.Value(taskTypeVideo, defaultVideoProvider)
+	failedBefore := metrics.JobsFailed.Value(taskTypeVideo, defaultVideoProvider)
+
+	runner := &fakeRunner{}
+	runner.add(testVideoJob("job-ok"))
+	runQueue(t, newTestWorker(runner, &flakyVideoGenerator{failures: 1}), 10)
+	runner = &fakeRunner{}
+	runner.add(testVideoJob("job-bad"))
+	runQueue(t, newTestWorker(runner, &flakyVideoGenerator{failures: 10}), 10)
+
+	succeeded := metrics.JobsSucceeded.Value(taskTypeVideo, defaultVideoProvider)
+	failed := metrics.JobsFailed.Value(taskTypeVideo, defaultVideoProvider)
+	if succeeded-succeededBefore != 1 {
+		t.Fatalf("succeeded delta = %v, want 1", succeeded-succeededBefore)
+	}
+	if failed-failedBefore != 1 {
+		t.Fatalf("failed delta = %v, want 1 (retried attempts must not count)", failed-failedBefore)
+	}
+
+	rr := httptest.NewRecorder()
+	metrics.Default.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body := rr.Body.String()
+	for _, line := range []string{
+		fmt.Sprintf(`umkm_jobs_succeeded_total{task="VIDEO_GEN",provider=%q} %v`, defaultVideoProvider, succeeded),
+		fmt.Sprintf(`umkm_jobs_failed_total{task="VIDEO_GEN",provider=%q} %v`, defaultVideoProvider, failed),
+		fmt.Sprintf(`umkm_generation_duration_seconds_count{task="VIDEO_GEN",provider=%q}`, defaultVideoProvider),
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("scrape missing %q:\n%s", line, body)
+		}
+	}
+}
+
+type stubResolver map[string][]string
+
+func (s stubResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	var out []net.IPAddr
+	for _, addr := range s[host] {
+		out = append(out, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+	return out, nil
+}
+
+// redirectTransport redirects requests for the hosts in routes and records
+// every hop without touching the network.
+type redirectTransport struct {
+	routes    map[string]string
+	requested []string
+}
+
+func (rt *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requested = append(rt.requested, req.URL.String())
+	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("image")), Request: req}
+	if target, ok := rt.routes[req.URL.Host]; ok {
+		resp.StatusCode = http.StatusFound
+		resp.Header.Set("Location", target)
+	}
+	return resp, nil
+}
+
+func TestFetchSourceAssetRejectsPrivateResolution(t *testing.T) {
+	w := newTestWorker(&fakeRunner{}, nil)
+	w.sourceClient = newSourceClient(nil, stubResolver{"cdn.example.com": {"10.0.0.1"}})
+
+	if data, _ := w.fetchSourceAsset(testVideoJob("job-1"), "http://cdn.example.com/photo.png"); data != nil {
+		t.Fatalf("fetched %d bytes from a host resolving to a private address", len(data))
+	}
+}
+
+func TestFetchSourceAssetBlocksPrivateRedirect(t *testing.T) {
+	transport := &redirectTransport{routes: map[string]string{
+		"cdn.example.com": "http://169.254.169.254/latest/meta-data/",
+	}}
+	w := newTestWorker(&fakeRunner{}, nil)
+	w.sourceClient = newSourceClient(nil, nil)
+	w.sourceClient.Transport = transport
+
+	if data, _ := w.fetchSourceAsset(testVideoJob("job-1"), "https://cdn.example.com/photo.png"); data != nil {
+		t.Fatalf("fetched %d bytes through a redirect to the metadata address", len(data))
+	}
+	if len(transport.requested) != 1 {
+		t.Fatalf("requests = %v, want only the initial hop", transport.requested)
+	}
+}
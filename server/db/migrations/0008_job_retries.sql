@@ -0,0 +1,11 @@
+-- +goose Up
+alter table generation_requests
+    add column if not exists attempts int not null default 0,
+    add column if not exists next_run_at timestamptz;
+
+create index if not exists ix_generation_requests_queue on generation_requests (status, next_run_at, created_at);
+
+-- +goose Down
+drop index if exists ix_generation_requests_queue;
+alter table generation_requests drop column if exists next_run_at;
+alter table generation_requests drop column if exists attempts;
@@ -0,0 +1,9 @@
+-- +goose Up
+create table if not exists worker_heartbeat (
+    worker_id text primary key,
+    last_seen_at timestamptz not null default now(),
+    properties jsonb not null default '{}'::jsonb
+);
+
+-- +goose Down
+drop table if exists worker_heartbeat;
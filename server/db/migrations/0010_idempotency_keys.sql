@@ -0,0 +1,13 @@
+-- +goose Up
+create table if not exists idempotency_keys (
+    user_id uuid not null references users(id) on delete cascade,
+    key text not null,
+    job_id uuid not null,
+    created_at timestamptz not null default now(),
+    primary key (user_id, key)
+);
+create index if not exists ix_idempotency_keys_created_at on idempotency_keys (created_at);
+
+-- +goose Down
+drop index if exists ix_idempotency_keys_created_at;
+drop table if exists idempotency_keys;
@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION fn_consume_quota(p_user_id uuid, p_used int, p_today date)
+RETURNS TABLE (remaining int) AS $$
+DECLARE
+    quota_daily int;
+    quota_used int;
+    refreshed_at timestamptz;
+BEGIN
+    SELECT COALESCE((properties->>'quota_daily')::int, 2),
+           COALESCE((properties->>'quota_used_today')::int, 0),
+           (properties->>'quota_refreshed_at')::timestamptz
+    INTO quota_daily, quota_used, refreshed_at
+    FROM users
+    WHERE id = p_user_id
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'user not found';
+    END IF;
+
+    -- Usage counts towards the UTC day of the last refresh; a new day starts
+    -- from zero.
+    IF refreshed_at IS NULL OR (refreshed_at AT TIME ZONE 'UTC')::date < p_today THEN
+        quota_used := 0;
+    END IF;
+
+    IF quota_used + p_used > quota_daily THEN
+        RAISE EXCEPTION 'quota exceeded';
+    END IF;
+
+    UPDATE users
+    SET properties = jsonb_set(
+            jsonb_set(properties, '{quota_used_today}', to_jsonb(quota_used + p_used), true),
+            '{quota_refreshed_at}', to_jsonb(now()), true
+        ),
+        updated_at = now()
+    WHERE id = p_user_id;
+
+    remaining := quota_daily - (quota_used + p_used);
+    RETURN NEXT;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS fn_consume_quota(uuid, int, date);
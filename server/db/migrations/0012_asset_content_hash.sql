@@ -0,0 +1,6 @@
+-- +goose Up
+create index if not exists ix_assets_user_sha256 on assets (user_id, (properties->>'sha256'))
+    where properties ? 'sha256';
+
+-- +goose Down
+drop index if exists ix_assets_user_sha256;
@@ -0,0 +1,7 @@
+-- +goose Up
+alter table donations add column if not exists payment_ref text;
+create unique index if not exists ux_donations_payment_ref on donations (payment_ref);
+
+-- +goose Down
+drop index if exists ux_donations_payment_ref;
+alter table donations drop column if exists payment_ref;
@@ -0,0 +1,5 @@
+-- +goose Up
+alter table integration_tokens add column if not exists previous_key_fingerprint text;
+
+-- +goose Down
+alter table integration_tokens drop column if exists previous_key_fingerprint;
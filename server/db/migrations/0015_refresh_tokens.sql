@@ -0,0 +1,16 @@
+-- +goose Up
+create table if not exists refresh_tokens (
+    id uuid primary key default gen_random_uuid(),
+    user_id uuid not null references users(id) on delete cascade,
+    family_id uuid not null,
+    token_hash text not null,
+    expires_at timestamptz not null,
+    revoked_at timestamptz,
+    created_at timestamptz not null default now()
+);
+
+create unique index if not exists ux_refresh_tokens_token_hash on refresh_tokens (token_hash);
+create index if not exists ix_refresh_tokens_family on refresh_tokens (family_id);
+
+-- +goose Down
+drop table if exists refresh_tokens;
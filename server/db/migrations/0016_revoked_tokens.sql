@@ -0,0 +1,12 @@
+-- +goose Up
+create table if not exists revoked_tokens (
+    jti text primary key,
+    user_id uuid references users(id) on delete cascade,
+    expires_at timestamptz not null,
+    revoked_at timestamptz not null default now()
+);
+
+create index if not exists ix_revoked_tokens_expires_at on revoked_tokens (expires_at);
+
+-- +goose Down
+drop table if exists revoked_tokens;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE usage_events ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE usage_events DROP CONSTRAINT IF EXISTS usage_events_event_type_check;
+ALTER TABLE usage_events
+    ADD CONSTRAINT usage_events_event_type_check
+    CHECK (event_type IN ('IMAGE_GEN','VIDEO_GEN','UPSCALE','PROMPT_ENHANCE','PROMPT_RANDOM','PROMPT_CLEAR','AUTH_LOGIN','AUTH_REFRESH','AUTH_LOGOUT'));
+
+-- +goose Down
+DELETE FROM usage_events WHERE event_type IN ('AUTH_LOGIN','AUTH_REFRESH','AUTH_LOGOUT');
+ALTER TABLE usage_events DROP CONSTRAINT IF EXISTS usage_events_event_type_check;
+ALTER TABLE usage_events
+    ADD CONSTRAINT usage_events_event_type_check
+    CHECK (event_type IN ('IMAGE_GEN','VIDEO_GEN','UPSCALE','PROMPT_ENHANCE','PROMPT_RANDOM','PROMPT_CLEAR'));
+ALTER TABLE usage_events ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,39 @@
+-- +goose Up
+alter table prompt_templates add column if not exists category text not null default 'general';
+alter table prompt_templates add column if not exists locale text not null default 'en';
+create index if not exists ix_prompt_templates_curated on prompt_templates (category, locale) where user_id is null;
+
+-- Curated templates are shared by every user and have no owner. Fixed ids
+-- let the rollback remove exactly these rows.
+insert into prompt_templates (id, user_id, title, tags, category, locale, template_json) values
+('0dad7a9e-7f90-4d14-8683-4fe3415b238d', null, 'Food hero shot', '{food,hero}', 'food', 'en',
+ '{"title":"Signature dish","product_type":"food","style":"warm natural light, shallow depth of field","background":"rustic wooden table","instructions":"Keep the dish centred with steam visible","aspect_ratio":"1:1","quantity":1}'::jsonb),
+('a6bf5532-db4b-439e-a69b-6d2c9f81cae4', null, 'Foto menu makanan', '{food,menu}', 'food', 'id',
+ '{"title":"Menu andalan","product_type":"makanan","style":"cahaya alami hangat, fokus dekat","background":"meja kayu rustik","instructions":"Letakkan hidangan di tengah dengan uap terlihat","aspect_ratio":"1:1","quantity":1,"extras":{"locale":"id"}}'::jsonb),
+('f763a135-110a-4753-b2ec-d35be17e2492', null, 'Iced drink', '{beverage,summer}', 'beverage', 'en',
+ '{"title":"Iced coffee","product_type":"beverage","style":"bright, condensation on glass","background":"pastel gradient","aspect_ratio":"4:5","quantity":1}'::jsonb),
+('2dc6f8c3-0e14-4a95-8d32-eb3b9aae4f89', null, 'Minuman dingin', '{beverage,summer}', 'beverage', 'id',
+ '{"title":"Es kopi susu","product_type":"minuman","style":"cerah, embun pada gelas","background":"gradasi pastel","aspect_ratio":"4:5","quantity":1,"extras":{"locale":"id"}}'::jsonb),
+('03002237-684c-49d0-a264-41113f3aada2', null, 'Fashion catalogue', '{fashion,catalogue}', 'fashion', 'en',
+ '{"title":"New collection","product_type":"fashion","style":"clean studio lighting","background":"seamless white","aspect_ratio":"3:4","quantity":1}'::jsonb),
+('34985528-7a1d-4f12-b1ad-91264fcad111', null, 'Katalog fesyen', '{fashion,catalogue}', 'fashion', 'id',
+ '{"title":"Koleksi terbaru","product_type":"fesyen","style":"pencahayaan studio bersih","background":"latar putih polos","aspect_ratio":"3:4","quantity":1,"extras":{"locale":"id"}}'::jsonb),
+('c636d62a-061f-4abf-8001-6c5f9afc08a5', null, 'Handcraft showcase', '{craft}', 'craft', 'en',
+ '{"title":"Handmade craft","product_type":"craft","style":"soft daylight, texture detail","background":"linen cloth","aspect_ratio":"1:1","quantity":1}'::jsonb),
+('0a925760-95d9-44a7-a3d4-139da59520ed', null, 'Kerajinan tangan', '{craft}', 'craft', 'id',
+ '{"title":"Kerajinan tangan","product_type":"kerajinan","style":"cahaya siang lembut, detail tekstur","background":"kain linen","aspect_ratio":"1:1","quantity":1,"extras":{"locale":"id"}}'::jsonb);
+
+-- +goose Down
+delete from prompt_templates where id in (
+  '0dad7a9e-7f90-4d14-8683-4fe3415b238d',
+  'a6bf5532-db4b-439e-a69b-6d2c9f81cae4',
+  'f763a135-110a-4753-b2ec-d35be17e2492',
+  '2dc6f8c3-0e14-4a95-8d32-eb3b9aae4f89',
+  '03002237-684c-49d0-a264-41113f3aada2',
+  '34985528-7a1d-4f12-b1ad-91264fcad111',
+  'c636d62a-061f-4abf-8001-6c5f9afc08a5',
+  '0a925760-95d9-44a7-a3d4-139da59520ed'
+);
+drop index if exists ix_prompt_templates_curated;
+alter table prompt_templates drop column if exists locale;
+alter table prompt_templates drop column if exists category;
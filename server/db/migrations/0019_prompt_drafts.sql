@@ -0,0 +1,13 @@
+-- +goose Up
+create table if not exists prompt_drafts (
+    id uuid primary key default gen_random_uuid(),
+    user_id uuid not null references users(id) on delete cascade,
+    name text not null,
+    prompt_json jsonb not null,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists ix_prompt_drafts_user_created on prompt_drafts (user_id, created_at desc);
+
+-- +goose Down
+drop table if exists prompt_drafts;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Keys are reserved before their job exists; job_id stays null until the job
+-- is recorded.
+alter table idempotency_keys alter column job_id drop not null;
+
+-- +goose Down
+delete from idempotency_keys where job_id is null;
+alter table idempotency_keys alter column job_id set not null;
@@ -9,6 +9,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/oschwald/geoip2-golang v1.11.0
 	github.com/rs/zerolog v1.33.0
+	golang.org/x/image v0.25.0
 	golang.org/x/text v0.29.0
 )
 
@@ -169,6 +169,58 @@ LIMIT $2 OFFSET $3
 	return jobs, nil
 }
 
+type ListImageJobsFilteredParams struct {
+	UserID string
+	Status string
+	Since  *time.Time
+	Until  *time.Time
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListImageJobsFiltered(ctx context.Context, arg ListImageJobsFilteredParams) ([]ImageJob, error) {
+	rows, err := q.db.Query(ctx, `
+SELECT id, user_id, provider, model, status, quantity, aspect_ratio, prompt, source_asset, output, error, created_at, updated_at
+FROM image_jobs
+WHERE user_id = $1
+  AND ($2::text = '' OR status = $2::text)
+  AND ($3::timestamptz IS NULL OR created_at >= $3::timestamptz)
+  AND ($4::timestamptz IS NULL OR created_at < $4::timestamptz)
+ORDER BY created_at DESC
+LIMIT $5 OFFSET $6
+`, arg.UserID, arg.Status, arg.Since, arg.Until, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var jobs []ImageJob
+	for rows.Next() {
+		var job ImageJob
+		if err := rows.Scan(
+			&job.ID,
+			&job.UserID,
+			&job.Provider,
+			&job.Model,
+			&job.Status,
+			&job.Quantity,
+			&job.AspectRatio,
+			&job.Prompt,
+			&job.SourceAsset,
+			&job.Output,
+			&job.Error,
+			&job.CreatedAt,
+			&job.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
 type StatsSummaryRow struct {
 	Total       int64
 	Succeeded   int64
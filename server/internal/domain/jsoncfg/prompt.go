@@ -3,18 +3,29 @@ package jsoncfg
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"server/internal/watermark"
 )
 
 type WatermarkConfig struct {
-	Enabled  bool   `json:"enabled"`
-	Text     string `json:"text"`
-	Position string `json:"position"`
+	Enabled  bool    `json:"enabled"`
+	Text     string  `json:"text"`
+	Position string  `json:"position"`
+	Opacity  float64 `json:"opacity,omitempty"`
+	OffsetX  int     `json:"offset_x,omitempty"`
+	OffsetY  int     `json:"offset_y,omitempty"`
 }
 
 type ExtrasConfig struct {
-	Locale  string `json:"locale"`
-	Quality string `json:"quality"`
+	Locale         string `json:"locale"`
+	Quality        string `json:"quality"`
+	NegativePrompt string `json:"negative_prompt,omitempty"`
+	// Region hints which provider region should serve the job, e.g. "cn".
+	Region string `json:"region,omitempty"`
 }
 
 // SourceAssetConfig represents an uploaded or remote asset referenced by a prompt.
@@ -51,29 +62,72 @@ type PromptJSON struct {
 	Extras       ExtrasConfig      `json:"extras"`
 	SourceAsset  SourceAssetConfig `json:"source_asset"`
 	Workflow     WorkflowConfig    `json:"workflow"`
+	OutputFormat string            `json:"output_format,omitempty"`
+	Seed         *int              `json:"seed,omitempty"`
 }
 
-var allowedAspectRatios = map[string]struct{}{
-	"1:1":  {},
-	"4:3":  {},
-	"3:4":  {},
-	"16:9": {},
-	"9:16": {},
-}
+// DefaultAspectRatios lists the aspect ratios accepted by Validate unless
+// overridden with SetAllowedAspectRatios.
+var DefaultAspectRatios = []string{"1:1", "4:3", "3:4", "16:9", "9:16", "4:5", "5:4", "3:2", "2:3"}
+
+const (
+	// aspectBaseDimension is the short side used when checking a ratio against MaxAspectDimension.
+	aspectBaseDimension = 1024
+	// MaxAspectDimension caps the long side, in pixels, of an aspect ratio rendered at aspectBaseDimension.
+	MaxAspectDimension = 2048
+)
+
+// DefaultPlanMaxQuantities lists the per-plan quantity caps used unless
+// overridden with SetQuantityLimits.
+var DefaultPlanMaxQuantities = map[string]int{"free": 2, "pro": 8, "supporter": 8}
+
+var (
+	quantityMu        sync.RWMutex
+	defaultQuantity   = DefaultPromptQuantity
+	planMaxQuantities = copyPlanQuantities(DefaultPlanMaxQuantities)
+)
+
+var (
+	textLimitMu           sync.RWMutex
+	maxTitleLength        = DefaultMaxTitleLength
+	maxInstructionsLength = DefaultMaxInstructionsLength
+)
+
+var (
+	aspectMu            sync.RWMutex
+	allowedAspectRatios = aspectRatioSet(DefaultAspectRatios)
+	aspectRatioOrder    = append([]string(nil), DefaultAspectRatios...)
+)
 
 const (
 	// DefaultPromptVersion represents the schema version persisted for prompts.
 	DefaultPromptVersion = "2024-01"
 	// DefaultPromptAspectRatio is used when the request omits the aspect ratio.
 	DefaultPromptAspectRatio = "1:1"
-	// DefaultPromptQuantity is the minimum quantity allowed for free users.
+	// DefaultPromptQuantity is applied when the request omits the quantity,
+	// unless overridden with SetQuantityLimits.
 	DefaultPromptQuantity = 1
-	// MaxPromptQuantity enforces the free tier cap for generated assets.
+	// MaxPromptQuantity caps generated assets for plans without a configured
+	// limit, matching the free tier.
 	MaxPromptQuantity = 2
+	// DefaultPlan is the plan assumed by Normalize and Validate.
+	DefaultPlan = "free"
 	// DefaultExtrasLocale is applied when no locale preference is provided.
 	DefaultExtrasLocale = "en"
 	// DefaultExtrasQuality represents the baseline generation quality.
 	DefaultExtrasQuality = "standard"
+	// MaxNegativePromptLength caps the user supplied negative prompt, in characters.
+	MaxNegativePromptLength = 500
+	// DefaultMaxTitleLength caps the title, in characters, unless overridden
+	// with SetTextLimits.
+	DefaultMaxTitleLength = 150
+	// DefaultMaxInstructionsLength caps the instructions, in characters, unless
+	// overridden with SetTextLimits.
+	DefaultMaxInstructionsLength = 2000
+	// MaxPromptReferences is the most reference URLs Normalize keeps.
+	MaxPromptReferences = 8
+	// MaxSeed is the largest seed accepted by the image providers.
+	MaxSeed = 2147483647
 	// DefaultWorkflowMode is applied when the prompt does not specify an editing intent.
 	DefaultWorkflowMode = WorkflowModeGenerate
 )
@@ -86,6 +140,21 @@ const (
 	WorkflowModeRetouch    = "retouch"
 )
 
+// Output formats the worker can transcode generated images into. An empty
+// format keeps whatever the provider returned.
+const (
+	OutputFormatPNG  = "png"
+	OutputFormatJPEG = "jpeg"
+	OutputFormatWebP = "webp"
+)
+
+var allowedOutputFormats = map[string]struct{}{
+	"":               {},
+	OutputFormatPNG:  {},
+	OutputFormatJPEG: {},
+	OutputFormatWebP: {},
+}
+
 var allowedWorkflowModes = map[string]struct{}{
 	WorkflowModeGenerate:   {},
 	WorkflowModeBackground: {},
@@ -93,20 +162,22 @@ var allowedWorkflowModes = map[string]struct{}{
 	WorkflowModeRetouch:    {},
 }
 
-// Normalize ensures the prompt JSON respects server defaults and limits.
+// Normalize ensures the prompt JSON respects server defaults and the limits
+// of DefaultPlan.
 func (p *PromptJSON) Normalize(preferredLocale string) {
+	p.NormalizeForPlan(preferredLocale, DefaultPlan)
+}
+
+// NormalizeForPlan ensures the prompt JSON respects server defaults and clamps
+// the quantity to the cap of plan.
+func (p *PromptJSON) NormalizeForPlan(preferredLocale, plan string) {
 	if p == nil {
 		return
 	}
 	if p.Version == "" {
 		p.Version = DefaultPromptVersion
 	}
-	if p.Quantity <= 0 {
-		p.Quantity = DefaultPromptQuantity
-	}
-	if p.Quantity > MaxPromptQuantity {
-		p.Quantity = MaxPromptQuantity
-	}
+	p.Quantity = ClampQuantity(plan, p.Quantity)
 	if p.AspectRatio == "" {
 		p.AspectRatio = DefaultPromptAspectRatio
 	}
@@ -120,6 +191,9 @@ func (p *PromptJSON) Normalize(preferredLocale string) {
 	if p.Extras.Quality == "" {
 		p.Extras.Quality = DefaultExtrasQuality
 	}
+	p.Extras.NegativePrompt = strings.Join(strings.Fields(p.Extras.NegativePrompt), " ")
+	p.References = normalizeReferences(p.References)
+	p.Extras.Region = strings.ToLower(strings.TrimSpace(p.Extras.Region))
 
 	p.Workflow.Mode = normalizeWorkflowMode(p.Workflow.Mode)
 	p.Workflow.BackgroundTheme = strings.TrimSpace(p.Workflow.BackgroundTheme)
@@ -128,6 +202,8 @@ func (p *PromptJSON) Normalize(preferredLocale string) {
 	p.Workflow.RetouchStrength = strings.TrimSpace(p.Workflow.RetouchStrength)
 	p.Workflow.Notes = strings.TrimSpace(p.Workflow.Notes)
 
+	p.OutputFormat = NormalizeOutputFormat(p.OutputFormat)
+
 	p.SourceAsset.AssetID = strings.TrimSpace(p.SourceAsset.AssetID)
 	p.SourceAsset.StorageKey = strings.TrimSpace(p.SourceAsset.StorageKey)
 	p.SourceAsset.URL = strings.TrimSpace(p.SourceAsset.URL)
@@ -135,8 +211,14 @@ func (p *PromptJSON) Normalize(preferredLocale string) {
 	p.SourceAsset.Filename = strings.TrimSpace(p.SourceAsset.Filename)
 }
 
-// Validate ensures the prompt JSON satisfies the required contract before persistence or enhancement.
+// Validate ensures the prompt JSON satisfies the required contract before
+// persistence or enhancement, using the limits of DefaultPlan.
 func (p PromptJSON) Validate() error {
+	return p.ValidateForPlan(DefaultPlan)
+}
+
+// ValidateForPlan is Validate with the quantity checked against the cap of plan.
+func (p PromptJSON) ValidateForPlan(plan string) error {
 	if strings.TrimSpace(p.Title) == "" {
 		return fmt.Errorf("title is required")
 	}
@@ -149,20 +231,40 @@ func (p PromptJSON) Validate() error {
 	if strings.TrimSpace(p.Background) == "" {
 		return fmt.Errorf("background is required")
 	}
-	if p.Quantity < 1 || p.Quantity > MaxPromptQuantity {
-		return fmt.Errorf("quantity must be between 1 and %d", MaxPromptQuantity)
+	titleMax, instructionsMax := TextLimits()
+	if utf8.RuneCountInString(p.Title) > titleMax {
+		return fmt.Errorf("title must be at most %d characters", titleMax)
+	}
+	if utf8.RuneCountInString(p.Instructions) > instructionsMax {
+		return fmt.Errorf("instructions must be at most %d characters", instructionsMax)
 	}
-	if _, ok := allowedAspectRatios[p.AspectRatio]; !ok {
-		return fmt.Errorf("aspect_ratio must be one of 1:1, 4:3, 3:4, 16:9, 9:16")
+	if maxQuantity := MaxQuantityForPlan(plan); p.Quantity < 1 || p.Quantity > maxQuantity {
+		return fmt.Errorf("quantity must be between 1 and %d", maxQuantity)
+	}
+	if err := validateAspectRatio(p.AspectRatio); err != nil {
+		return err
 	}
 	if p.Watermark.Enabled {
-		if strings.TrimSpace(p.Watermark.Text) == "" {
-			return fmt.Errorf("watermark.text is required when watermark.enabled is true")
+		// Text and position may be left out; the worker fills in the user's
+		// brand name and a bottom-right placement.
+		if strings.TrimSpace(p.Watermark.Position) != "" {
+			if _, err := watermark.ParsePosition(p.Watermark.Position); err != nil {
+				return fmt.Errorf("watermark.position must be one of top-left, top-right, bottom-left, bottom-right, center")
+			}
 		}
-		if strings.TrimSpace(p.Watermark.Position) == "" {
-			return fmt.Errorf("watermark.position is required when watermark.enabled is true")
+		if p.Watermark.Opacity < 0 || p.Watermark.Opacity > 1 {
+			return fmt.Errorf("watermark.opacity must be between 0 and 1")
 		}
 	}
+	if utf8.RuneCountInString(p.Extras.NegativePrompt) > MaxNegativePromptLength {
+		return fmt.Errorf("extras.negative_prompt must be at most %d characters", MaxNegativePromptLength)
+	}
+	if p.Seed != nil && (*p.Seed < 1 || *p.Seed > MaxSeed) {
+		return fmt.Errorf("seed must be between 1 and %d", MaxSeed)
+	}
+	if _, ok := allowedOutputFormats[NormalizeOutputFormat(p.OutputFormat)]; !ok {
+		return fmt.Errorf("output_format must be one of png, jpeg, webp")
+	}
 	mode := normalizeWorkflowMode(p.Workflow.Mode)
 	if _, ok := allowedWorkflowModes[mode]; !ok {
 		return fmt.Errorf("workflow.mode must be one of generate, background, enhance, retouch")
@@ -203,6 +305,177 @@ func (s SourceAssetConfig) IsZero() bool {
 	return strings.TrimSpace(s.AssetID) == "" && strings.TrimSpace(s.StorageKey) == "" && strings.TrimSpace(s.URL) == ""
 }
 
+// NormalizeOutputFormat lowercases the requested output format and folds the
+// "jpg" alias into "jpeg".
+func NormalizeOutputFormat(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "jpg" {
+		return OutputFormatJPEG
+	}
+	return format
+}
+
+// SetQuantityLimits replaces the quantity applied when a request omits it and
+// the per-plan caps. Plans missing from planMax fall back to MaxPromptQuantity;
+// a non-positive defaultQty keeps DefaultPromptQuantity.
+func SetQuantityLimits(defaultQty int, planMax map[string]int) {
+	if defaultQty <= 0 {
+		defaultQty = DefaultPromptQuantity
+	}
+	quantityMu.Lock()
+	defer quantityMu.Unlock()
+	defaultQuantity = defaultQty
+	planMaxQuantities = copyPlanQuantities(planMax)
+}
+
+// MaxQuantityForPlan returns the most assets a single request on plan may ask
+// for.
+func MaxQuantityForPlan(plan string) int {
+	plan = strings.ToLower(strings.TrimSpace(plan))
+	if plan == "" {
+		plan = DefaultPlan
+	}
+	quantityMu.RLock()
+	defer quantityMu.RUnlock()
+	if n, ok := planMaxQuantities[plan]; ok && n > 0 {
+		return n
+	}
+	return MaxPromptQuantity
+}
+
+// ClampQuantity bounds quantity to 1..MaxQuantityForPlan(plan), substituting
+// the configured default when quantity is not positive.
+func ClampQuantity(plan string, quantity int) int {
+	maxQuantity := MaxQuantityForPlan(plan)
+	if quantity <= 0 {
+		quantityMu.RLock()
+		quantity = defaultQuantity
+		quantityMu.RUnlock()
+	}
+	return min(quantity, maxQuantity)
+}
+
+func copyPlanQuantities(in map[string]int) map[string]int {
+	out := make(map[string]int, len(in))
+	for plan, n := range in {
+		out[strings.ToLower(strings.TrimSpace(plan))] = n
+	}
+	return out
+}
+
+// SetTextLimits replaces the character caps Validate applies to the title and
+// instructions. A non-positive value restores the corresponding default.
+func SetTextLimits(title, instructions int) {
+	if title <= 0 {
+		title = DefaultMaxTitleLength
+	}
+	if instructions <= 0 {
+		instructions = DefaultMaxInstructionsLength
+	}
+	textLimitMu.Lock()
+	defer textLimitMu.Unlock()
+	maxTitleLength, maxInstructionsLength = title, instructions
+}
+
+// TextLimits returns the title and instructions caps currently applied by
+// Validate.
+func TextLimits() (title, instructions int) {
+	textLimitMu.RLock()
+	defer textLimitMu.RUnlock()
+	return maxTitleLength, maxInstructionsLength
+}
+
+// normalizeReferences trims reference URLs, drops blank ones and keeps at
+// most MaxPromptReferences.
+func normalizeReferences(refs []string) []string {
+	if len(refs) == 0 {
+		return refs
+	}
+	out := refs[:0]
+	for _, ref := range refs {
+		if ref = strings.TrimSpace(ref); ref != "" && len(out) < MaxPromptReferences {
+			out = append(out, ref)
+		}
+	}
+	return out
+}
+
+// SetAllowedAspectRatios replaces the aspect ratios accepted by Validate. Every
+// ratio must be a positive "w:h" pair whose long side stays within
+// MaxAspectDimension.
+func SetAllowedAspectRatios(ratios ...string) error {
+	normalized := make([]string, 0, len(ratios))
+	for _, ratio := range ratios {
+		ratio = strings.TrimSpace(ratio)
+		if err := checkAspectDimensions(ratio); err != nil {
+			return err
+		}
+		normalized = append(normalized, ratio)
+	}
+	if len(normalized) == 0 {
+		return fmt.Errorf("at least one aspect ratio is required")
+	}
+	aspectMu.Lock()
+	defer aspectMu.Unlock()
+	allowedAspectRatios = aspectRatioSet(normalized)
+	aspectRatioOrder = normalized
+	return nil
+}
+
+// AllowedAspectRatios returns the aspect ratios currently accepted by Validate.
+func AllowedAspectRatios() []string {
+	aspectMu.RLock()
+	defer aspectMu.RUnlock()
+	return append([]string(nil), aspectRatioOrder...)
+}
+
+func validateAspectRatio(ratio string) error {
+	aspectMu.RLock()
+	_, ok := allowedAspectRatios[ratio]
+	order := aspectRatioOrder
+	aspectMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("aspect_ratio must be one of %s", strings.Join(order, ", "))
+	}
+	return checkAspectDimensions(ratio)
+}
+
+func checkAspectDimensions(ratio string) error {
+	w, h, ok := parseAspectRatio(ratio)
+	if !ok {
+		return fmt.Errorf("aspect_ratio %q must be in w:h form", ratio)
+	}
+	short, long := w, h
+	if short > long {
+		short, long = long, short
+	}
+	if long*aspectBaseDimension/short > MaxAspectDimension {
+		return fmt.Errorf("aspect_ratio %s exceeds the maximum dimension of %dpx", ratio, MaxAspectDimension)
+	}
+	return nil
+}
+
+func parseAspectRatio(ratio string) (int, int, bool) {
+	parts := strings.Split(ratio, ":")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	w, errW := strconv.Atoi(strings.TrimSpace(parts[0]))
+	h, errH := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return 0, 0, false
+	}
+	return w, h, true
+}
+
+func aspectRatioSet(ratios []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(ratios))
+	for _, ratio := range ratios {
+		set[ratio] = struct{}{}
+	}
+	return set
+}
+
 func normalizeWorkflowMode(mode string) string {
 	mode = strings.ToLower(strings.TrimSpace(mode))
 	if mode == "" {
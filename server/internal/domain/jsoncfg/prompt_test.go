@@ -1,6 +1,10 @@
 package jsoncfg
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
 
 func TestPromptJSONNormalizeDefaults(t *testing.T) {
 	p := &PromptJSON{}
@@ -78,9 +82,223 @@ func TestPromptJSONValidate(t *testing.T) {
 	}
 
 	prompt.AspectRatio = "1:1"
+	prompt.OutputFormat = "JPG"
+	if err := prompt.Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error for jpg output format: %v", err)
+	}
+	prompt.OutputFormat = "gif"
+	if err := prompt.Validate(); err == nil {
+		t.Fatalf("Validate() expected error for unsupported output format")
+	}
+
+	prompt.OutputFormat = ""
+	seed := 0
+	prompt.Seed = &seed
+	if err := prompt.Validate(); err == nil {
+		t.Fatalf("Validate() expected error for zero seed")
+	}
+	seed = 42
+	if err := prompt.Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error for seed: %v", err)
+	}
+
+	prompt.Seed = nil
+	prompt.Extras.NegativePrompt = strings.Repeat("x", MaxNegativePromptLength+1)
+	if err := prompt.Validate(); err == nil {
+		t.Fatalf("Validate() expected error for overlong negative prompt")
+	}
+
+	prompt.Extras.NegativePrompt = ""
+	prompt.Watermark.Position = "bottom-middle"
+	if err := prompt.Validate(); err == nil {
+		t.Fatalf("Validate() expected error for unknown watermark position")
+	}
+	prompt.Watermark.Position = "top-left"
+	prompt.Watermark.Opacity = 1.5
+	if err := prompt.Validate(); err == nil {
+		t.Fatalf("Validate() expected error for watermark opacity above 1")
+	}
+
+	prompt.Watermark.Opacity = 0
 	prompt.Watermark.Enabled = true
 	prompt.Watermark.Text = ""
+	prompt.Watermark.Position = ""
+	if err := prompt.Validate(); err != nil {
+		t.Fatalf("Validate() with brand-default watermark: %v", err)
+	}
+}
+
+func TestPromptJSONValidateAspectRatios(t *testing.T) {
+	base := PromptJSON{
+		Title:       "Kopi Susu",
+		ProductType: "beverage",
+		Style:       "minimal",
+		Background:  "wood",
+		Quantity:    1,
+	}
+	tests := []struct {
+		name    string
+		ratio   string
+		allowed []string
+		wantErr bool
+	}{
+		{name: "square", ratio: "1:1"},
+		{name: "landscape", ratio: "16:9"},
+		{name: "extended portrait", ratio: "4:5"},
+		{name: "extended landscape", ratio: "3:2"},
+		{name: "unknown ratio", ratio: "7:3", wantErr: true},
+		{name: "malformed", ratio: "wide", wantErr: true},
+		{name: "override allows custom ratio", ratio: "2:1", allowed: []string{"1:1", "2:1"}},
+		{name: "override drops default", ratio: "16:9", allowed: []string{"1:1"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.allowed != nil {
+				if err := SetAllowedAspectRatios(tc.allowed...); err != nil {
+					t.Fatalf("SetAllowedAspectRatios: %v", err)
+				}
+				t.Cleanup(func() { _ = SetAllowedAspectRatios(DefaultAspectRatios...) })
+			}
+			prompt := base
+			prompt.AspectRatio = tc.ratio
+			err := prompt.Validate()
+			if tc.wantErr && err == nil {
+				t.Fatalf("Validate() expected error for %q", tc.ratio)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("Validate() unexpected error for %q: %v", tc.ratio, err)
+			}
+		})
+	}
+}
+
+func TestSetAllowedAspectRatiosRejectsOversized(t *testing.T) {
+	for _, ratio := range []string{"21:9", "1:3", "0:1", "abc"} {
+		if err := SetAllowedAspectRatios("1:1", ratio); err == nil {
+			t.Fatalf("SetAllowedAspectRatios(%q) expected error", ratio)
+		}
+	}
+	if got := AllowedAspectRatios(); len(got) != len(DefaultAspectRatios) {
+		t.Fatalf("allowed ratios changed after rejected override: %v", got)
+	}
+}
+
+func TestPromptJSONNormalizeForPlanClampsQuantity(t *testing.T) {
+	tests := []struct {
+		plan string
+		in   int
+		want int
+	}{
+		{plan: "free", in: 10, want: 2},
+		{plan: "pro", in: 10, want: 8},
+		{plan: "supporter", in: 5, want: 5},
+		{plan: "PRO", in: 0, want: DefaultPromptQuantity},
+		{plan: "enterprise", in: 10, want: MaxPromptQuantity},
+		{plan: "", in: 10, want: 2},
+	}
+	for _, tc := range tests {
+		p := &PromptJSON{Quantity: tc.in}
+		p.NormalizeForPlan("", tc.plan)
+		if p.Quantity != tc.want {
+			t.Fatalf("NormalizeForPlan(%q) quantity %d = %d, want %d", tc.plan, tc.in, p.Quantity, tc.want)
+		}
+	}
+}
+
+func TestPromptJSONValidateForPlanQuantity(t *testing.T) {
+	prompt := PromptJSON{
+		Title:       "Kopi Susu",
+		ProductType: "beverage",
+		Style:       "minimalis",
+		Background:  "putih",
+		AspectRatio: "1:1",
+		Quantity:    6,
+	}
+	if err := prompt.ValidateForPlan("pro"); err != nil {
+		t.Fatalf("ValidateForPlan(pro) unexpected error: %v", err)
+	}
+	err := prompt.ValidateForPlan("free")
+	if err == nil || !strings.Contains(err.Error(), "between 1 and 2") {
+		t.Fatalf("ValidateForPlan(free) error = %v, want quantity cap of 2", err)
+	}
 	if err := prompt.Validate(); err == nil {
-		t.Fatalf("Validate() expected error when watermark text missing")
+		t.Fatalf("Validate() expected the default plan cap to reject quantity 6")
+	}
+}
+
+func TestSetQuantityLimits(t *testing.T) {
+	SetQuantityLimits(3, map[string]int{"Free": 4, "pro": 12})
+	t.Cleanup(func() { SetQuantityLimits(DefaultPromptQuantity, DefaultPlanMaxQuantities) })
+
+	if got := MaxQuantityForPlan("free"); got != 4 {
+		t.Fatalf("MaxQuantityForPlan(free) = %d, want 4", got)
+	}
+	if got := MaxQuantityForPlan("supporter"); got != MaxPromptQuantity {
+		t.Fatalf("MaxQuantityForPlan(supporter) = %d, want fallback %d", got, MaxPromptQuantity)
+	}
+	p := &PromptJSON{}
+	p.NormalizeForPlan("", "pro")
+	if p.Quantity != 3 {
+		t.Fatalf("default quantity = %d, want 3", p.Quantity)
+	}
+	if got := ClampQuantity("supporter", 0); got != MaxPromptQuantity {
+		t.Fatalf("ClampQuantity(supporter, 0) = %d, want default clamped to %d", got, MaxPromptQuantity)
+	}
+}
+
+func TestPromptJSONValidateTextLimits(t *testing.T) {
+	SetTextLimits(10, 20)
+	t.Cleanup(func() { SetTextLimits(0, 0) })
+
+	cases := []struct {
+		name         string
+		title        string
+		instructions string
+		wantErr      string
+	}{
+		{name: "title at limit", title: strings.Repeat("a", 10)},
+		{name: "title over limit", title: strings.Repeat("a", 11), wantErr: "title must be at most 10 characters"},
+		{name: "multibyte title at limit", title: strings.Repeat("é", 10)},
+		{name: "instructions at limit", title: "Kopi", instructions: strings.Repeat("b", 20)},
+		{name: "instructions over limit", title: "Kopi", instructions: strings.Repeat("b", 21), wantErr: "instructions must be at most 20 characters"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			prompt := PromptJSON{
+				Title:        tc.title,
+				ProductType:  "food",
+				Style:        "modern",
+				Background:   "studio",
+				Instructions: tc.instructions,
+				AspectRatio:  "1:1",
+				Quantity:     1,
+			}
+			err := prompt.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Fatalf("Validate() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPromptJSONNormalizeTrimsReferences(t *testing.T) {
+	refs := []string{" https://example.com/0.png ", "", "   "}
+	for i := 1; i <= MaxPromptReferences+2; i++ {
+		refs = append(refs, fmt.Sprintf("https://example.com/%d.png", i))
+	}
+	p := &PromptJSON{References: refs}
+	p.Normalize("")
+
+	if len(p.References) != MaxPromptReferences {
+		t.Fatalf("references = %d, want %d", len(p.References), MaxPromptReferences)
+	}
+	if p.References[0] != "https://example.com/0.png" {
+		t.Fatalf("first reference = %q, want it trimmed", p.References[0])
 	}
 }
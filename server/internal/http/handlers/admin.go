@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"server/internal/sqlinline"
+)
+
+const failedJobsLimit = 200
+
+type failedJobDTO struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	TaskType  string    `json:"task_type"`
+	Provider  string    `json:"provider"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AdminFailedJobs lists jobs that exhausted their retries in the last 24 hours.
+func (a *App) AdminFailedJobs(w http.ResponseWriter, r *http.Request) {
+	if a.currentUserID(r) == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	if !a.isAdmin(r) {
+		a.error(w, http.StatusForbidden, ErrForbidden, "admin access required")
+		return
+	}
+	rows, err := a.SQL.Query(r.Context(), sqlinline.QListFailedJobs, failedJobsLimit)
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("list failed jobs failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load failed jobs")
+		return
+	}
+	defer rows.Close()
+	items := make([]failedJobDTO, 0)
+	for rows.Next() {
+		var item failedJobDTO
+		if err := rows.Scan(&item.ID, &item.UserID, &item.TaskType, &item.Provider, &item.Attempts, &item.Error, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			a.Logger.Error().Err(err).Msg("scan failed job failed")
+			a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load failed jobs")
+			return
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		a.Logger.Error().Err(err).Msg("list failed jobs failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load failed jobs")
+		return
+	}
+	a.json(w, http.StatusOK, map[string]any{"items": items})
+}
+
+type providerStatsDTO struct {
+	Provider          string  `json:"provider"`
+	Total             int64   `json:"total"`
+	Succeeded         int64   `json:"succeeded"`
+	Failed            int64   `json:"failed"`
+	SuccessRate       float64 `json:"success_rate"`
+	AvgLatencySeconds float64 `json:"avg_latency_seconds"`
+}
+
+// AdminProviderStats groups image jobs by provider and reports how many ran,
+// the share of finished jobs that succeeded and how long they took on average.
+// Queued and running jobs count towards the total but not the success rate.
+func (a *App) AdminProviderStats(w http.ResponseWriter, r *http.Request) {
+	if a.currentUserID(r) == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	if !a.isAdmin(r) {
+		a.error(w, http.StatusForbidden, ErrForbidden, "admin access required")
+		return
+	}
+	rows, err := a.SQL.Query(r.Context(), sqlinline.QImageJobProviderStats)
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("load provider stats failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load provider stats")
+		return
+	}
+	defer rows.Close()
+	type accumulator struct {
+		stats    providerStatsDTO
+		finished int64
+		latency  float64
+	}
+	byProvider := make(map[string]*accumulator)
+	for rows.Next() {
+		var (
+			provider, status string
+			count            int64
+			latency          float64
+		)
+		if err := rows.Scan(&provider, &status, &count, &latency); err != nil {
+			a.Logger.Error().Err(err).Msg("scan provider stats failed")
+			a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load provider stats")
+			return
+		}
+		acc, ok := byProvider[provider]
+		if !ok {
+			acc = &accumulator{stats: providerStatsDTO{Provider: provider}}
+			byProvider[provider] = acc
+		}
+		acc.stats.Total += count
+		switch status {
+		case "SUCCEEDED":
+			acc.stats.Succeeded += count
+		case "FAILED":
+			acc.stats.Failed += count
+		default:
+			continue
+		}
+		acc.finished += count
+		acc.latency += latency
+	}
+	if err := rows.Err(); err != nil {
+		a.Logger.Error().Err(err).Msg("load provider stats failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load provider stats")
+		return
+	}
+	items := make([]providerStatsDTO, 0, len(byProvider))
+	for _, acc := range byProvider {
+		if acc.finished > 0 {
+			acc.stats.SuccessRate = roundTo2(100 * float64(acc.stats.Succeeded) / float64(acc.finished))
+			acc.stats.AvgLatencySeconds = roundTo2(acc.latency / float64(acc.finished))
+		}
+		items = append(items, acc.stats)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Provider < items[j].Provider })
+	a.json(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func roundTo2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type failedJobsSQL struct {
+	items   []failedJobDTO
+	queried bool
+	scanErr error
+	rowsErr error
+}
+
+func (s *failedJobsSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *failedJobsSQL) QueryRow(context.Context, string, ...any) pgx.Row {
+	return SimpleRow{}
+}
+
+func (s *failedJobsSQL) Query(_ context.Context, query string, args ...any) (pgx.Rows, error) {
+	if query != sqlinline.QListFailedJobs {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	s.queried = true
+	return &failedJobRows{items: s.items, scanErr: s.scanErr, err: s.rowsErr}, nil
+}
+
+type failedJobRows struct {
+	TestRowsBase
+	items   []failedJobDTO
+	idx     int
+	scanErr error
+	err     error
+}
+
+func (r *failedJobRows) Next() bool {
+	if r.idx >= len(r.items) {
+		return false
+	}
+	r.idx++
+	return true
+}
+
+func (r *failedJobRows) Scan(dest ...any) error {
+	if len(dest) != 8 {
+		return fmt.Errorf("unexpected scan args: %d", len(dest))
+	}
+	if r.scanErr != nil {
+		return r.scanErr
+	}
+	item := r.items[r.idx-1]
+	*dest[0].(*string) = item.ID
+	*dest[1].(*string) = item.UserID
+	*dest[2].(*string) = item.TaskType
+	*dest[3].(*string) = item.Provider
+	*dest[4].(*int) = item.Attempts
+	*dest[5].(*string) = item.Error
+	*dest[6].(*time.Time) = item.CreatedAt
+	*dest[7].(*time.Time) = item.UpdatedAt
+	return nil
+}
+
+func (r *failedJobRows) Err() error { return r.err }
+
+func (r *failedJobRows) Close() {}
+
+func TestAdminFailedJobs(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	stub := &failedJobsSQL{items: []failedJobDTO{{
+		ID:        "job-1",
+		UserID:    "user-9",
+		TaskType:  "IMAGE_GEN",
+		Provider:  "qwen-image-plus",
+		Attempts:  3,
+		Error:     "image generation: provider unavailable",
+		CreatedAt: created,
+		UpdatedAt: created,
+	}}}
+
+	cases := []struct {
+		name       string
+		claims     *middleware.TokenClaims
+		wantStatus int
+	}{
+		{name: "supporter", claims: &middleware.TokenClaims{Sub: "user-1", Plan: "supporter"}, wantStatus: http.StatusOK},
+		{name: "admin flag", claims: &middleware.TokenClaims{Sub: "user-1", Plan: "free", Admin: true}, wantStatus: http.StatusOK},
+		{name: "free plan", claims: &middleware.TokenClaims{Sub: "user-1", Plan: "free"}, wantStatus: http.StatusForbidden},
+		{name: "anonymous", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub.queried = false
+			app := &App{SQL: stub}
+			req := httptest.NewRequest("GET", "/v1/admin/jobs/failed", nil)
+			req = req.WithContext(middleware.ContextWithClaims(req.Context(), tc.claims))
+			rr := httptest.NewRecorder()
+
+			app.AdminFailedJobs(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				if stub.queried {
+					t.Fatal("expected query to be skipped for unauthorized caller")
+				}
+				return
+			}
+			var payload struct {
+				Items []failedJobDTO `json:"items"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(payload.Items) != 1 {
+				t.Fatalf("expected 1 item, got %d", len(payload.Items))
+			}
+			got := payload.Items[0]
+			if got.ID != "job-1" || got.UserID != "user-9" || got.Attempts != 3 || got.Error != "image generation: provider unavailable" {
+				t.Fatalf("unexpected item: %+v", got)
+			}
+		})
+	}
+}
+
+func TestAdminFailedJobsQueryError(t *testing.T) {
+	app := &App{SQL: &erroringSQL{err: errors.New("boom")}}
+	req := httptest.NewRequest("GET", "/v1/admin/jobs/failed", nil)
+	req = req.WithContext(middleware.ContextWithClaims(req.Context(), &middleware.TokenClaims{Sub: "user-1", Admin: true}))
+	rr := httptest.NewRecorder()
+
+	app.AdminFailedJobs(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rr.Code)
+	}
+}
+
+type erroringSQL struct {
+	err error
+}
+
+func (e *erroringSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, e.err
+}
+
+func (e *erroringSQL) QueryRow(context.Context, string, ...any) pgx.Row {
+	return NewSimpleRow(func(...any) error { return e.err })
+}
+
+func (e *erroringSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, e.err
+}
+
+type seededImageJob struct {
+	provider string
+	status   string
+	latency  time.Duration
+}
+
+// providerStatsSQL groups seeded jobs by provider and status the way
+// QImageJobProviderStats does.
+type providerStatsSQL struct {
+	jobs    []seededImageJob
+	scanErr error
+}
+
+func (s *providerStatsSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *providerStatsSQL) QueryRow(context.Context, string, ...any) pgx.Row {
+	return SimpleRow{}
+}
+
+func (s *providerStatsSQL) Query(_ context.Context, query string, _ ...any) (pgx.Rows, error) {
+	if query != sqlinline.QImageJobProviderStats {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	rows := &providerStatsRows{scanErr: s.scanErr}
+	index := make(map[[2]string]int)
+	for _, job := range s.jobs {
+		key := [2]string{job.provider, job.status}
+		i, ok := index[key]
+		if !ok {
+			i = len(rows.groups)
+			index[key] = i
+			rows.groups = append(rows.groups, providerStatusGroup{provider: job.provider, status: job.status})
+		}
+		rows.groups[i].count++
+		rows.groups[i].latency += job.latency.Seconds()
+	}
+	return rows, nil
+}
+
+type providerStatusGroup struct {
+	provider string
+	status   string
+	count    int64
+	latency  float64
+}
+
+type providerStatsRows struct {
+	TestRowsBase
+	groups  []providerStatusGroup
+	idx     int
+	scanErr error
+}
+
+func (r *providerStatsRows) Next() bool {
+	if r.idx >= len(r.groups) {
+		return false
+	}
+	r.idx++
+	return true
+}
+
+func (r *providerStatsRows) Scan(dest ...any) error {
+	if len(dest) != 4 {
+		return fmt.Errorf("unexpected scan args: %d", len(dest))
+	}
+	if r.scanErr != nil {
+		return r.scanErr
+	}
+	g := r.groups[r.idx-1]
+	*dest[0].(*string) = g.provider
+	*dest[1].(*string) = g.status
+	*dest[2].(*int64) = g.count
+	*dest[3].(*float64) = g.latency
+	return nil
+}
+
+func (r *providerStatsRows) Err() error { return nil }
+
+func (r *providerStatsRows) Close() {}
+
+func TestAdminFailedJobsSurfacesRowErrors(t *testing.T) {
+	for name, stub := range map[string]*failedJobsSQL{
+		"scan":      {items: []failedJobDTO{{ID: "job-1"}}, scanErr: errors.New("cannot scan NULL into *string")},
+		"iteration": {rowsErr: errors.New("connection reset")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			app := &App{SQL: stub}
+			req := httptest.NewRequest("GET", "/v1/admin/jobs/failed", nil)
+			req = req.WithContext(middleware.ContextWithClaims(req.Context(), &middleware.TokenClaims{Sub: "user-1", Admin: true}))
+			rr := httptest.NewRecorder()
+
+			app.AdminFailedJobs(rr, req)
+
+			if rr.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500; body=%s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminProviderStats(t *testing.T) {
+	stub := &providerStatsSQL{jobs: []seededImageJob{
+		{provider: "qwen-image-plus", status: "SUCCEEDED", latency: 10 * time.Second},
+		{provider: "qwen-image-plus", status: "SUCCEEDED", latency: 20 * time.Second},
+		{provider: "qwen-image-plus", status: "FAILED", latency: 3 * time.Second},
+		{provider: "gemini-2.5-flash", status: "SUCCEEDED", latency: 4 * time.Second},
+		{provider: "qwen-image-plus", status: "QUEUED", latency: time.Hour},
+		{provider: "gemini-2.5-flash", status: "FAILED", latency: 2 * time.Second},
+		{provider: "gemini-2.5-flash", status: "RUNNING", latency: time.Hour},
+	}}
+	app := &App{SQL: stub}
+	req := httptest.NewRequest("GET", "/v1/admin/stats/providers", nil)
+	req = req.WithContext(middleware.ContextWithClaims(req.Context(), &middleware.TokenClaims{Sub: "user-1", Admin: true}))
+	rr := httptest.NewRecorder()
+
+	app.AdminProviderStats(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var payload struct {
+		Items []providerStatsDTO `json:"items"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := []providerStatsDTO{
+		{Provider: "gemini-2.5-flash", Total: 3, Succeeded: 1, Failed: 1, SuccessRate: 50, AvgLatencySeconds: 3},
+		{Provider: "qwen-image-plus", Total: 4, Succeeded: 2, Failed: 1, SuccessRate: 66.67, AvgLatencySeconds: 11},
+	}
+	if len(payload.Items) != len(want) {
+		t.Fatalf("items = %+v, want %+v", payload.Items, want)
+	}
+	for i := range want {
+		if payload.Items[i] != want[i] {
+			t.Fatalf("items[%d] = %+v, want %+v", i, payload.Items[i], want[i])
+		}
+	}
+}
+
+func TestAdminProviderStatsRequiresAdmin(t *testing.T) {
+	app := &App{SQL: &erroringSQL{err: errors.New("should not query")}}
+	req := httptest.NewRequest("GET", "/v1/admin/stats/providers", nil)
+	req = req.WithContext(middleware.ContextWithClaims(req.Context(), &middleware.TokenClaims{Sub: "user-1", Plan: "free"}))
+	rr := httptest.NewRecorder()
+
+	app.AdminProviderStats(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", rr.Code)
+	}
+}
+
+func TestAdminProviderStatsSurfacesScanErrors(t *testing.T) {
+	stub := &providerStatsSQL{
+		jobs:    []seededImageJob{{provider: "qwen-image-plus", status: "SUCCEEDED"}},
+		scanErr: errors.New("cannot scan NULL into *float64"),
+	}
+	app := &App{SQL: stub}
+	req := httptest.NewRequest("GET", "/v1/admin/stats/providers", nil)
+	req = req.WithContext(middleware.ContextWithClaims(req.Context(), &middleware.TokenClaims{Sub: "user-1", Admin: true}))
+	rr := httptest.NewRecorder()
+
+	app.AdminProviderStats(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500; body=%s", rr.Code, rr.Body.String())
+	}
+}
@@ -13,13 +13,18 @@ import (
 	"server/internal/infra/credentials"
 	"server/internal/infra/geoip"
 	googleauth "server/internal/infra/google"
+	"server/internal/metrics"
 	"server/internal/middleware"
+	"server/internal/moderation"
+	"server/internal/netguard"
+	"server/internal/providers/breaker"
 	"server/internal/providers/genai"
 	"server/internal/providers/image"
 	"server/internal/providers/prompt"
 	"server/internal/providers/qwen"
 	"server/internal/providers/video"
 	"server/internal/storage"
+	"server/internal/webhook"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog"
@@ -31,16 +36,21 @@ type App struct {
 	DB                  db.DBTX
 	SQL                 infra.SQLExecutor
 	GeoIPResolver       geoip.CountryResolver
-	GoogleVerifier      *googleauth.Verifier
+	GoogleVerifier      IDTokenVerifier
 	PromptEnhancer      prompt.Enhancer
+	Moderator           *moderation.Moderator
 	ImageProviders      map[string]image.Generator
 	VideoProviders      map[string]video.Generator
 	JWTSecret           string
-	FileStore           *storage.FileStore
+	Storage             storage.Storage
 	ImageEditor         imagegen.Editor
+	urlSigner           *storage.URLSigner
 	imageLimiter        chan struct{}
+	userImageSlots      *userSlots
 	sourceHostAllowlist map[string]struct{}
+	trustedHosts        map[string]struct{}
 	sourceFetcher       httpDoer
+	callbackNotifier    *webhook.Notifier
 }
 
 type httpDoer interface {
@@ -55,7 +65,6 @@ func NewApp(cfg *infra.Config, pool *pgxpool.Pool, logger zerolog.Logger) *App {
 	}
 	credentialStore := credentials.NewStore(runner)
 	staticEnhancer := prompt.NewStaticEnhancer()
-	var promptProvider prompt.Enhancer = staticEnhancer
 
 	loadKey := func(envValue string, getter func(context.Context) (string, error), setter func(context.Context, string) error, provider string) string {
 		ctxLoad, cancelLoad := context.WithTimeout(context.Background(), 2*time.Second)
@@ -78,148 +87,135 @@ func NewApp(cfg *infra.Config, pool *pgxpool.Pool, logger zerolog.Logger) *App {
 		return strings.TrimSpace(keyFromDB)
 	}
 
-	providerChoice := strings.TrimSpace(strings.ToLower(cfg.PromptProvider))
-
-	// Preload provider credentials so we can wire graceful fallbacks (Gemini -> OpenAI -> Static).
 	openaiKey := loadKey(cfg.OpenAIAPIKey, credentialStore.OpenAIAPIKey, credentialStore.SetOpenAIAPIKey, credentials.ProviderOpenAI)
-	var openaiEnhancer prompt.Enhancer
-	if openaiKey != "" {
-		enhancer, err := prompt.NewOpenAIEnhancer(prompt.OpenAIOptions{
-			APIKey:       openaiKey,
-			Model:        cfg.OpenAIModel,
-			BaseURL:      cfg.OpenAIBaseURL,
-			Organization: cfg.OpenAIOrg,
-			HTTPClient:   &http.Client{Timeout: 15 * time.Second},
-			Fallback:     staticEnhancer,
-			OnFallback: func(reason string, err error) {
-				evt := logger.Info().Str("provider", credentials.ProviderOpenAI).Str("reason", reason)
-				if err != nil {
-					evt = evt.Err(err)
-				}
-				evt.Msg("openai enhancer fallback")
-			},
-			OnWarning: func(reason, detail string) {
-				logger.Warn().
-					Str("provider", credentials.ProviderOpenAI).
-					Str("reason", reason).
-					Str("detail", detail).
-					Msg("openai enhancer normalization")
-			},
-		})
-		if err != nil {
-			logger.Warn().Err(err).Str("provider", credentials.ProviderOpenAI).Msg("failed to initialize openai enhancer, falling back to static prompts")
-		} else {
-			openaiEnhancer = enhancer
-		}
-	}
-
 	qwenKey := loadKey(cfg.QwenAPIKey, credentialStore.QwenAPIKey, credentialStore.SetQwenAPIKey, credentials.ProviderQwen)
 	geminiKey := loadKey(cfg.GeminiAPIKey, credentialStore.GeminiAPIKey, credentialStore.SetGeminiAPIKey, credentials.ProviderGemini)
-	var geminiEnhancer prompt.Enhancer
-	if geminiKey != "" {
-		geminiFallback := prompt.Enhancer(staticEnhancer)
-		if openaiEnhancer != nil {
-			geminiFallback = openaiEnhancer
-		}
-		enhancer, err := prompt.NewGeminiEnhancer(prompt.GeminiOptions{
-			APIKey:     geminiKey,
-			Model:      cfg.GeminiModel,
-			BaseURL:    cfg.GeminiBaseURL,
-			HTTPClient: &http.Client{Timeout: 15 * time.Second},
-			Fallback:   geminiFallback,
-			OnFallback: func(reason string, err error) {
-				evt := logger.Info().Str("provider", credentials.ProviderGemini).Str("reason", reason)
-				if err != nil {
-					evt = evt.Err(err)
-				}
-				evt.Msg("gemini enhancer fallback")
-			},
-		})
-		if err != nil {
-			logger.Warn().Err(err).Str("provider", credentials.ProviderGemini).Msg("failed to initialize gemini enhancer, falling back to static prompts")
-		} else {
-			geminiEnhancer = enhancer
-		}
-	}
 
-	switch providerChoice {
-	case credentials.ProviderOpenAI:
-		switch {
-		case openaiEnhancer != nil:
-			promptProvider = openaiEnhancer
-		case geminiEnhancer != nil:
-			logger.Warn().Str("provider", credentials.ProviderOpenAI).Msg("openai api key unavailable; using gemini enhancer instead")
-			promptProvider = geminiEnhancer
-		default:
-			logger.Warn().Str("provider", credentials.ProviderOpenAI).Msg("openai api key missing; prompt enhancer will use static provider")
-		}
-	case credentials.ProviderGemini, "":
-		switch {
-		case geminiEnhancer != nil:
-			promptProvider = geminiEnhancer
-		case openaiEnhancer != nil:
-			logger.Warn().Str("provider", credentials.ProviderGemini).Msg("gemini api key unavailable; using openai enhancer instead")
-			promptProvider = openaiEnhancer
-		default:
-			logger.Warn().Str("provider", credentials.ProviderGemini).Msg("gemini api key missing; prompt enhancer will use static provider")
-		}
-	case "static":
-		logger.Info().Msg("prompt provider configured as static; dynamic prompts disabled")
-	default:
-		logger.Warn().Str("provider", providerChoice).Msg("unknown prompt provider; using static prompts")
+	promptProvider := buildPromptChain(cfg.PromptProviderChain, staticEnhancer, map[string]promptEnhancerFactory{
+		infra.PromptProviderOpenAI: func(fallback prompt.Enhancer) (prompt.Enhancer, error) {
+			if openaiKey == "" {
+				return nil, nil
+			}
+			return prompt.NewOpenAIEnhancer(prompt.OpenAIOptions{
+				APIKey:         openaiKey,
+				Model:          cfg.OpenAIModel,
+				SecondaryModel: cfg.OpenAISecondaryModel,
+				BaseURL:        cfg.OpenAIBaseURL,
+				Organization:   cfg.OpenAIOrg,
+				HTTPClient:     &http.Client{Timeout: 15 * time.Second},
+				Fallback:       fallback,
+				OnFallback: func(reason string, err error) {
+					metrics.EnhancerFallbacks.Inc(credentials.ProviderOpenAI, reason)
+					evt := logger.Info().Str("provider", credentials.ProviderOpenAI).Str("reason", reason)
+					if err != nil {
+						evt = evt.Err(err)
+					}
+					evt.Msg("openai enhancer fallback")
+				},
+				OnWarning: func(reason, detail string) {
+					logger.Warn().
+						Str("provider", credentials.ProviderOpenAI).
+						Str("reason", reason).
+						Str("detail", detail).
+						Msg("openai enhancer normalization")
+				},
+			})
+		},
+		infra.PromptProviderGemini: func(fallback prompt.Enhancer) (prompt.Enhancer, error) {
+			if geminiKey == "" {
+				return nil, nil
+			}
+			return prompt.NewGeminiEnhancer(prompt.GeminiOptions{
+				APIKey:     geminiKey,
+				Model:      cfg.GeminiModel,
+				BaseURL:    cfg.GeminiBaseURL,
+				HTTPClient: &http.Client{Timeout: 15 * time.Second},
+				Fallback:   fallback,
+				OnFallback: func(reason string, err error) {
+					metrics.EnhancerFallbacks.Inc(credentials.ProviderGemini, reason)
+					evt := logger.Info().Str("provider", credentials.ProviderGemini).Str("reason", reason)
+					if err != nil {
+						evt = evt.Err(err)
+					}
+					evt.Msg("gemini enhancer fallback")
+				},
+			})
+		},
+	}, logger)
+
+	moderationKey := ""
+	if cfg.ModerationOpenAI {
+		moderationKey = openaiKey
 	}
+	moderator := moderation.New(moderation.Options{
+		Denylist:      cfg.ModerationDenylist,
+		OpenAIAPIKey:  moderationKey,
+		OpenAIBaseURL: cfg.OpenAIBaseURL,
+	})
 
 	geminiClient, err := genai.NewClient(genai.Options{
-		APIKey:     geminiKey,
-		BaseURL:    cfg.GeminiBaseURL,
-		Model:      cfg.GeminiModel,
-		HTTPClient: &http.Client{Timeout: 30 * time.Second},
-		Logger:     &logger,
+		APIKey:                   geminiKey,
+		BaseURL:                  cfg.GeminiBaseURL,
+		Model:                    cfg.GeminiModel,
+		HTTPClient:               &http.Client{Timeout: 30 * time.Second},
+		Logger:                   &logger,
+		DisableSyntheticFallback: !cfg.SyntheticFallback,
+		SyntheticVideoSeconds:    int(cfg.SyntheticVideoLength / time.Second),
 	})
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to configure gemini client")
 	}
 
-	if geminiKey == "" {
+	if geminiKey == "" && cfg.SyntheticFallback {
 		logger.Warn().Str("model", geminiClient.Model()).Msg("gemini api key missing; synthetic media assets will be generated")
+	} else if geminiKey == "" {
+		logger.Warn().Str("model", geminiClient.Model()).Msg("gemini api key missing; synthetic fallback disabled, gemini jobs will fail")
 	}
 
 	qwenClient, err := qwen.NewClient(qwen.Options{
-		APIKey:         qwenKey,
-		BaseURL:        cfg.QwenBaseURL,
-		Model:          cfg.QwenModel,
-		DefaultSize:    cfg.QwenDefaultSize,
-		PromptExtend:   true,
-		Watermark:      false,
-		HTTPClient:     &http.Client{Timeout: 45 * time.Second},
-		Logger:         &logger,
-		RequestTimeout: 45 * time.Second,
+		APIKey:                   qwenKey,
+		BaseURL:                  cfg.QwenBaseURL,
+		RegionBaseURLs:           cfg.QwenRegionBaseURLs,
+		Model:                    cfg.QwenModel,
+		VideoModel:               cfg.QwenVideoModel,
+		DefaultSize:              cfg.QwenDefaultSize,
+		PromptExtend:             true,
+		Watermark:                false,
+		HTTPClient:               &http.Client{Timeout: 45 * time.Second},
+		Logger:                   &logger,
+		RequestTimeout:           45 * time.Second,
+		DisableSyntheticFallback: !cfg.SyntheticFallback,
 	})
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to configure qwen client")
 	}
-	if !qwenClient.HasCredentials() {
+	if !qwenClient.HasCredentials() && !cfg.SyntheticFallback {
+		logger.Warn().Str("model", qwenClient.Model()).Msg("qwen api key missing; synthetic fallback disabled, qwen jobs will fail")
+	} else if !qwenClient.HasCredentials() {
 		logger.Warn().Str("model", qwenClient.Model()).Msg("qwen api key missing; worker will fall back to synthetic assets")
 	}
 
 	geminiImage := image.NewGeminiGenerator(geminiClient)
 	geminiVideo := video.NewGeminiGenerator(geminiClient)
-	qwenImage := image.NewQwenGenerator(qwenClient, geminiImage)
-
-	fileStore, err := storage.NewFileStore(cfg.StoragePath)
-	if err != nil {
-		logger.Fatal().Err(err).Msg("failed to configure storage root")
+	qwenImage := image.NewQwenGenerator(qwenClient, geminiImage).WithBreaker(breaker.New(breaker.Options{
+		Threshold:     cfg.QwenBreakerThreshold,
+		Cooldown:      cfg.QwenBreakerCooldown,
+		OnStateChange: breaker.LogStateChanges(logger, "qwen"),
+	}))
+	qwenVideo := video.NewQwenGenerator(qwenClient, geminiVideo)
+	openaiImageOpts := image.OpenAIOptions{
+		APIKey:       openaiKey,
+		BaseURL:      cfg.OpenAIBaseURL,
+		Organization: cfg.OpenAIOrg,
 	}
+	openaiImageOpts.Model = "gpt-image-1"
+	gptImage := image.NewOpenAIGenerator(openaiImageOpts, geminiImage)
+	openaiImageOpts.Model = "dall-e-3"
+	dallEImage := image.NewOpenAIGenerator(openaiImageOpts, geminiImage)
 
-	imageProviders := map[string]image.Generator{
-		"qwen":                              qwenImage,
-		"qwen-image":                        qwenImage,
-		"qwen-image-plus":                   qwenImage,
-		strings.ToLower(qwenClient.Model()): qwenImage,
-		"gemini":                            geminiImage,
-		"gemini-1.5-flash":                  geminiImage,
-		"gemini-2.0-flash":                  geminiImage,
-		"gemini-2.5-flash":                  geminiImage,
+	store, err := storage.New(cfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to configure storage")
 	}
 
 	imageEditor := imagegen.NewQwenClient(imagegen.QwenOptions{
@@ -228,34 +224,61 @@ func NewApp(cfg *infra.Config, pool *pgxpool.Pool, logger zerolog.Logger) *App {
 		HTTPClient: &http.Client{Timeout: 60 * time.Second},
 	})
 
-	allowedHosts := make(map[string]struct{})
-	for _, host := range cfg.ImageSourceAllowlist {
-		if normalized := strings.ToLower(strings.TrimSpace(host)); normalized != "" {
-			allowedHosts[normalized] = struct{}{}
-		}
-	}
+	trustedHosts := netguard.Allowlist(cfg.TrustedHosts)
 
 	return &App{
-		Config:         cfg,
-		Logger:         logger,
-		DB:             pool,
-		SQL:            runner,
-		GeoIPResolver:  geoResolver,
-		GoogleVerifier: googleauth.NewVerifier(cfg.GoogleIssuer, cfg.GoogleClientID),
-		PromptEnhancer: promptProvider,
-		ImageProviders: imageProviders,
-		VideoProviders: map[string]video.Generator{
-			"gemini":           geminiVideo,
-			"gemini-1.5-flash": geminiVideo,
-			"gemini-2.0-flash": geminiVideo,
-			"gemini-2.5-flash": geminiVideo,
-		},
+		Config:              cfg,
+		Logger:              logger,
+		DB:                  pool,
+		SQL:                 runner,
+		GeoIPResolver:       geoResolver,
+		GoogleVerifier:      googleauth.NewVerifier(cfg.GoogleIssuer, cfg.GoogleClientIDs...),
+		PromptEnhancer:      promptProvider,
+		Moderator:           moderator,
+		ImageProviders:      imageProviderSet(qwenClient.Model(), qwenImage, geminiImage, gptImage, dallEImage),
+		VideoProviders:      videoProviderSet(qwenClient.VideoModel(), qwenVideo, geminiVideo),
 		JWTSecret:           cfg.JWTSecret,
-		FileStore:           fileStore,
+		Storage:             store,
 		ImageEditor:         imageEditor,
-		imageLimiter:        make(chan struct{}, 2),
-		sourceHostAllowlist: allowedHosts,
-		sourceFetcher:       &http.Client{Timeout: 20 * time.Second},
+		urlSigner:           storage.NewURLSigner(cfg.StorageSignedBaseURL, cfg.StorageSigningSecret),
+		imageLimiter:        make(chan struct{}, cfg.ImageConcurrency),
+		userImageSlots:      newUserSlots(),
+		sourceHostAllowlist: netguard.Allowlist(cfg.ImageSourceAllowlist),
+		trustedHosts:        trustedHosts,
+		sourceFetcher:       newSourceFetcher(trustedHosts, nil),
+		callbackNotifier:    webhook.NewNotifier(trustedHosts),
+	}
+}
+
+// imageProviderSet maps every provider key accepted by image requests to its
+// generator, including the configured Qwen model name.
+func imageProviderSet(qwenModel string, qwenImage, geminiImage, gptImage, dallEImage image.Generator) map[string]image.Generator {
+	return map[string]image.Generator{
+		"qwen":                     qwenImage,
+		"qwen-image":               qwenImage,
+		"qwen-image-plus":          qwenImage,
+		strings.ToLower(qwenModel): qwenImage,
+		"gemini":                   geminiImage,
+		"gemini-1.5-flash":         geminiImage,
+		"gemini-2.0-flash":         geminiImage,
+		"gemini-2.5-flash":         geminiImage,
+		"openai":                   gptImage,
+		"gpt-image-1":              gptImage,
+		"dall-e-3":                 dallEImage,
+	}
+}
+
+// videoProviderSet maps every provider key accepted by video requests to its
+// generator, including the configured Qwen video model name.
+func videoProviderSet(qwenVideoModel string, qwenVideo, geminiVideo video.Generator) map[string]video.Generator {
+	return map[string]video.Generator{
+		"qwen":                          qwenVideo,
+		"wan":                           qwenVideo,
+		strings.ToLower(qwenVideoModel): qwenVideo,
+		"gemini":                        geminiVideo,
+		"gemini-1.5-flash":              geminiVideo,
+		"gemini-2.0-flash":              geminiVideo,
+		"gemini-2.5-flash":              geminiVideo,
 	}
 }
 
@@ -268,7 +291,7 @@ func (a *App) json(w http.ResponseWriter, code int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-func (a *App) error(w http.ResponseWriter, status int, code, message string) {
+func (a *App) error(w http.ResponseWriter, status int, code ErrorCode, message string) {
 	a.json(w, status, map[string]any{
 		"error": map[string]any{
 			"code":    code,
@@ -286,6 +309,13 @@ func (a *App) assetURL(storageKey string) string {
 	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "data:") {
 		return storageKey
 	}
+	if a.urlSigner != nil {
+		signed, err := a.urlSigner.SignedURL(storageKey, a.Config.StorageSignedURLTTL)
+		if err == nil {
+			return signed
+		}
+		a.Logger.Warn().Err(err).Str("storage_key", storageKey).Msg("sign asset url failed")
+	}
 	base := strings.TrimRight(a.Config.StorageBaseURL, "/")
 	key := strings.TrimLeft(storageKey, "/")
 	return base + "/" + key
@@ -294,3 +324,13 @@ func (a *App) assetURL(storageKey string) string {
 func (a *App) currentUserID(r *http.Request) string {
 	return middleware.UserIDFromContext(r.Context())
 }
+
+// isAdmin reports whether the caller may use operator endpoints: supporters
+// and tokens carrying the admin flag.
+func (a *App) isAdmin(r *http.Request) bool {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil {
+		return false
+	}
+	return claims.Admin || strings.EqualFold(claims.Plan, "supporter")
+}
@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io/fs"
 	"net/http"
+	"net/url"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"server/internal/sqlinline"
+	"server/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -14,7 +21,7 @@ import (
 func (a *App) ListAssets(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
@@ -24,7 +31,7 @@ func (a *App) ListAssets(w http.ResponseWriter, r *http.Request) {
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 	rows, err := a.SQL.Query(r.Context(), sqlinline.QListAssetsByUser, userID, limit, offset)
 	if err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to load assets")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load assets")
 		return
 	}
 	defer rows.Close()
@@ -40,16 +47,17 @@ func (a *App) ListAssets(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		items = append(items, map[string]any{
-			"id":           id,
-			"request_id":   requestID,
-			"storage_key":  storageKey,
-			"mime":         mime,
-			"bytes":        bytes,
-			"width":        width,
-			"height":       height,
-			"aspect_ratio": aspect,
-			"properties":   json.RawMessage(props),
-			"created_at":   createdAt,
+			"id":            id,
+			"request_id":    requestID,
+			"storage_key":   storageKey,
+			"mime":          mime,
+			"bytes":         bytes,
+			"width":         width,
+			"height":        height,
+			"aspect_ratio":  aspect,
+			"properties":    json.RawMessage(props),
+			"thumbnail_url": a.thumbnailURL(props),
+			"created_at":    createdAt,
 		})
 	}
 	a.json(w, http.StatusOK, map[string]any{"items": items})
@@ -58,7 +66,7 @@ func (a *App) ListAssets(w http.ResponseWriter, r *http.Request) {
 func (a *App) DownloadAsset(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	assetID := chi.URLParam(r, "id")
@@ -69,11 +77,11 @@ func (a *App) DownloadAsset(w http.ResponseWriter, r *http.Request) {
 	var aspect string
 	var props []byte
 	if err := row.Scan(&id, &ownerID, &storageKey, &mime, &bytes, &width, &height, &aspect, &props); err != nil {
-		a.error(w, http.StatusNotFound, "not_found", "asset not found")
+		a.error(w, http.StatusNotFound, ErrNotFound, "asset not found")
 		return
 	}
 	if ownerID != userID {
-		a.error(w, http.StatusForbidden, "forbidden", "not your asset")
+		a.error(w, http.StatusForbidden, ErrForbidden, "not your asset")
 		return
 	}
 	a.json(w, http.StatusOK, map[string]any{
@@ -84,3 +92,166 @@ func (a *App) DownloadAsset(w http.ResponseWriter, r *http.Request) {
 		"aspect_ratio": aspect,
 	})
 }
+
+// ServeAsset streams a stored asset owned by the caller. Range requests are
+// honoured so large videos can be scrubbed without downloading them whole.
+func (a *App) ServeAsset(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	key, ok := assetKeyParam(chi.URLParam(r, "*"))
+	if !ok {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid asset key")
+		return
+	}
+	if a.Storage == nil {
+		a.error(w, http.StatusServiceUnavailable, ErrStorageUnavailable, "asset storage not configured")
+		return
+	}
+	var id, ownerID, mime string
+	if err := a.SQL.QueryRow(r.Context(), sqlinline.QSelectAssetByStorageKey, key).Scan(&id, &ownerID, &mime); err != nil {
+		a.error(w, http.StatusNotFound, ErrNotFound, "asset not found")
+		return
+	}
+	if ownerID != userID {
+		a.error(w, http.StatusForbidden, ErrForbidden, "not your asset")
+		return
+	}
+	a.serveStoredAsset(w, r, key, mime)
+}
+
+// ServeSignedAsset streams the asset named by a link from assetURL when
+// STORAGE_SIGNING_SECRET is set. The link itself is the credential, so no
+// bearer token is needed; expired or altered links get 403.
+func (a *App) ServeSignedAsset(w http.ResponseWriter, r *http.Request) {
+	if a.urlSigner == nil {
+		a.error(w, http.StatusNotFound, ErrNotFound, "signed urls are not enabled")
+		return
+	}
+	key, ok := assetKeyParam(chi.URLParam(r, "*"))
+	if !ok {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid asset key")
+		return
+	}
+	query := r.URL.Query()
+	if err := a.urlSigner.Verify(key, query.Get("expires"), query.Get("sig")); err != nil {
+		msg := "invalid signature"
+		if errors.Is(err, storage.ErrSignatureExpired) {
+			msg = "link expired"
+		}
+		a.error(w, http.StatusForbidden, ErrForbidden, msg)
+		return
+	}
+	if a.Storage == nil {
+		a.error(w, http.StatusServiceUnavailable, ErrStorageUnavailable, "asset storage not configured")
+		return
+	}
+	w.Header().Set("Cache-Control", "private, no-store")
+	a.serveStoredAsset(w, r, key, "")
+}
+
+// serveStoredAsset writes key from storage with range support. An empty mime
+// lets http.ServeContent infer the type from the extension.
+func (a *App) serveStoredAsset(w http.ResponseWriter, r *http.Request, key, mime string) {
+	if mime != "" {
+		w.Header().Set("Content-Type", mime)
+	}
+	opener, ok := a.Storage.(storage.Opener)
+	if !ok {
+		data, err := a.Storage.Read(r.Context(), key)
+		if err != nil {
+			a.assetReadError(w, err)
+			return
+		}
+		http.ServeContent(w, r, path.Base(key), time.Time{}, bytes.NewReader(data))
+		return
+	}
+	file, err := opener.Open(r.Context(), key)
+	if err != nil {
+		a.assetReadError(w, err)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to open asset")
+		return
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+func (a *App) assetReadError(w http.ResponseWriter, err error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		a.error(w, http.StatusNotFound, ErrNotFound, "asset not found")
+		return
+	}
+	a.error(w, http.StatusInternalServerError, ErrInternal, "failed to open asset")
+}
+
+// assetKeyParam unescapes the wildcard path segment and rejects keys that are
+// absolute or contain parent directory references.
+func assetKeyParam(raw string) (string, bool) {
+	key, err := url.PathUnescape(raw)
+	if err != nil {
+		return "", false
+	}
+	key = strings.TrimSpace(key)
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", false
+		}
+	}
+	return key, true
+}
+
+// DeleteAsset soft-deletes an asset owned by the caller. The stored bytes are
+// removed later by the worker once the purge grace period has passed.
+func (a *App) DeleteAsset(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	assetID := chi.URLParam(r, "id")
+	row := a.SQL.QueryRow(r.Context(), sqlinline.QSelectAssetByID, assetID)
+	var id, ownerID, storageKey, mime string
+	var bytes int64
+	var width, height int
+	var aspect string
+	var props []byte
+	if err := row.Scan(&id, &ownerID, &storageKey, &mime, &bytes, &width, &height, &aspect, &props); err != nil {
+		a.error(w, http.StatusNotFound, ErrNotFound, "asset not found")
+		return
+	}
+	if ownerID != userID {
+		a.error(w, http.StatusForbidden, ErrForbidden, "not your asset")
+		return
+	}
+	tag, err := a.SQL.Exec(r.Context(), sqlinline.QSoftDeleteAsset, id, userID)
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to delete asset")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		a.error(w, http.StatusNotFound, ErrNotFound, "asset not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// thumbnailURL resolves the gallery preview recorded in asset properties by
+// the worker, or returns an empty string when none was generated.
+func (a *App) thumbnailURL(props []byte) string {
+	var meta struct {
+		ThumbnailKey string `json:"thumbnail_key"`
+	}
+	if len(props) == 0 || json.Unmarshal(props, &meta) != nil {
+		return ""
+	}
+	return a.assetURL(meta.ThumbnailKey)
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/infra"
+	"server/internal/sqlinline"
+	"server/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+type assetOwnerSQL struct {
+	key     string
+	ownerID string
+	mime    string
+}
+
+func (s *assetOwnerSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *assetOwnerSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if query != sqlinline.QSelectAssetByStorageKey || args[0] != s.key {
+		return NewSimpleRow(nil)
+	}
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = "asset-1"
+		*dest[1].(*string) = s.ownerID
+		*dest[2].(*string) = s.mime
+		return nil
+	})
+}
+
+func (s *assetOwnerSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newAssetServingApp(t *testing.T) *App {
+	t.Helper()
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	key := "videos/user-123/clip.mp4"
+	if _, err := store.Write(context.Background(), key, []byte("0123456789")); err != nil {
+		t.Fatalf("write asset: %v", err)
+	}
+	return &App{
+		Config:  &infra.Config{},
+		Logger:  zerolog.Nop(),
+		SQL:     &assetOwnerSQL{key: key, ownerID: "user-123", mime: "video/mp4"},
+		Storage: store,
+	}
+}
+
+func TestServeAssetFullContent(t *testing.T) {
+	app := newAssetServingApp(t)
+	req := requestWithParam("GET", "/v1/assets/videos/user-123/clip.mp4", "*", "videos/user-123/clip.mp4", "user-123")
+	rr := httptest.NewRecorder()
+	app.ServeAsset(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Body.String(); got != "0123456789" {
+		t.Fatalf("body = %q", got)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "video/mp4" {
+		t.Fatalf("content type = %q", got)
+	}
+	if got := rr.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Fatalf("accept ranges = %q", got)
+	}
+}
+
+func TestServeAssetHonoursRange(t *testing.T) {
+	app := newAssetServingApp(t)
+	req := requestWithParam("GET", "/v1/assets/videos/user-123/clip.mp4", "*", "videos/user-123/clip.mp4", "user-123")
+	req.Header.Set("Range", "bytes=2-5")
+	rr := httptest.NewRecorder()
+	app.ServeAsset(rr, req)
+
+	if rr.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206; body=%s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Body.String(); got != "2345" {
+		t.Fatalf("body = %q, want 2345", got)
+	}
+	if got := rr.Header().Get("Content-Range"); got != "bytes 2-5/10" {
+		t.Fatalf("content range = %q", got)
+	}
+}
+
+// readOnlyStore hides FileStore.Open so ServeAsset takes the buffered path
+// used for object storage backends.
+type readOnlyStore struct {
+	storage.Storage
+}
+
+func TestServeAssetWithoutOpenerHonoursRange(t *testing.T) {
+	app := newAssetServingApp(t)
+	app.Storage = readOnlyStore{app.Storage}
+	req := requestWithParam("GET", "/v1/assets/videos/user-123/clip.mp4", "*", "videos/user-123/clip.mp4", "user-123")
+	req.Header.Set("Range", "bytes=2-5")
+	rr := httptest.NewRecorder()
+	app.ServeAsset(rr, req)
+
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "2345" {
+		t.Fatalf("status = %d body = %q, want 206 2345", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Type"); got != "video/mp4" {
+		t.Fatalf("content type = %q", got)
+	}
+}
+
+func TestServeAssetRejectsTraversal(t *testing.T) {
+	app := newAssetServingApp(t)
+	for _, key := range []string{"../etc/passwd", "videos/%2e%2e/%2e%2e/secret", "/etc/passwd", `videos\..\secret`} {
+		req := requestWithParam("GET", "/v1/assets/x", "*", key, "user-123")
+		rr := httptest.NewRecorder()
+		app.ServeAsset(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("key %q: status = %d, want 400", key, rr.Code)
+		}
+	}
+}
+
+func TestServeAssetRejectsOtherUsers(t *testing.T) {
+	app := newAssetServingApp(t)
+	req := requestWithParam("GET", "/v1/assets/videos/user-123/clip.mp4", "*", "videos/user-123/clip.mp4", "user-456")
+	rr := httptest.NewRecorder()
+	app.ServeAsset(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", rr.Code)
+	}
+}
+
+type softDeleteSQL struct {
+	ownerID string
+	deleted bool
+	execs   int
+}
+
+func (s *softDeleteSQL) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	if query != sqlinline.QSoftDeleteAsset {
+		return pgconn.CommandTag{}, errors.New("unexpected exec")
+	}
+	s.execs++
+	if s.deleted || args[1] != s.ownerID {
+		return pgconn.NewCommandTag("UPDATE 0"), nil
+	}
+	s.deleted = true
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+// QueryRow mirrors QSelectAssetByID's deleted_at filter: once soft deleted the
+// asset is no longer found.
+func (s *softDeleteSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if query != sqlinline.QSelectAssetByID || args[0] != "asset-1" || s.deleted {
+		return NewSimpleRow(nil)
+	}
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = "asset-1"
+		*dest[1].(*string) = s.ownerID
+		*dest[2].(*string) = "images/job/1.png"
+		*dest[3].(*string) = "image/png"
+		*dest[4].(*int64) = 3
+		*dest[5].(*int) = 1
+		*dest[6].(*int) = 1
+		*dest[7].(*string) = "1:1"
+		*dest[8].(*[]byte) = []byte(`{}`)
+		return nil
+	})
+}
+
+func (s *softDeleteSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestDeleteAssetSoftDeletesOwnedAsset(t *testing.T) {
+	sqlStub := &softDeleteSQL{ownerID: "user-123"}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: sqlStub}
+
+	deleteAsset := func(assetID, userID string) int {
+		req := requestWithParam("DELETE", "/v1/assets/"+assetID, "id", assetID, userID)
+		rr := httptest.NewRecorder()
+		app.DeleteAsset(rr, req)
+		return rr.Code
+	}
+
+	if code := deleteAsset("asset-1", "user-456"); code != http.StatusForbidden {
+		t.Fatalf("other user status = %d, want 403", code)
+	}
+	if sqlStub.execs != 0 {
+		t.Fatalf("soft delete ran for a foreign asset")
+	}
+	if code := deleteAsset("missing", "user-123"); code != http.StatusNotFound {
+		t.Fatalf("missing asset status = %d, want 404", code)
+	}
+	if code := deleteAsset("asset-1", "user-123"); code != http.StatusNoContent {
+		t.Fatalf("owner status = %d, want 204", code)
+	}
+	if !sqlStub.deleted {
+		t.Fatalf("asset was not soft deleted")
+	}
+	if code := deleteAsset("asset-1", "user-123"); code != http.StatusNotFound {
+		t.Fatalf("repeat delete status = %d, want 404", code)
+	}
+}
+
+func TestDownloadAssetHidesDeletedAsset(t *testing.T) {
+	if !strings.Contains(sqlinline.QSelectAssetByID, "not (properties ? 'deleted_at')") {
+		t.Fatal("QSelectAssetByID must skip soft-deleted assets")
+	}
+	sqlStub := &softDeleteSQL{ownerID: "user-123"}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: sqlStub}
+	download := func() int {
+		rr := httptest.NewRecorder()
+		app.DownloadAsset(rr, requestWithParam("GET", "/v1/assets/asset-1/download", "id", "asset-1", "user-123"))
+		return rr.Code
+	}
+
+	if code := download(); code != http.StatusOK {
+		t.Fatalf("download before delete status = %d, want 200", code)
+	}
+	rr := httptest.NewRecorder()
+	app.DeleteAsset(rr, requestWithParam("DELETE", "/v1/assets/asset-1", "id", "asset-1", "user-123"))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %d, want 204", rr.Code)
+	}
+	if code := download(); code != http.StatusNotFound {
+		t.Fatalf("download after delete status = %d, want 404", code)
+	}
+}
+
+func signedAssetRequest(t *testing.T, raw string) *http.Request {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse signed url %q: %v", raw, err)
+	}
+	return requestWithParam("GET", u.RequestURI(), "*", strings.TrimPrefix(u.Path, "/v1/files/"), "")
+}
+
+func TestServeSignedAsset(t *testing.T) {
+	app := newAssetServingApp(t)
+	app.urlSigner = storage.NewURLSigner("http://localhost:8080/v1/files", "signing-secret")
+	key := "videos/user-123/clip.mp4"
+
+	app.Config.StorageSignedURLTTL = time.Minute
+	valid := app.assetURL(key)
+	if !strings.HasPrefix(valid, "http://localhost:8080/v1/files/videos/user-123/clip.mp4?expires=") {
+		t.Fatalf("assetURL = %q, want signed link", valid)
+	}
+	app.Config.StorageSignedURLTTL = -time.Minute
+	expired := app.assetURL(key)
+	tampered := strings.Replace(valid, "clip.mp4", "other.mp4", 1)
+
+	cases := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{name: "valid", url: valid, wantStatus: http.StatusOK},
+		{name: "expired", url: expired, wantStatus: http.StatusForbidden},
+		{name: "tampered", url: tampered, wantStatus: http.StatusForbidden},
+		{name: "unsigned", url: "http://localhost:8080/v1/files/" + key, wantStatus: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			app.ServeSignedAsset(rr, signedAssetRequest(t, tc.url))
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			if tc.wantStatus == http.StatusOK && rr.Body.String() != "0123456789" {
+				t.Fatalf("body = %q", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestServeSignedAssetDisabledWithoutSecret(t *testing.T) {
+	app := newAssetServingApp(t)
+	if got := app.assetURL("videos/user-123/clip.mp4"); got != "/videos/user-123/clip.mp4" {
+		t.Fatalf("assetURL = %q, want unsigned link", got)
+	}
+	rr := httptest.NewRecorder()
+	app.ServeSignedAsset(rr, signedAssetRequest(t, "http://localhost:8080/v1/files/videos/user-123/clip.mp4?expires=1&sig=x"))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rr.Code)
+	}
+}
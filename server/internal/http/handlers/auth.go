@@ -3,20 +3,33 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"server/internal/middleware"
 	"server/internal/sqlinline"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
+// IDTokenVerifier checks a Google ID token and returns its claims.
+type IDTokenVerifier interface {
+	VerifyIDToken(ctx context.Context, token string) (map[string]any, error)
+}
+
 type googleVerifyRequest struct {
 	IDToken string `json:"id_token"`
 }
 
 type googleVerifyResponse struct {
-	Token string         `json:"token"`
-	User  userProfileDTO `json:"user"`
+	Token        string         `json:"token"`
+	RefreshToken string         `json:"refresh_token"`
+	User         userProfileDTO `json:"user"`
 }
 
 type userProfileDTO struct {
@@ -31,12 +44,11 @@ type userProfileDTO struct {
 
 func (a *App) AuthGoogleVerify(w http.ResponseWriter, r *http.Request) {
 	var req googleVerifyRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "invalid payload")
+	if !a.decodeJSON(w, r, &req) {
 		return
 	}
 	if req.IDToken == "" {
-		a.error(w, http.StatusBadRequest, "bad_request", "id_token required")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "id_token required")
 		return
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
@@ -44,11 +56,22 @@ func (a *App) AuthGoogleVerify(w http.ResponseWriter, r *http.Request) {
 	claims, err := a.GoogleVerifier.VerifyIDToken(ctx, req.IDToken)
 	if err != nil {
 		a.Logger.Error().Err(err).Msg("google verify failed")
-		a.error(w, http.StatusUnauthorized, "unauthorized", "invalid google token")
+		a.logAuthEvent(r, "", eventAuthLogin, false, "", "invalid_token")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "invalid google token")
 		return
 	}
 	sub, _ := claims["sub"].(string)
 	email, _ := claims["email"].(string)
+	if a.restrictsEmailDomains() && !emailVerified(claims) {
+		a.logAuthEvent(r, "", eventAuthLogin, false, email, "email_not_verified")
+		a.error(w, http.StatusForbidden, ErrForbidden, "email not verified")
+		return
+	}
+	if !a.emailDomainAllowed(email) {
+		a.logAuthEvent(r, "", eventAuthLogin, false, email, "email_domain_not_allowed")
+		a.error(w, http.StatusForbidden, ErrForbidden, "email domain not allowed")
+		return
+	}
 	name, _ := claims["name"].(string)
 	picture, _ := claims["picture"].(string)
 	locale, _ := claims["locale"].(string)
@@ -62,7 +85,8 @@ func (a *App) AuthGoogleVerify(w http.ResponseWriter, r *http.Request) {
 	var propsBytes []byte
 	if err := row.Scan(&userID, &plan, &propsBytes); err != nil {
 		a.Logger.Error().Err(err).Msg("upsert user failed")
-		a.error(w, http.StatusInternalServerError, "internal", "failed to persist user")
+		a.logAuthEvent(r, "", eventAuthLogin, false, email, "persist_failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to persist user")
 		return
 	}
 	props, quotaDaily, quotaUsed := extractQuota(propsBytes)
@@ -71,21 +95,22 @@ func (a *App) AuthGoogleVerify(w http.ResponseWriter, r *http.Request) {
 	} else if v, ok := props["google_locale"].(string); ok && v != "" {
 		locale = v
 	}
-	token, err := middleware.SignJWT(a.JWTSecret, middleware.TokenClaims{
-		Sub:      userID,
-		Plan:     plan,
-		Locale:   locale,
-		Exp:      time.Now().Add(24 * time.Hour).Unix(),
-		Issuer:   "umkm-saas",
-		Audience: "umkm-clients",
-	})
+	token, err := a.signAccessToken(userID, plan, locale, props)
 	if err != nil {
 		a.Logger.Error().Err(err).Msg("sign jwt failed")
-		a.error(w, http.StatusInternalServerError, "internal", "failed to sign token")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to sign token")
+		return
+	}
+	refreshToken, err := a.issueRefreshToken(r.Context(), userID, uuid.NewString())
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("issue refresh token failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to issue refresh token")
 		return
 	}
+	a.logAuthEvent(r, userID, eventAuthLogin, true, email, "")
 	a.json(w, http.StatusOK, googleVerifyResponse{
-		Token: token,
+		Token:        token,
+		RefreshToken: refreshToken,
 		User: userProfileDTO{
 			ID:            userID,
 			Email:         email,
@@ -98,18 +123,99 @@ func (a *App) AuthGoogleVerify(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// restrictsEmailDomains reports whether GOOGLE_EMAIL_DOMAIN_ALLOWLIST is set.
+func (a *App) restrictsEmailDomains() bool {
+	return a.Config != nil && len(a.Config.GoogleEmailDomains) > 0
+}
+
+// emailVerified reports whether Google vouches for the token's email. Anyone
+// can put an unverified address at an allowed domain on a Google account, so
+// the allowlist only trusts verified ones. ID tokens carry a boolean;
+// tokeninfo responses use the string "true".
+func emailVerified(claims map[string]any) bool {
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	default:
+		return false
+	}
+}
+
+// emailDomainAllowed applies GOOGLE_EMAIL_DOMAIN_ALLOWLIST; an empty list
+// allows every domain.
+func (a *App) emailDomainAllowed(email string) bool {
+	if !a.restrictsEmailDomains() {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for _, allowed := range a.Config.GoogleEmailDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *App) Me(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	a.writeUserProfile(w, a.SQL.QueryRow(r.Context(), sqlinline.QSelectUserByID, userID))
+}
+
+// maxBrandNameLength bounds the brand name so it still fits as watermark text
+// on small renders.
+const maxBrandNameLength = 60
+
+type updateMeRequest struct {
+	BrandName *string `json:"brand_name"`
+}
+
+// UpdateMe edits the caller's profile. The brand name is stored in
+// properties.brand_name and used by the worker as the default watermark text;
+// an empty value clears it.
+func (a *App) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	var req updateMeRequest
+	if !a.decodeJSON(w, r, &req) {
 		return
 	}
-	row := a.SQL.QueryRow(r.Context(), sqlinline.QSelectUserByID, userID)
+	if req.BrandName == nil {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "brand_name is required")
+		return
+	}
+	brandName := strings.TrimSpace(*req.BrandName)
+	if utf8.RuneCountInString(brandName) > maxBrandNameLength {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, fmt.Sprintf("brand_name must be at most %d characters", maxBrandNameLength))
+		return
+	}
+	if strings.IndexFunc(brandName, unicode.IsControl) >= 0 {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "brand_name must not contain control characters")
+		return
+	}
+	a.writeUserProfile(w, a.SQL.QueryRow(r.Context(), sqlinline.QUpdateUserBrandName, userID, brandName))
+}
+
+// writeUserProfile scans a row shaped like QSelectUserByID into the profile
+// response.
+func (a *App) writeUserProfile(w http.ResponseWriter, row pgx.Row) {
 	var id, googleSub, email, locale, plan string
 	var propsBytes []byte
 	var createdAt, updatedAt time.Time
 	if err := row.Scan(&id, &googleSub, &email, &locale, &plan, &propsBytes, &createdAt, &updatedAt); err != nil {
-		a.error(w, http.StatusNotFound, "not_found", "user not found")
+		a.error(w, http.StatusNotFound, ErrNotFound, "user not found")
 		return
 	}
 	props, quotaDaily, quotaUsed := extractQuota(propsBytes)
@@ -137,7 +243,26 @@ func extractQuota(b []byte) (map[string]any, int, int) {
 	if v, ok := props["quota_used_today"].(float64); ok {
 		quotaUsed = int(v)
 	}
-	return props, quotaDaily, quotaUsed
+	return props, quotaDaily, rolloverQuotaUsed(props, quotaUsed, time.Now())
+}
+
+// rolloverQuotaUsed mirrors fn_consume_quota: usage recorded on an earlier UTC
+// day no longer counts, even before the next enqueue resets it.
+func rolloverQuotaUsed(props map[string]any, used int, now time.Time) int {
+	raw, ok := props["quota_refreshed_at"].(string)
+	if !ok {
+		return 0
+	}
+	refreshed, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return used
+	}
+	ry, rm, rd := refreshed.UTC().Date()
+	ny, nm, nd := now.UTC().Date()
+	if time.Date(ry, rm, rd, 0, 0, 0, 0, time.UTC).Before(time.Date(ny, nm, nd, 0, 0, 0, 0, time.UTC)) {
+		return 0
+	}
+	return used
 }
 
 type countryResolver interface {
@@ -158,7 +283,7 @@ func resolveIPCountry(r *http.Request, resolver countryResolver) string {
 func (a *App) PromptClear(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	a.logUsageEvent(r, userID, "PROMPT_CLEAR", true, 0, map[string]any{"action": "clear"})
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	eventAuthLogin   = "AUTH_LOGIN"
+	eventAuthRefresh = "AUTH_REFRESH"
+	eventAuthLogout  = "AUTH_LOGOUT"
+)
+
+// logAuthEvent records a sign-in, refresh or logout for security review.
+// Unlike logUsageEvent it also records events without a known user, so
+// rejected attempts are kept. reason is empty on success.
+func (a *App) logAuthEvent(r *http.Request, userID, event string, success bool, email, reason string) {
+	props := map[string]any{
+		"country": resolveIPCountry(r, a.GeoIPResolver),
+	}
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		props["email_domain"] = strings.ToLower(email[at+1:])
+	}
+	if reason != "" {
+		props["reason"] = reason
+	}
+	var user any
+	if userID != "" {
+		user = userID
+	}
+	a.insertUsageEvent(r, user, event, success, 0, props)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+)
+
+type authLogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// AuthLogout revokes the presented access token by its jti. When the body
+// carries the session's refresh_token, its rotation chain is revoked too so
+// the client cannot mint new access tokens.
+func (a *App) AuthLogout(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.ClaimsFromContext(r.Context())
+	if claims == nil || claims.Sub == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	var req authLogoutRequest
+	if !a.decodeOptionalJSON(w, r, &req) {
+		return
+	}
+	ctx := r.Context()
+
+	if claims.ID != "" {
+		expiresAt := time.Now().Add(accessTokenTTL)
+		if claims.Exp != 0 {
+			expiresAt = time.Unix(claims.Exp, 0)
+		}
+		if _, err := a.SQL.Exec(ctx, sqlinline.QInsertRevokedToken, claims.ID, claims.Sub, expiresAt.UTC()); err != nil {
+			a.Logger.Error().Err(err).Str("user_id", claims.Sub).Msg("revoke token failed")
+			a.error(w, http.StatusInternalServerError, ErrInternal, "failed to revoke token")
+			return
+		}
+	}
+
+	if presented := strings.TrimSpace(req.RefreshToken); presented != "" {
+		var (
+			tokenID, userID, familyID string
+			expiresAt                 time.Time
+			revoked                   bool
+		)
+		err := a.SQL.QueryRow(ctx, sqlinline.QSelectRefreshToken, hashRefreshToken(presented)).Scan(&tokenID, &userID, &familyID, &expiresAt, &revoked)
+		switch {
+		case err == nil && userID == claims.Sub:
+			if _, err := a.SQL.Exec(ctx, sqlinline.QRevokeRefreshTokenFamily, familyID); err != nil {
+				a.Logger.Error().Err(err).Str("user_id", claims.Sub).Msg("revoke refresh tokens failed")
+				a.error(w, http.StatusInternalServerError, ErrInternal, "failed to revoke token")
+				return
+			}
+		case err != nil && !infra.IsNoRows(err):
+			a.Logger.Error().Err(err).Str("user_id", claims.Sub).Msg("load refresh token failed")
+			a.error(w, http.StatusInternalServerError, ErrInternal, "failed to revoke token")
+			return
+		}
+	}
+	a.logAuthEvent(r, claims.Sub, eventAuthLogout, true, "", "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TokenRevoked is the middleware.RevocationCheck backed by revoked_tokens.
+func (a *App) TokenRevoked(ctx context.Context, jti string) (bool, error) {
+	if a.SQL == nil {
+		return false, nil
+	}
+	var revoked bool
+	if err := a.SQL.QueryRow(ctx, sqlinline.QIsTokenRevoked, jti).Scan(&revoked); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+// revocationSQL records revoked jti values in memory.
+type revocationSQL struct {
+	revoked map[string]time.Time
+}
+
+func (s *revocationSQL) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	if query != sqlinline.QInsertRevokedToken {
+		return pgconn.CommandTag{}, errors.New("unexpected exec")
+	}
+	s.revoked[args[0].(string)] = args[2].(time.Time)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (s *revocationSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if query != sqlinline.QIsTokenRevoked {
+		return NewSimpleRow(nil)
+	}
+	_, revoked := s.revoked[args[0].(string)]
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*bool) = revoked
+		return nil
+	})
+}
+
+func (s *revocationSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestLoggedOutTokenIsRejected(t *testing.T) {
+	sqlStub := &revocationSQL{revoked: map[string]time.Time{}}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: sqlStub, JWTSecret: "test-secret"}
+	auth := middleware.AuthJWTWithRevocation(app.JWTSecret, app.TokenRevoked)
+	protected := auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	logout := auth(http.HandlerFunc(app.AuthLogout))
+
+	exp := time.Now().Add(time.Hour).Unix()
+	token, err := middleware.SignJWT(app.JWTSecret, middleware.TokenClaims{Sub: "user-123", Exp: exp})
+	if err != nil {
+		t.Fatalf("SignJWT() error: %v", err)
+	}
+	other, err := middleware.SignJWT(app.JWTSecret, middleware.TokenClaims{Sub: "user-123", Exp: exp})
+	if err != nil {
+		t.Fatalf("SignJWT() error: %v", err)
+	}
+	send := func(h http.Handler, method, bearer string) int {
+		req := httptest.NewRequest(method, "/v1/me", strings.NewReader(""))
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := send(protected, http.MethodGet, token); code != http.StatusOK {
+		t.Fatalf("before logout status = %d, want 200", code)
+	}
+	if code := send(logout, http.MethodPost, token); code != http.StatusNoContent {
+		t.Fatalf("logout status = %d, want 204", code)
+	}
+	if len(sqlStub.revoked) != 1 {
+		t.Fatalf("revoked = %v, want one jti", sqlStub.revoked)
+	}
+	for _, expiresAt := range sqlStub.revoked {
+		if expiresAt.Unix() != exp {
+			t.Fatalf("revocation expires at %v, want token expiry", expiresAt)
+		}
+	}
+	if code := send(protected, http.MethodGet, token); code != http.StatusUnauthorized {
+		t.Fatalf("after logout status = %d, want 401", code)
+	}
+	if code := send(protected, http.MethodGet, other); code != http.StatusOK {
+		t.Fatalf("other session status = %d, want 200", code)
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+)
+
+const (
+	accessTokenTTL         = 24 * time.Hour
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+	refreshTokenBytes      = 32
+)
+
+type authRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type authRefreshResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// signAccessToken issues the short-lived JWT used on every API call.
+func (a *App) signAccessToken(userID, plan, locale string, props map[string]any) (string, error) {
+	isAdmin, _ := props["admin"].(bool)
+	return middleware.SignJWT(a.JWTSecret, middleware.TokenClaims{
+		Sub:      userID,
+		Plan:     plan,
+		Locale:   locale,
+		Exp:      time.Now().Add(accessTokenTTL).Unix(),
+		Issuer:   "umkm-saas",
+		Audience: "umkm-clients",
+		Admin:    isAdmin,
+	})
+}
+
+func (a *App) refreshTokenTTL() time.Duration {
+	if a.Config != nil && a.Config.RefreshTokenTTL > 0 {
+		return a.Config.RefreshTokenTTL
+	}
+	return defaultRefreshTokenTTL
+}
+
+// issueRefreshToken stores the hash of a new random refresh token in
+// familyID and returns the token itself. A login starts a new family; every
+// rotation stays in it so reuse can revoke the whole chain.
+func (a *App) issueRefreshToken(ctx context.Context, userID, familyID string) (string, error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	expiresAt := time.Now().Add(a.refreshTokenTTL()).UTC()
+	if _, err := a.SQL.Exec(ctx, sqlinline.QInsertRefreshToken, userID, familyID, hashRefreshToken(token), expiresAt); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuthRefresh exchanges a refresh token for a new access token and rotates
+// the refresh token. Presenting a token that was already rotated revokes
+// every token in its family, since it means the token was copied.
+func (a *App) AuthRefresh(w http.ResponseWriter, r *http.Request) {
+	var req authRefreshRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	presented := strings.TrimSpace(req.RefreshToken)
+	if presented == "" {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "refresh_token required")
+		return
+	}
+	ctx := r.Context()
+
+	var (
+		tokenID, userID, familyID string
+		expiresAt                 time.Time
+		revoked                   bool
+	)
+	row := a.SQL.QueryRow(ctx, sqlinline.QSelectRefreshToken, hashRefreshToken(presented))
+	if err := row.Scan(&tokenID, &userID, &familyID, &expiresAt, &revoked); err != nil {
+		if !infra.IsNoRows(err) {
+			a.Logger.Error().Err(err).Msg("load refresh token failed")
+			a.error(w, http.StatusInternalServerError, ErrInternal, "failed to refresh token")
+			return
+		}
+		a.logAuthEvent(r, "", eventAuthRefresh, false, "", "invalid_token")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "invalid refresh token")
+		return
+	}
+	if revoked {
+		a.revokeRefreshFamily(ctx, userID, familyID)
+		a.logAuthEvent(r, userID, eventAuthRefresh, false, "", "token_reuse")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "refresh token reuse detected")
+		return
+	}
+	if !time.Now().Before(expiresAt) {
+		a.logAuthEvent(r, userID, eventAuthRefresh, false, "", "token_expired")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "refresh token expired")
+		return
+	}
+	tag, err := a.SQL.Exec(ctx, sqlinline.QRevokeRefreshToken, tokenID)
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("rotate refresh token failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to refresh token")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		// A concurrent request rotated the same token first.
+		a.revokeRefreshFamily(ctx, userID, familyID)
+		a.logAuthEvent(r, userID, eventAuthRefresh, false, "", "token_reuse")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "refresh token reuse detected")
+		return
+	}
+
+	var (
+		id, plan, locale string
+		propsBytes       []byte
+	)
+	if err := a.SQL.QueryRow(ctx, sqlinline.QSelectUserForToken, userID).Scan(&id, &plan, &locale, &propsBytes); err != nil {
+		a.Logger.Error().Err(err).Str("user_id", userID).Msg("load user for refresh failed")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "invalid refresh token")
+		return
+	}
+	if locale == "" {
+		locale = "en"
+	}
+	props, _, _ := extractQuota(propsBytes)
+	token, err := a.signAccessToken(id, plan, locale, props)
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("sign jwt failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to sign token")
+		return
+	}
+	refreshToken, err := a.issueRefreshToken(ctx, id, familyID)
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("issue refresh token failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to refresh token")
+		return
+	}
+	a.logAuthEvent(r, id, eventAuthRefresh, true, "", "")
+	a.json(w, http.StatusOK, authRefreshResponse{Token: token, RefreshToken: refreshToken})
+}
+
+func (a *App) revokeRefreshFamily(ctx context.Context, userID, familyID string) {
+	a.Logger.Warn().Str("user_id", userID).Str("family_id", familyID).Msg("refresh token reuse detected, revoking family")
+	if _, err := a.SQL.Exec(ctx, sqlinline.QRevokeRefreshTokenFamily, familyID); err != nil {
+		a.Logger.Error().Err(err).Str("family_id", familyID).Msg("revoke refresh token family failed")
+	}
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+type storedRefreshToken struct {
+	id        string
+	userID    string
+	familyID  string
+	expiresAt time.Time
+	revoked   bool
+}
+
+// refreshTokenSQL keeps refresh tokens in memory, keyed by hash.
+type refreshTokenSQL struct {
+	tokens map[string]*storedRefreshToken
+	nextID int
+}
+
+func newRefreshTokenSQL() *refreshTokenSQL {
+	return &refreshTokenSQL{tokens: map[string]*storedRefreshToken{}}
+}
+
+func (s *refreshTokenSQL) seed(token, userID, familyID string, expiresAt time.Time) {
+	s.nextID++
+	s.tokens[hashRefreshToken(token)] = &storedRefreshToken{
+		id:        fmt.Sprintf("rt-%d", s.nextID),
+		userID:    userID,
+		familyID:  familyID,
+		expiresAt: expiresAt,
+	}
+}
+
+func (s *refreshTokenSQL) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	switch query {
+	case sqlinline.QInsertRefreshToken:
+		s.nextID++
+		s.tokens[args[2].(string)] = &storedRefreshToken{
+			id:        fmt.Sprintf("rt-%d", s.nextID),
+			userID:    args[0].(string),
+			familyID:  args[1].(string),
+			expiresAt: args[3].(time.Time),
+		}
+		return pgconn.NewCommandTag("INSERT 0 1"), nil
+	case sqlinline.QRevokeRefreshToken:
+		for _, t := range s.tokens {
+			if t.id == args[0] && !t.revoked {
+				t.revoked = true
+				return pgconn.NewCommandTag("UPDATE 1"), nil
+			}
+		}
+		return pgconn.NewCommandTag("UPDATE 0"), nil
+	case sqlinline.QRevokeRefreshTokenFamily:
+		for _, t := range s.tokens {
+			if t.familyID == args[0] {
+				t.revoked = true
+			}
+		}
+		return pgconn.NewCommandTag("UPDATE 1"), nil
+	}
+	return pgconn.CommandTag{}, errors.New("unexpected exec")
+}
+
+func (s *refreshTokenSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	switch query {
+	case sqlinline.QSelectRefreshToken:
+		t, ok := s.tokens[args[0].(string)]
+		if !ok {
+			return NewSimpleRow(nil)
+		}
+		return NewSimpleRow(func(dest ...any) error {
+			*dest[0].(*string) = t.id
+			*dest[1].(*string) = t.userID
+			*dest[2].(*string) = t.familyID
+			*dest[3].(*time.Time) = t.expiresAt
+			*dest[4].(*bool) = t.revoked
+			return nil
+		})
+	case sqlinline.QSelectUserForToken:
+		return NewSimpleRow(func(dest ...any) error {
+			*dest[0].(*string) = args[0].(string)
+			*dest[1].(*string) = "pro"
+			*dest[2].(*string) = "id"
+			*dest[3].(*[]byte) = []byte(`{"admin":false}`)
+			return nil
+		})
+	}
+	return NewSimpleRow(nil)
+}
+
+func (s *refreshTokenSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *refreshTokenSQL) familyRevoked(familyID string) bool {
+	for _, t := range s.tokens {
+		if t.familyID == familyID && !t.revoked {
+			return false
+		}
+	}
+	return true
+}
+
+func postRefresh(app *App, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/refresh", strings.NewReader(`{"refresh_token":"`+token+`"}`))
+	rr := httptest.NewRecorder()
+	app.AuthRefresh(rr, req)
+	return rr
+}
+
+func newRefreshApp(sqlStub *refreshTokenSQL) *App {
+	return &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: sqlStub, JWTSecret: "test-secret"}
+}
+
+func TestAuthRefreshRotatesToken(t *testing.T) {
+	sqlStub := newRefreshTokenSQL()
+	sqlStub.seed("original", "user-123", "family-1", time.Now().Add(time.Hour))
+	app := newRefreshApp(sqlStub)
+
+	rr := postRefresh(app, "original")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rr.Code, rr.Body.String())
+	}
+	var resp authRefreshResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.RefreshToken == "" || resp.RefreshToken == "original" {
+		t.Fatalf("refresh token was not rotated: %q", resp.RefreshToken)
+	}
+	claims, err := middleware.VerifyJWT("test-secret", resp.Token)
+	if err != nil {
+		t.Fatalf("access token invalid: %v", err)
+	}
+	if claims.Sub != "user-123" || claims.Plan != "pro" || claims.Locale != "id" {
+		t.Fatalf("claims = %+v", claims)
+	}
+	if !sqlStub.tokens[hashRefreshToken("original")].revoked {
+		t.Fatalf("original refresh token was not revoked")
+	}
+	rotated := sqlStub.tokens[hashRefreshToken(resp.RefreshToken)]
+	if rotated == nil || rotated.familyID != "family-1" || rotated.revoked {
+		t.Fatalf("rotated token = %+v, want active token in family-1", rotated)
+	}
+
+	if rr := postRefresh(app, resp.RefreshToken); rr.Code != http.StatusOK {
+		t.Fatalf("second refresh status = %d, body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAuthRefreshDetectsReuse(t *testing.T) {
+	sqlStub := newRefreshTokenSQL()
+	sqlStub.seed("original", "user-123", "family-1", time.Now().Add(time.Hour))
+	sqlStub.seed("other-device", "user-123", "family-2", time.Now().Add(time.Hour))
+	app := newRefreshApp(sqlStub)
+
+	rr := postRefresh(app, "original")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("first refresh status = %d", rr.Code)
+	}
+	var resp authRefreshResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+
+	rr = postRefresh(app, "original")
+	if rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), "reuse") {
+		t.Fatalf("reuse status = %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if !sqlStub.familyRevoked("family-1") {
+		t.Fatalf("reuse did not revoke the token family")
+	}
+	if sqlStub.familyRevoked("family-2") {
+		t.Fatalf("reuse revoked an unrelated family")
+	}
+	if rr := postRefresh(app, resp.RefreshToken); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("rotated token after reuse status = %d, want 401", rr.Code)
+	}
+}
+
+func TestAuthRefreshRejectsExpiredToken(t *testing.T) {
+	sqlStub := newRefreshTokenSQL()
+	sqlStub.seed("stale", "user-123", "family-1", time.Now().Add(-time.Minute))
+	app := newRefreshApp(sqlStub)
+
+	rr := postRefresh(app, "stale")
+	if rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), "expired") {
+		t.Fatalf("status = %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if sqlStub.tokens[hashRefreshToken("stale")].revoked {
+		t.Fatalf("expired token should not be rotated")
+	}
+	if rr := postRefresh(app, "unknown"); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown token status = %d, want 401", rr.Code)
+	}
+}
@@ -1,10 +1,22 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"server/internal/infra"
 	"server/internal/middleware"
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
 )
 
 func TestSignAndVerifyJWT(t *testing.T) {
@@ -57,3 +69,266 @@ func TestVerifyJWTExpired(t *testing.T) {
 		t.Fatalf("VerifyJWT() expected expiration error")
 	}
 }
+
+func TestSignJWTSetsIssuedAtAndNotBefore(t *testing.T) {
+	before := time.Now().Unix()
+	token, err := middleware.SignJWT("secret", middleware.TokenClaims{Sub: "user-123"})
+	if err != nil {
+		t.Fatalf("SignJWT() error: %v", err)
+	}
+	parsed, err := middleware.VerifyJWT("secret", token)
+	if err != nil {
+		t.Fatalf("VerifyJWT() error: %v", err)
+	}
+	if parsed.Iat < before || parsed.Nbf < before {
+		t.Fatalf("VerifyJWT() iat=%d nbf=%d, want >= %d", parsed.Iat, parsed.Nbf, before)
+	}
+}
+
+func TestVerifyJWTNotYetValid(t *testing.T) {
+	now := time.Now()
+	claims := middleware.TokenClaims{
+		Sub: "user-123",
+		Exp: now.Add(2 * time.Hour).Unix(),
+		Nbf: now.Add(time.Hour).Unix(),
+	}
+	token, err := middleware.SignJWT("secret", claims)
+	if err != nil {
+		t.Fatalf("SignJWT() error: %v", err)
+	}
+	if _, err := middleware.VerifyJWT("secret", token); err == nil {
+		t.Fatalf("VerifyJWT() expected not-yet-valid error")
+	}
+
+	claims.Nbf = now.Add(middleware.ClockSkew / 2).Unix()
+	token, err = middleware.SignJWT("secret", claims)
+	if err != nil {
+		t.Fatalf("SignJWT() error: %v", err)
+	}
+	if _, err := middleware.VerifyJWT("secret", token); err != nil {
+		t.Fatalf("VerifyJWT() within clock skew: %v", err)
+	}
+}
+
+func TestVerifyJWTIssuedInFuture(t *testing.T) {
+	now := time.Now()
+	claims := middleware.TokenClaims{
+		Sub: "user-123",
+		Exp: now.Add(2 * time.Hour).Unix(),
+		Iat: now.Add(10 * time.Minute).Unix(),
+	}
+	token, err := middleware.SignJWT("secret", claims)
+	if err != nil {
+		t.Fatalf("SignJWT() error: %v", err)
+	}
+	if _, err := middleware.VerifyJWT("secret", token); err == nil {
+		t.Fatalf("VerifyJWT() expected issued-in-future error")
+	}
+}
+
+type stubIDTokenVerifier struct {
+	claims map[string]any
+	err    error
+}
+
+func (s stubIDTokenVerifier) VerifyIDToken(context.Context, string) (map[string]any, error) {
+	return s.claims, s.err
+}
+
+// googleLoginSQL answers the user upsert, accepts refresh token inserts and
+// records usage events.
+type googleLoginSQL struct {
+	upserts int
+	events  [][]any
+}
+
+func (s *googleLoginSQL) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	if query == sqlinline.QInsertUsageEvent {
+		s.events = append(s.events, args)
+	}
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (s *googleLoginSQL) QueryRow(_ context.Context, query string, _ ...any) pgx.Row {
+	if query != sqlinline.QUpsertGoogleUser {
+		return NewSimpleRow(nil)
+	}
+	s.upserts++
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = "user-123"
+		*dest[1].(*string) = "free"
+		*dest[2].(*[]byte) = []byte(`{}`)
+		return nil
+	})
+}
+
+func (s *googleLoginSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestAuthGoogleVerifyEmailDomainAllowlist(t *testing.T) {
+	cases := []struct {
+		name     string
+		domains  []string
+		email    string
+		verified any
+		want     int
+	}{
+		{name: "empty allowlist", domains: nil, email: "owner@gmail.com", want: http.StatusOK},
+		{name: "allowed domain", domains: []string{"cohort.id", "example.com"}, email: "Owner@Cohort.ID", verified: true, want: http.StatusOK},
+		{name: "allowed domain tokeninfo string", domains: []string{"cohort.id"}, email: "owner@cohort.id", verified: "true", want: http.StatusOK},
+		{name: "unverified allowed domain", domains: []string{"cohort.id"}, email: "owner@cohort.id", verified: false, want: http.StatusForbidden},
+		{name: "missing email_verified", domains: []string{"cohort.id"}, email: "owner@cohort.id", want: http.StatusForbidden},
+		{name: "rejected domain", domains: []string{"cohort.id"}, email: "owner@gmail.com", verified: true, want: http.StatusForbidden},
+		{name: "lookalike subdomain", domains: []string{"cohort.id"}, email: "owner@evil.cohort.id", verified: true, want: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlStub := &googleLoginSQL{}
+			claims := map[string]any{"sub": "google-1", "email": tc.email}
+			if tc.verified != nil {
+				claims["email_verified"] = tc.verified
+			}
+			app := &App{
+				Config:         &infra.Config{GoogleEmailDomains: tc.domains},
+				Logger:         zerolog.Nop(),
+				SQL:            sqlStub,
+				JWTSecret:      "test-secret",
+				GoogleVerifier: stubIDTokenVerifier{claims: claims},
+			}
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/google/verify", strings.NewReader(`{"id_token":"token"}`))
+			rr := httptest.NewRecorder()
+			app.AuthGoogleVerify(rr, req)
+			if rr.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.want, rr.Body.String())
+			}
+			if tc.want == http.StatusForbidden {
+				if sqlStub.upserts != 0 {
+					t.Fatalf("rejected login upserted the user")
+				}
+				if !strings.Contains(rr.Body.String(), string(ErrForbidden)) {
+					t.Fatalf("body = %s, want forbidden code", rr.Body.String())
+				}
+			} else if sqlStub.upserts != 1 {
+				t.Fatalf("upserts = %d, want 1", sqlStub.upserts)
+			}
+		})
+	}
+}
+
+func TestAuthGoogleVerifyRecordsLoginEvents(t *testing.T) {
+	login := func(verifier stubIDTokenVerifier) *googleLoginSQL {
+		sqlStub := &googleLoginSQL{}
+		app := &App{
+			Config:         &infra.Config{},
+			Logger:         zerolog.Nop(),
+			SQL:            sqlStub,
+			JWTSecret:      "test-secret",
+			GoogleVerifier: verifier,
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/auth/google/verify", strings.NewReader(`{"id_token":"token"}`))
+		app.AuthGoogleVerify(httptest.NewRecorder(), req)
+		if len(sqlStub.events) != 1 {
+			t.Fatalf("usage events = %d, want 1", len(sqlStub.events))
+		}
+		if got := sqlStub.events[0][2]; got != eventAuthLogin {
+			t.Fatalf("event type = %v, want %s", got, eventAuthLogin)
+		}
+		return sqlStub
+	}
+
+	ok := login(stubIDTokenVerifier{claims: map[string]any{"sub": "google-1", "email": "owner@Example.com"}})
+	event := ok.events[0]
+	if event[0] != "user-123" || event[3] != true {
+		t.Fatalf("success event user = %v, success = %v", event[0], event[3])
+	}
+	if props := string(event[5].(json.RawMessage)); !strings.Contains(props, `"email_domain":"example.com"`) {
+		t.Fatalf("success event props = %s, want email domain", props)
+	}
+
+	failed := login(stubIDTokenVerifier{err: errors.New("bad signature")})
+	event = failed.events[0]
+	if event[0] != nil || event[3] != false {
+		t.Fatalf("failure event user = %v, success = %v", event[0], event[3])
+	}
+	if props := string(event[5].(json.RawMessage)); !strings.Contains(props, `"reason":"invalid_token"`) {
+		t.Fatalf("failure event props = %s, want reason", props)
+	}
+}
+
+// brandNameSQL answers the brand name update with a user row whose properties
+// carry the stored name.
+type brandNameSQL struct {
+	updates [][]any
+}
+
+func (s *brandNameSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *brandNameSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if query != sqlinline.QUpdateUserBrandName {
+		return NewSimpleRow(nil)
+	}
+	s.updates = append(s.updates, args)
+	props, _ := json.Marshal(map[string]any{"quota_daily": 2, "brand_name": args[1]})
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = "user-123"
+		*dest[1].(*string) = "google-sub"
+		*dest[2].(*string) = "owner@example.com"
+		*dest[3].(*string) = "id"
+		*dest[4].(*string) = "free"
+		*dest[5].(*[]byte) = props
+		*dest[6].(*time.Time) = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		*dest[7].(*time.Time) = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		return nil
+	})
+}
+
+func (s *brandNameSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestUpdateMeBrandName(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		want      int
+		wantBrand string
+	}{
+		{name: "sets trimmed brand", body: `{"brand_name":"  Toko Sari  "}`, want: http.StatusOK, wantBrand: "Toko Sari"},
+		{name: "empty clears brand", body: `{"brand_name":""}`, want: http.StatusOK, wantBrand: ""},
+		{name: "missing field", body: `{}`, want: http.StatusBadRequest},
+		{name: "too long", body: `{"brand_name":"` + strings.Repeat("a", maxBrandNameLength+1) + `"}`, want: http.StatusBadRequest},
+		{name: "control characters", body: `{"brand_name":"Toko\nSari"}`, want: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlStub := &brandNameSQL{}
+			app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: sqlStub}
+			req := httptest.NewRequest(http.MethodPatch, "/v1/me", strings.NewReader(tc.body))
+			req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+			rr := httptest.NewRecorder()
+			app.UpdateMe(rr, req)
+			if rr.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.want, rr.Body.String())
+			}
+			if tc.want != http.StatusOK {
+				if len(sqlStub.updates) != 0 {
+					t.Fatalf("updates = %d, want none for rejected input", len(sqlStub.updates))
+				}
+				return
+			}
+			if len(sqlStub.updates) != 1 || sqlStub.updates[0][0] != "user-123" || sqlStub.updates[0][1] != tc.wantBrand {
+				t.Fatalf("update args = %v, want [user-123 %q]", sqlStub.updates, tc.wantBrand)
+			}
+			var resp userProfileDTO
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.ID != "user-123" || resp.PropertiesRaw["brand_name"] != tc.wantBrand {
+				t.Fatalf("profile = %+v, want brand_name %q", resp, tc.wantBrand)
+			}
+		})
+	}
+}
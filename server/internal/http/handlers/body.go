@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const defaultMaxJSONBodyKB = 256
+
+// jsonBodyLimit returns the MAX_JSON_BODY_KB cap in bytes.
+func (a *App) jsonBodyLimit() int64 {
+	kb := defaultMaxJSONBodyKB
+	if a.Config != nil && a.Config.MaxJSONBodyKB > 0 {
+		kb = a.Config.MaxJSONBodyKB
+	}
+	return int64(kb) << 10
+}
+
+// decodeJSON reads a JSON request body capped at MAX_JSON_BODY_KB into v. On
+// failure it writes 413 for oversized bodies or 400 otherwise and returns
+// false.
+func (a *App) decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	return a.decodeJSONLimit(w, r, v, a.jsonBodyLimit(), false)
+}
+
+// decodeOptionalJSON is decodeJSON for endpoints whose body may be omitted.
+func (a *App) decodeOptionalJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	return a.decodeJSONLimit(w, r, v, a.jsonBodyLimit(), true)
+}
+
+func (a *App) decodeJSONLimit(w http.ResponseWriter, r *http.Request, v any, limit int64, optional bool) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil || (optional && errors.Is(err, io.EOF)) {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		a.error(w, http.StatusRequestEntityTooLarge, ErrTooLarge, fmt.Sprintf("request body exceeds %dKB limit", limit>>10))
+		return false
+	}
+	a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid payload")
+	return false
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/internal/infra"
+	"server/internal/middleware"
+
+	"github.com/rs/zerolog"
+)
+
+func TestJSONHandlersRejectOversizedBodies(t *testing.T) {
+	app := &App{Config: &infra.Config{MaxJSONBodyKB: 1}, Logger: zerolog.Nop(), SQL: &erroringSQL{}, ImageEditor: &stubEditor{}}
+	body := `{"provider":"qwen-image-plus","prompt":{"title":"` + strings.Repeat("a", 2048) + `"}}`
+
+	cases := []struct {
+		name    string
+		target  string
+		handler http.HandlerFunc
+	}{
+		{name: "images", target: "/v1/images/generate", handler: app.ImagesGenerate},
+		{name: "videos", target: "/v1/videos/generate", handler: app.VideosGenerate},
+		{name: "enhance", target: "/v1/prompts/enhance", handler: app.PromptEnhance},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(body))
+			req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-1"))
+			rr := httptest.NewRecorder()
+
+			tc.handler(rr, req)
+
+			if rr.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want 413; body=%s", rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), `"code":"too_large"`) {
+				t.Fatalf("unexpected error body: %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestDecodeJSONAcceptsBodiesWithinLimit(t *testing.T) {
+	app := &App{Config: &infra.Config{MaxJSONBodyKB: 1}}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"ok"}`))
+	rr := httptest.NewRecorder()
+	var v struct {
+		Name string `json:"name"`
+	}
+	if !app.decodeJSON(rr, req, &v) || v.Name != "ok" {
+		t.Fatalf("decodeJSON failed: status=%d body=%s", rr.Code, rr.Body.String())
+	}
+}
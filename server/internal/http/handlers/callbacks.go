@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"server/internal/webhook"
+)
+
+const callbackDeliveryTimeout = 30 * time.Second
+
+// parseCallbackURL validates an optional client-supplied callback URL using
+// the same public-host rules applied to source assets.
+func (a *App) parseCallbackURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed == nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", errors.New("callback_url must be a public http(s) URL")
+	}
+	if err := ensurePublicHTTPURL(parsed, a.trustedHosts); err != nil {
+		return "", errors.New("callback_url must be publicly accessible")
+	}
+	return parsed.String(), nil
+}
+
+// notifyCallback delivers payload in the background so the HTTP response is
+// not held up by a slow receiver.
+func (a *App) notifyCallback(target string, payload webhook.Payload) {
+	if target == "" || a.callbackNotifier == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), callbackDeliveryTimeout)
+		defer cancel()
+		if err := a.callbackNotifier.Deliver(ctx, target, payload); err != nil {
+			a.Logger.Warn().Err(err).Str("job_id", payload.JobID).Msg("callback delivery failed")
+		}
+	}()
+}
@@ -3,26 +3,59 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type donationRequest struct {
 	Amount      int64   `json:"amount"`
 	Note        string  `json:"note"`
 	Testimonial *string `json:"testimonial"`
+	PaymentRef  string  `json:"payment_ref"`
+}
+
+type donationResponse struct {
+	ID          string    `json:"id"`
+	UserID      *string   `json:"user_id"`
+	Amount      int64     `json:"amount"`
+	Note        string    `json:"note"`
+	Testimonial string    `json:"testimonial"`
+	PaymentRef  string    `json:"payment_ref,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
+// donationAckResponse acknowledges a payment_ref that was already recorded.
+// It carries nothing from the stored donation, so knowing a reference does not
+// reveal who made it.
+type donationAckResponse struct {
+	PaymentRef string `json:"payment_ref"`
+	Duplicate  bool   `json:"duplicate"`
+}
+
+const maxPaymentRefLength = 255
+
+// DonationsCreate records a donation. A repeated payment_ref, as sent by a
+// gateway redelivering its webhook, is acknowledged with 200 instead of
+// inserting a duplicate.
 func (a *App) DonationsCreate(w http.ResponseWriter, r *http.Request) {
 	var req donationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "invalid payload")
+	if !a.decodeJSON(w, r, &req) {
 		return
 	}
 	if req.Amount <= 0 {
-		a.error(w, http.StatusBadRequest, "bad_request", "amount must be positive")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "amount must be positive")
+		return
+	}
+	paymentRef := strings.TrimSpace(req.PaymentRef)
+	if len(paymentRef) > maxPaymentRefLength {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "payment_ref must be at most 255 characters")
 		return
 	}
 	userID := a.currentUserID(r)
@@ -30,19 +63,64 @@ func (a *App) DonationsCreate(w http.ResponseWriter, r *http.Request) {
 	if req.Testimonial != nil {
 		testimonial = *req.Testimonial
 	}
-	row := a.SQL.QueryRow(r.Context(), sqlinline.QInsertDonation, userID, req.Amount, req.Note, testimonial, json.RawMessage(`{}`))
-	var donationID string
-	if err := row.Scan(&donationID); err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to create donation")
+	row := a.SQL.QueryRow(r.Context(), sqlinline.QInsertDonation, userID, req.Amount, req.Note, testimonial, json.RawMessage(`{}`), paymentRef)
+	var resp donationResponse
+	var donor sql.NullString
+	err := row.Scan(&resp.ID, &donor, &resp.Amount, &resp.Note, &resp.Testimonial, &resp.PaymentRef, &resp.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) && paymentRef != "" {
+		// The insert hit an existing payment_ref. Look it up in a new
+		// statement: a row committed by a concurrent delivery is not visible
+		// to the insert's own snapshot.
+		var exists bool
+		if err := a.SQL.QueryRow(r.Context(), sqlinline.QDonationPaymentRefExists, paymentRef).Scan(&exists); err != nil || !exists {
+			a.error(w, http.StatusInternalServerError, ErrInternal, "failed to create donation")
+			return
+		}
+		a.json(w, http.StatusOK, donationAckResponse{PaymentRef: paymentRef, Duplicate: true})
+		return
+	}
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to create donation")
 		return
 	}
-	a.json(w, http.StatusCreated, map[string]any{"id": donationID})
+	if donor.Valid {
+		resp.UserID = &donor.String
+	}
+	a.json(w, http.StatusCreated, resp)
 }
 
+const (
+	defaultTestimonialLimit = 10
+	maxTestimonialLimit     = 50
+)
+
 func (a *App) DonationsTestimonials(w http.ResponseWriter, r *http.Request) {
-	rows, err := a.SQL.Query(r.Context(), sqlinline.QListDonations, 10)
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 {
+		limit = defaultTestimonialLimit
+	}
+	if limit > maxTestimonialLimit {
+		limit = maxTestimonialLimit
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	var minAmount int64
+	if raw := strings.TrimSpace(query.Get("min_amount")); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 0 {
+			a.error(w, http.StatusBadRequest, ErrBadRequest, "min_amount must be a non-negative integer")
+			return
+		}
+		minAmount = parsed
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	rows, err := a.SQL.Query(r.Context(), sqlinline.QListDonations, limit+1, offset, minAmount)
 	if err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to load donations")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load donations")
 		return
 	}
 	defer rows.Close()
@@ -70,5 +148,10 @@ func (a *App) DonationsTestimonials(w http.ResponseWriter, r *http.Request) {
 			"properties":  json.RawMessage(props),
 		})
 	}
-	a.json(w, http.StatusOK, map[string]any{"items": items})
+	resp := map[string]any{"items": items, "next_offset": nil}
+	if len(items) > limit {
+		resp["items"] = items[:limit]
+		resp["next_offset"] = offset + limit
+	}
+	a.json(w, http.StatusOK, resp)
 }
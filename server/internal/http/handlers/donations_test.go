@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -71,6 +73,7 @@ type donationRow struct {
 
 type donationTestSQL struct {
 	rows []donationRow
+	args []any
 }
 
 func (d *donationTestSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
@@ -85,10 +88,19 @@ func (d *donationTestSQL) Query(_ context.Context, query string, args ...any) (p
 	if query != sqlinline.QListDonations {
 		return nil, fmt.Errorf("unexpected query: %s", query)
 	}
-	if len(args) != 1 {
+	if len(args) != 3 {
 		return nil, fmt.Errorf("unexpected args count: %d", len(args))
 	}
-	return &donationRowsIterator{rows: d.rows}, nil
+	d.args = args
+	limit, offset, minAmount := args[0].(int), args[1].(int), args[2].(int64)
+	var matched []donationRow
+	for _, row := range d.rows {
+		if row.amount >= minAmount {
+			matched = append(matched, row)
+		}
+	}
+	matched = matched[min(offset, len(matched)):]
+	return &donationRowsIterator{rows: matched[:min(limit, len(matched))]}, nil
 }
 
 type donationRowsIterator struct {
@@ -151,3 +163,173 @@ func (d *donationRowsIterator) Scan(dest ...any) error {
 func (d *donationRowsIterator) Err() error { return nil }
 
 func (d *donationRowsIterator) Close() {}
+
+func fetchTestimonials(t *testing.T, app *App, target string) (int, []map[string]any, *float64) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	app.DonationsTestimonials(rr, httptest.NewRequest("GET", target, nil))
+	var payload struct {
+		Items      []map[string]any `json:"items"`
+		NextOffset *float64         `json:"next_offset"`
+	}
+	if rr.Code == 200 {
+		if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+	}
+	return rr.Code, payload.Items, payload.NextOffset
+}
+
+func TestDonationsTestimonialsPaginates(t *testing.T) {
+	var rows []donationRow
+	for i := 0; i < 60; i++ {
+		rows = append(rows, donationRow{id: fmt.Sprintf("donation-%02d", i), amount: int64(1000 * (i + 1)), testimonial: "thanks"})
+	}
+	sqlStub := &donationTestSQL{rows: rows}
+	app := &App{SQL: sqlStub}
+
+	code, items, next := fetchTestimonials(t, app, "/donations/testimonials")
+	if code != 200 || len(items) != defaultTestimonialLimit || next == nil || *next != defaultTestimonialLimit {
+		t.Fatalf("default page: code=%d items=%d next=%v", code, len(items), next)
+	}
+
+	_, items, _ = fetchTestimonials(t, app, "/donations/testimonials?limit=500")
+	if len(items) != maxTestimonialLimit {
+		t.Fatalf("clamped page has %d items, want %d", len(items), maxTestimonialLimit)
+	}
+
+	_, items, next = fetchTestimonials(t, app, "/donations/testimonials?limit=25&offset=50")
+	if len(items) != 10 || next != nil {
+		t.Fatalf("last page: items=%d next=%v", len(items), next)
+	}
+	if items[0]["id"] != "donation-50" {
+		t.Fatalf("last page starts at %v, want donation-50", items[0]["id"])
+	}
+
+	_, _, _ = fetchTestimonials(t, app, "/donations/testimonials?offset=-4")
+	if sqlStub.args[1] != 0 {
+		t.Fatalf("negative offset passed as %v, want 0", sqlStub.args[1])
+	}
+}
+
+func TestDonationsTestimonialsFiltersByMinAmount(t *testing.T) {
+	app := &App{SQL: &donationTestSQL{rows: []donationRow{
+		{id: "small", amount: 5000, testimonial: "nice"},
+		{id: "large", amount: 100000, testimonial: "great"},
+		{id: "anonymous", userID: sql.NullString{}, amount: 250000, testimonial: "keep it up"},
+	}}}
+
+	code, items, _ := fetchTestimonials(t, app, "/donations/testimonials?min_amount=50000")
+	if code != 200 || len(items) != 2 {
+		t.Fatalf("code=%d items=%d, want 2 items", code, len(items))
+	}
+	for _, item := range items {
+		if item["id"] == "small" {
+			t.Fatalf("donation below min_amount returned: %#v", item)
+		}
+	}
+
+	for _, bad := range []string{"-1", "abc", "1.5"} {
+		if code, _, _ := fetchTestimonials(t, app, "/donations/testimonials?min_amount="+bad); code != 400 {
+			t.Fatalf("min_amount=%s: status = %d, want 400", bad, code)
+		}
+	}
+}
+
+type donationInsertSQL struct {
+	byRef   map[string]donationRow
+	inserts int
+}
+
+func (d *donationInsertSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (d *donationInsertSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	switch query {
+	case sqlinline.QDonationPaymentRefExists:
+		_, exists := d.byRef[args[0].(string)]
+		return NewSimpleRow(func(dest ...any) error {
+			*dest[0].(*bool) = exists
+			return nil
+		})
+	case sqlinline.QInsertDonation:
+	default:
+		return NewSimpleRow(nil)
+	}
+	ref := args[5].(string)
+	if _, exists := d.byRef[ref]; exists && ref != "" {
+		return NewSimpleRow(nil)
+	}
+	d.inserts++
+	row := donationRow{
+		id:          fmt.Sprintf("donation-%d", d.inserts),
+		userID:      sql.NullString{String: args[0].(string), Valid: args[0].(string) != ""},
+		amount:      args[1].(int64),
+		note:        args[2].(string),
+		testimonial: args[3].(string),
+		createdAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if ref != "" {
+		d.byRef[ref] = row
+	}
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = row.id
+		*dest[1].(*sql.NullString) = row.userID
+		*dest[2].(*int64) = row.amount
+		*dest[3].(*string) = row.note
+		*dest[4].(*string) = row.testimonial
+		*dest[5].(*string) = ref
+		*dest[6].(*time.Time) = row.createdAt
+		return nil
+	})
+}
+
+func (d *donationInsertSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func TestDonationsCreateIsIdempotentOnPaymentRef(t *testing.T) {
+	sqlStub := &donationInsertSQL{byRef: map[string]donationRow{}}
+	app := &App{SQL: sqlStub}
+
+	post := func(body string) (int, donationResponse) {
+		rr := httptest.NewRecorder()
+		app.DonationsCreate(rr, httptest.NewRequest("POST", "/v1/donations", strings.NewReader(body)))
+		var resp donationResponse
+		if rr.Code < 300 {
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+		}
+		return rr.Code, resp
+	}
+
+	code, first := post(`{"amount":50000,"note":"semangat","testimonial":"mantap","payment_ref":"midtrans-001"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("first insert status = %d, want 201", code)
+	}
+	if first.ID == "" || first.PaymentRef != "midtrans-001" || first.UserID != nil {
+		t.Fatalf("unexpected donation: %+v", first)
+	}
+
+	rr := httptest.NewRecorder()
+	app.DonationsCreate(rr, httptest.NewRequest("POST", "/v1/donations", strings.NewReader(`{"amount":50000,"note":"semangat","testimonial":"mantap","payment_ref":"midtrans-001"}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("duplicate status = %d, want 200", rr.Code)
+	}
+	var ack map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&ack); err != nil {
+		t.Fatalf("decode duplicate response: %v", err)
+	}
+	if len(ack) != 2 || ack["payment_ref"] != "midtrans-001" || ack["duplicate"] != true {
+		t.Fatalf("duplicate response = %v, want only the acknowledgement", ack)
+	}
+	if sqlStub.inserts != 1 {
+		t.Fatalf("inserts = %d, want 1", sqlStub.inserts)
+	}
+
+	if code, _ := post(`{"amount":10000,"payment_ref":"` + strings.Repeat("x", maxPaymentRefLength+1) + `"}`); code != http.StatusBadRequest {
+		t.Fatalf("overlong payment_ref status = %d, want 400", code)
+	}
+}
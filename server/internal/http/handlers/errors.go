@@ -0,0 +1,49 @@
+package handlers
+
+// ErrorCode is the machine-readable value of error.code in API error
+// responses. Clients may branch on these values; they are part of the API
+// contract and must not be renamed.
+type ErrorCode string
+
+const (
+	// ErrBadRequest: the request was malformed or failed validation.
+	ErrBadRequest ErrorCode = "bad_request"
+	// ErrUnauthorized: the caller is not authenticated.
+	ErrUnauthorized ErrorCode = "unauthorized"
+	// ErrForbidden: the caller may not access the resource.
+	ErrForbidden ErrorCode = "forbidden"
+	// ErrNotFound: the resource does not exist or belongs to another user.
+	ErrNotFound ErrorCode = "not_found"
+	// ErrConflict: the resource is in a state that does not allow the action.
+	ErrConflict ErrorCode = "conflict"
+	// ErrTooLarge: the request body or upload exceeds the allowed size.
+	ErrTooLarge ErrorCode = "too_large"
+	// ErrUnsupportedMediaType: the upload is not an accepted image format.
+	ErrUnsupportedMediaType ErrorCode = "unsupported_media_type"
+	// ErrUnsupportedProvider: the requested provider is not available.
+	ErrUnsupportedProvider ErrorCode = "unsupported_provider"
+	// ErrPlanRestricted: the caller's plan does not include the provider.
+	ErrPlanRestricted ErrorCode = "plan_restricted"
+	// ErrQuotaExceeded: the caller has used up today's quota.
+	ErrQuotaExceeded ErrorCode = "quota_exceeded"
+	// ErrInvalidSource: the source image URL or asset cannot be used.
+	ErrInvalidSource ErrorCode = "invalid_source"
+	// ErrInvalidCallback: the callback URL is not a public http(s) URL.
+	ErrInvalidCallback ErrorCode = "invalid_callback"
+	// ErrModerationBlocked: the prompt was rejected by content moderation.
+	ErrModerationBlocked ErrorCode = "moderation_blocked"
+	// ErrJobPending: the job has not produced output yet.
+	ErrJobPending ErrorCode = "job_pending"
+	// ErrNoImage: the job finished without an image to return.
+	ErrNoImage ErrorCode = "no_image"
+	// ErrGenerationFailed: the upstream provider failed to generate output.
+	ErrGenerationFailed ErrorCode = "generation_failed"
+	// ErrDownloadFailed: fetching a generated asset from its origin failed.
+	ErrDownloadFailed ErrorCode = "download_error"
+	// ErrUnavailable: a required dependency is not configured.
+	ErrUnavailable ErrorCode = "unavailable"
+	// ErrStorageUnavailable: asset storage is not configured.
+	ErrStorageUnavailable ErrorCode = "storage_unavailable"
+	// ErrInternal: an unexpected server-side failure.
+	ErrInternal ErrorCode = "internal"
+)
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/providers/video"
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type quotaExhaustedSQL struct{}
+
+func (quotaExhaustedSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (quotaExhaustedSQL) QueryRow(_ context.Context, query string, _ ...any) pgx.Row {
+	if query != sqlinline.QEnqueueVideoJob {
+		return NewSimpleRow(nil)
+	}
+	return stubRow{scan: func(...any) error {
+		return &pgconn.PgError{Severity: "ERROR", Code: "P0001", Message: "quota exceeded"}
+	}}
+}
+
+func (quotaExhaustedSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestErrorCodesForCommonFailures(t *testing.T) {
+	cases := []struct {
+		name       string
+		sql        infra.SQLExecutor
+		plan       string
+		body       string
+		noUser     bool
+		wantStatus int
+		wantCode   ErrorCode
+	}{
+		{name: "missing user", noUser: true, body: `{"provider":"qwen"}`, wantStatus: http.StatusUnauthorized, wantCode: ErrUnauthorized},
+		{name: "malformed payload", body: `{`, wantStatus: http.StatusBadRequest, wantCode: ErrBadRequest},
+		{name: "unknown provider", plan: "supporter", body: `{"provider":"sora"}`, wantStatus: http.StatusBadRequest, wantCode: ErrUnsupportedProvider},
+		{name: "plan restricted", body: `{"provider":"gemini-2.5-flash"}`, wantStatus: http.StatusForbidden, wantCode: ErrPlanRestricted},
+		{name: "invalid callback", body: `{"provider":"qwen","callback_url":"http://127.0.0.1/hook"}`, wantStatus: http.StatusUnprocessableEntity, wantCode: ErrInvalidCallback},
+		{name: "quota exceeded", sql: quotaExhaustedSQL{}, body: `{"provider":"qwen","prompt":"promo"}`, wantStatus: http.StatusTooManyRequests, wantCode: ErrQuotaExceeded},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlStub := tc.sql
+			if sqlStub == nil {
+				sqlStub = &enqueueVideoSQL{}
+			}
+			app := &App{
+				Config:         &infra.Config{PlanProviders: map[string][]string{"free": {"qwen"}}},
+				SQL:            sqlStub,
+				VideoProviders: map[string]video.Generator{"qwen": nil, "gemini-2.5-flash": nil},
+			}
+			req := httptest.NewRequest("POST", "/v1/videos/generate", bytes.NewReader([]byte(tc.body)))
+			if !tc.noUser {
+				req = req.WithContext(middleware.ContextWithClaims(req.Context(), &middleware.TokenClaims{Sub: "user-123", Plan: tc.plan}))
+			}
+			rr := httptest.NewRecorder()
+
+			app.VideosGenerate(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			var resp struct {
+				Error struct {
+					Code    ErrorCode `json:"code"`
+					Message string    `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Error.Code != tc.wantCode {
+				t.Fatalf("code = %q, want %q", resp.Error.Code, tc.wantCode)
+			}
+			if resp.Error.Message == "" {
+				t.Fatalf("expected an error message")
+			}
+		})
+	}
+}
@@ -1,9 +1,56 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"time"
+
+	"server/internal/sqlinline"
+)
+
+const (
+	defaultWorkerHeartbeatStale = 30 * time.Second
+	readinessPingTimeout        = 2 * time.Second
 )
 
 func (a *App) Health(w http.ResponseWriter, r *http.Request) {
 	a.json(w, http.StatusOK, map[string]string{"status": "ok"})
 }
+
+// Ready is the readiness probe: it pings the database with a short timeout so
+// traffic is only routed once the pool can serve queries.
+func (a *App) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessPingTimeout)
+	defer cancel()
+	var one int
+	if err := a.SQL.QueryRow(ctx, sqlinline.QPing).Scan(&one); err != nil {
+		a.Logger.Warn().Err(err).Msg("readiness ping failed")
+		a.error(w, http.StatusServiceUnavailable, ErrUnavailable, "database unavailable")
+		return
+	}
+	a.json(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// WorkerHealth reports whether the background worker has checked in recently.
+func (a *App) WorkerHealth(w http.ResponseWriter, r *http.Request) {
+	threshold := defaultWorkerHeartbeatStale
+	if a.Config != nil && a.Config.WorkerHeartbeatStale > 0 {
+		threshold = a.Config.WorkerHeartbeatStale
+	}
+	var lastSeen *time.Time
+	if err := a.SQL.QueryRow(r.Context(), sqlinline.QSelectWorkerHeartbeat).Scan(&lastSeen); err != nil {
+		a.error(w, http.StatusServiceUnavailable, ErrUnavailable, "worker heartbeat unavailable")
+		return
+	}
+	resp := map[string]any{
+		"status":          "ok",
+		"last_heartbeat":  lastSeen,
+		"stale_after_sec": int(threshold / time.Second),
+	}
+	if lastSeen == nil || time.Since(*lastSeen) > threshold {
+		resp["status"] = "stale"
+		a.json(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	a.json(w, http.StatusOK, resp)
+}
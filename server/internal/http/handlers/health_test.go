@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"server/internal/infra"
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+type heartbeatSQL struct {
+	lastSeen *time.Time
+}
+
+func (h *heartbeatSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (h *heartbeatSQL) QueryRow(_ context.Context, query string, _ ...any) pgx.Row {
+	if query != sqlinline.QSelectWorkerHeartbeat {
+		return NewSimpleRow(func(...any) error { return errors.New("unexpected query") })
+	}
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(**time.Time) = h.lastSeen
+		return nil
+	})
+}
+
+func (h *heartbeatSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestWorkerHealth(t *testing.T) {
+	fresh := time.Now().Add(-5 * time.Second)
+	stale := time.Now().Add(-2 * time.Minute)
+	cases := []struct {
+		name       string
+		lastSeen   *time.Time
+		wantStatus int
+		wantState  string
+	}{
+		{name: "fresh", lastSeen: &fresh, wantStatus: http.StatusOK, wantState: "ok"},
+		{name: "stale", lastSeen: &stale, wantStatus: http.StatusServiceUnavailable, wantState: "stale"},
+		{name: "never seen", lastSeen: nil, wantStatus: http.StatusServiceUnavailable, wantState: "stale"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := &App{Config: &infra.Config{WorkerHeartbeatStale: 30 * time.Second}, SQL: &heartbeatSQL{lastSeen: tc.lastSeen}}
+			rr := httptest.NewRecorder()
+
+			app.WorkerHealth(rr, httptest.NewRequest("GET", "/v1/healthz/worker", nil))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			var payload map[string]any
+			if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if payload["status"] != tc.wantState {
+				t.Fatalf("status field = %v, want %s", payload["status"], tc.wantState)
+			}
+		})
+	}
+}
+
+type pingSQL struct {
+	heartbeatSQL
+	err      error
+	deadline bool
+}
+
+func (p *pingSQL) QueryRow(ctx context.Context, query string, _ ...any) pgx.Row {
+	if query != sqlinline.QPing {
+		return NewSimpleRow(func(...any) error { return errors.New("unexpected query") })
+	}
+	_, p.deadline = ctx.Deadline()
+	return NewSimpleRow(func(dest ...any) error {
+		if p.err != nil {
+			return p.err
+		}
+		*dest[0].(*int) = 1
+		return nil
+	})
+}
+
+func TestReady(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "healthy", wantStatus: http.StatusOK},
+		{name: "ping fails", err: context.DeadlineExceeded, wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &pingSQL{err: tc.err}
+			app := &App{Logger: zerolog.Nop(), SQL: stub}
+			rr := httptest.NewRecorder()
+
+			app.Ready(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			if !stub.deadline {
+				t.Fatalf("ping ran without a timeout")
+			}
+		})
+	}
+}
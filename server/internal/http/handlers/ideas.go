@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"time"
 
@@ -15,16 +14,18 @@ type ideasFromImageRequest struct {
 func (a *App) IdeasFromImage(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	var req ideasFromImageRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "invalid payload")
+	// The image travels base64 encoded, so allow the upload limit plus the
+	// encoding overhead rather than the JSON body cap.
+	limit := int64(a.uploadLimitMB(r)) << 20 * 4 / 3
+	if !a.decodeJSONLimit(w, r, &req, limit+a.jsonBodyLimit(), false) {
 		return
 	}
 	if req.ImageBase64 == "" {
-		a.error(w, http.StatusBadRequest, "bad_request", "image_base64 required")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "image_base64 required")
 		return
 	}
 	ideas := []jsoncfg.IdeaSuggestion{
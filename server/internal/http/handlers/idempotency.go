@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	idempotencyHeader       = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+var errInvalidIdempotencyKey = errors.New("idempotency key must be at most 255 characters")
+
+// idempotencyKey returns the trimmed Idempotency-Key header scoped to the
+// request's method and path, so one key reused on another endpoint does not
+// replay the wrong job. It is empty when the client did not send a key.
+func idempotencyKey(r *http.Request) (string, error) {
+	key := strings.TrimSpace(r.Header.Get(idempotencyHeader))
+	if len(key) > maxIdempotencyKeyLength {
+		return "", errInvalidIdempotencyKey
+	}
+	if key == "" {
+		return "", nil
+	}
+	return r.Method + " " + r.URL.Path + " " + key, nil
+}
+
+// reserveIdempotencyKey claims key before its job is created, so concurrent
+// retries cannot both enqueue. When another request holds the key it returns
+// reserved false with the job recorded for it and the user's remaining quota;
+// an empty job id means that request has not finished yet. A reservation that
+// never got a job, because its request crashed, can be reclaimed after five
+// minutes rather than blocking the key for a day.
+func (a *App) reserveIdempotencyKey(ctx context.Context, userID, key string) (reserved bool, jobID string, remaining int, err error) {
+	if key == "" || a.SQL == nil {
+		return true, "", 0, nil
+	}
+	var inserted bool
+	err = a.SQL.QueryRow(ctx, sqlinline.QReserveIdempotencyKey, userID, key).Scan(&inserted)
+	if err == nil {
+		return true, "", 0, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, "", 0, err
+	}
+	err = a.SQL.QueryRow(ctx, sqlinline.QLookupIdempotentJob, userID, key).Scan(&jobID, &remaining)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The holder released the key in the meantime; the client can retry.
+		return false, "", 0, nil
+	}
+	if err != nil {
+		return false, "", 0, err
+	}
+	return false, jobID, remaining, nil
+}
+
+// recordIdempotentJob remembers the job created for a reserved key. Failures
+// are logged rather than surfaced since the job itself was created
+// successfully.
+func (a *App) recordIdempotentJob(ctx context.Context, userID, key, jobID string) {
+	if key == "" || a.SQL == nil {
+		return
+	}
+	if _, err := a.SQL.Exec(context.WithoutCancel(ctx), sqlinline.QRecordIdempotentJob, userID, key, jobID); err != nil {
+		a.Logger.Warn().Err(err).Str("job_id", jobID).Msg("record idempotency key failed")
+	}
+}
+
+// releaseIdempotencyKey drops a reservation whose job was never created, so
+// the client can retry with the same key. It runs even when the request
+// context is already cancelled, since that is often why creation failed.
+func (a *App) releaseIdempotencyKey(ctx context.Context, userID, key string) {
+	if key == "" || a.SQL == nil {
+		return
+	}
+	if _, err := a.SQL.Exec(context.WithoutCancel(ctx), sqlinline.QReleaseIdempotencyKey, userID, key); err != nil {
+		a.Logger.Warn().Err(err).Msg("release idempotency key failed")
+	}
+}
@@ -4,36 +4,58 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"image"
 	_ "image/gif"
-	_ "image/jpeg"
+	"image/jpeg"
 	_ "image/png"
 	"io"
 	"math"
-	"net"
+	"mime"
 	"net/http"
 	"net/url"
 	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"server/internal/db"
 	"server/internal/domain/jsoncfg"
 	"server/internal/imagegen"
+	"server/internal/metrics"
+	"server/internal/netguard"
 	"server/internal/sqlinline"
+	"server/internal/webhook"
+	"server/pkg/exif"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
-const maxUploadBytes = 12 << 20
+const (
+	defaultMaxUploadMB  = 12
+	defaultSourceMaxMB  = 20
+	defaultJobListLimit = 20
+	maxJobListLimit     = 100
+	maxZipEntryBytes    = 32 << 20
+	zipFetchConcurrency = 4
+)
+
+type imageJobListResponse struct {
+	Items      []imageJobResponse `json:"items"`
+	NextOffset *int               `json:"next_offset"`
+}
 
 type imageJobResponse struct {
 	ID          string          `json:"id"`
@@ -54,37 +76,49 @@ type imageJobResponse struct {
 func (a *App) ImagesUpload(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
-	if a.FileStore == nil {
-		a.error(w, http.StatusInternalServerError, "internal", "file storage unavailable")
+	if a.Storage == nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "file storage unavailable")
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+1024)
-	if err := r.ParseMultipartForm(maxUploadBytes + 1024); err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "invalid upload payload")
+	limitMB := a.uploadLimitMB(r)
+	limit := int64(limitMB) << 20
+	r.Body = http.MaxBytesReader(w, r.Body, limit+1024)
+	if err := r.ParseMultipartForm(limit + 1024); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			a.error(w, http.StatusRequestEntityTooLarge, ErrTooLarge, fmt.Sprintf("file exceeds %dMB limit", limitMB))
+			return
+		}
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid upload payload")
 		return
 	}
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "file is required")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "file is required")
 		return
 	}
 	defer file.Close()
 
-	data, err := io.ReadAll(io.LimitReader(file, maxUploadBytes+1))
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
 	if err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "failed to read file")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "failed to read file")
 		return
 	}
 	if len(data) == 0 {
-		a.error(w, http.StatusBadRequest, "bad_request", "empty file")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "empty file")
 		return
 	}
-	if len(data) > maxUploadBytes {
-		a.error(w, http.StatusRequestEntityTooLarge, "too_large", "file exceeds 12MB limit")
+	if int64(len(data)) > limit {
+		a.error(w, http.StatusRequestEntityTooLarge, ErrTooLarge, fmt.Sprintf("file exceeds %dMB limit", limitMB))
+		return
+	}
+
+	if containsScriptSignature(data) {
+		a.error(w, http.StatusUnsupportedMediaType, ErrUnsupportedMediaType, "file contains embedded script content")
 		return
 	}
 
@@ -92,28 +126,47 @@ func (a *App) ImagesUpload(w http.ResponseWriter, r *http.Request) {
 	if len(sniff) > 512 {
 		sniff = sniff[:512]
 	}
-	detectedMIME := http.DetectContentType(sniff)
+	sniffedMIME := http.DetectContentType(sniff)
+	detectedMIME := sniffedMIME
 	width, height, normalizedMIME, err := decodeImageDimensions(data, detectedMIME)
 	if err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "unsupported image format")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "unsupported image format")
 		return
 	}
 	if normalizedMIME != "" {
 		detectedMIME = normalizedMIME
 	}
+	if !uploadFormatsAgree(header.Filename, sniffedMIME, detectedMIME) {
+		a.error(w, http.StatusUnsupportedMediaType, ErrUnsupportedMediaType, "file extension does not match its content")
+		return
+	}
 	if !isSupportedImageMime(detectedMIME) {
-		a.error(w, http.StatusBadRequest, "bad_request", "format not supported")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "format not supported")
+		return
+	}
+	if detectedMIME == "image/jpeg" {
+		if upright, uprightW, uprightH, err := normalizeJPEGOrientation(data); err != nil {
+			a.Logger.Warn().Err(err).Str("user_id", userID).Msg("exif orientation normalization failed")
+		} else if upright != nil {
+			data, width, height = upright, uprightW, uprightH
+		}
+	}
+	sum := sha256.Sum256(data)
+	contentHash := hex.EncodeToString(sum[:])
+	if existing, found := a.findUploadByHash(r.Context(), userID, contentHash); found {
+		a.json(w, http.StatusOK, existing)
 		return
 	}
+
 	aspect := deriveAspectLabel(width, height)
 	ext := extensionForUpload(detectedMIME)
 	if ext == "" {
 		ext = ".png"
 	}
 	storageKey := fmt.Sprintf("uploads/%s/%d%s", userID, time.Now().UnixNano(), ext)
-	savedKey, err := a.FileStore.Write(r.Context(), storageKey, data)
+	savedKey, err := a.Storage.Write(r.Context(), storageKey, data)
 	if err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to persist file")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to persist file")
 		return
 	}
 
@@ -122,6 +175,7 @@ func (a *App) ImagesUpload(w http.ResponseWriter, r *http.Request) {
 		"original_filename": header.Filename,
 		"filename":          filepath.Base(savedKey),
 		"url":               a.assetURL(savedKey),
+		"sha256":            contentHash,
 	}
 	if mode := strings.TrimSpace(r.FormValue("mode")); mode != "" {
 		props["mode"] = mode
@@ -148,7 +202,7 @@ func (a *App) ImagesUpload(w http.ResponseWriter, r *http.Request) {
 	)
 	var assetID string
 	if err := row.Scan(&assetID); err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to record upload")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to record upload")
 		return
 	}
 
@@ -164,6 +218,64 @@ func (a *App) ImagesUpload(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// uploadLimitMB resolves the upload size limit for the caller: the plan
+// override when one is configured, otherwise MAX_UPLOAD_MB.
+func (a *App) uploadLimitMB(r *http.Request) int {
+	limit := defaultMaxUploadMB
+	if a.Config != nil {
+		if a.Config.MaxUploadMB > 0 {
+			limit = a.Config.MaxUploadMB
+		}
+		if override := a.Config.PlanMaxUploadMB[a.callerPlan(r)]; override > 0 {
+			limit = override
+		}
+	}
+	return limit
+}
+
+// sourceLimitMB resolves how large a remote source image plan may reference:
+// the plan override when one is configured, otherwise SOURCE_IMAGE_MAX_MB.
+func (a *App) sourceLimitMB(plan string) int {
+	limit := defaultSourceMaxMB
+	if a.Config != nil {
+		if a.Config.SourceImageMaxMB > 0 {
+			limit = a.Config.SourceImageMaxMB
+		}
+		if override := a.Config.PlanSourceImageMB[plan]; override > 0 {
+			limit = override
+		}
+	}
+	return limit
+}
+
+// findUploadByHash returns the upload response for an asset the user already
+// stored with identical bytes. Lookup failures are logged and treated as a
+// miss so the upload still goes through.
+func (a *App) findUploadByHash(ctx context.Context, userID, contentHash string) (map[string]any, bool) {
+	var assetID, storageKey, mime, aspect string
+	var size int64
+	var width, height int
+	err := a.SQL.QueryRow(ctx, sqlinline.QFindAssetByHash, userID, contentHash).
+		Scan(&assetID, &storageKey, &mime, &size, &width, &height, &aspect)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			a.Logger.Warn().Err(err).Str("user_id", userID).Msg("upload dedup lookup failed")
+		}
+		return nil, false
+	}
+	return map[string]any{
+		"asset_id":     assetID,
+		"storage_key":  storageKey,
+		"mime":         mime,
+		"bytes":        size,
+		"width":        width,
+		"height":       height,
+		"aspect_ratio": aspect,
+		"url":          a.assetURL(storageKey),
+		"deduplicated": true,
+	}, true
+}
+
 func decodeImageDimensions(data []byte, fallback string) (int, int, string, error) {
 	reader := bytes.NewReader(data)
 	cfg, format, err := image.DecodeConfig(reader)
@@ -178,6 +290,25 @@ func decodeImageDimensions(data []byte, fallback string) (int, int, string, erro
 	return 0, 0, fallback, err
 }
 
+// normalizeJPEGOrientation rotates a JPEG carrying an EXIF orientation flag so
+// its pixels are stored upright. It returns nil data when no change is needed.
+func normalizeJPEGOrientation(data []byte) ([]byte, int, int, error) {
+	orientation := exif.Orientation(data)
+	if orientation == 1 {
+		return nil, 0, 0, nil
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	upright := exif.Apply(img, orientation)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, upright, &jpeg.Options{Quality: 92}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), upright.Bounds().Dx(), upright.Bounds().Dy(), nil
+}
+
 func decodeWebPDimensions(data []byte) (int, int, error) {
 	if len(data) < 30 {
 		return 0, 0, fmt.Errorf("webp: insufficient data")
@@ -304,63 +435,121 @@ func gcd(a, b int) int {
 	return a
 }
 
+// imageRequestProvider normalizes the provider named in an image request,
+// defaulting to qwen-image-plus.
+func imageRequestProvider(raw string) string {
+	provider := strings.TrimSpace(strings.ToLower(raw))
+	if provider == "" {
+		provider = "qwen-image-plus"
+	}
+	return provider
+}
+
+// imageEditProvider maps a normalized request provider onto the editor model
+// ImagesGenerate runs; ok is false for providers it does not serve.
+func imageEditProvider(provider string) (string, bool) {
+	switch provider {
+	case "qwen-image-plus", "qwen-image-edit":
+		return "qwen-image-edit", true
+	}
+	return "", false
+}
+
 func (a *App) ImagesGenerate(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	if a.ImageEditor == nil {
-		a.error(w, http.StatusServiceUnavailable, "unavailable", "image editor unavailable")
+		a.error(w, http.StatusServiceUnavailable, ErrUnavailable, "image editor unavailable")
 		return
 	}
 
 	var req imagegen.GenerateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "invalid payload")
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	key, err := idempotencyKey(r)
+	if err != nil {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, err.Error())
 		return
 	}
 
-	provider := strings.TrimSpace(strings.ToLower(req.Provider))
-	if provider == "" || provider == "qwen-image-plus" {
-		provider = "qwen-image-edit"
+	if req.Seed != nil && (*req.Seed < 1 || *req.Seed > jsoncfg.MaxSeed) {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, fmt.Sprintf("seed must be between 1 and %d", jsoncfg.MaxSeed))
+		return
+	}
+	titleMax, instructionsMax := jsoncfg.TextLimits()
+	if utf8.RuneCountInString(req.Prompt.Title) > titleMax {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, fmt.Sprintf("prompt.title must be at most %d characters", titleMax))
+		return
 	}
-	if provider != "qwen-image-edit" {
-		a.error(w, http.StatusBadRequest, "bad_request", "unsupported provider")
+	if utf8.RuneCountInString(req.Prompt.Instructions) > instructionsMax {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, fmt.Sprintf("prompt.instructions must be at most %d characters", instructionsMax))
 		return
 	}
 
-	sourceURL := strings.TrimSpace(req.Prompt.SourceAsset.URL)
-	parsedURL, err := url.Parse(sourceURL)
-	if err != nil || parsedURL == nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
-		a.error(w, http.StatusUnprocessableEntity, "invalid_source", "prompt.source_asset.url must be a public http(s) URL")
+	provider := imageRequestProvider(req.Provider)
+	if !a.requireProviderForPlan(w, r, provider) {
 		return
 	}
-	host := strings.ToLower(parsedURL.Hostname())
-	_, allowlisted := a.sourceHostAllowlist[host]
-	if err := ensurePublicHTTPURL(parsedURL, a.sourceHostAllowlist); err != nil {
-		a.error(w, http.StatusUnprocessableEntity, "invalid_source", err.Error())
+	provider, ok := imageEditProvider(provider)
+	if !ok {
+		a.error(w, http.StatusBadRequest, ErrUnsupportedProvider, "unsupported provider")
 		return
 	}
 
-	quantity := req.Quantity
-	if quantity <= 0 {
-		quantity = 1
+	callbackURL, err := a.parseCallbackURL(req.CallbackURL)
+	if err != nil {
+		a.error(w, http.StatusUnprocessableEntity, ErrInvalidCallback, err.Error())
+		return
+	}
+	if !a.allowedByModeration(w, r, req.Prompt.Title, req.Prompt.Instructions) {
+		return
 	}
-	if quantity > 8 {
-		quantity = 8
+	sourceURL := strings.TrimSpace(req.Prompt.SourceAsset.URL)
+	assetID := strings.TrimSpace(req.Prompt.SourceAsset.AssetID)
+	var uploaded *imagegen.SourceImage
+	var parsedURL *url.URL
+	var allowlisted bool
+	if _, uuidErr := uuid.Parse(assetID); sourceURL == "" && uuidErr == nil {
+		src, err := a.loadUploadedSource(r.Context(), userID, assetID)
+		if err != nil {
+			if errors.Is(err, errSourceAssetNotFound) {
+				a.error(w, http.StatusNotFound, ErrNotFound, "source asset not found")
+				return
+			}
+			a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load source asset")
+			return
+		}
+		uploaded = &src
+	} else {
+		parsedURL, err = url.Parse(sourceURL)
+		if err != nil || parsedURL == nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			a.error(w, http.StatusUnprocessableEntity, ErrInvalidSource, "prompt.source_asset.url must be a public http(s) URL")
+			return
+		}
+		host := strings.ToLower(parsedURL.Hostname())
+		_, allowlisted = a.sourceHostAllowlist[host]
+		if err := ensurePublicHTTPURL(parsedURL, a.trustedHosts); err != nil {
+			a.error(w, http.StatusUnprocessableEntity, ErrInvalidSource, err.Error())
+			return
+		}
 	}
 
+	quantity := jsoncfg.ClampQuantity(a.callerPlan(r), req.Quantity)
+
 	q := db.New(a.DB)
 
 	promptJSON, err := json.Marshal(req.Prompt)
 	if err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "failed to encode prompt")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "failed to encode prompt")
 		return
 	}
 	sourceJSON, err := json.Marshal(req.Prompt.SourceAsset)
 	if err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "failed to encode source asset")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "failed to encode source asset")
 		return
 	}
 
@@ -374,6 +563,17 @@ func (a *App) ImagesGenerate(w http.ResponseWriter, r *http.Request) {
 		userPtr = &userID
 	}
 
+	if reserved, jobID, _, err := a.reserveIdempotencyKey(r.Context(), userID, key); err != nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to check idempotency key")
+		return
+	} else if !reserved {
+		if jobID == "" {
+			a.error(w, http.StatusConflict, ErrConflict, "a request with this idempotency key is still in progress")
+			return
+		}
+		a.replayImageJob(w, r, jobID)
+		return
+	}
 	jobID, err := q.CreateImageJob(r.Context(), db.CreateImageJobParams{
 		UserID:      userPtr,
 		Provider:    provider,
@@ -384,22 +584,32 @@ func (a *App) ImagesGenerate(w http.ResponseWriter, r *http.Request) {
 		SourceAsset: sourceJSON,
 	})
 	if err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to create job")
+		a.releaseIdempotencyKey(r.Context(), userID, key)
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to create job")
 		return
 	}
+	a.recordIdempotentJob(r.Context(), userID, key, jobID.String())
+	metrics.JobsEnqueued.Inc("IMAGE_GEN", provider)
 
-	source, err := a.prepareSourceImage(r.Context(), sourceURL, parsedURL, req.Prompt.SourceAsset.AssetID, allowlisted)
-	if err != nil {
-		_ = q.FailImageJob(r.Context(), db.FailImageJobParams{ID: jobID, Error: err.Error()})
-		a.error(w, http.StatusUnprocessableEntity, "invalid_source", err.Error())
-		return
+	var source imagegen.SourceImage
+	if uploaded != nil {
+		source = *uploaded
+	} else {
+		source, err = a.prepareSourceImage(r.Context(), sourceURL, parsedURL, assetID, allowlisted, a.callerPlan(r))
+		if err != nil {
+			_ = q.FailImageJob(r.Context(), db.FailImageJobParams{ID: jobID, Error: err.Error()})
+			a.notifyCallback(callbackURL, webhook.Payload{JobID: jobID.String(), Status: "FAILED", Error: err.Error()})
+			a.error(w, http.StatusUnprocessableEntity, ErrInvalidSource, err.Error())
+			return
+		}
 	}
 
 	if err := q.StartImageJob(r.Context(), jobID); err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to start job")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to start job")
 		return
 	}
 
+	generationStarted := time.Now()
 	instruction := imagegen.BuildInstruction(req)
 	negative := ""
 	if req.Prompt.Extras != nil {
@@ -412,33 +622,39 @@ func (a *App) ImagesGenerate(w http.ResponseWriter, r *http.Request) {
 		url string
 		err error
 	}, quantity)
+	slotLimit := a.userImageLimit(a.callerPlan(r))
 	var wg sync.WaitGroup
 	for i := 0; i < quantity; i++ {
 		idx := i
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := a.acquireImageSlot(r.Context()); err != nil {
+			if err := a.acquireImageSlot(r.Context(), userID, slotLimit); err != nil {
 				results[idx].err = err
 				return
 			}
-			defer a.releaseImageSlot()
+			defer a.releaseImageSlot(userID)
 			ctx, cancel := context.WithTimeout(r.Context(), 90*time.Second)
 			defer cancel()
-			url, err := a.ImageEditor.EditOnce(ctx, source, instruction, req.Prompt.Watermark.Enabled, negative, nil)
-			results[idx] = struct {
-				url string
-				err error
-			}{url: url, err: err}
+			results[idx].url, results[idx].err = a.ImageEditor.EditOnce(ctx, source, instruction, req.Prompt.Watermark.Enabled, negative, req.Seed)
 		}()
 	}
 	wg.Wait()
+	var generationErr error
+	for _, res := range results {
+		if res.err != nil {
+			generationErr = res.err
+			break
+		}
+	}
+	metrics.ObserveGeneration("IMAGE_GEN", provider, time.Since(generationStarted), generationErr, true)
 
 	var urls []string
 	for _, res := range results {
 		if res.err != nil {
 			_ = q.FailImageJob(r.Context(), db.FailImageJobParams{ID: jobID, Error: res.err.Error()})
-			a.error(w, http.StatusBadGateway, "generation_failed", res.err.Error())
+			a.notifyCallback(callbackURL, webhook.Payload{JobID: jobID.String(), Status: "FAILED", Error: res.err.Error()})
+			a.error(w, http.StatusBadGateway, ErrGenerationFailed, res.err.Error())
 			return
 		}
 		urls = append(urls, res.url)
@@ -456,14 +672,15 @@ func (a *App) ImagesGenerate(w http.ResponseWriter, r *http.Request) {
 	outputJSON, err := json.Marshal(outputPayload)
 	if err != nil {
 		_ = q.FailImageJob(r.Context(), db.FailImageJobParams{ID: jobID, Error: err.Error()})
-		a.error(w, http.StatusInternalServerError, "internal", "failed to encode output")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to encode output")
 		return
 	}
 
 	if err := q.CompleteImageJob(r.Context(), db.CompleteImageJobParams{ID: jobID, Output: outputJSON}); err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to persist output")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to persist output")
 		return
 	}
+	a.notifyCallback(callbackURL, webhook.Payload{JobID: jobID.String(), Status: "SUCCEEDED", AssetURLs: urls})
 
 	a.json(w, http.StatusCreated, imagegen.GenerateResponse{
 		JobID:  jobID.String(),
@@ -472,37 +689,72 @@ func (a *App) ImagesGenerate(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// replayImageJob answers a repeated ImagesGenerate request with the job that
+// was created for the same idempotency key instead of generating again.
+func (a *App) replayImageJob(w http.ResponseWriter, r *http.Request, jobID string) {
+	id, err := uuid.Parse(jobID)
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "invalid idempotent job")
+		return
+	}
+	job, err := db.New(a.DB).GetImageJob(r.Context(), id)
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load job")
+		return
+	}
+	resp := imagegen.GenerateResponse{JobID: job.ID.String(), Status: job.Status}
+	var output struct {
+		Images []struct {
+			URL string `json:"url"`
+		} `json:"images"`
+	}
+	if len(job.Output) > 0 && json.Unmarshal(job.Output, &output) == nil {
+		for _, img := range output.Images {
+			resp.Images = append(resp.Images, img.URL)
+		}
+	}
+	if job.Error.Valid {
+		resp.Message = job.Error.String
+	}
+	a.json(w, http.StatusOK, resp)
+}
+
 func (a *App) ImageJob(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	idStr := chi.URLParam(r, "id")
 	if idStr == "" {
-		a.error(w, http.StatusBadRequest, "bad_request", "job id required")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "job id required")
 		return
 	}
 	jobID, err := uuid.Parse(idStr)
 	if err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "invalid job id")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid job id")
 		return
 	}
 	q := db.New(a.DB)
 	job, err := q.GetImageJob(r.Context(), jobID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			a.error(w, http.StatusNotFound, "not_found", "job not found")
+			a.queuedImageJob(w, r, jobID.String(), userID)
 			return
 		}
-		a.error(w, http.StatusInternalServerError, "internal", "failed to load job")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load job")
 		return
 	}
 	if job.UserID.Valid && job.UserID.String != userID {
-		a.error(w, http.StatusNotFound, "not_found", "job not found")
+		a.error(w, http.StatusNotFound, ErrNotFound, "job not found")
 		return
 	}
 
+	a.json(w, http.StatusOK, newImageJobResponse(job))
+}
+
+// newImageJobResponse maps an image_jobs row onto the public DTO.
+func newImageJobResponse(job db.ImageJob) imageJobResponse {
 	var aspectPtr *string
 	if job.AspectRatio.Valid {
 		v := job.AspectRatio.String
@@ -535,62 +787,195 @@ func (a *App) ImageJob(w http.ResponseWriter, r *http.Request) {
 	}
 	resp.Error = errPtr
 
+	return resp
+}
+
+// ListImageJobs pages through the caller's image jobs, newest first,
+// optionally narrowed by status and a created_at window.
+func (a *App) ListImageJobs(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = defaultJobListLimit
+	}
+	if limit > maxJobListLimit {
+		limit = maxJobListLimit
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	filter, err := parseImageJobFilter(r)
+	if err != nil {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, err.Error())
+		return
+	}
+	// Fetch one extra row to learn whether another page exists.
+	jobs, err := db.New(a.DB).ListImageJobsFiltered(r.Context(), db.ListImageJobsFilteredParams{
+		UserID: userID,
+		Status: filter.Status,
+		Since:  filter.Since,
+		Until:  filter.Until,
+		Limit:  int32(limit + 1),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load jobs")
+		return
+	}
+	resp := imageJobListResponse{Items: make([]imageJobResponse, 0, len(jobs))}
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+		next := offset + limit
+		resp.NextOffset = &next
+	}
+	for _, job := range jobs {
+		resp.Items = append(resp.Items, newImageJobResponse(job))
+	}
+	a.json(w, http.StatusOK, resp)
+}
+
+type imageJobFilter struct {
+	Status string
+	Since  *time.Time
+	Until  *time.Time
+}
+
+// parseImageJobFilter reads the status, since and until query parameters.
+// Dates must be RFC3339; until is exclusive.
+func parseImageJobFilter(r *http.Request) (imageJobFilter, error) {
+	var filter imageJobFilter
+	query := r.URL.Query()
+	if raw := strings.TrimSpace(query.Get("status")); raw != "" {
+		status := strings.ToUpper(raw)
+		switch status {
+		case "QUEUED", "RUNNING", "SUCCEEDED", "FAILED":
+			filter.Status = status
+		default:
+			return filter, fmt.Errorf("status must be one of QUEUED, RUNNING, SUCCEEDED, FAILED")
+		}
+	}
+	for _, bound := range []struct {
+		name string
+		dst  **time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		raw := strings.TrimSpace(query.Get(bound.name))
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC3339 timestamp", bound.name)
+		}
+		parsed = parsed.UTC()
+		*bound.dst = &parsed
+	}
+	if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+		return filter, fmt.Errorf("since must be before until")
+	}
+	return filter, nil
+}
+
+// ImageAssets lists the stored assets of a queued image job owned by the
+// caller, with storage keys resolved to public or signed URLs.
+func (a *App) ImageAssets(w http.ResponseWriter, r *http.Request) {
+	a.jobAssets(w, r, "IMAGE_GEN")
+}
+
+// queuedImageJob renders an image job processed by the background worker,
+// which lives in generation_requests rather than image_jobs.
+func (a *App) queuedImageJob(w http.ResponseWriter, r *http.Request, jobID, userID string) {
+	if a.SQL == nil {
+		a.error(w, http.StatusNotFound, ErrNotFound, "job not found")
+		return
+	}
+	job, err := a.loadJobForUser(r.Context(), jobID, userID)
+	if err != nil || job.TaskType != "IMAGE_GEN" {
+		a.error(w, http.StatusNotFound, ErrNotFound, "job not found")
+		return
+	}
+	resp := imageJobResponse{
+		ID:        job.ID,
+		UserID:    job.UserID,
+		Provider:  job.Provider,
+		Model:     job.Provider,
+		Status:    job.Status,
+		Quantity:  int32(job.Quantity),
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+	if job.Aspect != "" {
+		aspect := job.Aspect
+		resp.AspectRatio = &aspect
+	}
+	if msg := job.ErrorMessage(); msg != "" {
+		resp.Error = &msg
+	}
 	a.json(w, http.StatusOK, resp)
 }
 
 func (a *App) ImageDownload(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	idStr := chi.URLParam(r, "job_id")
 	if idStr == "" {
-		a.error(w, http.StatusBadRequest, "bad_request", "job id required")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "job id required")
 		return
 	}
 	jobID, err := uuid.Parse(idStr)
 	if err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "invalid job id")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid job id")
 		return
 	}
 	q := db.New(a.DB)
 	job, err := q.GetImageJob(r.Context(), jobID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			a.error(w, http.StatusNotFound, "not_found", "job not found")
+			a.error(w, http.StatusNotFound, ErrNotFound, "job not found")
 			return
 		}
-		a.error(w, http.StatusInternalServerError, "internal", "failed to load job")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load job")
 		return
 	}
 	if job.UserID.Valid && job.UserID.String != userID {
-		a.error(w, http.StatusNotFound, "not_found", "job not found")
+		a.error(w, http.StatusNotFound, ErrNotFound, "job not found")
 		return
 	}
 	if job.Status != "SUCCEEDED" || len(job.Output) == 0 {
-		a.error(w, http.StatusConflict, "job_pending", "job has not completed")
+		a.error(w, http.StatusConflict, ErrJobPending, "job has not completed")
+		return
+	}
+	filename, ok := downloadFilename(r.URL.Query().Get("filename"), job.Prompt, job.ID)
+	if !ok {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid filename")
 		return
 	}
 	urls := extractImageURLs(job.Output)
 	if len(urls) == 0 {
-		a.error(w, http.StatusNotFound, "no_image", "no image available")
+		a.error(w, http.StatusNotFound, ErrNoImage, "no image available")
 		return
 	}
 
 	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, urls[0], nil)
 	if err != nil {
-		a.error(w, http.StatusBadGateway, "download_error", err.Error())
+		a.error(w, http.StatusBadGateway, ErrDownloadFailed, err.Error())
 		return
 	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		a.error(w, http.StatusBadGateway, "download_error", err.Error())
+		a.error(w, http.StatusBadGateway, ErrDownloadFailed, err.Error())
 		return
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= http.StatusBadRequest {
-		a.error(w, http.StatusBadGateway, "download_error", fmt.Sprintf("remote status %d", resp.StatusCode))
+		a.error(w, http.StatusBadGateway, ErrDownloadFailed, fmt.Sprintf("remote status %d", resp.StatusCode))
 		return
 	}
 
@@ -599,7 +984,7 @@ func (a *App) ImageDownload(w http.ResponseWriter, r *http.Request) {
 		contentType = "image/png"
 	}
 	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=job-%s.png", job.ID.String()))
+	w.Header().Set("Content-Disposition", attachmentDisposition(filename+imageExtension(contentType, urls[0])))
 	w.WriteHeader(http.StatusOK)
 	_, _ = io.Copy(w, resp.Body)
 }
@@ -607,74 +992,176 @@ func (a *App) ImageDownload(w http.ResponseWriter, r *http.Request) {
 func (a *App) ImageDownloadZip(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	idStr := chi.URLParam(r, "job_id")
 	if idStr == "" {
-		a.error(w, http.StatusBadRequest, "bad_request", "job id required")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "job id required")
 		return
 	}
 	jobID, err := uuid.Parse(idStr)
 	if err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "invalid job id")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid job id")
 		return
 	}
 	q := db.New(a.DB)
 	job, err := q.GetImageJob(r.Context(), jobID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			a.error(w, http.StatusNotFound, "not_found", "job not found")
+			a.error(w, http.StatusNotFound, ErrNotFound, "job not found")
 			return
 		}
-		a.error(w, http.StatusInternalServerError, "internal", "failed to load job")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load job")
 		return
 	}
 	if job.UserID.Valid && job.UserID.String != userID {
-		a.error(w, http.StatusNotFound, "not_found", "job not found")
+		a.error(w, http.StatusNotFound, ErrNotFound, "job not found")
 		return
 	}
 
+	filename, ok := downloadFilename(r.URL.Query().Get("filename"), job.Prompt, job.ID)
+	if !ok {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid filename")
+		return
+	}
 	urls := extractImageURLs(job.Output)
 	if len(urls) == 0 {
-		a.error(w, http.StatusNotFound, "no_image", "no image available")
+		a.error(w, http.StatusNotFound, ErrNoImage, "no image available")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=job-%s.zip", job.ID.String()))
+	w.Header().Set("Content-Disposition", attachmentDisposition(filename+".zip"))
 
 	zipWriter := zip.NewWriter(w)
-	defer zipWriter.Close()
-
-	for idx, imgURL := range urls {
-		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, imgURL, nil)
-		if err != nil {
+	flusher, _ := w.(http.Flusher)
+	manifest := zipManifest{JobID: job.ID.String(), Total: len(urls), Files: make([]zipManifestFile, 0, len(urls))}
+	for idx, pending := range a.fetchZipEntries(r.Context(), urls) {
+		entry := <-pending
+		file := zipManifestFile{Index: idx + 1, SourceURL: urls[idx], Status: "ok"}
+		if entry.err != nil {
+			file.Status = "failed"
+			file.Error = entry.err.Error()
+			manifest.Files = append(manifest.Files, file)
 			continue
 		}
-		resp, err := http.DefaultClient.Do(req)
+		file.Name = fmt.Sprintf("%s_%02d%s", filename, idx+1, entry.ext)
+		writer, err := zipWriter.Create(file.Name)
 		if err != nil {
-			continue
-		}
-		if resp.StatusCode >= http.StatusBadRequest {
-			resp.Body.Close()
-			continue
+			break
 		}
-		filename := fmt.Sprintf("image_%02d.png", idx+1)
-		if ct := resp.Header.Get("Content-Type"); strings.Contains(ct, "jpeg") {
-			filename = fmt.Sprintf("image_%02d.jpg", idx+1)
+		if _, err := writer.Write(entry.data); err != nil {
+			break
 		}
-		writer, err := zipWriter.Create(filename)
-		if err != nil {
-			resp.Body.Close()
-			continue
+		manifest.Included++
+		manifest.Files = append(manifest.Files, file)
+		// Push each finished entry to the client instead of buffering the
+		// whole archive behind the slowest download.
+		_ = zipWriter.Flush()
+		if flusher != nil {
+			flusher.Flush()
 		}
-		_, _ = io.Copy(writer, resp.Body)
-		resp.Body.Close()
 	}
+	if writer, err := zipWriter.Create("manifest.json"); err == nil {
+		enc := json.NewEncoder(writer)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(manifest)
+	}
+	_ = zipWriter.Close()
+}
+
+// zipManifest is written as manifest.json at the end of every job archive so
+// clients can tell which sources were skipped and why.
+type zipManifest struct {
+	JobID    string            `json:"job_id"`
+	Included int               `json:"included"`
+	Total    int               `json:"total"`
+	Files    []zipManifestFile `json:"files"`
+}
+
+type zipManifestFile struct {
+	Index     int    `json:"index"`
+	Name      string `json:"name,omitempty"`
+	SourceURL string `json:"source_url"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+type zipEntry struct {
+	ext  string
+	data []byte
+	err  error
 }
 
-func (a *App) acquireImageSlot(ctx context.Context) error {
+// fetchZipEntries downloads urls concurrently, at most zipFetchConcurrency at
+// a time. The returned channels are in url order so the archive layout stays
+// deterministic even though downloads finish out of order.
+func (a *App) fetchZipEntries(ctx context.Context, urls []string) []chan zipEntry {
+	client := a.sourceFetcher
+	if client == nil {
+		client = http.DefaultClient
+	}
+	sem := make(chan struct{}, zipFetchConcurrency)
+	results := make([]chan zipEntry, len(urls))
+	for idx, imgURL := range urls {
+		results[idx] = make(chan zipEntry, 1)
+		go func(idx int, imgURL string, out chan<- zipEntry) {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				out <- zipEntry{err: ctx.Err()}
+				return
+			}
+			defer func() { <-sem }()
+			out <- fetchZipEntry(ctx, client, imgURL)
+		}(idx, imgURL, results[idx])
+	}
+	return results
+}
+
+func fetchZipEntry(ctx context.Context, client httpDoer, imgURL string) zipEntry {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imgURL, nil)
+	if err != nil {
+		return zipEntry{err: err}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return zipEntry{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return zipEntry{err: fmt.Errorf("source returned status %d", resp.StatusCode)}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxZipEntryBytes+1))
+	if err != nil {
+		return zipEntry{err: err}
+	}
+	if len(data) > maxZipEntryBytes {
+		return zipEntry{err: fmt.Errorf("image exceeds %d bytes", maxZipEntryBytes)}
+	}
+	return zipEntry{ext: imageExtension(resp.Header.Get("Content-Type"), imgURL), data: data}
+}
+
+// userImageLimit resolves how many synchronous generation slots one user on
+// plan may hold at once. Zero means the user is only bound by the global cap.
+func (a *App) userImageLimit(plan string) int {
+	if a.Config == nil {
+		return 0
+	}
+	if override := a.Config.PlanImageConcurrency[plan]; override > 0 {
+		return override
+	}
+	return a.Config.UserImageConcurrency
+}
+
+// acquireImageSlot waits for one of userID's slots (when userLimit is
+// positive) and then for a global slot, so a user queued behind their own cap
+// never holds a global slot while waiting.
+func (a *App) acquireImageSlot(ctx context.Context, userID string, userLimit int) error {
+	if err := a.userImageSlots.acquire(ctx, userID, userLimit); err != nil {
+		return err
+	}
 	if a.imageLimiter == nil {
 		return nil
 	}
@@ -682,11 +1169,13 @@ func (a *App) acquireImageSlot(ctx context.Context) error {
 	case a.imageLimiter <- struct{}{}:
 		return nil
 	case <-ctx.Done():
+		a.userImageSlots.release(userID)
 		return ctx.Err()
 	}
 }
 
-func (a *App) releaseImageSlot() {
+func (a *App) releaseImageSlot(userID string) {
+	a.userImageSlots.release(userID)
 	if a.imageLimiter == nil {
 		return
 	}
@@ -696,6 +1185,165 @@ func (a *App) releaseImageSlot() {
 	}
 }
 
+// userSlots counts the generation slots each user holds. Waiters are woken by
+// closing and replacing changed whenever a slot is released.
+type userSlots struct {
+	mu      sync.Mutex
+	held    map[string]int
+	changed chan struct{}
+}
+
+func newUserSlots() *userSlots {
+	return &userSlots{held: make(map[string]int), changed: make(chan struct{})}
+}
+
+// acquire blocks until userID holds fewer than limit slots and takes one. A
+// non-positive limit never blocks; a nil receiver or empty userID is a no-op.
+func (s *userSlots) acquire(ctx context.Context, userID string, limit int) error {
+	if s == nil || userID == "" {
+		return nil
+	}
+	for {
+		s.mu.Lock()
+		if limit <= 0 || s.held[userID] < limit {
+			s.held[userID]++
+			s.mu.Unlock()
+			return nil
+		}
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *userSlots) release(userID string) {
+	if s == nil || userID == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held[userID] == 0 {
+		return
+	}
+	if s.held[userID]--; s.held[userID] == 0 {
+		delete(s.held, userID)
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// DrainImageSlots claims every image slot, waiting for in-flight synchronous
+// generations to release theirs, so shutdown does not cut them off mid-call.
+// The slots stay claimed afterwards and it gives up when ctx is done.
+func (a *App) DrainImageSlots(ctx context.Context) error {
+	if a.imageLimiter == nil {
+		return nil
+	}
+	for i := 0; i < cap(a.imageLimiter); i++ {
+		select {
+		case a.imageLimiter <- struct{}{}:
+		case <-ctx.Done():
+			return fmt.Errorf("drain image slots: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
+// maxDownloadFilename caps the basename (without extension) offered in
+// Content-Disposition.
+const maxDownloadFilename = 100
+
+// downloadFilename picks the attachment basename for a job download: the
+// caller's ?filename= when given, else the prompt title, else job-<id>. Any
+// directory part is dropped and the result is reduced to a safe character
+// set. It reports false when requested contains control characters.
+func downloadFilename(requested string, promptJSON []byte, jobID uuid.UUID) (string, bool) {
+	if strings.IndexFunc(requested, unicode.IsControl) >= 0 {
+		return "", false
+	}
+	if name := sanitizeFilename(requestedBase(requested)); name != "" {
+		return name, true
+	}
+	var meta struct {
+		Title string `json:"title"`
+	}
+	if len(promptJSON) > 0 && json.Unmarshal(promptJSON, &meta) == nil {
+		if name := sanitizeFilename(meta.Title); name != "" {
+			return name, true
+		}
+	}
+	return "job-" + jobID.String(), true
+}
+
+// requestedBase drops any directory part and extension from a caller-supplied
+// filename; both slash styles count as separators.
+func requestedBase(raw string) string {
+	raw = strings.TrimSpace(strings.ReplaceAll(raw, "\\", "/"))
+	if raw == "" {
+		return ""
+	}
+	base := path.Base(raw)
+	if base == "." || base == ".." || base == "/" {
+		return ""
+	}
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// sanitizeFilename replaces anything outside [A-Za-z0-9._-] with underscores
+// and trims the result to maxDownloadFilename bytes.
+func sanitizeFilename(raw string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	name := strings.Trim(b.String(), "._")
+	if len(name) > maxDownloadFilename {
+		name = strings.TrimRight(name[:maxDownloadFilename], "._")
+	}
+	return name
+}
+
+func attachmentDisposition(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+}
+
+// imageExtension picks a file extension for a downloaded image from its
+// Content-Type, falling back to the source URL's extension when the type is
+// missing or generic, and to .png otherwise.
+func imageExtension(contentType, sourceURL string) string {
+	switch {
+	case strings.Contains(contentType, "jpeg"):
+		return ".jpg"
+	case strings.Contains(contentType, "webp"):
+		return ".webp"
+	case strings.Contains(contentType, "png"):
+		return ".png"
+	case strings.Contains(contentType, "gif"):
+		return ".gif"
+	}
+	if parsed, err := url.Parse(sourceURL); err == nil {
+		switch ext := strings.ToLower(path.Ext(parsed.Path)); ext {
+		case ".jpg", ".jpeg":
+			return ".jpg"
+		case ".png", ".webp", ".gif":
+			return ext
+		}
+	}
+	return ".png"
+}
+
+// extractImageURLs reads the image URLs from a job's output JSON. Both the
+// {"images":[{"url":...}]} shape written by ImagesGenerate and a single
+// top-level {"url":...} are accepted; malformed output yields nil.
 func extractImageURLs(raw []byte) []string {
 	if len(raw) == 0 {
 		return nil
@@ -704,20 +1352,24 @@ func extractImageURLs(raw []byte) []string {
 		Images []struct {
 			URL string `json:"url"`
 		} `json:"images"`
+		URL string `json:"url"`
 	}
 	if err := json.Unmarshal(raw, &payload); err != nil {
 		return nil
 	}
-	urls := make([]string, 0, len(payload.Images))
+	urls := make([]string, 0, len(payload.Images)+1)
 	for _, item := range payload.Images {
 		if u := strings.TrimSpace(item.URL); u != "" {
 			urls = append(urls, u)
 		}
 	}
+	if u := strings.TrimSpace(payload.URL); u != "" && !slices.Contains(urls, u) {
+		urls = append(urls, u)
+	}
 	return urls
 }
 
-func (a *App) prepareSourceImage(ctx context.Context, rawURL string, parsed *url.URL, assetID string, allowlisted bool) (imagegen.SourceImage, error) {
+func (a *App) prepareSourceImage(ctx context.Context, rawURL string, parsed *url.URL, assetID string, allowlisted bool, plan string) (imagegen.SourceImage, error) {
 	src := imagegen.SourceImage{URL: rawURL}
 	baseName := strings.TrimSpace(path.Base(parsed.Path))
 	if baseName != "" && baseName != "." && baseName != "/" {
@@ -726,7 +1378,7 @@ func (a *App) prepareSourceImage(ctx context.Context, rawURL string, parsed *url
 		src.Name = strings.TrimSpace(assetID)
 	}
 	if allowlisted {
-		data, mimeType, err := a.fetchAllowlistedSource(ctx, rawURL)
+		data, mimeType, err := a.fetchAllowlistedSource(ctx, rawURL, plan)
 		if err != nil {
 			return imagegen.SourceImage{}, err
 		}
@@ -749,6 +1401,51 @@ func (a *App) prepareSourceImage(ctx context.Context, rawURL string, parsed *url
 	return src, nil
 }
 
+var errSourceAssetNotFound = errors.New("source asset not found")
+
+// loadUploadedSource reads a previously uploaded asset owned by userID from the
+// file store so it can be edited without being hosted at a public URL.
+func (a *App) loadUploadedSource(ctx context.Context, userID, assetID string) (imagegen.SourceImage, error) {
+	row := a.SQL.QueryRow(ctx, sqlinline.QSelectAssetByID, assetID)
+	var id, ownerID, storageKey, mime string
+	var size int64
+	var width, height int
+	var aspect string
+	var props []byte
+	if err := row.Scan(&id, &ownerID, &storageKey, &mime, &size, &width, &height, &aspect, &props); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return imagegen.SourceImage{}, errSourceAssetNotFound
+		}
+		return imagegen.SourceImage{}, err
+	}
+	if ownerID != userID {
+		return imagegen.SourceImage{}, errSourceAssetNotFound
+	}
+	if a.Storage == nil {
+		return imagegen.SourceImage{}, errors.New("file storage unavailable")
+	}
+	data, err := a.Storage.Read(ctx, storageKey)
+	if err != nil {
+		return imagegen.SourceImage{}, fmt.Errorf("read source asset: %w", err)
+	}
+	if width == 0 || height == 0 {
+		if w, h, normalized, dimErr := decodeImageDimensions(data, mime); dimErr == nil {
+			width, height = w, h
+			if normalized != "" {
+				mime = normalized
+			}
+		}
+	}
+	return imagegen.SourceImage{
+		URL:      a.assetURL(storageKey),
+		Data:     data,
+		MIMEType: mime,
+		Name:     filepath.Base(storageKey),
+		Width:    width,
+		Height:   height,
+	}, nil
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -756,7 +1453,9 @@ func min(a, b int) int {
 	return b
 }
 
-func (a *App) fetchAllowlistedSource(ctx context.Context, rawURL string) ([]byte, string, error) {
+// fetchAllowlistedSource downloads a source image from an allowlisted host,
+// refusing bodies larger than plan's source limit.
+func (a *App) fetchAllowlistedSource(ctx context.Context, rawURL, plan string) ([]byte, string, error) {
 	client := a.sourceFetcher
 	if client == nil {
 		client = http.DefaultClient
@@ -773,13 +1472,14 @@ func (a *App) fetchAllowlistedSource(ctx context.Context, rawURL string) ([]byte
 	if resp.StatusCode >= 300 {
 		return nil, "", fmt.Errorf("failed to fetch source asset: http %d", resp.StatusCode)
 	}
-	const maxSourceBytes = 20 << 20
+	limitMB := a.sourceLimitMB(plan)
+	maxSourceBytes := int64(limitMB) << 20
 	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSourceBytes+1))
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to read source asset: %w", err)
 	}
-	if len(data) > maxSourceBytes {
-		return nil, "", errors.New("source asset exceeds 20MB limit")
+	if int64(len(data)) > maxSourceBytes {
+		return nil, "", fmt.Errorf("source asset exceeds the %dMB limit for the %s plan", limitMB, plan)
 	}
 	mimeType := strings.TrimSpace(resp.Header.Get("Content-Type"))
 	if idx := strings.Index(mimeType, ";"); idx >= 0 {
@@ -788,22 +1488,11 @@ func (a *App) fetchAllowlistedSource(ctx context.Context, rawURL string) ([]byte
 	return data, mimeType, nil
 }
 
-func ensurePublicHTTPURL(u *url.URL, allowlist map[string]struct{}) error {
-	host := strings.TrimSpace(u.Hostname())
-	if host == "" {
+func ensurePublicHTTPURL(u *url.URL, trusted map[string]struct{}) error {
+	switch err := netguard.CheckHost(u, trusted); {
+	case errors.Is(err, netguard.ErrNoHost):
 		return errors.New("prompt.source_asset.url must include a hostname")
-	}
-	lower := strings.ToLower(host)
-	if _, ok := allowlist[lower]; ok {
-		return nil
-	}
-	if ip := net.ParseIP(host); ip != nil {
-		if ip.IsLoopback() || ip.IsUnspecified() || ip.IsPrivate() || ip.IsLinkLocalMulticast() || ip.IsLinkLocalUnicast() {
-			return errors.New("prompt.source_asset.url must be publicly accessible")
-		}
-		return nil
-	}
-	if lower == "localhost" || strings.HasSuffix(lower, ".local") || strings.HasSuffix(lower, ".internal") {
+	case err != nil:
 		return errors.New("prompt.source_asset.url must be publicly accessible")
 	}
 	return nil
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"server/internal/domain/jsoncfg"
+	"server/internal/imagegen"
+)
+
+type imageEstimateResponse struct {
+	Provider       string `json:"provider"`
+	Quantity       int    `json:"quantity"`
+	Cost           int    `json:"cost"`
+	QuotaDaily     int    `json:"quota_daily"`
+	QuotaUsedToday int    `json:"quota_used_today"`
+	Remaining      int    `json:"remaining"`
+	RemainingAfter int    `json:"remaining_after"`
+	Allowed        bool   `json:"allowed"`
+}
+
+// ImagesEstimate applies the same provider and quantity rules as
+// ImagesGenerate and reports the projected quota cost. Nothing is enqueued
+// and no quota is consumed.
+func (a *App) ImagesEstimate(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+
+	var req imagegen.GenerateRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	provider := imageRequestProvider(req.Provider)
+	if !a.requireProviderForPlan(w, r, provider) {
+		return
+	}
+	if provider != "qwen-image-plus" && provider != "qwen-image-edit" {
+		a.error(w, http.StatusBadRequest, ErrUnsupportedProvider, "unsupported provider")
+		return
+	}
+
+	quota, err := a.loadQuota(r.Context(), userID)
+	if err != nil {
+		a.error(w, http.StatusNotFound, ErrNotFound, "user not found")
+		return
+	}
+	quantity := jsoncfg.ClampQuantity(a.callerPlan(r), req.Quantity)
+	a.json(w, http.StatusOK, imageEstimateResponse{
+		Provider:       provider,
+		Quantity:       quantity,
+		Cost:           quantity,
+		QuotaDaily:     quota.QuotaDaily,
+		QuotaUsedToday: quota.QuotaUsedToday,
+		Remaining:      quota.Remaining,
+		RemainingAfter: max(quota.Remaining-quantity, 0),
+		Allowed:        quota.Remaining >= quantity,
+	})
+}
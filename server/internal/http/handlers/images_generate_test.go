@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"database/sql"
@@ -10,15 +11,19 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"server/internal/db"
+	"server/internal/domain/jsoncfg"
 	"server/internal/imagegen"
 	"server/internal/infra"
 	"server/internal/middleware"
+	"server/internal/storage"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -50,8 +55,10 @@ func (r stubRow) Scan(dest ...any) error {
 }
 
 type stubDB struct {
-	mu   sync.Mutex
-	jobs map[uuid.UUID]*db.ImageJob
+	mu        sync.Mutex
+	jobs      map[uuid.UUID]*db.ImageJob
+	listArgs  []any
+	createErr error
 }
 
 func newStubDB() *stubDB {
@@ -99,11 +106,83 @@ func (s *stubDB) Exec(ctx context.Context, query string, args ...any) (pgconn.Co
 }
 
 func (s *stubDB) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
-	return nil, fmt.Errorf("unsupported query: %s", query)
+	if !strings.Contains(query, "FROM image_jobs") || !strings.Contains(query, "WHERE user_id = $1") {
+		return nil, fmt.Errorf("unsupported query: %s", query)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listArgs = append([]any(nil), args...)
+	userID := args[0].(string)
+	limit, offset := int(args[len(args)-2].(int32)), int(args[len(args)-1].(int32))
+	var status string
+	var since, until *time.Time
+	if len(args) == 6 {
+		status, since, until = args[1].(string), args[2].(*time.Time), args[3].(*time.Time)
+	}
+	var matched []db.ImageJob
+	for _, job := range s.jobs {
+		if !job.UserID.Valid || job.UserID.String != userID {
+			continue
+		}
+		if (status != "" && job.Status != status) ||
+			(since != nil && job.CreatedAt.Before(*since)) ||
+			(until != nil && !job.CreatedAt.Before(*until)) {
+			continue
+		}
+		matched = append(matched, *job)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].CreatedAt.After(matched[j].CreatedAt) })
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	matched = matched[offset:]
+	if limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return &imageJobRows{jobs: matched}, nil
 }
 
+type imageJobRows struct {
+	TestRowsBase
+	jobs []db.ImageJob
+	idx  int
+}
+
+func (r *imageJobRows) Next() bool {
+	if r.idx >= len(r.jobs) {
+		return false
+	}
+	r.idx++
+	return true
+}
+
+func (r *imageJobRows) Scan(dest ...any) error {
+	job := r.jobs[r.idx-1]
+	*dest[0].(*uuid.UUID) = job.ID
+	*dest[1].(*sql.NullString) = job.UserID
+	*dest[2].(*string) = job.Provider
+	*dest[3].(*string) = job.Model
+	*dest[4].(*string) = job.Status
+	*dest[5].(*int32) = job.Quantity
+	*dest[6].(*sql.NullString) = job.AspectRatio
+	*dest[7].(*[]byte) = job.Prompt
+	*dest[8].(*[]byte) = job.SourceAsset
+	*dest[9].(*[]byte) = job.Output
+	*dest[10].(*sql.NullString) = job.Error
+	*dest[11].(*time.Time) = job.CreatedAt
+	*dest[12].(*time.Time) = job.UpdatedAt
+	return nil
+}
+
+func (r *imageJobRows) Err() error { return nil }
+
+func (r *imageJobRows) Close() {}
+
 func (s *stubDB) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
 	if strings.Contains(query, "INSERT INTO image_jobs") {
+		if s.createErr != nil {
+			return stubRow{scan: func(...any) error { return s.createErr }}
+		}
 		id := uuid.New()
 		job := &db.ImageJob{
 			ID:          id,
@@ -136,6 +215,30 @@ func (s *stubDB) QueryRow(ctx context.Context, query string, args ...any) pgx.Ro
 			return fmt.Errorf("unsupported scan target")
 		}}
 	}
+	if strings.Contains(query, "FROM image_jobs") && strings.Contains(query, "WHERE id = $1") {
+		s.mu.Lock()
+		job := s.jobs[args[0].(uuid.UUID)]
+		s.mu.Unlock()
+		if job == nil {
+			return NewSimpleRow(nil)
+		}
+		return stubRow{scan: func(dest ...any) error {
+			*dest[0].(*uuid.UUID) = job.ID
+			*dest[1].(*sql.NullString) = job.UserID
+			*dest[2].(*string) = job.Provider
+			*dest[3].(*string) = job.Model
+			*dest[4].(*string) = job.Status
+			*dest[5].(*int32) = job.Quantity
+			*dest[6].(*sql.NullString) = job.AspectRatio
+			*dest[7].(*[]byte) = job.Prompt
+			*dest[8].(*[]byte) = job.SourceAsset
+			*dest[9].(*[]byte) = job.Output
+			*dest[10].(*sql.NullString) = job.Error
+			*dest[11].(*time.Time) = job.CreatedAt
+			*dest[12].(*time.Time) = job.UpdatedAt
+			return nil
+		}}
+	}
 	return stubRow{scan: func(dest ...any) error {
 		return fmt.Errorf("unsupported query: %s", query)
 	}}
@@ -255,6 +358,8 @@ func TestImagesGenerateHandler(t *testing.T) {
 			},
 		},
 		configure: func(app *App) {
+			// localhost stands in for the asset store, a trusted host.
+			app.trustedHosts = map[string]struct{}{"localhost": {}}
 			app.sourceFetcher = &stubFetcher{body: tinyTransparentPNG, contentType: "image/png"}
 		},
 		verify: func(t *testing.T, editor *stubEditor) {
@@ -273,6 +378,58 @@ func TestImagesGenerateHandler(t *testing.T) {
 				t.Fatalf("unexpected dimensions: %dx%d", editor.sources[0].Width, editor.sources[0].Height)
 			}
 		},
+	}, {
+		name: "source within plan limit",
+		editor: func() *stubEditor {
+			return &stubEditor{urls: []string{"https://example.com/one.png"}}
+		},
+		allowlist:  []string{"localhost"},
+		wantStatus: http.StatusCreated,
+		wantImages: 1,
+		wantJob:    "SUCCEEDED",
+		body: map[string]any{
+			"provider": "qwen-image-plus",
+			"quantity": 1,
+			"prompt": map[string]any{
+				"title":        "Sample",
+				"watermark":    map[string]any{"enabled": false},
+				"source_asset": map[string]any{"asset_id": "upl", "url": "http://localhost:1919/static/uploads/file.png"},
+			},
+		},
+		configure: func(app *App) {
+			app.trustedHosts = map[string]struct{}{"localhost": {}}
+			app.Config.PlanSourceImageMB = map[string]int{"free": 1}
+			app.sourceFetcher = &stubFetcher{body: tinyTransparentPNG, contentType: "image/png"}
+		},
+	}, {
+		name: "source exceeds plan limit",
+		editor: func() *stubEditor {
+			return &stubEditor{urls: []string{"https://example.com/one.png"}}
+		},
+		allowlist:  []string{"localhost"},
+		wantStatus: http.StatusUnprocessableEntity,
+		wantJob:    "FAILED",
+		body: map[string]any{
+			"provider": "qwen-image-plus",
+			"quantity": 1,
+			"prompt": map[string]any{
+				"title":        "Sample",
+				"watermark":    map[string]any{"enabled": false},
+				"source_asset": map[string]any{"asset_id": "upl", "url": "http://localhost:1919/static/uploads/file.png"},
+			},
+		},
+		configure: func(app *App) {
+			app.trustedHosts = map[string]struct{}{"localhost": {}}
+			app.Config.PlanSourceImageMB = map[string]int{"free": 1}
+			app.sourceFetcher = &stubFetcher{body: make([]byte, 1<<20+1), contentType: "image/png"}
+		},
+		verify: func(t *testing.T, editor *stubEditor) {
+			editor.mu.Lock()
+			defer editor.mu.Unlock()
+			if len(editor.sources) != 0 {
+				t.Fatalf("expected editor not to be called, got %d sources", len(editor.sources))
+			}
+		},
 	}, {
 		name:       "editor failure",
 		editor:     func() *stubEditor { return &stubEditor{err: errors.New("generation failed")} },
@@ -366,6 +523,71 @@ func TestImagesGenerateHandler(t *testing.T) {
 	}
 }
 
+func TestImagesGenerateEnforcesTextLimits(t *testing.T) {
+	titleMax, instructionsMax := jsoncfg.TextLimits()
+	for _, tc := range []struct {
+		field string
+		value string
+	}{
+		{field: "title", value: strings.Repeat("a", titleMax+1)},
+		{field: "instructions", value: strings.Repeat("a", instructionsMax+1)},
+	} {
+		t.Run(tc.field, func(t *testing.T) {
+			dbStub := newStubDB()
+			editor := &stubEditor{}
+			app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), DB: dbStub, ImageEditor: editor}
+			prompt := map[string]any{
+				"title":        "Sample",
+				"source_asset": map[string]any{"asset_id": "upl", "url": "https://example.com/source.png"},
+			}
+			prompt[tc.field] = tc.value
+			body, _ := json.Marshal(map[string]any{"provider": "qwen-image-plus", "quantity": 1, "prompt": prompt})
+			req := httptest.NewRequest("POST", "/v1/images/generate", bytes.NewReader(body))
+			req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+			rr := httptest.NewRecorder()
+
+			app.ImagesGenerate(rr, req)
+
+			if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "prompt."+tc.field) {
+				t.Fatalf("status = %d body = %s, want 400 naming prompt.%s", rr.Code, rr.Body.String(), tc.field)
+			}
+			if editor.calls != 0 || dbStub.lastJob() != nil {
+				t.Fatal("overlong prompt reached the editor")
+			}
+		})
+	}
+}
+
+func TestImagesGenerateReleasesIdempotencyKeyOnCreateFailure(t *testing.T) {
+	dbStub := newStubDB()
+	dbStub.createErr = errors.New("connection reset")
+	keys := &idempotentVideoSQL{keys: map[string]string{}}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), DB: dbStub, SQL: keys, ImageEditor: &stubEditor{urls: []string{"https://example.com/one.png"}}}
+	submit := func() int {
+		body, _ := json.Marshal(map[string]any{"provider": "qwen-image-plus", "quantity": 1, "prompt": map[string]any{
+			"title":        "Sample",
+			"source_asset": map[string]any{"asset_id": "upl", "url": "https://example.com/source.png"},
+		}})
+		req := httptest.NewRequest("POST", "/v1/images/generate", bytes.NewReader(body))
+		req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+		req.Header.Set("Idempotency-Key", "order-42")
+		rr := httptest.NewRecorder()
+		app.ImagesGenerate(rr, req)
+		return rr.Code
+	}
+
+	if code := submit(); code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", code)
+	}
+	if len(keys.keys) != 0 {
+		t.Fatalf("reservations = %v, want the key released", keys.keys)
+	}
+	dbStub.createErr = nil
+	if code := submit(); code != http.StatusCreated {
+		t.Fatalf("retry status = %d, want 201", code)
+	}
+}
+
 type stubFetcher struct {
 	mu          sync.Mutex
 	body        []byte
@@ -393,3 +615,401 @@ func (s *stubFetcher) Do(req *http.Request) (*http.Response, error) {
 	resp.Body = io.NopCloser(bytes.NewReader(append([]byte(nil), s.body...)))
 	return resp, nil
 }
+
+type uploadedAssetSQL struct {
+	ownerID    string
+	storageKey string
+}
+
+func (s *uploadedAssetSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("unexpected exec")
+}
+
+func (s *uploadedAssetSQL) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	if s.ownerID == "" {
+		return NewSimpleRow(nil)
+	}
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = args[0].(string)
+		*dest[1].(*string) = s.ownerID
+		*dest[2].(*string) = s.storageKey
+		*dest[3].(*string) = "image/png"
+		*dest[4].(*int64) = int64(len(tinyTransparentPNG))
+		*dest[5].(*int) = 1
+		*dest[6].(*int) = 1
+		*dest[7].(*string) = "1:1"
+		*dest[8].(*[]byte) = []byte(`{}`)
+		return nil
+	})
+}
+
+func (s *uploadedAssetSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
+
+func TestImagesGenerateUploadedSource(t *testing.T) {
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	storageKey, err := store.Write(context.Background(), "uploads/user-123/source.png", tinyTransparentPNG)
+	if err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		ownerID    string
+		wantStatus int
+		wantJob    bool
+	}{
+		{name: "owned asset", ownerID: "user-123", wantStatus: http.StatusCreated, wantJob: true},
+		{name: "asset owned by another user", ownerID: "user-456", wantStatus: http.StatusNotFound},
+		{name: "missing asset", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dbStub := newStubDB()
+			editor := &stubEditor{}
+			app := &App{
+				Config:       &infra.Config{},
+				Logger:       zerolog.Nop(),
+				DB:           dbStub,
+				SQL:          &uploadedAssetSQL{ownerID: tc.ownerID, storageKey: storageKey},
+				Storage:      store,
+				ImageEditor:  editor,
+				imageLimiter: make(chan struct{}, 2),
+			}
+
+			body, _ := json.Marshal(map[string]any{
+				"provider": "qwen-image-edit",
+				"quantity": 1,
+				"prompt": map[string]any{
+					"title":        "Sample",
+					"source_asset": map[string]any{"asset_id": uuid.NewString()},
+				},
+			})
+			req := httptest.NewRequest(http.MethodPost, "/v1/images/generate", bytes.NewReader(body))
+			req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+			rr := httptest.NewRecorder()
+
+			app.ImagesGenerate(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			if got := dbStub.lastJob() != nil; got != tc.wantJob {
+				t.Fatalf("job recorded = %v, want %v", got, tc.wantJob)
+			}
+			if !tc.wantJob {
+				if editor.calls != 0 {
+					t.Fatalf("editor should not be called")
+				}
+				return
+			}
+			if len(editor.sources) != 1 || !bytes.Equal(editor.sources[0].Data, tinyTransparentPNG) {
+				t.Fatalf("expected uploaded bytes to be passed to the editor")
+			}
+			if editor.sources[0].MIMEType != "image/png" || editor.sources[0].Name != "source.png" {
+				t.Fatalf("unexpected source metadata: %+v", editor.sources[0])
+			}
+		})
+	}
+}
+
+func TestImageDownloadZipSkipsFailedSources(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png-bytes"))
+		case "/ok.jpg":
+			time.Sleep(20 * time.Millisecond)
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = w.Write([]byte("jpeg-bytes"))
+		case "/untyped.webp":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte("webp-bytes"))
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer source.Close()
+
+	urls := []string{source.URL + "/ok.jpg", source.URL + "/broken", source.URL + "/ok.png", source.URL + "/untyped.webp"}
+	output, err := json.Marshal(map[string]any{"images": []map[string]string{
+		{"url": urls[0]}, {"url": urls[1]}, {"url": urls[2]}, {"url": urls[3]},
+	}})
+	if err != nil {
+		t.Fatalf("marshal output: %v", err)
+	}
+	dbStub := newStubDB()
+	jobID := uuid.New()
+	dbStub.jobs[jobID] = &db.ImageJob{
+		ID:     jobID,
+		UserID: sql.NullString{String: "user-123", Valid: true},
+		Status: "SUCCEEDED",
+		Prompt: []byte(`{"title":"Promo Ramadan"}`),
+		Output: output,
+	}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), DB: dbStub}
+
+	req := requestWithParam("GET", "/v1/images/"+jobID.String()+"/download.zip", "job_id", jobID.String(), "user-123")
+	rr := httptest.NewRecorder()
+	app.ImageDownloadZip(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if got, want := rr.Header().Get("Content-Disposition"), "attachment; filename=Promo_Ramadan.zip"; got != want {
+		t.Fatalf("Content-Disposition = %q, want %q", got, want)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	var names []string
+	contents := map[string]string{}
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[file.Name] = string(data)
+	}
+	want := []string{"Promo_Ramadan_01.jpg", "Promo_Ramadan_03.png", "Promo_Ramadan_04.webp", "manifest.json"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("entries = %v, want %v", names, want)
+	}
+	if contents["Promo_Ramadan_01.jpg"] != "jpeg-bytes" || contents["Promo_Ramadan_03.png"] != "png-bytes" || contents["Promo_Ramadan_04.webp"] != "webp-bytes" {
+		t.Fatalf("unexpected entry contents: %v", contents)
+	}
+
+	var manifest zipManifest
+	if err := json.Unmarshal([]byte(contents["manifest.json"]), &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if manifest.JobID != jobID.String() || manifest.Included != 3 || manifest.Total != 4 || len(manifest.Files) != 4 {
+		t.Fatalf("unexpected manifest summary: %+v", manifest)
+	}
+	for i, file := range manifest.Files {
+		if file.Index != i+1 || file.SourceURL != urls[i] {
+			t.Fatalf("manifest file %d = %+v", i, file)
+		}
+	}
+	if failed := manifest.Files[1]; failed.Status != "failed" || failed.Name != "" || !strings.Contains(failed.Error, "status 500") {
+		t.Fatalf("manifest missing failure: %+v", failed)
+	}
+	if ok := manifest.Files[2]; ok.Status != "ok" || ok.Name != "Promo_Ramadan_03.png" {
+		t.Fatalf("unexpected manifest entry: %+v", ok)
+	}
+}
+
+func TestImageDownloadContentDispositionFilename(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write([]byte("jpeg-bytes"))
+	}))
+	defer source.Close()
+
+	output, err := json.Marshal(map[string]any{"images": []map[string]string{{"url": source.URL + "/a.jpg"}}})
+	if err != nil {
+		t.Fatalf("marshal output: %v", err)
+	}
+	dbStub := newStubDB()
+	jobID := uuid.New()
+	dbStub.jobs[jobID] = &db.ImageJob{
+		ID:     jobID,
+		UserID: sql.NullString{String: "user-123", Valid: true},
+		Status: "SUCCEEDED",
+		Prompt: []byte(`{"title":"Kopi Susu / Gula Aren"}`),
+		Output: output,
+	}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), DB: dbStub}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		want     string
+	}{
+		{name: "custom safe name", query: "?filename=promo-banner.png", wantCode: http.StatusOK, want: `attachment; filename=promo-banner.jpg`},
+		{name: "traversal stripped", query: "?filename=" + url.QueryEscape(`..\..\etc/passwd`), wantCode: http.StatusOK, want: `attachment; filename=passwd.jpg`},
+		{name: "title default", wantCode: http.StatusOK, want: `attachment; filename=Kopi_Susu___Gula_Aren.jpg`},
+		{name: "control characters rejected", query: "?filename=" + url.QueryEscape("a\r\nb"), wantCode: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := requestWithParam("GET", "/v1/images/"+jobID.String()+"/download"+tc.query, "job_id", jobID.String(), "user-123")
+			rr := httptest.NewRecorder()
+			app.ImageDownload(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("status = %d, body=%s", rr.Code, rr.Body.String())
+			}
+			if tc.want == "" {
+				return
+			}
+			if got := rr.Header().Get("Content-Disposition"); got != tc.want {
+				t.Fatalf("Content-Disposition = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestImageDownloadZipIsNotGzipped(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(bytes.Repeat([]byte("png-bytes"), 512))
+	}))
+	defer source.Close()
+
+	output, err := json.Marshal(map[string]any{"images": []map[string]string{{"url": source.URL + "/a.png"}}})
+	if err != nil {
+		t.Fatalf("marshal output: %v", err)
+	}
+	dbStub := newStubDB()
+	jobID := uuid.New()
+	dbStub.jobs[jobID] = &db.ImageJob{
+		ID:     jobID,
+		UserID: sql.NullString{String: "user-123", Valid: true},
+		Status: "SUCCEEDED",
+		Output: output,
+	}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), DB: dbStub}
+	handler := middleware.Gzip(middleware.DefaultGzipMinSize)(http.HandlerFunc(app.ImageDownloadZip))
+
+	req := requestWithParam("GET", "/v1/images/"+jobID.String()+"/download.zip", "job_id", jobID.String(), "user-123")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("Content-Encoding = %q, want zip served as-is", got)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len())); err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+}
+
+func TestAcquireImageSlotEnforcesGlobalCap(t *testing.T) {
+	app := &App{imageLimiter: make(chan struct{}, 2), userImageSlots: newUserSlots()}
+	for _, user := range []string{"user-1", "user-2"} {
+		if err := app.acquireImageSlot(context.Background(), user, 0); err != nil {
+			t.Fatalf("acquire for %s: %v", user, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := app.acquireImageSlot(ctx, "user-3", 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third acquire err = %v, want deadline exceeded while global slots are full", err)
+	}
+
+	app.releaseImageSlot("user-1")
+	if err := app.acquireImageSlot(context.Background(), "user-3", 0); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
+
+func TestAcquireImageSlotEnforcesPerUserCap(t *testing.T) {
+	app := &App{
+		Config:         &infra.Config{UserImageConcurrency: 3, PlanImageConcurrency: map[string]int{"free": 1}},
+		imageLimiter:   make(chan struct{}, 4),
+		userImageSlots: newUserSlots(),
+	}
+	limit := app.userImageLimit("free")
+	if limit != 1 {
+		t.Fatalf("free limit = %d, want plan override 1", limit)
+	}
+	if err := app.acquireImageSlot(context.Background(), "user-1", limit); err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := app.acquireImageSlot(ctx, "user-1", limit); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second acquire err = %v, want deadline exceeded at the per-user cap", err)
+	}
+	if held := len(app.imageLimiter); held != 1 {
+		t.Fatalf("global slots held = %d, want 1: a capped waiter must not hold a global slot", held)
+	}
+	if err := app.acquireImageSlot(context.Background(), "user-2", limit); err != nil {
+		t.Fatalf("other user acquire: %v", err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- app.acquireImageSlot(context.Background(), "user-1", limit) }()
+	select {
+	case err := <-acquired:
+		t.Fatalf("acquire returned before release: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	app.releaseImageSlot("user-1")
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("acquire after release: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("waiter was not woken by release")
+	}
+}
+
+func TestShutdownWaitsForImageSlots(t *testing.T) {
+	app := &App{imageLimiter: make(chan struct{}, 2)}
+	server := infra.NewHTTPServer(&infra.Config{Port: "0"}, http.NotFoundHandler())
+	server.RegisterDrain(app.DrainImageSlots)
+
+	if err := app.acquireImageSlot(context.Background(), "", 0); err != nil {
+		t.Fatalf("acquire slot: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		done <- server.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned while a slot was held: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	app.releaseImageSlot("")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("shutdown error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("shutdown did not return after the slot was released")
+	}
+}
+
+func TestShutdownGivesUpOnHeldImageSlotAfterGrace(t *testing.T) {
+	app := &App{imageLimiter: make(chan struct{}, 1)}
+	server := infra.NewHTTPServer(&infra.Config{Port: "0"}, http.NotFoundHandler())
+	server.RegisterDrain(app.DrainImageSlots)
+	if err := app.acquireImageSlot(context.Background(), "", 0); err != nil {
+		t.Fatalf("acquire slot: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err := server.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("shutdown took %s, want it bounded by the grace period", elapsed)
+	}
+}
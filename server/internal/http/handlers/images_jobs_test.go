@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"server/internal/db"
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+func seedImageJobs(s *stubDB, userID string, n int, base time.Time) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, n)
+	for i := 0; i < n; i++ {
+		id := uuid.New()
+		s.jobs[id] = &db.ImageJob{
+			ID:        id,
+			UserID:    sql.NullString{String: userID, Valid: true},
+			Provider:  "qwen",
+			Model:     "qwen-image-plus",
+			Status:    "SUCCEEDED",
+			Quantity:  1,
+			CreatedAt: base.Add(-time.Duration(i) * time.Minute),
+			UpdatedAt: base.Add(-time.Duration(i) * time.Minute),
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func listImageJobs(t *testing.T, app *App, query string) (int, imageJobListResponse) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/v1/images/jobs"+query, nil)
+	req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+	rr := httptest.NewRecorder()
+	app.ListImageJobs(rr, req)
+	var resp imageJobListResponse
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+	}
+	return rr.Code, resp
+}
+
+func TestListImageJobsPaginates(t *testing.T) {
+	dbStub := newStubDB()
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	ids := seedImageJobs(dbStub, "user-123", 5, base)
+	seedImageJobs(dbStub, "user-456", 2, base)
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), DB: dbStub}
+
+	status, page := listImageJobs(t, app, "?limit=2")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	if len(page.Items) != 2 || page.Items[0].ID != ids[0].String() || page.Items[1].ID != ids[1].String() {
+		t.Fatalf("unexpected first page: %+v", page.Items)
+	}
+	if page.NextOffset == nil || *page.NextOffset != 2 {
+		t.Fatalf("next_offset = %v, want 2", page.NextOffset)
+	}
+
+	_, page = listImageJobs(t, app, "?limit=2&offset=4")
+	if len(page.Items) != 1 || page.Items[0].ID != ids[4].String() {
+		t.Fatalf("unexpected last page: %+v", page.Items)
+	}
+	if page.NextOffset != nil {
+		t.Fatalf("next_offset = %d on last page, want null", *page.NextOffset)
+	}
+	for _, item := range page.Items {
+		if item.UserID != "user-123" {
+			t.Fatalf("listed another user's job: %+v", item)
+		}
+	}
+}
+
+func TestListImageJobsClampsLimit(t *testing.T) {
+	cases := []struct {
+		query      string
+		wantLimit  int32
+		wantOffset int32
+	}{
+		{query: "", wantLimit: defaultJobListLimit + 1},
+		{query: "?limit=0&offset=-5", wantLimit: defaultJobListLimit + 1},
+		{query: "?limit=500&offset=10", wantLimit: maxJobListLimit + 1, wantOffset: 10},
+		{query: "?limit=abc", wantLimit: defaultJobListLimit + 1},
+	}
+	for _, tc := range cases {
+		dbStub := newStubDB()
+		app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), DB: dbStub}
+		status, page := listImageJobs(t, app, tc.query)
+		if status != http.StatusOK {
+			t.Fatalf("%q: status = %d", tc.query, status)
+		}
+		if page.Items == nil || len(page.Items) != 0 {
+			t.Fatalf("%q: items = %v, want empty list", tc.query, page.Items)
+		}
+		limit, offset := dbStub.listArgs[4].(int32), dbStub.listArgs[5].(int32)
+		if limit != tc.wantLimit || offset != tc.wantOffset {
+			t.Fatalf("%q: limit/offset = %d/%d, want %d/%d", tc.query, limit, offset, tc.wantLimit, tc.wantOffset)
+		}
+	}
+}
+
+func TestListImageJobsFiltersByStatusAndDate(t *testing.T) {
+	dbStub := newStubDB()
+	base := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	ids := seedImageJobs(dbStub, "user-123", 4, base)
+	dbStub.jobs[ids[1]].Status = "FAILED"
+	dbStub.jobs[ids[3]].Status = "FAILED"
+	dbStub.jobs[ids[3]].CreatedAt = base.AddDate(0, 0, -10)
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), DB: dbStub}
+
+	status, page := listImageJobs(t, app, "?status=failed")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	if len(page.Items) != 2 {
+		t.Fatalf("failed filter returned %d items, want 2", len(page.Items))
+	}
+	for _, item := range page.Items {
+		if item.Status != "FAILED" {
+			t.Fatalf("unexpected status %q in filtered list", item.Status)
+		}
+	}
+
+	since := base.AddDate(0, 0, -7).Format(time.RFC3339)
+	_, page = listImageJobs(t, app, "?status=FAILED&since="+since)
+	if len(page.Items) != 1 || page.Items[0].ID != ids[1].String() {
+		t.Fatalf("last week's failures = %+v, want only %s", page.Items, ids[1])
+	}
+
+	until := base.Add(-90 * time.Second).Format(time.RFC3339)
+	_, page = listImageJobs(t, app, "?until="+until)
+	if len(page.Items) != 2 || page.Items[0].ID != ids[2].String() {
+		t.Fatalf("until filter = %+v", page.Items)
+	}
+}
+
+func TestListImageJobsRejectsBadFilters(t *testing.T) {
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), DB: newStubDB()}
+	for _, query := range []string{
+		"?status=DONE",
+		"?since=yesterday",
+		"?until=2024-06-01",
+		"?since=2024-06-02T00:00:00Z&until=2024-06-01T00:00:00Z",
+	} {
+		if status, _ := listImageJobs(t, app, query); status != http.StatusBadRequest {
+			t.Fatalf("%q: status = %d, want 400", query, status)
+		}
+	}
+}
+
+func TestExtractImageURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{name: "images array", raw: `{"images":[{"url":" https://cdn.example.com/a.png "},{"url":""},{"url":"https://cdn.example.com/b.png"}]}`, want: []string{"https://cdn.example.com/a.png", "https://cdn.example.com/b.png"}},
+		{name: "top-level url", raw: `{"url":"https://cdn.example.com/only.png"}`, want: []string{"https://cdn.example.com/only.png"}},
+		{name: "both shapes dedupe", raw: `{"images":[{"url":"https://cdn.example.com/a.png"}],"url":"https://cdn.example.com/a.png"}`, want: []string{"https://cdn.example.com/a.png"}},
+		{name: "malformed", raw: `{"images":[{"url":`, want: nil},
+		{name: "empty", raw: ``, want: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := extractImageURLs([]byte(tc.raw))
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("extractImageURLs() = %#v, want nil", got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("extractImageURLs() = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+// jobAssetsSQL serves a job through jobStatusSQL and its assets from
+// QSelectJobAssets.
+type jobAssetsSQL struct {
+	jobStatusSQL
+	assets [][]any
+	listed bool
+}
+
+func (s *jobAssetsSQL) Query(_ context.Context, query string, args ...any) (pgx.Rows, error) {
+	if query != sqlinline.QSelectJobAssets {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	if args[0] != s.job.ID || args[1] != s.job.UserID {
+		return nil, fmt.Errorf("unexpected args: %v", args)
+	}
+	s.listed = true
+	return &jobAssetRows{items: s.assets}, nil
+}
+
+type jobAssetRows struct {
+	TestRowsBase
+	items [][]any
+	idx   int
+}
+
+func (r *jobAssetRows) Next() bool {
+	if r.idx >= len(r.items) {
+		return false
+	}
+	r.idx++
+	return true
+}
+
+func (r *jobAssetRows) Scan(dest ...any) error {
+	item := r.items[r.idx-1]
+	if item == nil {
+		return errors.New("scan failed")
+	}
+	*dest[0].(*string) = item[0].(string)
+	*dest[1].(*string) = item[1].(string)
+	*dest[2].(*string) = item[2].(string)
+	*dest[3].(*int64) = item[3].(int64)
+	*dest[4].(*int) = item[4].(int)
+	*dest[5].(*int) = item[5].(int)
+	*dest[6].(*string) = item[6].(string)
+	*dest[7].(*[]byte) = item[7].([]byte)
+	*dest[8].(*time.Time) = item[8].(time.Time)
+	return nil
+}
+
+func (r *jobAssetRows) Err() error { return nil }
+
+func (r *jobAssetRows) Close() {}
+
+func TestImageAssetsListsOwnedJobAssets(t *testing.T) {
+	job := failedJob("IMAGE_GEN")
+	job.Status = "SUCCEEDED"
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	stub := &jobAssetsSQL{jobStatusSQL: jobStatusSQL{job: job}, assets: [][]any{
+		{"asset-1", "images/user-123/a.png", "image/png", int64(2048), 1024, 1024, "1:1", []byte(`{"thumbnail_key":"thumbs/a.webp"}`), created},
+		{"asset-2", "images/user-123/b.png", "image/png", int64(4096), 1024, 1024, "1:1", []byte(`{}`), created},
+	}}
+	app := &App{Config: &infra.Config{StorageBaseURL: "https://cdn.example.com/static"}, Logger: zerolog.Nop(), SQL: stub}
+
+	rr := httptest.NewRecorder()
+	app.ImageAssets(rr, requestWithParam("GET", "/v1/images/"+job.ID+"/assets", "job_id", job.ID, job.UserID))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var payload struct {
+		Items []struct {
+			ID           string `json:"id"`
+			URL          string `json:"url"`
+			ThumbnailURL string `json:"thumbnail_url"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(payload.Items) != 2 {
+		t.Fatalf("items = %d, want 2", len(payload.Items))
+	}
+	if got := payload.Items[0]; got.ID != "asset-1" || got.URL != "https://cdn.example.com/static/images/user-123/a.png" || got.ThumbnailURL != "https://cdn.example.com/static/thumbs/a.webp" {
+		t.Fatalf("first item = %+v", got)
+	}
+}
+
+func TestImageAssetsRejectsOtherUsersAndVideoJobs(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		taskType string
+		caller   string
+	}{
+		{name: "other user", taskType: "IMAGE_GEN", caller: "user-999"},
+		{name: "video job", taskType: "VIDEO_GEN", caller: "user-123"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			job := failedJob(tc.taskType)
+			stub := &jobAssetsSQL{jobStatusSQL: jobStatusSQL{job: job}}
+			app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: stub}
+
+			rr := httptest.NewRecorder()
+			app.ImageAssets(rr, requestWithParam("GET", "/v1/images/"+job.ID+"/assets", "job_id", job.ID, tc.caller))
+
+			if rr.Code != http.StatusNotFound {
+				t.Fatalf("status = %d, want 404; body=%s", rr.Code, rr.Body.String())
+			}
+			if stub.listed {
+				t.Fatalf("assets were queried for a job the caller cannot see")
+			}
+		})
+	}
+}
+
+func TestJobAssetsSurfacesScanErrors(t *testing.T) {
+	job := failedJob("IMAGE_GEN")
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	stub := &jobAssetsSQL{jobStatusSQL: jobStatusSQL{job: job}, assets: [][]any{
+		{"asset-1", "images/user-123/a.png", "image/png", int64(2048), 1024, 1024, "1:1", []byte(`{}`), created},
+		nil,
+	}}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: stub}
+
+	rr := httptest.NewRecorder()
+	app.ImageAssets(rr, requestWithParam("GET", "/v1/images/"+job.ID+"/assets", "job_id", job.ID, job.UserID))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500 for an unreadable asset row; body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestVideoAssetsRejectsImageJobs(t *testing.T) {
+	job := failedJob("IMAGE_GEN")
+	stub := &jobAssetsSQL{jobStatusSQL: jobStatusSQL{job: job}}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: stub}
+
+	rr := httptest.NewRecorder()
+	app.VideoAssets(rr, requestWithParam("GET", "/v1/videos/"+job.ID+"/assets", "job_id", job.ID, job.UserID))
+
+	if rr.Code != http.StatusNotFound || stub.listed {
+		t.Fatalf("status = %d listed = %v, want 404 without listing", rr.Code, stub.listed)
+	}
+}
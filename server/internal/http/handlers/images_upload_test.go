@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+	"server/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+type storedUpload struct {
+	id         string
+	userID     string
+	storageKey string
+	mime       string
+	size       int64
+	width      int
+	height     int
+	aspect     string
+	hash       string
+}
+
+type uploadTableSQL struct {
+	uploads []storedUpload
+}
+
+func (s *uploadTableSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *uploadTableSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	switch query {
+	case sqlinline.QFindAssetByHash:
+		for _, upload := range s.uploads {
+			if upload.userID != args[0] || upload.hash != args[1] {
+				continue
+			}
+			return NewSimpleRow(func(dest ...any) error {
+				*dest[0].(*string) = upload.id
+				*dest[1].(*string) = upload.storageKey
+				*dest[2].(*string) = upload.mime
+				*dest[3].(*int64) = upload.size
+				*dest[4].(*int) = upload.width
+				*dest[5].(*int) = upload.height
+				*dest[6].(*string) = upload.aspect
+				return nil
+			})
+		}
+		return NewSimpleRow(nil)
+	case sqlinline.QInsertUploadedAsset:
+		var props map[string]any
+		if err := json.Unmarshal(args[8].(json.RawMessage), &props); err != nil {
+			return NewSimpleRow(func(...any) error { return err })
+		}
+		hash, _ := props["sha256"].(string)
+		upload := storedUpload{
+			id:         fmt.Sprintf("asset-%d", len(s.uploads)+1),
+			userID:     args[0].(string),
+			storageKey: args[2].(string),
+			mime:       args[3].(string),
+			size:       args[4].(int64),
+			width:      args[5].(int),
+			height:     args[6].(int),
+			aspect:     args[7].(string),
+			hash:       hash,
+		}
+		s.uploads = append(s.uploads, upload)
+		return NewSimpleRow(func(dest ...any) error {
+			*dest[0].(*string) = upload.id
+			return nil
+		})
+	}
+	return NewSimpleRow(nil)
+}
+
+func (s *uploadTableSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func uploadImage(t *testing.T, app *App, data []byte) (int, map[string]any) {
+	t.Helper()
+	return uploadImageAs(t, app, "product.png", data, &middleware.TokenClaims{Sub: "user-123", Plan: "free"})
+}
+
+func uploadImageAs(t *testing.T, app *App, filename string, data []byte, claims *middleware.TokenClaims) (int, map[string]any) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	_, _ = part.Write(data)
+	_ = form.Close()
+
+	req := httptest.NewRequest("POST", "/v1/images/uploads", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req = req.WithContext(middleware.ContextWithClaims(req.Context(), claims))
+	rr := httptest.NewRecorder()
+	app.ImagesUpload(rr, req)
+
+	var resp map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v; body=%s", err, rr.Body.String())
+	}
+	return rr.Code, resp
+}
+
+func TestImagesUploadDeduplicatesByContentHash(t *testing.T) {
+	root := t.TempDir()
+	store, err := storage.NewFileStore(root)
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	sqlStub := &uploadTableSQL{}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: sqlStub, Storage: store}
+
+	status, first := uploadImage(t, app, tinyTransparentPNG)
+	if status != http.StatusCreated {
+		t.Fatalf("first upload status = %d, want 201; resp=%v", status, first)
+	}
+
+	status, second := uploadImage(t, app, tinyTransparentPNG)
+	if status != http.StatusOK {
+		t.Fatalf("identical upload status = %d, want 200; resp=%v", status, second)
+	}
+	if second["asset_id"] != first["asset_id"] || second["storage_key"] != first["storage_key"] {
+		t.Fatalf("identical upload did not reuse asset: first=%v second=%v", first, second)
+	}
+	if second["deduplicated"] != true {
+		t.Fatalf("expected deduplicated flag, got %v", second)
+	}
+
+	different := append(append([]byte(nil), tinyTransparentPNG...), 0x00)
+	status, third := uploadImage(t, app, different)
+	if status != http.StatusCreated {
+		t.Fatalf("different upload status = %d, want 201; resp=%v", status, third)
+	}
+	if third["asset_id"] == first["asset_id"] {
+		t.Fatalf("different bytes reused asset %v", first["asset_id"])
+	}
+
+	if len(sqlStub.uploads) != 2 {
+		t.Fatalf("recorded %d uploads, want 2", len(sqlStub.uploads))
+	}
+	if sqlStub.uploads[0].hash == "" || sqlStub.uploads[0].hash == sqlStub.uploads[1].hash {
+		t.Fatalf("unexpected stored hashes: %+v", sqlStub.uploads)
+	}
+	files, err := os.ReadDir(filepath.Join(root, "uploads", "user-123"))
+	if err != nil {
+		t.Fatalf("read upload dir: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("wrote %d files, want 2", len(files))
+	}
+}
+
+// exifRotate90 is an APP1 segment whose IFD0 carries orientation 6 (rotate
+// 90 degrees clockwise), as written by most phone cameras held upright.
+var exifRotate90 = []byte{
+	0xFF, 0xE1, 0x00, 0x22,
+	'E', 'x', 'i', 'f', 0x00, 0x00,
+	'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00,
+	0x01, 0x00,
+	0x12, 0x01, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00,
+}
+
+func TestImagesUploadAppliesExifOrientation(t *testing.T) {
+	var plain bytes.Buffer
+	if err := jpeg.Encode(&plain, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	rotated := append([]byte{}, plain.Bytes()[:2]...)
+	rotated = append(rotated, exifRotate90...)
+	rotated = append(rotated, plain.Bytes()[2:]...)
+
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	sqlStub := &uploadTableSQL{}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: sqlStub, Storage: store}
+
+	status, resp := uploadImageAs(t, app, "photo.jpg", rotated, &middleware.TokenClaims{Sub: "user-123"})
+	if status != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; resp=%v", status, resp)
+	}
+	if resp["width"] != float64(20) || resp["height"] != float64(40) {
+		t.Fatalf("dimensions = %vx%v, want 20x40", resp["width"], resp["height"])
+	}
+	if resp["aspect_ratio"] != deriveAspectLabel(20, 40) {
+		t.Fatalf("aspect = %v, want %s", resp["aspect_ratio"], deriveAspectLabel(20, 40))
+	}
+	stored, err := store.Read(context.Background(), resp["storage_key"].(string))
+	if err != nil {
+		t.Fatalf("read stored upload: %v", err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(stored))
+	if err != nil {
+		t.Fatalf("decode stored upload: %v", err)
+	}
+	if cfg.Width != 20 || cfg.Height != 40 {
+		t.Fatalf("stored dimensions = %dx%d, want 20x40", cfg.Width, cfg.Height)
+	}
+}
+
+func TestImagesUploadKeepsPNGUntouched(t *testing.T) {
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: &uploadTableSQL{}, Storage: store}
+
+	status, resp := uploadImage(t, app, tinyTransparentPNG)
+	if status != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; resp=%v", status, resp)
+	}
+	stored, err := store.Read(context.Background(), resp["storage_key"].(string))
+	if err != nil {
+		t.Fatalf("read stored upload: %v", err)
+	}
+	if !bytes.Equal(stored, tinyTransparentPNG) {
+		t.Fatalf("png upload was rewritten")
+	}
+}
+
+func TestImagesUploadEnforcesPlanLimits(t *testing.T) {
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	app := &App{
+		Config: &infra.Config{
+			MaxUploadMB:     1,
+			PlanMaxUploadMB: map[string]int{"supporter": 2},
+		},
+		Logger:  zerolog.Nop(),
+		SQL:     &uploadTableSQL{},
+		Storage: store,
+	}
+	// Trailing bytes after IEND keep the PNG decodable while pushing it past 1MB.
+	large := append(append([]byte(nil), tinyTransparentPNG...), make([]byte, 3<<19)...)
+
+	status, resp := uploadImageAs(t, app, "product.png", large, &middleware.TokenClaims{Sub: "user-123", Plan: "free"})
+	if status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("free status = %d, want 413; resp=%v", status, resp)
+	}
+	errBody, _ := resp["error"].(map[string]any)
+	if msg, _ := errBody["message"].(string); msg != "file exceeds 1MB limit" {
+		t.Fatalf("free error message = %q", msg)
+	}
+
+	status, resp = uploadImageAs(t, app, "product.png", large, &middleware.TokenClaims{Sub: "user-123", Plan: "supporter"})
+	if status != http.StatusCreated {
+		t.Fatalf("supporter status = %d, want 201; resp=%v", status, resp)
+	}
+}
+
+func TestImagesUploadRejectsDisguisedFiles(t *testing.T) {
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	claims := &middleware.TokenClaims{Sub: "user-123"}
+
+	htmlPrefixed := append([]byte("<html><script>alert(1)</script></html>"), tinyTransparentPNG...)
+	scriptTrailer := append(append([]byte(nil), tinyTransparentPNG...), []byte("<SCRIPT src=//evil.example></SCRIPT>")...)
+	shortMarker := append(append([]byte(nil), tinyTransparentPNG...), []byte("\x8f<%\x03")...)
+
+	cases := []struct {
+		name       string
+		filename   string
+		data       []byte
+		wantStatus int
+	}{
+		{name: "valid png", filename: "product.png", data: tinyTransparentPNG, wantStatus: http.StatusCreated},
+		{name: "png without extension", filename: "blob", data: tinyTransparentPNG, wantStatus: http.StatusCreated},
+		{name: "png with incidental short marker", filename: "product.png", data: shortMarker, wantStatus: http.StatusCreated},
+		{name: "html prefixed png", filename: "product.png", data: htmlPrefixed, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "png with trailing script", filename: "product.png", data: scriptTrailer, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "png named as jpeg", filename: "product.jpg", data: tinyTransparentPNG, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "png named as html", filename: "product.html", data: tinyTransparentPNG, wantStatus: http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: &uploadTableSQL{}, Storage: store}
+			status, resp := uploadImageAs(t, app, tc.filename, tc.data, claims)
+			if status != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; resp=%v", status, tc.wantStatus, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"server/internal/sqlinline"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const maxBatchStatusJobs = 50
+
+type jobsStatusRequest struct {
+	JobIDs []string `json:"job_ids"`
+}
+
+type jobStatusDTO struct {
+	ID          string    `json:"id"`
+	TaskType    string    `json:"task_type"`
+	Status      string    `json:"status"`
+	Provider    string    `json:"provider"`
+	Quantity    int       `json:"quantity"`
+	AspectRatio string    `json:"aspect_ratio"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// JobsStatus returns the status of several queued jobs in one call. Ids that
+// are malformed, unknown or owned by another user are omitted.
+func (a *App) JobsStatus(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	var body json.RawMessage
+	if !a.decodeJSON(w, r, &body) {
+		return
+	}
+	// Accept either a bare array of ids or {"job_ids": [...]}.
+	var req jobsStatusRequest
+	var err error
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &req.JobIDs)
+	} else {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid payload")
+		return
+	}
+	if len(req.JobIDs) == 0 {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "job_ids required")
+		return
+	}
+	if len(req.JobIDs) > maxBatchStatusJobs {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, fmt.Sprintf("at most %d job_ids allowed", maxBatchStatusJobs))
+		return
+	}
+
+	items := make([]jobStatusDTO, 0, len(req.JobIDs))
+	seen := make(map[string]struct{}, len(req.JobIDs))
+	for _, raw := range req.JobIDs {
+		id, err := uuid.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			continue
+		}
+		jobID := id.String()
+		if _, ok := seen[jobID]; ok {
+			continue
+		}
+		seen[jobID] = struct{}{}
+		job, err := a.loadJobForUser(r.Context(), jobID, userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load job status")
+			return
+		}
+		items = append(items, jobStatusDTO{
+			ID:          job.ID,
+			TaskType:    job.TaskType,
+			Status:      job.Status,
+			Provider:    job.Provider,
+			Quantity:    job.Quantity,
+			AspectRatio: job.Aspect,
+			Error:       job.ErrorMessage(),
+			CreatedAt:   job.CreatedAt,
+			UpdatedAt:   job.UpdatedAt,
+		})
+	}
+	a.json(w, http.StatusOK, map[string]any{"jobs": items})
+}
+
+// CancelJob cancels a queued job before the worker claims it and refunds the
+// quota it consumed. Jobs that already started cannot be cancelled.
+func (a *App) CancelJob(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "job_id"))
+	if err != nil {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid job id")
+		return
+	}
+	var previous string
+	var remaining int
+	err = a.SQL.QueryRow(r.Context(), sqlinline.QCancelJob, id.String(), userID).Scan(&previous, &remaining)
+	if errors.Is(err, pgx.ErrNoRows) {
+		a.error(w, http.StatusNotFound, ErrNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to cancel job")
+		return
+	}
+	if previous != "QUEUED" {
+		a.error(w, http.StatusConflict, ErrConflict, fmt.Sprintf("job is %s and can no longer be cancelled", previous))
+		return
+	}
+	a.json(w, http.StatusOK, jobResponse{JobID: id.String(), Status: "CANCELED", RemainingQuota: remaining})
+}
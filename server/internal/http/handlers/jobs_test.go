@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type jobTableSQL struct {
+	jobs    map[string]jobRecord
+	lookups int
+}
+
+func (s *jobTableSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *jobTableSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if query != sqlinline.QSelectJobStatus {
+		return NewSimpleRow(nil)
+	}
+	s.lookups++
+	job, ok := s.jobs[args[0].(string)]
+	if !ok || job.UserID != args[1] {
+		return NewSimpleRow(nil)
+	}
+	return (&jobStatusSQL{job: job}).QueryRow(context.Background(), query, args...)
+}
+
+func (s *jobTableSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newJobTable(jobs ...jobRecord) *jobTableSQL {
+	table := &jobTableSQL{jobs: map[string]jobRecord{}}
+	for _, job := range jobs {
+		table.jobs[job.ID] = job
+	}
+	return table
+}
+
+func postJobsStatus(app *App, body, userID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/v1/jobs/status", strings.NewReader(body))
+	req = req.WithContext(middleware.ContextWithUserID(req.Context(), userID))
+	rr := httptest.NewRecorder()
+	app.JobsStatus(rr, req)
+	return rr
+}
+
+func TestJobsStatusExcludesForeignJobs(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	owned := jobRecord{ID: "11111111-1111-4111-8111-111111111111", UserID: "user-123", TaskType: "IMAGE_GEN", Status: "RUNNING", CreatedAt: now, UpdatedAt: now, Properties: []byte(`{}`)}
+	failed := failedJob("VIDEO_GEN")
+	foreign := jobRecord{ID: "22222222-2222-4222-8222-222222222222", UserID: "user-999", TaskType: "IMAGE_GEN", Status: "SUCCEEDED", CreatedAt: now, UpdatedAt: now, Properties: []byte(`{}`)}
+	stub := newJobTable(owned, failed, foreign)
+	app := &App{SQL: stub}
+
+	body := `["` + owned.ID + `","` + foreign.ID + `","not-a-uuid","` + failed.ID + `","` + strings.ToUpper(owned.ID) + `"]`
+	rr := postJobsStatus(app, body, "user-123")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Jobs []jobStatusDTO `json:"jobs"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Jobs) != 2 {
+		t.Fatalf("got %d jobs, want 2: %+v", len(resp.Jobs), resp.Jobs)
+	}
+	if resp.Jobs[0].ID != owned.ID || resp.Jobs[0].Status != "RUNNING" {
+		t.Fatalf("unexpected first job: %+v", resp.Jobs[0])
+	}
+	if resp.Jobs[1].ID != failed.ID || resp.Jobs[1].Error != "image generation: provider unavailable" {
+		t.Fatalf("unexpected second job: %+v", resp.Jobs[1])
+	}
+	if stub.lookups != 3 {
+		t.Fatalf("lookups = %d, want 3 (invalid and duplicate ids skipped)", stub.lookups)
+	}
+}
+
+func TestJobsStatusAcceptsObjectPayload(t *testing.T) {
+	job := failedJob("IMAGE_GEN")
+	app := &App{SQL: newJobTable(job)}
+	rr := postJobsStatus(app, `{"job_ids":["`+job.ID+`"]}`, job.UserID)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), job.ID) {
+		t.Fatalf("status = %d body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestJobsStatusRejectsOversizedBatch(t *testing.T) {
+	ids := make([]string, maxBatchStatusJobs+1)
+	for i := range ids {
+		ids[i] = failedJob("IMAGE_GEN").ID
+	}
+	body, _ := json.Marshal(ids)
+	rr := postJobsStatus(&App{SQL: newJobTable()}, string(body), "user-123")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rr.Code)
+	}
+}
+
+type cancelJobSQL struct {
+	jobTableSQL
+	quotaDaily int
+	quotaUsed  int
+}
+
+func (s *cancelJobSQL) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	if query != sqlinline.QCancelJob {
+		return s.jobTableSQL.QueryRow(ctx, query, args...)
+	}
+	job, ok := s.jobs[args[0].(string)]
+	if !ok || job.UserID != args[1] {
+		return NewSimpleRow(nil)
+	}
+	previous := job.Status
+	if previous == "QUEUED" {
+		job.Status = "CANCELED"
+		s.jobs[job.ID] = job
+		s.quotaUsed = max(s.quotaUsed-job.Quantity, 0)
+	}
+	remaining := s.quotaDaily - s.quotaUsed
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = previous
+		*dest[1].(*int) = remaining
+		return nil
+	})
+}
+
+func TestCancelJob(t *testing.T) {
+	queued := jobRecord{ID: "33333333-3333-4333-8333-333333333333", UserID: "user-123", TaskType: "IMAGE_GEN", Status: "QUEUED", Quantity: 3}
+	running := jobRecord{ID: "44444444-4444-4444-8444-444444444444", UserID: "user-123", TaskType: "IMAGE_GEN", Status: "RUNNING", Quantity: 2}
+	foreign := jobRecord{ID: "55555555-5555-4555-8555-555555555555", UserID: "user-999", TaskType: "IMAGE_GEN", Status: "QUEUED", Quantity: 1}
+
+	cases := []struct {
+		name          string
+		jobID         string
+		wantStatus    int
+		wantRemaining int
+		wantQuotaUsed int
+		wantJobStatus string
+	}{
+		{name: "queued job refunds quota", jobID: queued.ID, wantStatus: http.StatusOK, wantRemaining: 7, wantQuotaUsed: 3, wantJobStatus: "CANCELED"},
+		{name: "running job conflicts", jobID: running.ID, wantStatus: http.StatusConflict, wantQuotaUsed: 6, wantJobStatus: "RUNNING"},
+		{name: "foreign job not found", jobID: foreign.ID, wantStatus: http.StatusNotFound, wantQuotaUsed: 6},
+		{name: "invalid id", jobID: "abc", wantStatus: http.StatusBadRequest, wantQuotaUsed: 6},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &cancelJobSQL{jobTableSQL: *newJobTable(queued, running, foreign), quotaDaily: 10, quotaUsed: 6}
+			app := &App{SQL: stub}
+			rr := httptest.NewRecorder()
+			app.CancelJob(rr, requestWithParam("POST", "/v1/images/"+tc.jobID+"/cancel", "job_id", tc.jobID, "user-123"))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			if stub.quotaUsed != tc.wantQuotaUsed {
+				t.Fatalf("quota used = %d, want %d", stub.quotaUsed, tc.wantQuotaUsed)
+			}
+			if tc.wantJobStatus != "" && stub.jobs[tc.jobID].Status != tc.wantJobStatus {
+				t.Fatalf("job status = %q, want %q", stub.jobs[tc.jobID].Status, tc.wantJobStatus)
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var resp jobResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.JobID != tc.jobID || resp.Status != "CANCELED" || resp.RemainingQuota != tc.wantRemaining {
+				t.Fatalf("unexpected response: %+v", resp)
+			}
+		})
+	}
+}
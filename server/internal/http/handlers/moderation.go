@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const moderationTimeout = 5 * time.Second
+
+// allowedByModeration screens prompt text before a job is created, so a
+// blocked prompt never consumes quota. It writes a 422 moderation_blocked
+// response and returns false when the prompt is rejected. A failing remote
+// check is logged and the prompt is allowed.
+func (a *App) allowedByModeration(w http.ResponseWriter, r *http.Request, texts ...string) bool {
+	if a.Moderator == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), moderationTimeout)
+	defer cancel()
+	verdict, err := a.Moderator.Check(ctx, texts...)
+	if err != nil {
+		a.Logger.Warn().Err(err).Msg("moderation check failed; allowing prompt")
+		return true
+	}
+	if verdict.Blocked {
+		a.Logger.Info().Str("user_id", a.currentUserID(r)).Str("reason", verdict.Reason).Msg("prompt blocked by moderation")
+		a.error(w, http.StatusUnprocessableEntity, ErrModerationBlocked, "prompt was blocked by content moderation")
+		return false
+	}
+	return true
+}
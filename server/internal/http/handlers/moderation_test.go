@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/internal/middleware"
+	"server/internal/moderation"
+	"server/internal/providers/video"
+
+	"github.com/rs/zerolog"
+)
+
+func TestVideosGenerateModeration(t *testing.T) {
+	cases := []struct {
+		name       string
+		prompt     string
+		wantStatus int
+	}{
+		{name: "clean prompt queued", prompt: "Promo kopi susu gula aren", wantStatus: http.StatusAccepted},
+		{name: "denylisted term blocked", prompt: "Promo with a Firearm on display", wantStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &enqueueVideoSQL{}
+			app := &App{
+				Logger:         zerolog.Nop(),
+				SQL:            stub,
+				VideoProviders: map[string]video.Generator{"gemini": nil},
+				Moderator:      moderation.New(moderation.Options{Denylist: []string{"firearm"}}),
+			}
+			body, _ := json.Marshal(map[string]any{"provider": "gemini", "prompt": tc.prompt})
+			req := httptest.NewRequest("POST", "/v1/videos/generate", bytes.NewReader(body))
+			req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+			rr := httptest.NewRecorder()
+
+			app.VideosGenerate(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			if tc.wantStatus == http.StatusAccepted {
+				if stub.args == nil {
+					t.Fatal("expected clean prompt to be enqueued")
+				}
+				return
+			}
+			if stub.args != nil {
+				t.Fatal("blocked prompt was enqueued and consumed quota")
+			}
+			if !strings.Contains(rr.Body.String(), string(ErrModerationBlocked)) {
+				t.Fatalf("body = %s, want %s code", rr.Body.String(), ErrModerationBlocked)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"server/internal/middleware"
+)
+
+const defaultPlan = "free"
+
+// callerPlan returns the lower-cased plan from the caller's token, treating a
+// missing plan as free.
+func (a *App) callerPlan(r *http.Request) string {
+	if claims := middleware.ClaimsFromContext(r.Context()); claims != nil {
+		if plan := strings.ToLower(strings.TrimSpace(claims.Plan)); plan != "" {
+			return plan
+		}
+	}
+	return defaultPlan
+}
+
+// planAllowsProvider reports whether plan may request provider. Plans without
+// a configured allowlist, or whose list contains "*", may use any provider.
+func (a *App) planAllowsProvider(plan, provider string) bool {
+	if a.Config == nil {
+		return true
+	}
+	allowed, ok := a.Config.PlanProviders[plan]
+	if !ok {
+		return true
+	}
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	for _, candidate := range allowed {
+		if candidate == "*" || candidate == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// requireProviderForPlan writes a 403 and returns false when the caller's plan
+// cannot use provider.
+func (a *App) requireProviderForPlan(w http.ResponseWriter, r *http.Request, provider string) bool {
+	plan := a.callerPlan(r)
+	if a.planAllowsProvider(plan, provider) {
+		return true
+	}
+	a.error(w, http.StatusForbidden, ErrPlanRestricted, "the "+plan+" plan cannot use provider "+provider+"; upgrade to access premium models")
+	return false
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"server/internal/infra"
+	"server/internal/providers/prompt"
+
+	"github.com/rs/zerolog"
+)
+
+// promptEnhancerFactory builds an enhancer that falls back to fallback. It
+// returns a nil enhancer when the provider has no credentials.
+type promptEnhancerFactory func(fallback prompt.Enhancer) (prompt.Enhancer, error)
+
+// buildPromptChain wires enhancers in PROMPT_PROVIDER_CHAIN order: each one
+// falls back to the next available provider and the chain ends with static.
+// Providers without credentials or that fail to initialize are skipped.
+func buildPromptChain(chain []string, static prompt.Enhancer, factories map[string]promptEnhancerFactory, logger zerolog.Logger) prompt.Enhancer {
+	current := static
+	for i := len(chain) - 1; i >= 0; i-- {
+		name := chain[i]
+		if name == infra.PromptProviderStatic {
+			continue
+		}
+		factory, ok := factories[name]
+		if !ok {
+			logger.Warn().Str("provider", name).Msg("unknown prompt provider in chain; skipping")
+			continue
+		}
+		enhancer, err := factory(current)
+		if err != nil {
+			logger.Warn().Err(err).Str("provider", name).Msg("failed to initialize prompt enhancer; skipping")
+			continue
+		}
+		if enhancer == nil {
+			logger.Warn().Str("provider", name).Msg("prompt provider api key missing; skipping")
+			continue
+		}
+		current = enhancer
+	}
+	if current == static {
+		logger.Info().Msg("prompt enhancer will use static provider")
+	}
+	return current
+}
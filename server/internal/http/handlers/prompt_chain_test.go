@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"server/internal/providers/prompt"
+
+	"github.com/rs/zerolog"
+)
+
+type chainedEnhancer struct {
+	name     string
+	fallback prompt.Enhancer
+}
+
+func (e *chainedEnhancer) Enhance(context.Context, prompt.EnhanceRequest) (*prompt.EnhanceResponse, error) {
+	return &prompt.EnhanceResponse{Provider: e.name}, nil
+}
+
+func (e *chainedEnhancer) Random(context.Context, string, int) ([]prompt.EnhanceResponse, error) {
+	return nil, nil
+}
+
+func chainFactory(name string) promptEnhancerFactory {
+	return func(fallback prompt.Enhancer) (prompt.Enhancer, error) {
+		return &chainedEnhancer{name: name, fallback: fallback}, nil
+	}
+}
+
+// chainOrder walks the fallback links from head down to the static enhancer.
+func chainOrder(head, static prompt.Enhancer) string {
+	var names []string
+	for current := head; ; {
+		if current == static {
+			return strings.Join(append(names, "static"), ",")
+		}
+		link := current.(*chainedEnhancer)
+		names = append(names, link.name)
+		current = link.fallback
+	}
+}
+
+func TestBuildPromptChainFollowsConfiguredOrder(t *testing.T) {
+	static := prompt.NewStaticEnhancer()
+	factories := map[string]promptEnhancerFactory{
+		"openai": chainFactory("openai"),
+		"gemini": chainFactory("gemini"),
+	}
+	cases := []struct {
+		chain []string
+		want  string
+	}{
+		{chain: []string{"openai", "gemini", "static"}, want: "openai,gemini,static"},
+		{chain: []string{"gemini", "openai", "static"}, want: "gemini,openai,static"},
+		{chain: []string{"openai", "static"}, want: "openai,static"},
+		{chain: []string{"static"}, want: "static"},
+	}
+	for _, tc := range cases {
+		head := buildPromptChain(tc.chain, static, factories, zerolog.Nop())
+		if got := chainOrder(head, static); got != tc.want {
+			t.Fatalf("chain %v wired as %q, want %q", tc.chain, got, tc.want)
+		}
+	}
+}
+
+func TestBuildPromptChainSkipsUnavailableProviders(t *testing.T) {
+	static := prompt.NewStaticEnhancer()
+	factories := map[string]promptEnhancerFactory{
+		"openai": func(prompt.Enhancer) (prompt.Enhancer, error) { return nil, nil },
+		"gemini": func(prompt.Enhancer) (prompt.Enhancer, error) { return nil, errors.New("bad config") },
+	}
+	head := buildPromptChain([]string{"openai", "gemini", "static"}, static, factories, zerolog.Nop())
+	if head != static {
+		t.Fatalf("expected static enhancer when no provider is available, got %T", head)
+	}
+
+	factories["gemini"] = chainFactory("gemini")
+	head = buildPromptChain([]string{"openai", "gemini", "static"}, static, factories, zerolog.Nop())
+	if got := chainOrder(head, static); got != "gemini,static" {
+		t.Fatalf("chain wired as %q, want gemini,static", got)
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/domain/jsoncfg"
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultPromptDraftLimit = 20
+	maxPromptDraftLimit     = 100
+	maxPromptDraftName      = 120
+)
+
+type savePromptDraftRequest struct {
+	Name   string             `json:"name"`
+	Prompt jsoncfg.PromptJSON `json:"prompt"`
+}
+
+type promptDraftDTO struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Prompt    jsoncfg.PromptJSON `json:"prompt"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// SavePromptDraft stores a normalized prompt for the current user so an
+// enhanced prompt survives a page refresh. The name defaults to the title.
+func (a *App) SavePromptDraft(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	var req savePromptDraftRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	plan := a.callerPlan(r)
+	req.Prompt.NormalizeForPlan(middleware.LocaleFromContext(r.Context()), plan)
+	if err := req.Prompt.ValidateForPlan(plan); err != nil {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, err.Error())
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = strings.TrimSpace(req.Prompt.Title)
+	}
+	if len(name) > maxPromptDraftName {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "name must be at most 120 characters")
+		return
+	}
+	draft := promptDraftDTO{Name: name, Prompt: req.Prompt}
+	row := a.SQL.QueryRow(r.Context(), sqlinline.QInsertPromptDraft, userID, name, jsoncfg.MustMarshal(req.Prompt))
+	if err := row.Scan(&draft.ID, &draft.CreatedAt); err != nil {
+		a.Logger.Error().Err(err).Str("user_id", userID).Msg("save prompt draft failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to save draft")
+		return
+	}
+	a.json(w, http.StatusCreated, draft)
+}
+
+// ListPromptDrafts returns the current user's drafts, newest first.
+func (a *App) ListPromptDrafts(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = defaultPromptDraftLimit
+	}
+	if limit > maxPromptDraftLimit {
+		limit = maxPromptDraftLimit
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	rows, err := a.SQL.Query(r.Context(), sqlinline.QListPromptDrafts, userID, limit, offset)
+	if err != nil {
+		a.Logger.Error().Err(err).Str("user_id", userID).Msg("list prompt drafts failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load drafts")
+		return
+	}
+	defer rows.Close()
+	items := make([]promptDraftDTO, 0)
+	for rows.Next() {
+		var item promptDraftDTO
+		var raw []byte
+		if err := rows.Scan(&item.ID, &item.Name, &raw, &item.CreatedAt); err != nil {
+			continue
+		}
+		if err := json.Unmarshal(raw, &item.Prompt); err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	a.json(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// DeletePromptDraft removes one of the current user's drafts. Drafts owned by
+// someone else are reported as not found.
+func (a *App) DeletePromptDraft(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	tag, err := a.SQL.Exec(r.Context(), sqlinline.QDeletePromptDraft, chi.URLParam(r, "id"), userID)
+	if err != nil {
+		a.Logger.Error().Err(err).Str("user_id", userID).Msg("delete prompt draft failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to delete draft")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		a.error(w, http.StatusNotFound, ErrNotFound, "draft not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+type storedDraft struct {
+	id, userID, name string
+	prompt           []byte
+	createdAt        time.Time
+}
+
+// promptDraftSQL keeps drafts in memory and scopes them by user like the
+// prompt_drafts queries do.
+type promptDraftSQL struct {
+	drafts []storedDraft
+}
+
+func (s *promptDraftSQL) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	if query != sqlinline.QDeletePromptDraft {
+		return pgconn.CommandTag{}, errors.New("unexpected exec")
+	}
+	for i, d := range s.drafts {
+		if d.id == args[0] && d.userID == args[1] {
+			s.drafts = append(s.drafts[:i], s.drafts[i+1:]...)
+			return pgconn.NewCommandTag("DELETE 1"), nil
+		}
+	}
+	return pgconn.NewCommandTag("DELETE 0"), nil
+}
+
+func (s *promptDraftSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if query != sqlinline.QInsertPromptDraft {
+		return NewSimpleRow(nil)
+	}
+	d := storedDraft{
+		id:        fmt.Sprintf("draft-%d", len(s.drafts)+1),
+		userID:    args[0].(string),
+		name:      args[1].(string),
+		prompt:    args[2].(json.RawMessage),
+		createdAt: time.Date(2024, 5, 1, 10, len(s.drafts), 0, 0, time.UTC),
+	}
+	s.drafts = append(s.drafts, d)
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = d.id
+		*dest[1].(*time.Time) = d.createdAt
+		return nil
+	})
+}
+
+func (s *promptDraftSQL) Query(_ context.Context, query string, args ...any) (pgx.Rows, error) {
+	if query != sqlinline.QListPromptDrafts {
+		return nil, errors.New("unexpected query")
+	}
+	var owned []storedDraft
+	for i := len(s.drafts) - 1; i >= 0; i-- {
+		if s.drafts[i].userID == args[0] {
+			owned = append(owned, s.drafts[i])
+		}
+	}
+	return &promptDraftRows{drafts: owned}, nil
+}
+
+type promptDraftRows struct {
+	TestRowsBase
+	drafts []storedDraft
+	idx    int
+}
+
+func (r *promptDraftRows) Next() bool {
+	if r.idx >= len(r.drafts) {
+		return false
+	}
+	r.idx++
+	return true
+}
+
+func (r *promptDraftRows) Scan(dest ...any) error {
+	d := r.drafts[r.idx-1]
+	*dest[0].(*string) = d.id
+	*dest[1].(*string) = d.name
+	*dest[2].(*[]byte) = d.prompt
+	*dest[3].(*time.Time) = d.createdAt
+	return nil
+}
+
+func (r *promptDraftRows) Err() error { return nil }
+
+func (r *promptDraftRows) Close() {}
+
+func TestPromptDraftsSaveListDelete(t *testing.T) {
+	app := &App{Logger: zerolog.Nop(), SQL: &promptDraftSQL{}}
+
+	save := func(userID, body string) *httptest.ResponseRecorder {
+		req := requestWithParam("POST", "/v1/prompts/drafts", "", "", userID)
+		req.Body = io.NopCloser(strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		app.SavePromptDraft(rr, req)
+		return rr
+	}
+	list := func(userID string) []promptDraftDTO {
+		req := requestWithParam("GET", "/v1/prompts/drafts", "", "", userID)
+		rr := httptest.NewRecorder()
+		app.ListPromptDrafts(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("list status = %d; body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Items []promptDraftDTO `json:"items"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode list: %v", err)
+		}
+		return resp.Items
+	}
+	remove := func(id, userID string) int {
+		req := requestWithParam("DELETE", "/v1/prompts/drafts/"+id, "id", id, userID)
+		rr := httptest.NewRecorder()
+		app.DeletePromptDraft(rr, req)
+		return rr.Code
+	}
+
+	rr := save("user-1", `{"prompt":{"title":"Kopi","product_type":"food","style":"minimal","background":"white"}}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("save status = %d; body=%s", rr.Code, rr.Body.String())
+	}
+	var saved promptDraftDTO
+	if err := json.NewDecoder(rr.Body).Decode(&saved); err != nil {
+		t.Fatalf("decode save: %v", err)
+	}
+	if saved.ID == "" || saved.Name != "Kopi" || saved.Prompt.Version == "" {
+		t.Fatalf("saved draft = %+v, want id, title as name and a normalized prompt", saved)
+	}
+	if rr := save("user-1", `{"prompt":{"title":""}}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid prompt status = %d, want 400", rr.Code)
+	}
+	if rr := save("user-2", `{"name":"Batik","prompt":{"title":"Batik","product_type":"fashion","style":"bold","background":"studio"}}`); rr.Code != http.StatusCreated {
+		t.Fatalf("second user save status = %d", rr.Code)
+	}
+
+	if items := list("user-1"); len(items) != 1 || items[0].ID != saved.ID || items[0].Prompt.Title != "Kopi" {
+		t.Fatalf("user-1 drafts = %+v", items)
+	}
+	if code := remove(saved.ID, "user-2"); code != http.StatusNotFound {
+		t.Fatalf("foreign delete status = %d, want 404", code)
+	}
+	if code := remove(saved.ID, "user-1"); code != http.StatusNoContent {
+		t.Fatalf("owner delete status = %d, want 204", code)
+	}
+	if items := list("user-1"); len(items) != 0 {
+		t.Fatalf("drafts after delete = %+v", items)
+	}
+	if items := list("user-2"); len(items) != 1 || items[0].Name != "Batik" {
+		t.Fatalf("user-2 drafts = %+v", items)
+	}
+	if code := remove(saved.ID, ""); code != http.StatusUnauthorized {
+		t.Fatalf("anonymous delete status = %d, want 401", code)
+	}
+}
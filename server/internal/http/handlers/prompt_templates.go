@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"server/internal/domain/jsoncfg"
+	"server/internal/i18n"
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type promptTemplateDTO struct {
+	ID       string             `json:"id"`
+	Category string             `json:"category"`
+	Locale   string             `json:"locale"`
+	Name     string             `json:"name"`
+	Prompt   jsoncfg.PromptJSON `json:"prompt"`
+}
+
+type applyPromptTemplateRequest struct {
+	Overrides json.RawMessage `json:"overrides"`
+}
+
+type applyPromptTemplateResponse struct {
+	TemplateID string             `json:"template_id"`
+	Prompt     jsoncfg.PromptJSON `json:"prompt"`
+}
+
+// ListPromptTemplates returns the curated templates for the request locale,
+// optionally narrowed to one product category. Each category is served in the
+// first locale on the request's fallback chain that has templates for it.
+func (a *App) ListPromptTemplates(w http.ResponseWriter, r *http.Request) {
+	category := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("category")))
+	locale := strings.TrimSpace(r.URL.Query().Get("locale"))
+	if locale == "" {
+		locale = middleware.LocaleFromContext(r.Context())
+	}
+	rows, err := a.SQL.Query(r.Context(), sqlinline.QListPromptTemplates, category, i18n.Chain(locale))
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("list prompt templates failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load templates")
+		return
+	}
+	defer rows.Close()
+	items := make([]promptTemplateDTO, 0)
+	for rows.Next() {
+		item, err := scanPromptTemplate(rows)
+		if err != nil {
+			a.Logger.Warn().Err(err).Msg("skip malformed prompt template")
+			continue
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		a.Logger.Error().Err(err).Msg("list prompt templates failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load templates")
+		return
+	}
+	a.json(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// ApplyPromptTemplate merges the user's overrides onto a template and returns
+// the normalized prompt. Fields absent from overrides keep the template value.
+func (a *App) ApplyPromptTemplate(w http.ResponseWriter, r *http.Request) {
+	var req applyPromptTemplateRequest
+	if !a.decodeOptionalJSON(w, r, &req) {
+		return
+	}
+	template, err := scanPromptTemplate(a.SQL.QueryRow(r.Context(), sqlinline.QSelectPromptTemplate, chi.URLParam(r, "id")))
+	if err != nil {
+		if !infra.IsNoRows(err) {
+			a.Logger.Error().Err(err).Msg("load prompt template failed")
+		}
+		a.error(w, http.StatusNotFound, ErrNotFound, "template not found")
+		return
+	}
+	merged := template.Prompt
+	if len(req.Overrides) > 0 && string(req.Overrides) != "null" {
+		if err := json.Unmarshal(req.Overrides, &merged); err != nil {
+			a.error(w, http.StatusBadRequest, ErrBadRequest, "invalid overrides")
+			return
+		}
+	}
+	plan := a.callerPlan(r)
+	merged.NormalizeForPlan(template.Locale, plan)
+	if err := merged.ValidateForPlan(plan); err != nil {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, err.Error())
+		return
+	}
+	a.json(w, http.StatusOK, applyPromptTemplateResponse{TemplateID: template.ID, Prompt: merged})
+}
+
+func scanPromptTemplate(row pgx.Row) (promptTemplateDTO, error) {
+	var item promptTemplateDTO
+	var raw []byte
+	if err := row.Scan(&item.ID, &item.Category, &item.Locale, &item.Name, &raw); err != nil {
+		return promptTemplateDTO{}, err
+	}
+	if err := json.Unmarshal(raw, &item.Prompt); err != nil {
+		return promptTemplateDTO{}, err
+	}
+	return item, nil
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+type promptTemplateRow struct {
+	id, category, locale, name, prompt string
+}
+
+// promptTemplateSQL filters its rows the way QListPromptTemplates does,
+// keeping each category in its best-ranked locale on the chain.
+type promptTemplateSQL struct {
+	rows []promptTemplateRow
+	args []any
+}
+
+func (s *promptTemplateSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *promptTemplateSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if query != sqlinline.QSelectPromptTemplate {
+		return NewSimpleRow(nil)
+	}
+	for _, row := range s.rows {
+		if row.id == args[0] {
+			return NewSimpleRow(row.scan)
+		}
+	}
+	return NewSimpleRow(nil)
+}
+
+func (s *promptTemplateSQL) Query(_ context.Context, query string, args ...any) (pgx.Rows, error) {
+	if query != sqlinline.QListPromptTemplates {
+		return nil, errors.New("unexpected query")
+	}
+	s.args = args
+	chain := args[1].([]string)
+	rank := func(locale string) int { return slices.Index(chain, locale) }
+	best := map[string]int{}
+	for _, row := range s.rows {
+		if args[0] != "" && row.category != args[0] || rank(row.locale) < 0 {
+			continue
+		}
+		if r, ok := best[row.category]; !ok || rank(row.locale) < r {
+			best[row.category] = rank(row.locale)
+		}
+	}
+	var matched []promptTemplateRow
+	for _, row := range s.rows {
+		if r, ok := best[row.category]; ok && rank(row.locale) == r {
+			matched = append(matched, row)
+		}
+	}
+	return &promptTemplateRows{rows: matched}, nil
+}
+
+func (row promptTemplateRow) scan(dest ...any) error {
+	*dest[0].(*string) = row.id
+	*dest[1].(*string) = row.category
+	*dest[2].(*string) = row.locale
+	*dest[3].(*string) = row.name
+	*dest[4].(*[]byte) = []byte(row.prompt)
+	return nil
+}
+
+type promptTemplateRows struct {
+	TestRowsBase
+	rows []promptTemplateRow
+	idx  int
+}
+
+func (r *promptTemplateRows) Next() bool {
+	if r.idx >= len(r.rows) {
+		return false
+	}
+	r.idx++
+	return true
+}
+
+func (r *promptTemplateRows) Scan(dest ...any) error { return r.rows[r.idx-1].scan(dest...) }
+
+func (r *promptTemplateRows) Err() error { return nil }
+
+func (r *promptTemplateRows) Close() {}
+
+func newPromptTemplateApp() (*App, *promptTemplateSQL) {
+	sqlStub := &promptTemplateSQL{rows: []promptTemplateRow{
+		{id: "tpl-food-en", category: "food", locale: "en", name: "Food hero shot",
+			prompt: `{"title":"Signature dish","product_type":"food","style":"warm light","background":"wooden table","aspect_ratio":"1:1","quantity":1}`},
+		{id: "tpl-food-id", category: "food", locale: "id", name: "Foto menu makanan",
+			prompt: `{"title":"Menu andalan","product_type":"makanan","style":"cahaya hangat","background":"meja kayu","extras":{"locale":"id"}}`},
+		{id: "tpl-fashion-en", category: "fashion", locale: "en", name: "Fashion catalogue",
+			prompt: `{"title":"New collection","product_type":"fashion","style":"studio","background":"white","aspect_ratio":"3:4"}`},
+	}}
+	return &App{Logger: zerolog.Nop(), SQL: sqlStub}, sqlStub
+}
+
+func TestListPromptTemplatesFiltersByCategoryAndLocale(t *testing.T) {
+	app, sqlStub := newPromptTemplateApp()
+	req := httptest.NewRequest("GET", "/v1/prompts/templates?category=Food", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.LocaleKey, "id"))
+	rr := httptest.NewRecorder()
+
+	app.ListPromptTemplates(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if sqlStub.args[0] != "food" || !slices.Equal(sqlStub.args[1].([]string), []string{"id", "en"}) {
+		t.Fatalf("query args = %v, want [food [id en]]", sqlStub.args)
+	}
+	var resp struct {
+		Items []promptTemplateDTO `json:"items"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].ID != "tpl-food-id" || resp.Items[0].Prompt.Title != "Menu andalan" {
+		t.Fatalf("items = %+v, want the Indonesian food template", resp.Items)
+	}
+}
+
+func TestListPromptTemplatesFallsBackPerCategory(t *testing.T) {
+	app, sqlStub := newPromptTemplateApp()
+	req := httptest.NewRequest("GET", "/v1/prompts/templates?locale=ms-MY", nil)
+	rr := httptest.NewRecorder()
+
+	app.ListPromptTemplates(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	if !slices.Equal(sqlStub.args[1].([]string), []string{"ms", "id", "en"}) {
+		t.Fatalf("locale chain = %v, want [ms id en]", sqlStub.args[1])
+	}
+	var resp struct {
+		Items []promptTemplateDTO `json:"items"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	got := map[string]string{}
+	for _, item := range resp.Items {
+		got[item.Category] = item.ID
+	}
+	want := map[string]string{"food": "tpl-food-id", "fashion": "tpl-fashion-en"}
+	if len(got) != len(want) || got["food"] != want["food"] || got["fashion"] != want["fashion"] {
+		t.Fatalf("templates = %v, want %v", got, want)
+	}
+}
+
+func TestApplyPromptTemplateMergesOverrides(t *testing.T) {
+	app, _ := newPromptTemplateApp()
+	apply := func(id, body string) *httptest.ResponseRecorder {
+		req := requestWithParam("POST", "/v1/prompts/templates/"+id+"/apply", "id", id, "user-123")
+		req.Body = io.NopCloser(strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		app.ApplyPromptTemplate(rr, req)
+		return rr
+	}
+
+	rr := apply("tpl-food-en", `{"overrides":{"title":"Nasi goreng","quantity":2,"extras":{"negative_prompt":"blurry"}}}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp applyPromptTemplateResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	got := resp.Prompt
+	if got.Title != "Nasi goreng" || got.Quantity != 2 || got.Extras.NegativePrompt != "blurry" {
+		t.Fatalf("overrides not applied: %+v", got)
+	}
+	if got.Style != "warm light" || got.Background != "wooden table" {
+		t.Fatalf("template fields lost: %+v", got)
+	}
+	if got.Version == "" || got.Extras.Locale != "en" || got.Extras.Quality == "" {
+		t.Fatalf("prompt not normalized: %+v", got)
+	}
+
+	if rr := apply("tpl-food-en", `{"overrides":{"title":""}}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid override status = %d, want 400", rr.Code)
+	}
+	if rr := apply("missing", `{}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("missing template status = %d, want 404", rr.Code)
+	}
+}
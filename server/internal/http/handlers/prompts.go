@@ -2,8 +2,9 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"server/internal/domain/jsoncfg"
@@ -19,26 +20,27 @@ type promptEnhanceRequest struct {
 }
 
 type promptEnhanceResponse struct {
-	Prompt jsoncfg.PromptJSON `json:"prompt"`
-	Ideas  []map[string]any   `json:"ideas"`
-	Extra  map[string]string  `json:"extra"`
+	Prompt         jsoncfg.PromptJSON `json:"prompt"`
+	Ideas          []map[string]any   `json:"ideas"`
+	Extra          map[string]string  `json:"extra"`
+	DetectedLocale string             `json:"detected_locale"`
 }
 
 func (a *App) PromptEnhance(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	var req promptEnhanceRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "invalid payload")
+	if !a.decodeJSON(w, r, &req) {
 		return
 	}
 	locale := middleware.LocaleFromContext(r.Context())
-	req.Prompt.Normalize(locale)
-	if err := req.Prompt.Validate(); err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", err.Error())
+	plan := a.callerPlan(r)
+	req.Prompt.NormalizeForPlan(locale, plan)
+	if err := req.Prompt.ValidateForPlan(plan); err != nil {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, err.Error())
 		return
 	}
 	enhanceReq := prompt.EnhanceRequest{Prompt: req.Prompt, Locale: req.Prompt.Extras.Locale}
@@ -51,13 +53,29 @@ func (a *App) PromptEnhance(w http.ResponseWriter, r *http.Request) {
 	}
 	if !success {
 		a.logUsageEvent(r, userID, "PROMPT_ENHANCE", false, latency, map[string]any{"error": "enhancer_failed"})
-		a.error(w, http.StatusInternalServerError, "internal", "enhancer failed")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "enhancer failed")
 		return
 	}
-	enriched := req.Prompt
+	out := enhancedPrompt(req.Prompt, res)
+	props := map[string]any{
+		"locale":   out.Prompt.Extras.Locale,
+		"provider": res.Provider,
+	}
+	if len(res.Metadata) > 0 {
+		props["metadata"] = res.Metadata
+	}
+	a.logUsageEvent(r, userID, "PROMPT_ENHANCE", true, latency, props)
+	a.json(w, http.StatusOK, out)
+}
+
+// enhancedPrompt merges an enhancer result into the submitted prompt.
+func enhancedPrompt(p jsoncfg.PromptJSON, res *prompt.EnhanceResponse) promptEnhanceResponse {
+	enriched := p
+	detectedLocale := p.Extras.Locale
 	if res.Metadata != nil {
 		if v, ok := res.Metadata["locale"]; ok && v != "" {
 			enriched.Extras.Locale = v
+			detectedLocale = v
 		}
 	}
 	ideas := make([]map[string]any, 0, len(res.Ideas))
@@ -75,26 +93,20 @@ func (a *App) PromptEnhance(w http.ResponseWriter, r *http.Request) {
 			"keywords":    res.Keywords,
 		})
 	}
-	props := map[string]any{
-		"locale":   enriched.Extras.Locale,
-		"provider": res.Provider,
-	}
-	if len(res.Metadata) > 0 {
-		props["metadata"] = res.Metadata
-	}
-	a.logUsageEvent(r, userID, "PROMPT_ENHANCE", true, latency, props)
-	a.json(w, http.StatusOK, promptEnhanceResponse{Prompt: enriched, Ideas: ideas, Extra: res.Metadata})
+	return promptEnhanceResponse{Prompt: enriched, Ideas: ideas, Extra: res.Metadata, DetectedLocale: detectedLocale}
 }
 
 func (a *App) PromptRandom(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	locale := middleware.LocaleFromContext(r.Context())
+	count, _ := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("count")))
+	count = prompt.ClampRandomCount(count)
 	started := time.Now()
-	list, err := a.PromptEnhancer.Random(r.Context(), locale)
+	list, err := a.PromptEnhancer.Random(r.Context(), locale, count)
 	success := err == nil
 	latency := int(time.Since(started).Milliseconds())
 	if latency < 0 {
@@ -102,14 +114,14 @@ func (a *App) PromptRandom(w http.ResponseWriter, r *http.Request) {
 	}
 	if !success {
 		a.logUsageEvent(r, userID, "PROMPT_RANDOM", false, latency, map[string]any{"locale": locale})
-		a.error(w, http.StatusInternalServerError, "internal", "failed to fetch prompts")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to fetch prompts")
 		return
 	}
 	provider := ""
 	if len(list) > 0 {
 		provider = list[0].Provider
 	}
-	props := map[string]any{"locale": locale, "provider": provider}
+	props := map[string]any{"locale": locale, "provider": provider, "count": count}
 	if provider == "static" && len(list) > 0 {
 		if reason := list[0].Metadata["fallback_reason"]; reason != "" {
 			props["fallback_reason"] = reason
@@ -123,6 +135,12 @@ func (a *App) logUsageEvent(r *http.Request, userID, event string, success bool,
 	if userID == "" {
 		return
 	}
+	a.insertUsageEvent(r, userID, event, success, latency, props)
+}
+
+// insertUsageEvent writes a usage_events row; user is the user ID or nil for
+// events without a known user, such as a rejected sign-in.
+func (a *App) insertUsageEvent(r *http.Request, user any, event string, success bool, latency int, props map[string]any) {
 	var requestID any
 	if id := middleware.RequestIDFromContext(r.Context()); id != "" {
 		if parsed, err := uuid.Parse(id); err == nil {
@@ -135,7 +153,7 @@ func (a *App) logUsageEvent(r *http.Request, userID, event string, success bool,
 	payload := jsoncfg.MustMarshal(props)
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
-	if _, err := a.SQL.Exec(ctx, sqlinline.QInsertUsageEvent, userID, requestID, event, success, latency, payload); err != nil {
+	if _, err := a.SQL.Exec(ctx, sqlinline.QInsertUsageEvent, user, requestID, event, success, latency, payload); err != nil {
 		a.Logger.Error().Err(err).Str("event", event).Msg("log usage failed")
 	}
 }
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"server/internal/domain/jsoncfg"
+	"server/internal/middleware"
+	"server/internal/providers/prompt"
+)
+
+const (
+	maxPromptBatchSize     = 20
+	promptBatchConcurrency = 4
+)
+
+type promptEnhanceBatchRequest struct {
+	Prompts []jsoncfg.PromptJSON `json:"prompts"`
+}
+
+type promptEnhanceBatchItem struct {
+	Index   int                    `json:"index"`
+	Success bool                   `json:"success"`
+	Result  *promptEnhanceResponse `json:"result,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+type promptEnhanceBatchResponse struct {
+	Items     []promptEnhanceBatchItem `json:"items"`
+	Succeeded int                      `json:"succeeded"`
+	Failed    int                      `json:"failed"`
+}
+
+// PromptEnhanceBatch enhances up to maxPromptBatchSize prompts in one call.
+// Items are reported in request order with their own success flag, so one
+// invalid prompt does not fail the rest of the batch.
+func (a *App) PromptEnhanceBatch(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	var req promptEnhanceBatchRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if len(req.Prompts) == 0 {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "prompts required")
+		return
+	}
+	if len(req.Prompts) > maxPromptBatchSize {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, fmt.Sprintf("at most %d prompts per batch", maxPromptBatchSize))
+		return
+	}
+	locale := middleware.LocaleFromContext(r.Context())
+	plan := a.callerPlan(r)
+	started := time.Now()
+
+	items := make([]promptEnhanceBatchItem, len(req.Prompts))
+	providers := make([]string, len(req.Prompts))
+	sem := make(chan struct{}, promptBatchConcurrency)
+	var wg sync.WaitGroup
+	for i := range req.Prompts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			items[i], providers[i] = a.enhanceBatchItem(r.Context(), i, req.Prompts[i], locale, plan)
+		}(i)
+	}
+	wg.Wait()
+
+	resp := promptEnhanceBatchResponse{Items: items}
+	usedProviders := map[string]int{}
+	for i, item := range items {
+		if item.Success {
+			resp.Succeeded++
+			usedProviders[providers[i]]++
+		} else {
+			resp.Failed++
+		}
+	}
+	latency := int(time.Since(started).Milliseconds())
+	if latency < 0 {
+		latency = 0
+	}
+	a.logUsageEvent(r, userID, "PROMPT_ENHANCE", resp.Succeeded > 0, latency, map[string]any{
+		"batch":     true,
+		"count":     len(items),
+		"succeeded": resp.Succeeded,
+		"failed":    resp.Failed,
+		"locale":    locale,
+		"providers": usedProviders,
+	})
+	a.json(w, http.StatusOK, resp)
+}
+
+func (a *App) enhanceBatchItem(ctx context.Context, index int, p jsoncfg.PromptJSON, locale, plan string) (promptEnhanceBatchItem, string) {
+	item := promptEnhanceBatchItem{Index: index}
+	p.NormalizeForPlan(locale, plan)
+	if err := p.ValidateForPlan(plan); err != nil {
+		item.Error = err.Error()
+		return item, ""
+	}
+	res, err := a.PromptEnhancer.Enhance(ctx, prompt.EnhanceRequest{Prompt: p, Locale: p.Extras.Locale})
+	if err != nil || res == nil {
+		item.Error = "enhancer failed"
+		return item, ""
+	}
+	out := enhancedPrompt(p, res)
+	item.Success = true
+	item.Result = &out
+	return item, res.Provider
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/internal/domain/jsoncfg"
+	"server/internal/middleware"
+	"server/internal/providers/prompt"
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type stubEnhancer struct {
+	res     *prompt.EnhanceResponse
+	lastReq prompt.EnhanceRequest
+}
+
+func (s *stubEnhancer) Enhance(_ context.Context, req prompt.EnhanceRequest) (*prompt.EnhanceResponse, error) {
+	s.lastReq = req
+	if s.res == nil {
+		return nil, errors.New("no response")
+	}
+	return s.res, nil
+}
+
+func (s *stubEnhancer) Random(context.Context, string, int) ([]prompt.EnhanceResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestPromptEnhanceDetectedLocale(t *testing.T) {
+	cases := []struct {
+		name     string
+		metadata map[string]string
+		want     string
+	}{
+		{name: "model reports a different locale", metadata: map[string]string{"locale": "en"}, want: "en"},
+		{name: "falls back to requested locale", metadata: nil, want: "id"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			enhancer := &stubEnhancer{res: &prompt.EnhanceResponse{Title: "Kopi", Description: "Kopi susu", Metadata: tc.metadata}}
+			app := &App{SQL: &enqueueVideoSQL{}, PromptEnhancer: enhancer}
+			body := `{"prompt":{"title":"Kopi","product_type":"food","style":"minimal","background":"white","extras":{"locale":"id"}}}`
+			req := httptest.NewRequest("POST", "/v1/prompts/enhance", strings.NewReader(body))
+			req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+			rr := httptest.NewRecorder()
+
+			app.PromptEnhance(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+			}
+			var resp map[string]any
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if got, ok := resp["detected_locale"].(string); !ok || got != tc.want {
+				t.Fatalf("detected_locale = %#v, want %q", resp["detected_locale"], tc.want)
+			}
+		})
+	}
+}
+
+// titleEnhancer echoes the prompt title so batch results can be matched to
+// their input; it is safe for concurrent use.
+type titleEnhancer struct{}
+
+func (titleEnhancer) Enhance(_ context.Context, req prompt.EnhanceRequest) (*prompt.EnhanceResponse, error) {
+	return &prompt.EnhanceResponse{Title: req.Prompt.Title + " Signature", Provider: "stub"}, nil
+}
+
+func (titleEnhancer) Random(context.Context, string, int) ([]prompt.EnhanceResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+type usageEventSQL struct {
+	enqueueVideoSQL
+	events [][]any
+}
+
+func (s *usageEventSQL) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	if query == sqlinline.QInsertUsageEvent {
+		s.events = append(s.events, args)
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func TestPromptEnhanceBatchReportsPerItemResults(t *testing.T) {
+	sqlStub := &usageEventSQL{}
+	app := &App{SQL: sqlStub, PromptEnhancer: titleEnhancer{}}
+	body := `{"prompts":[
+		{"title":"Kopi","product_type":"food","style":"minimal","background":"white"},
+		{"title":"","product_type":"food","style":"minimal","background":"white"},
+		{"title":"Batik","product_type":"fashion","style":"bold","background":"studio"}
+	]}`
+	req := httptest.NewRequest("POST", "/v1/prompts/enhance/batch", strings.NewReader(body))
+	req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+	rr := httptest.NewRecorder()
+
+	app.PromptEnhanceBatch(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp promptEnhanceBatchResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Succeeded != 2 || resp.Failed != 1 || len(resp.Items) != 3 {
+		t.Fatalf("succeeded = %d, failed = %d, items = %d", resp.Succeeded, resp.Failed, len(resp.Items))
+	}
+	for i, want := range []string{"Kopi Signature", "", "Batik Signature"} {
+		item := resp.Items[i]
+		if item.Index != i {
+			t.Fatalf("item %d has index %d", i, item.Index)
+		}
+		if want == "" {
+			if item.Success || !strings.Contains(item.Error, "title is required") {
+				t.Fatalf("item %d = %+v, want validation failure", i, item)
+			}
+			continue
+		}
+		if !item.Success || item.Result == nil || item.Result.Ideas[0]["title"] != want {
+			t.Fatalf("item %d = %+v, want title %q", i, item, want)
+		}
+	}
+	if len(sqlStub.events) != 1 {
+		t.Fatalf("usage events = %d, want a single aggregate event", len(sqlStub.events))
+	}
+	if props := string(sqlStub.events[0][5].(json.RawMessage)); !strings.Contains(props, `"succeeded":2`) || !strings.Contains(props, `"failed":1`) {
+		t.Fatalf("usage event props = %s", props)
+	}
+}
+
+func TestPromptEnhanceBatchRejectsOversizedBatch(t *testing.T) {
+	app := &App{SQL: &usageEventSQL{}, PromptEnhancer: titleEnhancer{}}
+	prompts := make([]string, maxPromptBatchSize+1)
+	for i := range prompts {
+		prompts[i] = `{"title":"Kopi"}`
+	}
+	body := `{"prompts":[` + strings.Join(prompts, ",") + `]}`
+	req := httptest.NewRequest("POST", "/v1/prompts/enhance/batch", strings.NewReader(body))
+	req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+	rr := httptest.NewRecorder()
+
+	app.PromptEnhanceBatch(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rr.Code)
+	}
+}
+
+func TestPromptEnhanceRejectsOverlongInstructions(t *testing.T) {
+	app := &App{SQL: &usageEventSQL{}, PromptEnhancer: titleEnhancer{}}
+	body, err := json.Marshal(map[string]any{"prompt": map[string]any{
+		"title":        "Kopi",
+		"product_type": "food",
+		"style":        "modern",
+		"background":   "studio",
+		"instructions": strings.Repeat("x", jsoncfg.DefaultMaxInstructionsLength+1),
+	}})
+	if err != nil {
+		t.Fatalf("marshal body: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/v1/prompts/enhance", bytes.NewReader(body))
+	req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+	rr := httptest.NewRecorder()
+
+	app.PromptEnhance(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400; body=%s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "instructions must be at most") {
+		t.Fatalf("body = %s, want the offending field named", rr.Body.String())
+	}
+}
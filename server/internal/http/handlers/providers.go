@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"server/internal/domain/jsoncfg"
+)
+
+const (
+	providerMediaImage = "image"
+	providerMediaVideo = "video"
+)
+
+// credentialReporter is implemented by generators that know whether their
+// upstream API key is configured.
+type credentialReporter interface {
+	HasCredentials() bool
+}
+
+// sourceImageEditor is implemented by generators that can work from an
+// uploaded source image instead of only generating from text.
+type sourceImageEditor interface {
+	SupportsSourceImage() bool
+}
+
+type providerInfoDTO struct {
+	Key                   string   `json:"key"`
+	Media                 string   `json:"media"`
+	SupportsEditing       bool     `json:"supports_editing"`
+	AspectRatios          []string `json:"aspect_ratios"`
+	CredentialsConfigured bool     `json:"credentials_configured"`
+}
+
+// ListProviders describes every provider key the generate endpoints accept so
+// clients can discover capabilities instead of hardcoding them. Image keys that
+// ImagesGenerate would reject are left out. Video requests take no aspect
+// ratio, so video providers report an empty list.
+func (a *App) ListProviders(w http.ResponseWriter, r *http.Request) {
+	items := make([]providerInfoDTO, 0, len(a.ImageProviders)+len(a.VideoProviders))
+	ratios := jsoncfg.AllowedAspectRatios()
+	for key, gen := range a.ImageProviders {
+		if _, ok := imageEditProvider(key); !ok {
+			continue
+		}
+		editor, ok := gen.(sourceImageEditor)
+		items = append(items, providerInfoDTO{
+			Key:                   key,
+			Media:                 providerMediaImage,
+			SupportsEditing:       ok && editor.SupportsSourceImage(),
+			AspectRatios:          ratios,
+			CredentialsConfigured: hasCredentials(gen),
+		})
+	}
+	for key, gen := range a.VideoProviders {
+		editor, ok := gen.(sourceImageEditor)
+		items = append(items, providerInfoDTO{
+			Key:                   key,
+			Media:                 providerMediaVideo,
+			SupportsEditing:       ok && editor.SupportsSourceImage(),
+			AspectRatios:          []string{},
+			CredentialsConfigured: hasCredentials(gen),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Media != items[j].Media {
+			return items[i].Media < items[j].Media
+		}
+		return items[i].Key < items[j].Key
+	})
+	a.json(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func hasCredentials(gen any) bool {
+	reporter, ok := gen.(credentialReporter)
+	return ok && reporter.HasCredentials()
+}
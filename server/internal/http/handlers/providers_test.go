@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"server/internal/domain/jsoncfg"
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/providers/genai"
+	"server/internal/providers/image"
+	"server/internal/providers/qwen"
+	"server/internal/providers/video"
+
+	"github.com/rs/zerolog"
+)
+
+func TestListProvidersDescribesDefaultSet(t *testing.T) {
+	qwenClient, err := qwen.NewClient(qwen.Options{APIKey: "dashscope-key"})
+	if err != nil {
+		t.Fatalf("qwen client: %v", err)
+	}
+	geminiClient, err := genai.NewClient(genai.Options{})
+	if err != nil {
+		t.Fatalf("gemini client: %v", err)
+	}
+	geminiImage := image.NewGeminiGenerator(geminiClient)
+	app := &App{
+		ImageProviders: imageProviderSet(qwenClient.Model(),
+			image.NewQwenGenerator(qwenClient, geminiImage),
+			geminiImage,
+			image.NewOpenAIGenerator(image.OpenAIOptions{Model: "gpt-image-1"}, geminiImage),
+			image.NewOpenAIGenerator(image.OpenAIOptions{Model: "dall-e-3"}, geminiImage),
+		),
+		VideoProviders: videoProviderSet(qwenClient.VideoModel(),
+			video.NewQwenGenerator(qwenClient, video.NewGeminiGenerator(geminiClient)),
+			video.NewGeminiGenerator(geminiClient),
+		),
+	}
+
+	rr := httptest.NewRecorder()
+	app.ListProviders(rr, httptest.NewRequest(http.MethodGet, "/v1/providers", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	var resp struct {
+		Items []providerInfoDTO `json:"items"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	byKey := map[string]providerInfoDTO{}
+	for _, item := range resp.Items {
+		byKey[item.Media+"/"+item.Key] = item
+	}
+	if len(byKey) != len(resp.Items) || len(resp.Items) != 8 {
+		t.Fatalf("got %d items (%d unique), want 8: %+v", len(resp.Items), len(byKey), resp.Items)
+	}
+
+	want := map[string]providerInfoDTO{
+		"image/qwen-image-plus": {Key: "qwen-image-plus", Media: "image", SupportsEditing: true, AspectRatios: jsoncfg.AllowedAspectRatios(), CredentialsConfigured: true},
+		"video/wan":             {Key: "wan", Media: "video", AspectRatios: []string{}, CredentialsConfigured: true},
+		"video/gemini":          {Key: "gemini", Media: "video", SupportsEditing: true, AspectRatios: []string{}},
+	}
+	for key, expected := range want {
+		got, ok := byKey[key]
+		if !ok {
+			t.Fatalf("missing provider %s", key)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("%s = %+v, want %+v", key, got, expected)
+		}
+	}
+	for _, key := range []string{"image/gemini", "image/dall-e-3", "image/qwen"} {
+		if _, ok := byKey[key]; ok {
+			t.Fatalf("%s advertised but rejected by image generation", key)
+		}
+	}
+	if first, second := resp.Items[0], resp.Items[1]; first.Media != "image" || second.Key != "gemini" {
+		t.Fatalf("items not sorted by media then key: %+v, %+v", first, second)
+	}
+}
+
+func TestListProvidersImageKeysPassGenerateCheck(t *testing.T) {
+	qwenClient, err := qwen.NewClient(qwen.Options{APIKey: "dashscope-key"})
+	if err != nil {
+		t.Fatalf("qwen client: %v", err)
+	}
+	qwenImage := image.NewQwenGenerator(qwenClient, nil)
+	app := &App{
+		Config:         &infra.Config{},
+		Logger:         zerolog.Nop(),
+		ImageProviders: imageProviderSet(qwenClient.Model(), qwenImage, qwenImage, qwenImage, qwenImage),
+		ImageEditor:    &stubEditor{},
+	}
+	rr := httptest.NewRecorder()
+	app.ListProviders(rr, httptest.NewRequest(http.MethodGet, "/v1/providers", nil))
+	var resp struct {
+		Items []providerInfoDTO `json:"items"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	for _, item := range resp.Items {
+		if item.Media != providerMediaImage {
+			continue
+		}
+		// An unsupported scheme fails right after the provider check, so a
+		// 422 proves the provider itself was accepted.
+		body, _ := json.Marshal(map[string]any{
+			"provider": item.Key,
+			"prompt":   map[string]any{"source_asset": map[string]any{"url": "ftp://example.com/a.png"}},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/v1/images/generate", bytes.NewReader(body))
+		req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+		gen := httptest.NewRecorder()
+		app.ImagesGenerate(gen, req)
+		if gen.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("provider %s: status = %d, want 422 from source validation; body=%s", item.Key, gen.Code, gen.Body.String())
+		}
+	}
+}
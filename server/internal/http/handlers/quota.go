@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type quotaDTO struct {
+	QuotaDaily       int        `json:"quota_daily"`
+	QuotaUsedToday   int        `json:"quota_used_today"`
+	Remaining        int        `json:"remaining"`
+	QuotaRefreshedAt *time.Time `json:"quota_refreshed_at"`
+	ResetsAt         time.Time  `json:"resets_at"`
+}
+
+// Quota reports the caller's daily quota without having to enqueue a job.
+func (a *App) Quota(w http.ResponseWriter, r *http.Request) {
+	userID := a.currentUserID(r)
+	if userID == "" {
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
+		return
+	}
+	resp, err := a.loadQuota(r.Context(), userID)
+	if err != nil {
+		a.error(w, http.StatusNotFound, ErrNotFound, "user not found")
+		return
+	}
+	a.json(w, http.StatusOK, resp)
+}
+
+// loadQuota reads the user's quota counters, applying the daily rollover.
+func (a *App) loadQuota(ctx context.Context, userID string) (quotaDTO, error) {
+	row := a.SQL.QueryRow(ctx, sqlinline.QSelectUserByID, userID)
+	var id, googleSub, email, locale, plan string
+	var propsBytes []byte
+	var createdAt, updatedAt time.Time
+	if err := row.Scan(&id, &googleSub, &email, &locale, &plan, &propsBytes, &createdAt, &updatedAt); err != nil {
+		return quotaDTO{}, err
+	}
+	props, quotaDaily, quotaUsed := extractQuota(propsBytes)
+	resp := quotaDTO{
+		QuotaDaily:     quotaDaily,
+		QuotaUsedToday: quotaUsed,
+		Remaining:      max(quotaDaily-quotaUsed, 0),
+		ResetsAt:       nextQuotaReset(time.Now()),
+	}
+	if raw, ok := props["quota_refreshed_at"].(string); ok {
+		if refreshed, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			resp.QuotaRefreshedAt = &refreshed
+		}
+	}
+	return resp, nil
+}
+
+// nextQuotaReset returns the next UTC midnight after now.
+func nextQuotaReset(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// isQuotaExceeded reports whether err is the exception raised by
+// fn_consume_quota when a job would exceed the daily quota.
+func isQuotaExceeded(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Message == "quota exceeded"
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/domain/jsoncfg"
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/sqlinline"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+type userRowSQL struct {
+	userID     string
+	properties string
+}
+
+func (s *userRowSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *userRowSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if query != sqlinline.QSelectUserByID || args[0] != s.userID {
+		return NewSimpleRow(nil)
+	}
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = s.userID
+		*dest[1].(*string) = "google-sub"
+		*dest[2].(*string) = "owner@example.com"
+		*dest[3].(*string) = "id"
+		*dest[4].(*string) = "free"
+		*dest[5].(*[]byte) = []byte(s.properties)
+		*dest[6].(*time.Time) = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		*dest[7].(*time.Time) = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		return nil
+	})
+}
+
+func (s *userRowSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func fetchQuota(t *testing.T, properties string) quotaDTO {
+	t.Helper()
+	app := &App{SQL: &userRowSQL{userID: "user-123", properties: properties}}
+	req := httptest.NewRequest("GET", "/v1/quota", nil)
+	req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+	rr := httptest.NewRecorder()
+	app.Quota(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp quotaDTO
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return resp
+}
+
+func TestQuotaReportsUsageAndReset(t *testing.T) {
+	before := time.Now().UTC()
+	wantRefreshed := before.Truncate(time.Microsecond)
+	resp := fetchQuota(t, `{"quota_daily":5,"quota_used_today":3,"quota_refreshed_at":"`+wantRefreshed.Format(time.RFC3339Nano)+`"}`)
+
+	if resp.QuotaDaily != 5 || resp.QuotaUsedToday != 3 || resp.Remaining != 2 {
+		t.Fatalf("unexpected quota fields: %+v", resp)
+	}
+	if resp.QuotaRefreshedAt == nil || !resp.QuotaRefreshedAt.Equal(wantRefreshed) {
+		t.Fatalf("quota_refreshed_at = %v, want %v", resp.QuotaRefreshedAt, wantRefreshed)
+	}
+	if !resp.ResetsAt.After(before) || resp.ResetsAt.Sub(before) > 24*time.Hour {
+		t.Fatalf("resets_at = %v, want within 24h after %v", resp.ResetsAt, before)
+	}
+	if h, m, s := resp.ResetsAt.UTC().Clock(); h != 0 || m != 0 || s != 0 {
+		t.Fatalf("resets_at = %v, want UTC midnight", resp.ResetsAt)
+	}
+}
+
+func TestQuotaResetsStaleUsage(t *testing.T) {
+	stale := time.Now().UTC().AddDate(0, 0, -1).Format(time.RFC3339Nano)
+	resp := fetchQuota(t, `{"quota_daily":5,"quota_used_today":5,"quota_refreshed_at":"`+stale+`"}`)
+	if resp.QuotaUsedToday != 0 || resp.Remaining != 5 {
+		t.Fatalf("stale usage should reset, got %+v", resp)
+	}
+}
+
+func TestRolloverQuotaUsed(t *testing.T) {
+	now := time.Date(2024, 5, 2, 0, 30, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		refreshed any
+		want      int
+	}{
+		{name: "same day", refreshed: "2024-05-02T00:10:00Z", want: 3},
+		{name: "previous day", refreshed: "2024-05-01T23:59:59.999999+00:00", want: 0},
+		{name: "same instant in another zone", refreshed: "2024-05-02T07:10:00+07:00", want: 3},
+		{name: "previous UTC day in another zone", refreshed: "2024-05-02T06:59:00+07:00", want: 0},
+		{name: "never refreshed", refreshed: nil, want: 0},
+		{name: "unparseable", refreshed: "yesterday", want: 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			props := map[string]any{}
+			if tc.refreshed != nil {
+				props["quota_refreshed_at"] = tc.refreshed
+			}
+			if got := rolloverQuotaUsed(props, 3, now); got != tc.want {
+				t.Fatalf("rolloverQuotaUsed() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNextQuotaReset(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	cases := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), want: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{now: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), want: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{now: time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{now: time.Date(2024, 5, 2, 3, 0, 0, 0, jakarta), want: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		if got := nextQuotaReset(tc.now); !got.Equal(tc.want) {
+			t.Fatalf("nextQuotaReset(%v) = %v, want %v", tc.now, got, tc.want)
+		}
+	}
+}
+
+func TestQuotaRequiresUser(t *testing.T) {
+	app := &App{SQL: &userRowSQL{}}
+	rr := httptest.NewRecorder()
+	app.Quota(rr, httptest.NewRequest("GET", "/v1/quota", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rr.Code)
+	}
+}
+
+type countingUserSQL struct {
+	userRowSQL
+	execs int
+}
+
+func (s *countingUserSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	s.execs++
+	return pgconn.CommandTag{}, nil
+}
+
+func TestImagesEstimateLeavesQuotaUnchanged(t *testing.T) {
+	sqlStub := &countingUserSQL{userRowSQL: userRowSQL{
+		userID:     "user-123",
+		properties: `{"quota_daily":5,"quota_used_today":2,"quota_refreshed_at":"` + time.Now().UTC().Format(time.RFC3339Nano) + `"}`,
+	}}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: sqlStub}
+	withUser := func(req *http.Request) *http.Request {
+		ctx := middleware.ContextWithUserID(req.Context(), "user-123")
+		ctx = middleware.ContextWithClaims(ctx, &middleware.TokenClaims{Sub: "user-123", Plan: "pro"})
+		return req.WithContext(ctx)
+	}
+	quota := func() quotaDTO {
+		rr := httptest.NewRecorder()
+		app.Quota(rr, withUser(httptest.NewRequest("GET", "/v1/quota", nil)))
+		var resp quotaDTO
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode quota: %v", err)
+		}
+		return resp
+	}
+
+	before := quota()
+	rr := httptest.NewRecorder()
+	app.ImagesEstimate(rr, withUser(httptest.NewRequest("POST", "/v1/images/estimate", strings.NewReader(`{"quantity":12}`))))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var est imageEstimateResponse
+	if err := json.NewDecoder(rr.Body).Decode(&est); err != nil {
+		t.Fatalf("decode estimate: %v", err)
+	}
+	if want := jsoncfg.MaxQuantityForPlan("pro"); est.Quantity != want || est.Cost != want {
+		t.Fatalf("quantity = %d cost = %d, want clamped to %d", est.Quantity, est.Cost, want)
+	}
+	if est.Remaining != 3 || est.RemainingAfter != 0 || est.Allowed {
+		t.Fatalf("unexpected estimate: %+v", est)
+	}
+
+	after := quota()
+	if after.QuotaUsedToday != before.QuotaUsedToday || after.Remaining != before.Remaining {
+		t.Fatalf("quota changed: before %+v, after %+v", before, after)
+	}
+	if sqlStub.execs != 0 {
+		t.Fatalf("estimate issued %d writes", sqlStub.execs)
+	}
+}
+
+func TestImagesEstimateClampsQuantityToPlan(t *testing.T) {
+	sqlStub := &countingUserSQL{userRowSQL: userRowSQL{
+		userID:     "user-123",
+		properties: `{"quota_daily":10,"quota_used_today":0,"quota_refreshed_at":"` + time.Now().UTC().Format(time.RFC3339Nano) + `"}`,
+	}}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), SQL: sqlStub}
+	for plan, want := range map[string]int{"free": 2, "pro": 8, "supporter": 8} {
+		req := httptest.NewRequest("POST", "/v1/images/estimate", strings.NewReader(`{"quantity":12}`))
+		ctx := middleware.ContextWithUserID(req.Context(), "user-123")
+		ctx = middleware.ContextWithClaims(ctx, &middleware.TokenClaims{Sub: "user-123", Plan: plan})
+		rr := httptest.NewRecorder()
+		app.ImagesEstimate(rr, req.WithContext(ctx))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200; body=%s", plan, rr.Code, rr.Body.String())
+		}
+		var est imageEstimateResponse
+		if err := json.NewDecoder(rr.Body).Decode(&est); err != nil {
+			t.Fatalf("%s: decode estimate: %v", plan, err)
+		}
+		if est.Quantity != want {
+			t.Fatalf("%s: quantity = %d, want %d", plan, est.Quantity, want)
+		}
+	}
+}
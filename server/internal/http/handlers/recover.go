@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"server/internal/middleware"
+)
+
+// Recover turns a handler panic into the standard JSON 500 response and logs
+// the stack with the request id, so one bad request neither drops the
+// connection nor takes the server down.
+func (a *App) Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rec)
+			}
+			a.Logger.Error().
+				Str("request_id", middleware.RequestIDFromContext(r.Context())).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Interface("panic", rec).
+				Bytes("stack", debug.Stack()).
+				Msg("handler panic recovered")
+			a.error(w, http.StatusInternalServerError, ErrInternal, "internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+func TestRecoverReturnsJSON500AndKeepsServing(t *testing.T) {
+	var logs bytes.Buffer
+	app := &App{Logger: zerolog.New(&logs)}
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(app.Recover)
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["boom"]++
+	})
+	r.Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/panic", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("panic request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", resp.StatusCode)
+	}
+	var payload struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode error body: %v", err)
+	}
+	if payload.Error.Code != string(ErrInternal) {
+		t.Fatalf("error code = %q, want internal", payload.Error.Code)
+	}
+	if !strings.Contains(logs.String(), `"request_id":"req-123"`) || !strings.Contains(logs.String(), `"stack"`) {
+		t.Fatalf("panic log missing request id or stack: %s", logs.String())
+	}
+
+	resp, err = srv.Client().Get(srv.URL + "/ok")
+	if err != nil {
+		t.Fatalf("follow-up request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("follow-up status = %d, want 200", resp.StatusCode)
+	}
+}
@@ -12,7 +12,7 @@ type EnqueueReq struct {
 	Model       string         `json:"model"`        // veo-2 | veo-3 | ...
 	SourceAsset *string        `json:"source_asset"` // UUID
 	PromptJSON  map[string]any `json:"prompt_json"`  // disimpan ke JSONB
-	AspectRatio string         `json:"aspect_ratio"` // 1:1, 4:3, 3:4, 16:9, 9:16
+	AspectRatio string         `json:"aspect_ratio"` // lihat jsoncfg.AllowedAspectRatios
 	Quantity    int            `json:"quantity"`     // server akan cap max 2 utk free
 	TaskType    string         `json:"task_type"`    // IMAGE_GEN | VIDEO_GEN | UPSCALE
 }
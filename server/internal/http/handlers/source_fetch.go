@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"server/internal/netguard"
+)
+
+const (
+	sourceFetchTimeout = 20 * time.Second
+	maxSourceRedirects = 10
+)
+
+// newSourceFetcher returns the client used to download source assets. Every
+// redirect hop is checked so a public URL cannot bounce the server onto a
+// private or metadata address, and connections are dialed to the address
+// validated at lookup time so a second DNS answer cannot swap in a private
+// one. Allowlisted hosts get the same checks; only trusted hosts are exempt.
+func newSourceFetcher(trusted map[string]struct{}, resolver netguard.Resolver) *http.Client {
+	return netguard.NewClient(sourceFetchTimeout, maxSourceRedirects, trusted, resolver)
+}
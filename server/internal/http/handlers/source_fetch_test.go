@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"server/internal/infra"
+	"server/internal/netguard"
+
+	"github.com/rs/zerolog"
+)
+
+// redirectTransport answers requests by host without touching the network.
+type redirectTransport struct {
+	routes    map[string]string
+	requested []string
+}
+
+func (rt *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requested = append(rt.requested, req.URL.String())
+	resp := &http.Response{Header: make(http.Header), Body: io.NopCloser(bytes.NewReader(nil)), Request: req}
+	if target, ok := rt.routes[req.URL.Host]; ok {
+		resp.StatusCode = http.StatusFound
+		resp.Header.Set("Location", target)
+		return resp, nil
+	}
+	resp.StatusCode = http.StatusOK
+	resp.Header.Set("Content-Type", "image/png")
+	resp.Body = io.NopCloser(bytes.NewReader(tinyTransparentPNG))
+	return resp, nil
+}
+
+func TestFetchAllowlistedSourceBlocksPrivateRedirect(t *testing.T) {
+	transport := &redirectTransport{routes: map[string]string{
+		"cdn.example.com": "http://169.254.169.254/latest/meta-data/",
+	}}
+	client := newSourceFetcher(nil, nil)
+	client.Transport = transport
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), sourceFetcher: client}
+
+	_, _, err := app.fetchAllowlistedSource(context.Background(), "https://cdn.example.com/photo.png", "free")
+	if err == nil || !strings.Contains(err.Error(), "169.254.169.254 blocked") {
+		t.Fatalf("err = %v, want redirect to metadata address blocked", err)
+	}
+	if len(transport.requested) != 1 {
+		t.Fatalf("requests = %v, want only the initial hop", transport.requested)
+	}
+}
+
+func TestFetchAllowlistedSourceFollowsPublicRedirect(t *testing.T) {
+	transport := &redirectTransport{routes: map[string]string{
+		"cdn.example.com": "https://images.example.net/photo.png",
+	}}
+	client := newSourceFetcher(nil, nil)
+	client.Transport = transport
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), sourceFetcher: client}
+
+	data, mimeType, err := app.fetchAllowlistedSource(context.Background(), "https://cdn.example.com/photo.png", "free")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if !bytes.Equal(data, tinyTransparentPNG) || mimeType != "image/png" {
+		t.Fatalf("data len = %d mime = %q, want redirected png", len(data), mimeType)
+	}
+	if len(transport.requested) != 2 || transport.requested[1] != "https://images.example.net/photo.png" {
+		t.Fatalf("requests = %v, want redirect followed", transport.requested)
+	}
+}
+
+type stubResolver map[string][]string
+
+func (s stubResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	var out []net.IPAddr
+	for _, addr := range s[host] {
+		out = append(out, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+	return out, nil
+}
+
+func TestPrepareSourceImageRejectsAllowlistedPrivateResolution(t *testing.T) {
+	// Wired as in NewApp: the host is allowlisted for server-side fetches but
+	// not trusted, so its resolved addresses must still be public.
+	cfg := &infra.Config{ImageSourceAllowlist: []string{"cdn.example.com", "localhost"}, TrustedHosts: []string{"localhost"}}
+	resolver := stubResolver{"cdn.example.com": {"10.0.0.1"}}
+	app := &App{
+		Config:              cfg,
+		Logger:              zerolog.Nop(),
+		sourceHostAllowlist: netguard.Allowlist(cfg.ImageSourceAllowlist),
+		sourceFetcher:       newSourceFetcher(netguard.Allowlist(cfg.TrustedHosts), resolver),
+	}
+
+	rawURL := "http://cdn.example.com/photo.png"
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+	_, allowlisted := app.sourceHostAllowlist[parsed.Hostname()]
+	if !allowlisted {
+		t.Fatal("expected cdn.example.com to be allowlisted")
+	}
+	_, err = app.prepareSourceImage(context.Background(), rawURL, parsed, "", allowlisted, "free")
+	if err == nil || !strings.Contains(err.Error(), "non-public address 10.0.0.1") {
+		t.Fatalf("err = %v, want private resolution rejected", err)
+	}
+}
+
+func TestFetchAllowlistedSourceDialsPinnedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(tinyTransparentPNG)
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("split server addr: %v", err)
+	}
+	// assets.test only exists in the stub resolver, so the request can only
+	// succeed by dialing the pinned loopback address. It is trusted because
+	// loopback is never public.
+	resolver := stubResolver{"assets.test": {"127.0.0.1"}}
+	trusted := map[string]struct{}{"assets.test": {}}
+	app := &App{Config: &infra.Config{}, Logger: zerolog.Nop(), sourceFetcher: newSourceFetcher(trusted, resolver)}
+
+	data, _, err := app.fetchAllowlistedSource(context.Background(), "http://assets.test:"+port+"/photo.png", "free")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if !bytes.Equal(data, tinyTransparentPNG) {
+		t.Fatalf("data len = %d, want served png", len(data))
+	}
+}
@@ -10,7 +10,7 @@ func (a *App) StatsSummary(w http.ResponseWriter, r *http.Request) {
 	row := a.SQL.QueryRow(r.Context(), sqlinline.QStatsSummary)
 	var totalUsers, imageGenerated, videoGenerated, requestSuccess, requestFail, image24, video24 int64
 	if err := row.Scan(&totalUsers, &imageGenerated, &videoGenerated, &requestSuccess, &requestFail, &image24, &video24); err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to load stats")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load stats")
 		return
 	}
 	a.json(w, http.StatusOK, map[string]any{
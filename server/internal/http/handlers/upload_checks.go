@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// scriptScanBytes bounds how much of an upload is scanned for markup.
+const scriptScanBytes = 1024
+
+// scriptSignatures are markers of HTML, SVG or server-side script content.
+// None of them can legitimately appear in the header of a raster image, so
+// finding one means the file is a polyglot built to be served as something
+// else. Markers stay long enough that compressed pixel data does not produce
+// them by chance; a two-byte tag like "<%" turns up in ordinary images.
+var scriptSignatures = [][]byte{
+	[]byte("<script"),
+	[]byte("<html"),
+	[]byte("<!doctype"),
+	[]byte("<svg"),
+	[]byte("<iframe"),
+	[]byte("<body"),
+	[]byte("<?php"),
+	[]byte("javascript:"),
+}
+
+// containsScriptSignature reports whether the first scriptScanBytes of data
+// contain a script-like marker, ignoring case.
+func containsScriptSignature(data []byte) bool {
+	head := data
+	if len(head) > scriptScanBytes {
+		head = head[:scriptScanBytes]
+	}
+	head = bytes.ToLower(head)
+	for _, sig := range scriptSignatures {
+		if bytes.Contains(head, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadFormatsAgree reports whether the filename extension, the sniffed MIME
+// type and the format the decoder recognised all describe the same image
+// type. Uploads without an extension are judged on the last two alone.
+func uploadFormatsAgree(filename, sniffed, decoded string) bool {
+	sniffed = canonicalImageMime(sniffed)
+	decoded = canonicalImageMime(decoded)
+	if sniffed == "" || sniffed != decoded {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(strings.TrimSpace(filename)))
+	if ext == "" {
+		return true
+	}
+	switch ext {
+	case ".png":
+		return decoded == "image/png"
+	case ".jpg", ".jpeg", ".jpe", ".jfif":
+		return decoded == "image/jpeg"
+	case ".webp":
+		return decoded == "image/webp"
+	default:
+		return false
+	}
+}
+
+func canonicalImageMime(mime string) string {
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	if idx := strings.Index(mime, ";"); idx >= 0 {
+		mime = strings.TrimSpace(mime[:idx])
+	}
+	if mime == "image/jpg" {
+		return "image/jpeg"
+	}
+	return mime
+}
@@ -3,19 +3,29 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"server/internal/domain/jsoncfg"
+	"server/internal/metrics"
+	"server/internal/middleware"
+	"server/internal/providers/video"
 	"server/internal/sqlinline"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type videoGenerateRequest struct {
-	Provider string `json:"provider"`
-	Prompt   string `json:"prompt"`
-	Locale   string `json:"locale"`
+	Provider    string                    `json:"provider"`
+	Prompt      string                    `json:"prompt"`
+	Locale      string                    `json:"locale"`
+	CallbackURL string                    `json:"callback_url"`
+	SourceAsset jsoncfg.SourceAssetConfig `json:"source_asset"`
 }
 
 type jobResponse struct {
@@ -27,21 +37,59 @@ type jobResponse struct {
 func (a *App) VideosGenerate(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	var req videoGenerateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		a.error(w, http.StatusBadRequest, "bad_request", "invalid payload")
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	key, err := idempotencyKey(r)
+	if err != nil {
+		a.error(w, http.StatusBadRequest, ErrBadRequest, err.Error())
 		return
 	}
 	if req.Provider == "" {
 		req.Provider = "veo2"
 	}
-	if _, ok := a.VideoProviders[req.Provider]; !ok {
-		a.error(w, http.StatusBadRequest, "bad_request", "unsupported provider")
+	generator, ok := a.VideoProviders[req.Provider]
+	if !ok {
+		a.error(w, http.StatusBadRequest, ErrUnsupportedProvider, "unsupported provider")
+		return
+	}
+	if !a.requireProviderForPlan(w, r, req.Provider) {
+		return
+	}
+	callbackURL, err := a.parseCallbackURL(req.CallbackURL)
+	if err != nil {
+		a.error(w, http.StatusUnprocessableEntity, ErrInvalidCallback, err.Error())
+		return
+	}
+	source, ok := a.videoSourceAsset(w, r, userID, generator, req.SourceAsset)
+	if !ok {
+		return
+	}
+	if !a.allowedByModeration(w, r, req.Prompt) {
 		return
 	}
+	if reserved, jobID, remaining, err := a.reserveIdempotencyKey(r.Context(), userID, key); err != nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to check idempotency key")
+		return
+	} else if !reserved {
+		if jobID == "" {
+			a.error(w, http.StatusConflict, ErrConflict, "a request with this idempotency key is still in progress")
+			return
+		}
+		a.json(w, http.StatusAccepted, jobResponse{JobID: jobID, Status: "QUEUED", RemainingQuota: remaining})
+		return
+	}
+	properties := map[string]any{}
+	if callbackURL != "" {
+		properties["callback_url"] = callbackURL
+	}
+	if requestID := middleware.RequestIDFromContext(r.Context()); requestID != "" {
+		properties["request_id"] = requestID
+	}
 	promptPayload := map[string]any{
 		"version": "2024-06-01",
 		"prompt":  req.Prompt,
@@ -49,34 +97,101 @@ func (a *App) VideosGenerate(w http.ResponseWriter, r *http.Request) {
 	if req.Locale != "" {
 		promptPayload["locale"] = req.Locale
 	}
+	if !source.IsZero() {
+		promptPayload["source_asset"] = source
+	}
 	promptJSON := jsoncfg.MustMarshal(promptPayload)
-	row := a.SQL.QueryRow(r.Context(), sqlinline.QEnqueueVideoJob, userID, promptJSON, req.Provider)
+	row := a.SQL.QueryRow(r.Context(), sqlinline.QEnqueueVideoJob, userID, promptJSON, req.Provider, jsoncfg.MustMarshal(properties))
 	var jobID string
 	var remaining int
 	if err := row.Scan(&jobID, &remaining); err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to queue video job")
+		a.releaseIdempotencyKey(r.Context(), userID, key)
+		if isQuotaExceeded(err) {
+			a.error(w, http.StatusTooManyRequests, ErrQuotaExceeded, "daily quota exceeded")
+			return
+		}
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to queue video job")
 		return
 	}
+	metrics.JobsEnqueued.Inc("VIDEO_GEN", req.Provider)
+	a.recordIdempotentJob(r.Context(), userID, key, jobID)
 	a.json(w, http.StatusAccepted, jobResponse{JobID: jobID, Status: "QUEUED", RemainingQuota: remaining})
 }
 
+// videoSourceAsset validates the optional image-to-video source. Uploaded
+// assets must belong to userID and remote ones must be public http(s) URLs.
+// Only the asset id or URL is kept so the worker resolves storage itself. On
+// failure the error response has been written and ok is false.
+func (a *App) videoSourceAsset(w http.ResponseWriter, r *http.Request, userID string, generator video.Generator, src jsoncfg.SourceAssetConfig) (jsoncfg.SourceAssetConfig, bool) {
+	assetID := strings.TrimSpace(src.AssetID)
+	sourceURL := strings.TrimSpace(src.URL)
+	if assetID == "" && sourceURL == "" {
+		if !src.IsZero() {
+			a.error(w, http.StatusUnprocessableEntity, ErrInvalidSource, "source_asset needs an asset_id or url")
+			return jsoncfg.SourceAssetConfig{}, false
+		}
+		return jsoncfg.SourceAssetConfig{}, true
+	}
+	if editor, ok := generator.(sourceImageEditor); !ok || !editor.SupportsSourceImage() {
+		a.error(w, http.StatusBadRequest, ErrUnsupportedProvider, "provider does not support source images")
+		return jsoncfg.SourceAssetConfig{}, false
+	}
+	out := jsoncfg.SourceAssetConfig{Mime: strings.TrimSpace(src.Mime), Filename: strings.TrimSpace(src.Filename)}
+	if assetID != "" {
+		if _, err := uuid.Parse(assetID); err != nil {
+			a.error(w, http.StatusNotFound, ErrNotFound, "source asset not found")
+			return jsoncfg.SourceAssetConfig{}, false
+		}
+		var id, ownerID, storageKey, mime string
+		var size int64
+		var width, height int
+		var aspect string
+		var props []byte
+		if err := a.SQL.QueryRow(r.Context(), sqlinline.QSelectAssetByID, assetID).Scan(&id, &ownerID, &storageKey, &mime, &size, &width, &height, &aspect, &props); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				a.error(w, http.StatusNotFound, ErrNotFound, "source asset not found")
+				return jsoncfg.SourceAssetConfig{}, false
+			}
+			a.error(w, http.StatusInternalServerError, ErrInternal, "failed to load source asset")
+			return jsoncfg.SourceAssetConfig{}, false
+		}
+		if ownerID != userID {
+			a.error(w, http.StatusNotFound, ErrNotFound, "source asset not found")
+			return jsoncfg.SourceAssetConfig{}, false
+		}
+		out.AssetID = assetID
+		return out, true
+	}
+	parsed, err := url.Parse(sourceURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		a.error(w, http.StatusUnprocessableEntity, ErrInvalidSource, "source_asset.url must be a public http(s) URL")
+		return jsoncfg.SourceAssetConfig{}, false
+	}
+	if err := ensurePublicHTTPURL(parsed, a.trustedHosts); err != nil {
+		a.error(w, http.StatusUnprocessableEntity, ErrInvalidSource, err.Error())
+		return jsoncfg.SourceAssetConfig{}, false
+	}
+	out.URL = sourceURL
+	return out, true
+}
+
 func (a *App) VideoStatus(w http.ResponseWriter, r *http.Request) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	jobID := chi.URLParam(r, "job_id")
 	if jobID == "" {
-		a.error(w, http.StatusBadRequest, "bad_request", "job_id required")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "job_id required")
 		return
 	}
 	job, err := a.loadJobForUser(r.Context(), jobID, userID)
 	if err != nil {
-		a.error(w, http.StatusNotFound, "not_found", "job not found")
+		a.error(w, http.StatusNotFound, ErrNotFound, "job not found")
 		return
 	}
-	a.json(w, http.StatusOK, map[string]any{
+	resp := map[string]any{
 		"id":           job.ID,
 		"user_id":      job.UserID,
 		"task_type":    job.TaskType,
@@ -87,27 +202,39 @@ func (a *App) VideoStatus(w http.ResponseWriter, r *http.Request) {
 		"created_at":   job.CreatedAt,
 		"updated_at":   job.UpdatedAt,
 		"properties":   json.RawMessage(job.Properties),
-	})
+	}
+	if msg := job.ErrorMessage(); msg != "" {
+		resp["error"] = msg
+	}
+	a.json(w, http.StatusOK, resp)
 }
 
 func (a *App) VideoAssets(w http.ResponseWriter, r *http.Request) {
+	a.jobAssets(w, r, "VIDEO_GEN")
+}
+
+// jobAssets lists the assets of the caller's job named by the job_id URL
+// parameter. Jobs of another task type are reported as not found, and a row
+// that fails to scan fails the request rather than being dropped.
+func (a *App) jobAssets(w http.ResponseWriter, r *http.Request, taskType string) {
 	userID := a.currentUserID(r)
 	if userID == "" {
-		a.error(w, http.StatusUnauthorized, "unauthorized", "missing user context")
+		a.error(w, http.StatusUnauthorized, ErrUnauthorized, "missing user context")
 		return
 	}
 	jobID := chi.URLParam(r, "job_id")
 	if jobID == "" {
-		a.error(w, http.StatusBadRequest, "bad_request", "job_id required")
+		a.error(w, http.StatusBadRequest, ErrBadRequest, "job_id required")
 		return
 	}
-	if _, err := a.loadJobForUser(r.Context(), jobID, userID); err != nil {
-		a.error(w, http.StatusNotFound, "not_found", "job not found")
+	job, err := a.loadJobForUser(r.Context(), jobID, userID)
+	if err != nil || job.TaskType != taskType {
+		a.error(w, http.StatusNotFound, ErrNotFound, "job not found")
 		return
 	}
 	rows, err := a.SQL.Query(r.Context(), sqlinline.QSelectJobAssets, jobID, userID)
 	if err != nil {
-		a.error(w, http.StatusInternalServerError, "internal", "failed to fetch video assets")
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to fetch job assets")
 		return
 	}
 	defer rows.Close()
@@ -120,20 +247,27 @@ func (a *App) VideoAssets(w http.ResponseWriter, r *http.Request) {
 		var props []byte
 		var createdAt time.Time
 		if err := rows.Scan(&id, &storageKey, &mime, &bytes, &width, &height, &aspect, &props, &createdAt); err != nil {
-			continue
+			a.error(w, http.StatusInternalServerError, ErrInternal, "failed to read job assets")
+			return
 		}
 		items = append(items, map[string]any{
-			"id":           id,
-			"storage_key":  storageKey,
-			"mime":         mime,
-			"bytes":        bytes,
-			"width":        width,
-			"height":       height,
-			"aspect_ratio": aspect,
-			"properties":   json.RawMessage(props),
-			"created_at":   createdAt,
+			"id":            id,
+			"storage_key":   storageKey,
+			"url":           a.assetURL(storageKey),
+			"mime":          mime,
+			"bytes":         bytes,
+			"width":         width,
+			"height":        height,
+			"aspect_ratio":  aspect,
+			"properties":    json.RawMessage(props),
+			"thumbnail_url": a.thumbnailURL(props),
+			"created_at":    createdAt,
 		})
 	}
+	if err := rows.Err(); err != nil {
+		a.error(w, http.StatusInternalServerError, ErrInternal, "failed to read job assets")
+		return
+	}
 	a.json(w, http.StatusOK, map[string]any{"items": items})
 }
 
@@ -150,6 +284,20 @@ type jobRecord struct {
 	Properties []byte
 }
 
+// ErrorMessage returns the failure reason recorded by the worker, if any.
+func (j *jobRecord) ErrorMessage() string {
+	if j == nil || len(j.Properties) == 0 {
+		return ""
+	}
+	var props struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(j.Properties, &props); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(props.Error)
+}
+
 func (a *App) loadJobForUser(ctx context.Context, jobID, userID string) (*jobRecord, error) {
 	row := a.SQL.QueryRow(ctx, sqlinline.QSelectJobStatus, jobID, userID)
 	var job jobRecord
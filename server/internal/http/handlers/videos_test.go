@@ -0,0 +1,529 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/infra"
+	"server/internal/middleware"
+	"server/internal/providers/video"
+	"server/internal/sqlinline"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type jobStatusSQL struct {
+	job jobRecord
+}
+
+func (s *jobStatusSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *jobStatusSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if query != sqlinline.QSelectJobStatus || args[0] != s.job.ID || args[1] != s.job.UserID {
+		return NewSimpleRow(nil)
+	}
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = s.job.ID
+		*dest[1].(*string) = s.job.UserID
+		*dest[2].(*string) = s.job.TaskType
+		*dest[3].(*string) = s.job.Status
+		*dest[4].(*string) = s.job.Provider
+		*dest[5].(*int) = s.job.Quantity
+		*dest[6].(*string) = s.job.Aspect
+		*dest[7].(*time.Time) = s.job.CreatedAt
+		*dest[8].(*time.Time) = s.job.UpdatedAt
+		*dest[9].(*[]byte) = s.job.Properties
+		return nil
+	})
+}
+
+func (s *jobStatusSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func failedJob(taskType string) jobRecord {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	return jobRecord{
+		ID:         "4b1f0c52-3c3e-4f55-9d43-1d7c3e0f7a10",
+		UserID:     "user-123",
+		TaskType:   taskType,
+		Status:     "FAILED",
+		Provider:   "qwen-image-plus",
+		Quantity:   1,
+		Aspect:     "1:1",
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Properties: []byte(`{"error":"image generation: provider unavailable","status_history":[]}`),
+	}
+}
+
+func requestWithParam(method, target, key, value, userID string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	if userID != "" {
+		ctx = middleware.ContextWithUserID(ctx, userID)
+	}
+	return req.WithContext(ctx)
+}
+
+func TestVideoStatusSurfacesJobError(t *testing.T) {
+	job := failedJob("VIDEO_GEN")
+	app := &App{SQL: &jobStatusSQL{job: job}}
+
+	rr := httptest.NewRecorder()
+	app.VideoStatus(rr, requestWithParam("GET", "/v1/videos/"+job.ID+"/status", "job_id", job.ID, job.UserID))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var payload map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload["error"] != "image generation: provider unavailable" {
+		t.Fatalf("unexpected error field: %#v", payload["error"])
+	}
+}
+
+func TestVideoStatusOmitsErrorWhenAbsent(t *testing.T) {
+	job := failedJob("VIDEO_GEN")
+	job.Status = "SUCCEEDED"
+	job.Properties = []byte(`{}`)
+	app := &App{SQL: &jobStatusSQL{job: job}}
+
+	rr := httptest.NewRecorder()
+	app.VideoStatus(rr, requestWithParam("GET", "/v1/videos/"+job.ID+"/status", "job_id", job.ID, job.UserID))
+
+	var payload map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if _, ok := payload["error"]; ok {
+		t.Fatalf("expected no error field, got %#v", payload["error"])
+	}
+}
+
+func TestImageJobFallsBackToQueuedJobError(t *testing.T) {
+	job := failedJob("IMAGE_GEN")
+	stub := &jobStatusSQL{job: job}
+	app := &App{DB: stub, SQL: stub}
+
+	rr := httptest.NewRecorder()
+	app.ImageJob(rr, requestWithParam("GET", "/v1/images/jobs/"+job.ID, "id", job.ID, job.UserID))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rr.Code, rr.Body.String())
+	}
+	var resp imageJobResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Status != "FAILED" {
+		t.Fatalf("status = %q, want FAILED", resp.Status)
+	}
+	if resp.Error == nil || *resp.Error != "image generation: provider unavailable" {
+		t.Fatalf("unexpected error field: %v", resp.Error)
+	}
+}
+
+func TestImageJobQueuedFallbackHidesForeignJobs(t *testing.T) {
+	job := failedJob("IMAGE_GEN")
+	stub := &jobStatusSQL{job: job}
+	app := &App{DB: stub, SQL: stub}
+
+	rr := httptest.NewRecorder()
+	app.ImageJob(rr, requestWithParam("GET", "/v1/images/jobs/"+job.ID, "id", job.ID, "someone-else"))
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rr.Code)
+	}
+}
+
+type enqueueVideoSQL struct {
+	args []any
+	// assets maps uploaded asset ids to their owner.
+	assets map[string]string
+	// deleted lists soft-deleted asset ids, which QSelectAssetByID skips.
+	deleted map[string]bool
+}
+
+func (s *enqueueVideoSQL) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *enqueueVideoSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	if query == sqlinline.QSelectAssetByID {
+		owner, ok := s.assets[args[0].(string)]
+		if !ok || s.deleted[args[0].(string)] {
+			return NewSimpleRow(nil)
+		}
+		return NewSimpleRow(func(dest ...any) error {
+			*dest[0].(*string) = args[0].(string)
+			*dest[1].(*string) = owner
+			*dest[2].(*string) = "uploads/" + owner + "/photo.png"
+			*dest[3].(*string) = "image/png"
+			return nil
+		})
+	}
+	if query != sqlinline.QEnqueueVideoJob {
+		return NewSimpleRow(nil)
+	}
+	s.args = args
+	return NewSimpleRow(func(dest ...any) error {
+		*dest[0].(*string) = "job-1"
+		*dest[1].(*int) = 4
+		return nil
+	})
+}
+
+func (s *enqueueVideoSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestVideosGenerateCallbackURL(t *testing.T) {
+	cases := []struct {
+		name         string
+		callback     string
+		wantStatus   int
+		wantCallback string
+	}{
+		{name: "public callback persisted", callback: "https://hooks.example.com/jobs", wantStatus: http.StatusAccepted, wantCallback: "https://hooks.example.com/jobs"},
+		{name: "no callback", wantStatus: http.StatusAccepted},
+		{name: "private callback rejected", callback: "http://127.0.0.1:9000/hook", wantStatus: http.StatusUnprocessableEntity},
+		{name: "non http callback rejected", callback: "ftp://hooks.example.com/jobs", wantStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &enqueueVideoSQL{}
+			app := &App{SQL: stub, VideoProviders: map[string]video.Generator{"gemini": nil}}
+			body, _ := json.Marshal(map[string]any{"provider": "gemini", "prompt": "promo", "callback_url": tc.callback})
+			req := httptest.NewRequest("POST", "/v1/videos/generate", bytes.NewReader(body))
+			req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+			rr := httptest.NewRecorder()
+
+			app.VideosGenerate(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			if tc.wantStatus != http.StatusAccepted {
+				if stub.args != nil {
+					t.Fatal("expected job not to be enqueued")
+				}
+				return
+			}
+			var props map[string]any
+			if err := json.Unmarshal(stub.args[3].(json.RawMessage), &props); err != nil {
+				t.Fatalf("decode properties: %v", err)
+			}
+			got, _ := props["callback_url"].(string)
+			if got != tc.wantCallback {
+				t.Fatalf("callback_url = %q, want %q", got, tc.wantCallback)
+			}
+			if tc.wantCallback == "" && strings.Contains(string(stub.args[3].(json.RawMessage)), "callback_url") {
+				t.Fatalf("unexpected callback_url in properties: %s", stub.args[3])
+			}
+		})
+	}
+}
+
+func TestVideosGenerateSourceAsset(t *testing.T) {
+	owned := uuid.NewString()
+	foreign := uuid.NewString()
+	deleted := uuid.NewString()
+	cases := []struct {
+		name       string
+		provider   string
+		source     map[string]any
+		wantStatus int
+	}{
+		{name: "owned asset queued", provider: "gemini", source: map[string]any{"asset_id": owned, "storage_key": "uploads/other/secret.png"}, wantStatus: http.StatusAccepted},
+		{name: "foreign asset rejected", provider: "gemini", source: map[string]any{"asset_id": foreign}, wantStatus: http.StatusNotFound},
+		{name: "deleted asset rejected", provider: "gemini", source: map[string]any{"asset_id": deleted}, wantStatus: http.StatusNotFound},
+		{name: "private url rejected", provider: "gemini", source: map[string]any{"url": "http://127.0.0.1/photo.png"}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "text only provider rejected", provider: "wan", source: map[string]any{"asset_id": owned}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &enqueueVideoSQL{
+				assets:  map[string]string{owned: "user-123", foreign: "user-456", deleted: "user-123"},
+				deleted: map[string]bool{deleted: true},
+			}
+			app := &App{SQL: stub, VideoProviders: map[string]video.Generator{
+				"gemini": video.NewGeminiGenerator(nil),
+				"wan":    video.NewQwenGenerator(nil, nil),
+			}}
+			body, _ := json.Marshal(map[string]any{"provider": tc.provider, "prompt": "animate the product", "source_asset": tc.source})
+			req := httptest.NewRequest("POST", "/v1/videos/generate", bytes.NewReader(body))
+			req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+			rr := httptest.NewRecorder()
+
+			app.VideosGenerate(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			if tc.wantStatus != http.StatusAccepted {
+				if stub.args != nil {
+					t.Fatal("expected job not to be enqueued")
+				}
+				return
+			}
+			var prompt struct {
+				SourceAsset map[string]string `json:"source_asset"`
+			}
+			if err := json.Unmarshal(stub.args[1].(json.RawMessage), &prompt); err != nil {
+				t.Fatalf("decode prompt: %v", err)
+			}
+			if prompt.SourceAsset["asset_id"] != owned || prompt.SourceAsset["storage_key"] != "" {
+				t.Fatalf("source_asset = %v, want only the validated asset id", prompt.SourceAsset)
+			}
+		})
+	}
+}
+
+type idempotentVideoSQL struct {
+	quota      int
+	enqueued   int
+	enqueueErr error
+	keys       map[string]string
+}
+
+func (s *idempotentVideoSQL) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	k := args[0].(string) + "/" + args[1].(string)
+	switch query {
+	case sqlinline.QRecordIdempotentJob:
+		s.keys[k] = args[2].(string)
+	case sqlinline.QReleaseIdempotencyKey:
+		if s.keys[k] == "" {
+			delete(s.keys, k)
+		}
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (s *idempotentVideoSQL) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	switch query {
+	case sqlinline.QReserveIdempotencyKey:
+		k := args[0].(string) + "/" + args[1].(string)
+		if _, held := s.keys[k]; held {
+			return NewSimpleRow(nil)
+		}
+		s.keys[k] = ""
+		return NewSimpleRow(func(dest ...any) error {
+			*dest[0].(*bool) = true
+			return nil
+		})
+	case sqlinline.QLookupIdempotentJob:
+		jobID, ok := s.keys[args[0].(string)+"/"+args[1].(string)]
+		if !ok {
+			return NewSimpleRow(nil)
+		}
+		return NewSimpleRow(func(dest ...any) error {
+			*dest[0].(*string) = jobID
+			*dest[1].(*int) = s.quota
+			return nil
+		})
+	case sqlinline.QEnqueueVideoJob:
+		if s.enqueueErr != nil {
+			return NewSimpleRow(func(...any) error { return s.enqueueErr })
+		}
+		s.enqueued++
+		s.quota--
+		jobID := "job-" + strings.Repeat("x", s.enqueued)
+		return NewSimpleRow(func(dest ...any) error {
+			*dest[0].(*string) = jobID
+			*dest[1].(*int) = s.quota
+			return nil
+		})
+	}
+	return NewSimpleRow(nil)
+}
+
+func (s *idempotentVideoSQL) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestVideosGenerateIdempotencyKeyReplaysJob(t *testing.T) {
+	stub := &idempotentVideoSQL{quota: 5, keys: map[string]string{}}
+	app := &App{SQL: stub, VideoProviders: map[string]video.Generator{"gemini": nil}}
+
+	submit := func(key string) jobResponse {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"provider": "gemini", "prompt": "promo"})
+		req := httptest.NewRequest("POST", "/v1/videos/generate", bytes.NewReader(body))
+		req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		app.VideosGenerate(rr, req)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("status = %d, want 202; body=%s", rr.Code, rr.Body.String())
+		}
+		var resp jobResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp
+	}
+
+	first := submit("order-42")
+	second := submit("order-42")
+	if second.JobID != first.JobID {
+		t.Fatalf("replayed job id = %q, want %q", second.JobID, first.JobID)
+	}
+	if second.RemainingQuota != first.RemainingQuota {
+		t.Fatalf("replayed remaining = %d, want %d", second.RemainingQuota, first.RemainingQuota)
+	}
+	if stub.enqueued != 1 {
+		t.Fatalf("enqueued %d jobs, want 1", stub.enqueued)
+	}
+
+	third := submit("order-43")
+	if third.JobID == first.JobID || third.RemainingQuota != first.RemainingQuota-1 {
+		t.Fatalf("new key should enqueue a fresh job, got %+v", third)
+	}
+}
+
+func TestVideosGenerateIdempotencyKeyReservation(t *testing.T) {
+	submit := func(app *App, key string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"provider": "gemini", "prompt": "promo"})
+		req := httptest.NewRequest("POST", "/v1/videos/generate", bytes.NewReader(body))
+		req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+		req.Header.Set("Idempotency-Key", key)
+		rr := httptest.NewRecorder()
+		app.VideosGenerate(rr, req)
+		return rr
+	}
+
+	t.Run("in flight", func(t *testing.T) {
+		stub := &idempotentVideoSQL{quota: 5, keys: map[string]string{"user-123/POST /v1/videos/generate order-42": ""}}
+		app := &App{SQL: stub, VideoProviders: map[string]video.Generator{"gemini": nil}}
+		rr := submit(app, "order-42")
+		if rr.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want 409; body=%s", rr.Code, rr.Body.String())
+		}
+		if stub.enqueued != 0 {
+			t.Fatalf("enqueued %d jobs while the key was held", stub.enqueued)
+		}
+	})
+
+	t.Run("failed enqueue releases key", func(t *testing.T) {
+		stub := &idempotentVideoSQL{quota: 5, keys: map[string]string{}, enqueueErr: &pgconn.PgError{Message: "quota exceeded"}}
+		app := &App{SQL: stub, VideoProviders: map[string]video.Generator{"gemini": nil}}
+		if rr := submit(app, "order-42"); rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("status = %d, want 429; body=%s", rr.Code, rr.Body.String())
+		}
+		stub.enqueueErr = nil
+		if rr := submit(app, "order-42"); rr.Code != http.StatusAccepted {
+			t.Fatalf("retry status = %d, want 202; body=%s", rr.Code, rr.Body.String())
+		}
+		if stub.enqueued != 1 {
+			t.Fatalf("enqueued %d jobs, want 1", stub.enqueued)
+		}
+	})
+}
+
+func TestIdempotencyKeyIsScopedToRoute(t *testing.T) {
+	video := httptest.NewRequest("POST", "/v1/videos/generate", nil)
+	video.Header.Set("Idempotency-Key", " order-42 ")
+	image := httptest.NewRequest("POST", "/v1/images/generate", nil)
+	image.Header.Set("Idempotency-Key", "order-42")
+
+	videoKey, err := idempotencyKey(video)
+	if err != nil {
+		t.Fatalf("idempotencyKey: %v", err)
+	}
+	imageKey, _ := idempotencyKey(image)
+	if videoKey == imageKey {
+		t.Fatalf("keys for different routes collide: %q", videoKey)
+	}
+	if empty, _ := idempotencyKey(httptest.NewRequest("POST", "/v1/videos/generate", nil)); empty != "" {
+		t.Fatalf("missing header key = %q, want empty", empty)
+	}
+}
+
+func TestVideosGenerateEnforcesPlanProviders(t *testing.T) {
+	cases := []struct {
+		name       string
+		plan       string
+		provider   string
+		wantStatus int
+	}{
+		{name: "free user denied premium model", plan: "free", provider: "gemini-2.5-flash", wantStatus: http.StatusForbidden},
+		{name: "missing plan treated as free", plan: "", provider: "gemini-2.5-flash", wantStatus: http.StatusForbidden},
+		{name: "free user allowed base model", plan: "free", provider: "qwen", wantStatus: http.StatusAccepted},
+		{name: "supporter allowed premium model", plan: "supporter", provider: "gemini-2.5-flash", wantStatus: http.StatusAccepted},
+		{name: "unknown provider rejected before plan check", plan: "free", provider: "veo2", wantStatus: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &enqueueVideoSQL{}
+			app := &App{
+				Config: &infra.Config{PlanProviders: map[string][]string{
+					"free":      {"qwen", "qwen-image-plus"},
+					"supporter": {"*"},
+				}},
+				SQL:            stub,
+				VideoProviders: map[string]video.Generator{"qwen": nil, "gemini-2.5-flash": nil},
+			}
+			body, _ := json.Marshal(map[string]any{"provider": tc.provider, "prompt": "promo"})
+			req := httptest.NewRequest("POST", "/v1/videos/generate", bytes.NewReader(body))
+			req = req.WithContext(middleware.ContextWithClaims(req.Context(), &middleware.TokenClaims{Sub: "user-123", Plan: tc.plan}))
+			rr := httptest.NewRecorder()
+
+			app.VideosGenerate(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			if tc.wantStatus == http.StatusForbidden {
+				if stub.args != nil {
+					t.Fatal("quota consumed for a denied provider")
+				}
+				if !strings.Contains(rr.Body.String(), "cannot use provider gemini-2.5-flash") {
+					t.Fatalf("unexpected error body: %s", rr.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestVideosGeneratePersistsRequestID(t *testing.T) {
+	stub := &enqueueVideoSQL{}
+	app := &App{SQL: stub, VideoProviders: map[string]video.Generator{"gemini": nil}}
+	body, _ := json.Marshal(map[string]any{"provider": "gemini", "prompt": "promo"})
+	req := httptest.NewRequest("POST", "/v1/videos/generate", bytes.NewReader(body))
+	req.Header.Set("X-Request-ID", "req-abc-123")
+	req = req.WithContext(middleware.ContextWithUserID(req.Context(), "user-123"))
+	rr := httptest.NewRecorder()
+
+	middleware.RequestID(http.HandlerFunc(app.VideosGenerate)).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202; body=%s", rr.Code, rr.Body.String())
+	}
+	var props map[string]any
+	if err := json.Unmarshal(stub.args[3].(json.RawMessage), &props); err != nil {
+		t.Fatalf("decode properties: %v", err)
+	}
+	if got := props["request_id"]; got != "req-abc-123" {
+		t.Fatalf("request_id = %v, want req-abc-123", got)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"server/internal/http/handlers"
+	"server/internal/infra"
+	"server/internal/metrics"
 	"server/internal/middleware"
 
 	"github.com/go-chi/chi/v5"
@@ -16,55 +18,98 @@ func NewRouter(app *handlers.App) http.Handler {
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger(app.Logger))
+	r.Use(app.Recover)
 
 	var geoLookup middleware.CountryLookup
 	if app.GeoIPResolver != nil {
-		geoLookup = app.GeoIPResolver.CountryCode
+		geoLookup = middleware.CachedCountryLookup(app.GeoIPResolver.CountryCode, app.Config.GeoIPCacheSize, app.Config.GeoIPCacheTTL)
 	}
 	r.Use(middleware.I18N("en", geoLookup))
-	r.Use(middleware.CORS([]string{"http://localhost:3000", "https://script.google.com"}))
+	r.Use(middleware.CORS(app.Config.CORSAllowedOrigins))
+	r.Use(middleware.Gzip(middleware.DefaultGzipMinSize))
 	r.Use(middleware.RateLimit(app.Config.RateLimitPerMin, time.Minute))
 
-	if base := strings.TrimSpace(app.Config.StoragePath); base != "" {
+	// With signing enabled assets are only reachable through /v1/files, so the
+	// unauthenticated /static mount would leak them.
+	if base := strings.TrimSpace(app.Config.StoragePath); base != "" && app.Config.StorageDriver != infra.StorageDriverS3 && app.Config.StorageSigningSecret == "" {
 		fs := http.StripPrefix("/static/", http.FileServer(http.Dir(base)))
 		r.Handle("/static/*", fs)
 	}
 
+	// Metrics reveal traffic and provider health, so they are only served to
+	// scrapers presenting METRICS_TOKEN.
+	if token := app.Config.MetricsToken; token != "" {
+		r.With(middleware.RequireBearerToken(token)).Handle("/metrics", metrics.Default)
+	}
+	r.Get("/readyz", app.Ready)
+
+	userLimit := middleware.PerUserRateLimit(app.Config.UserRateLimitPerMin)
+	auth := middleware.AuthJWTWithRevocation(app.JWTSecret, app.TokenRevoked)
+
 	r.Route("/v1", func(r chi.Router) {
+		r.Use(middleware.RequireJSON("/v1/images/uploads"))
 		r.Get("/healthz", app.Health)
+		r.Get("/files/*", app.ServeSignedAsset)
+		r.Get("/healthz/worker", app.WorkerHealth)
+		r.Get("/providers", app.ListProviders)
 		r.Get("/openapi.json", app.OpenAPIJSON)
 		r.Get("/docs", app.OpenAPIDocs)
 
 		r.Post("/auth/google/verify", app.AuthGoogleVerify)
-		r.With(middleware.AuthJWT(app.JWTSecret)).Get("/me", app.Me)
+		r.Post("/auth/refresh", app.AuthRefresh)
+		r.With(auth).Post("/auth/logout", app.AuthLogout)
+		r.With(auth, userLimit).Get("/me", app.Me)
+		r.With(auth, userLimit).Patch("/me", app.UpdateMe)
+		r.With(auth, userLimit).Get("/quota", app.Quota)
 
-		r.With(middleware.AuthJWT(app.JWTSecret)).Route("/prompts", func(r chi.Router) {
+		r.With(auth, userLimit).Route("/prompts", func(r chi.Router) {
 			r.Post("/enhance", app.PromptEnhance)
+			r.Post("/enhance/batch", app.PromptEnhanceBatch)
 			r.Post("/random", app.PromptRandom)
 			r.Post("/clear", app.PromptClear)
+			r.Get("/templates", app.ListPromptTemplates)
+			r.Post("/templates/{id}/apply", app.ApplyPromptTemplate)
+			r.Get("/drafts", app.ListPromptDrafts)
+			r.Post("/drafts", app.SavePromptDraft)
+			r.Delete("/drafts/{id}", app.DeletePromptDraft)
 		})
 
-		r.With(middleware.AuthJWT(app.JWTSecret)).Route("/images", func(r chi.Router) {
+		r.With(auth, userLimit).Route("/images", func(r chi.Router) {
 			r.Post("/uploads", app.ImagesUpload)
 			r.Post("/generate", app.ImagesGenerate)
+			r.Post("/estimate", app.ImagesEstimate)
+			r.Get("/jobs", app.ListImageJobs)
 			r.Get("/jobs/{id}", app.ImageJob)
 			r.Get("/{job_id}/download", app.ImageDownload)
 			r.Get("/{job_id}/download.zip", app.ImageDownloadZip)
+			r.Get("/{job_id}/assets", app.ImageAssets)
+			r.Post("/{job_id}/cancel", app.CancelJob)
 		})
 
-		r.With(middleware.AuthJWT(app.JWTSecret)).Route("/ideas", func(r chi.Router) {
+		r.With(auth, userLimit).Route("/ideas", func(r chi.Router) {
 			r.Post("/from-image", app.IdeasFromImage)
 		})
 
-		r.With(middleware.AuthJWT(app.JWTSecret)).Route("/videos", func(r chi.Router) {
+		r.With(auth, userLimit).Route("/videos", func(r chi.Router) {
 			r.Post("/generate", app.VideosGenerate)
 			r.Get("/{job_id}/status", app.VideoStatus)
 			r.Get("/{job_id}/assets", app.VideoAssets)
 		})
 
-		r.With(middleware.AuthJWT(app.JWTSecret)).Route("/assets", func(r chi.Router) {
+		r.With(auth, userLimit).Route("/jobs", func(r chi.Router) {
+			r.Post("/status", app.JobsStatus)
+		})
+
+		r.With(auth, userLimit).Route("/assets", func(r chi.Router) {
 			r.Get("/", app.ListAssets)
 			r.Get("/{id}/download", app.DownloadAsset)
+			r.Delete("/{id}", app.DeleteAsset)
+			r.Get("/*", app.ServeAsset)
+		})
+
+		r.With(auth, userLimit).Route("/admin", func(r chi.Router) {
+			r.Get("/jobs/failed", app.AdminFailedJobs)
+			r.Get("/stats/providers", app.AdminProviderStats)
 		})
 
 		r.Get("/stats/summary", app.StatsSummary)
@@ -0,0 +1,109 @@
+// Package i18n resolves requested locales against the set the API supports,
+// walking a per-language fallback chain before settling on DefaultLocale.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale terminates every fallback chain.
+const DefaultLocale = "en"
+
+var (
+	// DefaultSupported lists the locales served unless overridden with Configure.
+	DefaultSupported = []string{"en", "id", "ms", "jv"}
+	// DefaultFallbacks maps a language to the locales tried after it, in order.
+	DefaultFallbacks = map[string][]string{
+		"ms": {"id", "en"},
+		"jv": {"id", "en"},
+		"su": {"id", "en"},
+	}
+)
+
+var (
+	mu        sync.RWMutex
+	supported = localeSet(DefaultSupported)
+	fallbacks = copyFallbacks(DefaultFallbacks)
+)
+
+// Configure replaces the supported locales and fallback chains. DefaultLocale
+// is always supported so every chain can terminate.
+func Configure(locales []string, chains map[string][]string) error {
+	set := localeSet(locales)
+	if len(set) == 0 {
+		return fmt.Errorf("at least one supported locale is required")
+	}
+	set[DefaultLocale] = struct{}{}
+	mu.Lock()
+	defer mu.Unlock()
+	supported = set
+	fallbacks = copyFallbacks(chains)
+	return nil
+}
+
+// Resolve maps a language tag such as "ms-MY" or "jv" to the first supported
+// locale on its fallback chain, or DefaultLocale when none matches.
+func Resolve(tag string) string {
+	return Chain(tag)[0]
+}
+
+// Chain returns the supported locales to try for tag, most preferred first.
+// The result always ends with DefaultLocale.
+func Chain(tag string) []string {
+	lang := Language(tag)
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]string, 0, 3)
+	seen := map[string]struct{}{}
+	add := func(locale string) {
+		if _, ok := supported[locale]; !ok {
+			return
+		}
+		if _, dup := seen[locale]; dup {
+			return
+		}
+		seen[locale] = struct{}{}
+		out = append(out, locale)
+	}
+	add(lang)
+	for _, next := range fallbacks[lang] {
+		add(next)
+	}
+	add(DefaultLocale)
+	return out
+}
+
+// Language returns the lower-cased primary subtag of a BCP 47 style tag, so
+// "ms_MY" and "MS-my" both yield "ms".
+func Language(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if idx := strings.IndexAny(tag, "-_"); idx >= 0 {
+		tag = tag[:idx]
+	}
+	return tag
+}
+
+func localeSet(locales []string) map[string]struct{} {
+	out := make(map[string]struct{}, len(locales))
+	for _, locale := range locales {
+		if lang := Language(locale); lang != "" {
+			out[lang] = struct{}{}
+		}
+	}
+	return out
+}
+
+func copyFallbacks(in map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(in))
+	for lang, chain := range in {
+		lang = Language(lang)
+		for _, next := range chain {
+			if next = Language(next); next != "" {
+				out[lang] = append(out[lang], next)
+			}
+		}
+	}
+	return out
+}
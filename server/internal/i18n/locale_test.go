@@ -0,0 +1,50 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveFollowsFallbackChain(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "ms-MY", want: "ms"},
+		{tag: "jv", want: "jv"},
+		{tag: "ID_id", want: "id"},
+		{tag: "fr-FR", want: "en"},
+		{tag: "", want: "en"},
+	}
+	for _, tc := range tests {
+		if got := Resolve(tc.tag); got != tc.want {
+			t.Fatalf("Resolve(%q) = %q, want %q", tc.tag, got, tc.want)
+		}
+	}
+}
+
+func TestChainSkipsUnsupportedLocales(t *testing.T) {
+	if err := Configure([]string{"id"}, map[string][]string{"ms": {"id", "en"}, "jv": {"id"}}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	t.Cleanup(func() { _ = Configure(DefaultSupported, DefaultFallbacks) })
+
+	if got := Chain("ms"); !reflect.DeepEqual(got, []string{"id", "en"}) {
+		t.Fatalf("Chain(ms) = %v, want [id en]", got)
+	}
+	if got := Resolve("jv-ID"); got != "id" {
+		t.Fatalf("Resolve(jv-ID) = %q, want id", got)
+	}
+	if got := Chain("xx"); !reflect.DeepEqual(got, []string{"en"}) {
+		t.Fatalf("Chain(xx) = %v, want [en]", got)
+	}
+}
+
+func TestConfigureRequiresLocales(t *testing.T) {
+	if err := Configure(nil, nil); err == nil {
+		t.Fatalf("Configure(nil) expected error")
+	}
+	if got := Chain("ms"); !reflect.DeepEqual(got, []string{"ms", "id", "en"}) {
+		t.Fatalf("Chain(ms) after rejected Configure = %v", got)
+	}
+}
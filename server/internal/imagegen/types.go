@@ -15,6 +15,8 @@ type GenerateRequest struct {
 	Provider    string `json:"provider"`
 	Quantity    int    `json:"quantity"`
 	AspectRatio string `json:"aspect_ratio"`
+	CallbackURL string `json:"callback_url,omitempty"`
+	Seed        *int   `json:"seed,omitempty"`
 
 	Prompt struct {
 		Title        string `json:"title"`
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -16,16 +17,37 @@ type Config struct {
 	Port                 string
 	DatabaseURL          string
 	JWTSecret            string
+	RefreshTokenTTL      time.Duration
 	StorageBaseURL       string
 	StoragePath          string
+	StorageDriver        string
+	StorageSigningSecret string
+	StorageSignedBaseURL string
+	StorageSignedURLTTL  time.Duration
+	S3Endpoint           string
+	S3Region             string
+	S3Bucket             string
+	S3AccessKeyID        string
+	S3SecretAccessKey    string
+	S3Prefix             string
 	GeoIPDBPath          string
-	GoogleClientID       string
+	GeoIPCacheSize       int
+	GeoIPCacheTTL        time.Duration
+	GoogleClientIDs      []string
+	GoogleEmailDomains   []string
 	GoogleIssuer         string
 	PromptProvider       string
+	PromptProviderChain  []string
+	ModerationDenylist   []string
+	ModerationOpenAI     bool
 	QwenAPIKey           string
 	QwenModel            string
+	QwenVideoModel       string
 	QwenBaseURL          string
+	QwenRegionBaseURLs   map[string]string
 	QwenDefaultSize      string
+	QwenBreakerThreshold int
+	QwenBreakerCooldown  time.Duration
 	GeminiAPIKey         string
 	GeminiModel          string
 	GeminiBaseURL        string
@@ -33,11 +55,44 @@ type Config struct {
 	OpenAIModel          string
 	OpenAIBaseURL        string
 	OpenAIOrg            string
+	OpenAISecondaryModel string
 	ImageSourceAllowlist []string
+	TrustedHosts         []string
+	MaxUploadMB          int
+	MaxJSONBodyKB        int
+	PlanMaxUploadMB      map[string]int
+	SourceImageMaxMB     int
+	PlanSourceImageMB    map[string]int
+	DefaultImageQuantity int
+	PlanMaxQuantity      map[string]int
+	TitleMaxChars        int
+	InstructionsMaxChars int
+	PlanProviders        map[string][]string
+	ImageConcurrency     int
+	UserImageConcurrency int
+	PlanImageConcurrency map[string]int
 	HTTPReadTimeout      time.Duration
 	HTTPWriteTimeout     time.Duration
 	HTTPIdleTimeout      time.Duration
+	CORSAllowedOrigins   []string
+	SupportedLocales     []string
+	LocaleFallbacks      map[string][]string
+	NegativePrompts      map[string][]string
 	RateLimitPerMin      int
+	UserRateLimitPerMin  int
+	WorkerMaxAttempts    int
+	WorkerShutdownGrace  time.Duration
+	WorkerConcurrency    int
+	WorkerHeartbeatStale time.Duration
+	WorkerMaxPoll        time.Duration
+	WorkerMetricsAddr    string
+	MetricsToken         string
+	AssetPurgeGrace      time.Duration
+	AssetRetentionDays   map[string]int
+	SyntheticFallback    bool
+	SyntheticVideoLength time.Duration
+	ImageGenTimeout      time.Duration
+	VideoGenTimeout      time.Duration
 	CertFile             string
 	KeyFile              string
 }
@@ -57,34 +112,85 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	appEnv := getEnv("APP_ENV", "development")
 	cfg := &Config{
-		AppEnv:           getEnv("APP_ENV", "development"),
-		Port:             port,
-		DatabaseURL:      os.Getenv("DATABASE_URL"),
-		JWTSecret:        os.Getenv("JWT_SECRET"),
-		StorageBaseURL:   getEnv("STORAGE_BASE_URL", storageBaseDefault),
-		StoragePath:      getEnv("STORAGE_PATH", "./storage"),
-		GeoIPDBPath:      os.Getenv("GEOIP_DB_PATH"),
-		GoogleClientID:   os.Getenv("GOOGLE_CLIENT_ID"),
-		GoogleIssuer:     getEnv("GOOGLE_ISSUER", "https://accounts.google.com"),
-		PromptProvider:   getEnv("PROMPT_PROVIDER", "gemini"),
-		QwenAPIKey:       os.Getenv("QWEN_API_KEY"),
-		QwenModel:        getEnv("QWEN_MODEL", "qwen-image-plus"),
-		QwenBaseURL:      getEnv("QWEN_BASE_URL", "https://dashscope-intl.aliyuncs.com/api/v1"),
-		QwenDefaultSize:  getEnv("QWEN_DEFAULT_SIZE", "1328*1328"),
-		GeminiAPIKey:     os.Getenv("GEMINI_API_KEY"),
-		GeminiModel:      getEnv("GEMINI_MODEL", "gemini-2.5-flash"),
-		GeminiBaseURL:    getEnv("GEMINI_BASE_URL", "https://generativelanguage.googleapis.com/v1beta"),
-		OpenAIAPIKey:     os.Getenv("OPENAI_API_KEY"),
-		OpenAIModel:      getEnv("OPENAI_MODEL", "gpt-4o-mini"),
-		OpenAIBaseURL:    getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
-		OpenAIOrg:        os.Getenv("OPENAI_ORG"),
-		HTTPReadTimeout:  time.Second * time.Duration(getEnvInt("HTTP_READ_TIMEOUT_SECONDS", 15)),
-		HTTPWriteTimeout: time.Second * time.Duration(getEnvInt("HTTP_WRITE_TIMEOUT_SECONDS", 30)),
-		HTTPIdleTimeout:  time.Second * time.Duration(getEnvInt("HTTP_IDLE_TIMEOUT_SECONDS", 60)),
-		RateLimitPerMin:  getEnvInt("RATE_LIMIT_PER_MINUTE", 30),
-		CertFile:         getEnv("HTTP_TLS_CERT_FILE", "./tls/localhost.pem"),
-		KeyFile:          getEnv("HTTP_TLS_KEY_FILE", "./tls/localhost-key.pem"),
+		AppEnv:               appEnv,
+		Port:                 port,
+		DatabaseURL:          os.Getenv("DATABASE_URL"),
+		JWTSecret:            os.Getenv("JWT_SECRET"),
+		RefreshTokenTTL:      24 * time.Hour * time.Duration(getEnvInt("REFRESH_TOKEN_TTL_DAYS", 30)),
+		StorageBaseURL:       getEnv("STORAGE_BASE_URL", storageBaseDefault),
+		StoragePath:          getEnv("STORAGE_PATH", "./storage"),
+		StorageDriver:        strings.ToLower(strings.TrimSpace(getEnv("STORAGE_DRIVER", StorageDriverLocal))),
+		StorageSigningSecret: os.Getenv("STORAGE_SIGNING_SECRET"),
+		StorageSignedBaseURL: getEnv("STORAGE_SIGNED_BASE_URL", fmt.Sprintf("http://localhost:%s/v1/files", port)),
+		StorageSignedURLTTL:  time.Minute * time.Duration(getEnvInt("STORAGE_SIGNED_URL_TTL_MINUTES", 60)),
+		S3Endpoint:           os.Getenv("S3_ENDPOINT"),
+		S3Region:             getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:             os.Getenv("S3_BUCKET"),
+		S3AccessKeyID:        os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey:    os.Getenv("S3_SECRET_ACCESS_KEY"),
+		S3Prefix:             os.Getenv("S3_PREFIX"),
+		GeoIPDBPath:          os.Getenv("GEOIP_DB_PATH"),
+		GeoIPCacheSize:       getEnvInt("GEOIP_CACHE_SIZE", 4096),
+		GeoIPCacheTTL:        time.Second * time.Duration(getEnvInt("GEOIP_CACHE_TTL_SECONDS", 3600)),
+		GoogleClientIDs:      getEnvList("GOOGLE_CLIENT_ID"),
+		GoogleEmailDomains:   emailDomains(getEnvList("GOOGLE_EMAIL_DOMAIN_ALLOWLIST")),
+		GoogleIssuer:         getEnv("GOOGLE_ISSUER", "https://accounts.google.com"),
+		PromptProvider:       getEnv("PROMPT_PROVIDER", "gemini"),
+		QwenAPIKey:           os.Getenv("QWEN_API_KEY"),
+		QwenModel:            getEnv("QWEN_MODEL", "qwen-image-plus"),
+		QwenVideoModel:       getEnv("QWEN_VIDEO_MODEL", "wan2.1-t2v-turbo"),
+		QwenBaseURL:          getEnv("QWEN_BASE_URL", "https://dashscope-intl.aliyuncs.com/api/v1"),
+		QwenRegionBaseURLs:   getEnvMap("QWEN_REGION_BASE_URLS", "intl=https://dashscope-intl.aliyuncs.com/api/v1,cn=https://dashscope.aliyuncs.com/api/v1"),
+		QwenDefaultSize:      getEnv("QWEN_DEFAULT_SIZE", "1328*1328"),
+		QwenBreakerThreshold: getEnvInt("QWEN_BREAKER_THRESHOLD", 5),
+		QwenBreakerCooldown:  time.Second * time.Duration(getEnvInt("QWEN_BREAKER_COOLDOWN_SECONDS", 30)),
+		GeminiAPIKey:         os.Getenv("GEMINI_API_KEY"),
+		GeminiModel:          getEnv("GEMINI_MODEL", "gemini-2.5-flash"),
+		GeminiBaseURL:        getEnv("GEMINI_BASE_URL", "https://generativelanguage.googleapis.com/v1beta"),
+		OpenAIAPIKey:         os.Getenv("OPENAI_API_KEY"),
+		OpenAIModel:          getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+		OpenAIBaseURL:        getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		OpenAIOrg:            os.Getenv("OPENAI_ORG"),
+		OpenAISecondaryModel: getEnv("OPENAI_SECONDARY_MODEL", "gpt-3.5-turbo"),
+		MaxUploadMB:          getEnvInt("MAX_UPLOAD_MB", 12),
+		MaxJSONBodyKB:        getEnvInt("MAX_JSON_BODY_KB", 256),
+		PlanMaxUploadMB:      getEnvPlanInts("MAX_UPLOAD_MB_BY_PLAN", "supporter=25"),
+		SourceImageMaxMB:     getEnvInt("SOURCE_IMAGE_MAX_MB", 20),
+		PlanSourceImageMB:    getEnvPlanInts("SOURCE_IMAGE_MAX_MB_BY_PLAN", "free=10,pro=30,supporter=30"),
+		DefaultImageQuantity: getEnvInt("DEFAULT_IMAGE_QUANTITY", 1),
+		PlanMaxQuantity:      getEnvPlanInts("MAX_QUANTITY_BY_PLAN", "free=2,pro=8,supporter=8"),
+		TitleMaxChars:        getEnvInt("PROMPT_TITLE_MAX_CHARS", 150),
+		InstructionsMaxChars: getEnvInt("PROMPT_INSTRUCTIONS_MAX_CHARS", 2000),
+		ImageConcurrency:     getEnvInt("IMAGE_CONCURRENCY", 4),
+		UserImageConcurrency: getEnvInt("IMAGE_CONCURRENCY_PER_USER", 2),
+		PlanImageConcurrency: getEnvPlanInts("IMAGE_CONCURRENCY_PER_USER_BY_PLAN", ""),
+		HTTPReadTimeout:      time.Second * time.Duration(getEnvInt("HTTP_READ_TIMEOUT_SECONDS", 15)),
+		HTTPWriteTimeout:     time.Second * time.Duration(getEnvInt("HTTP_WRITE_TIMEOUT_SECONDS", 30)),
+		HTTPIdleTimeout:      time.Second * time.Duration(getEnvInt("HTTP_IDLE_TIMEOUT_SECONDS", 60)),
+		CORSAllowedOrigins:   getEnvList("CORS_ALLOWED_ORIGINS"),
+		SupportedLocales:     getEnvList("SUPPORTED_LOCALES"),
+		LocaleFallbacks:      getEnvPlanLists("LOCALE_FALLBACKS", "ms=id|en,jv=id|en,su=id|en"),
+		NegativePrompts:      getEnvPlanLists("NEGATIVE_PROMPT_BY_CATEGORY", defaultNegativePrompts),
+		RateLimitPerMin:      getEnvInt("RATE_LIMIT_PER_MINUTE", 30),
+		UserRateLimitPerMin:  getEnvInt("USER_RATE_LIMIT_PER_MINUTE", 60),
+		WorkerMaxAttempts:    getEnvInt("WORKER_MAX_ATTEMPTS", 3),
+		WorkerShutdownGrace:  time.Second * time.Duration(getEnvInt("WORKER_SHUTDOWN_GRACE_SECONDS", 10)),
+		WorkerConcurrency:    getEnvInt("WORKER_CONCURRENCY", 1),
+		WorkerHeartbeatStale: time.Second * time.Duration(getEnvInt("WORKER_HEARTBEAT_STALE_SECONDS", 30)),
+		WorkerMaxPoll:        time.Second * time.Duration(getEnvInt("WORKER_MAX_POLL", 30)),
+		WorkerMetricsAddr:    os.Getenv("WORKER_METRICS_ADDR"),
+		MetricsToken:         strings.TrimSpace(os.Getenv("METRICS_TOKEN")),
+		AssetPurgeGrace:      time.Hour * time.Duration(getEnvInt("ASSET_PURGE_GRACE_HOURS", 72)),
+		AssetRetentionDays:   getEnvPlanInts("ASSET_RETENTION_DAYS_BY_PLAN", ""),
+		ModerationOpenAI:     getEnvBool("MODERATION_OPENAI", true),
+		SyntheticFallback:    getEnvBool("SYNTHETIC_FALLBACK", !isProductionEnv(appEnv)),
+		SyntheticVideoLength: time.Second * time.Duration(getEnvInt("SYNTHETIC_VIDEO_SECONDS", 0)),
+		ImageGenTimeout:      time.Second * time.Duration(getEnvInt("IMAGE_GEN_TIMEOUT", 90)),
+		VideoGenTimeout:      time.Second * time.Duration(getEnvInt("VIDEO_GEN_TIMEOUT", 180)),
+		CertFile:             getEnv("HTTP_TLS_CERT_FILE", "./tls/localhost.pem"),
+		KeyFile:              getEnv("HTTP_TLS_KEY_FILE", "./tls/localhost-key.pem"),
 	}
 
 	if parsedBase, err := url.Parse(cfg.StorageBaseURL); err == nil && parsedBase != nil {
@@ -115,6 +221,56 @@ func LoadConfig() (*Config, error) {
 		sort.Strings(cfg.ImageSourceAllowlist)
 	}
 
+	cfg.PlanProviders = getEnvPlanLists("PLAN_PROVIDER_ALLOWLIST", defaultPlanProviders(cfg.QwenModel, cfg.QwenVideoModel))
+
+	trusted := getEnvList("TRUSTED_INTERNAL_HOSTS")
+	if parsedBase, err := url.Parse(cfg.StorageBaseURL); err == nil && parsedBase != nil && parsedBase.Hostname() != "" {
+		trusted = append(trusted, parsedBase.Hostname())
+	}
+	for _, host := range trusted {
+		if host = strings.ToLower(host); !slices.Contains(cfg.TrustedHosts, host) {
+			cfg.TrustedHosts = append(cfg.TrustedHosts, host)
+		}
+	}
+	sort.Strings(cfg.TrustedHosts)
+
+	denylist, err := moderationDenylist(getEnvList("MODERATION_DENYLIST"), os.Getenv("MODERATION_DENYLIST_FILE"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.ModerationDenylist = denylist
+
+	chain, err := promptProviderChain(getEnvList("PROMPT_PROVIDER_CHAIN"), cfg.PromptProvider)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PromptProviderChain = chain
+
+	if len(cfg.CORSAllowedOrigins) == 0 {
+		cfg.CORSAllowedOrigins = []string{"http://localhost:3000", "https://script.google.com"}
+	}
+	if len(cfg.SupportedLocales) == 0 {
+		cfg.SupportedLocales = []string{"en", "id", "ms", "jv"}
+	}
+
+	if err := validateStorageDriver(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.SyntheticVideoLength < 0 || cfg.SyntheticVideoLength > time.Minute {
+		return nil, fmt.Errorf("SYNTHETIC_VIDEO_SECONDS must be between 0 and 60")
+	}
+	if cfg.ImageConcurrency < 1 {
+		return nil, fmt.Errorf("IMAGE_CONCURRENCY must be at least 1")
+	}
+	if cfg.UserImageConcurrency < 0 {
+		return nil, fmt.Errorf("IMAGE_CONCURRENCY_PER_USER must not be negative")
+	}
+	for region, base := range cfg.QwenRegionBaseURLs {
+		if parsed, err := url.Parse(base); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("QWEN_REGION_BASE_URLS: region %q needs an http(s) base URL", region)
+		}
+	}
+
 	if cfg.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL is required")
 	}
@@ -141,3 +297,222 @@ func getEnvInt(key string, fallback int) int {
 	}
 	return fallback
 }
+
+func getEnvBool(key string, fallback bool) bool {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+// getEnvList parses a comma separated list, dropping blank entries.
+func getEnvList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// emailDomains lower-cases domains and strips a leading "@".
+func emailDomains(items []string) []string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.ToLower(strings.TrimPrefix(item, "@")); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// moderationDenylist combines MODERATION_DENYLIST with the terms in
+// MODERATION_DENYLIST_FILE, one per line. Blank lines and lines starting with
+// "#" are ignored.
+func moderationDenylist(terms []string, path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return terms, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("MODERATION_DENYLIST_FILE: %w", err)
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			terms = append(terms, line)
+		}
+	}
+	return terms, nil
+}
+
+// defaultNegativePrompts seeds NEGATIVE_PROMPT_BY_CATEGORY with negatives
+// for the product types the prompt schema accepts. Categories not listed use
+// the generic default negative prompt.
+const defaultNegativePrompts = "food=low quality|blurry|unappetizing|burnt|mold|plastic-looking food|messy plate," +
+	"fashion=low quality|blurry|wrinkled fabric|lint|loose threads|distorted body," +
+	"skincare=low quality|blurry|smudged label|dented packaging|spilled product," +
+	"shoes=low quality|blurry|scuffed|dirty soles|mismatched pair," +
+	"bag=low quality|blurry|scratched leather|loose stitching|sagging shape"
+
+// Prompt enhancer names accepted in PROMPT_PROVIDER_CHAIN.
+const (
+	PromptProviderGemini = "gemini"
+	PromptProviderOpenAI = "openai"
+	PromptProviderStatic = "static"
+)
+
+// defaultPlanProviders limits the free plan to the Qwen provider keys,
+// including the configured image and video model names so requests naming
+// them are not refused when QWEN_MODEL or QWEN_VIDEO_MODEL change.
+func defaultPlanProviders(qwenModel, qwenVideoModel string) string {
+	free := []string{"qwen", "qwen-image", "qwen-image-plus", "qwen-image-edit", "wan"}
+	for _, model := range []string{qwenModel, qwenVideoModel} {
+		if model = strings.ToLower(strings.TrimSpace(model)); model != "" && !slices.Contains(free, model) {
+			free = append(free, model)
+		}
+	}
+	return "free=" + strings.Join(free, "|")
+}
+
+// promptProviderChain validates the enhancer order from PROMPT_PROVIDER_CHAIN.
+// Without one the order follows PROMPT_PROVIDER as before: the preferred
+// provider, the other remote provider, then static. Static prompts always
+// terminate the chain.
+func promptProviderChain(names []string, preferred string) ([]string, error) {
+	if len(names) == 0 {
+		switch strings.ToLower(strings.TrimSpace(preferred)) {
+		case PromptProviderOpenAI:
+			names = []string{PromptProviderOpenAI, PromptProviderGemini}
+		case PromptProviderGemini, "":
+			names = []string{PromptProviderGemini, PromptProviderOpenAI}
+		}
+	}
+	chain := make([]string, 0, len(names)+1)
+	seen := make(map[string]struct{}, len(names))
+	for i, name := range names {
+		name = strings.ToLower(name)
+		switch name {
+		case PromptProviderGemini, PromptProviderOpenAI:
+		case PromptProviderStatic:
+			if i != len(names)-1 {
+				return nil, fmt.Errorf("PROMPT_PROVIDER_CHAIN: %q must be the last provider", name)
+			}
+		default:
+			return nil, fmt.Errorf("PROMPT_PROVIDER_CHAIN: unknown provider %q", name)
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("PROMPT_PROVIDER_CHAIN: provider %q listed twice", name)
+		}
+		seen[name] = struct{}{}
+		chain = append(chain, name)
+	}
+	if _, ok := seen[PromptProviderStatic]; !ok {
+		chain = append(chain, PromptProviderStatic)
+	}
+	return chain, nil
+}
+
+// Storage backends accepted in STORAGE_DRIVER.
+const (
+	StorageDriverLocal = "local"
+	StorageDriverS3    = "s3"
+)
+
+// validateStorageDriver rejects unknown STORAGE_DRIVER values and an s3 driver
+// missing the settings needed to reach the bucket. STORAGE_BASE_URL must be set
+// explicitly for s3 because its default points at /static, which only the
+// local driver mounts.
+func validateStorageDriver(cfg *Config) error {
+	switch cfg.StorageDriver {
+	case StorageDriverLocal:
+		return nil
+	case StorageDriverS3:
+		missing := make([]string, 0, 5)
+		for _, field := range []struct{ name, value string }{
+			{"S3_ENDPOINT", cfg.S3Endpoint},
+			{"S3_BUCKET", cfg.S3Bucket},
+			{"S3_ACCESS_KEY_ID", cfg.S3AccessKeyID},
+			{"S3_SECRET_ACCESS_KEY", cfg.S3SecretAccessKey},
+			{"STORAGE_BASE_URL", os.Getenv("STORAGE_BASE_URL")},
+		} {
+			if strings.TrimSpace(field.value) == "" {
+				missing = append(missing, field.name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("STORAGE_DRIVER=s3 requires %s", strings.Join(missing, ", "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("STORAGE_DRIVER: unknown driver %q", cfg.StorageDriver)
+	}
+}
+
+// isProductionEnv reports whether APP_ENV names a production deployment.
+func isProductionEnv(env string) bool {
+	switch strings.ToLower(strings.TrimSpace(env)) {
+	case "production", "prod":
+		return true
+	default:
+		return false
+	}
+}
+
+// getEnvPlanInts parses a comma separated list of plan=value pairs. Plan names
+// are lower-cased and entries with a non-positive or malformed value are
+// skipped.
+func getEnvPlanInts(key, fallback string) map[string]int {
+	out := make(map[string]int)
+	for _, pair := range strings.Split(getEnv(key, fallback), ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if name == "" || err != nil || n <= 0 {
+			continue
+		}
+		out[name] = n
+	}
+	return out
+}
+
+// getEnvMap parses a comma separated list of name=value pairs. Names are
+// lower-cased; values are kept verbatim apart from surrounding whitespace.
+func getEnvMap(key, fallback string) map[string]string {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(getEnv(key, fallback), ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			continue
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// getEnvPlanLists parses a comma separated list of plan=a|b|c entries into
+// lower-cased provider lists keyed by plan.
+func getEnvPlanLists(key, fallback string) map[string][]string {
+	out := make(map[string][]string)
+	for _, pair := range strings.Split(getEnv(key, fallback), ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			continue
+		}
+		for _, item := range strings.Split(value, "|") {
+			if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+				out[name] = append(out[name], item)
+			}
+		}
+	}
+	return out
+}
@@ -1,6 +1,13 @@
 package infra
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestLoadConfigDefaultStorageBaseURL(t *testing.T) {
 	t.Setenv("DATABASE_URL", "postgres://example")
@@ -80,3 +87,475 @@ func TestLoadConfigMergesExplicitAllowlist(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadConfigTrustsOnlyStorageAndExplicitHosts(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("STORAGE_BASE_URL", "http://minio:9000/assets")
+	t.Setenv("IMAGE_SOURCE_HOST_ALLOWLIST", "media.example.com")
+	t.Setenv("TRUSTED_INTERNAL_HOSTS", " Thumbs.Internal ")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if want := []string{"minio", "thumbs.internal"}; !slices.Equal(cfg.TrustedHosts, want) {
+		t.Fatalf("TrustedHosts = %#v, want %#v", cfg.TrustedHosts, want)
+	}
+}
+
+func TestLoadConfigParsesUploadLimits(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("MAX_UPLOAD_MB", "8")
+	t.Setenv("MAX_UPLOAD_MB_BY_PLAN", "Supporter=40, pro=20, broken, free=0")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.MaxUploadMB != 8 {
+		t.Fatalf("MaxUploadMB = %d, want 8", cfg.MaxUploadMB)
+	}
+	if len(cfg.PlanMaxUploadMB) != 2 || cfg.PlanMaxUploadMB["supporter"] != 40 || cfg.PlanMaxUploadMB["pro"] != 20 {
+		t.Fatalf("PlanMaxUploadMB mismatch: %#v", cfg.PlanMaxUploadMB)
+	}
+}
+
+func TestLoadConfigParsesPlanProviders(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("PLAN_PROVIDER_ALLOWLIST", "Free=qwen-image-plus| Qwen ,supporter=*,broken")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	free := cfg.PlanProviders["free"]
+	if len(free) != 2 || free[0] != "qwen-image-plus" || free[1] != "qwen" {
+		t.Fatalf("free providers = %#v", free)
+	}
+	if got := cfg.PlanProviders["supporter"]; len(got) != 1 || got[0] != "*" {
+		t.Fatalf("supporter providers = %#v", got)
+	}
+	if len(cfg.PlanProviders) != 2 {
+		t.Fatalf("PlanProviders = %#v", cfg.PlanProviders)
+	}
+}
+
+func TestLoadConfigDefaultPlanProvidersIncludeConfiguredModels(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("QWEN_MODEL", "Qwen-Image-Max")
+	t.Setenv("QWEN_VIDEO_MODEL", "wan2.2-t2v-plus")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	free := cfg.PlanProviders["free"]
+	for _, want := range []string{"qwen", "qwen-image-plus", "qwen-image-edit", "wan", "qwen-image-max", "wan2.2-t2v-plus"} {
+		if !slices.Contains(free, want) {
+			t.Fatalf("free providers = %#v, missing %q", free, want)
+		}
+	}
+	if len(cfg.PlanProviders) != 1 {
+		t.Fatalf("PlanProviders = %#v, want only free restricted", cfg.PlanProviders)
+	}
+}
+
+func TestLoadConfigSyntheticFallbackDefaults(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	cases := []struct {
+		env, override string
+		want          bool
+	}{
+		{env: "development", want: true},
+		{env: "production", want: false},
+		{env: "prod", want: false},
+		{env: "production", override: "true", want: true},
+		{env: "development", override: "false", want: false},
+	}
+	for _, tc := range cases {
+		t.Setenv("APP_ENV", tc.env)
+		t.Setenv("SYNTHETIC_FALLBACK", tc.override)
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig returned error: %v", err)
+		}
+		if cfg.SyntheticFallback != tc.want {
+			t.Fatalf("APP_ENV=%s SYNTHETIC_FALLBACK=%q: SyntheticFallback = %v, want %v", tc.env, tc.override, cfg.SyntheticFallback, tc.want)
+		}
+	}
+}
+
+func TestLoadConfigGoogleClientIDs(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("GOOGLE_CLIENT_ID", "web.apps.googleusercontent.com, android.apps.googleusercontent.com,")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.GoogleClientIDs) != 2 || cfg.GoogleClientIDs[0] != "web.apps.googleusercontent.com" || cfg.GoogleClientIDs[1] != "android.apps.googleusercontent.com" {
+		t.Fatalf("GoogleClientIDs mismatch: %#v", cfg.GoogleClientIDs)
+	}
+}
+
+func TestLoadConfigPromptProviderChain(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	cases := []struct {
+		chain    string
+		provider string
+		want     string
+	}{
+		{chain: "", provider: "gemini", want: "gemini,openai,static"},
+		{chain: "", provider: "openai", want: "openai,gemini,static"},
+		{chain: "", provider: "static", want: "static"},
+		{chain: "OpenAI, gemini", provider: "gemini", want: "openai,gemini,static"},
+		{chain: "openai,static", provider: "gemini", want: "openai,static"},
+	}
+	for _, tc := range cases {
+		t.Setenv("PROMPT_PROVIDER_CHAIN", tc.chain)
+		t.Setenv("PROMPT_PROVIDER", tc.provider)
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("chain %q: LoadConfig returned error: %v", tc.chain, err)
+		}
+		if got := strings.Join(cfg.PromptProviderChain, ","); got != tc.want {
+			t.Fatalf("chain %q provider %q: got %q want %q", tc.chain, tc.provider, got, tc.want)
+		}
+	}
+
+	for _, chain := range []string{"openai,claude", "gemini,gemini", "static,openai"} {
+		t.Setenv("PROMPT_PROVIDER_CHAIN", chain)
+		if _, err := LoadConfig(); err == nil {
+			t.Fatalf("chain %q: expected validation error", chain)
+		}
+	}
+}
+
+func TestLoadConfigModerationDenylist(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	if err := os.WriteFile(path, []byte("# weapons\nfirearm\n\nexplicit content\n"), 0o600); err != nil {
+		t.Fatalf("write denylist: %v", err)
+	}
+	t.Setenv("MODERATION_DENYLIST", "gore, drugs")
+	t.Setenv("MODERATION_DENYLIST_FILE", path)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if got := strings.Join(cfg.ModerationDenylist, "|"); got != "gore|drugs|firearm|explicit content" {
+		t.Fatalf("ModerationDenylist mismatch: %q", got)
+	}
+
+	t.Setenv("MODERATION_DENYLIST_FILE", filepath.Join(t.TempDir(), "missing.txt"))
+	if _, err := LoadConfig(); err == nil {
+		t.Fatalf("expected error for missing denylist file")
+	}
+}
+
+func TestLoadConfigStorageDriver(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("STORAGE_DRIVER", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.StorageDriver != StorageDriverLocal {
+		t.Fatalf("StorageDriver = %q, want local", cfg.StorageDriver)
+	}
+
+	t.Setenv("STORAGE_DRIVER", "S3")
+	t.Setenv("S3_ENDPOINT", "https://s3.example.com")
+	t.Setenv("S3_BUCKET", "assets")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY") {
+		t.Fatalf("expected missing credentials error, got %v", err)
+	}
+	t.Setenv("S3_ACCESS_KEY_ID", "key")
+	t.Setenv("S3_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("STORAGE_BASE_URL", "")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "STORAGE_BASE_URL") {
+		t.Fatalf("expected missing STORAGE_BASE_URL error, got %v", err)
+	}
+	t.Setenv("STORAGE_BASE_URL", "https://cdn.example.com/assets")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.StorageDriver != StorageDriverS3 || cfg.S3Region != "us-east-1" {
+		t.Fatalf("driver = %q region = %q", cfg.StorageDriver, cfg.S3Region)
+	}
+
+	t.Setenv("STORAGE_DRIVER", "gcs")
+	if _, err := LoadConfig(); err == nil {
+		t.Fatal("expected unknown driver error")
+	}
+}
+
+func TestLoadConfigAssetRetention(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("ASSET_RETENTION_DAYS_BY_PLAN", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.AssetRetentionDays) != 0 {
+		t.Fatalf("default AssetRetentionDays = %#v, want retention off", cfg.AssetRetentionDays)
+	}
+
+	t.Setenv("ASSET_RETENTION_DAYS_BY_PLAN", "Free=14, supporter=365, pro=0")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.AssetRetentionDays) != 2 || cfg.AssetRetentionDays["free"] != 14 || cfg.AssetRetentionDays["supporter"] != 365 {
+		t.Fatalf("AssetRetentionDays = %#v", cfg.AssetRetentionDays)
+	}
+}
+
+func TestLoadConfigPlanMaxQuantity(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("MAX_QUANTITY_BY_PLAN", "")
+	t.Setenv("DEFAULT_IMAGE_QUANTITY", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.DefaultImageQuantity != 1 {
+		t.Fatalf("default DefaultImageQuantity = %d, want 1", cfg.DefaultImageQuantity)
+	}
+	if len(cfg.PlanMaxQuantity) != 3 || cfg.PlanMaxQuantity["free"] != 2 || cfg.PlanMaxQuantity["pro"] != 8 || cfg.PlanMaxQuantity["supporter"] != 8 {
+		t.Fatalf("default PlanMaxQuantity = %#v", cfg.PlanMaxQuantity)
+	}
+
+	t.Setenv("MAX_QUANTITY_BY_PLAN", "free=1,Pro=12")
+	t.Setenv("DEFAULT_IMAGE_QUANTITY", "2")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.DefaultImageQuantity != 2 || len(cfg.PlanMaxQuantity) != 2 || cfg.PlanMaxQuantity["pro"] != 12 {
+		t.Fatalf("PlanMaxQuantity = %#v default = %d", cfg.PlanMaxQuantity, cfg.DefaultImageQuantity)
+	}
+}
+
+func TestLoadConfigPromptTextLimits(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("PROMPT_TITLE_MAX_CHARS", "")
+	t.Setenv("PROMPT_INSTRUCTIONS_MAX_CHARS", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.TitleMaxChars != 150 || cfg.InstructionsMaxChars != 2000 {
+		t.Fatalf("defaults = %d/%d, want 150/2000", cfg.TitleMaxChars, cfg.InstructionsMaxChars)
+	}
+
+	t.Setenv("PROMPT_TITLE_MAX_CHARS", "80")
+	t.Setenv("PROMPT_INSTRUCTIONS_MAX_CHARS", "500")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.TitleMaxChars != 80 || cfg.InstructionsMaxChars != 500 {
+		t.Fatalf("TitleMaxChars = %d InstructionsMaxChars = %d", cfg.TitleMaxChars, cfg.InstructionsMaxChars)
+	}
+}
+
+func TestLoadConfigSourceImageLimits(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("SOURCE_IMAGE_MAX_MB", "")
+	t.Setenv("SOURCE_IMAGE_MAX_MB_BY_PLAN", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.SourceImageMaxMB != 20 {
+		t.Fatalf("default SourceImageMaxMB = %d, want 20", cfg.SourceImageMaxMB)
+	}
+	if cfg.PlanSourceImageMB["free"] != 10 || cfg.PlanSourceImageMB["pro"] != 30 {
+		t.Fatalf("default PlanSourceImageMB = %#v", cfg.PlanSourceImageMB)
+	}
+
+	t.Setenv("SOURCE_IMAGE_MAX_MB", "8")
+	t.Setenv("SOURCE_IMAGE_MAX_MB_BY_PLAN", "Free=2")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.SourceImageMaxMB != 8 || len(cfg.PlanSourceImageMB) != 1 || cfg.PlanSourceImageMB["free"] != 2 {
+		t.Fatalf("SourceImageMaxMB = %d PlanSourceImageMB = %#v", cfg.SourceImageMaxMB, cfg.PlanSourceImageMB)
+	}
+}
+
+func TestLoadConfigImageConcurrency(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("IMAGE_CONCURRENCY", "")
+	t.Setenv("IMAGE_CONCURRENCY_PER_USER", "")
+	t.Setenv("IMAGE_CONCURRENCY_PER_USER_BY_PLAN", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.ImageConcurrency != 4 || cfg.UserImageConcurrency != 2 || len(cfg.PlanImageConcurrency) != 0 {
+		t.Fatalf("defaults = %d/%d/%#v", cfg.ImageConcurrency, cfg.UserImageConcurrency, cfg.PlanImageConcurrency)
+	}
+
+	t.Setenv("IMAGE_CONCURRENCY", "8")
+	t.Setenv("IMAGE_CONCURRENCY_PER_USER_BY_PLAN", "pro=4")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.ImageConcurrency != 8 || cfg.PlanImageConcurrency["pro"] != 4 {
+		t.Fatalf("ImageConcurrency = %d PlanImageConcurrency = %#v", cfg.ImageConcurrency, cfg.PlanImageConcurrency)
+	}
+
+	t.Setenv("IMAGE_CONCURRENCY", "0")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "IMAGE_CONCURRENCY") {
+		t.Fatalf("expected IMAGE_CONCURRENCY error, got %v", err)
+	}
+}
+
+func TestLoadConfigNegativePrompts(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("NEGATIVE_PROMPT_BY_CATEGORY", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if terms := cfg.NegativePrompts["food"]; len(terms) == 0 || !slices.Contains(terms, "burnt") {
+		t.Fatalf("default food negatives = %#v", terms)
+	}
+
+	t.Setenv("NEGATIVE_PROMPT_BY_CATEGORY", "Electronics=Fingerprints| glare")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.NegativePrompts) != 1 || !slices.Equal(cfg.NegativePrompts["electronics"], []string{"fingerprints", "glare"}) {
+		t.Fatalf("NegativePrompts = %#v", cfg.NegativePrompts)
+	}
+}
+
+func TestLoadConfigLocales(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("SUPPORTED_LOCALES", "")
+	t.Setenv("LOCALE_FALLBACKS", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.SupportedLocales) != 4 || cfg.SupportedLocales[2] != "ms" {
+		t.Fatalf("default SupportedLocales = %#v", cfg.SupportedLocales)
+	}
+	if chain := cfg.LocaleFallbacks["ms"]; len(chain) != 2 || chain[0] != "id" || chain[1] != "en" {
+		t.Fatalf("default LocaleFallbacks[ms] = %#v", chain)
+	}
+
+	t.Setenv("SUPPORTED_LOCALES", "id, en")
+	t.Setenv("LOCALE_FALLBACKS", "MS=id")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.SupportedLocales) != 2 || len(cfg.LocaleFallbacks) != 1 || cfg.LocaleFallbacks["ms"][0] != "id" {
+		t.Fatalf("SupportedLocales = %#v LocaleFallbacks = %#v", cfg.SupportedLocales, cfg.LocaleFallbacks)
+	}
+}
+
+func TestLoadConfigQwenRegionBaseURLs(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("QWEN_REGION_BASE_URLS", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.QwenRegionBaseURLs) != 2 || cfg.QwenRegionBaseURLs["cn"] != "https://dashscope.aliyuncs.com/api/v1" {
+		t.Fatalf("default QwenRegionBaseURLs = %#v", cfg.QwenRegionBaseURLs)
+	}
+
+	t.Setenv("QWEN_REGION_BASE_URLS", "SG=https://sg.example.com/api/v1")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.QwenRegionBaseURLs) != 1 || cfg.QwenRegionBaseURLs["sg"] != "https://sg.example.com/api/v1" {
+		t.Fatalf("QwenRegionBaseURLs = %#v", cfg.QwenRegionBaseURLs)
+	}
+
+	t.Setenv("QWEN_REGION_BASE_URLS", "cn=dashscope.aliyuncs.com")
+	if _, err := LoadConfig(); err == nil {
+		t.Fatalf("expected error for base URL without scheme")
+	}
+}
+
+func TestLoadConfigSyntheticVideoLength(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("SYNTHETIC_VIDEO_SECONDS", "6")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.SyntheticVideoLength != 6*time.Second {
+		t.Fatalf("SyntheticVideoLength = %v, want 6s", cfg.SyntheticVideoLength)
+	}
+
+	t.Setenv("SYNTHETIC_VIDEO_SECONDS", "600")
+	if _, err := LoadConfig(); err == nil {
+		t.Fatalf("expected error for synthetic video longer than a minute")
+	}
+}
+
+func TestLoadConfigCORSAllowedOrigins(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://example")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if got := strings.Join(cfg.CORSAllowedOrigins, ","); got != "http://localhost:3000,https://script.google.com" {
+		t.Fatalf("default CORSAllowedOrigins = %q", got)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", " https://app.example.com , *")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if got := strings.Join(cfg.CORSAllowedOrigins, ","); got != "https://app.example.com,*" {
+		t.Fatalf("CORSAllowedOrigins = %q", got)
+	}
+}
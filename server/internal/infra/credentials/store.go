@@ -2,9 +2,13 @@ package credentials
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"server/internal/infra"
 	"server/internal/sqlinline"
@@ -16,6 +20,17 @@ const (
 	ProviderQwen   = "qwen"
 )
 
+// ErrNoAPIKey is returned when rotating a provider that has no stored key.
+var ErrNoAPIKey = errors.New("no api key stored for provider")
+
+// KeyInfo describes a stored key without exposing it.
+type KeyInfo struct {
+	Provider               string
+	MaskedKey              string
+	PreviousKeyFingerprint string
+	UpdatedAt              time.Time
+}
+
 type Store struct {
 	sql infra.SQLExecutor
 }
@@ -84,3 +99,70 @@ func (s *Store) upsert(ctx context.Context, provider, token string, props map[st
 	_, err = s.sql.Exec(ctx, sqlinline.QUpsertIntegrationToken, provider, token, raw)
 	return err
 }
+
+func (s *Store) ListAPIKeys(ctx context.Context) ([]KeyInfo, error) {
+	rows, err := s.sql.Query(ctx, sqlinline.QListIntegrationTokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []KeyInfo
+	for rows.Next() {
+		var (
+			info  KeyInfo
+			token string
+		)
+		if err := rows.Scan(&info.Provider, &token, &info.PreviousKeyFingerprint, &info.UpdatedAt); err != nil {
+			return nil, err
+		}
+		info.MaskedKey = MaskKey(strings.TrimSpace(token))
+		keys = append(keys, info)
+	}
+	return keys, rows.Err()
+}
+
+// RotateAPIKey replaces the stored key for provider and records the
+// fingerprint of the key it replaced. It returns that fingerprint.
+func (s *Store) RotateAPIKey(ctx context.Context, provider, key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("%s api key is required", provider)
+	}
+	previous, err := s.Token(ctx, provider)
+	if err != nil {
+		return "", err
+	}
+	if previous == "" {
+		return "", fmt.Errorf("%w: %s", ErrNoAPIKey, provider)
+	}
+	fingerprint := Fingerprint(previous)
+	if _, err := s.sql.Exec(ctx, sqlinline.QRotateIntegrationToken, provider, key, fingerprint); err != nil {
+		return "", err
+	}
+	return fingerprint, nil
+}
+
+// DeleteAPIKey removes the stored key for provider and reports whether one
+// existed. Deleting a provider without a key is a no-op.
+func (s *Store) DeleteAPIKey(ctx context.Context, provider string) (bool, error) {
+	tag, err := s.sql.Exec(ctx, sqlinline.QDeleteIntegrationToken, provider)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MaskKey keeps the first and last four characters of key. Short keys are
+// masked entirely.
+func MaskKey(key string) string {
+	if len(key) <= 12 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + "****" + key[len(key)-4:]
+}
+
+// Fingerprint identifies a key in audit records without revealing it.
+func Fingerprint(key string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
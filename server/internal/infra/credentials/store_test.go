@@ -3,7 +3,11 @@ package credentials
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
+
+	"server/internal/sqlinline"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -12,6 +16,8 @@ import (
 type stubExecutor struct {
 	token string
 	err   error
+	rows  [][]any
+	tag   pgconn.CommandTag
 	exec  struct {
 		query string
 		args  []any
@@ -21,7 +27,7 @@ type stubExecutor struct {
 func (s *stubExecutor) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
 	s.exec.query = query
 	s.exec.args = args
-	return pgconn.CommandTag{}, s.err
+	return s.tag, s.err
 }
 
 func (s *stubExecutor) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
@@ -29,7 +35,37 @@ func (s *stubExecutor) QueryRow(ctx context.Context, query string, args ...any)
 }
 
 func (s *stubExecutor) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
-	return nil, errors.New("not implemented")
+	if query != sqlinline.QListIntegrationTokens {
+		return nil, errors.New("not implemented")
+	}
+	return &stubRows{rows: s.rows, idx: -1}, nil
+}
+
+type stubRows struct {
+	rows [][]any
+	idx  int
+}
+
+func (r *stubRows) Close()                                       {}
+func (r *stubRows) Err() error                                   { return nil }
+func (r *stubRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *stubRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *stubRows) Values() ([]any, error)                       { return r.rows[r.idx], nil }
+func (r *stubRows) RawValues() [][]byte                          { return nil }
+func (r *stubRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *stubRows) Next() bool {
+	r.idx++
+	return r.idx < len(r.rows)
+}
+
+func (r *stubRows) Scan(dest ...any) error {
+	row := r.rows[r.idx]
+	*dest[0].(*string) = row[0].(string)
+	*dest[1].(*string) = row[1].(string)
+	*dest[2].(*string) = row[2].(string)
+	*dest[3].(*time.Time) = row[3].(time.Time)
+	return nil
 }
 
 type stubRow struct {
@@ -169,3 +205,90 @@ func TestSetQwenAPIKeyEmpty(t *testing.T) {
 		t.Fatal("expected error for empty key")
 	}
 }
+
+func TestListAPIKeysMasksTokens(t *testing.T) {
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewStore(&stubExecutor{rows: [][]any{
+		{ProviderGemini, "AIzaSyD-example-secret-9f3k", "", updated},
+		{ProviderOpenAI, "sk-short", "sha256:0011223344556677", updated},
+	}})
+	keys, err := store.ListAPIKeys(context.Background())
+	if err != nil {
+		t.Fatalf("ListAPIKeys error: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(keys))
+	}
+	if keys[0].MaskedKey != "AIza****9f3k" {
+		t.Fatalf("expected masked gemini key, got %q", keys[0].MaskedKey)
+	}
+	if keys[1].MaskedKey != "********" {
+		t.Fatalf("expected short key fully masked, got %q", keys[1].MaskedKey)
+	}
+	for _, key := range keys {
+		if strings.Contains(key.MaskedKey, "secret") || strings.Contains(key.MaskedKey, "short") {
+			t.Fatalf("masked key leaks secret: %q", key.MaskedKey)
+		}
+	}
+	if keys[1].PreviousKeyFingerprint != "sha256:0011223344556677" || !keys[1].UpdatedAt.Equal(updated) {
+		t.Fatalf("unexpected key info: %+v", keys[1])
+	}
+}
+
+func TestRotateAPIKeyRecordsPreviousFingerprint(t *testing.T) {
+	exec := &stubExecutor{token: "old-secret"}
+	store := NewStore(exec)
+	fingerprint, err := store.RotateAPIKey(context.Background(), ProviderGemini, " new-secret ")
+	if err != nil {
+		t.Fatalf("RotateAPIKey error: %v", err)
+	}
+	if fingerprint != Fingerprint("old-secret") || fingerprint == Fingerprint("new-secret") {
+		t.Fatalf("unexpected fingerprint %q", fingerprint)
+	}
+	if !strings.HasPrefix(fingerprint, "sha256:") || strings.Contains(fingerprint, "old-secret") {
+		t.Fatalf("fingerprint should not reveal the key: %q", fingerprint)
+	}
+	if exec.exec.query != sqlinline.QRotateIntegrationToken {
+		t.Fatalf("expected rotate query, got %q", exec.exec.query)
+	}
+	if len(exec.exec.args) != 3 || exec.exec.args[0] != ProviderGemini || exec.exec.args[1] != "new-secret" || exec.exec.args[2] != fingerprint {
+		t.Fatalf("unexpected rotate args: %v", exec.exec.args)
+	}
+}
+
+func TestRotateAPIKeyRequiresExistingKey(t *testing.T) {
+	exec := &stubExecutor{err: pgx.ErrNoRows}
+	store := NewStore(exec)
+	if _, err := store.RotateAPIKey(context.Background(), ProviderOpenAI, "new-secret"); !errors.Is(err, ErrNoAPIKey) {
+		t.Fatalf("expected ErrNoAPIKey, got %v", err)
+	}
+	if exec.exec.query != "" {
+		t.Fatalf("rotate should not write when no key exists")
+	}
+}
+
+func TestDeleteAPIKey(t *testing.T) {
+	exec := &stubExecutor{tag: pgconn.NewCommandTag("DELETE 1")}
+	store := NewStore(exec)
+	deleted, err := store.DeleteAPIKey(context.Background(), ProviderGemini)
+	if err != nil {
+		t.Fatalf("DeleteAPIKey error: %v", err)
+	}
+	if !deleted {
+		t.Fatal("expected key to be reported as deleted")
+	}
+	if exec.exec.query != sqlinline.QDeleteIntegrationToken || len(exec.exec.args) != 1 || exec.exec.args[0] != ProviderGemini {
+		t.Fatalf("unexpected delete call: %q %v", exec.exec.query, exec.exec.args)
+	}
+}
+
+func TestDeleteAPIKeyMissingProviderIsNoop(t *testing.T) {
+	store := NewStore(&stubExecutor{tag: pgconn.NewCommandTag("DELETE 0")})
+	deleted, err := store.DeleteAPIKey(context.Background(), ProviderOpenAI)
+	if err != nil {
+		t.Fatalf("DeleteAPIKey error: %v", err)
+	}
+	if deleted {
+		t.Fatal("expected no key to be deleted")
+	}
+}
@@ -3,6 +3,9 @@ package google
 import (
 	"context"
 	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
@@ -10,11 +13,15 @@ import (
 	"errors"
 	"math/big"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// defaultKeyCacheTTL applies when Google sends no usable max-age.
+const defaultKeyCacheTTL = time.Hour
+
 type jwks struct {
 	Keys []jwk `json:"keys"`
 }
@@ -25,22 +32,33 @@ type jwk struct {
 	Alg string `json:"alg"`
 	N   string `json:"n"`
 	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
 }
 
 type Verifier struct {
 	issuer     string
-	clientID   string
+	audiences  map[string]struct{}
 	mu         sync.RWMutex
-	cache      map[string]*rsa.PublicKey
-	fetched    time.Time
+	cache      map[string]crypto.PublicKey
+	expires    time.Time
 	httpClient *http.Client
 }
 
-func NewVerifier(issuer, clientID string) *Verifier {
+// NewVerifier accepts ID tokens whose aud is any of clientIDs, so web and
+// mobile OAuth clients of the same project can share one backend.
+func NewVerifier(issuer string, clientIDs ...string) *Verifier {
+	audiences := make(map[string]struct{}, len(clientIDs))
+	for _, id := range clientIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			audiences[id] = struct{}{}
+		}
+	}
 	return &Verifier{
 		issuer:     issuer,
-		clientID:   clientID,
-		cache:      make(map[string]*rsa.PublicKey),
+		audiences:  audiences,
+		cache:      make(map[string]crypto.PublicKey),
 		httpClient: &http.Client{Timeout: 10 * time.Second},
 	}
 }
@@ -64,27 +82,46 @@ func (v *Verifier) VerifyIDToken(ctx context.Context, token string) (map[string]
 			return nil, errors.New("unknown kid")
 		}
 	}
-	hashed := sha256.Sum256([]byte(signingInput))
-	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature); err != nil {
+	alg, _ := header["alg"].(string)
+	if err := verifySignature(alg, key, signingInput, signature); err != nil {
 		return nil, err
 	}
 	if iss, _ := payload["iss"].(string); iss != v.issuer {
 		return nil, errors.New("invalid issuer")
 	}
-	if !audienceMatches(payload["aud"], v.clientID) {
+	if !audienceAllowed(payload["aud"], v.audiences) {
 		return nil, errors.New("invalid audience")
 	}
+	if err := checkTokenTimes(payload, time.Now()); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// clockSkew tolerates drift between Google's clock and ours for nbf and iat.
+const clockSkew = 60 * time.Second
+
+// checkTokenTimes enforces exp, nbf and iat. Only exp is required by Google,
+// the other claims are checked when present.
+func checkTokenTimes(payload map[string]any, now time.Time) error {
 	if exp, ok := payload["exp"].(float64); ok {
-		if time.Now().Unix() > int64(exp) {
-			return nil, errors.New("token expired")
+		if now.Unix() > int64(exp) {
+			return errors.New("token expired")
 		}
 	}
-	return payload, nil
+	leeway := now.Add(clockSkew).Unix()
+	if nbf, ok := payload["nbf"].(float64); ok && int64(nbf) > leeway {
+		return errors.New("token not yet valid")
+	}
+	if iat, ok := payload["iat"].(float64); ok && int64(iat) > leeway {
+		return errors.New("token issued in the future")
+	}
+	return nil
 }
 
 func (v *Verifier) ensureKeys(ctx context.Context) error {
 	v.mu.RLock()
-	fresh := time.Since(v.fetched) < time.Hour && len(v.cache) > 0
+	fresh := time.Now().Before(v.expires) && len(v.cache) > 0
 	v.mu.RUnlock()
 	if fresh {
 		return nil
@@ -92,8 +129,11 @@ func (v *Verifier) ensureKeys(ctx context.Context) error {
 	return v.refresh(ctx)
 }
 
+// refresh reloads the signing keys. They are cached for the shorter of the
+// max-age values Google sends on the discovery document and the JWKS,
+// or defaultKeyCacheTTL when neither response carries one.
 func (v *Verifier) refresh(ctx context.Context) error {
-	cfg, err := v.fetchConfig(ctx)
+	cfg, configTTL, err := v.fetchConfig(ctx)
 	if err != nil {
 		return err
 	}
@@ -112,12 +152,27 @@ func (v *Verifier) refresh(ctx context.Context) error {
 	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
 		return err
 	}
-	keys := make(map[string]*rsa.PublicKey)
+	ttl := defaultKeyCacheTTL
+	if jwksTTL, ok := maxAge(resp.Header); ok {
+		ttl = jwksTTL
+	}
+	if configTTL > 0 && configTTL < ttl {
+		ttl = configTTL
+	}
+	keys := make(map[string]crypto.PublicKey)
 	for _, key := range set.Keys {
-		if key.Kty != "RSA" {
+		var (
+			pub crypto.PublicKey
+			err error
+		)
+		switch key.Kty {
+		case "RSA":
+			pub, err = rsaKeyFromJWK(key)
+		case "EC":
+			pub, err = ecKeyFromJWK(key)
+		default:
 			continue
 		}
-		pub, err := rsaKeyFromJWK(key)
 		if err != nil {
 			continue
 		}
@@ -128,21 +183,23 @@ func (v *Verifier) refresh(ctx context.Context) error {
 	}
 	v.mu.Lock()
 	v.cache = keys
-	v.fetched = time.Now()
+	v.expires = time.Now().Add(ttl)
 	v.mu.Unlock()
 	return nil
 }
 
+// fetchConfig loads the discovery document and its max-age, or zero when
+// the response carries none.
 func (v *Verifier) fetchConfig(ctx context.Context) (*struct {
 	JWKSURI string `json:"jwks_uri"`
-}, error) {
+}, time.Duration, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.issuer+"/.well-known/openid-configuration", nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	resp, err := v.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -151,12 +208,29 @@ func (v *Verifier) fetchConfig(ctx context.Context) (*struct {
 		JWKSURI string `json:"jwks_uri"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&cfg); err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	ttl, _ := maxAge(resp.Header)
+	return &cfg, ttl, nil
+}
+
+// maxAge extracts a positive max-age directive from Cache-Control.
+func maxAge(h http.Header) (time.Duration, bool) {
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(directive), "=")
+		if !ok || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err != nil || seconds <= 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
 	}
-	return &cfg, nil
+	return 0, false
 }
 
-func (v *Verifier) keyFor(kid string) (*rsa.PublicKey, bool) {
+func (v *Verifier) keyFor(kid string) (crypto.PublicKey, bool) {
 	v.mu.RLock()
 	defer v.mu.RUnlock()
 	pk, ok := v.cache[kid]
@@ -182,6 +256,61 @@ func rsaKeyFromJWK(j jwk) (*rsa.PublicKey, error) {
 	return &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: e}, nil
 }
 
+// ecKeyFromJWK parses a P-256 key, rejecting points that are not on the
+// curve.
+func ecKeyFromJWK(j jwk) (*ecdsa.PublicKey, error) {
+	if j.Crv != "P-256" {
+		return nil, errors.New("unsupported curve")
+	}
+	x, err := base64.RawURLEncoding.DecodeString(j.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(j.Y)
+	if err != nil {
+		return nil, err
+	}
+	if len(x) != 32 || len(y) != 32 {
+		return nil, errors.New("invalid ec coordinates")
+	}
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
+
+// verifySignature checks signature with the algorithm named in the token
+// header; the key type must match so an RSA key cannot verify an ES256
+// token or vice versa.
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	hashed := sha256.Sum256([]byte(signingInput))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key does not match RS256")
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], signature)
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("key does not match ES256")
+		}
+		if len(signature) != 64 {
+			return errors.New("invalid ES256 signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, hashed[:], r, s) {
+			return errors.New("invalid ES256 signature")
+		}
+		return nil
+	default:
+		return errors.New("unsupported signing algorithm")
+	}
+}
+
 func parseJWT(token string) (map[string]any, map[string]any, []byte, string, error) {
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
@@ -210,19 +339,25 @@ func parseJWT(token string) (map[string]any, map[string]any, []byte, string, err
 	return header, payload, signature, parts[0] + "." + parts[1], nil
 }
 
-func audienceMatches(aud any, clientID string) bool {
+// audienceAllowed reports whether the aud claim, a string or a list, names
+// one of the allowed client IDs.
+func audienceAllowed(aud any, allowed map[string]struct{}) bool {
+	matches := func(s string) bool {
+		_, ok := allowed[s]
+		return ok
+	}
 	switch v := aud.(type) {
 	case string:
-		return v == clientID
+		return matches(v)
 	case []any:
 		for _, item := range v {
-			if s, ok := item.(string); ok && s == clientID {
+			if s, ok := item.(string); ok && matches(s) {
 				return true
 			}
 		}
 	case []string:
 		for _, item := range v {
-			if item == clientID {
+			if matches(item) {
 				return true
 			}
 		}
@@ -1,6 +1,21 @@
 package google
 
-import "testing"
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
 
 func TestAudienceMatches(t *testing.T) {
 	cases := []struct {
@@ -17,8 +32,207 @@ func TestAudienceMatches(t *testing.T) {
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			if got := audienceMatches(tc.aud, tc.clientID); got != tc.want {
-				t.Fatalf("audienceMatches(%v, %q) = %v, want %v", tc.aud, tc.clientID, got, tc.want)
+			if got := audienceAllowed(tc.aud, NewVerifier("", tc.clientID).audiences); got != tc.want {
+				t.Fatalf("audienceAllowed(%v, %q) = %v, want %v", tc.aud, tc.clientID, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifierAcceptsEachConfiguredAudience(t *testing.T) {
+	v := NewVerifier("https://accounts.google.com", "web-client", " android-client ", "")
+	cases := []struct {
+		aud  any
+		want bool
+	}{
+		{aud: "web-client", want: true},
+		{aud: "android-client", want: true},
+		{aud: []any{"unknown", "android-client"}, want: true},
+		{aud: "ios-client", want: false},
+		{aud: "", want: false},
+	}
+	for _, tc := range cases {
+		if got := audienceAllowed(tc.aud, v.audiences); got != tc.want {
+			t.Fatalf("audienceAllowed(%v) = %v, want %v", tc.aud, got, tc.want)
+		}
+	}
+}
+
+func TestCheckTokenTimes(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	at := func(d time.Duration) float64 { return float64(now.Add(d).Unix()) }
+	cases := []struct {
+		name    string
+		payload map[string]any
+		wantErr bool
+	}{
+		{name: "valid", payload: map[string]any{"exp": at(time.Hour), "iat": at(-time.Minute), "nbf": at(-time.Minute)}, wantErr: false},
+		{name: "expired", payload: map[string]any{"exp": at(-time.Second)}, wantErr: true},
+		{name: "not yet valid", payload: map[string]any{"exp": at(2 * time.Hour), "nbf": at(time.Hour)}, wantErr: true},
+		{name: "issued in future", payload: map[string]any{"exp": at(2 * time.Hour), "iat": at(10 * time.Minute)}, wantErr: true},
+		{name: "within skew", payload: map[string]any{"exp": at(time.Hour), "iat": at(clockSkew / 2), "nbf": at(clockSkew / 2)}, wantErr: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkTokenTimes(tc.payload, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("checkTokenTimes() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func rsaJWK(t *testing.T, kid string) (*rsa.PrivateKey, jwk) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	return key, jwk{
+		Kid: kid,
+		Kty: "RSA",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(t *testing.T, kid string) (*ecdsa.PrivateKey, jwk) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec key: %v", err)
+	}
+	return key, jwk{
+		Kid: kid,
+		Kty: "EC",
+		Alg: "ES256",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+// newJWKSServer serves a discovery document and the given keys with the
+// given Cache-Control headers.
+func newJWKSServer(t *testing.T, configCache, jwksCache string, keys ...jwk) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		if configCache != "" {
+			w.Header().Set("Cache-Control", configCache)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"jwks_uri": srv.URL + "/certs"})
+	})
+	mux.HandleFunc("/certs", func(w http.ResponseWriter, r *http.Request) {
+		if jwksCache != "" {
+			w.Header().Set("Cache-Control", jwksCache)
+		}
+		_ = json.NewEncoder(w).Encode(jwks{Keys: keys})
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// signToken builds a compact JWT; sign receives the SHA-256 of the signing
+// input.
+func signToken(t *testing.T, header, payload map[string]any, sign func(digest []byte) []byte) string {
+	t.Helper()
+	headerJSON, _ := json.Marshal(header)
+	payloadJSON, _ := json.Marshal(payload)
+	input := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payloadJSON)
+	digest := sha256.Sum256([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign(digest[:]))
+}
+
+func TestRefreshHonoursCacheControlMaxAge(t *testing.T) {
+	cases := []struct {
+		name        string
+		configCache string
+		jwksCache   string
+		want        time.Duration
+	}{
+		{name: "jwks max-age", configCache: "", jwksCache: "public, max-age=19800, must-revalidate, no-transform", want: 19800 * time.Second},
+		{name: "shorter discovery max-age", configCache: "public, max-age=3600", jwksCache: "public, max-age=19800", want: time.Hour},
+		{name: "shorter jwks max-age", configCache: "max-age=7200", jwksCache: "max-age=300", want: 300 * time.Second},
+		{name: "no headers", want: defaultKeyCacheTTL},
+		{name: "invalid max-age", jwksCache: "max-age=soon", want: defaultKeyCacheTTL},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, key := rsaJWK(t, "key-1")
+			srv := newJWKSServer(t, tc.configCache, tc.jwksCache, key)
+			v := NewVerifier(srv.URL, "client")
+			start := time.Now()
+			if err := v.refresh(context.Background()); err != nil {
+				t.Fatalf("refresh: %v", err)
+			}
+			if _, ok := v.keyFor("key-1"); !ok {
+				t.Fatalf("key-1 not cached")
+			}
+			lower, upper := start.Add(tc.want), time.Now().Add(tc.want)
+			if v.expires.Before(lower) || v.expires.After(upper) {
+				t.Fatalf("expires = %v, want within [%v, %v]", v.expires, lower, upper)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRS256AndES256(t *testing.T) {
+	rsaKey, rsaPub := rsaJWK(t, "rsa-1")
+	ecKey, ecPub := ecJWK(t, "ec-1")
+	srv := newJWKSServer(t, "", "", rsaPub, ecPub)
+	v := NewVerifier(srv.URL, "client")
+	payload := map[string]any{
+		"iss": srv.URL,
+		"aud": "client",
+		"sub": "google-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	signRSA := func(digest []byte) []byte {
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest)
+		if err != nil {
+			t.Fatalf("rsa sign: %v", err)
+		}
+		return sig
+	}
+	signEC := func(digest []byte) []byte {
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest)
+		if err != nil {
+			t.Fatalf("ec sign: %v", err)
+		}
+		return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	cases := []struct {
+		name    string
+		header  map[string]any
+		sign    func([]byte) []byte
+		wantErr bool
+	}{
+		{name: "RS256", header: map[string]any{"alg": "RS256", "kid": "rsa-1"}, sign: signRSA},
+		{name: "ES256", header: map[string]any{"alg": "ES256", "kid": "ec-1"}, sign: signEC},
+		{name: "ES256 header on RSA key", header: map[string]any{"alg": "ES256", "kid": "rsa-1"}, sign: signEC, wantErr: true},
+		{name: "RS256 header on EC key", header: map[string]any{"alg": "RS256", "kid": "ec-1"}, sign: signRSA, wantErr: true},
+		{name: "unsupported alg", header: map[string]any{"alg": "none", "kid": "rsa-1"}, sign: signRSA, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := signToken(t, tc.header, payload, tc.sign)
+			claims, err := v.VerifyIDToken(context.Background(), token)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("VerifyIDToken() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyIDToken() error: %v", err)
+			}
+			if claims["sub"] != "google-1" {
+				t.Fatalf("claims = %v", claims)
 			}
 		})
 	}
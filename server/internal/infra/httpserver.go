@@ -2,6 +2,7 @@ package infra
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -9,7 +10,8 @@ import (
 // Server adalah wrapper untuk http.Server.
 type Server struct {
 	*http.Server
-	cfg *Config
+	cfg    *Config
+	drains []func(context.Context) error
 }
 
 // NewHTTPServer membuat instance Server baru.
@@ -39,7 +41,18 @@ func (s *Server) Start() error {
 	return s.ListenAndServe()
 }
 
-// Shutdown mematikan server dengan graceful shutdown.
+// RegisterDrain menambahkan fungsi yang ditunggu Shutdown setelah listener
+// ditutup, misalnya untuk menunggu pekerjaan di luar handler selesai.
+func (s *Server) RegisterDrain(fn func(context.Context) error) {
+	s.drains = append(s.drains, fn)
+}
+
+// Shutdown mematikan server dengan graceful shutdown, lalu menjalankan setiap
+// drain yang terdaftar. Semuanya dibatasi oleh deadline ctx.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.Server.Shutdown(ctx)
+	errs := []error{s.Server.Shutdown(ctx)}
+	for _, drain := range s.drains {
+		errs = append(errs, drain(ctx))
+	}
+	return errors.Join(errs...)
 }
@@ -32,42 +32,94 @@ func NewSQLRunner(pool *pgxpool.Pool, logger zerolog.Logger) *SQLRunner {
 }
 
 func (r *SQLRunner) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return execLogged(ctx, r.Pool, r.Logger, query, args...)
+}
+
+func (r *SQLRunner) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	return queryRowLogged(ctx, r.Pool, r.Logger, query, args...)
+}
+
+func (r *SQLRunner) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return queryLogged(ctx, r.Pool, r.Logger, query, args...)
+}
+
+// InTx runs fn inside a single transaction, committing when fn returns nil
+// and rolling back otherwise.
+func (r *SQLRunner) InTx(ctx context.Context, fn func(SQLExecutor) error) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if err := fn(&txRunner{tx: tx, logger: r.Logger}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// querier is the subset of pgx shared by pools and transactions.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+type txRunner struct {
+	tx     pgx.Tx
+	logger zerolog.Logger
+}
+
+func (t *txRunner) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return execLogged(ctx, t.tx, t.logger, query, args...)
+}
+
+func (t *txRunner) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	return queryRowLogged(ctx, t.tx, t.logger, query, args...)
+}
+
+func (t *txRunner) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return queryLogged(ctx, t.tx, t.logger, query, args...)
+}
+
+func execLogged(ctx context.Context, db querier, logger zerolog.Logger, query string, args ...any) (pgconn.CommandTag, error) {
 	marker, trimmed, err := extractMarker(query)
 	if err != nil {
 		return pgconn.CommandTag{}, err
 	}
-	r.Logger.Info().Msgf("sql[%s] exec", marker)
-	tag, err := r.Pool.Exec(ctx, trimmed, args...)
+	logger.Info().Msgf("sql[%s] exec", marker)
+	tag, err := db.Exec(ctx, trimmed, args...)
 	if err != nil {
-		r.Logger.Error().Err(err).Msgf("sql[%s] error", marker)
+		logger.Error().Err(err).Msgf("sql[%s] error", marker)
 		return tag, err
 	}
-	r.Logger.Info().Msgf("sql[%s] ok", marker)
+	logger.Info().Msgf("sql[%s] ok", marker)
 	return tag, nil
 }
 
-func (r *SQLRunner) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+func queryRowLogged(ctx context.Context, db querier, logger zerolog.Logger, query string, args ...any) pgx.Row {
 	marker, trimmed, err := extractMarker(query)
 	if err != nil {
 		return errorRow{err: err}
 	}
-	r.Logger.Info().Msgf("sql[%s] query_row", marker)
-	row := r.Pool.QueryRow(ctx, trimmed, args...)
-	return loggingRow{row: row, logger: r.Logger, marker: marker}
+	logger.Info().Msgf("sql[%s] query_row", marker)
+	row := db.QueryRow(ctx, trimmed, args...)
+	return loggingRow{row: row, logger: logger, marker: marker}
 }
 
-func (r *SQLRunner) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+func queryLogged(ctx context.Context, db querier, logger zerolog.Logger, query string, args ...any) (pgx.Rows, error) {
 	marker, trimmed, err := extractMarker(query)
 	if err != nil {
 		return nil, err
 	}
-	r.Logger.Info().Msgf("sql[%s] query", marker)
-	rows, err := r.Pool.Query(ctx, trimmed, args...)
+	logger.Info().Msgf("sql[%s] query", marker)
+	rows, err := db.Query(ctx, trimmed, args...)
 	if err != nil {
-		r.Logger.Error().Err(err).Msgf("sql[%s] error", marker)
+		logger.Error().Err(err).Msgf("sql[%s] error", marker)
 		return nil, err
 	}
-	return loggingRows{Rows: rows, logger: r.Logger, marker: marker}, nil
+	return loggingRows{Rows: rows, logger: logger, marker: marker}, nil
 }
 
 type loggingRow struct {
@@ -120,7 +172,10 @@ func extractMarker(query string) (string, string, error) {
 	return strings.TrimSpace(strings.TrimPrefix(markerLine, "--sql ")), strings.Join(lines[1:], "\n"), nil
 }
 
-var _ SQLExecutor = (*SQLRunner)(nil)
+var (
+	_ SQLExecutor = (*SQLRunner)(nil)
+	_ SQLExecutor = (*txRunner)(nil)
+)
 
 // IsNoRows reports whether the provided error indicates that a query returned
 // no rows. It mirrors pgx.ErrNoRows but keeps the dependency contained within
@@ -0,0 +1,32 @@
+package metrics
+
+import "time"
+
+// Default is the registry served on /metrics by the API and the worker.
+var Default = NewRegistry()
+
+var (
+	// JobsEnqueued counts generation jobs accepted by the API.
+	JobsEnqueued = Default.CounterVec("umkm_jobs_enqueued_total", "Generation jobs enqueued.", "task", "provider")
+	// JobsSucceeded counts generation jobs that produced output.
+	JobsSucceeded = Default.CounterVec("umkm_jobs_succeeded_total", "Generation jobs that succeeded.", "task", "provider")
+	// JobsFailed counts generation jobs that failed for good, after retries.
+	JobsFailed = Default.CounterVec("umkm_jobs_failed_total", "Generation jobs that failed after all attempts.", "task", "provider")
+	// EnhancerFallbacks counts prompt enhancer fallbacks by reason.
+	EnhancerFallbacks = Default.CounterVec("umkm_prompt_enhancer_fallbacks_total", "Prompt enhancer fallbacks to the next provider.", "provider", "reason")
+	// GenerationSeconds records how long each generation attempt took.
+	GenerationSeconds = Default.HistogramVec("umkm_generation_duration_seconds", "Generation latency per attempt.",
+		[]float64{1, 2.5, 5, 10, 20, 30, 60, 90, 120, 180, 300}, "task", "provider")
+)
+
+// ObserveGeneration records one generation attempt. final marks the last
+// attempt of a job, so failures are only counted once retries are exhausted.
+func ObserveGeneration(task, provider string, elapsed time.Duration, err error, final bool) {
+	GenerationSeconds.Observe(elapsed.Seconds(), task, provider)
+	switch {
+	case err == nil:
+		JobsSucceeded.Inc(task, provider)
+	case final:
+		JobsFailed.Inc(task, provider)
+	}
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritesTextExposition(t *testing.T) {
+	reg := NewRegistry()
+	jobs := reg.CounterVec("test_jobs_total", "Jobs processed.", "task", "provider")
+	latency := reg.HistogramVec("test_latency_seconds", "Latency.", []float64{1, 5}, "provider")
+
+	jobs.Inc("IMAGE_GEN", "qwen")
+	jobs.Inc("IMAGE_GEN", "qwen")
+	jobs.Add(3, "VIDEO_GEN", `we"ird`)
+	latency.Observe(0.5, "qwen")
+	latency.Observe(3, "qwen")
+	latency.Observe(7, "qwen")
+
+	rr := httptest.NewRecorder()
+	reg.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("content type = %q", ct)
+	}
+	want := `# HELP test_jobs_total Jobs processed.
+# TYPE test_jobs_total counter
+test_jobs_total{task="IMAGE_GEN",provider="qwen"} 2
+test_jobs_total{task="VIDEO_GEN",provider="we\"ird"} 3
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{provider="qwen",le="1"} 1
+test_latency_seconds_bucket{provider="qwen",le="5"} 2
+test_latency_seconds_bucket{provider="qwen",le="+Inf"} 3
+test_latency_seconds_sum{provider="qwen"} 10.5
+test_latency_seconds_count{provider="qwen"} 3
+`
+	if got := rr.Body.String(); got != want {
+		t.Fatalf("exposition mismatch:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type TokenClaims struct {
@@ -19,15 +21,39 @@ type TokenClaims struct {
 	Exp      int64  `json:"exp"`
 	Issuer   string `json:"iss"`
 	Audience string `json:"aud"`
+	Admin    bool   `json:"admin,omitempty"`
+	ID       string `json:"jti,omitempty"`
+	Nbf      int64  `json:"nbf,omitempty"`
+	Iat      int64  `json:"iat,omitempty"`
 }
 
+// ClockSkew is how far nbf and iat may lie in the future before a token is
+// rejected, to tolerate clock drift between servers.
+const ClockSkew = 60 * time.Second
+
+// RevocationCheck reports whether the token with the given jti was revoked.
+type RevocationCheck func(ctx context.Context, jti string) (bool, error)
+
 type userKey string
 
 const (
 	userIDKey userKey = "user_id"
+	claimsKey userKey = "claims"
 )
 
+// SignJWT signs claims with HS256, assigning a random jti when claims.ID is
+// empty so the token can be revoked individually. Iat and Nbf default to now.
 func SignJWT(secret string, claims TokenClaims) (string, error) {
+	if claims.ID == "" {
+		claims.ID = uuid.NewString()
+	}
+	now := time.Now().Unix()
+	if claims.Iat == 0 {
+		claims.Iat = now
+	}
+	if claims.Nbf == 0 {
+		claims.Nbf = now
+	}
 	header := map[string]string{"alg": "HS256", "typ": "JWT"}
 	headerJSON, _ := json.Marshal(header)
 	payloadJSON, _ := json.Marshal(claims)
@@ -61,13 +87,28 @@ func VerifyJWT(secret, token string) (*TokenClaims, error) {
 	if err := json.Unmarshal(payload, &claims); err != nil {
 		return nil, err
 	}
-	if claims.Exp != 0 && time.Now().Unix() > claims.Exp {
+	now := time.Now()
+	if claims.Exp != 0 && now.Unix() > claims.Exp {
 		return nil, errors.New("token expired")
 	}
+	leeway := now.Add(ClockSkew).Unix()
+	if claims.Nbf != 0 && claims.Nbf > leeway {
+		return nil, errors.New("token not yet valid")
+	}
+	if claims.Iat != 0 && claims.Iat > leeway {
+		return nil, errors.New("token issued in the future")
+	}
 	return &claims, nil
 }
 
 func AuthJWT(secret string) func(http.Handler) http.Handler {
+	return AuthJWTWithRevocation(secret, nil)
+}
+
+// AuthJWTWithRevocation behaves like AuthJWT and additionally rejects tokens
+// whose jti isRevoked reports as revoked. Tokens without a jti predate
+// revocation support and are only checked for expiry.
+func AuthJWTWithRevocation(secret string, isRevoked RevocationCheck) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -85,7 +126,19 @@ func AuthJWT(secret string) func(http.Handler) http.Handler {
 				http.Error(w, "invalid token", http.StatusUnauthorized)
 				return
 			}
+			if isRevoked != nil && claims.ID != "" {
+				revoked, err := isRevoked(r.Context(), claims.ID)
+				if err != nil {
+					http.Error(w, "unable to verify token", http.StatusServiceUnavailable)
+					return
+				}
+				if revoked {
+					http.Error(w, "token revoked", http.StatusUnauthorized)
+					return
+				}
+			}
 			ctx := context.WithValue(r.Context(), userIDKey, claims.Sub)
+			ctx = context.WithValue(ctx, claimsKey, claims)
 			ctx = context.WithValue(ctx, LocaleKey, claims.Locale)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -105,3 +158,20 @@ func ContextWithUserID(ctx context.Context, userID string) context.Context {
 	}
 	return context.WithValue(ctx, userIDKey, userID)
 }
+
+// ClaimsFromContext returns the verified token claims attached by AuthJWT.
+func ClaimsFromContext(ctx context.Context) *TokenClaims {
+	if v, ok := ctx.Value(claimsKey).(*TokenClaims); ok {
+		return v
+	}
+	return nil
+}
+
+// ContextWithClaims attaches claims and the subject user ID to ctx.
+func ContextWithClaims(ctx context.Context, claims *TokenClaims) context.Context {
+	if claims == nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, claimsKey, claims)
+	return ContextWithUserID(ctx, claims.Sub)
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const unsupportedMediaTypeBody = `{"error":{"code":"unsupported_media_type","message":"Content-Type must be application/json"}}` + "\n"
+
+// RequireJSON rejects POST, PUT and PATCH requests that carry a body with a
+// Content-Type other than application/json (or a +json type) with 415.
+// Bodiless requests pass through, as do the exempt paths, which handle their
+// own encodings such as multipart uploads.
+func RequireJSON(exemptPaths ...string) func(http.Handler) http.Handler {
+	exempt := make(map[string]struct{}, len(exemptPaths))
+	for _, p := range exemptPaths {
+		exempt[p] = struct{}{}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasJSONBodyMethod(r.Method) || r.ContentLength == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := exempt[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !isJSONContentType(r.Header.Get("Content-Type")) {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				_, _ = w.Write([]byte(unsupportedMediaTypeBody))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hasJSONBodyMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+func isJSONContentType(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireJSON(t *testing.T) {
+	handler := RequireJSON("/v1/images/uploads")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{name: "json", method: http.MethodPost, path: "/v1/prompts/enhance", contentType: "application/json; charset=utf-8", body: `{}`, want: http.StatusNoContent},
+		{name: "text plain", method: http.MethodPost, path: "/v1/prompts/enhance", contentType: "text/plain", body: `{}`, want: http.StatusUnsupportedMediaType},
+		{name: "form encoded", method: http.MethodPost, path: "/v1/videos/generate", contentType: "application/x-www-form-urlencoded", body: "prompt=x", want: http.StatusUnsupportedMediaType},
+		{name: "missing header", method: http.MethodPost, path: "/v1/videos/generate", body: `{}`, want: http.StatusUnsupportedMediaType},
+		{name: "empty body", method: http.MethodPost, path: "/v1/auth/logout", want: http.StatusNoContent},
+		{name: "multipart upload exempt", method: http.MethodPost, path: "/v1/images/uploads", contentType: "multipart/form-data; boundary=x", body: "--x--", want: http.StatusNoContent},
+		{name: "get ignored", method: http.MethodGet, path: "/v1/me", contentType: "text/plain", body: "x", want: http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.want)
+			}
+			if tc.want == http.StatusUnsupportedMediaType && !strings.Contains(rec.Body.String(), `"code":"unsupported_media_type"`) {
+				t.Fatalf("unexpected body: %s", rec.Body.String())
+			}
+		})
+	}
+}
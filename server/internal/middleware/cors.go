@@ -1,23 +1,46 @@
 package middleware
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
+// CORS answers cross-origin requests from allowedOrigins. Listed origins are
+// echoed back with Allow-Credentials so browsers send cookies and auth
+// headers. An entry of "*" lets any other origin in with a literal "*" and no
+// credentials, which is all browsers accept for a wildcard. Preflight OPTIONS
+// requests are answered directly with 204.
 func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
 	allow := make(map[string]struct{}, len(allowedOrigins))
+	allowAll := false
 	for _, origin := range allowedOrigins {
-		allow[origin] = struct{}{}
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		if origin != "" {
+			allow[strings.ToLower(origin)] = struct{}{}
+		}
 	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 			if origin != "" {
-				if _, ok := allow[origin]; ok {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-					w.Header().Set("Vary", "Origin")
-					w.Header().Set("Access-Control-Allow-Credentials", "true")
-					w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Locale")
-					w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
+				w.Header().Add("Vary", "Origin")
+				_, ok := allow[strings.ToLower(origin)]
+				if ok || allowAll {
+					if ok {
+						w.Header().Set("Access-Control-Allow-Origin", origin)
+						w.Header().Set("Access-Control-Allow-Credentials", "true")
+					} else {
+						w.Header().Set("Access-Control-Allow-Origin", "*")
+					}
+					w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Locale, Idempotency-Key")
+					w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
+					w.Header().Set("Access-Control-Expose-Headers", "Authorization, Content-Disposition, X-Request-ID")
+					w.Header().Set("Access-Control-Max-Age", "600")
 				}
 			}
 			if r.Method == http.MethodOptions {
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func corsRequest(t *testing.T, allowed []string, method, origin string) *httptest.ResponseRecorder {
+	t.Helper()
+	handler := CORS(allowed)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(method, "/v1/me", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	rec := corsRequest(t, []string{"https://app.example.com"}, http.MethodGet, "https://app.example.com")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("Allow-Origin = %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "Authorization") {
+		t.Fatalf("Expose-Headers = %q, want Authorization exposed", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Fatalf("Vary = %q", got)
+	}
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	rec := corsRequest(t, []string{"https://app.example.com"}, http.MethodGet, "https://evil.example.com")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("Allow-Origin = %q, want none for disallowed origin", got)
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	rec := corsRequest(t, []string{"https://app.example.com"}, http.MethodOptions, "https://app.example.com")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got == "" {
+		t.Fatal("missing Allow-Methods on preflight")
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got == "" {
+		t.Fatal("missing Allow-Headers on preflight")
+	}
+}
+
+func TestCORSWildcardOmitsCredentials(t *testing.T) {
+	rec := corsRequest(t, []string{"*"}, http.MethodGet, "https://anywhere.example.org")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Fatalf("Allow-Credentials = %q, want none with a wildcard origin", got)
+	}
+}
+
+func TestCORSListedOriginKeepsCredentialsAlongsideWildcard(t *testing.T) {
+	rec := corsRequest(t, []string{"*", "https://app.example.com"}, http.MethodGet, "https://app.example.com")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("Allow-Origin = %q, want reflected origin", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Fatalf("Allow-Credentials = %q", got)
+	}
+}
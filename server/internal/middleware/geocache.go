@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type geoCacheEntry struct {
+	ip      string
+	country string
+	expires time.Time
+}
+
+// geoCache is a bounded LRU of IP to country code results with a fixed TTL.
+type geoCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+func newGeoCache(size int, ttl time.Duration) *geoCache {
+	return &geoCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+		now:     time.Now,
+	}
+}
+
+func (c *geoCache) get(ip string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[ip]
+	if !ok {
+		return "", false
+	}
+	entry := el.Value.(*geoCacheEntry)
+	if c.now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, ip)
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return entry.country, true
+}
+
+func (c *geoCache) put(ip, country string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.entries[ip]; ok {
+		entry := el.Value.(*geoCacheEntry)
+		entry.country = country
+		entry.expires = expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[ip] = c.order.PushFront(&geoCacheEntry{ip: ip, country: country, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*geoCacheEntry).ip)
+	}
+}
+
+// CachedCountryLookup wraps lookup with a bounded LRU cache keyed by IP so
+// repeat visitors do not hit the resolver on every request. Failed lookups are
+// not cached. A non-positive size disables caching.
+func CachedCountryLookup(lookup CountryLookup, size int, ttl time.Duration) CountryLookup {
+	if lookup == nil || size <= 0 || ttl <= 0 {
+		return lookup
+	}
+	return newGeoCache(size, ttl).wrap(lookup)
+}
+
+func (c *geoCache) wrap(lookup CountryLookup) CountryLookup {
+	return func(ip string) (string, error) {
+		if country, ok := c.get(ip); ok {
+			return country, nil
+		}
+		country, err := lookup(ip)
+		if err != nil {
+			return "", err
+		}
+		c.put(ip, country)
+		return country, nil
+	}
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultGzipMinSize is the smallest response body worth compressing.
+const DefaultGzipMinSize = 1024
+
+var gzipWriterPool = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// Gzip compresses responses for clients sending Accept-Encoding: gzip once
+// the body reaches minSize bytes. Media that is already compressed (images,
+// video, archives) and partial content are passed through untouched.
+func Gzip(minSize int) func(http.Handler) http.Handler {
+	if minSize <= 0 {
+		minSize = DefaultGzipMinSize
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize, status: http.StatusOK}
+			defer gw.close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of the body until it knows whether
+// the response is large and compressible enough to gzip.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize     int
+	status      int
+	buf         []byte
+	decided     bool
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	g.status = code
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		g.decide(false)
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(p)
+		}
+		return g.ResponseWriter.Write(p)
+	}
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= g.minSize {
+		if err := g.flushBuffer(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what has been buffered so far, compressing it when eligible.
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		_ = g.flushBuffer(len(g.buf) >= g.minSize)
+	}
+	if g.gz != nil {
+		_ = g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *gzipResponseWriter) flushBuffer(largeEnough bool) error {
+	g.decide(largeEnough)
+	buf := g.buf
+	g.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if g.gz != nil {
+		_, err := g.gz.Write(buf)
+		return err
+	}
+	_, err := g.ResponseWriter.Write(buf)
+	return err
+}
+
+func (g *gzipResponseWriter) decide(largeEnough bool) {
+	if g.decided {
+		return
+	}
+	g.decided = true
+	h := g.ResponseWriter.Header()
+	if h.Get("Content-Type") == "" && len(g.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(g.buf))
+	}
+	compressible := g.status != http.StatusPartialContent && h.Get("Content-Encoding") == "" &&
+		h.Get("Content-Range") == "" && compressibleType(h.Get("Content-Type"))
+	if compressible {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if compressible && largeEnough {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		gz := gzipWriterPool.Get().(*gzip.Writer)
+		gz.Reset(g.ResponseWriter)
+		g.gz = gz
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+}
+
+func (g *gzipResponseWriter) close() {
+	if !g.wroteHeader {
+		if len(g.buf) == 0 {
+			return
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+	if !g.decided {
+		_ = g.flushBuffer(false)
+	}
+	if g.gz != nil {
+		_ = g.gz.Close()
+		gzipWriterPool.Put(g.gz)
+		g.gz = nil
+	}
+}
+
+// compressibleType reports whether a response of this Content-Type benefits
+// from gzip. Images, audio, video and archives are already compressed.
+func compressibleType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	switch {
+	case mediaType == "":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/javascript", mediaType == "application/xml",
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return false
+}
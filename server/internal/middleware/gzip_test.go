@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipRecorder(t *testing.T, contentType, body, acceptEncoding string) *httptest.ResponseRecorder {
+	t.Helper()
+	handler := Gzip(DefaultGzipMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, body)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/v1/images/jobs", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGzipCompressesLargeJSON(t *testing.T) {
+	body := `{"items":[` + strings.Repeat(`{"id":"job","status":"SUCCEEDED"},`, 100) + `{}]}`
+	rec := gzipRecorder(t, "application/json; charset=utf-8", body, "br, gzip")
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Fatalf("Vary = %q", got)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if string(plain) != body {
+		t.Fatalf("decompressed body mismatch")
+	}
+}
+
+func TestGzipSkipsSmallAndUnacceptedResponses(t *testing.T) {
+	rec := gzipRecorder(t, "application/json", `{"ok":true}`, "gzip")
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{"ok":true}` {
+		t.Fatalf("small body should pass through: %q %q", rec.Header().Get("Content-Encoding"), rec.Body.String())
+	}
+
+	large := strings.Repeat("a", 4096)
+	rec = gzipRecorder(t, "text/plain", large, "")
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != large {
+		t.Fatal("client without Accept-Encoding got a compressed body")
+	}
+	rec = gzipRecorder(t, "text/plain", large, "gzip;q=0")
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Fatal("gzip;q=0 should disable compression")
+	}
+}
+
+func TestGzipSkipsCompressedMedia(t *testing.T) {
+	large := strings.Repeat("\x50\x4b\x03\x04", 1024)
+	for _, contentType := range []string{"application/zip", "image/png", "video/mp4"} {
+		rec := gzipRecorder(t, contentType, large, "gzip")
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Fatalf("%s: Content-Encoding = %q, want none", contentType, got)
+		}
+		if rec.Body.String() != large {
+			t.Fatalf("%s: body altered", contentType)
+		}
+	}
+}
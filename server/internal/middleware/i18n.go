@@ -5,6 +5,8 @@ import (
 	"net"
 	"net/http"
 	"strings"
+
+	"server/internal/i18n"
 )
 
 type localeContextKey struct{}
@@ -52,23 +54,25 @@ func detectLocale(r *http.Request, fallback string, country string) string {
 }
 
 func parseAcceptLanguage(header string) string {
-	parts := strings.Split(header, ",")
-	for _, part := range parts {
-		locale := strings.TrimSpace(strings.Split(part, ";")[0])
-		if locale == "" {
-			continue
-		}
+	if locale := firstAcceptLanguage(header); locale != "" {
 		return normalizeLocale(locale)
 	}
 	return ""
 }
 
-func normalizeLocale(locale string) string {
-	locale = strings.ToLower(locale)
-	if strings.HasPrefix(locale, "id") {
-		return "id"
+func firstAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		if locale := strings.TrimSpace(strings.Split(part, ";")[0]); locale != "" {
+			return locale
+		}
 	}
-	return "en"
+	return ""
+}
+
+// normalizeLocale resolves a requested tag through the i18n fallback chain,
+// so "ms-MY" stays Malay when supported and otherwise degrades via id to en.
+func normalizeLocale(locale string) string {
+	return i18n.Resolve(locale)
 }
 
 // ClientIP returns the best-effort client IP address for the request.
@@ -121,10 +125,10 @@ func ResolveCountry(r *http.Request, lookup CountryLookup) string {
 	if region := localeRegion(r.Header.Get("Accept-Language")); region != "" {
 		return region
 	}
-	if locale := normalizeLocale(r.Header.Get("X-Locale")); locale == "id" {
+	if i18n.Language(r.Header.Get("X-Locale")) == "id" {
 		return "ID"
 	}
-	if locale := parseAcceptLanguage(r.Header.Get("Accept-Language")); locale == "id" {
+	if i18n.Language(firstAcceptLanguage(r.Header.Get("Accept-Language"))) == "id" {
 		return "ID"
 	}
 	if lookup != nil {
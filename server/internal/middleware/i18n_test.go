@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type assertError string
@@ -41,6 +42,28 @@ func TestDetectLocale(t *testing.T) {
 			},
 			want: "id",
 		},
+		{
+			name: "malay locale kept",
+			setup: func(r *http.Request) {
+				r.Header.Set("X-Locale", "ms-MY")
+			},
+			want: "ms",
+		},
+		{
+			name: "javanese accept-language kept",
+			setup: func(r *http.Request) {
+				r.Header.Set("Accept-Language", "jv,id;q=0.8")
+			},
+			want: "jv",
+		},
+		{
+			name: "unknown locale resolves to en",
+			setup: func(r *http.Request) {
+				r.Header.Set("X-Locale", "fr-FR")
+			},
+			country: "ID",
+			want:    "en",
+		},
 		{
 			name:    "country id overrides",
 			country: "ID",
@@ -156,3 +179,60 @@ func TestLocaleFromContext(t *testing.T) {
 		t.Fatalf("LocaleFromContext() with value = %q, want %q", got, "id")
 	}
 }
+
+func TestCachedCountryLookupReusesResult(t *testing.T) {
+	calls := 0
+	lookup := CachedCountryLookup(func(ip string) (string, error) {
+		calls++
+		return "ID", nil
+	}, 8, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		country, err := lookup("203.0.113.7")
+		if err != nil {
+			t.Fatalf("lookup: %v", err)
+		}
+		if country != "ID" {
+			t.Fatalf("country = %q, want ID", country)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("resolver calls = %d, want 1", calls)
+	}
+}
+
+func TestCachedCountryLookupEvictsAndExpires(t *testing.T) {
+	calls := map[string]int{}
+	lookup := func(ip string) (string, error) {
+		calls[ip]++
+		if ip == "198.51.100.1" {
+			return "", assertError("lookup failed")
+		}
+		return "US", nil
+	}
+	cache := newGeoCache(2, time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	wrapped := cache.wrap(lookup)
+	cached := func(ip string) { _, _ = wrapped(ip) }
+
+	cached("203.0.113.1")
+	cached("203.0.113.2")
+	cached("203.0.113.3")
+	cached("203.0.113.1")
+	if calls["203.0.113.1"] != 2 {
+		t.Fatalf("least recently used entry should be evicted, calls = %d", calls["203.0.113.1"])
+	}
+
+	now = now.Add(2 * time.Minute)
+	cached("203.0.113.1")
+	if calls["203.0.113.1"] != 3 {
+		t.Fatalf("expired entry should be refreshed, calls = %d", calls["203.0.113.1"])
+	}
+
+	cached("198.51.100.1")
+	cached("198.51.100.1")
+	if calls["198.51.100.1"] != 2 {
+		t.Fatalf("failed lookups should not be cached, calls = %d", calls["198.51.100.1"])
+	}
+}
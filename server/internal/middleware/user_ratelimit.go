@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const userBucketIdleTTL = 10 * time.Minute
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// userLimiter keeps one token bucket per authenticated user. Buckets refill
+// continuously at limit tokens per window and are evicted once idle.
+type userLimiter struct {
+	mu        sync.Mutex
+	limit     float64
+	rate      float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newUserLimiter(limit int, per time.Duration) *userLimiter {
+	return &userLimiter{
+		limit:   float64(limit),
+		rate:    float64(limit) / per.Seconds(),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// allow consumes a token for userID. When the bucket is empty it reports how
+// long the caller should wait before the next token is available.
+func (l *userLimiter) allow(userID string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[userID]
+	if !ok {
+		b = &tokenBucket{tokens: l.limit, lastSeen: now}
+		l.buckets[userID] = b
+	}
+	b.tokens = math.Min(l.limit, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	b.lastSeen = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *userLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < userBucketIdleTTL {
+		return
+	}
+	l.lastSweep = now
+	for id, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= userBucketIdleTTL {
+			delete(l.buckets, id)
+		}
+	}
+}
+
+func (l *userLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := UserIDFromContext(r.Context())
+		if userID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok, wait := l.allow(userID); !ok {
+			retry := int(math.Ceil(wait.Seconds()))
+			if retry < 1 {
+				retry = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PerUserRateLimit limits each authenticated user to limit requests per
+// minute using a token bucket. It must run after AuthJWT; requests without a
+// user in context are passed through. A non-positive limit disables it.
+func PerUserRateLimit(limit int) func(http.Handler) http.Handler {
+	if limit <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return newUserLimiter(limit, time.Minute).middleware
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPerUserRateLimitIsolatesUsers(t *testing.T) {
+	limiter := newUserLimiter(3, time.Minute)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	handler := limiter.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	call := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(ContextWithUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 3; i++ {
+		if rec := call("user-a"); rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d status = %d, want 204", i+1, rec.Code)
+		}
+	}
+	rec := call("user-a")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over limit status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "20" {
+		t.Fatalf("Retry-After = %q, want 20", got)
+	}
+
+	if rec := call("user-b"); rec.Code != http.StatusNoContent {
+		t.Fatalf("second user status = %d, want 204", rec.Code)
+	}
+
+	now = now.Add(20 * time.Second)
+	if rec := call("user-a"); rec.Code != http.StatusNoContent {
+		t.Fatalf("refilled status = %d, want 204", rec.Code)
+	}
+}
+
+func TestPerUserRateLimitEvictsIdleBuckets(t *testing.T) {
+	limiter := newUserLimiter(1, time.Minute)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	limiter.allow("user-a")
+	now = now.Add(userBucketIdleTTL)
+	limiter.allow("user-b")
+
+	if _, ok := limiter.buckets["user-a"]; ok {
+		t.Fatalf("idle bucket should be evicted")
+	}
+	if _, ok := limiter.buckets["user-b"]; !ok {
+		t.Fatalf("active bucket should be kept")
+	}
+}
@@ -0,0 +1,162 @@
+// Package moderation screens prompt text before a generation job is queued.
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultTimeout       = 5 * time.Second
+)
+
+// Options configures a Moderator. The OpenAI moderation endpoint is only
+// called when OpenAIAPIKey is set.
+type Options struct {
+	Denylist      []string
+	OpenAIAPIKey  string
+	OpenAIBaseURL string
+	HTTPClient    *http.Client
+}
+
+// Verdict is the outcome of a moderation check. Reason names the denylisted
+// term or the flagged OpenAI categories.
+type Verdict struct {
+	Blocked bool
+	Reason  string
+}
+
+// Moderator checks text against a denylist of terms and, optionally, the
+// OpenAI moderation endpoint.
+type Moderator struct {
+	terms   [][]string
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// New returns a Moderator. Denylist terms are matched case-insensitively on
+// whole words, so "gun" does not block "burgundy".
+func New(opts Options) *Moderator {
+	m := &Moderator{
+		apiKey:  strings.TrimSpace(opts.OpenAIAPIKey),
+		baseURL: strings.TrimRight(strings.TrimSpace(opts.OpenAIBaseURL), "/"),
+		client:  opts.HTTPClient,
+	}
+	if m.baseURL == "" {
+		m.baseURL = defaultOpenAIBaseURL
+	}
+	if m.client == nil {
+		m.client = &http.Client{Timeout: defaultTimeout}
+	}
+	for _, term := range opts.Denylist {
+		if words := words(term); len(words) > 0 {
+			m.terms = append(m.terms, words)
+		}
+	}
+	return m
+}
+
+// Check screens texts. The denylist is consulted first; the remote check
+// only runs when it passes. A remote failure is returned as an error with a
+// non-blocking verdict so callers can decide whether to fail open.
+func (m *Moderator) Check(ctx context.Context, texts ...string) (Verdict, error) {
+	if m == nil {
+		return Verdict{}, nil
+	}
+	var parts []string
+	for _, text := range texts {
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	if len(parts) == 0 {
+		return Verdict{}, nil
+	}
+	if term, ok := m.matchDenylist(parts); ok {
+		return Verdict{Blocked: true, Reason: "denylisted term: " + term}, nil
+	}
+	if m.apiKey == "" {
+		return Verdict{}, nil
+	}
+	return m.checkOpenAI(ctx, strings.Join(parts, "\n"))
+}
+
+func (m *Moderator) matchDenylist(texts []string) (string, bool) {
+	for _, text := range texts {
+		haystack := " " + strings.Join(words(text), " ") + " "
+		for _, term := range m.terms {
+			if strings.Contains(haystack, " "+strings.Join(term, " ")+" ") {
+				return strings.Join(term, " "), true
+			}
+		}
+	}
+	return "", false
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+type openAIModerationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+func (m *Moderator) checkOpenAI(ctx context.Context, input string) (Verdict, error) {
+	body, err := json.Marshal(map[string]string{"input": input})
+	if err != nil {
+		return Verdict{}, fmt.Errorf("moderation: encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+"/moderations", bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, fmt.Errorf("moderation: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("moderation: request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Verdict{}, fmt.Errorf("moderation: read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return Verdict{}, fmt.Errorf("moderation: status %d", resp.StatusCode)
+	}
+	var out openAIModerationResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return Verdict{}, fmt.Errorf("moderation: decode response: %w", err)
+	}
+	for _, result := range out.Results {
+		if !result.Flagged {
+			continue
+		}
+		var categories []string
+		for name, flagged := range result.Categories {
+			if flagged {
+				categories = append(categories, name)
+			}
+		}
+		sort.Strings(categories)
+		return Verdict{Blocked: true, Reason: "flagged: " + strings.Join(categories, ",")}, nil
+	}
+	return Verdict{}, nil
+}
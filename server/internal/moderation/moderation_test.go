@@ -0,0 +1,58 @@
+package moderation
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckDenylist(t *testing.T) {
+	m := New(Options{Denylist: []string{"Gun", "adult content"}})
+	cases := []struct {
+		texts   []string
+		blocked bool
+	}{
+		{texts: []string{"Kopi susu gula aren", "warm morning light"}, blocked: false},
+		{texts: []string{"Burgundy scarf"}, blocked: false},
+		{texts: []string{"Leather holster", "show a GUN on the table"}, blocked: true},
+		{texts: []string{"Poster with adult-content warning"}, blocked: true},
+	}
+	for _, tc := range cases {
+		verdict, err := m.Check(context.Background(), tc.texts...)
+		if err != nil {
+			t.Fatalf("%v: unexpected error %v", tc.texts, err)
+		}
+		if verdict.Blocked != tc.blocked {
+			t.Fatalf("%v: blocked = %v, want %v (%s)", tc.texts, verdict.Blocked, tc.blocked, verdict.Reason)
+		}
+	}
+}
+
+func TestCheckOpenAIModeration(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/moderations" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "violent") {
+			_, _ = io.WriteString(w, `{"results":[{"flagged":true,"categories":{"violence":true,"hate":false}}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"results":[{"flagged":false,"categories":{}}]}`)
+	}))
+	defer srv.Close()
+
+	m := New(Options{OpenAIAPIKey: "key", OpenAIBaseURL: srv.URL, HTTPClient: srv.Client()})
+	verdict, err := m.Check(context.Background(), "Nasi goreng")
+	if err != nil || verdict.Blocked {
+		t.Fatalf("clean prompt: verdict = %+v, err = %v", verdict, err)
+	}
+	verdict, err = m.Check(context.Background(), "a violent scene")
+	if err != nil || !verdict.Blocked || verdict.Reason != "flagged: violence" {
+		t.Fatalf("flagged prompt: verdict = %+v, err = %v", verdict, err)
+	}
+}
@@ -0,0 +1,147 @@
+// Package netguard builds HTTP clients for URLs supplied by users, such as
+// source images and job callbacks. Hosts must resolve to public addresses,
+// connections are dialed to the addresses that passed the check, and every
+// redirect hop is validated again, so a public URL cannot reach loopback,
+// private or metadata endpoints. The only exemption is an explicit set of
+// trusted hosts run by the operator, such as the asset store.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoHost reports a URL without a hostname.
+	ErrNoHost = errors.New("netguard: url has no hostname")
+	// ErrNotPublic reports a host that is not publicly accessible.
+	ErrNotPublic = errors.New("host is not publicly accessible")
+)
+
+// Resolver looks up the addresses a host resolves to. *net.Resolver
+// satisfies it; tests substitute fixed answers.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Allowlist normalizes hosts into the lookup set CheckHost and NewClient
+// expect.
+func Allowlist(hosts []string) map[string]struct{} {
+	out := make(map[string]struct{}, len(hosts))
+	for _, host := range hosts {
+		if normalized := strings.ToLower(strings.TrimSpace(host)); normalized != "" {
+			out[normalized] = struct{}{}
+		}
+	}
+	return out
+}
+
+// NewClient returns a client whose connections go through PinnedDialer and
+// whose redirects must stay on public http(s) hosts. Hosts in trusted skip
+// the public address check.
+func NewClient(timeout time.Duration, maxRedirects int, trusted map[string]struct{}, resolver Resolver) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would resolve the host itself and defeat the pinned dial.
+	transport.Proxy = nil
+	transport.DialContext = PinnedDialer(trusted, resolver)
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			if err := CheckHost(req.URL, trusted); err != nil {
+				return fmt.Errorf("redirect to %s blocked: %w", req.URL.Hostname(), ErrNotPublic)
+			}
+			return nil
+		},
+	}
+}
+
+// CheckHost rejects a URL whose host is missing, a non-public IP literal or a
+// local name. Other names are checked by PinnedDialer once resolved.
+// Trusted hosts always pass.
+func CheckHost(u *url.URL, trusted map[string]struct{}) error {
+	host := strings.TrimSpace(u.Hostname())
+	if host == "" {
+		return ErrNoHost
+	}
+	lower := strings.ToLower(host)
+	if _, ok := trusted[lower]; ok {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return ErrNotPublic
+		}
+		return nil
+	}
+	if lower == "localhost" || strings.HasSuffix(lower, ".local") || strings.HasSuffix(lower, ".internal") {
+		return ErrNotPublic
+	}
+	return nil
+}
+
+// PinnedDialer resolves the target host once, rejects it when any address is
+// not public, and connects to the resolved addresses directly so a second DNS
+// answer cannot swap in a private one. Trusted hosts skip the address check
+// but are still pinned.
+func PinnedDialer(trusted map[string]struct{}, resolver Resolver) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		var ips []net.IP
+		if ip := net.ParseIP(host); ip != nil {
+			ips = []net.IP{ip}
+		} else {
+			answers, err := resolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			for _, answer := range answers {
+				ips = append(ips, answer.IP)
+			}
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("no addresses found for %s", host)
+		}
+		if _, ok := trusted[strings.ToLower(host)]; !ok {
+			for _, ip := range ips {
+				if !IsPublicIP(ip) {
+					return nil, fmt.Errorf("%s resolves to non-public address %s", host, ip)
+				}
+			}
+		}
+		var errs []error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+}
+
+// IsPublicIP reports whether ip is routable on the public internet rather
+// than a loopback, private, link-local or unspecified address.
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsUnspecified() || ip.IsPrivate() || ip.IsLinkLocalMulticast() || ip.IsLinkLocalUnicast())
+}
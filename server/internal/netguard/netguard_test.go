@@ -0,0 +1,35 @@
+package netguard
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestCheckHost(t *testing.T) {
+	trusted := Allowlist([]string{" Storage.Internal "})
+	for _, tc := range []struct {
+		raw  string
+		want error
+	}{
+		{raw: "https://cdn.example.com/a.png"},
+		{raw: "http://93.184.216.34/a.png"},
+		{raw: "http://storage.internal/a.png"},
+		{raw: "file:///etc/passwd", want: ErrNoHost},
+		{raw: "http://127.0.0.1:8080/", want: ErrNotPublic},
+		{raw: "http://169.254.169.254/latest/meta-data/", want: ErrNotPublic},
+		{raw: "http://[::1]/", want: ErrNotPublic},
+		{raw: "http://10.0.0.5/", want: ErrNotPublic},
+		{raw: "http://LocalHost/", want: ErrNotPublic},
+		{raw: "http://metadata.google.internal/", want: ErrNotPublic},
+		{raw: "http://printer.local/", want: ErrNotPublic},
+	} {
+		u, err := url.Parse(tc.raw)
+		if err != nil {
+			t.Fatalf("parse %s: %v", tc.raw, err)
+		}
+		if err := CheckHost(u, trusted); !errors.Is(err, tc.want) {
+			t.Fatalf("CheckHost(%s) = %v, want %v", tc.raw, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package breaker implements a consecutive-failure circuit breaker used to
+// stop calling an upstream provider while it is down.
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// State is the position of the breaker.
+type State int
+
+const (
+	// Closed lets every call through.
+	Closed State = iota
+	// Open rejects calls until the cooldown elapses.
+	Open
+	// HalfOpen lets a single probe call through to test recovery.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+const (
+	defaultThreshold = 5
+	defaultCooldown  = 30 * time.Second
+)
+
+// Options configures a Breaker. Zero values select a threshold of 5 failures
+// and a 30 second cooldown.
+type Options struct {
+	Threshold     int
+	Cooldown      time.Duration
+	OnStateChange func(from, to State)
+	Now           func() time.Time
+}
+
+// Breaker opens after Threshold consecutive failures. Once Cooldown has
+// passed it half-opens and admits one probe: a success closes it again, a
+// failure re-opens it for another cooldown.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(from, to State)
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// New returns a closed Breaker.
+func New(opts Options) *Breaker {
+	b := &Breaker{
+		threshold: opts.Threshold,
+		cooldown:  opts.Cooldown,
+		onChange:  opts.OnStateChange,
+		now:       opts.Now,
+	}
+	if b.threshold <= 0 {
+		b.threshold = defaultThreshold
+	}
+	if b.cooldown <= 0 {
+		b.cooldown = defaultCooldown
+	}
+	if b.now == nil {
+		b.now = time.Now
+	}
+	return b
+}
+
+// Allow reports whether a call may proceed. Every allowed call must be
+// followed by Success or Failure. A nil Breaker allows everything.
+func (b *Breaker) Allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(HalfOpen)
+		b.probing = true
+		return true
+	case HalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Success records a successful call and closes the breaker.
+func (b *Breaker) Success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+	b.setState(Closed)
+}
+
+// Failure records a failed call, opening the breaker once the threshold is
+// reached or when a half-open probe fails.
+func (b *Breaker) Failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.state == HalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(Open)
+	}
+}
+
+// Release ends an allowed call without recording an outcome, e.g. when the
+// caller cancelled it. A half-open breaker admits a new probe afterwards.
+func (b *Breaker) Release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// State returns the current state without side effects.
+func (b *Breaker) State() State {
+	if b == nil {
+		return Closed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) setState(to State) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	if b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
+
+// LogStateChanges returns an OnStateChange hook that logs each transition of
+// the breaker guarding provider.
+func LogStateChanges(logger zerolog.Logger, provider string) func(from, to State) {
+	return func(from, to State) {
+		evt := logger.Info()
+		if to == Open {
+			evt = logger.Warn()
+		}
+		evt.Str("provider", provider).Str("from", from.String()).Str("to", to.String()).Msg("circuit breaker state changed")
+	}
+}
@@ -0,0 +1,81 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var transitions []string
+	b := New(Options{
+		Threshold: 3,
+		Cooldown:  time.Minute,
+		Now:       func() time.Time { return now },
+		OnStateChange: func(from, to State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		if !b.Allow() {
+			t.Fatalf("call %d rejected before threshold", i)
+		}
+		b.Failure()
+	}
+	if b.State() != Closed {
+		t.Fatalf("state = %s after 2 failures, want closed", b.State())
+	}
+	b.Allow()
+	b.Failure()
+	if b.State() != Open {
+		t.Fatalf("state = %s after 3 failures, want open", b.State())
+	}
+	if b.Allow() {
+		t.Fatalf("open breaker allowed a call during cooldown")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.Allow() {
+		t.Fatalf("breaker did not admit a probe after cooldown")
+	}
+	if b.State() != HalfOpen {
+		t.Fatalf("state = %s, want half-open", b.State())
+	}
+	if b.Allow() {
+		t.Fatalf("half-open breaker admitted a second concurrent probe")
+	}
+	b.Failure()
+	if b.State() != Open {
+		t.Fatalf("failed probe left state %s, want open", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	if !b.Allow() {
+		t.Fatalf("breaker did not admit a probe after second cooldown")
+	}
+	b.Success()
+	if b.State() != Closed || !b.Allow() {
+		t.Fatalf("successful probe left state %s, want closed", b.State())
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions = %v, want %v", transitions, want)
+		}
+	}
+}
+
+func TestSuccessResetsFailureCount(t *testing.T) {
+	b := New(Options{Threshold: 2})
+	b.Failure()
+	b.Success()
+	b.Failure()
+	if b.State() != Closed {
+		t.Fatalf("non-consecutive failures opened the breaker")
+	}
+}
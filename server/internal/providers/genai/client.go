@@ -7,12 +7,14 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	"image/png"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -22,6 +24,7 @@ import (
 	"github.com/rs/zerolog"
 
 	"server/internal/infra"
+	"server/pkg/bitmapfont"
 )
 
 // Options controls how the Gemini client is configured.
@@ -31,8 +34,18 @@ type Options struct {
 	Model      string
 	HTTPClient *http.Client
 	Logger     *infra.Logger
+	// DisableSyntheticFallback makes a missing API key an error instead of
+	// producing placeholder assets.
+	DisableSyntheticFallback bool
+	// SyntheticVideoSeconds fixes the length of placeholder videos. Zero
+	// derives it from the prompt length.
+	SyntheticVideoSeconds int
 }
 
+// ErrMissingAPIKey is returned when no API key is configured and synthetic
+// fallback is disabled.
+var ErrMissingAPIKey = errors.New("genai: api key is required")
+
 // Client provides a lightweight facade over Gemini so that providers can focus
 // on translating domain requests to API calls. The real HTTP invocation is
 // intentionally stubbed with deterministic synthetic assets until the external
@@ -44,6 +57,8 @@ type Client struct {
 	model      string
 	httpClient *http.Client
 	logger     *infra.Logger
+	synthetic  bool
+	videoSecs  int
 }
 
 // ImageRequest represents the information required to generate images.
@@ -61,6 +76,17 @@ type VideoRequest struct {
 	Prompt    string
 	Locale    string
 	RequestID string
+	// SourceImage, when set, is animated instead of generating from text
+	// alone.
+	SourceImage *SourceImage
+}
+
+// SourceImage is a still image passed to Gemini as conditioning input. Data
+// is sent inline when present; otherwise URL is referenced as file data.
+type SourceImage struct {
+	Data []byte
+	MIME string
+	URL  string
 }
 
 // ImageAsset is the normalized representation returned by the Gemini client.
@@ -130,7 +156,8 @@ type geminiToolConfig struct {
 }
 
 type geminiImageGenerationConfig struct {
-	NumberOfImages int `json:"numberOfImages,omitempty"`
+	NumberOfImages int    `json:"numberOfImages,omitempty"`
+	AspectRatio    string `json:"aspectRatio,omitempty"`
 }
 
 type geminiVideoGenerationConfig struct{}
@@ -184,9 +211,22 @@ func NewClient(opts Options) (*Client, error) {
 		model:      model,
 		httpClient: client,
 		logger:     logger,
+		synthetic:  !opts.DisableSyntheticFallback,
+		videoSecs:  opts.SyntheticVideoSeconds,
 	}, nil
 }
 
+// SyntheticFallback reports whether placeholder assets are produced when no
+// API key is configured.
+func (c *Client) SyntheticFallback() bool {
+	return c.synthetic
+}
+
+// HasCredentials reports whether the client can perform remote calls.
+func (c *Client) HasCredentials() bool {
+	return c.apiKey != ""
+}
+
 // Model returns the configured Gemini model identifier.
 func (c *Client) Model() string {
 	return c.model
@@ -202,6 +242,9 @@ func (c *Client) GenerateImages(ctx context.Context, req ImageRequest) ([]ImageA
 	}
 
 	if c.apiKey == "" {
+		if !c.synthetic {
+			return nil, ErrMissingAPIKey
+		}
 		return c.syntheticImages(req)
 	}
 
@@ -226,6 +269,9 @@ func (c *Client) GenerateVideo(ctx context.Context, req VideoRequest) (*VideoAss
 	}
 
 	if c.apiKey == "" {
+		if !c.synthetic {
+			return nil, ErrMissingAPIKey
+		}
 		return c.syntheticVideo(req), nil
 	}
 
@@ -254,7 +300,7 @@ func (c *Client) syntheticImages(req ImageRequest) ([]ImageAsset, error) {
 	for i := 0; i < quantity; i++ {
 		seed := deterministicSeed(req.RequestID, req.Prompt, req.Locale, req.WatermarkTag, i)
 		storageKey := syntheticStorageKey("image", c.model, seed, i+1, "png")
-		img := renderSyntheticImage(width, height, seed, req.Prompt)
+		img := renderSyntheticImage(width, height, seed, req.Prompt, req.Locale)
 		assets[i] = ImageAsset{
 			StorageKey: storageKey,
 			URL:        c.assetURL(storageKey),
@@ -277,12 +323,16 @@ func (c *Client) syntheticImages(req ImageRequest) ([]ImageAsset, error) {
 func (c *Client) syntheticVideo(req VideoRequest) *VideoAsset {
 	seed := deterministicSeed(req.RequestID, req.Prompt, req.Locale, c.model, 0)
 	storageKey := syntheticStorageKey("video", c.model, seed, 1, "mp4")
+	length := c.videoSecs
+	if length <= 0 {
+		length = estimateVideoLength(req.Prompt)
+	}
 	asset := &VideoAsset{
 		StorageKey: storageKey,
 		URL:        c.assetURL(storageKey),
 		Format:     "video/mp4",
-		Length:     estimateVideoLength(req.Prompt),
-		Data:       renderSyntheticVideo(seed, req.Prompt),
+		Length:     length,
+		Data:       renderSyntheticVideo(seed, length),
 	}
 
 	c.logger.Debug().
@@ -295,6 +345,7 @@ func (c *Client) syntheticVideo(req VideoRequest) *VideoAsset {
 
 func (c *Client) remoteGenerateImages(ctx context.Context, req ImageRequest) ([]ImageAsset, error) {
 	quantity := clampQuantity(req.Quantity)
+	aspectRatio := geminiAspectRatio(req.AspectRatio)
 	payload := geminiGenerateContentRequest{
 		Contents: []geminiContent{
 			{
@@ -308,6 +359,7 @@ func (c *Client) remoteGenerateImages(ctx context.Context, req ImageRequest) ([]
 		ToolConfig: &geminiToolConfig{
 			ImageGenerationConfig: &geminiImageGenerationConfig{
 				NumberOfImages: quantity,
+				AspectRatio:    aspectRatio,
 			},
 		},
 	}
@@ -332,6 +384,14 @@ func (c *Client) remoteGenerateImages(ctx context.Context, req ImageRequest) ([]
 			w, h := decodeImageDimensions(asset.Data)
 			if w == 0 || h == 0 {
 				w, h = width, height
+			} else if !matchesAspect(w, h, width, height) {
+				c.logger.Warn().
+					Str("request_id", req.RequestID).
+					Str("model", c.model).
+					Str("aspect_ratio", aspectRatio).
+					Int("width", w).
+					Int("height", h).
+					Msg("genai: remote image does not match requested aspect ratio")
 			}
 			assets = append(assets, ImageAsset{
 				StorageKey: "",
@@ -360,13 +420,15 @@ func (c *Client) remoteGenerateImages(ctx context.Context, req ImageRequest) ([]
 }
 
 func (c *Client) remoteGenerateVideo(ctx context.Context, req VideoRequest) (*VideoAsset, error) {
+	parts := []geminiPart{{Text: buildVideoPrompt(req)}}
+	if part, ok := sourceImagePart(req.SourceImage); ok {
+		parts = append(parts, part)
+	}
 	payload := geminiGenerateContentRequest{
 		Contents: []geminiContent{
 			{
-				Role: "user",
-				Parts: []geminiPart{{
-					Text: buildVideoPrompt(req),
-				}},
+				Role:  "user",
+				Parts: parts,
 			},
 		},
 		Tools: []geminiTool{{VideoGeneration: &geminiVideoTool{}}},
@@ -406,6 +468,28 @@ func (c *Client) remoteGenerateVideo(ctx context.Context, req VideoRequest) (*Vi
 	return nil, fmt.Errorf("no video content returned")
 }
 
+// sourceImagePart converts src into an inline or file data part. It reports
+// false when src carries neither bytes nor a URL.
+func sourceImagePart(src *SourceImage) (geminiPart, bool) {
+	if src == nil {
+		return geminiPart{}, false
+	}
+	mime := strings.TrimSpace(src.MIME)
+	if len(src.Data) > 0 {
+		if mime == "" {
+			mime = http.DetectContentType(src.Data)
+		}
+		return geminiPart{InlineData: &geminiInlineData{
+			MimeType: mime,
+			Data:     base64.StdEncoding.EncodeToString(src.Data),
+		}}, true
+	}
+	if uri := strings.TrimSpace(src.URL); uri != "" {
+		return geminiPart{FileData: &geminiFileData{MimeType: mime, FileURI: uri}}, true
+	}
+	return geminiPart{}, false
+}
+
 type inlineAsset struct {
 	Data   []byte
 	Format string
@@ -599,7 +683,7 @@ func syntheticStorageKey(kind, model, seed string, index int, ext string) string
 	return fmt.Sprintf("synthetic/%s/%s-%s/%02d.%s", escapedModel, escapedKind, seed, index, ext)
 }
 
-func renderSyntheticImage(width, height int, seed, prompt string) []byte {
+func renderSyntheticImage(width, height int, seed, prompt, locale string) []byte {
 	if width <= 0 {
 		width = 1024
 	}
@@ -628,6 +712,7 @@ func renderSyntheticImage(width, height int, seed, prompt string) []byte {
 			img.Set(xx, y, diagonal)
 		}
 	}
+	drawSyntheticCaption(img, prompt, locale)
 
 	var buf bytes.Buffer
 	if err := png.Encode(&buf, img); err != nil {
@@ -636,10 +721,41 @@ func renderSyntheticImage(width, height int, seed, prompt string) []byte {
 	return buf.Bytes()
 }
 
-func renderSyntheticVideo(seed, prompt string) []byte {
-	lines := []string{
-		"Synthetic Gemini video placeholder", fmt.Sprintf("Seed: %s", seed), fmt.Sprintf("Prompt: %s", strings.TrimSpace(prompt)), "", "This placeholder represents where rendered video bytes would be stored once the", "Gemini video API integration is enabled."}
-	return []byte(strings.Join(lines, "\n"))
+// drawSyntheticCaption labels a placeholder with the prompt title and locale
+// on a dark band along the bottom edge so developers can tell outputs apart.
+func drawSyntheticCaption(img *image.RGBA, prompt, locale string) {
+	bounds := img.Bounds()
+	scale := maxInt(1, minInt(bounds.Dx(), bounds.Dy())/(bitmapfont.GlyphHeight*20))
+	margin := 4 * scale
+	lines := []string{syntheticCaptionTitle(prompt)}
+	if locale = strings.TrimSpace(locale); locale != "" {
+		lines = append(lines, "LOCALE: "+locale)
+	}
+	lineHeight := (bitmapfont.GlyphHeight + 3) * scale
+	maxChars := (bounds.Dx() - 2*margin) / ((bitmapfont.GlyphWidth + 1) * scale)
+	if maxChars <= 0 {
+		return
+	}
+
+	band := image.Rect(0, bounds.Dy()-len(lines)*lineHeight-2*margin, bounds.Dx(), bounds.Dy())
+	draw.Draw(img, band, &image.Uniform{color.RGBA{A: 0xb0}}, image.Point{}, draw.Over)
+	for i, line := range lines {
+		if runes := []rune(line); len(runes) > maxChars {
+			line = string(runes[:maxInt(maxChars-3, 0)]) + "..."
+		}
+		origin := image.Pt(margin, band.Min.Y+margin+i*lineHeight)
+		bitmapfont.Draw(img, origin, line, scale, color.White)
+	}
+}
+
+// syntheticCaptionTitle returns the first non-empty line of the prompt.
+func syntheticCaptionTitle(prompt string) string {
+	for _, line := range strings.Split(prompt, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return "SYNTHETIC IMAGE"
 }
 
 func colorFromSeed(seed string, shift int) color.RGBA {
@@ -713,6 +829,42 @@ func normalizeAspect(aspect string) (int, int) {
 	}
 }
 
+// geminiAspectRatios lists the aspect ratio tokens accepted by Gemini image
+// generation.
+var geminiAspectRatios = []string{"1:1", "2:3", "3:2", "3:4", "4:3", "4:5", "5:4", "9:16", "16:9", "21:9"}
+
+// geminiAspectRatio maps a requested aspect ratio onto the closest token Gemini
+// supports so the model does not reject or silently ignore it.
+func geminiAspectRatio(aspect string) string {
+	normalized := strings.TrimSpace(strings.ToLower(aspect))
+	for _, token := range geminiAspectRatios {
+		if normalized == token {
+			return token
+		}
+	}
+	width, height := normalizeAspect(aspect)
+	target := float64(width) / float64(height)
+	best := "1:1"
+	bestDelta := math.MaxFloat64
+	for _, token := range geminiAspectRatios {
+		w, h := normalizeAspect(token)
+		delta := math.Abs(float64(w)/float64(h) - target)
+		if delta < bestDelta {
+			best, bestDelta = token, delta
+		}
+	}
+	return best
+}
+
+func matchesAspect(width, height, wantWidth, wantHeight int) bool {
+	if width <= 0 || height <= 0 || wantWidth <= 0 || wantHeight <= 0 {
+		return true
+	}
+	got := float64(width) / float64(height)
+	want := float64(wantWidth) / float64(wantHeight)
+	return math.Abs(got-want)/want <= 0.02
+}
+
 func estimateVideoLength(prompt string) int {
 	words := len(strings.Fields(prompt))
 	if words == 0 {
@@ -0,0 +1,243 @@
+package genai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type captureTransport struct {
+	response []byte
+	lastBody []byte
+}
+
+func (c *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	c.lastBody = body
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(c.response)),
+	}, nil
+}
+
+func TestRemoteGenerateImagesSendsAspectRatio(t *testing.T) {
+	img := renderSyntheticImage(1920, 1080, "seed", "prompt", "en")
+	response, _ := json.Marshal(map[string]any{
+		"candidates": []any{
+			map[string]any{
+				"content": map[string]any{
+					"parts": []any{
+						map[string]any{"inlineData": map[string]any{
+							"mimeType": "image/png",
+							"data":     base64.StdEncoding.EncodeToString(img),
+						}},
+					},
+				},
+			},
+		},
+	})
+	transport := &captureTransport{response: response}
+	client, err := NewClient(Options{
+		APIKey:     "test",
+		HTTPClient: &http.Client{Transport: transport},
+	})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	assets, err := client.GenerateImages(context.Background(), ImageRequest{
+		Prompt:      "product shot",
+		Quantity:    1,
+		AspectRatio: "16:9",
+	})
+	if err != nil {
+		t.Fatalf("generate images: %v", err)
+	}
+	if len(assets) != 1 || assets[0].Width != 1920 || assets[0].Height != 1080 {
+		t.Fatalf("unexpected assets: %+v", assets)
+	}
+
+	var payload struct {
+		ToolConfig struct {
+			ImageGenerationConfig map[string]any `json:"imageGenerationConfig"`
+		} `json:"toolConfig"`
+	}
+	if err := json.Unmarshal(transport.lastBody, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if got := payload.ToolConfig.ImageGenerationConfig["aspectRatio"]; got != "16:9" {
+		t.Fatalf("aspectRatio = %v, want 16:9", got)
+	}
+}
+
+func TestGeminiAspectRatio(t *testing.T) {
+	cases := map[string]string{
+		"":       "1:1",
+		"square": "1:1",
+		"9:16":   "9:16",
+		" 4:5 ":  "4:5",
+		"2:1":    "16:9",
+		"1:2":    "9:16",
+		"7:5":    "4:3",
+	}
+	for in, want := range cases {
+		if got := geminiAspectRatio(in); got != want {
+			t.Errorf("geminiAspectRatio(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRenderSyntheticImageCaptionsPrompt(t *testing.T) {
+	first := renderSyntheticImage(512, 512, "a1b2c3d4e5f6", "Kopi Susu Gula Aren\nStyle: minimal", "id")
+	again := renderSyntheticImage(512, 512, "a1b2c3d4e5f6", "Kopi Susu Gula Aren\nStyle: minimal", "id")
+	other := renderSyntheticImage(512, 512, "a1b2c3d4e5f6", "Keripik Singkong Pedas\nStyle: minimal", "id")
+	if len(first) == 0 {
+		t.Fatalf("expected png bytes")
+	}
+	if !bytes.Equal(first, again) {
+		t.Fatalf("same seed and prompt should render identical bytes")
+	}
+	if bytes.Equal(first, other) {
+		t.Fatalf("different prompts should render different bytes")
+	}
+	if bytes.Equal(first, renderSyntheticImage(512, 512, "a1b2c3d4e5f6", "Kopi Susu Gula Aren\nStyle: minimal", "en")) {
+		t.Fatalf("different locales should render different bytes")
+	}
+}
+
+func TestGenerateWithoutAPIKeyHonoursSyntheticFallback(t *testing.T) {
+	synthetic, err := NewClient(Options{})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	assets, err := synthetic.GenerateImages(context.Background(), ImageRequest{Prompt: "kopi", Quantity: 1})
+	if err != nil || len(assets) != 1 || len(assets[0].Data) == 0 {
+		t.Fatalf("synthetic images = %d, err = %v", len(assets), err)
+	}
+	if video, err := synthetic.GenerateVideo(context.Background(), VideoRequest{Prompt: "kopi"}); err != nil || video == nil {
+		t.Fatalf("synthetic video = %v, err = %v", video, err)
+	}
+
+	strict, err := NewClient(Options{DisableSyntheticFallback: true})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, err := strict.GenerateImages(context.Background(), ImageRequest{Prompt: "kopi"}); !errors.Is(err, ErrMissingAPIKey) {
+		t.Fatalf("images err = %v, want ErrMissingAPIKey", err)
+	}
+	if _, err := strict.GenerateVideo(context.Background(), VideoRequest{Prompt: "kopi"}); !errors.Is(err, ErrMissingAPIKey) {
+		t.Fatalf("video err = %v, want ErrMissingAPIKey", err)
+	}
+}
+
+func TestSyntheticVideoIsMP4(t *testing.T) {
+	client, err := NewClient(Options{SyntheticVideoSeconds: 3})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	video, err := client.GenerateVideo(context.Background(), VideoRequest{Prompt: "kopi susu", RequestID: "req-1"})
+	if err != nil {
+		t.Fatalf("GenerateVideo: %v", err)
+	}
+	data := video.Data
+	if len(data) < 16 || string(data[4:8]) != "ftyp" || string(data[8:12]) != "isom" {
+		t.Fatalf("video does not start with an ftyp box: % x", data[:minInt(len(data), 16)])
+	}
+	if ftypSize := binary.BigEndian.Uint32(data[:4]); ftypSize < 16 || int(ftypSize) > len(data) {
+		t.Fatalf("ftyp size = %d", ftypSize)
+	}
+	if video.Length != 3 {
+		t.Fatalf("length = %d, want 3", video.Length)
+	}
+
+	var kinds []string
+	boxes := map[string][]byte{}
+	for offset := 0; offset < len(data); {
+		size := int(binary.BigEndian.Uint32(data[offset:]))
+		if size < 8 || offset+size > len(data) {
+			t.Fatalf("box at %d has size %d, file is %d bytes", offset, size, len(data))
+		}
+		kind := string(data[offset+4 : offset+8])
+		kinds = append(kinds, kind)
+		boxes[kind] = data[offset : offset+size]
+		offset += size
+	}
+	if strings.Join(kinds, ",") != "ftyp,free,moov,mdat" {
+		t.Fatalf("top-level boxes = %v", kinds)
+	}
+	moov := boxes["moov"]
+	stco := bytes.Index(moov, []byte("stco"))
+	if stco < 0 {
+		t.Fatal("moov has no stco box")
+	}
+	chunk := int(binary.BigEndian.Uint32(moov[stco+12:]))
+	if chunk+4 > len(data) || int(binary.BigEndian.Uint32(data[chunk:]))+4 != len(data)-chunk {
+		t.Fatalf("chunk offset %d does not point at the sample", chunk)
+	}
+	if data[chunk+4]&0x1f != 5 {
+		t.Fatalf("sample NAL type = %d, want IDR", data[chunk+4]&0x1f)
+	}
+	mvhd := bytes.Index(moov, []byte("mvhd"))
+	if timescale, duration := binary.BigEndian.Uint32(moov[mvhd+16:]), binary.BigEndian.Uint32(moov[mvhd+20:]); duration/timescale != 3 {
+		t.Fatalf("duration = %d/%d, want 3s", duration, timescale)
+	}
+
+	again, err := client.GenerateVideo(context.Background(), VideoRequest{Prompt: "kopi susu", RequestID: "req-1"})
+	if err != nil || !bytes.Equal(again.Data, data) {
+		t.Fatalf("synthetic video is not deterministic (err = %v)", err)
+	}
+}
+
+func TestGenerateVideoSendsSourceImage(t *testing.T) {
+	response, _ := json.Marshal(map[string]any{
+		"candidates": []any{
+			map[string]any{
+				"content": map[string]any{
+					"parts": []any{
+						map[string]any{"inlineData": map[string]any{
+							"mimeType": "video/mp4",
+							"data":     base64.StdEncoding.EncodeToString([]byte("mp4-bytes")),
+						}},
+					},
+				},
+			},
+		},
+	})
+	transport := &captureTransport{response: response}
+	client, err := NewClient(Options{APIKey: "test", HTTPClient: &http.Client{Transport: transport}})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	photo := []byte("product-photo")
+
+	if _, err := client.GenerateVideo(context.Background(), VideoRequest{
+		Prompt:      "animate the product",
+		SourceImage: &SourceImage{Data: photo, MIME: "image/jpeg"},
+	}); err != nil {
+		t.Fatalf("generate video: %v", err)
+	}
+
+	var payload geminiGenerateContentRequest
+	if err := json.Unmarshal(transport.lastBody, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	parts := payload.Contents[0].Parts
+	if len(parts) != 2 || parts[1].InlineData == nil {
+		t.Fatalf("parts = %+v, want prompt text and inline source image", parts)
+	}
+	if parts[1].InlineData.MimeType != "image/jpeg" || parts[1].InlineData.Data != base64.StdEncoding.EncodeToString(photo) {
+		t.Fatalf("inline source = %+v", parts[1].InlineData)
+	}
+}
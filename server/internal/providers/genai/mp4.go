@@ -0,0 +1,257 @@
+package genai
+
+import (
+	"encoding/binary"
+)
+
+// Synthetic videos are a single black H.264 frame held for the whole clip.
+// The frame is coded with I_PCM macroblocks so no encoder is needed, and the
+// stream stays within Constrained Baseline so browsers can play it.
+const (
+	syntheticVideoWidth     = 64
+	syntheticVideoHeight    = 64
+	syntheticVideoTimescale = 1000
+)
+
+// renderSyntheticVideo returns a playable MP4 of seconds length. The seed is
+// recorded in a free box so identical requests yield identical bytes.
+func renderSyntheticVideo(seed string, seconds int) []byte {
+	if seconds <= 0 {
+		seconds = 1
+	}
+	duration := uint32(seconds * syntheticVideoTimescale)
+	sps := h264SPS(syntheticVideoWidth, syntheticVideoHeight)
+	pps := h264PPS()
+	frame := h264BlackIDR(syntheticVideoWidth, syntheticVideoHeight)
+
+	sample := make([]byte, 4, 4+len(frame))
+	binary.BigEndian.PutUint32(sample, uint32(len(frame)))
+	sample = append(sample, frame...)
+
+	ftyp := mp4Box("ftyp", []byte("isom"), u32(0x200), []byte("isomiso2avc1mp41"))
+	free := mp4Box("free", []byte("seed:"+seed))
+	// The chunk offset depends on the moov size, which does not depend on
+	// the offset value, so build it once to measure and again to finalize.
+	moov := syntheticMoov(sps, pps, duration, uint32(len(sample)), 0)
+	offset := uint32(len(ftyp) + len(free) + len(moov) + 8)
+	moov = syntheticMoov(sps, pps, duration, uint32(len(sample)), offset)
+
+	out := make([]byte, 0, len(ftyp)+len(free)+len(moov)+8+len(sample))
+	out = append(out, ftyp...)
+	out = append(out, free...)
+	out = append(out, moov...)
+	return append(out, mp4Box("mdat", sample)...)
+}
+
+func syntheticMoov(sps, pps []byte, duration, sampleSize, chunkOffset uint32) []byte {
+	matrix := cat(u32(0x00010000), u32(0), u32(0), u32(0), u32(0x00010000), u32(0), u32(0), u32(0), u32(0x40000000))
+	mvhd := mp4FullBox("mvhd", 0, 0,
+		u32(0), u32(0), u32(syntheticVideoTimescale), u32(duration),
+		u32(0x00010000), u16(0x0100), make([]byte, 10), matrix, make([]byte, 24), u32(2))
+	tkhd := mp4FullBox("tkhd", 0, 3,
+		u32(0), u32(0), u32(1), u32(0), u32(duration), make([]byte, 8),
+		u16(0), u16(0), u16(0), u16(0), matrix,
+		u32(syntheticVideoWidth<<16), u32(syntheticVideoHeight<<16))
+	mdhd := mp4FullBox("mdhd", 0, 0,
+		u32(0), u32(0), u32(syntheticVideoTimescale), u32(duration), u16(0x55c4), u16(0))
+	hdlr := mp4FullBox("hdlr", 0, 0, u32(0), []byte("vide"), make([]byte, 12), []byte("VideoHandler\x00"))
+	vmhd := mp4FullBox("vmhd", 0, 1, make([]byte, 8))
+	dinf := mp4Box("dinf", mp4FullBox("dref", 0, 0, u32(1), mp4FullBox("url ", 0, 1)))
+
+	avcC := mp4Box("avcC",
+		[]byte{1, sps[1], sps[2], sps[3], 0xff, 0xe1}, u16(uint16(len(sps))), sps,
+		[]byte{1}, u16(uint16(len(pps))), pps)
+	compressor := make([]byte, 32)
+	avc1 := mp4Box("avc1",
+		make([]byte, 6), u16(1), make([]byte, 16),
+		u16(syntheticVideoWidth), u16(syntheticVideoHeight),
+		u32(0x00480000), u32(0x00480000), u32(0), u16(1), compressor,
+		u16(0x0018), u16(0xffff), avcC)
+	stbl := mp4Box("stbl",
+		mp4FullBox("stsd", 0, 0, u32(1), avc1),
+		mp4FullBox("stts", 0, 0, u32(1), u32(1), u32(duration)),
+		mp4FullBox("stss", 0, 0, u32(1), u32(1)),
+		mp4FullBox("stsc", 0, 0, u32(1), u32(1), u32(1), u32(1)),
+		mp4FullBox("stsz", 0, 0, u32(0), u32(1), u32(sampleSize)),
+		mp4FullBox("stco", 0, 0, u32(1), u32(chunkOffset)))
+	minf := mp4Box("minf", vmhd, dinf, stbl)
+	trak := mp4Box("trak", tkhd, mp4Box("mdia", mdhd, hdlr, minf))
+	return mp4Box("moov", mvhd, trak)
+}
+
+func mp4Box(kind string, payload ...[]byte) []byte {
+	body := cat(payload...)
+	out := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(out, uint32(8+len(body)))
+	copy(out[4:], kind)
+	return append(out, body...)
+}
+
+func mp4FullBox(kind string, version byte, flags uint32, payload ...[]byte) []byte {
+	header := u32(flags & 0x00ffffff)
+	header[0] = version
+	return mp4Box(kind, append([][]byte{header}, payload...)...)
+}
+
+func cat(parts ...[]byte) []byte {
+	var out []byte
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+func u32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func u16(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+// h264SPS returns a Baseline level 1.0 sequence parameter set NAL unit.
+func h264SPS(width, height int) []byte {
+	var w bitWriter
+	w.bits(66, 8)   // profile_idc: Baseline
+	w.bits(0xc0, 8) // constraint_set0_flag, constraint_set1_flag
+	w.bits(10, 8)   // level_idc
+	w.ue(0)         // seq_parameter_set_id
+	w.ue(0)         // log2_max_frame_num_minus4
+	w.ue(2)         // pic_order_cnt_type
+	w.ue(1)         // max_num_ref_frames
+	w.bits(0, 1)    // gaps_in_frame_num_value_allowed_flag
+	w.ue(uint32(width/16 - 1))
+	w.ue(uint32(height/16 - 1))
+	w.bits(1, 1) // frame_mbs_only_flag
+	w.bits(1, 1) // direct_8x8_inference_flag
+	w.bits(0, 1) // frame_cropping_flag
+	w.bits(0, 1) // vui_parameters_present_flag
+	return h264NAL(0x67, w.trailing())
+}
+
+// h264PPS returns a CAVLC picture parameter set NAL unit.
+func h264PPS() []byte {
+	var w bitWriter
+	w.ue(0)      // pic_parameter_set_id
+	w.ue(0)      // seq_parameter_set_id
+	w.bits(0, 1) // entropy_coding_mode_flag
+	w.bits(0, 1) // bottom_field_pic_order_in_frame_present_flag
+	w.ue(0)      // num_slice_groups_minus1
+	w.ue(0)      // num_ref_idx_l0_default_active_minus1
+	w.ue(0)      // num_ref_idx_l1_default_active_minus1
+	w.bits(0, 1) // weighted_pred_flag
+	w.bits(0, 2) // weighted_bipred_idc
+	w.se(0)      // pic_init_qp_minus26
+	w.se(0)      // pic_init_qs_minus26
+	w.se(0)      // chroma_qp_index_offset
+	w.bits(0, 1) // deblocking_filter_control_present_flag
+	w.bits(0, 1) // constrained_intra_pred_flag
+	w.bits(0, 1) // redundant_pic_cnt_present_flag
+	return h264NAL(0x68, w.trailing())
+}
+
+// h264BlackIDR returns an IDR slice NAL unit whose macroblocks are all I_PCM
+// with video-range black samples.
+func h264BlackIDR(width, height int) []byte {
+	var w bitWriter
+	w.ue(0)      // first_mb_in_slice
+	w.ue(7)      // slice_type: I, all slices
+	w.ue(0)      // pic_parameter_set_id
+	w.bits(0, 4) // frame_num
+	w.ue(0)      // idr_pic_id
+	w.bits(0, 1) // no_output_of_prior_pics_flag
+	w.bits(0, 1) // long_term_reference_flag
+	w.se(0)      // slice_qp_delta
+
+	luma := make([]byte, 256)
+	chroma := make([]byte, 128)
+	for i := range luma {
+		luma[i] = 16
+	}
+	for i := range chroma {
+		chroma[i] = 128
+	}
+	for mb := 0; mb < (width/16)*(height/16); mb++ {
+		w.ue(25) // mb_type: I_PCM
+		w.align()
+		w.bytes(luma)
+		w.bytes(chroma)
+	}
+	return h264NAL(0x65, w.trailing())
+}
+
+// h264NAL prefixes rbsp with the NAL header byte and inserts emulation
+// prevention bytes.
+func h264NAL(header byte, rbsp []byte) []byte {
+	out := []byte{header}
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros >= 2 && b <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+type bitWriter struct {
+	buf   []byte
+	cur   byte
+	nbits uint
+}
+
+func (w *bitWriter) bits(v uint32, n uint) {
+	for i := int(n) - 1; i >= 0; i-- {
+		w.cur = w.cur<<1 | byte(v>>uint(i)&1)
+		w.nbits++
+		if w.nbits == 8 {
+			w.buf = append(w.buf, w.cur)
+			w.cur, w.nbits = 0, 0
+		}
+	}
+}
+
+// ue writes v as an unsigned Exp-Golomb code.
+func (w *bitWriter) ue(v uint32) {
+	v++
+	n := uint(0)
+	for x := v; x > 1; x >>= 1 {
+		n++
+	}
+	w.bits(0, n)
+	w.bits(v, n+1)
+}
+
+// se writes v as a signed Exp-Golomb code.
+func (w *bitWriter) se(v int32) {
+	if v <= 0 {
+		w.ue(uint32(-2 * v))
+		return
+	}
+	w.ue(uint32(2*v - 1))
+}
+
+func (w *bitWriter) align() {
+	for w.nbits != 0 {
+		w.bits(0, 1)
+	}
+}
+
+func (w *bitWriter) bytes(data []byte) {
+	for _, b := range data {
+		w.bits(uint32(b), 8)
+	}
+}
+
+// trailing appends rbsp_trailing_bits and returns the written bytes.
+func (w *bitWriter) trailing() []byte {
+	w.bits(1, 1)
+	w.align()
+	return w.buf
+}
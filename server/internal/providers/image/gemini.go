@@ -40,4 +40,9 @@ func (g *GeminiGenerator) Generate(ctx context.Context, req GenerateRequest) ([]
 	return out, nil
 }
 
+// HasCredentials reports whether a Gemini API key is configured.
+func (g *GeminiGenerator) HasCredentials() bool {
+	return g != nil && g.client != nil && g.client.HasCredentials()
+}
+
 var _ Generator = (*GeminiGenerator)(nil)
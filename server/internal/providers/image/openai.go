@@ -0,0 +1,259 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdimage "image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOpenAIImageModel   = "gpt-image-1"
+	defaultOpenAIImageBaseURL = "https://api.openai.com/v1"
+	openAIImageTimeout        = 120 * time.Second
+)
+
+// ErrOpenAIMissingAPIKey is returned when no OpenAI key is configured and no
+// fallback generator is available.
+var ErrOpenAIMissingAPIKey = errors.New("openai image generator missing api key")
+
+// OpenAIOptions configures the OpenAI images API generator.
+type OpenAIOptions struct {
+	APIKey       string
+	Model        string
+	BaseURL      string
+	Organization string
+	HTTPClient   *http.Client
+}
+
+// OpenAIGenerator calls the OpenAI images API (dall-e-3, gpt-image-1) and
+// falls back to another generator when credentials are missing or the remote
+// call fails in a way the user cannot fix.
+type OpenAIGenerator struct {
+	apiKey       string
+	model        string
+	baseURL      string
+	organization string
+	client       *http.Client
+	fallback     Generator
+}
+
+type openAIImageRequest struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	N              int    `json:"n"`
+	Size           string `json:"size,omitempty"`
+	ResponseFormat string `json:"response_format,omitempty"`
+}
+
+type openAIImageResponse struct {
+	Data []struct {
+		B64JSON string `json:"b64_json"`
+		URL     string `json:"url"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+type openAIStatusError struct {
+	status  int
+	message string
+}
+
+func (e *openAIStatusError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("openai images: status %d", e.status)
+	}
+	return fmt.Sprintf("openai images: status %d: %s", e.status, e.message)
+}
+
+// NewOpenAIGenerator wires the OpenAI images API with an optional fallback
+// generator.
+func NewOpenAIGenerator(opts OpenAIOptions, fallback Generator) *OpenAIGenerator {
+	baseURL := strings.TrimRight(strings.TrimSpace(opts.BaseURL), "/")
+	if baseURL == "" {
+		baseURL = defaultOpenAIImageBaseURL
+	}
+	model := strings.ToLower(strings.TrimSpace(opts.Model))
+	if model == "" {
+		model = defaultOpenAIImageModel
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: openAIImageTimeout}
+	}
+	return &OpenAIGenerator{
+		apiKey:       strings.TrimSpace(opts.APIKey),
+		model:        model,
+		baseURL:      baseURL,
+		organization: strings.TrimSpace(opts.Organization),
+		client:       client,
+		fallback:     fallback,
+	}
+}
+
+// Generate fulfils the Generator interface. Each image is requested
+// separately because dall-e-3 only accepts n=1.
+func (g *OpenAIGenerator) Generate(ctx context.Context, req GenerateRequest) ([]Asset, error) {
+	if g == nil {
+		return nil, fmt.Errorf("openai generator not configured")
+	}
+	if g.apiKey == "" {
+		if g.fallback != nil {
+			return g.fallback.Generate(ctx, req)
+		}
+		return nil, ErrOpenAIMissingAPIKey
+	}
+	quantity := req.Quantity
+	if quantity <= 0 {
+		quantity = 1
+	}
+	prompt := strings.TrimSpace(req.Prompt)
+	if negative := strings.TrimSpace(req.NegativePrompt); negative != "" {
+		prompt = fmt.Sprintf("%s\nAvoid: %s", prompt, negative)
+	}
+	assets := make([]Asset, 0, quantity)
+	for i := 0; i < quantity; i++ {
+		asset, err := g.generateOne(ctx, buildVariationPrompt(prompt, quantity, i), req.AspectRatio)
+		if err != nil {
+			if shouldFallbackFromOpenAI(err) && g.fallback != nil {
+				return g.fallback.Generate(ctx, req)
+			}
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
+
+func (g *OpenAIGenerator) String() string {
+	if g == nil {
+		return "openai"
+	}
+	return g.model
+}
+
+// HasCredentials reports whether an OpenAI API key is configured.
+func (g *OpenAIGenerator) HasCredentials() bool {
+	return g != nil && g.apiKey != ""
+}
+
+var _ Generator = (*OpenAIGenerator)(nil)
+
+func (g *OpenAIGenerator) generateOne(ctx context.Context, prompt, aspect string) (Asset, error) {
+	payload := openAIImageRequest{
+		Model:  g.model,
+		Prompt: prompt,
+		N:      1,
+		Size:   openAIImageSize(g.model, aspect),
+	}
+	// gpt-image-1 always returns base64 and rejects response_format.
+	if strings.HasPrefix(g.model, "dall-e") {
+		payload.ResponseFormat = "b64_json"
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Asset{}, fmt.Errorf("openai images: encode request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+"/images/generations", bytes.NewReader(body))
+	if err != nil {
+		return Asset{}, fmt.Errorf("openai images: build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+g.apiKey)
+	if g.organization != "" {
+		httpReq.Header.Set("OpenAI-Organization", g.organization)
+	}
+	resp, err := g.client.Do(httpReq)
+	if err != nil {
+		return Asset{}, fmt.Errorf("openai images: request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return Asset{}, fmt.Errorf("openai images: read response: %w", err)
+	}
+	var out openAIImageResponse
+	decodeErr := json.Unmarshal(raw, &out)
+	if resp.StatusCode >= 300 {
+		statusErr := &openAIStatusError{status: resp.StatusCode}
+		if decodeErr == nil && out.Error != nil {
+			statusErr.message = strings.TrimSpace(out.Error.Message)
+		}
+		return Asset{}, statusErr
+	}
+	if decodeErr != nil {
+		return Asset{}, fmt.Errorf("openai images: decode response: %w", decodeErr)
+	}
+	if len(out.Data) == 0 || strings.TrimSpace(out.Data[0].B64JSON) == "" {
+		return Asset{}, errors.New("openai images: response contained no image data")
+	}
+	return decodeOpenAIImage(out.Data[0].B64JSON)
+}
+
+// decodeOpenAIImage normalizes a base64 payload into an Asset, sniffing the
+// format and dimensions from the decoded bytes.
+func decodeOpenAIImage(b64 string) (Asset, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b64))
+	if err != nil {
+		return Asset{}, fmt.Errorf("openai images: decode base64: %w", err)
+	}
+	asset := Asset{
+		Format: normalizeFormat(http.DetectContentType(data)),
+		Data:   data,
+	}
+	if cfg, _, err := stdimage.DecodeConfig(bytes.NewReader(data)); err == nil {
+		asset.Width = cfg.Width
+		asset.Height = cfg.Height
+	}
+	return asset, nil
+}
+
+// openAIImageSize maps an aspect ratio onto the sizes each model accepts.
+func openAIImageSize(model, aspect string) string {
+	landscape, portrait := "1536x1024", "1024x1536"
+	if strings.HasPrefix(model, "dall-e") {
+		landscape, portrait = "1792x1024", "1024x1792"
+	}
+	w, h, ok := parseRatio(aspect)
+	switch {
+	case !ok || w == h:
+		return "1024x1024"
+	case w > h:
+		return landscape
+	default:
+		return portrait
+	}
+}
+
+func parseRatio(aspect string) (int, int, bool) {
+	var w, h int
+	if _, err := fmt.Sscanf(strings.TrimSpace(aspect), "%d:%d", &w, &h); err != nil || w <= 0 || h <= 0 {
+		return 0, 0, false
+	}
+	return w, h, true
+}
+
+func shouldFallbackFromOpenAI(err error) bool {
+	var statusErr *openAIStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status == http.StatusUnauthorized ||
+			statusErr.status == http.StatusForbidden ||
+			statusErr.status == http.StatusTooManyRequests ||
+			statusErr.status >= 500
+	}
+	return err != nil && !errors.Is(err, context.Canceled)
+}
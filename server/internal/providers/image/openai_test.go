@@ -0,0 +1,113 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	stdimage "image"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type openAIRoundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f openAIRoundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func openAIResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestOpenAIGeneratorDecodesBase64Images(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, stdimage.NewNRGBA(stdimage.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	var requests []openAIImageRequest
+	client := &http.Client{Transport: openAIRoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.String() != "https://api.test/v1/images/generations" {
+			t.Fatalf("unexpected url %s", r.URL)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Fatalf("Authorization = %q", got)
+		}
+		var payload openAIImageRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		requests = append(requests, payload)
+		return openAIResponse(http.StatusOK, `{"data":[{"b64_json":"`+encoded+`"}]}`), nil
+	})}
+	gen := NewOpenAIGenerator(OpenAIOptions{APIKey: "sk-test", Model: "dall-e-3", BaseURL: "https://api.test/v1/", HTTPClient: client}, nil)
+
+	assets, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "kopi susu", Quantity: 2, AspectRatio: "16:9"})
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if len(assets) != 2 || len(requests) != 2 {
+		t.Fatalf("got %d assets from %d requests, want 2/2", len(assets), len(requests))
+	}
+	asset := assets[0]
+	if asset.Format != "image/png" || asset.Width != 3 || asset.Height != 2 || !bytes.Equal(asset.Data, buf.Bytes()) {
+		t.Fatalf("unexpected asset: format=%s %dx%d bytes=%d", asset.Format, asset.Width, asset.Height, len(asset.Data))
+	}
+	first := requests[0]
+	if first.Model != "dall-e-3" || first.N != 1 || first.Size != "1792x1024" || first.ResponseFormat != "b64_json" {
+		t.Fatalf("unexpected request: %+v", first)
+	}
+	if !strings.Contains(requests[1].Prompt, "Variation #2") {
+		t.Fatalf("second prompt should be a variation: %q", requests[1].Prompt)
+	}
+}
+
+func TestOpenAIGeneratorFallback(t *testing.T) {
+	fallback := &stubGenerator{assets: []Asset{{URL: "synthetic"}}}
+
+	missingKey := NewOpenAIGenerator(OpenAIOptions{}, fallback)
+	if _, err := missingKey.Generate(context.Background(), GenerateRequest{Prompt: "x"}); err != nil || fallback.calls != 1 {
+		t.Fatalf("missing key should use fallback: err=%v calls=%d", err, fallback.calls)
+	}
+
+	serverError := NewOpenAIGenerator(OpenAIOptions{APIKey: "sk-test", HTTPClient: &http.Client{Transport: openAIRoundTripFunc(func(*http.Request) (*http.Response, error) {
+		return openAIResponse(http.StatusServiceUnavailable, `{"error":{"message":"overloaded"}}`), nil
+	})}}, fallback)
+	if _, err := serverError.Generate(context.Background(), GenerateRequest{Prompt: "x"}); err != nil || fallback.calls != 2 {
+		t.Fatalf("5xx should use fallback: err=%v calls=%d", err, fallback.calls)
+	}
+
+	badRequest := NewOpenAIGenerator(OpenAIOptions{APIKey: "sk-test", HTTPClient: &http.Client{Transport: openAIRoundTripFunc(func(*http.Request) (*http.Response, error) {
+		return openAIResponse(http.StatusBadRequest, `{"error":{"message":"prompt rejected by safety system"}}`), nil
+	})}}, fallback)
+	_, err := badRequest.Generate(context.Background(), GenerateRequest{Prompt: "x"})
+	if err == nil || !strings.Contains(err.Error(), "safety system") || fallback.calls != 2 {
+		t.Fatalf("4xx should surface error: err=%v calls=%d", err, fallback.calls)
+	}
+}
+
+func TestOpenAIImageSize(t *testing.T) {
+	cases := []struct {
+		model, aspect, want string
+	}{
+		{"gpt-image-1", "1:1", "1024x1024"},
+		{"gpt-image-1", "16:9", "1536x1024"},
+		{"gpt-image-1", "4:5", "1024x1536"},
+		{"dall-e-3", "9:16", "1024x1792"},
+		{"dall-e-3", "", "1024x1024"},
+	}
+	for _, tc := range cases {
+		if got := openAIImageSize(tc.model, tc.aspect); got != tc.want {
+			t.Fatalf("openAIImageSize(%q, %q) = %q, want %q", tc.model, tc.aspect, got, tc.want)
+		}
+	}
+}
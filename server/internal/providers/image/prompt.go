@@ -10,6 +10,38 @@ import (
 // DefaultNegativePrompt captures undesirable artefacts we want the model to avoid.
 const DefaultNegativePrompt = "low quality, blurry, distorted, washed out, incorrect anatomy, extra limbs, text artefacts, watermark"
 
+// NegativePromptFor returns the base negative prompt for a product category:
+// the configured terms for category when there are any, otherwise
+// DefaultNegativePrompt.
+func NegativePromptFor(category string, byCategory map[string][]string) string {
+	if terms := byCategory[strings.ToLower(strings.TrimSpace(category))]; len(terms) > 0 {
+		return strings.Join(terms, ", ")
+	}
+	return DefaultNegativePrompt
+}
+
+// MergeNegativePrompt appends user supplied negative terms to base, dropping
+// empty and case-insensitive duplicate terms.
+func MergeNegativePrompt(base, user string) string {
+	seen := make(map[string]struct{})
+	terms := make([]string, 0, 16)
+	for _, source := range []string{base, user} {
+		for _, term := range strings.Split(source, ",") {
+			term = strings.Join(strings.Fields(term), " ")
+			if term == "" {
+				continue
+			}
+			key := strings.ToLower(term)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			terms = append(terms, term)
+		}
+	}
+	return strings.Join(terms, ", ")
+}
+
 // BuildMarketingPrompt converts the structured prompt JSON into a natural language
 // instruction tailored for text-to-image models. The prompt emphasises branding,
 // photography direction, locale, and any creative constraints required by the
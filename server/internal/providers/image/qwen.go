@@ -7,25 +7,49 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
+	"server/internal/providers/breaker"
 	"server/internal/providers/qwen"
 )
 
-// QwenGenerator orchestrates calls to DashScope's Qwen image model and falls back
-// to another generator (e.g. synthetic Gemini) when credentials are missing or
-// the remote call fails.
+// ErrQwenCircuitOpen is returned while the Qwen circuit breaker is open and
+// no fallback generator is configured.
+var ErrQwenCircuitOpen = errors.New("qwen circuit breaker open")
+
+// Reasons recorded in Asset.FallbackReason when the fallback generator
+// served a request.
+const (
+	FallbackNotConfigured       = "provider_not_configured"
+	FallbackMissingCredentials  = "missing_credentials"
+	FallbackCircuitOpen         = "circuit_open"
+	FallbackProviderUnavailable = "provider_unavailable"
+	FallbackProviderRejected    = "provider_rejected"
+)
+
+// qwenImageClient is the part of *qwen.Client the image generator uses.
+// SyntheticFallback reports whether a missing API key may be covered by the
+// fallback generator instead of failing the request.
 type qwenImageClient interface {
 	GenerateImage(context.Context, qwen.ImageRequest) (*qwen.ImageAsset, error)
 	HasCredentials() bool
+	SyntheticFallback() bool
 	Model() string
 }
 
+// qwenRegionChecker is implemented by clients that can route a request to a
+// named DashScope region.
+type qwenRegionChecker interface {
+	HasRegion(region string) bool
+}
+
 // QwenGenerator orchestrates calls to DashScope's Qwen image model and falls back
 // to another generator (e.g. synthetic Gemini) when credentials are missing or
 // the remote call fails.
 type QwenGenerator struct {
 	client   qwenImageClient
 	fallback Generator
+	breaker  *breaker.Breaker
 }
 
 // NewQwenGenerator wires a Qwen client with an optional fallback generator.
@@ -33,6 +57,13 @@ func NewQwenGenerator(client qwenImageClient, fallback Generator) *QwenGenerator
 	return &QwenGenerator{client: client, fallback: fallback}
 }
 
+// WithBreaker guards Qwen calls with b. While it is open requests go straight
+// to the fallback instead of waiting for DashScope to time out.
+func (g *QwenGenerator) WithBreaker(b *breaker.Breaker) *QwenGenerator {
+	g.breaker = b
+	return g
+}
+
 // Generate fulfils the Generator interface.
 func (g *QwenGenerator) Generate(ctx context.Context, req GenerateRequest) ([]Asset, error) {
 	if g == nil {
@@ -40,15 +71,21 @@ func (g *QwenGenerator) Generate(ctx context.Context, req GenerateRequest) ([]As
 	}
 	if g.client == nil {
 		if g.fallback != nil {
-			return g.fallback.Generate(ctx, req)
+			return g.generateFallback(ctx, req, FallbackNotConfigured)
 		}
 		return nil, fmt.Errorf("qwen generator not configured")
 	}
 	if !g.client.HasCredentials() {
-		if g.fallback != nil {
-			return g.fallback.Generate(ctx, req)
+		if g.fallback != nil && g.client.SyntheticFallback() {
+			return g.generateFallback(ctx, req, FallbackMissingCredentials)
+		}
+		return nil, fmt.Errorf("qwen generator missing credentials: %w", qwen.ErrMissingAPIKey)
+	}
+	region := strings.TrimSpace(req.Region)
+	if region != "" {
+		if checker, ok := g.client.(qwenRegionChecker); !ok || !checker.HasRegion(region) {
+			return nil, fmt.Errorf("%w %q", qwen.ErrUnknownRegion, region)
 		}
-		return nil, fmt.Errorf("qwen generator missing credentials")
 	}
 	quantity := req.Quantity
 	if quantity <= 0 {
@@ -65,12 +102,14 @@ func (g *QwenGenerator) Generate(ctx context.Context, req GenerateRequest) ([]As
 		Notes:           strings.TrimSpace(req.Workflow.Notes),
 	}
 	source := qwenSourceFromRequest(req.SourceImage)
-	assets := make([]Asset, 0, quantity)
-	for i := 0; i < quantity; i++ {
+	requests := make([]qwen.ImageRequest, quantity)
+	for i := range requests {
 		prompt := buildVariationPrompt(strings.TrimSpace(req.Prompt), quantity, i)
-		seed := deterministicSeed(req.RequestID, req.Provider, req.Locale, prompt, i)
-		workflow := derivedWorkflow(baseWorkflow, source)
-		imageReq := qwen.ImageRequest{
+		seed := req.Seed
+		if seed <= 0 {
+			seed = deterministicSeed(req.RequestID, req.Provider, req.Locale, prompt, i)
+		}
+		requests[i] = qwen.ImageRequest{
 			Prompt:         prompt,
 			NegativePrompt: strings.TrimSpace(req.NegativePrompt),
 			Size:           size,
@@ -78,29 +117,124 @@ func (g *QwenGenerator) Generate(ctx context.Context, req GenerateRequest) ([]As
 			RequestID:      req.RequestID,
 			Quality:        strings.TrimSpace(req.Quality),
 			Locale:         strings.TrimSpace(req.Locale),
-			Workflow:       workflow,
+			Workflow:       derivedWorkflow(baseWorkflow, source),
 			SourceImage:    source,
+			Region:         region,
 		}
+	}
 
-		asset, err := g.invokeQwen(ctx, imageReq)
-		if err != nil {
-			if shouldFallbackToSynthetic(err) && g.fallback != nil {
-				return g.fallback.Generate(ctx, req)
+	results := g.generateVariations(ctx, requests, req.Seed > 0)
+	var fallbackErr error
+	for _, res := range results {
+		switch {
+		case res.err == nil:
+		case errors.Is(res.err, errVariationAborted):
+		case errors.Is(res.err, ErrQwenCircuitOpen), shouldFallbackToSynthetic(res.err):
+			if fallbackErr == nil {
+				fallbackErr = res.err
 			}
-			return nil, err
+		default:
+			return nil, res.err
+		}
+	}
+	if fallbackErr != nil {
+		if g.fallback != nil {
+			return g.generateFallback(ctx, req, fallbackReason(fallbackErr))
 		}
-		assets = append(assets, Asset{
+		return nil, fallbackErr
+	}
+	assets := make([]Asset, len(results))
+	for i, res := range results {
+		assets[i] = Asset{
 			StorageKey: "",
-			URL:        asset.URL,
-			Format:     normalizeFormat(asset.Format),
-			Width:      asset.Width,
-			Height:     asset.Height,
-			Data:       asset.Data,
-		})
+			URL:        res.asset.URL,
+			Format:     normalizeFormat(res.asset.Format),
+			Width:      res.asset.Width,
+			Height:     res.asset.Height,
+			Data:       res.asset.Data,
+			Seed:       res.asset.seed,
+		}
 	}
 	return assets, nil
 }
 
+// generateFallback serves req from the fallback generator and tags every
+// asset with reason so callers can tell the result is degraded.
+func (g *QwenGenerator) generateFallback(ctx context.Context, req GenerateRequest, reason string) ([]Asset, error) {
+	assets, err := g.fallback.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	tagged := make([]Asset, len(assets))
+	for i, asset := range assets {
+		if asset.FallbackReason == "" {
+			asset.FallbackReason = reason
+		}
+		tagged[i] = asset
+	}
+	return tagged, nil
+}
+
+// maxConcurrentVariations caps how many Qwen calls one Generate runs at once.
+const maxConcurrentVariations = 4
+
+// errVariationAborted marks variations skipped or cancelled because a sibling
+// call already failed.
+var errVariationAborted = errors.New("qwen variation aborted")
+
+type variationResult struct {
+	asset seededAsset
+	err   error
+}
+
+// generateVariations runs requests on a pool of up to maxConcurrentVariations
+// workers. Results keep the request order; the first failure cancels the
+// calls still outstanding.
+func (g *QwenGenerator) generateVariations(ctx context.Context, requests []qwen.ImageRequest, keepSeed bool) []variationResult {
+	results := make([]variationResult, len(requests))
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(len(requests), maxConcurrentVariations) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if callCtx.Err() != nil && ctx.Err() == nil {
+					results[i].err = errVariationAborted
+					continue
+				}
+				asset, err := g.generateVariation(callCtx, requests[i], keepSeed)
+				if err != nil && callCtx.Err() != nil && ctx.Err() == nil {
+					err = errVariationAborted
+				}
+				results[i] = variationResult{asset: asset, err: err}
+				if err != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// generateVariation makes one breaker-guarded Qwen call.
+func (g *QwenGenerator) generateVariation(ctx context.Context, req qwen.ImageRequest, keepSeed bool) (seededAsset, error) {
+	if !g.breaker.Allow() {
+		return seededAsset{}, ErrQwenCircuitOpen
+	}
+	asset, err := g.invokeQwen(ctx, req, keepSeed)
+	g.recordBreakerOutcome(err)
+	return asset, err
+}
+
 func (g *QwenGenerator) String() string {
 	if g == nil || g.client == nil {
 		return "qwen"
@@ -108,23 +242,77 @@ func (g *QwenGenerator) String() string {
 	return g.client.Model()
 }
 
+// HasCredentials reports whether a DashScope API key is configured.
+func (g *QwenGenerator) HasCredentials() bool {
+	return g != nil && g.client != nil && g.client.HasCredentials()
+}
+
+// SupportsSourceImage reports that Qwen honours GenerateRequest.SourceImage,
+// so it can edit an uploaded product photo rather than only generate.
+func (g *QwenGenerator) SupportsSourceImage() bool {
+	return true
+}
+
 var _ Generator = (*QwenGenerator)(nil)
 
-func (g *QwenGenerator) invokeQwen(ctx context.Context, req qwen.ImageRequest) (*qwen.ImageAsset, error) {
+// seededAsset pairs a Qwen result with the seed of the request that produced it.
+type seededAsset struct {
+	*qwen.ImageAsset
+	seed int
+}
+
+// invokeQwen calls Qwen and retries once with a simplified payload on
+// parameter errors. A caller-supplied seed survives the retry so the result
+// stays reproducible.
+func (g *QwenGenerator) invokeQwen(ctx context.Context, req qwen.ImageRequest, keepSeed bool) (seededAsset, error) {
 	asset, err := g.client.GenerateImage(ctx, req)
 	if err == nil {
-		return asset, nil
+		return seededAsset{ImageAsset: asset, seed: req.Seed}, nil
 	}
 	if !shouldRetryQwenError(err) {
-		return nil, err
+		return seededAsset{}, err
 	}
 
 	simplified := simplifyQwenRequest(req)
+	if keepSeed {
+		simplified.Seed = req.Seed
+	}
 	asset, retryErr := g.client.GenerateImage(ctx, simplified)
 	if retryErr != nil {
-		return nil, retryErr
+		return seededAsset{}, retryErr
+	}
+	return seededAsset{ImageAsset: asset, seed: simplified.Seed}, nil
+}
+
+// recordBreakerOutcome feeds a call result to the breaker. Rejected
+// parameters mean DashScope answered, so only outages count as failures;
+// a cancelled call records nothing.
+func (g *QwenGenerator) recordBreakerOutcome(err error) {
+	switch {
+	case err == nil:
+		g.breaker.Success()
+	case errors.Is(err, context.Canceled):
+		g.breaker.Release()
+	case shouldRetryQwenError(err) && !isTransientQwenError(err):
+		g.breaker.Success()
+	default:
+		g.breaker.Failure()
+	}
+}
+
+// fallbackReason classifies an error accepted by shouldFallbackToSynthetic or
+// the breaker into one of the Fallback* reasons.
+func fallbackReason(err error) string {
+	switch {
+	case errors.Is(err, ErrQwenCircuitOpen):
+		return FallbackCircuitOpen
+	case errors.Is(err, qwen.ErrMissingAPIKey):
+		return FallbackMissingCredentials
+	case isTransientQwenError(err):
+		return FallbackProviderUnavailable
+	default:
+		return FallbackProviderRejected
 	}
-	return asset, nil
 }
 
 func shouldFallbackToSynthetic(err error) bool {
@@ -3,13 +3,18 @@ package image
 import (
 	"context"
 	"errors"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"server/internal/providers/breaker"
 	"server/internal/providers/qwen"
 )
 
 type stubQwenClient struct {
+	mu             sync.Mutex
 	asset          *qwen.ImageAsset
 	err            error
 	hasCredentials bool
@@ -18,9 +23,12 @@ type stubQwenClient struct {
 	lastReq        qwen.ImageRequest
 	requests       []qwen.ImageRequest
 	queue          []stubQwenResponse
+	noSynthetic    bool
 }
 
 func (s *stubQwenClient) GenerateImage(ctx context.Context, req qwen.ImageRequest) (*qwen.ImageAsset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.calls++
 	s.lastReq = req
 	s.requests = append(s.requests, req)
@@ -40,6 +48,10 @@ func (s *stubQwenClient) GenerateImage(ctx context.Context, req qwen.ImageReques
 	return s.asset, nil
 }
 
+func (s *stubQwenClient) SyntheticFallback() bool {
+	return !s.noSynthetic
+}
+
 func (s *stubQwenClient) HasCredentials() bool {
 	return s.hasCredentials
 }
@@ -87,6 +99,23 @@ func TestQwenGeneratorFallsBackWhenNoCredentials(t *testing.T) {
 	if len(assets) != 1 || assets[0].URL != "fallback" {
 		t.Fatalf("unexpected assets: %#v", assets)
 	}
+	if assets[0].FallbackReason != FallbackMissingCredentials {
+		t.Fatalf("fallback reason = %q, want %q", assets[0].FallbackReason, FallbackMissingCredentials)
+	}
+}
+
+func TestQwenGeneratorFailsWithoutCredentialsWhenSyntheticDisabled(t *testing.T) {
+	fallback := &stubGenerator{assets: []Asset{{URL: "fallback"}}}
+	client := &stubQwenClient{hasCredentials: false, noSynthetic: true}
+
+	gen := NewQwenGenerator(client, fallback)
+	_, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "hello"})
+	if !errors.Is(err, qwen.ErrMissingAPIKey) {
+		t.Fatalf("err = %v, want qwen.ErrMissingAPIKey", err)
+	}
+	if fallback.calls != 0 || client.calls != 0 {
+		t.Fatalf("fallback calls = %d, client calls = %d; want none", fallback.calls, client.calls)
+	}
 }
 
 func TestQwenGeneratorFallsBackOnMissingAPIKeyError(t *testing.T) {
@@ -109,6 +138,9 @@ func TestQwenGeneratorFallsBackOnMissingAPIKeyError(t *testing.T) {
 	if len(assets) != 1 || assets[0].URL != "synthetic" {
 		t.Fatalf("unexpected assets: %#v", assets)
 	}
+	if assets[0].FallbackReason != FallbackMissingCredentials {
+		t.Fatalf("fallback reason = %q, want %q", assets[0].FallbackReason, FallbackMissingCredentials)
+	}
 }
 
 func TestQwenGeneratorFallsBackOnInternalError(t *testing.T) {
@@ -131,6 +163,12 @@ func TestQwenGeneratorFallsBackOnInternalError(t *testing.T) {
 	if len(assets) != 1 || assets[0].URL != "synthetic" {
 		t.Fatalf("unexpected assets: %#v", assets)
 	}
+	if assets[0].FallbackReason != FallbackProviderUnavailable {
+		t.Fatalf("fallback reason = %q, want %q", assets[0].FallbackReason, FallbackProviderUnavailable)
+	}
+	if fallback.assets[0].FallbackReason != "" {
+		t.Fatalf("fallback generator's assets were modified: %#v", fallback.assets)
+	}
 }
 
 func TestQwenGeneratorReturnsErrorWhenRemoteFails(t *testing.T) {
@@ -244,3 +282,251 @@ func TestQwenGeneratorDefaultsEnhanceWorkflowForSourceImage(t *testing.T) {
 		t.Fatalf("workflow mode = %q, want %q", got, WorkflowModeEnhance)
 	}
 }
+
+func TestQwenGeneratorHonoursCallerSeed(t *testing.T) {
+	generated := &qwen.ImageAsset{URL: "https://example.com/image.png", Format: "image/png", Width: 1024, Height: 1024}
+	run := func(requestID string) ([]qwen.ImageRequest, []Asset) {
+		client := &stubQwenClient{hasCredentials: true, asset: generated}
+		gen := NewQwenGenerator(client, nil)
+		assets, err := gen.Generate(context.Background(), GenerateRequest{
+			Prompt:    "hello",
+			Quantity:  2,
+			RequestID: requestID,
+			Seed:      12345,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Variations run concurrently, so compare them in prompt order.
+		sort.Slice(client.requests, func(i, j int) bool { return client.requests[i].Prompt < client.requests[j].Prompt })
+		return client.requests, assets
+	}
+
+	first, assets := run("job-1")
+	second, _ := run("job-2")
+	if len(first) != 2 || len(second) != 2 {
+		t.Fatalf("request counts = %d, %d; want 2", len(first), len(second))
+	}
+	for i := range first {
+		if first[i].Seed != 12345 {
+			t.Fatalf("request %d seed = %d, want 12345", i, first[i].Seed)
+		}
+		a, b := first[i], second[i]
+		a.RequestID, b.RequestID = "", ""
+		if a != b {
+			t.Fatalf("request %d payload differs across runs:\n%#v\n%#v", i, a, b)
+		}
+	}
+	for i, asset := range assets {
+		if asset.Seed != 12345 {
+			t.Fatalf("asset %d seed = %d, want 12345", i, asset.Seed)
+		}
+	}
+}
+
+func TestQwenGeneratorKeepsCallerSeedOnRetry(t *testing.T) {
+	generated := &qwen.ImageAsset{URL: "https://example.com/image.png", Format: "image/png"}
+	client := &stubQwenClient{
+		hasCredentials: true,
+		queue: []stubQwenResponse{
+			{err: errors.New("qwen: status 400: invalid parameter locale")},
+			{asset: generated},
+		},
+	}
+	gen := NewQwenGenerator(client, nil)
+	assets, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "hello", Locale: "id", Seed: 77})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.requests) != 2 || client.requests[1].Seed != 77 {
+		t.Fatalf("retry should keep caller seed, got %#v", client.requests)
+	}
+	if assets[0].Seed != 77 {
+		t.Fatalf("asset seed = %d, want 77", assets[0].Seed)
+	}
+}
+
+func TestQwenGeneratorCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	fallback := &stubGenerator{assets: []Asset{{URL: "synthetic"}}}
+	client := &stubQwenClient{
+		hasCredentials: true,
+		err:            errors.New("qwen: status 503: service unavailable"),
+	}
+	cb := breaker.New(breaker.Options{Threshold: 2, Cooldown: time.Minute, Now: func() time.Time { return now }})
+	gen := NewQwenGenerator(client, fallback).WithBreaker(cb)
+	generate := func(wantReason string) {
+		t.Helper()
+		assets, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "sample"})
+		if err != nil || len(assets) != 1 {
+			t.Fatalf("assets = %#v, err = %v", assets, err)
+		}
+		if assets[0].FallbackReason != wantReason {
+			t.Fatalf("fallback reason = %q, want %q", assets[0].FallbackReason, wantReason)
+		}
+	}
+
+	generate(FallbackProviderUnavailable)
+	generate(FallbackProviderUnavailable)
+	if cb.State() != breaker.Open {
+		t.Fatalf("state = %s after two outages, want open", cb.State())
+	}
+	calls := client.calls
+	generate(FallbackCircuitOpen)
+	if client.calls != calls {
+		t.Fatalf("open breaker still called qwen")
+	}
+	if fallback.calls != 3 {
+		t.Fatalf("fallback calls = %d, want 3", fallback.calls)
+	}
+
+	now = now.Add(time.Minute)
+	client.err = nil
+	client.asset = &qwen.ImageAsset{URL: "https://example.com/recovered.png", Format: "image/png"}
+	assets, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "sample"})
+	if err != nil || len(assets) != 1 || assets[0].URL != "https://example.com/recovered.png" {
+		t.Fatalf("probe after cooldown: assets = %#v, err = %v", assets, err)
+	}
+	if cb.State() != breaker.Closed {
+		t.Fatalf("state = %s after successful probe, want closed", cb.State())
+	}
+}
+
+func TestQwenGeneratorCircuitOpenWithoutFallback(t *testing.T) {
+	client := &stubQwenClient{hasCredentials: true, err: errors.New("qwen: request timeout")}
+	cb := breaker.New(breaker.Options{Threshold: 1, Cooldown: time.Hour})
+	gen := NewQwenGenerator(client, nil).WithBreaker(cb)
+	if _, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "sample"}); err == nil {
+		t.Fatalf("expected the first outage to surface")
+	}
+	if _, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "sample"}); !errors.Is(err, ErrQwenCircuitOpen) {
+		t.Fatalf("err = %v, want ErrQwenCircuitOpen", err)
+	}
+}
+
+func TestQwenGeneratorParameterErrorsDoNotTripBreaker(t *testing.T) {
+	client := &stubQwenClient{hasCredentials: true, err: errors.New("qwen: status 400: invalid parameter size")}
+	cb := breaker.New(breaker.Options{Threshold: 1})
+	gen := NewQwenGenerator(client, nil).WithBreaker(cb)
+	_, _ = gen.Generate(context.Background(), GenerateRequest{Prompt: "sample"})
+	if cb.State() != breaker.Closed {
+		t.Fatalf("state = %s after a parameter error, want closed", cb.State())
+	}
+}
+
+// concurrentQwenClient holds every call until want calls are in flight, so
+// a test can tell the variations were issued concurrently.
+type concurrentQwenClient struct {
+	want     int
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	ready    chan struct{}
+	failOn   string
+}
+
+func (c *concurrentQwenClient) GenerateImage(ctx context.Context, req qwen.ImageRequest) (*qwen.ImageAsset, error) {
+	c.mu.Lock()
+	c.inFlight++
+	c.peak = max(c.peak, c.inFlight)
+	if c.inFlight == c.want {
+		close(c.ready)
+	}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+	select {
+	case <-c.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(2 * time.Second):
+		return nil, errors.New("variations were not issued concurrently")
+	}
+	if c.failOn != "" && strings.Contains(req.Prompt, c.failOn) {
+		return nil, errors.New("qwen: status 400: content policy violation")
+	}
+	return &qwen.ImageAsset{URL: "https://example.com/" + req.Prompt, Format: "image/png"}, nil
+}
+
+func (c *concurrentQwenClient) HasCredentials() bool { return true }
+
+func (c *concurrentQwenClient) SyntheticFallback() bool { return true }
+
+func (c *concurrentQwenClient) Model() string { return "qwen-image-plus" }
+
+func TestQwenGeneratorRunsVariationsConcurrently(t *testing.T) {
+	client := &concurrentQwenClient{want: 3, ready: make(chan struct{})}
+	gen := NewQwenGenerator(client, nil)
+
+	assets, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "kopi", Quantity: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.peak != 3 {
+		t.Fatalf("peak concurrency = %d, want 3", client.peak)
+	}
+	if len(assets) != 3 {
+		t.Fatalf("got %d assets, want 3", len(assets))
+	}
+	for i, asset := range assets {
+		want := "https://example.com/" + buildVariationPrompt("kopi", 3, i)
+		if asset.URL != want {
+			t.Fatalf("asset %d url = %q, want %q", i, asset.URL, want)
+		}
+	}
+}
+
+func TestQwenGeneratorVariationErrorSkipsFallback(t *testing.T) {
+	client := &concurrentQwenClient{want: 2, ready: make(chan struct{}), failOn: buildVariationPrompt("kopi", 2, 1)}
+	fallback := &stubGenerator{assets: []Asset{{URL: "fallback"}}}
+	gen := NewQwenGenerator(client, fallback)
+
+	_, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "kopi", Quantity: 2})
+	if err == nil || !strings.Contains(err.Error(), "content policy") {
+		t.Fatalf("err = %v, want the variation's content policy error", err)
+	}
+	if fallback.calls != 0 {
+		t.Fatalf("fallback called %d times for a non-transient error", fallback.calls)
+	}
+}
+
+func TestQwenGeneratorRejectsUnknownRegion(t *testing.T) {
+	client := &stubQwenClient{hasCredentials: true, asset: &qwen.ImageAsset{URL: "https://example.com/out.png"}}
+	fallback := &stubGenerator{assets: []Asset{{URL: "fallback"}}}
+	gen := NewQwenGenerator(client, fallback)
+
+	_, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "kopi", Quantity: 1, Region: "cn"})
+	if !errors.Is(err, qwen.ErrUnknownRegion) {
+		t.Fatalf("error = %v, want ErrUnknownRegion", err)
+	}
+	if client.calls != 0 || fallback.calls != 0 {
+		t.Fatalf("unknown region reached a provider: qwen=%d fallback=%d", client.calls, fallback.calls)
+	}
+}
+
+func TestQwenGeneratorPassesRegionToClient(t *testing.T) {
+	client := &regionStubQwenClient{
+		stubQwenClient: stubQwenClient{hasCredentials: true, asset: &qwen.ImageAsset{URL: "https://example.com/out.png"}},
+		regions:        map[string]bool{"cn": true},
+	}
+	gen := NewQwenGenerator(client, nil)
+
+	if _, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "kopi", Quantity: 1, Region: "cn"}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if client.lastReq.Region != "cn" {
+		t.Fatalf("client region = %q, want cn", client.lastReq.Region)
+	}
+}
+
+type regionStubQwenClient struct {
+	stubQwenClient
+	regions map[string]bool
+}
+
+func (s *regionStubQwenClient) HasRegion(region string) bool {
+	return s.regions[region]
+}
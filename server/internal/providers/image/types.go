@@ -50,6 +50,11 @@ type GenerateRequest struct {
 	NegativePrompt string
 	Workflow       Workflow
 	SourceImage    *SourceImage
+	// Seed pins the provider seed when positive; otherwise one is derived
+	// from the request.
+	Seed int
+	// Region is a provider region hint, such as a DashScope endpoint name.
+	Region string
 }
 
 // Asset represents a generated or edited image.
@@ -60,6 +65,11 @@ type Asset struct {
 	Width      int
 	Height     int
 	Data       []byte
+	// Seed is the provider seed that produced the asset, when known.
+	Seed int
+	// FallbackReason is set when a fallback generator produced the asset
+	// instead of the requested provider, e.g. "missing_credentials".
+	FallbackReason string
 }
 
 // Generator is the contract implemented by all image providers.
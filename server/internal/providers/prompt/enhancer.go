@@ -32,7 +32,8 @@ type EnhanceResponse struct {
 
 type Enhancer interface {
 	Enhance(ctx context.Context, req EnhanceRequest) (*EnhanceResponse, error)
-	Random(ctx context.Context, locale string) ([]EnhanceResponse, error)
+	// Random returns count prompt ideas; callers clamp count with ClampRandomCount.
+	Random(ctx context.Context, locale string, count int) ([]EnhanceResponse, error)
 }
 
 type StaticEnhancer struct{}
@@ -69,13 +70,15 @@ func (s *StaticEnhancer) Enhance(ctx context.Context, req EnhanceRequest) (*Enha
 	return res, nil
 }
 
-func (s *StaticEnhancer) Random(ctx context.Context, locale string) ([]EnhanceResponse, error) {
+func (s *StaticEnhancer) Random(ctx context.Context, locale string, count int) ([]EnhanceResponse, error) {
 	items := []EnhanceResponse{
 		{Title: "Nasi Uduk Rempah", Description: "Hidangan sarapan khas Betawi", Keywords: []string{"nasi", "rempah"}, Metadata: map[string]string{"locale": locale}, Provider: staticProviderName},
 		{Title: "Es Kopi Gula Aren", Description: "Minuman kekinian untuk UMKM", Keywords: []string{"kopi", "gula aren"}, Metadata: map[string]string{"locale": locale}, Provider: staticProviderName},
 		{Title: "Kue Lapis Legit", Description: "Dessert klasik Nusantara", Keywords: []string{"dessert", "nusantara"}, Metadata: map[string]string{"locale": locale}, Provider: staticProviderName},
+		{Title: "Keripik Tempe Renyah", Description: "Camilan gurih oleh-oleh khas daerah", Keywords: []string{"camilan", "tempe"}, Metadata: map[string]string{"locale": locale}, Provider: staticProviderName},
+		{Title: "Batik Tulis Modern", Description: "Kain batik dengan motif kontemporer", Keywords: []string{"batik", "fashion"}, Metadata: map[string]string{"locale": locale}, Provider: staticProviderName},
 	}
-	return items, nil
+	return items[:ClampRandomCount(count)], nil
 }
 
 var _ Enhancer = (*StaticEnhancer)(nil)
@@ -121,11 +121,14 @@ func (g *GeminiEnhancer) Enhance(ctx context.Context, req EnhanceRequest) (*Enha
 	if err != nil {
 		return g.useFallback(ctx, req, "parse_payload", err)
 	}
+	if err := validateEnhancePayload(parsed); err != nil {
+		return g.useFallback(ctx, req, "invalid_payload", err)
+	}
 	response := &EnhanceResponse{
 		Title:       coalesce(parsed.Title, req.Prompt.Title),
 		Description: coalesce(parsed.Description, req.Prompt.Instructions),
 		Keywords:    normalizeKeywords(parsed.Keywords, req.Prompt.ProductType),
-		Metadata:    ensureMetadata(parsed.Metadata, req.Locale),
+		Metadata:    enhanceMetadata(parsed.Metadata, coalesce(req.Locale, req.Prompt.Extras.Locale)),
 		Provider:    geminiProviderName,
 	}
 	if len(parsed.Ideas) > 0 {
@@ -147,14 +150,14 @@ func (g *GeminiEnhancer) Enhance(ctx context.Context, req EnhanceRequest) (*Enha
 	return response, nil
 }
 
-func (g *GeminiEnhancer) Random(ctx context.Context, locale string) ([]EnhanceResponse, error) {
+func (g *GeminiEnhancer) Random(ctx context.Context, locale string, count int) ([]EnhanceResponse, error) {
 	if g.apiKey == "" {
-		return g.useFallbackRandom(ctx, locale, "missing_api_key", nil)
+		return g.useFallbackRandom(ctx, locale, count, "missing_api_key", nil)
 	}
 	payload := geminiRequest{
 		SystemInstruction: &geminiContent{Parts: []geminiPart{{Text: "You are a helpful marketing assistant that always responds with valid JSON."}}},
 		Contents: []geminiContent{
-			{Role: "user", Parts: []geminiPart{{Text: buildRandomPromptPayload(locale)}}},
+			{Role: "user", Parts: []geminiPart{{Text: buildRandomPromptPayload(locale, count)}}},
 		},
 		GenerationConfig: &geminiGenerationConfig{
 			Temperature:      0.7,
@@ -163,14 +166,17 @@ func (g *GeminiEnhancer) Random(ctx context.Context, locale string) ([]EnhanceRe
 	}
 	text, reason, err := g.call(ctx, payload)
 	if err != nil {
-		return g.useFallbackRandom(ctx, locale, reason, err)
+		return g.useFallbackRandom(ctx, locale, count, reason, err)
 	}
 	parsed, err := parseModelPayload[modelRandomPayload](text)
 	if err != nil {
-		return g.useFallbackRandom(ctx, locale, "parse_payload", err)
+		return g.useFallbackRandom(ctx, locale, count, "parse_payload", err)
 	}
 	if len(parsed.Items) == 0 {
-		return g.useFallbackRandom(ctx, locale, "empty_items", errors.New("no items returned"))
+		return g.useFallbackRandom(ctx, locale, count, "empty_items", errors.New("no items returned"))
+	}
+	if count = ClampRandomCount(count); len(parsed.Items) > count {
+		parsed.Items = parsed.Items[:count]
 	}
 	var results []EnhanceResponse
 	for _, item := range parsed.Items {
@@ -217,10 +223,10 @@ func (g *GeminiEnhancer) useFallback(ctx context.Context, req EnhanceRequest, re
 	return res, err
 }
 
-func (g *GeminiEnhancer) useFallbackRandom(ctx context.Context, locale string, reason string, fallbackErr error) ([]EnhanceResponse, error) {
+func (g *GeminiEnhancer) useFallbackRandom(ctx context.Context, locale string, count int, reason string, fallbackErr error) ([]EnhanceResponse, error) {
 	g.emitFallback(reason, fallbackErr)
 	if g.fallback != nil {
-		items, err := g.fallback.Random(ctx, locale)
+		items, err := g.fallback.Random(ctx, locale, count)
 		for i := range items {
 			if items[i].Provider == "" {
 				items[i].Provider = staticProviderName
@@ -235,7 +241,7 @@ func (g *GeminiEnhancer) useFallbackRandom(ctx context.Context, locale string, r
 		return items, err
 	}
 	fallback := NewStaticEnhancer()
-	items, err := fallback.Random(ctx, locale)
+	items, err := fallback.Random(ctx, locale, count)
 	for i := range items {
 		items[i].Provider = staticProviderName
 		if items[i].Metadata == nil {
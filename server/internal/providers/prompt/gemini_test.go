@@ -1,9 +1,13 @@
 package prompt
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"server/internal/domain/jsoncfg"
@@ -27,7 +31,7 @@ func (f fakeEnhancer) Enhance(ctx context.Context, req EnhanceRequest) (*Enhance
 	return nil, errors.New("enhance not implemented")
 }
 
-func (f fakeEnhancer) Random(ctx context.Context, locale string) ([]EnhanceResponse, error) {
+func (f fakeEnhancer) Random(ctx context.Context, locale string, count int) ([]EnhanceResponse, error) {
 	if f.random != nil {
 		return f.random(ctx, locale)
 	}
@@ -94,3 +98,38 @@ func TestGeminiEnhancerFallsBackToChainedProvider(t *testing.T) {
 		t.Fatal("expected fallback_reason metadata to be populated")
 	}
 }
+
+func TestEnhancersRejectEmptyPayload(t *testing.T) {
+	const empty = `{"title":"","description":"  ","keywords":[],"metadata":{}}`
+	geminiBody, _ := json.Marshal(map[string]any{"candidates": []map[string]any{{"content": map[string]any{"parts": []map[string]any{{"text": empty}}}}}})
+	openAIBody, _ := json.Marshal(map[string]any{"choices": []map[string]any{{"message": map[string]any{"content": empty}}}})
+	reply := func(body []byte) *http.Client {
+		return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body)), Header: make(http.Header)}, nil
+		})}
+	}
+
+	var reasons []string
+	onFallback := func(reason string, err error) { reasons = append(reasons, reason) }
+	gemini, err := NewGeminiEnhancer(GeminiOptions{APIKey: "dummy", HTTPClient: reply(geminiBody), OnFallback: onFallback})
+	if err != nil {
+		t.Fatalf("NewGeminiEnhancer returned error: %v", err)
+	}
+	openai, err := NewOpenAIEnhancer(OpenAIOptions{APIKey: "dummy", HTTPClient: reply(openAIBody), OnFallback: onFallback})
+	if err != nil {
+		t.Fatalf("NewOpenAIEnhancer returned error: %v", err)
+	}
+
+	for _, enhancer := range []Enhancer{gemini, openai} {
+		res, err := enhancer.Enhance(context.Background(), EnhanceRequest{Prompt: jsoncfg.PromptJSON{Title: "Kopi", ProductType: "minuman"}, Locale: "id"})
+		if err != nil {
+			t.Fatalf("Enhance returned error: %v", err)
+		}
+		if res.Provider != staticProviderName || res.Metadata["fallback_reason"] != "invalid_payload" {
+			t.Fatalf("expected static fallback with invalid_payload, got provider=%q metadata=%v", res.Provider, res.Metadata)
+		}
+	}
+	if strings.Join(reasons, ",") != "invalid_payload,invalid_payload" {
+		t.Fatalf("fallback reasons = %v", reasons)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"server/internal/domain/jsoncfg"
+	"server/internal/i18n"
 )
 
 const (
@@ -41,22 +42,58 @@ func buildEnhancePromptPayload(req EnhanceRequest) string {
 	if locale == "" {
 		locale = p.Extras.Locale
 	}
+	chain := i18n.Chain(locale)
+	locale = chain[0]
 	sb := &strings.Builder{}
 	fmt.Fprintf(sb, "You are a marketing prompt expert helping Indonesian small businesses. Respond strictly with JSON matching this schema: ")
 	sb.WriteString(`{"title":string,"description":string,"keywords":string[],"ideas":[{"title":string,"description":string,"keywords":string[]}],"metadata":{"locale":string}}`)
-	fmt.Fprintf(sb, ". Use locale '%s' for language choices. Input details: title=%q, product_type=%q, style=%q, background=%q, instructions=%q, watermark_enabled=%t. Focus on persuasive yet concise copy.", locale, p.Title, p.ProductType, p.Style, p.Background, p.Instructions, p.Watermark.Enabled)
+	fmt.Fprintf(sb, ". Use locale '%s' for language choices%s. Input details: title=%q, product_type=%q, style=%q, background=%q, instructions=%q, watermark_enabled=%t. Focus on persuasive yet concise copy. Set metadata.locale to the language code the copy is actually written in.", locale, localeFallbackHint(chain), p.Title, p.ProductType, p.Style, p.Background, p.Instructions, p.Watermark.Enabled)
 	return sb.String()
 }
 
-func buildRandomPromptPayload(locale string) string {
-	if locale == "" {
-		locale = "en"
+const (
+	// MinRandomCount and MaxRandomCount bound how many ideas Random returns.
+	MinRandomCount = 1
+	MaxRandomCount = 5
+	// DefaultRandomCount is used when the caller does not ask for a count.
+	DefaultRandomCount = 3
+)
+
+// ClampRandomCount maps a requested idea count into the supported range.
+// Non-positive values select DefaultRandomCount.
+func ClampRandomCount(count int) int {
+	switch {
+	case count <= 0:
+		return DefaultRandomCount
+	case count > MaxRandomCount:
+		return MaxRandomCount
+	default:
+		return count
+	}
+}
+
+func buildRandomPromptPayload(locale string, count int) string {
+	chain := i18n.Chain(locale)
+	locale = chain[0]
+	count = ClampRandomCount(count)
+	noun := "ideas"
+	if count == 1 {
+		noun = "idea"
 	}
 	sb := &strings.Builder{}
-	fmt.Fprintf(sb, "Generate three unique product marketing prompt ideas for small businesses. Respond strictly as JSON: {\"items\":[{\"title\":string,\"description\":string,\"keywords\":string[]}],\"locale\":%q}. Use locale '%s' for language and make each response noticeably different. randomness_token=%d.", locale, locale, time.Now().UnixNano())
+	fmt.Fprintf(sb, "Generate exactly %d unique product marketing prompt %s for small businesses. Respond strictly as JSON: {\"items\":[{\"title\":string,\"description\":string,\"keywords\":string[]}],\"locale\":%q}. Use locale '%s' for language%s and make each response noticeably different. randomness_token=%d.", count, noun, locale, locale, localeFallbackHint(chain), time.Now().UnixNano())
 	return sb.String()
 }
 
+// localeFallbackHint tells the model which locales to fall back to, in order,
+// when it cannot write the first entry of chain fluently.
+func localeFallbackHint(chain []string) string {
+	if len(chain) < 2 {
+		return ""
+	}
+	return fmt.Sprintf(" (if you cannot write '%s' fluently, fall back to '%s')", chain[0], strings.Join(chain[1:], "' then '"))
+}
+
 func ensureMetadata(meta map[string]string, locale string) map[string]string {
 	if meta == nil {
 		meta = map[string]string{}
@@ -69,6 +106,20 @@ func ensureMetadata(meta map[string]string, locale string) map[string]string {
 	return meta
 }
 
+// enhanceMetadata keeps the locale the model reports it wrote in, falling back
+// to the requested locale, so clients can tell when the two differ.
+func enhanceMetadata(meta map[string]string, requested string) map[string]string {
+	if meta == nil {
+		meta = map[string]string{}
+	}
+	if detected := strings.ToLower(strings.TrimSpace(meta["locale"])); detected != "" {
+		meta["locale"] = detected
+		return meta
+	}
+	delete(meta, "locale")
+	return ensureMetadata(meta, requested)
+}
+
 func normalizeKeywords(keywords []string, fallback string) []string {
 	seen := make(map[string]struct{})
 	var result []string
@@ -113,6 +164,15 @@ func parseModelPayload[T any](raw string) (T, error) {
 	return decoded, nil
 }
 
+// validateEnhancePayload rejects replies that decode cleanly but carry no
+// usable copy, so callers fall back instead of returning blank prompts.
+func validateEnhancePayload(p modelEnhancePayload) error {
+	if strings.TrimSpace(p.Title) == "" && strings.TrimSpace(p.Description) == "" {
+		return errors.New("enhance payload has empty title and description")
+	}
+	return nil
+}
+
 func extractJSONFragment(raw string) string {
 	text := strings.TrimSpace(raw)
 	if text == "" {
@@ -0,0 +1,35 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"server/internal/domain/jsoncfg"
+)
+
+func TestPayloadBuildersFollowLocaleChain(t *testing.T) {
+	tests := []struct {
+		locale   string
+		wantUse  string
+		wantHint string
+	}{
+		{locale: "ms-MY", wantUse: "Use locale 'ms'", wantHint: "fall back to 'id' then 'en'"},
+		{locale: "jv", wantUse: "Use locale 'jv'", wantHint: "fall back to 'id' then 'en'"},
+		{locale: "fr", wantUse: "Use locale 'en'", wantHint: ""},
+	}
+	for _, tc := range tests {
+		enhance := buildEnhancePromptPayload(EnhanceRequest{Prompt: jsoncfg.PromptJSON{Title: "Kopi"}, Locale: tc.locale})
+		random := buildRandomPromptPayload(tc.locale, 2)
+		for name, payload := range map[string]string{"enhance": enhance, "random": random} {
+			if !strings.Contains(payload, tc.wantUse) {
+				t.Fatalf("%s payload for %q missing %q: %s", name, tc.locale, tc.wantUse, payload)
+			}
+			if tc.wantHint == "" && strings.Contains(payload, "fall back to") {
+				t.Fatalf("%s payload for %q has unexpected fallback hint: %s", name, tc.locale, payload)
+			}
+			if tc.wantHint != "" && !strings.Contains(payload, tc.wantHint) {
+				t.Fatalf("%s payload for %q missing %q: %s", name, tc.locale, tc.wantHint, payload)
+			}
+		}
+	}
+}
@@ -12,30 +12,34 @@ import (
 )
 
 type OpenAIOptions struct {
-	APIKey       string
-	Model        string
-	BaseURL      string
-	Organization string
-	HTTPClient   *http.Client
-	Fallback     Enhancer
-	OnFallback   func(reason string, err error)
-	OnWarning    func(reason, detail string)
+	APIKey         string
+	Model          string
+	SecondaryModel string
+	BaseURL        string
+	Organization   string
+	HTTPClient     *http.Client
+	Fallback       Enhancer
+	OnFallback     func(reason string, err error)
+	OnWarning      func(reason, detail string)
 }
 
 type OpenAIEnhancer struct {
-	apiKey       string
-	model        string
-	baseURL      string
-	organization string
-	client       *http.Client
-	fallback     Enhancer
-	onFallback   func(reason string, err error)
+	apiKey         string
+	model          string
+	secondaryModel string
+	baseURL        string
+	organization   string
+	client         *http.Client
+	fallback       Enhancer
+	onFallback     func(reason string, err error)
 }
 
 const openAIDefaultTimeout = 15 * time.Second
 
 const defaultOpenAIModel = "gpt-4o-mini"
 
+const openAIRateLimitReason = "http_429"
+
 var openAIModelCanonical = map[string]string{
 	"gpt-3.5-turbo": "gpt-3.5-turbo",
 	"gpt-4o-mini":   "gpt-4o-mini",
@@ -94,18 +98,29 @@ func NewOpenAIEnhancer(opts OpenAIOptions) (*OpenAIEnhancer, error) {
 		detail := fmt.Sprintf("requested=%s resolved=%s", coalesce(modelInput, defaultOpenAIModel), normalizedModel)
 		opts.OnWarning("model_"+normalizationReason, detail)
 	}
+	secondaryModel := ""
+	if secondaryInput := strings.TrimSpace(opts.SecondaryModel); secondaryInput != "" {
+		resolved, secondaryReason := normalizeOpenAIModel(secondaryInput)
+		if secondaryReason == "defaulted" && opts.OnWarning != nil {
+			opts.OnWarning("secondary_model_defaulted", fmt.Sprintf("requested=%s resolved=%s", secondaryInput, resolved))
+		}
+		if resolved != normalizedModel {
+			secondaryModel = resolved
+		}
+	}
 	client := opts.HTTPClient
 	if client == nil {
 		client = &http.Client{Timeout: openAIDefaultTimeout}
 	}
 	return &OpenAIEnhancer{
-		apiKey:       strings.TrimSpace(opts.APIKey),
-		model:        normalizedModel,
-		baseURL:      baseURL,
-		organization: strings.TrimSpace(opts.Organization),
-		client:       client,
-		fallback:     opts.Fallback,
-		onFallback:   opts.OnFallback,
+		apiKey:         strings.TrimSpace(opts.APIKey),
+		model:          normalizedModel,
+		secondaryModel: secondaryModel,
+		baseURL:        baseURL,
+		organization:   strings.TrimSpace(opts.Organization),
+		client:         client,
+		fallback:       opts.Fallback,
+		onFallback:     opts.OnFallback,
 	}, nil
 }
 
@@ -124,53 +139,28 @@ func (o *OpenAIEnhancer) Enhance(ctx context.Context, req EnhanceRequest) (*Enha
 			{Role: "user", Content: buildEnhancePromptPayload(req)},
 		},
 	}
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(payload); err != nil {
-		return o.useFallback(ctx, req, "encode_request", err)
-	}
-	endpoint := fmt.Sprintf("%s/chat/completions", o.baseURL)
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &buf)
+	text, model, reason, err := o.completeWithRetry(ctx, payload)
 	if err != nil {
-		return o.useFallback(ctx, req, "build_request", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
-	if o.organization != "" {
-		httpReq.Header.Set("OpenAI-Organization", o.organization)
-	}
-	resp, err := o.client.Do(httpReq)
-	if err != nil {
-		return o.useFallback(ctx, req, "http_request", err)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-	if resp.StatusCode >= 300 {
-		return o.useFallback(ctx, req, fmt.Sprintf("http_%d", resp.StatusCode), fmt.Errorf("openai status %d", resp.StatusCode))
-	}
-	var out openAIChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return o.useFallback(ctx, req, "decode_response", err)
-	}
-	if len(out.Choices) == 0 {
-		return o.useFallback(ctx, req, "empty_choices", errors.New("no choices"))
-	}
-	text := strings.TrimSpace(out.Choices[0].Message.Content)
-	if text == "" {
-		return o.useFallback(ctx, req, "empty_response", errors.New("empty response"))
+		return o.useFallback(ctx, req, reason, err)
 	}
 	parsed, err := parseModelPayload[modelEnhancePayload](text)
 	if err != nil {
 		return o.useFallback(ctx, req, "parse_payload", err)
 	}
+	if err := validateEnhancePayload(parsed); err != nil {
+		return o.useFallback(ctx, req, "invalid_payload", err)
+	}
 	locale := coalesce(req.Locale, req.Prompt.Extras.Locale)
 	response := &EnhanceResponse{
 		Title:       coalesce(parsed.Title, req.Prompt.Title),
 		Description: coalesce(parsed.Description, req.Prompt.Instructions),
 		Keywords:    normalizeKeywords(parsed.Keywords, req.Prompt.ProductType),
-		Metadata:    ensureMetadata(parsed.Metadata, locale),
+		Metadata:    enhanceMetadata(parsed.Metadata, locale),
 		Provider:    openAIProviderName,
 	}
+	if model != o.model {
+		response.Metadata["model"] = model
+	}
 	if len(parsed.Ideas) > 0 {
 		for _, idea := range parsed.Ideas {
 			response.Ideas = append(response.Ideas, EnhanceIdea{
@@ -190,9 +180,9 @@ func (o *OpenAIEnhancer) Enhance(ctx context.Context, req EnhanceRequest) (*Enha
 	return response, nil
 }
 
-func (o *OpenAIEnhancer) Random(ctx context.Context, locale string) ([]EnhanceResponse, error) {
+func (o *OpenAIEnhancer) Random(ctx context.Context, locale string, count int) ([]EnhanceResponse, error) {
 	if o.apiKey == "" {
-		return o.useFallbackRandom(ctx, locale, "missing_api_key", nil)
+		return o.useFallbackRandom(ctx, locale, count, "missing_api_key", nil)
 	}
 	payload := openAIChatRequest{
 		Model:       o.model,
@@ -202,17 +192,72 @@ func (o *OpenAIEnhancer) Random(ctx context.Context, locale string) ([]EnhanceRe
 		},
 		Messages: []openAIMessage{
 			{Role: "system", Content: "You are a helpful marketing prompt assistant that only responds with valid JSON."},
-			{Role: "user", Content: buildRandomPromptPayload(locale)},
+			{Role: "user", Content: buildRandomPromptPayload(locale, count)},
 		},
 	}
+	text, model, reason, err := o.completeWithRetry(ctx, payload)
+	if err != nil {
+		return o.useFallbackRandom(ctx, locale, count, reason, err)
+	}
+	parsed, err := parseModelPayload[modelRandomPayload](text)
+	if err != nil {
+		return o.useFallbackRandom(ctx, locale, count, "parse_payload", err)
+	}
+	if len(parsed.Items) == 0 {
+		return o.useFallbackRandom(ctx, locale, count, "empty_items", errors.New("no items"))
+	}
+	if count = ClampRandomCount(count); len(parsed.Items) > count {
+		parsed.Items = parsed.Items[:count]
+	}
+	var items []EnhanceResponse
+	for _, item := range parsed.Items {
+		meta := ensureMetadata(map[string]string{"locale": parsed.Locale}, locale)
+		if model != o.model {
+			meta["model"] = model
+		}
+		res := EnhanceResponse{
+			Title:       coalesce(item.Title, item.Description),
+			Description: coalesce(item.Description, item.Title),
+			Keywords:    normalizeKeywords(item.Keywords, item.Title),
+			Metadata:    meta,
+			Provider:    openAIProviderName,
+		}
+		items = append(items, res)
+	}
+	return items, nil
+}
+
+// completeWithRetry sends payload and, when OpenAI rate limits the primary
+// model, retries once against the secondary model. It returns the reply text
+// and the model that produced it, or a fallback reason. A rate limit keeps the
+// http_429 reason even if the retry fails so it is not mistaken for an outage.
+func (o *OpenAIEnhancer) completeWithRetry(ctx context.Context, payload openAIChatRequest) (string, string, string, error) {
+	text, reason, err := o.complete(ctx, payload)
+	if err == nil {
+		return text, payload.Model, "", nil
+	}
+	if reason != openAIRateLimitReason || o.secondaryModel == "" {
+		return "", payload.Model, reason, err
+	}
+	payload.Model = o.secondaryModel
+	text, _, retryErr := o.complete(ctx, payload)
+	if retryErr != nil {
+		return "", payload.Model, openAIRateLimitReason, errors.Join(err, retryErr)
+	}
+	return text, payload.Model, "", nil
+}
+
+// complete performs a single chat completion call and returns the trimmed
+// reply text, or a fallback reason describing what went wrong.
+func (o *OpenAIEnhancer) complete(ctx context.Context, payload openAIChatRequest) (string, string, error) {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(payload); err != nil {
-		return o.useFallbackRandom(ctx, locale, "encode_request", err)
+		return "", "encode_request", err
 	}
 	endpoint := fmt.Sprintf("%s/chat/completions", o.baseURL)
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &buf)
 	if err != nil {
-		return o.useFallbackRandom(ctx, locale, "build_request", err)
+		return "", "build_request", err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
@@ -221,45 +266,26 @@ func (o *OpenAIEnhancer) Random(ctx context.Context, locale string) ([]EnhanceRe
 	}
 	resp, err := o.client.Do(httpReq)
 	if err != nil {
-		return o.useFallbackRandom(ctx, locale, "http_request", err)
+		return "", "http_request", err
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 	if resp.StatusCode >= 300 {
-		return o.useFallbackRandom(ctx, locale, fmt.Sprintf("http_%d", resp.StatusCode), fmt.Errorf("openai status %d", resp.StatusCode))
+		return "", fmt.Sprintf("http_%d", resp.StatusCode), fmt.Errorf("openai status %d (model %s)", resp.StatusCode, payload.Model)
 	}
 	var out openAIChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return o.useFallbackRandom(ctx, locale, "decode_response", err)
+		return "", "decode_response", err
 	}
 	if len(out.Choices) == 0 {
-		return o.useFallbackRandom(ctx, locale, "empty_choices", errors.New("no choices"))
+		return "", "empty_choices", errors.New("no choices")
 	}
 	text := strings.TrimSpace(out.Choices[0].Message.Content)
 	if text == "" {
-		return o.useFallbackRandom(ctx, locale, "empty_response", errors.New("empty response"))
-	}
-	parsed, err := parseModelPayload[modelRandomPayload](text)
-	if err != nil {
-		return o.useFallbackRandom(ctx, locale, "parse_payload", err)
-	}
-	if len(parsed.Items) == 0 {
-		return o.useFallbackRandom(ctx, locale, "empty_items", errors.New("no items"))
+		return "", "empty_response", errors.New("empty response")
 	}
-	var items []EnhanceResponse
-	for _, item := range parsed.Items {
-		meta := ensureMetadata(map[string]string{"locale": parsed.Locale}, locale)
-		res := EnhanceResponse{
-			Title:       coalesce(item.Title, item.Description),
-			Description: coalesce(item.Description, item.Title),
-			Keywords:    normalizeKeywords(item.Keywords, item.Title),
-			Metadata:    meta,
-			Provider:    openAIProviderName,
-		}
-		items = append(items, res)
-	}
-	return items, nil
+	return text, "", nil
 }
 
 func (o *OpenAIEnhancer) useFallback(ctx context.Context, req EnhanceRequest, reason string, fallbackErr error) (*EnhanceResponse, error) {
@@ -293,10 +319,10 @@ func (o *OpenAIEnhancer) useFallback(ctx context.Context, req EnhanceRequest, re
 	return res, err
 }
 
-func (o *OpenAIEnhancer) useFallbackRandom(ctx context.Context, locale string, reason string, fallbackErr error) ([]EnhanceResponse, error) {
+func (o *OpenAIEnhancer) useFallbackRandom(ctx context.Context, locale string, count int, reason string, fallbackErr error) ([]EnhanceResponse, error) {
 	o.emitFallback(reason, fallbackErr)
 	if o.fallback != nil {
-		items, err := o.fallback.Random(ctx, locale)
+		items, err := o.fallback.Random(ctx, locale, count)
 		for i := range items {
 			if items[i].Provider == "" {
 				items[i].Provider = staticProviderName
@@ -311,7 +337,7 @@ func (o *OpenAIEnhancer) useFallbackRandom(ctx context.Context, locale string, r
 		return items, err
 	}
 	fallback := NewStaticEnhancer()
-	items, err := fallback.Random(ctx, locale)
+	items, err := fallback.Random(ctx, locale, count)
 	for i := range items {
 		items[i].Provider = staticProviderName
 		if items[i].Metadata == nil {
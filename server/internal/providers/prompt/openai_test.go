@@ -1,9 +1,14 @@
 package prompt
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"server/internal/domain/jsoncfg"
@@ -97,3 +102,180 @@ func TestNewOpenAIEnhancerWarnsOnUnsupportedModel(t *testing.T) {
 		t.Fatal("expected warning detail to be set")
 	}
 }
+
+func TestStaticEnhancerRandomHonorsCount(t *testing.T) {
+	cases := []struct {
+		count int
+		want  int
+	}{
+		{count: 1, want: 1},
+		{count: 5, want: 5},
+		{count: 0, want: DefaultRandomCount},
+		{count: 9, want: MaxRandomCount},
+	}
+	for _, tc := range cases {
+		items, err := NewStaticEnhancer().Random(context.Background(), "id", tc.count)
+		if err != nil {
+			t.Fatalf("Random(%d) returned error: %v", tc.count, err)
+		}
+		if len(items) != tc.want {
+			t.Fatalf("Random(%d) returned %d items, want %d", tc.count, len(items), tc.want)
+		}
+	}
+}
+
+func TestOpenAIEnhancerRandomRequestsCount(t *testing.T) {
+	for _, count := range []int{1, 5} {
+		var requested string
+		enhancer, err := NewOpenAIEnhancer(OpenAIOptions{
+			APIKey: "dummy",
+			HTTPClient: &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				var payload openAIChatRequest
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Fatalf("decode request: %v", err)
+				}
+				requested = payload.Messages[len(payload.Messages)-1].Content
+				items := make([]map[string]any, 6)
+				for i := range items {
+					items[i] = map[string]any{"title": fmt.Sprintf("Idea %d", i+1), "description": "desc", "keywords": []string{"umkm"}}
+				}
+				content, _ := json.Marshal(map[string]any{"items": items, "locale": "id"})
+				body, _ := json.Marshal(map[string]any{"choices": []map[string]any{{"message": map[string]any{"content": string(content)}}}})
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body)), Header: make(http.Header)}, nil
+			})},
+		})
+		if err != nil {
+			t.Fatalf("NewOpenAIEnhancer returned error: %v", err)
+		}
+		items, err := enhancer.Random(context.Background(), "id", count)
+		if err != nil {
+			t.Fatalf("Random(%d) returned error: %v", count, err)
+		}
+		if !strings.Contains(requested, fmt.Sprintf("Generate exactly %d ", count)) {
+			t.Fatalf("prompt does not request %d ideas: %q", count, requested)
+		}
+		if len(items) != count {
+			t.Fatalf("Random(%d) returned %d items", count, len(items))
+		}
+	}
+}
+
+func openAIChatReply(t *testing.T, content string) *http.Response {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"choices": []map[string]any{{"message": map[string]any{"content": content}}}})
+	if err != nil {
+		t.Fatalf("encode reply: %v", err)
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body)), Header: make(http.Header)}
+}
+
+func TestOpenAIEnhancerRetriesSecondaryModelOnRateLimit(t *testing.T) {
+	var models []string
+	var fallbackReason string
+	enhancer, err := NewOpenAIEnhancer(OpenAIOptions{
+		APIKey:         "dummy",
+		Model:          "gpt-4o-mini",
+		SecondaryModel: "gpt-3.5-turbo",
+		HTTPClient: &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			var payload openAIChatRequest
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Fatalf("decode request: %v", err)
+			}
+			models = append(models, payload.Model)
+			if payload.Model == "gpt-4o-mini" {
+				return &http.Response{StatusCode: http.StatusTooManyRequests, Body: io.NopCloser(strings.NewReader(`{}`)), Header: make(http.Header)}, nil
+			}
+			return openAIChatReply(t, `{"title":"Kopi Senja","description":"Kopi susu gula aren","keywords":["kopi"]}`), nil
+		})},
+		OnFallback: func(reason string, err error) { fallbackReason = reason },
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAIEnhancer returned error: %v", err)
+	}
+	res, err := enhancer.Enhance(context.Background(), EnhanceRequest{Prompt: jsoncfg.PromptJSON{Title: "kopi"}, Locale: "id"})
+	if err != nil {
+		t.Fatalf("Enhance returned error: %v", err)
+	}
+	if strings.Join(models, ",") != "gpt-4o-mini,gpt-3.5-turbo" {
+		t.Fatalf("models called = %v", models)
+	}
+	if res.Provider != openAIProviderName || res.Title != "Kopi Senja" {
+		t.Fatalf("unexpected response: %+v", res)
+	}
+	if res.Metadata["model"] != "gpt-3.5-turbo" {
+		t.Fatalf("metadata model = %q, want secondary", res.Metadata["model"])
+	}
+	if fallbackReason != "" {
+		t.Fatalf("unexpected fallback %q", fallbackReason)
+	}
+}
+
+func TestOpenAIEnhancerRateLimitReasonSurvivesFailedRetry(t *testing.T) {
+	cases := []struct {
+		name       string
+		statuses   map[string]int
+		wantCalls  int
+		wantReason string
+	}{
+		{name: "both rate limited", statuses: map[string]int{"gpt-4o-mini": 429, "gpt-3.5-turbo": 429}, wantCalls: 2, wantReason: "http_429"},
+		{name: "secondary outage", statuses: map[string]int{"gpt-4o-mini": 429, "gpt-3.5-turbo": 503}, wantCalls: 2, wantReason: "http_429"},
+		{name: "primary outage does not retry", statuses: map[string]int{"gpt-4o-mini": 503}, wantCalls: 1, wantReason: "http_503"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			var reason string
+			enhancer, err := NewOpenAIEnhancer(OpenAIOptions{
+				APIKey:         "dummy",
+				SecondaryModel: "gpt-3.5-turbo",
+				HTTPClient: &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					calls++
+					var payload openAIChatRequest
+					_ = json.NewDecoder(r.Body).Decode(&payload)
+					return &http.Response{StatusCode: tc.statuses[payload.Model], Body: io.NopCloser(strings.NewReader(`{}`)), Header: make(http.Header)}, nil
+				})},
+				OnFallback: func(r string, err error) { reason = r },
+			})
+			if err != nil {
+				t.Fatalf("NewOpenAIEnhancer returned error: %v", err)
+			}
+			res, err := enhancer.Enhance(context.Background(), EnhanceRequest{Prompt: jsoncfg.PromptJSON{Title: "kopi"}})
+			if err != nil {
+				t.Fatalf("Enhance returned error: %v", err)
+			}
+			if calls != tc.wantCalls {
+				t.Fatalf("calls = %d, want %d", calls, tc.wantCalls)
+			}
+			if reason != tc.wantReason || res.Metadata["fallback_reason"] != tc.wantReason {
+				t.Fatalf("reason = %q metadata=%q, want %q", reason, res.Metadata["fallback_reason"], tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestOpenAIEnhancerKeepsDetectedLocale(t *testing.T) {
+	for _, tc := range []struct {
+		reply string
+		want  string
+	}{
+		{reply: `{"title":"Coffee","description":"Iced latte","metadata":{"locale":"EN"}}`, want: "en"},
+		{reply: `{"title":"Kopi","description":"Es kopi susu"}`, want: "id"},
+	} {
+		enhancer, err := NewOpenAIEnhancer(OpenAIOptions{
+			APIKey: "dummy",
+			HTTPClient: &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				return openAIChatReply(t, tc.reply), nil
+			})},
+		})
+		if err != nil {
+			t.Fatalf("NewOpenAIEnhancer returned error: %v", err)
+		}
+		res, err := enhancer.Enhance(context.Background(), EnhanceRequest{Prompt: jsoncfg.PromptJSON{Title: "kopi"}, Locale: "id"})
+		if err != nil {
+			t.Fatalf("Enhance returned error: %v", err)
+		}
+		if res.Metadata["locale"] != tc.want {
+			t.Fatalf("metadata locale = %q, want %q", res.Metadata["locale"], tc.want)
+		}
+	}
+}
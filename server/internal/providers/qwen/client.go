@@ -22,29 +22,46 @@ import (
 // ErrMissingAPIKey indicates that the client was configured without credentials.
 var ErrMissingAPIKey = errors.New("qwen: api key is required")
 
+// ErrUnknownRegion is returned when a request names a region missing from
+// Options.RegionBaseURLs.
+var ErrUnknownRegion = errors.New("qwen: unknown region")
+
 // Options configures the DashScope Qwen client.
 type Options struct {
 	APIKey         string
 	BaseURL        string
 	Model          string
+	VideoModel     string
 	DefaultSize    string
 	PromptExtend   bool
 	Watermark      bool
 	HTTPClient     *http.Client
 	Logger         *infra.Logger
 	RequestTimeout time.Duration
+	PollInterval   time.Duration
+	// RegionBaseURLs maps region names such as "intl" or "cn" to DashScope
+	// base URLs selectable per request via ImageRequest.Region.
+	RegionBaseURLs map[string]string
+	// DisableSyntheticFallback tells generators wrapping this client to fail
+	// instead of falling back when no API key is configured.
+	DisableSyntheticFallback bool
 }
 
-// Client performs HTTP calls to the DashScope Qwen text-to-image API.
+// Client performs HTTP calls to the DashScope Qwen text-to-image and video
+// synthesis APIs.
 type Client struct {
 	apiKey       string
 	baseURL      string
+	regions      map[string]string
 	model        string
+	videoModel   string
 	defaultSize  string
 	promptExtend bool
 	watermark    bool
 	httpClient   *http.Client
 	logger       *infra.Logger
+	pollInterval time.Duration
+	synthetic    bool
 }
 
 // ImageRequest captures the required inputs for image generation.
@@ -58,6 +75,9 @@ type ImageRequest struct {
 	Locale         string
 	Workflow       Workflow
 	SourceImage    *SourceImage
+	// Region selects a base URL from Options.RegionBaseURLs; empty uses
+	// Options.BaseURL.
+	Region string
 }
 
 // ImageAsset is the normalized result from the Qwen API.
@@ -184,6 +204,14 @@ func NewClient(opts Options) (*Client, error) {
 	if model == "" {
 		model = "qwen-image-plus"
 	}
+	videoModel := strings.TrimSpace(opts.VideoModel)
+	if videoModel == "" {
+		videoModel = "wan2.1-t2v-turbo"
+	}
+	pollInterval := opts.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
 	defaultSize := strings.TrimSpace(opts.DefaultSize)
 	if defaultSize == "" {
 		defaultSize = "1328*1328"
@@ -199,12 +227,16 @@ func NewClient(opts Options) (*Client, error) {
 	return &Client{
 		apiKey:       strings.TrimSpace(opts.APIKey),
 		baseURL:      baseURL,
+		regions:      regionBaseURLs(opts.RegionBaseURLs),
 		model:        model,
+		videoModel:   videoModel,
 		defaultSize:  defaultSize,
 		promptExtend: opts.PromptExtend,
 		watermark:    opts.Watermark,
+		synthetic:    !opts.DisableSyntheticFallback,
 		httpClient:   httpClient,
 		logger:       logger,
+		pollInterval: pollInterval,
 	}, nil
 }
 
@@ -213,11 +245,47 @@ func (c *Client) Model() string {
 	return c.model
 }
 
+// SyntheticFallback reports whether callers may fall back to another
+// generator when no API key is configured.
+func (c *Client) SyntheticFallback() bool {
+	return c.synthetic
+}
+
 // HasCredentials reports whether the client can perform remote calls.
 func (c *Client) HasCredentials() bool {
 	return c.apiKey != ""
 }
 
+// HasRegion reports whether region is empty or configured in
+// Options.RegionBaseURLs.
+func (c *Client) HasRegion(region string) bool {
+	_, err := c.regionBaseURL(region)
+	return err == nil
+}
+
+func (c *Client) regionBaseURL(region string) (string, error) {
+	region = strings.ToLower(strings.TrimSpace(region))
+	if region == "" {
+		return c.baseURL, nil
+	}
+	if base, ok := c.regions[region]; ok {
+		return base, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownRegion, region)
+}
+
+func regionBaseURLs(in map[string]string) map[string]string {
+	out := make(map[string]string, len(in))
+	for region, base := range in {
+		region = strings.ToLower(strings.TrimSpace(region))
+		base = strings.TrimRight(strings.TrimSpace(base), "/")
+		if region != "" && base != "" {
+			out[region] = base
+		}
+	}
+	return out
+}
+
 // GenerateImage invokes the DashScope API once and returns a single image asset.
 func (c *Client) GenerateImage(ctx context.Context, req ImageRequest) (*ImageAsset, error) {
 	if !c.HasCredentials() {
@@ -273,7 +341,11 @@ func (c *Client) GenerateImage(ctx context.Context, req ImageRequest) (*ImageAss
 		payload.Parameters.Workflow = wf
 	}
 
-	endpoint := c.baseURL + "/services/aigc/multimodal-generation/generation"
+	base, err := c.regionBaseURL(req.Region)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := base + "/services/aigc/multimodal-generation/generation"
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("qwen: encode request: %w", err)
@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestEncodeImageContentWithInlineData(t *testing.T) {
@@ -161,9 +163,132 @@ func TestGenerateImageEditingPayload(t *testing.T) {
 	}
 }
 
+func TestGenerateImageUsesRegionBaseURL(t *testing.T) {
+	transport := &captureTransport{responses: map[string]responseStub{}}
+	client, err := NewClient(Options{
+		APIKey:  "test",
+		BaseURL: "https://dashscope-intl.aliyuncs.com/api/v1",
+		RegionBaseURLs: map[string]string{
+			"intl": "https://dashscope-intl.aliyuncs.com/api/v1",
+			"CN":   "https://dashscope.aliyuncs.com/api/v1/",
+		},
+		HTTPClient: &http.Client{Transport: transport},
+	})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	transport.setJSONResponse("/api/v1/services/aigc/multimodal-generation/generation", map[string]any{
+		"output": map[string]any{"choices": []any{map[string]any{"message": map[string]any{
+			"content": []any{map[string]any{"image": "https://example.com/generated/out.png"}},
+		}}}},
+	})
+	transport.setBinaryResponse("https://example.com/generated/out.png", []byte{0x89, 'P', 'N', 'G'})
+
+	for region, want := range map[string]string{
+		"":     "https://dashscope-intl.aliyuncs.com/api/v1/services/aigc/multimodal-generation/generation",
+		"cn":   "https://dashscope.aliyuncs.com/api/v1/services/aigc/multimodal-generation/generation",
+		" CN ": "https://dashscope.aliyuncs.com/api/v1/services/aigc/multimodal-generation/generation",
+	} {
+		transport.lastURL = ""
+		if _, err := client.GenerateImage(context.Background(), ImageRequest{Prompt: "kopi susu", Region: region}); err != nil {
+			t.Fatalf("region %q: generate image: %v", region, err)
+		}
+		if transport.lastURL != want {
+			t.Fatalf("region %q: request url = %q, want %q", region, transport.lastURL, want)
+		}
+	}
+
+	transport.lastURL = ""
+	_, err = client.GenerateImage(context.Background(), ImageRequest{Prompt: "kopi susu", Region: "eu"})
+	if !errors.Is(err, ErrUnknownRegion) {
+		t.Fatalf("unknown region error = %v, want ErrUnknownRegion", err)
+	}
+	if transport.lastURL != "" {
+		t.Fatalf("unknown region still sent a request to %s", transport.lastURL)
+	}
+	if !client.HasRegion("intl") || client.HasRegion("eu") {
+		t.Fatalf("HasRegion reported the wrong set")
+	}
+}
+
+func TestGenerateVideoSubmitsSynthesisTask(t *testing.T) {
+	transport := &captureTransport{responses: map[string]responseStub{}}
+	client, err := NewClient(Options{
+		APIKey:       "test",
+		VideoModel:   "wan2.1-t2v-plus",
+		PromptExtend: true,
+		HTTPClient:   &http.Client{Transport: transport},
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	transport.setJSONResponse("/api/v1/services/aigc/video-generation/video-synthesis", map[string]any{
+		"output":     map[string]any{"task_id": "task-1", "task_status": "PENDING"},
+		"request_id": "req-1",
+	})
+	transport.setJSONResponse("https://dashscope-intl.aliyuncs.com/api/v1/tasks/task-1", map[string]any{
+		"output": map[string]any{
+			"task_id":     "task-1",
+			"task_status": "SUCCEEDED",
+			"video_url":   "https://example.com/generated/out.mp4",
+		},
+		"usage": map[string]any{"video_duration": 5},
+	})
+	transport.setBinaryResponse("https://example.com/generated/out.mp4", []byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p'})
+
+	asset, err := client.GenerateVideo(context.Background(), VideoRequest{
+		Prompt: "product spin",
+		Size:   "1280*720",
+		Seed:   42,
+	})
+	if err != nil {
+		t.Fatalf("generate video: %v", err)
+	}
+	if asset.URL != "https://example.com/generated/out.mp4" {
+		t.Fatalf("url = %q", asset.URL)
+	}
+	if asset.Format != "video/mp4" {
+		t.Fatalf("format = %q, want video/mp4", asset.Format)
+	}
+	if asset.Length != 5 {
+		t.Fatalf("length = %d, want 5", asset.Length)
+	}
+	if len(asset.Data) == 0 {
+		t.Fatalf("expected downloaded video data")
+	}
+	if got := transport.lastHeader.Get("X-DashScope-Async"); got != "enable" {
+		t.Fatalf("X-DashScope-Async = %q, want enable", got)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(transport.lastBody, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if model := payload["model"]; model != "wan2.1-t2v-plus" {
+		t.Fatalf("model = %v, want wan2.1-t2v-plus", model)
+	}
+	input := payload["input"].(map[string]any)
+	if prompt := input["prompt"]; prompt != "product spin" {
+		t.Fatalf("prompt = %v, want product spin", prompt)
+	}
+	params := payload["parameters"].(map[string]any)
+	if size := params["size"]; size != "1280*720" {
+		t.Fatalf("size = %v, want 1280*720", size)
+	}
+	if seed := params["seed"]; seed != float64(42) {
+		t.Fatalf("seed = %v, want 42", seed)
+	}
+	if extend := params["prompt_extend"]; extend != true {
+		t.Fatalf("prompt_extend = %v, want true", extend)
+	}
+}
+
 type captureTransport struct {
-	responses map[string]responseStub
-	lastBody  []byte
+	responses  map[string]responseStub
+	lastBody   []byte
+	lastHeader http.Header
+	lastURL    string
 }
 
 type responseStub struct {
@@ -180,6 +305,8 @@ func (c *captureTransport) RoundTrip(req *http.Request) (*http.Response, error)
 		}
 		req.Body.Close()
 		c.lastBody = body
+		c.lastHeader = req.Header.Clone()
+		c.lastURL = req.URL.String()
 		if stub, ok := c.responses[req.URL.Path]; ok {
 			return stub.toResponse(), nil
 		}
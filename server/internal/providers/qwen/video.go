@@ -0,0 +1,201 @@
+package qwen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VideoRequest captures the inputs for a DashScope text-to-video synthesis task.
+type VideoRequest struct {
+	Prompt         string
+	NegativePrompt string
+	Size           string
+	Seed           int
+	RequestID      string
+}
+
+// VideoAsset is the normalized video result from the DashScope API.
+type VideoAsset struct {
+	URL    string
+	Data   []byte
+	Format string
+	Length int
+}
+
+type videoSynthesisRequest struct {
+	Model      string               `json:"model"`
+	Input      videoSynthesisInput  `json:"input"`
+	Parameters videoSynthesisParams `json:"parameters"`
+}
+
+type videoSynthesisInput struct {
+	Prompt         string `json:"prompt"`
+	NegativePrompt string `json:"negative_prompt,omitempty"`
+}
+
+type videoSynthesisParams struct {
+	Size         string `json:"size,omitempty"`
+	Seed         *int   `json:"seed,omitempty"`
+	PromptExtend *bool  `json:"prompt_extend,omitempty"`
+	Watermark    *bool  `json:"watermark,omitempty"`
+}
+
+type videoTaskResponse struct {
+	Output struct {
+		TaskID     string `json:"task_id"`
+		TaskStatus string `json:"task_status"`
+		VideoURL   string `json:"video_url"`
+		Code       string `json:"code"`
+		Message    string `json:"message"`
+	} `json:"output"`
+	Usage struct {
+		VideoDuration int `json:"video_duration"`
+	} `json:"usage"`
+	RequestID string `json:"request_id"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+}
+
+// VideoModel returns the configured video synthesis model identifier.
+func (c *Client) VideoModel() string {
+	return c.videoModel
+}
+
+// GenerateVideo submits an asynchronous DashScope video synthesis task, waits
+// for it to finish and downloads the resulting clip.
+func (c *Client) GenerateVideo(ctx context.Context, req VideoRequest) (*VideoAsset, error) {
+	if !c.HasCredentials() {
+		return nil, ErrMissingAPIKey
+	}
+	prompt := strings.TrimSpace(req.Prompt)
+	if prompt == "" {
+		return nil, errors.New("qwen: prompt is required")
+	}
+	payload := videoSynthesisRequest{
+		Model: c.videoModel,
+		Input: videoSynthesisInput{
+			Prompt:         prompt,
+			NegativePrompt: strings.TrimSpace(req.NegativePrompt),
+		},
+		Parameters: videoSynthesisParams{
+			Size: strings.TrimSpace(req.Size),
+		},
+	}
+	if req.Seed > 0 {
+		payload.Parameters.Seed = &req.Seed
+	}
+	if extend := c.promptExtend; extend {
+		payload.Parameters.PromptExtend = &extend
+	}
+	watermark := c.watermark
+	payload.Parameters.Watermark = &watermark
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("qwen: encode video request: %w", err)
+	}
+	endpoint := c.baseURL + "/services/aigc/video-generation/video-synthesis"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("qwen: build video request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	httpReq.Header.Set("X-DashScope-Async", "enable")
+
+	submitted, err := c.doVideoTask(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	taskID := strings.TrimSpace(submitted.Output.TaskID)
+	if taskID == "" {
+		return nil, errors.New("qwen: empty video task id")
+	}
+
+	result, err := c.waitVideoTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	videoURL := strings.TrimSpace(result.Output.VideoURL)
+	if videoURL == "" {
+		return nil, errors.New("qwen: empty video url")
+	}
+	data, format, err := c.download(ctx, videoURL)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(format, "video/") {
+		format = "video/mp4"
+	}
+	c.logger.Debug().
+		Str("model", c.videoModel).
+		Str("task_id", taskID).
+		Str("url", videoURL).
+		Msg("qwen: generated video asset")
+	return &VideoAsset{URL: videoURL, Data: data, Format: format, Length: result.Usage.VideoDuration}, nil
+}
+
+func (c *Client) waitVideoTask(ctx context.Context, taskID string) (*videoTaskResponse, error) {
+	endpoint := c.baseURL + "/tasks/" + taskID
+	for {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("qwen: build task request: %w", err)
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+		task, err := c.doVideoTask(httpReq)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToUpper(strings.TrimSpace(task.Output.TaskStatus)) {
+		case "SUCCEEDED":
+			return task, nil
+		case "FAILED", "CANCELED", "UNKNOWN":
+			if task.Output.Message != "" {
+				return nil, fmt.Errorf("qwen: %s (%s)", task.Output.Message, task.Output.Code)
+			}
+			return nil, fmt.Errorf("qwen: video task %s", strings.ToLower(task.Output.TaskStatus))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+func (c *Client) doVideoTask(httpReq *http.Request) (*videoTaskResponse, error) {
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("qwen: http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("qwen: read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var detail errorResponse
+		if err := json.Unmarshal(raw, &detail); err == nil && detail.Message != "" {
+			return nil, fmt.Errorf("qwen: %s (%s)", detail.Message, detail.Code)
+		}
+		return nil, fmt.Errorf("qwen: status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+
+	var decoded videoTaskResponse
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("qwen: decode response: %w", err)
+	}
+	if decoded.Code != "" {
+		return nil, fmt.Errorf("qwen: %s (%s)", decoded.Message, decoded.Code)
+	}
+	return &decoded, nil
+}
@@ -11,6 +11,16 @@ type GenerateRequest struct {
 	Provider  string
 	RequestID string
 	Locale    string
+	// SourceImage is an optional still to animate into the clip.
+	SourceImage *SourceImage
+}
+
+// SourceImage is a still image used as the first frame or reference for an
+// image-to-video request.
+type SourceImage struct {
+	Data []byte
+	MIME string
+	URL  string
 }
 
 type Asset struct {
@@ -34,10 +44,15 @@ func NewGeminiGenerator(client *genai.Client) *GeminiGenerator {
 }
 
 func (g *GeminiGenerator) Generate(ctx context.Context, req GenerateRequest) (*Asset, error) {
+	var source *genai.SourceImage
+	if req.SourceImage != nil {
+		source = &genai.SourceImage{Data: req.SourceImage.Data, MIME: req.SourceImage.MIME, URL: req.SourceImage.URL}
+	}
 	asset, err := g.client.GenerateVideo(ctx, genai.VideoRequest{
-		Prompt:    req.Prompt,
-		Locale:    req.Locale,
-		RequestID: req.RequestID,
+		Prompt:      req.Prompt,
+		Locale:      req.Locale,
+		RequestID:   req.RequestID,
+		SourceImage: source,
 	})
 	if err != nil {
 		return nil, err
@@ -51,4 +66,14 @@ func (g *GeminiGenerator) Generate(ctx context.Context, req GenerateRequest) (*A
 	}, nil
 }
 
+// HasCredentials reports whether a Gemini API key is configured.
+func (g *GeminiGenerator) HasCredentials() bool {
+	return g != nil && g.client != nil && g.client.HasCredentials()
+}
+
+// SupportsSourceImage reports that Gemini honours GenerateRequest.SourceImage.
+func (g *GeminiGenerator) SupportsSourceImage() bool {
+	return true
+}
+
 var _ Generator = (*GeminiGenerator)(nil)
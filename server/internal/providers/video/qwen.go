@@ -0,0 +1,107 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"server/internal/providers/qwen"
+)
+
+// qwenVideoClient is the part of *qwen.Client the video generator uses.
+// SyntheticFallback reports whether a missing API key may be covered by the
+// fallback generator instead of failing the request.
+type qwenVideoClient interface {
+	GenerateVideo(context.Context, qwen.VideoRequest) (*qwen.VideoAsset, error)
+	HasCredentials() bool
+	SyntheticFallback() bool
+	VideoModel() string
+}
+
+// QwenGenerator submits video synthesis tasks to DashScope's wan models and
+// falls back to another generator (e.g. synthetic Gemini) when credentials are
+// missing or the remote call fails.
+type QwenGenerator struct {
+	client   qwenVideoClient
+	fallback Generator
+}
+
+// NewQwenGenerator wires a Qwen client with an optional fallback generator.
+func NewQwenGenerator(client qwenVideoClient, fallback Generator) *QwenGenerator {
+	return &QwenGenerator{client: client, fallback: fallback}
+}
+
+// Generate fulfils the Generator interface.
+func (g *QwenGenerator) Generate(ctx context.Context, req GenerateRequest) (*Asset, error) {
+	if g == nil {
+		return nil, fmt.Errorf("qwen video generator not configured")
+	}
+	if g.client == nil {
+		if g.fallback != nil {
+			return g.fallback.Generate(ctx, req)
+		}
+		return nil, fmt.Errorf("qwen video generator not configured")
+	}
+	if !g.client.HasCredentials() {
+		if g.fallback != nil && g.client.SyntheticFallback() {
+			return g.fallback.Generate(ctx, req)
+		}
+		return nil, fmt.Errorf("qwen video generator missing credentials: %w", qwen.ErrMissingAPIKey)
+	}
+	asset, err := g.client.GenerateVideo(ctx, qwen.VideoRequest{
+		Prompt:    strings.TrimSpace(req.Prompt),
+		RequestID: req.RequestID,
+	})
+	if err != nil {
+		if shouldFallbackToSynthetic(err) && g.fallback != nil {
+			return g.fallback.Generate(ctx, req)
+		}
+		return nil, err
+	}
+	return &Asset{
+		URL:    asset.URL,
+		Format: asset.Format,
+		Length: asset.Length,
+		Data:   asset.Data,
+	}, nil
+}
+
+func (g *QwenGenerator) String() string {
+	if g == nil || g.client == nil {
+		return "qwen"
+	}
+	return g.client.VideoModel()
+}
+
+// HasCredentials reports whether a DashScope API key is configured.
+func (g *QwenGenerator) HasCredentials() bool {
+	return g != nil && g.client != nil && g.client.HasCredentials()
+}
+
+var _ Generator = (*QwenGenerator)(nil)
+
+func shouldFallbackToSynthetic(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, qwen.ErrMissingAPIKey) {
+		return true
+	}
+	msg := strings.ToLower(strings.TrimSpace(err.Error()))
+	tokens := []string{
+		"unauthorized",
+		"forbidden",
+		"internalerror",
+		"internal error",
+		"service unavailable",
+		"server unavailable",
+		"timeout",
+	}
+	for _, token := range tokens {
+		if strings.Contains(msg, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"server/internal/providers/genai"
+	"server/internal/providers/qwen"
+)
+
+type stubQwenVideoClient struct {
+	asset          *qwen.VideoAsset
+	err            error
+	hasCredentials bool
+	calls          int
+	lastReq        qwen.VideoRequest
+	noSynthetic    bool
+}
+
+func (s *stubQwenVideoClient) GenerateVideo(ctx context.Context, req qwen.VideoRequest) (*qwen.VideoAsset, error) {
+	s.calls++
+	s.lastReq = req
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.asset, nil
+}
+
+func (s *stubQwenVideoClient) HasCredentials() bool {
+	return s.hasCredentials
+}
+
+func (s *stubQwenVideoClient) SyntheticFallback() bool {
+	return !s.noSynthetic
+}
+
+func (s *stubQwenVideoClient) VideoModel() string {
+	return "wan2.1-t2v-turbo"
+}
+
+func TestQwenGeneratorFallsBackToSyntheticWithoutCredentials(t *testing.T) {
+	client, err := qwen.NewClient(qwen.Options{})
+	if err != nil {
+		t.Fatalf("new qwen client: %v", err)
+	}
+	geminiClient, err := genai.NewClient(genai.Options{})
+	if err != nil {
+		t.Fatalf("new gemini client: %v", err)
+	}
+	gen := NewQwenGenerator(client, NewGeminiGenerator(geminiClient))
+
+	asset, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "product spin", RequestID: "req-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if asset == nil || len(asset.Data) == 0 {
+		t.Fatalf("expected synthetic video data")
+	}
+	if asset.Format != "video/mp4" {
+		t.Fatalf("format = %q, want video/mp4", asset.Format)
+	}
+}
+
+func TestQwenGeneratorReturnsRemoteAsset(t *testing.T) {
+	client := &stubQwenVideoClient{
+		hasCredentials: true,
+		asset:          &qwen.VideoAsset{URL: "https://example.com/out.mp4", Format: "video/mp4", Length: 5, Data: []byte{0x01}},
+	}
+	gen := NewQwenGenerator(client, nil)
+
+	asset, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "  product spin  "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.lastReq.Prompt != "product spin" {
+		t.Fatalf("prompt = %q, want trimmed prompt", client.lastReq.Prompt)
+	}
+	if asset.URL != "https://example.com/out.mp4" || asset.Length != 5 {
+		t.Fatalf("unexpected asset: %#v", asset)
+	}
+}
+
+func TestQwenGeneratorSurfacesNonTransientErrors(t *testing.T) {
+	client := &stubQwenVideoClient{
+		hasCredentials: true,
+		err:            errors.New("qwen: prompt rejected (DataInspectionFailed)"),
+	}
+	geminiClient, err := genai.NewClient(genai.Options{})
+	if err != nil {
+		t.Fatalf("new gemini client: %v", err)
+	}
+	gen := NewQwenGenerator(client, NewGeminiGenerator(geminiClient))
+
+	if _, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "product spin"}); err == nil {
+		t.Fatalf("expected error to be returned")
+	}
+}
+
+func TestQwenGeneratorWithoutCredentialsHonoursSyntheticPolicy(t *testing.T) {
+	client := &stubQwenVideoClient{noSynthetic: true}
+	geminiClient, err := genai.NewClient(genai.Options{})
+	if err != nil {
+		t.Fatalf("new gemini client: %v", err)
+	}
+	gen := NewQwenGenerator(client, NewGeminiGenerator(geminiClient))
+
+	if _, err := gen.Generate(context.Background(), GenerateRequest{Prompt: "product spin"}); !errors.Is(err, qwen.ErrMissingAPIKey) {
+		t.Fatalf("err = %v, want ErrMissingAPIKey", err)
+	}
+	if client.calls != 0 {
+		t.Fatalf("calls = %d, want no remote call", client.calls)
+	}
+}
@@ -0,0 +1,29 @@
+package sqlinline
+
+const QListFailedJobs = `--sql 4052f7c2-1ae3-47b7-b303-1ae930dc53de
+select
+  id,
+  user_id,
+  task_type,
+  provider,
+  attempts,
+  coalesce(nullif(properties->>'error', ''), error_message, '') as error,
+  created_at,
+  updated_at
+from generation_requests
+where status = 'FAILED'
+  and created_at >= now() - interval '24 hours'
+order by created_at desc
+limit $1::int;
+`
+
+const QImageJobProviderStats = `--sql 0a08c2ff-ebc4-4d5f-84ae-94ac7e3af1a7
+select
+  provider,
+  status,
+  count(*) as jobs,
+  coalesce(sum(extract(epoch from updated_at - created_at)), 0)::float8 as latency_seconds
+from image_jobs
+group by provider, status
+order by provider, status;
+`
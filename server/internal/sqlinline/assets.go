@@ -14,6 +14,7 @@ select
   created_at
 from assets
 where user_id = $1::uuid
+  and not (properties ? 'deleted_at')
 order by created_at desc
 limit $2::int offset $3::int;
 `
@@ -22,6 +23,26 @@ const QSelectAssetByID = `--sql 5e1a10af-829f-4e1d-9f62-9d725d543b48
 select id, user_id, storage_key, mime, bytes, width, height, aspect_ratio, properties
 from assets
 where id = $1::uuid
+  and not (properties ? 'deleted_at')
+limit 1;
+`
+
+const QSelectAssetByStorageKey = `--sql 63587806-259e-4359-bd1a-9acbaa16d698
+select id, user_id, mime
+from assets
+where storage_key = $1::text
+  and not (properties ? 'deleted_at')
+limit 1;
+`
+
+const QFindAssetByHash = `--sql 13e7b92f-cb1d-4333-a565-07bf95c6c5c1
+select id, storage_key, mime, bytes, width, height, coalesce(aspect_ratio, '')
+from assets
+where user_id = $1::uuid
+  and properties ? 'sha256'
+  and properties->>'sha256' = $2::text
+  and not (properties ? 'deleted_at')
+order by created_at desc
 limit 1;
 `
 
@@ -56,3 +77,49 @@ insert into assets(
   now()
 ) returning id;
 `
+
+const QSoftDeleteAsset = `--sql 1a611df7-4e9b-45bc-a777-ee86a6e16215
+update assets
+set properties = coalesce(properties, '{}'::jsonb) || jsonb_build_object('deleted_at', now()),
+    updated_at = now()
+where id = $1::uuid
+  and user_id = $2::uuid
+  and not (coalesce(properties, '{}'::jsonb) ? 'deleted_at');
+`
+
+const QSelectPurgeableAssets = `--sql 775d3f0b-4aeb-466e-95bb-40a69c357c9d
+select id, storage_key, coalesce(properties->>'thumbnail_key', '')
+from assets
+where properties ? 'deleted_at'
+  and (properties->>'deleted_at')::timestamptz < now() - make_interval(secs => $1::int)
+order by (properties->>'deleted_at')::timestamptz asc
+limit $2::int;
+`
+
+const QSelectExpiredPlanAssets = `--sql f5d36768-8758-42f6-b39b-17453e04e85f
+select a.id, a.storage_key, coalesce(a.properties->>'thumbnail_key', '')
+from assets a
+join users u on u.id = a.user_id
+where u.plan = $1::text
+  and a.created_at < now() - make_interval(secs => $2::int)
+  and not (coalesce(a.properties, '{}'::jsonb) ? 'deleted_at')
+  and not exists (
+    select 1
+    from generation_requests gr
+    where gr.status in ('QUEUED', 'RUNNING')
+      and (gr.source_asset_id = a.id or gr.id = a.request_id)
+  )
+  and not exists (
+    select 1
+    from image_jobs ij
+    where ij.status in ('QUEUED', 'RUNNING')
+      and ij.source_asset->>'asset_id' = a.id::text
+  )
+order by a.created_at asc
+limit $3::int;
+`
+
+const QDeleteAsset = `--sql 0e4efa61-2118-47bb-a5a3-76e3005cb3ee
+delete from assets
+where id = $1::uuid;
+`
@@ -1,14 +1,20 @@
 package sqlinline
 
 const QInsertDonation = `--sql 9b79c57c-3615-48a2-9d85-3426d5b3f7eb
-insert into donations(id, user_id, amount_int, note, testimonial, properties, created_at, updated_at)
-values (gen_random_uuid(), nullif($1::text, '')::uuid, $2::bigint, $3::text, $4::text, coalesce($5::jsonb, '{}'::jsonb), now(), now())
-returning id;
+insert into donations(id, user_id, amount_int, note, testimonial, properties, payment_ref, created_at, updated_at)
+values (gen_random_uuid(), nullif($1::text, '')::uuid, $2::bigint, $3::text, $4::text, coalesce($5::jsonb, '{}'::jsonb), nullif($6::text, ''), now(), now())
+on conflict (payment_ref) do nothing
+returning id, user_id, amount_int, note, testimonial, coalesce(payment_ref, '') as payment_ref, created_at;
+`
+
+const QDonationPaymentRefExists = `--sql 16bce3a1-a858-4d2a-af71-790e3ce49373
+select exists (select 1 from donations where payment_ref = $1::text);
 `
 
 const QListDonations = `--sql 7a08e4f6-cb8a-42c4-bd7f-291d6e913edc
 select id, user_id, amount_int, note, testimonial, properties, created_at
 from donations
+where amount_int >= $3::bigint
 order by created_at desc
-limit $1::int;
+limit $1::int offset $2::int;
 `
@@ -0,0 +1,5 @@
+package sqlinline
+
+const QPing = `--sql d3d006de-d477-4473-9211-1a4883a7528e
+select 1;
+`
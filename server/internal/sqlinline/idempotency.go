@@ -0,0 +1,40 @@
+package sqlinline
+
+const QLookupIdempotentJob = `--sql 0df08eda-d0f9-4319-b860-e520e2e8094f
+select coalesce(k.job_id::text, ''),
+       greatest(coalesce((u.properties->>'quota_daily')::int, 2) - case
+         when u.properties->>'quota_refreshed_at' is null
+           or ((u.properties->>'quota_refreshed_at')::timestamptz at time zone 'utc')::date < (now() at time zone 'utc')::date then 0
+         else coalesce((u.properties->>'quota_used_today')::int, 0)
+       end, 0) as remaining
+from idempotency_keys k
+join users u on u.id = k.user_id
+where k.user_id = $1::uuid
+  and k.key = $2::text
+  and k.created_at > now() - interval '24 hours';
+`
+
+const QReserveIdempotencyKey = `--sql 303715e2-3587-4f22-9ba6-5929097eb6bf
+insert into idempotency_keys (user_id, key)
+values ($1::uuid, $2::text)
+on conflict (user_id, key) do update
+set job_id = null, created_at = now()
+where idempotency_keys.created_at <= now() - interval '24 hours'
+   or (idempotency_keys.job_id is null and idempotency_keys.created_at <= now() - interval '5 minutes')
+returning true;
+`
+
+const QRecordIdempotentJob = `--sql f79aa733-5723-4b6d-a336-89129178ca51
+update idempotency_keys
+set job_id = $3::uuid
+where user_id = $1::uuid
+  and key = $2::text
+  and job_id is null;
+`
+
+const QReleaseIdempotencyKey = `--sql 3405b2a8-0672-4277-99aa-f1ebf584d8f1
+delete from idempotency_keys
+where user_id = $1::uuid
+  and key = $2::text
+  and job_id is null;
+`
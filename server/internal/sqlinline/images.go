@@ -11,7 +11,7 @@ input as (
     $5::text     as provider
 ),
 quota as (
-  select remaining from fn_consume_quota((select user_id from input), (select quantity from input))
+  select remaining from fn_consume_quota((select user_id from input), (select quantity from input), (now() at time zone 'utc')::date)
 ),
 job as (
   select job_id from fn_insert_job_and_usage(
@@ -37,6 +37,18 @@ set status = $2::text,
 where id = $1::uuid;
 `
 
+const QUpdateJobStatusWithError = `--sql 7379d702-8e6a-44d3-9cd3-e4d80e063b7c
+update generation_requests
+set status = $2::text,
+    error_message = $3::text,
+    updated_at = now(),
+    properties = jsonb_set(
+        jsonb_set(coalesce(properties, '{}'::jsonb), '{error}', to_jsonb($3::text), true),
+        '{status_history}', coalesce(properties->'status_history', '[]'::jsonb) || jsonb_build_object('status', $2::text, 'at', now()), true
+    )
+where id = $1::uuid;
+`
+
 const QInsertAsset = `--sql 1a0b29f1-9b31-4d4c-9f5c-52dd2ad9f267
 insert into assets(
   id,
@@ -69,6 +81,48 @@ insert into assets(
 ) returning id;
 `
 
+const QCancelJob = `--sql 69c9db84-5c6d-4cf3-b757-1d45ea308ba5
+with target as (
+    select id, user_id, quantity, status, created_at
+    from generation_requests
+    where id = $1::uuid
+      and user_id = $2::uuid
+    for update
+),
+cancelled as (
+    update generation_requests g
+    set status = 'CANCELED',
+        updated_at = now(),
+        properties = jsonb_set(coalesce(g.properties, '{}'::jsonb), '{status_history}', coalesce(g.properties->'status_history', '[]'::jsonb) || jsonb_build_object('status', 'CANCELED', 'at', now()), true)
+    from target
+    where g.id = target.id
+      and target.status = 'QUEUED'
+    returning g.user_id, g.quantity, target.created_at
+),
+refund as (
+    update users u
+    set properties = jsonb_set(u.properties, '{quota_used_today}', to_jsonb(greatest(coalesce((u.properties->>'quota_used_today')::int, 0) - c.quantity, 0)), true),
+        updated_at = now()
+    from cancelled c
+    where u.id = c.user_id
+      and (c.created_at at time zone 'utc')::date = (now() at time zone 'utc')::date
+      and ((u.properties->>'quota_refreshed_at')::timestamptz at time zone 'utc')::date = (now() at time zone 'utc')::date
+    returning greatest(coalesce((u.properties->>'quota_daily')::int, 2) - coalesce((u.properties->>'quota_used_today')::int, 0), 0) as remaining
+),
+current_quota as (
+    select greatest(coalesce((u.properties->>'quota_daily')::int, 2) - case
+             when u.properties->>'quota_refreshed_at' is null
+               or ((u.properties->>'quota_refreshed_at')::timestamptz at time zone 'utc')::date < (now() at time zone 'utc')::date then 0
+             else coalesce((u.properties->>'quota_used_today')::int, 0)
+           end, 0) as remaining
+    from users u
+    where u.id = $2::uuid
+)
+select target.status,
+       coalesce((select remaining from refund), (select remaining from current_quota), 0)
+from target;
+`
+
 const QSelectJobStatus = `--sql 8f12e6f8-812e-4c0d-bf9a-57f6318c12fb
 select id, user_id, task_type, status, provider, quantity, aspect_ratio, created_at, updated_at, properties
 from generation_requests
@@ -82,5 +136,6 @@ select id, storage_key, mime, bytes, width, height, aspect_ratio, properties, cr
 from assets
 where request_id = $1::uuid
   and user_id = $2::uuid
+  and not (properties ? 'deleted_at')
 order by created_at asc;
 `
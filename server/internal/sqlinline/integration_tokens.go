@@ -21,3 +21,22 @@ on conflict (provider) do update set
     properties = excluded.properties,
     updated_at = now();
 `
+
+const QListIntegrationTokens = `--sql 4608857a-b4e7-49b7-9866-0271928a54b0
+select provider, token, coalesce(previous_key_fingerprint, '') as previous_key_fingerprint, updated_at
+from integration_tokens
+order by provider;
+`
+
+const QRotateIntegrationToken = `--sql fbb5a4c5-028b-456d-8918-952356a22660
+update integration_tokens
+set token = $2::text,
+    previous_key_fingerprint = $3::text,
+    updated_at = now()
+where provider = $1::text;
+`
+
+const QDeleteIntegrationToken = `--sql 8e5b7390-a289-41bb-a715-924aa4680ac4
+delete from integration_tokens
+where provider = $1::text;
+`
@@ -0,0 +1,21 @@
+package sqlinline
+
+const QInsertPromptDraft = `--sql 76163384-b4ec-42cc-9ae2-9cd4d7e3fb6d
+insert into prompt_drafts (id, user_id, name, prompt_json, created_at)
+values (gen_random_uuid(), $1::uuid, $2::text, $3::jsonb, now())
+returning id, created_at;
+`
+
+const QListPromptDrafts = `--sql 50a06402-be18-42ca-b946-71ecd8232282
+select id, name, prompt_json, created_at
+from prompt_drafts
+where user_id = $1::uuid
+order by created_at desc
+limit $2::int offset $3::int;
+`
+
+const QDeletePromptDraft = `--sql 31499232-e64b-4e1f-a153-432b3e4d8dbc
+delete from prompt_drafts
+where id = $1::uuid
+  and user_id = $2::uuid;
+`
@@ -0,0 +1,24 @@
+package sqlinline
+
+const QListPromptTemplates = `--sql 71ea243c-5a38-4a43-a1d7-a144c288364e
+with candidates as (
+  select id, category, locale, title, template_json,
+         array_position($2::text[], locale) as rank
+  from prompt_templates
+  where user_id is null
+    and ($1::text = '' or category = $1::text)
+    and locale = any($2::text[])
+)
+select id, category, locale, title, template_json
+from candidates c
+where rank = (select min(rank) from candidates where category = c.category)
+order by category, title;
+`
+
+const QSelectPromptTemplate = `--sql c8f20493-071c-43ab-aea8-31ca913d6913
+select id, category, locale, title, template_json
+from prompt_templates
+where id = $1::uuid
+  and user_id is null
+limit 1;
+`
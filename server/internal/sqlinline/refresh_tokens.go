@@ -0,0 +1,37 @@
+package sqlinline
+
+const QInsertRefreshToken = `--sql d47a85cf-6d52-4350-81a9-4ef398ea06d4
+insert into refresh_tokens (id, user_id, family_id, token_hash, expires_at, created_at)
+values (gen_random_uuid(), $1::uuid, $2::uuid, $3::text, $4::timestamptz, now());
+`
+
+const QSelectRefreshToken = `--sql 29a37170-500e-4258-ac84-938c28b0265f
+select id, user_id, family_id, expires_at, revoked_at is not null as revoked
+from refresh_tokens
+where token_hash = $1::text
+limit 1;
+`
+
+const QRevokeRefreshToken = `--sql a456bb1c-1fde-4b0e-b452-080fc2f263af
+update refresh_tokens
+set revoked_at = now()
+where id = $1::uuid
+  and revoked_at is null;
+`
+
+const QRevokeRefreshTokenFamily = `--sql f67e5fe1-c0ae-4d3d-ab0e-ea0de1e5d53c
+update refresh_tokens
+set revoked_at = coalesce(revoked_at, now())
+where family_id = $1::uuid;
+`
+
+const QSelectUserForToken = `--sql 604406da-f0fc-45ad-b543-dedc8494b70b
+select
+    id,
+    plan,
+    coalesce(locale_pref, properties->>'preferred_locale', properties->>'google_locale', '') as locale,
+    properties
+from users
+where id = $1::uuid
+limit 1;
+`
@@ -0,0 +1,20 @@
+package sqlinline
+
+const QInsertRevokedToken = `--sql f599e6e1-6dbc-47bb-a7d6-5bf2a4b7a634
+insert into revoked_tokens (jti, user_id, expires_at, revoked_at)
+values ($1::text, nullif($2::text, '')::uuid, $3::timestamptz, now())
+on conflict (jti) do nothing;
+`
+
+const QIsTokenRevoked = `--sql bfe5a76f-dcde-4a39-9d66-f3362f58f123
+select exists (
+    select 1
+    from revoked_tokens
+    where jti = $1::text
+);
+`
+
+const QDeleteExpiredRevokedTokens = `--sql f75db745-3f32-4d63-a31d-cd2067ecba33
+delete from revoked_tokens
+where expires_at < now();
+`
@@ -0,0 +1,11 @@
+package sqlinline
+
+const QListReferencedStorageKeys = `--sql eb478700-0cc5-4b9c-ae80-56fc98d154f0
+select storage_key
+from assets
+where storage_key <> ''
+union
+select properties->>'thumbnail_key'
+from assets
+where coalesce(properties->>'thumbnail_key', '') <> '';
+`
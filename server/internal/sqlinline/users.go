@@ -137,3 +137,23 @@ set
 where id = $1::uuid
 returning id, email, plan, properties;
 `
+
+const QUpdateUserBrandName = `--sql c3cf1dc3-4790-4fb5-b9bc-57033bd31cb6
+update users
+set
+    properties = case
+        when $2::text = '' then coalesce(properties, '{}'::jsonb) - 'brand_name'
+        else jsonb_set(coalesce(properties, '{}'::jsonb), '{brand_name}', to_jsonb($2::text), true)
+    end,
+    updated_at = now()
+where id = $1::uuid
+returning
+    id,
+    coalesce(google_sub, clerk_user_id) as google_sub,
+    email,
+    coalesce(locale_pref, properties->>'preferred_locale') as locale,
+    plan,
+    properties,
+    created_at,
+    updated_at;
+`
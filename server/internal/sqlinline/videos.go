@@ -5,10 +5,11 @@ with input as (
   select
     $1::uuid as user_id,
     $2::jsonb as prompt_json,
-    $3::text as provider
+    $3::text as provider,
+    $4::jsonb as properties
 ),
 quota as (
-  select remaining from fn_consume_quota((select user_id from input), 1)
+  select remaining from fn_consume_quota((select user_id from input), 1, (now() at time zone 'utc')::date)
 ),
 job as (
   select job_id from fn_insert_job_and_usage(
@@ -19,7 +20,7 @@ job as (
     1,
     '16:9',
     (select provider from input),
-    '{}'::jsonb
+    (select properties from input)
   )
 )
 select job.job_id, quota.remaining
@@ -5,15 +5,39 @@ with next_job as (
     select id
     from generation_requests
     where status = 'QUEUED'
+      and (next_run_at is null or next_run_at <= now())
     order by created_at asc
     for update skip locked
     limit 1
 ),
 updated as (
     update generation_requests
-    set status = 'RUNNING', updated_at = now()
+    set status = 'RUNNING', attempts = attempts + 1, updated_at = now()
     where id in (select id from next_job)
-    returning id, user_id, task_type, provider, quantity, aspect_ratio, prompt_json
+    returning id, user_id, task_type, provider, quantity, aspect_ratio, prompt_json, attempts, coalesce(properties->>'callback_url', '') as callback_url, coalesce(properties->>'request_id', '') as request_id
 )
-select * from updated;
+select updated.*, coalesce(u.plan, '') as plan, coalesce(u.properties->>'brand_name', '') as brand_name
+from updated
+left join users u on u.id = updated.user_id;
+`
+
+const QRequeueJob = `--sql e28d3332-64e2-424e-93b1-4346e07643fc
+update generation_requests
+set status = 'QUEUED',
+    next_run_at = now() + make_interval(secs => $2::int),
+    updated_at = now(),
+    properties = jsonb_set(coalesce(properties, '{}'::jsonb), '{status_history}', coalesce(properties->'status_history', '[]'::jsonb) || jsonb_build_object('status', 'QUEUED', 'at', now(), 'attempts', attempts), true)
+where id = $1::uuid;
+`
+
+const QWorkerHeartbeat = `--sql d7c5cf5b-284f-4209-b1d8-580b6a4d0f82
+insert into worker_heartbeat (worker_id, last_seen_at)
+values ($1::text, now())
+on conflict (worker_id) do update
+set last_seen_at = excluded.last_seen_at;
+`
+
+const QSelectWorkerHeartbeat = `--sql 88ab79b2-b1b9-4510-9619-7d7080472c0a
+select max(last_seen_at)
+from worker_heartbeat;
 `
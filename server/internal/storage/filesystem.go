@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileStore persists assets onto the local filesystem. It is intended for
+// development and test environments where an object storage service is not
+// available.
+type FileStore struct {
+	basePath string
+}
+
+// NewFileStore initializes a FileStore rooted at basePath.
+func NewFileStore(basePath string) (*FileStore, error) {
+	basePath = strings.TrimSpace(basePath)
+	if basePath == "" {
+		return nil, errors.New("storage: base path is required")
+	}
+	if err := os.MkdirAll(basePath, 0o755); err != nil {
+		return nil, fmt.Errorf("storage: ensure base path: %w", err)
+	}
+	return &FileStore{basePath: basePath}, nil
+}
+
+// BasePath returns the configured root directory.
+func (s *FileStore) BasePath() string {
+	if s == nil {
+		return ""
+	}
+	return s.basePath
+}
+
+// Write persists the provided bytes at the given relative key and returns the
+// canonicalized storage key. Keys are cleaned to prevent directory traversal.
+func (s *FileStore) Write(ctx context.Context, key string, data []byte) (string, error) {
+	if s == nil {
+		return "", errors.New("storage: no store configured")
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	cleanKey, err := sanitizeKey(key)
+	if err != nil {
+		return "", err
+	}
+	fullPath := filepath.Join(s.basePath, filepath.FromSlash(cleanKey))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return "", fmt.Errorf("storage: ensure directory: %w", err)
+	}
+	if err := os.WriteFile(fullPath, data, 0o644); err != nil {
+		return "", fmt.Errorf("storage: write file: %w", err)
+	}
+	return cleanKey, nil
+}
+
+// sanitizeKey normalizes a key and prevents escaping the storage root.
+func sanitizeKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", errors.New("storage: key is required")
+	}
+	key = strings.ReplaceAll(key, "\\", "/")
+	key = strings.TrimPrefix(key, "./")
+	key = strings.TrimLeft(key, "/")
+	cleaned := filepath.Clean(key)
+	cleaned = strings.ReplaceAll(cleaned, "\\", "/")
+	if cleaned == "." || strings.HasPrefix(cleaned, "../") {
+		return "", errors.New("storage: invalid key")
+	}
+	return cleaned, nil
+}
+
+// Read fetches the bytes stored at the given key.
+func (s *FileStore) Read(ctx context.Context, key string) ([]byte, error) {
+	if s == nil {
+		return nil, errors.New("storage: no store configured")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cleanKey, err := sanitizeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(s.basePath, filepath.FromSlash(cleanKey))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("storage: read file: %w", err)
+	}
+	return data, nil
+}
+
+// Open returns a handle to the file stored at key so callers can stream it or
+// serve byte ranges without loading the whole asset into memory.
+func (s *FileStore) Open(ctx context.Context, key string) (*os.File, error) {
+	if s == nil {
+		return nil, errors.New("storage: no store configured")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cleanKey, err := sanitizeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(s.basePath, filepath.FromSlash(cleanKey)))
+	if err != nil {
+		return nil, fmt.Errorf("storage: open file: %w", err)
+	}
+	return file, nil
+}
+
+// Delete removes the file stored at key. Deleting a missing key is not an
+// error so sweeps can be retried safely.
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	if s == nil {
+		return errors.New("storage: no store configured")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cleanKey, err := sanitizeKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(s.basePath, filepath.FromSlash(cleanKey))); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("storage: delete file: %w", err)
+	}
+	return nil
+}
+
+// Object describes a stored file as seen by List.
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// List calls fn for every file under the storage root, in lexical order.
+func (s *FileStore) List(ctx context.Context, fn func(Object) error) error {
+	if s == nil {
+		return errors.New("storage: no store configured")
+	}
+	return filepath.WalkDir(s.basePath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("storage: stat file: %w", err)
+		}
+		rel, err := filepath.Rel(s.basePath, p)
+		if err != nil {
+			return fmt.Errorf("storage: resolve key: %w", err)
+		}
+		return fn(Object{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+	})
+}
@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3Service      = "s3"
+	s3Algorithm    = "AWS4-HMAC-SHA256"
+	s3AmzDate      = "20060102T150405Z"
+	s3ErrorBodyMax = 512
+)
+
+// S3Options configures an S3Store.
+type S3Options struct {
+	// Endpoint is the service base URL, e.g. https://s3.ap-southeast-1.amazonaws.com
+	// or a MinIO/R2 endpoint. Objects are addressed path-style.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix is prepended to every key so several environments can share a
+	// bucket. Keys returned by Write never include it.
+	Prefix     string
+	HTTPClient *http.Client
+	Now        func() time.Time
+}
+
+// S3Store persists assets in an S3-compatible bucket using SigV4 signed
+// requests.
+type S3Store struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	prefix    string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewS3Store validates opts and returns a store for the bucket.
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	endpoint, err := url.Parse(strings.TrimRight(strings.TrimSpace(opts.Endpoint), "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("storage: invalid s3 endpoint %q", opts.Endpoint)
+	}
+	bucket := strings.TrimSpace(opts.Bucket)
+	if bucket == "" {
+		return nil, errors.New("storage: s3 bucket is required")
+	}
+	if strings.TrimSpace(opts.AccessKeyID) == "" || strings.TrimSpace(opts.SecretAccessKey) == "" {
+		return nil, errors.New("storage: s3 credentials are required")
+	}
+	region := strings.TrimSpace(opts.Region)
+	if region == "" {
+		region = "us-east-1"
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &S3Store{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    bucket,
+		accessKey: strings.TrimSpace(opts.AccessKeyID),
+		secretKey: strings.TrimSpace(opts.SecretAccessKey),
+		prefix:    strings.Trim(strings.TrimSpace(opts.Prefix), "/"),
+		client:    client,
+		now:       now,
+	}, nil
+}
+
+// Write uploads data under key and returns the canonical (unprefixed) key.
+func (s *S3Store) Write(ctx context.Context, key string, data []byte) (string, error) {
+	if s == nil {
+		return "", errors.New("storage: no store configured")
+	}
+	cleanKey, err := sanitizeKey(key)
+	if err != nil {
+		return "", err
+	}
+	req, err := s.newRequest(ctx, http.MethodPut, cleanKey, data)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", http.DetectContentType(data))
+	resp, err := s.do(req, data)
+	if err != nil {
+		return "", fmt.Errorf("storage: put object: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("storage: put object: %w", s3StatusError(resp))
+	}
+	return cleanKey, nil
+}
+
+// Read downloads the object stored at key. Missing objects wrap
+// fs.ErrNotExist like FileStore does.
+func (s *S3Store) Read(ctx context.Context, key string) ([]byte, error) {
+	if s == nil {
+		return nil, errors.New("storage: no store configured")
+	}
+	cleanKey, err := sanitizeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.newRequest(ctx, http.MethodGet, cleanKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, nil)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get object: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("storage: get object %s: %w", cleanKey, fs.ErrNotExist)
+	default:
+		return nil, fmt.Errorf("storage: get object: %w", s3StatusError(resp))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get object: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes the object stored at key. Missing objects are not an error.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if s == nil {
+		return errors.New("storage: no store configured")
+	}
+	cleanKey, err := sanitizeKey(key)
+	if err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodDelete, cleanKey, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, nil)
+	if err != nil {
+		return fmt.Errorf("storage: delete object: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("storage: delete object: %w", s3StatusError(resp))
+	}
+}
+
+// objectKey applies the configured prefix to a sanitized key.
+func (s *S3Store) objectKey(cleanKey string) string {
+	if s.prefix == "" {
+		return cleanKey
+	}
+	return s.prefix + "/" + cleanKey
+}
+
+func (s *S3Store) objectURL(cleanKey string) *url.URL {
+	u := *s.endpoint
+	u.Path = path.Join("/", s.endpoint.Path, s.bucket, s.objectKey(cleanKey))
+	u.RawPath = s3EscapePath(u.Path)
+	return &u
+}
+
+func (s *S3Store) newRequest(ctx context.Context, method, cleanKey string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(cleanKey).String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("storage: build s3 request: %w", err)
+	}
+	return req, nil
+}
+
+func (s *S3Store) do(req *http.Request, body []byte) (*http.Response, error) {
+	signV4(req, body, s.accessKey, s.secretKey, s.region, s.now())
+	return s.client.Do(req)
+}
+
+func s3StatusError(resp *http.Response) error {
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, s3ErrorBodyMax))
+	return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+}
+
+// signV4 adds AWS Signature Version 4 headers to req. Host and every header
+// already set on req are signed.
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(s3AmzDate)
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/" + s3Service + "/aws4_request"
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, accessKey, scope, signedHeaders, signature))
+}
+
+// s3EscapePath URI-encodes each path segment per the SigV4 rules, keeping the
+// slashes between segments.
+func s3EscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func s3CanonicalQuery(values url.Values) string {
+	if len(values) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		vals := append([]string(nil), values[key]...)
+		sort.Strings(vals)
+		for _, val := range vals {
+			parts = append(parts, s3Escape(key)+"="+s3Escape(val))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything except RFC 3986 unreserved characters.
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
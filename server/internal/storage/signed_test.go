@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseSignedURL(t *testing.T, raw string) (string, string, string) {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse %q: %v", raw, err)
+	}
+	return strings.TrimPrefix(u.Path, "/v1/files/"), u.Query().Get("expires"), u.Query().Get("sig")
+}
+
+func TestURLSignerVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	signer := NewURLSigner("https://api.example.com/v1/files/", "s3cret")
+	signer.now = func() time.Time { return now }
+
+	raw, err := signer.SignedURL("/generated/job 1/0.png", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	if !strings.HasPrefix(raw, "https://api.example.com/v1/files/generated/job%201/0.png?expires=1700000600&sig=") {
+		t.Fatalf("unexpected url %q", raw)
+	}
+	key, expires, sig := parseSignedURL(t, raw)
+
+	if err := signer.Verify(key, expires, sig); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+
+	cases := []struct {
+		name              string
+		key, expires, sig string
+		advance           time.Duration
+		want              error
+	}{
+		{name: "expired", key: key, expires: expires, sig: sig, advance: 10 * time.Minute, want: ErrSignatureExpired},
+		{name: "other key", key: "generated/job 1/1.png", expires: expires, sig: sig, want: ErrSignatureInvalid},
+		{name: "extended expiry", key: key, expires: "1800000000", sig: sig, want: ErrSignatureInvalid},
+		{name: "tampered sig", key: key, expires: expires, sig: strings.Repeat("0", len(sig)), want: ErrSignatureInvalid},
+		{name: "missing", key: key, want: ErrSignatureInvalid},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			signer.now = func() time.Time { return now.Add(tc.advance) }
+			if err := signer.Verify(tc.key, tc.expires, tc.sig); !errors.Is(err, tc.want) {
+				t.Fatalf("Verify err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	other := NewURLSigner("https://api.example.com/v1/files", "different")
+	other.now = func() time.Time { return now }
+	if err := other.Verify(key, expires, sig); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("signature accepted under another secret: %v", err)
+	}
+}
+
+func TestNewURLSignerWithoutSecret(t *testing.T) {
+	if NewURLSigner("https://api.example.com/v1/files", " ") != nil {
+		t.Fatal("expected nil signer without a secret")
+	}
+}
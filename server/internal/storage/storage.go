@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"server/internal/infra"
+)
+
+// Storage is the asset store shared by the API and the worker. Keys are
+// relative slash-separated paths; Write returns the canonical key to persist.
+type Storage interface {
+	Write(ctx context.Context, key string, data []byte) (string, error)
+	Read(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Opener is implemented by stores that can hand out a seekable file, letting
+// callers serve byte ranges without buffering the asset.
+type Opener interface {
+	Open(ctx context.Context, key string) (*os.File, error)
+}
+
+// Lister is implemented by stores that can enumerate their keys.
+type Lister interface {
+	List(ctx context.Context, fn func(Object) error) error
+}
+
+// New builds the store selected by STORAGE_DRIVER.
+func New(cfg *infra.Config) (Storage, error) {
+	switch cfg.StorageDriver {
+	case "", infra.StorageDriverLocal:
+		basePath := cfg.StoragePath
+		if basePath == "" {
+			basePath = "./storage"
+		}
+		if !filepath.IsAbs(basePath) {
+			if abs, err := filepath.Abs(basePath); err == nil {
+				basePath = abs
+			}
+		}
+		return NewFileStore(basePath)
+	case infra.StorageDriverS3:
+		return NewS3Store(S3Options{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			Prefix:          cfg.S3Prefix,
+		})
+	default:
+		return nil, fmt.Errorf("storage: unknown driver %q", cfg.StorageDriver)
+	}
+}
+
+var (
+	_ Storage = (*FileStore)(nil)
+	_ Opener  = (*FileStore)(nil)
+	_ Lister  = (*FileStore)(nil)
+	_ Storage = (*S3Store)(nil)
+)
@@ -0,0 +1,81 @@
+// Package thumbnail renders small previews of generated images so gallery
+// grids do not have to download full-resolution assets.
+package thumbnail
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// DefaultMaxSide bounds the longest edge of a thumbnail in pixels.
+const DefaultMaxSide = 256
+
+// Scale returns src resized with nearest-neighbour sampling so its longest
+// edge is at most maxSide, preserving the aspect ratio. Images that already
+// fit are copied unchanged; they are never enlarged.
+func Scale(src image.Image, maxSide int) *image.NRGBA {
+	if maxSide <= 0 {
+		maxSide = DefaultMaxSide
+	}
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			dw, dh = maxSide, h*maxSide/w
+		} else {
+			dw, dh = w*maxSide/h, maxSide
+		}
+		if dw < 1 {
+			dw = 1
+		}
+		if dh < 1 {
+			dh = 1
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy := bounds.Min.Y + y*h/dh
+		for x := 0; x < dw; x++ {
+			sx := bounds.Min.X + x*w/dw
+			dst.Set(x, y, src.At(sx, sy))
+		}
+	}
+	return dst
+}
+
+// Generate decodes data and returns an encoded thumbnail along with its MIME
+// type and dimensions. Opaque images are written as JPEG; images with
+// transparency stay PNG.
+func Generate(data []byte, maxSide int) ([]byte, string, int, int, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", 0, 0, fmt.Errorf("thumbnail: decode image: %w", err)
+	}
+	thumb := Scale(img, maxSide)
+	var buf bytes.Buffer
+	mime := "image/jpeg"
+	if opaque(thumb) {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+	} else {
+		mime = "image/png"
+		err = png.Encode(&buf, thumb)
+	}
+	if err != nil {
+		return nil, "", 0, 0, fmt.Errorf("thumbnail: encode: %w", err)
+	}
+	return buf.Bytes(), mime, thumb.Bounds().Dx(), thumb.Bounds().Dy(), nil
+}
+
+func opaque(img *image.NRGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
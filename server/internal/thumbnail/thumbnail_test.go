@@ -0,0 +1,64 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerateShrinksAndKeepsAspect(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 1024, 512))
+	for y := 0; y < 512; y++ {
+		for x := 0; x < 1024; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: uint8(x ^ y), A: 255})
+		}
+	}
+	data := encodePNG(t, src)
+
+	thumb, mime, w, h, err := Generate(data, DefaultMaxSide)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if w != 256 || h != 128 {
+		t.Fatalf("thumbnail size = %dx%d, want 256x128", w, h)
+	}
+	if mime != "image/jpeg" {
+		t.Fatalf("mime = %q, want image/jpeg for an opaque source", mime)
+	}
+	if len(thumb) >= len(data) {
+		t.Fatalf("thumbnail (%d bytes) is not smaller than source (%d bytes)", len(thumb), len(data))
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil || cfg.Width != 256 || cfg.Height != 128 {
+		t.Fatalf("decoded thumbnail = %+v, err = %v", cfg, err)
+	}
+}
+
+func TestGenerateKeepsTransparencyAsPNG(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 300, 600))
+	_, mime, w, h, err := Generate(encodePNG(t, src), DefaultMaxSide)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if mime != "image/png" || w != 128 || h != 256 {
+		t.Fatalf("got %s %dx%d, want image/png 128x256", mime, w, h)
+	}
+}
+
+func TestScaleNeverEnlarges(t *testing.T) {
+	out := Scale(image.NewNRGBA(image.Rect(0, 0, 40, 30)), DefaultMaxSide)
+	if out.Bounds().Dx() != 40 || out.Bounds().Dy() != 30 {
+		t.Fatalf("small image resized to %v", out.Bounds().Size())
+	}
+}
@@ -11,13 +11,21 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/google/uuid"
 )
 
 var (
 	sqlMarkerPattern  = regexp.MustCompile(`(?i)\b(select|insert|update|delete|with)\b`)
 	uuidMarkerPattern = regexp.MustCompile(`^--sql [0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	// sqlStatementPattern is stricter than sqlMarkerPattern because
+	// concatenations and format strings are common in ordinary messages.
+	sqlStatementPattern = regexp.MustCompile(`(?is)\b(select\s.+\sfrom|insert\s+into|update\s+\S+\s+set|delete\s+from)\b`)
 )
 
+// dynamicPlaceholder stands in for non-literal operands of a concatenation.
+const dynamicPlaceholder = "?"
+
 type violation struct {
 	file    string
 	name    string
@@ -25,7 +33,16 @@ type violation struct {
 	message string
 }
 
+// marker is one --sql <uuid> occurrence.
+type marker struct {
+	uuid string
+	file string
+	name string
+	line int
+}
+
 func main() {
+	fix := flag.Bool("fix", false, "insert a fresh --sql <uuid> marker into SQL literals that lack one")
 	flag.Parse()
 	targets := flag.Args()
 	if len(targets) == 0 {
@@ -33,6 +50,29 @@ func main() {
 	}
 
 	var violations []violation
+	var markers []marker
+	check := func(path string) error {
+		if *fix {
+			fixed, err := fixFile(path)
+			if err != nil {
+				return err
+			}
+			if fixed > 0 {
+				fmt.Fprintf(os.Stderr, "sqllint: inserted %d marker(s) in %s\n", fixed, path)
+			}
+		}
+		vs, err := lintFile(path)
+		if err != nil {
+			return err
+		}
+		violations = append(violations, vs...)
+		ms, err := collectMarkers(path)
+		if err != nil {
+			return err
+		}
+		markers = append(markers, ms...)
+		return nil
+	}
 
 	for _, target := range targets {
 		info, err := os.Stat(target)
@@ -46,7 +86,7 @@ func main() {
 					return err
 				}
 				if d.IsDir() {
-					if strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" || d.Name() == "node_modules" {
+					if strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" || d.Name() == "node_modules" || d.Name() == "testdata" {
 						return filepath.SkipDir
 					}
 					return nil
@@ -54,29 +94,23 @@ func main() {
 				if filepath.Ext(path) != ".go" {
 					return nil
 				}
-				vs, err := lintFile(path)
-				if err != nil {
-					return err
-				}
-				violations = append(violations, vs...)
-				return nil
+				return check(path)
 			})
 			if walkErr != nil {
 				fmt.Fprintf(os.Stderr, "sqllint: %v\n", walkErr)
 				os.Exit(1)
 			}
 		} else if filepath.Ext(target) == ".go" {
-			vs, err := lintFile(target)
-			if err != nil {
+			if err := check(target); err != nil {
 				fmt.Fprintf(os.Stderr, "sqllint: %v\n", err)
 				os.Exit(1)
 			}
-			violations = append(violations, vs...)
 		}
 	}
 
+	violations = append(violations, duplicateMarkers(markers)...)
 	if len(violations) > 0 {
-		fmt.Fprintln(os.Stderr, "sqllint: missing SQL audit markers")
+		fmt.Fprintln(os.Stderr, "sqllint: SQL audit marker violations")
 		for _, v := range violations {
 			fmt.Fprintf(os.Stderr, "  %s:%d %s (%s)\n", v.file, v.line, v.message, v.name)
 		}
@@ -91,38 +125,269 @@ func lintFile(path string) ([]violation, error) {
 		return nil, err
 	}
 	var violations []violation
+	report := func(pos token.Pos, name, message string) {
+		violations = append(violations, violation{
+			file:    path,
+			line:    fset.Position(pos).Line,
+			name:    name,
+			message: message,
+		})
+	}
 	ast.Inspect(file, func(n ast.Node) bool {
-		vs, ok := n.(*ast.ValueSpec)
+		switch node := n.(type) {
+		case *ast.ValueSpec:
+			for _, value := range node.Values {
+				bl, ok := value.(*ast.BasicLit)
+				if !ok || bl.Kind != token.STRING {
+					continue
+				}
+				if needsMarker(bl) {
+					report(bl.Pos(), joinNames(node.Names), "missing or invalid --sql <uuid> marker")
+				}
+			}
+		case *ast.BinaryExpr:
+			if node.Op != token.ADD {
+				return true
+			}
+			text, ok := concatText(node)
+			if !ok {
+				return true
+			}
+			if sqlStatementPattern.MatchString(text) && !uuidMarkerPattern.MatchString(firstLine(text)) {
+				report(node.Pos(), "concatenation", "SQL built by concatenation is missing a --sql <uuid> marker")
+			}
+			// Nested operands are part of the same string.
+			return false
+		case *ast.CallExpr:
+			name, ok := sprintfName(node)
+			if !ok || len(node.Args) == 0 {
+				return true
+			}
+			text, ok := concatText(node.Args[0])
+			if !ok {
+				return true
+			}
+			if sqlStatementPattern.MatchString(text) && !uuidMarkerPattern.MatchString(firstLine(text)) {
+				report(node.Pos(), name, "SQL built by "+name+" is missing a --sql <uuid> marker")
+			}
+			return false
+		}
+		return true
+	})
+	return violations, nil
+}
+
+// collectMarkers returns every valid marker found in the string literals of
+// path, so copy-pasted UUIDs can be detected across files.
+func collectMarkers(path string) ([]marker, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var markers []marker
+	named := make(map[*ast.BasicLit]string)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.ValueSpec:
+			for _, value := range node.Values {
+				if bl, ok := value.(*ast.BasicLit); ok {
+					named[bl] = joinNames(node.Names)
+				}
+			}
+		case *ast.BasicLit:
+			if node.Kind != token.STRING {
+				return true
+			}
+			raw, err := unquote(node.Value)
+			if err != nil {
+				return true
+			}
+			line := firstLine(raw)
+			if !uuidMarkerPattern.MatchString(line) {
+				return true
+			}
+			markers = append(markers, marker{
+				uuid: strings.TrimPrefix(line, "--sql "),
+				file: path,
+				name: named[node],
+				line: fset.Position(node.Pos()).Line,
+			})
+		}
+		return true
+	})
+	return markers, nil
+}
+
+// duplicateMarkers reports every marker whose UUID was already used, naming
+// the location of the first use.
+func duplicateMarkers(markers []marker) []violation {
+	first := make(map[string]marker, len(markers))
+	var violations []violation
+	for _, m := range markers {
+		prev, seen := first[m.uuid]
+		if !seen {
+			first[m.uuid] = m
+			continue
+		}
+		violations = append(violations, violation{
+			file:    m.file,
+			line:    m.line,
+			name:    m.name,
+			message: fmt.Sprintf("duplicate --sql marker %s, first used at %s:%d", m.uuid, prev.file, prev.line),
+		})
+	}
+	return violations
+}
+
+// needsMarker reports whether a string literal looks like SQL but does not
+// start with a valid marker.
+func needsMarker(bl *ast.BasicLit) bool {
+	raw, err := unquote(bl.Value)
+	if err != nil {
+		return false
+	}
+	return sqlMarkerPattern.MatchString(raw) && !uuidMarkerPattern.MatchString(firstLine(raw))
+}
+
+// fixFile rewrites path in place, giving every SQL constant that lacks a
+// valid marker a freshly generated one, and returns how many literals were
+// changed. Literals that already carry a valid marker are left alone, so
+// running it twice is a no-op. Dynamically built SQL is not rewritten.
+func fixFile(path string) (int, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return 0, err
+	}
+	type edit struct {
+		start, end  int
+		replacement string
+	}
+	var edits []edit
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
 		if !ok {
 			return true
 		}
-		for _, value := range vs.Values {
+		for _, value := range spec.Values {
 			bl, ok := value.(*ast.BasicLit)
-			if !ok || bl.Kind != token.STRING {
-				continue
-			}
-			raw, err := unquote(bl.Value)
-			if err != nil {
+			if !ok || bl.Kind != token.STRING || !needsMarker(bl) {
 				continue
 			}
-			if !sqlMarkerPattern.MatchString(raw) {
+			replacement, ok := withMarker(bl.Value, "--sql "+uuid.NewString())
+			if !ok {
 				continue
 			}
-			marker := firstLine(raw)
-			if !uuidMarkerPattern.MatchString(marker) {
-				pos := fset.Position(bl.Pos())
-				v := violation{
-					file:    path,
-					line:    pos.Line,
-					name:    joinNames(vs.Names),
-					message: "missing or invalid --sql <uuid> marker",
-				}
-				violations = append(violations, v)
-			}
+			start := fset.Position(bl.Pos()).Offset
+			edits = append(edits, edit{start: start, end: start + len(bl.Value), replacement: replacement})
 		}
 		return true
 	})
-	return violations, nil
+	if len(edits) == 0 {
+		return 0, nil
+	}
+	var out strings.Builder
+	last := 0
+	for _, e := range edits {
+		out.WriteString(string(src[last:e.start]))
+		out.WriteString(e.replacement)
+		last = e.end
+	}
+	out.WriteString(string(src[last:]))
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, []byte(out.String()), info.Mode().Perm()); err != nil {
+		return 0, err
+	}
+	return len(edits), nil
+}
+
+// withMarker returns the literal source with marker as its first non-blank
+// line. Leading whitespace is kept and repeated after the marker so the SQL
+// keeps its indentation; an existing malformed --sql line is replaced.
+func withMarker(literal, marker string) (string, bool) {
+	raw, err := unquote(literal)
+	if err != nil {
+		return "", false
+	}
+	body := strings.TrimLeft(raw, "\n\r \t")
+	lead := raw[:len(raw)-len(body)]
+	indent := lead[strings.LastIndexAny(lead, "\n\r")+1:]
+	fixed := lead + marker + "\n" + indent + body
+	if strings.HasPrefix(body, "--sql") {
+		rest := ""
+		if idx := strings.Index(body, "\n"); idx >= 0 {
+			rest = body[idx+1:]
+		}
+		fixed = lead + marker + "\n" + rest
+	}
+	if literal[0] == '`' {
+		return "`" + fixed + "`", true
+	}
+	return strconv.Quote(fixed), true
+}
+
+// concatText flattens a string literal or a + chain into its text, with
+// non-literal operands replaced by a placeholder. It reports false when the
+// expression contains no string literal.
+func concatText(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		raw, err := unquote(e.Value)
+		if err != nil {
+			return "", false
+		}
+		return raw, true
+	case *ast.ParenExpr:
+		return concatText(e.X)
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		left, leftOK := concatText(e.X)
+		right, rightOK := concatText(e.Y)
+		if !leftOK && !rightOK {
+			return "", false
+		}
+		if !leftOK {
+			left = dynamicPlaceholder
+		}
+		if !rightOK {
+			right = dynamicPlaceholder
+		}
+		return left + right, true
+	default:
+		return "", false
+	}
+}
+
+// sprintfName returns the qualified name of fmt.Sprint-style calls, whose
+// first argument is treated as the SQL text.
+func sprintfName(call *ast.CallExpr) (string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || pkg.Name != "fmt" {
+		return "", false
+	}
+	switch sel.Sel.Name {
+	case "Sprintf", "Sprint", "Sprintln":
+		return "fmt." + sel.Sel.Name, true
+	default:
+		return "", false
+	}
 }
 
 func firstLine(s string) string {
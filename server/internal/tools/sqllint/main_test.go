@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLintFileFlagsDynamicSQL(t *testing.T) {
+	cases := []struct {
+		file string
+		line int
+		name string
+	}{
+		{file: "concat.go", line: 5, name: "concatenation"},
+		{file: "sprintf.go", line: 7, name: "fmt.Sprintf"},
+	}
+	for _, tc := range cases {
+		violations, err := lintFile(filepath.Join("testdata", tc.file))
+		if err != nil {
+			t.Fatalf("%s: lint: %v", tc.file, err)
+		}
+		if len(violations) != 1 {
+			t.Fatalf("%s: violations = %+v, want exactly one", tc.file, violations)
+		}
+		got := violations[0]
+		if got.line != tc.line || got.name != tc.name {
+			t.Fatalf("%s: violation = %+v, want %s at line %d", tc.file, got, tc.name, tc.line)
+		}
+		if !strings.Contains(got.message, "--sql <uuid>") {
+			t.Fatalf("%s: message = %q", tc.file, got.message)
+		}
+	}
+}
+
+func TestFixFileInsertsMarkers(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "unmarked.go"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "unmarked.go")
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	fixed, err := fixFile(path)
+	if err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	if fixed != 3 {
+		t.Fatalf("fixed = %d, want 3", fixed)
+	}
+	violations, err := lintFile(path)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Fatalf("violations after fix = %+v", violations)
+	}
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read fixed file: %v", err)
+	}
+	text := string(out)
+	if !regexp.MustCompile("(?m)^\t--sql [0-9a-f-]{36}\n\tselect id, name$").MatchString(text) {
+		t.Fatalf("indented literal not fixed in place:\n%s", text)
+	}
+	if strings.Contains(text, "not-a-uuid") {
+		t.Fatalf("malformed marker was kept:\n%s", text)
+	}
+	if !strings.Contains(text, "--sql 2b6f0d9e-8a41-4c7b-9e35-f1d0a7c4b862\n") {
+		t.Fatalf("valid marker was rewritten:\n%s", text)
+	}
+
+	again, err := fixFile(path)
+	if err != nil {
+		t.Fatalf("second fix: %v", err)
+	}
+	rerun, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read refixed file: %v", err)
+	}
+	if again != 0 || string(rerun) != text {
+		t.Fatalf("second fix changed %d literals; fix is not idempotent", again)
+	}
+}
+
+func TestDuplicateMarkersAcrossFiles(t *testing.T) {
+	var markers []marker
+	for _, name := range []string{"dup_a.go", "dup_b.go"} {
+		ms, err := collectMarkers(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("%s: collect: %v", name, err)
+		}
+		markers = append(markers, ms...)
+	}
+	if len(markers) != 3 {
+		t.Fatalf("markers = %+v, want 3", markers)
+	}
+
+	violations := duplicateMarkers(markers)
+	if len(violations) != 1 {
+		t.Fatalf("violations = %+v, want exactly one", violations)
+	}
+	got := violations[0]
+	if got.file != filepath.Join("testdata", "dup_b.go") || got.line != 6 || got.name != "QListGadgetsCopy" {
+		t.Fatalf("violation = %+v, want QListGadgetsCopy at dup_b.go:6", got)
+	}
+	first := filepath.Join("testdata", "dup_a.go") + ":3"
+	if !strings.Contains(got.message, "7e3a9c52-1f6d-4b08-a2c4-58d0e6b91f37") || !strings.Contains(got.message, first) {
+		t.Fatalf("message = %q, want uuid and %s", got.message, first)
+	}
+}
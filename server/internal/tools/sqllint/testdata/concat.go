@@ -0,0 +1,9 @@
+package fixtures
+
+func concatQueries(table, userID string) []string {
+	return []string{
+		"select id from " + table + " where user_id = '" + userID + "'",
+		"--sql 4f1c2a8e-5b7d-4e36-9a0b-3c8d1e2f7a64\nselect id from " + table,
+		"failed with status " + userID,
+	}
+}
@@ -0,0 +1,4 @@
+package fixtures
+
+const QListGadgets = `--sql 7e3a9c52-1f6d-4b08-a2c4-58d0e6b91f37
+select id, name from gadgets order by name`
@@ -0,0 +1,7 @@
+package fixtures
+
+const QCountGadgets = `--sql 0c84f2d1-6b3e-4a97-8d25-e1f7a90c4b63
+select count(*) from gadgets`
+
+const QListGadgetsCopy = `--sql 7e3a9c52-1f6d-4b08-a2c4-58d0e6b91f37
+select id, name from gadgets order by created_at`
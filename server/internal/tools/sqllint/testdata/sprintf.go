@@ -0,0 +1,11 @@
+package fixtures
+
+import "fmt"
+
+func sprintfQueries(table string, limit int) []string {
+	return []string{
+		fmt.Sprintf("delete from %s where created_at < now() - interval '%d days'", table, limit),
+		fmt.Sprintf("--sql 9d2e7b41-0c5a-4f83-b6e1-2a7c9f4d0e58\nupdate %s set deleted_at = now()", table),
+		fmt.Sprintf("select limit %d exceeded", limit),
+	}
+}
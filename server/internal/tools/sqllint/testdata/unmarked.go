@@ -0,0 +1,14 @@
+package fixtures
+
+const QListWidgets = `
+	select id, name
+	from widgets
+	order by name`
+
+const QDeleteWidget = "delete from widgets where id = $1"
+
+const QCountWidgets = `--sql not-a-uuid
+select count(*) from widgets`
+
+const QMarkedWidget = `--sql 2b6f0d9e-8a41-4c7b-9e35-f1d0a7c4b862
+select id from widgets where id = $1`
@@ -0,0 +1,136 @@
+// Package watermark composites brand text onto generated images so the mark
+// does not depend on the model honouring the prompt.
+package watermark
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"server/pkg/bitmapfont"
+)
+
+// Position anchors the watermark within the image.
+type Position string
+
+const (
+	TopLeft     Position = "top-left"
+	TopRight    Position = "top-right"
+	BottomLeft  Position = "bottom-left"
+	BottomRight Position = "bottom-right"
+	Center      Position = "center"
+)
+
+// DefaultOpacity is applied when Options.Opacity is zero.
+const DefaultOpacity = 0.6
+
+// ParsePosition normalizes a position string. Underscores and spaces are
+// accepted in place of hyphens.
+func ParsePosition(raw string) (Position, error) {
+	normalized := strings.ToLower(strings.TrimSpace(raw))
+	normalized = strings.NewReplacer("_", "-", " ", "-").Replace(normalized)
+	switch Position(normalized) {
+	case TopLeft, TopRight, BottomLeft, BottomRight, Center:
+		return Position(normalized), nil
+	default:
+		return "", fmt.Errorf("watermark position must be one of top-left, top-right, bottom-left, bottom-right, center")
+	}
+}
+
+// Options configures a watermark. Zero offsets select a margin proportional to
+// the text size; for Center the offsets shift the text from the middle.
+type Options struct {
+	Text     string
+	Position Position
+	Opacity  float64
+	OffsetX  int
+	OffsetY  int
+}
+
+// Draw returns a copy of src with the watermark text composited on top.
+func Draw(src image.Image, opts Options) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+
+	text := strings.TrimSpace(opts.Text)
+	if text == "" {
+		return dst
+	}
+	short := bounds.Dx()
+	if bounds.Dy() < short {
+		short = bounds.Dy()
+	}
+	scale := short / (bitmapfont.GlyphHeight * 25)
+	if scale < 1 {
+		scale = 1
+	}
+	textW, textH := bitmapfont.Measure(text, scale)
+	origin := anchor(opts, dst.Bounds().Size(), image.Pt(textW, textH), scale)
+
+	opacity := opts.Opacity
+	if opacity <= 0 {
+		opacity = DefaultOpacity
+	}
+	if opacity > 1 {
+		opacity = 1
+	}
+	alpha := uint8(opacity*255 + 0.5)
+	shadow := color.NRGBA{A: alpha / 2}
+	fill := color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: alpha}
+	bitmapfont.Draw(dst, origin.Add(image.Pt(scale, scale)), text, scale, shadow)
+	bitmapfont.Draw(dst, origin, text, scale, fill)
+	return dst
+}
+
+func anchor(opts Options, canvas, text image.Point, scale int) image.Point {
+	margin := 4 * scale
+	dx, dy := opts.OffsetX, opts.OffsetY
+	if opts.Position != Center {
+		if dx == 0 {
+			dx = margin
+		}
+		if dy == 0 {
+			dy = margin
+		}
+	}
+	switch opts.Position {
+	case TopLeft:
+		return image.Pt(dx, dy)
+	case TopRight:
+		return image.Pt(canvas.X-text.X-dx, dy)
+	case BottomLeft:
+		return image.Pt(dx, canvas.Y-text.Y-dy)
+	case Center:
+		return image.Pt((canvas.X-text.X)/2+dx, (canvas.Y-text.Y)/2+dy)
+	default:
+		return image.Pt(canvas.X-text.X-dx, canvas.Y-text.Y-dy)
+	}
+}
+
+// Apply decodes data, draws the watermark and re-encodes it in the original
+// format. JPEG input stays JPEG; everything else is written as PNG.
+func Apply(data []byte, opts Options) ([]byte, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("watermark: decode image: %w", err)
+	}
+	marked := Draw(img, opts)
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&buf, marked, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, "", fmt.Errorf("watermark: encode jpeg: %w", err)
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, marked); err != nil {
+		return nil, "", fmt.Errorf("watermark: encode png: %w", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
@@ -0,0 +1,94 @@
+package watermark
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func solidPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 20, G: 60, B: 120, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestApplyChangesImage(t *testing.T) {
+	original := solidPNG(t, 200, 120)
+	marked, mime, err := Apply(original, Options{Text: "Warung Bu Sri", Position: BottomRight})
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if mime != "image/png" {
+		t.Fatalf("mime = %q, want image/png", mime)
+	}
+	if bytes.Equal(marked, original) {
+		t.Fatalf("watermarked image should differ from the original")
+	}
+	img, err := png.Decode(bytes.NewReader(marked))
+	if err != nil {
+		t.Fatalf("decode watermarked image: %v", err)
+	}
+	if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 120 {
+		t.Fatalf("dimensions changed: %v", img.Bounds())
+	}
+}
+
+func TestDrawHonoursPosition(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 200, 200))
+	tests := []struct {
+		position Position
+		inside   image.Rectangle
+	}{
+		{TopLeft, image.Rect(0, 0, 100, 100)},
+		{TopRight, image.Rect(100, 0, 200, 100)},
+		{BottomLeft, image.Rect(0, 100, 100, 200)},
+		{BottomRight, image.Rect(100, 100, 200, 200)},
+		{Center, image.Rect(50, 50, 150, 150)},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.position), func(t *testing.T) {
+			out := Draw(src, Options{Text: "AB", Position: tc.position, Opacity: 1})
+			for y := 0; y < 200; y++ {
+				for x := 0; x < 200; x++ {
+					if out.NRGBAAt(x, y).A == 0 {
+						continue
+					}
+					if !image.Pt(x, y).In(tc.inside) {
+						t.Fatalf("watermark pixel at %d,%d outside %v", x, y, tc.inside)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	valid := map[string]Position{
+		"top-left":       TopLeft,
+		" Bottom_Right ": BottomRight,
+		"top right":      TopRight,
+		"CENTER":         Center,
+	}
+	for in, want := range valid {
+		got, err := ParsePosition(in)
+		if err != nil || got != want {
+			t.Fatalf("ParsePosition(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "middle", "left", "bottom-center"} {
+		if _, err := ParsePosition(in); err == nil {
+			t.Fatalf("ParsePosition(%q) expected error", in)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"server/internal/netguard"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultBackoff     = 500 * time.Millisecond
+	defaultTimeout     = 10 * time.Second
+	maxRedirects       = 5
+)
+
+// Payload is the JSON body posted to a job's callback URL once it reaches a
+// terminal state.
+type Payload struct {
+	JobID     string   `json:"job_id"`
+	Status    string   `json:"status"`
+	Error     string   `json:"error,omitempty"`
+	AssetURLs []string `json:"asset_urls"`
+}
+
+// Notifier delivers job completion callbacks, retrying non-2xx responses.
+type Notifier struct {
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// NewNotifier returns a Notifier with the default retry policy. Callback URLs
+// come from users, so deliveries go through a netguard client: targets and
+// redirects must resolve to public addresses unless their host is in
+// trusted.
+func NewNotifier(trusted map[string]struct{}) *Notifier {
+	return &Notifier{Client: newClient(trusted), MaxAttempts: defaultMaxAttempts, Backoff: defaultBackoff}
+}
+
+func newClient(trusted map[string]struct{}) *http.Client {
+	return netguard.NewClient(defaultTimeout, maxRedirects, trusted, nil)
+}
+
+// Deliver posts payload to target, retrying with a linear backoff until a 2xx
+// response is received or the attempt budget is exhausted.
+func (n *Notifier) Deliver(ctx context.Context, target string, payload Payload) error {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return errors.New("webhook: target url is required")
+	}
+	if payload.AssetURLs == nil {
+		payload.AssetURLs = []string{}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("webhook: encode payload: %w", err)
+	}
+	attempts := n.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultMaxAttempts
+	}
+	client := n.Client
+	if client == nil {
+		client = newClient(nil)
+	}
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 && n.Backoff > 0 {
+			timer := time.NewTimer(n.Backoff * time.Duration(attempt-1))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		lastErr = n.post(ctx, client, target, body)
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook: delivery failed after %d attempts: %w", attempts, lastErr)
+}
+
+func (n *Notifier) post(ctx context.Context, client *http.Client, target string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("http %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type captureServer struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	payloads []Payload
+}
+
+func (c *captureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	var p Payload
+	_ = json.NewDecoder(r.Body).Decode(&p)
+	c.payloads = append(c.payloads, p)
+	if c.calls <= c.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestDeliverRetriesUntilSuccess(t *testing.T) {
+	capture := &captureServer{failures: 2}
+	srv := httptest.NewServer(capture)
+	defer srv.Close()
+
+	n := &Notifier{Client: srv.Client(), MaxAttempts: 3}
+	err := n.Deliver(context.Background(), srv.URL, Payload{JobID: "job-1", Status: "SUCCEEDED", AssetURLs: []string{"https://cdn.example.com/a.png"}})
+	if err != nil {
+		t.Fatalf("Deliver error: %v", err)
+	}
+	if capture.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", capture.calls)
+	}
+	last := capture.payloads[len(capture.payloads)-1]
+	if last.JobID != "job-1" || last.Status != "SUCCEEDED" || len(last.AssetURLs) != 1 {
+		t.Fatalf("unexpected payload: %+v", last)
+	}
+}
+
+func TestDeliverGivesUpAfterMaxAttempts(t *testing.T) {
+	capture := &captureServer{failures: 10}
+	srv := httptest.NewServer(capture)
+	defer srv.Close()
+
+	n := &Notifier{Client: srv.Client(), MaxAttempts: 3}
+	if err := n.Deliver(context.Background(), srv.URL, Payload{JobID: "job-1", Status: "FAILED"}); err == nil {
+		t.Fatal("expected delivery error")
+	}
+	if capture.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", capture.calls)
+	}
+}
+
+func TestDeliverRequiresTarget(t *testing.T) {
+	if err := NewNotifier(nil).Deliver(context.Background(), " ", Payload{}); err == nil {
+		t.Fatal("expected error for empty target")
+	}
+}
+
+func TestNewNotifierRefusesPrivateTargets(t *testing.T) {
+	capture := &captureServer{}
+	srv := httptest.NewServer(capture)
+	defer srv.Close()
+
+	n := NewNotifier(nil)
+	n.Backoff = 0
+	err := n.Deliver(context.Background(), srv.URL, Payload{JobID: "job-1", Status: "SUCCEEDED"})
+	if err == nil || !strings.Contains(err.Error(), "non-public address 127.0.0.1") {
+		t.Fatalf("err = %v, want loopback target refused", err)
+	}
+	if capture.calls != 0 {
+		t.Fatalf("loopback receiver got %d calls, want 0", capture.calls)
+	}
+}
+
+func TestNewNotifierBlocksPrivateRedirect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusTemporaryRedirect)
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(map[string]struct{}{"127.0.0.1": {}})
+	n.Backoff = 0
+	err := n.Deliver(context.Background(), srv.URL, Payload{JobID: "job-1", Status: "SUCCEEDED"})
+	if err == nil || !strings.Contains(err.Error(), "169.254.169.254 blocked") {
+		t.Fatalf("err = %v, want redirect to metadata address blocked", err)
+	}
+}
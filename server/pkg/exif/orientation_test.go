@@ -0,0 +1,98 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// withOrientation splices an APP1 EXIF segment carrying orientation into a
+// JPEG right after the SOI marker.
+func withOrientation(t *testing.T, jpg []byte, order binary.ByteOrder, orientation uint16) []byte {
+	t.Helper()
+	var tiff bytes.Buffer
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	_ = binary.Write(&tiff, order, uint16(42))
+	_ = binary.Write(&tiff, order, uint32(8))
+	_ = binary.Write(&tiff, order, uint16(1))
+	_ = binary.Write(&tiff, order, uint16(orientationTag))
+	_ = binary.Write(&tiff, order, uint16(3))
+	_ = binary.Write(&tiff, order, uint32(1))
+	_ = binary.Write(&tiff, order, orientation)
+	_ = binary.Write(&tiff, order, uint16(0))
+	_ = binary.Write(&tiff, order, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestOrientationReadsBothByteOrders(t *testing.T) {
+	plain := encodeJPEG(t, 4, 2)
+	if got := Orientation(plain); got != 1 {
+		t.Fatalf("plain jpeg orientation = %d, want 1", got)
+	}
+	if got := Orientation(withOrientation(t, plain, binary.LittleEndian, 6)); got != 6 {
+		t.Fatalf("little endian orientation = %d, want 6", got)
+	}
+	if got := Orientation(withOrientation(t, plain, binary.BigEndian, 8)); got != 8 {
+		t.Fatalf("big endian orientation = %d, want 8", got)
+	}
+	if got := Orientation(withOrientation(t, plain, binary.BigEndian, 42)); got != 1 {
+		t.Fatalf("invalid orientation = %d, want 1", got)
+	}
+	if got := Orientation([]byte("\x89PNG\r\n\x1a\n")); got != 1 {
+		t.Fatalf("png orientation = %d, want 1", got)
+	}
+}
+
+func TestApplyRotatesPixels(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	marker := color.NRGBA{R: 255, A: 255}
+	src.SetNRGBA(0, 0, marker)
+
+	cases := []struct {
+		orientation int
+		w, h        int
+		x, y        int
+	}{
+		{orientation: 1, w: 3, h: 2, x: 0, y: 0},
+		{orientation: 2, w: 3, h: 2, x: 2, y: 0},
+		{orientation: 3, w: 3, h: 2, x: 2, y: 1},
+		{orientation: 4, w: 3, h: 2, x: 0, y: 1},
+		{orientation: 5, w: 2, h: 3, x: 0, y: 0},
+		{orientation: 6, w: 2, h: 3, x: 1, y: 0},
+		{orientation: 7, w: 2, h: 3, x: 1, y: 2},
+		{orientation: 8, w: 2, h: 3, x: 0, y: 2},
+	}
+	for _, tc := range cases {
+		out := Apply(src, tc.orientation)
+		if out.Bounds().Dx() != tc.w || out.Bounds().Dy() != tc.h {
+			t.Fatalf("orientation %d: size = %v, want %dx%d", tc.orientation, out.Bounds().Size(), tc.w, tc.h)
+		}
+		if got := out.NRGBAAt(tc.x, tc.y); got != marker {
+			t.Fatalf("orientation %d: marker not at (%d,%d)", tc.orientation, tc.x, tc.y)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package webp
+
+import "sort"
+
+// codeLengthCodeOrder is the order in which code length code lengths are
+// stored in the bitstream.
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// huffmanCode is a canonical prefix code over one alphabet. bits holds each
+// symbol's code already bit-reversed for the LSB-first stream; a code with a
+// single used symbol is written with zero bits, as decoders expect.
+type huffmanCode struct {
+	lengths []int
+	bits    []uint32
+	used    []int
+}
+
+// newHuffmanCode builds a length-limited canonical code from freq.
+func newHuffmanCode(freq []int, maxLength int) *huffmanCode {
+	h := &huffmanCode{lengths: huffmanLengths(freq, maxLength), bits: make([]uint32, len(freq))}
+	for symbol, f := range freq {
+		if f > 0 {
+			h.used = append(h.used, symbol)
+		}
+	}
+	if len(h.used) < 2 {
+		return h
+	}
+	var count [maxHuffmanCodeLength + 1]int
+	for _, l := range h.lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [maxHuffmanCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= maxHuffmanCodeLength; l++ {
+		code = (code + uint32(count[l-1])) << 1
+		next[l] = code
+	}
+	for symbol, l := range h.lengths {
+		if l > 0 {
+			h.bits[symbol] = reverseBits(next[l], l)
+			next[l]++
+		}
+	}
+	return h
+}
+
+// simple reports whether the code fits the simple form: at most two symbols,
+// all below 256.
+func (h *huffmanCode) simple() bool {
+	if len(h.used) > 2 {
+		return false
+	}
+	for _, symbol := range h.used {
+		if symbol >= literalSymbols {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *huffmanCode) write(bw *bitWriter, symbol int) {
+	if len(h.used) < 2 {
+		return
+	}
+	bw.writeBits(h.bits[symbol], uint(h.lengths[symbol]))
+}
+
+// writeHuffmanCode stores h in the bitstream. Codes with one or two small
+// symbols use the simple form; the rest send their code lengths run-length
+// encoded with a second prefix code.
+func writeHuffmanCode(bw *bitWriter, h *huffmanCode) {
+	if h.simple() {
+		symbols := h.used
+		if len(symbols) == 0 {
+			symbols = []int{0}
+		}
+		bw.writeBits(1, 1) // simple code
+		bw.writeBits(uint32(len(symbols)-1), 1)
+		if symbols[0] <= 1 {
+			bw.writeBits(0, 1)
+			bw.writeBits(uint32(symbols[0]), 1)
+		} else {
+			bw.writeBits(1, 1)
+			bw.writeBits(uint32(symbols[0]), 8)
+		}
+		if len(symbols) == 2 {
+			bw.writeBits(uint32(symbols[1]), 8)
+			// Simple codes give the first listed symbol code 0 and the
+			// second code 1.
+			h.lengths[symbols[0]], h.bits[symbols[0]] = 1, 0
+			h.lengths[symbols[1]], h.bits[symbols[1]] = 1, 1
+		}
+		return
+	}
+
+	lengths := h.lengths
+	if len(h.used) == 1 {
+		// A lone symbol still needs a non-zero length to be declared.
+		lengths = append([]int(nil), h.lengths...)
+		lengths[h.used[0]] = 1
+	}
+	tokens := codeLengthTokens(lengths)
+	var freq [len(codeLengthCodeOrder)]int
+	for _, t := range tokens {
+		freq[t.symbol]++
+	}
+	lengthCode := newHuffmanCode(freq[:], maxCodeLengthCodeBits)
+	declared := append([]int(nil), lengthCode.lengths...)
+	if len(lengthCode.used) == 1 {
+		declared[lengthCode.used[0]] = 1
+	}
+	count := 4
+	for i, symbol := range codeLengthCodeOrder {
+		if declared[symbol] > 0 {
+			count = max(count, i+1)
+		}
+	}
+	bw.writeBits(0, 1) // normal code
+	bw.writeBits(uint32(count-4), 4)
+	for _, symbol := range codeLengthCodeOrder[:count] {
+		bw.writeBits(uint32(declared[symbol]), 3)
+	}
+	bw.writeBits(0, 1) // code lengths cover the whole alphabet
+	for _, t := range tokens {
+		lengthCode.write(bw, t.symbol)
+		bw.writeBits(uint32(t.extra), uint(t.extraBits))
+	}
+}
+
+type codeLengthToken struct {
+	symbol, extra, extraBits int
+}
+
+// codeLengthTokens run-length encodes code lengths with symbols 16 (repeat
+// the previous non-zero length 3-6 times), 17 (3-10 zeros) and 18 (11-138
+// zeros).
+func codeLengthTokens(lengths []int) []codeLengthToken {
+	var tokens []codeLengthToken
+	prev := 8
+	for i := 0; i < len(lengths); {
+		value := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == value {
+			run++
+		}
+		i += run
+		if value == 0 {
+			for run >= 11 {
+				r := min(run, 138)
+				tokens = append(tokens, codeLengthToken{symbol: 18, extra: r - 11, extraBits: 7})
+				run -= r
+			}
+			if run >= 3 {
+				tokens = append(tokens, codeLengthToken{symbol: 17, extra: run - 3, extraBits: 3})
+				run = 0
+			}
+		} else {
+			if value != prev {
+				tokens = append(tokens, codeLengthToken{symbol: value})
+				prev = value
+				run--
+			}
+			for run >= 3 {
+				r := min(run, 6)
+				tokens = append(tokens, codeLengthToken{symbol: 16, extra: r - 3, extraBits: 2})
+				run -= r
+			}
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, codeLengthToken{symbol: value})
+		}
+	}
+	return tokens
+}
+
+// huffmanLengths returns Huffman code lengths for freq no longer than
+// maxLength. When the optimal tree is too deep the counts are halved, which
+// flattens the tree, until it fits.
+func huffmanLengths(freq []int, maxLength int) []int {
+	counts := append([]int(nil), freq...)
+	for {
+		lengths, depth := buildLengths(counts)
+		if depth <= maxLength {
+			return lengths
+		}
+		for i, c := range counts {
+			if c > 0 {
+				counts[i] = c>>1 | 1
+			}
+		}
+	}
+}
+
+func buildLengths(freq []int) ([]int, int) {
+	type node struct {
+		weight int
+		parent int
+	}
+	lengths := make([]int, len(freq))
+	var nodes []node
+	var leaves []int
+	for symbol, f := range freq {
+		if f > 0 {
+			leaves = append(leaves, symbol)
+		}
+	}
+	if len(leaves) < 2 {
+		for _, symbol := range leaves {
+			lengths[symbol] = 1
+		}
+		return lengths, len(leaves)
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return freq[leaves[i]] < freq[leaves[j]] })
+	for _, symbol := range leaves {
+		nodes = append(nodes, node{weight: freq[symbol], parent: -1})
+	}
+
+	// Two-queue construction: leaves are consumed in weight order and
+	// internal nodes are created in non-decreasing weight order.
+	leaf, internal := 0, len(leaves)
+	pick := func() int {
+		if leaf < len(leaves) && (internal >= len(nodes) || nodes[leaf].weight <= nodes[internal].weight) {
+			leaf++
+			return leaf - 1
+		}
+		internal++
+		return internal - 1
+	}
+	for len(nodes) < 2*len(leaves)-1 {
+		a, b := pick(), pick()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, parent: -1})
+		nodes[a].parent = len(nodes) - 1
+		nodes[b].parent = len(nodes) - 1
+	}
+
+	depths := make([]int, len(nodes))
+	maxDepth := 0
+	for i := len(nodes) - 2; i >= 0; i-- {
+		depths[i] = depths[nodes[i].parent] + 1
+		if i < len(leaves) {
+			maxDepth = max(maxDepth, depths[i])
+		}
+	}
+	for i, symbol := range leaves {
+		lengths[symbol] = depths[i]
+	}
+	return lengths, maxDepth
+}
+
+func reverseBits(v uint32, n int) uint32 {
+	var out uint32
+	for i := 0; i < n; i++ {
+		out = out<<1 | v&1
+		v >>= 1
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package webp
+
+const (
+	minMatchLength = 3
+	maxMatchLength = 4096
+	// maxMatchDistance keeps distance codes inside the 40 distance prefix
+	// symbols while still reaching many rows back on wide images.
+	maxMatchDistance = 1 << 18
+	hashBits         = 16
+	maxChainLength   = 32
+)
+
+// backwardRef is either a literal pixel (length 0) or a copy of length pixels
+// from distance pixels back.
+type backwardRef struct {
+	length   int
+	distance int
+}
+
+// backwardReferences greedily replaces repeated pixel runs with LZ77 copies.
+// The left and top neighbours are tried before the hash chain because flat
+// areas and predictor residuals repeat there most often.
+func backwardReferences(argb []uint32, width int) []backwardRef {
+	n := len(argb)
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, n)
+	insert := func(i int) {
+		if i+1 >= n {
+			return
+		}
+		h := pairHash(argb[i], argb[i+1])
+		prev[i] = head[h]
+		head[h] = int32(i)
+	}
+
+	refs := make([]backwardRef, 0, n/2)
+	for i := 0; i < n; {
+		limit := min(maxMatchLength, n-i)
+		bestLen, bestDist := 0, 0
+		try := func(dist int) {
+			if dist <= 0 || dist > i || dist > maxMatchDistance {
+				return
+			}
+			if l := matchLength(argb, i-dist, i, limit); l > bestLen {
+				bestLen, bestDist = l, dist
+			}
+		}
+		if limit >= minMatchLength {
+			try(1)
+			try(width)
+			if i+1 < n {
+				cand := head[pairHash(argb[i], argb[i+1])]
+				for chain := 0; cand >= 0 && chain < maxChainLength && bestLen < limit; chain++ {
+					try(i - int(cand))
+					cand = prev[cand]
+				}
+			}
+		}
+		if bestLen < minMatchLength {
+			refs = append(refs, backwardRef{})
+			insert(i)
+			i++
+			continue
+		}
+		refs = append(refs, backwardRef{length: bestLen, distance: bestDist})
+		for j := i; j < i+bestLen; j++ {
+			insert(j)
+		}
+		i += bestLen
+	}
+	return refs
+}
+
+func matchLength(argb []uint32, from, to, limit int) int {
+	l := 0
+	for l < limit && argb[from+l] == argb[to+l] {
+		l++
+	}
+	return l
+}
+
+func pairHash(a, b uint32) uint32 {
+	return (a*0x1e35a7bd ^ b*0x9e3779b1) >> (32 - hashBits)
+}
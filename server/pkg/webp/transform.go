@@ -0,0 +1,180 @@
+package webp
+
+// subtractGreen removes the green channel from red and blue, which leaves
+// mostly small values for natural images.
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		g := p >> 8 & 0xff
+		r := (p>>16 - g) & 0xff
+		b := (p - g) & 0xff
+		argb[i] = p&0xff00ff00 | r<<16 | b
+	}
+}
+
+// choosePredictors picks, for every tile, the predictor mode whose residuals
+// have the smallest magnitude. Modes are returned as a sub-image with the
+// mode in the green channel, as the predictor transform stores them.
+func choosePredictors(argb []uint32, width, height int) ([]uint32, int) {
+	tile := 1 << predictorBits
+	tilesX := (width + tile - 1) / tile
+	tilesY := (height + tile - 1) / tile
+	modes := make([]uint32, tilesX*tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			best, bestCost := 0, -1
+			for mode := 0; mode < numPredictorModes; mode++ {
+				cost := 0
+				for y := ty * tile; y < min((ty+1)*tile, height); y++ {
+					for x := tx * tile; x < min((tx+1)*tile, width); x++ {
+						i := y*width + x
+						cost += residualCost(subPixels(argb[i], predict(argb, width, x, y, mode)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[ty*tilesX+tx] = uint32(best) << 8
+		}
+	}
+	return modes, tilesX
+}
+
+// predictorResiduals returns the per-channel difference between each pixel
+// and its prediction.
+func predictorResiduals(argb []uint32, width, height int, modes []uint32, tilesX int) []uint32 {
+	out := make([]uint32, len(argb))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			mode := int(modes[(y>>predictorBits)*tilesX+x>>predictorBits] >> 8 & 0xf)
+			i := y*width + x
+			out[i] = subPixels(argb[i], predict(argb, width, x, y, mode))
+		}
+	}
+	return out
+}
+
+// predict returns the prediction for the pixel at (x, y). The first pixel is
+// predicted as opaque black, the rest of the top row from the left and the
+// left column from above, whatever the tile's mode.
+func predict(argb []uint32, width, x, y, mode int) uint32 {
+	i := y*width + x
+	switch {
+	case x == 0 && y == 0:
+		return opaqueBlack
+	case y == 0:
+		return argb[i-1]
+	case x == 0:
+		return argb[i-width]
+	}
+	// For the rightmost column the top-right pixel wraps to the first pixel
+	// of the current row, which is what argb[i-width+1] addresses.
+	l, t, tl, tr := argb[i-1], argb[i-width], argb[i-width-1], argb[i-width+1]
+	switch mode {
+	case 0:
+		return opaqueBlack
+	case 1:
+		return l
+	case 2:
+		return t
+	case 3:
+		return tr
+	case 4:
+		return tl
+	case 5:
+		return average2(average2(l, tr), t)
+	case 6:
+		return average2(l, tl)
+	case 7:
+		return average2(l, t)
+	case 8:
+		return average2(tl, t)
+	case 9:
+		return average2(t, tr)
+	case 10:
+		return average2(average2(l, tl), average2(t, tr))
+	case 11:
+		return selectPixel(l, t, tl)
+	case 12:
+		return clampAddSubtractFull(l, t, tl)
+	default:
+		return clampAddSubtractHalf(average2(l, t), tl)
+	}
+}
+
+// selectPixel chooses L or T, whichever is closer to the gradient estimate
+// L + T - TL.
+func selectPixel(l, t, tl uint32) uint32 {
+	toL, toT := 0, 0
+	for c := 0; c < 4; c++ {
+		toL += abs(channel(tl, c) - channel(t, c))
+		toT += abs(channel(tl, c) - channel(l, c))
+	}
+	if toL < toT {
+		return l
+	}
+	return t
+}
+
+// clampAddSubtractFull returns a + b - c per channel, clamped to 0-255.
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	var out uint32
+	for ch := 0; ch < 4; ch++ {
+		out |= clampByte(channel(a, ch)+channel(b, ch)-channel(c, ch)) << (8 * ch)
+	}
+	return out
+}
+
+// clampAddSubtractHalf returns a + (a - b) / 2 per channel, clamped to 0-255.
+func clampAddSubtractHalf(a, b uint32) uint32 {
+	var out uint32
+	for ch := 0; ch < 4; ch++ {
+		v := channel(a, ch)
+		out |= clampByte(v+(v-channel(b, ch))/2) << (8 * ch)
+	}
+	return out
+}
+
+func clampByte(v int) uint32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint32(v)
+}
+
+// channel returns channel c of p, counting from blue (0) to alpha (3).
+func channel(p uint32, c int) int {
+	return int(p >> (8 * c) & 0xff)
+}
+
+func average2(a, b uint32) uint32 {
+	return ((a^b)&0xfefefefe)>>1 + a&b
+}
+
+func subPixels(a, b uint32) uint32 {
+	var out uint32
+	for c := 0; c < 4; c++ {
+		out |= uint32(byte(channel(a, c)-channel(b, c))) << (8 * c)
+	}
+	return out
+}
+
+// residualCost approximates how expensive a residual is to entropy code by
+// the magnitude of each channel read as a signed byte.
+func residualCost(p uint32) int {
+	cost := 0
+	for c := 0; c < 4; c++ {
+		cost += abs(int(int8(channel(p, c))))
+	}
+	return cost
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,225 @@
+// Package webp encodes images as lossless WebP (VP8L). The encoder applies
+// the subtract-green and per-tile predictor transforms, finds LZ77 backward
+// references and entropy codes the result with canonical Huffman codes built
+// from the symbol histograms. It stays a small, dependency-free piece of code
+// rather than matching libwebp's compression ratio.
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+)
+
+const (
+	maxDimension  = 1 << 14
+	vp8lSignature = 0x2f
+
+	// predictorBits sets the predictor tile size to 1<<predictorBits pixels.
+	predictorBits = 4
+
+	transformPredictor    = 0
+	transformSubtractGrn  = 2
+	numPredictorModes     = 14
+	opaqueBlack           = 0xff000000
+	distanceCodeOffset    = 120
+	lengthPrefixCodes     = 24
+	distancePrefixCodes   = 40
+	literalSymbols        = 256
+	greenAlphabet         = literalSymbols + lengthPrefixCodes
+	maxHuffmanCodeLength  = 15
+	maxCodeLengthCodeBits = 7
+)
+
+// ErrTooLarge is returned when an image exceeds the VP8L dimension limit.
+var ErrTooLarge = errors.New("webp: image dimensions exceed 16384")
+
+// Encode writes img to w as a lossless WebP file.
+func Encode(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return errors.New("webp: empty image")
+	}
+	if width > maxDimension || height > maxDimension {
+		return ErrTooLarge
+	}
+
+	argb := make([]uint32, 0, width*height)
+	hasAlpha := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A != 0xff {
+				hasAlpha = true
+			}
+			argb = append(argb, uint32(c.A)<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3) // version
+
+	// Transforms are undone in reverse order, so subtract green is applied
+	// first and the predictor then works on the decorrelated pixels.
+	subtractGreen(argb)
+	bw.writeBits(1, 1)
+	bw.writeBits(transformSubtractGrn, 2)
+
+	modes, tilesX := choosePredictors(argb, width, height)
+	bw.writeBits(1, 1)
+	bw.writeBits(transformPredictor, 2)
+	bw.writeBits(predictorBits-2, 3)
+	writeImageData(bw, modes, tilesX, false)
+	residuals := predictorResiduals(argb, width, height, modes, tilesX)
+
+	bw.writeBits(0, 1) // no more transforms
+	writeImageData(bw, residuals, width, true)
+
+	payload := append([]byte{vp8lSignature}, bw.bytes()...)
+	chunkSize := len(payload)
+	padding := chunkSize & 1
+
+	var buf bytes.Buffer
+	buf.Grow(20 + chunkSize + padding)
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(4+8+chunkSize+padding))
+	buf.WriteString("WEBP")
+	buf.WriteString("VP8L")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(chunkSize))
+	buf.Write(payload)
+	if padding == 1 {
+		buf.WriteByte(0)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeImageData writes an entropy-coded image: no color cache, a single set
+// of prefix codes and the LZ77-coded pixels. Only the main image carries the
+// meta prefix code bit.
+func writeImageData(bw *bitWriter, argb []uint32, width int, main bool) {
+	bw.writeBits(0, 1) // no color cache
+	if main {
+		bw.writeBits(0, 1) // no meta prefix codes
+	}
+
+	refs := backwardReferences(argb, width)
+	var (
+		green            [greenAlphabet]int
+		red, blue, alpha [literalSymbols]int
+		distance         [distancePrefixCodes]int
+	)
+	pos := 0
+	for _, ref := range refs {
+		if ref.length == 0 {
+			p := argb[pos]
+			green[p>>8&0xff]++
+			red[p>>16&0xff]++
+			blue[p&0xff]++
+			alpha[p>>24]++
+			pos++
+			continue
+		}
+		lengthSymbol, _, _ := prefixEncode(ref.length)
+		green[literalSymbols+lengthSymbol]++
+		distSymbol, _, _ := prefixEncode(distanceCode(ref.distance, width))
+		distance[distSymbol]++
+		pos += ref.length
+	}
+
+	greenCode := newHuffmanCode(green[:], maxHuffmanCodeLength)
+	redCode := newHuffmanCode(red[:], maxHuffmanCodeLength)
+	blueCode := newHuffmanCode(blue[:], maxHuffmanCodeLength)
+	alphaCode := newHuffmanCode(alpha[:], maxHuffmanCodeLength)
+	distCode := newHuffmanCode(distance[:], maxHuffmanCodeLength)
+	for _, code := range []*huffmanCode{greenCode, redCode, blueCode, alphaCode, distCode} {
+		writeHuffmanCode(bw, code)
+	}
+
+	pos = 0
+	for _, ref := range refs {
+		if ref.length == 0 {
+			p := argb[pos]
+			greenCode.write(bw, int(p>>8&0xff))
+			redCode.write(bw, int(p>>16&0xff))
+			blueCode.write(bw, int(p&0xff))
+			alphaCode.write(bw, int(p>>24))
+			pos++
+			continue
+		}
+		symbol, extraBits, extra := prefixEncode(ref.length)
+		greenCode.write(bw, literalSymbols+symbol)
+		bw.writeBits(uint32(extra), uint(extraBits))
+		symbol, extraBits, extra = prefixEncode(distanceCode(ref.distance, width))
+		distCode.write(bw, symbol)
+		bw.writeBits(uint32(extra), uint(extraBits))
+		pos += ref.length
+	}
+}
+
+// prefixEncode splits a length or distance code value (at least 1) into its
+// prefix symbol and the extra bits that follow it.
+func prefixEncode(value int) (symbol, extraBits, extra int) {
+	d := value - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	high := 31
+	for d>>high == 0 {
+		high--
+	}
+	second := d >> (high - 1) & 1
+	extraBits = high - 1
+	return 2*high + second, extraBits, d & (1<<extraBits - 1)
+}
+
+// distanceCode maps a linear pixel distance onto the VP8L distance code
+// space. The left and top neighbours use their short two-dimensional codes;
+// everything else is sent as a plain distance.
+func distanceCode(distance, width int) int {
+	switch distance {
+	case width:
+		return 1
+	case 1:
+		return 2
+	}
+	return distance + distanceCodeOffset
+}
+
+// bitWriter packs values least significant bit first, as VP8L requires.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+// writeBits appends the n low bits of v, least significant bit first.
+func (b *bitWriter) writeBits(v uint32, n uint) {
+	b.acc |= uint64(v) << b.nbits
+	b.nbits += n
+	for b.nbits >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.nbits -= 8
+	}
+}
+
+func (b *bitWriter) bytes() []byte {
+	if b.nbits > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc = 0
+		b.nbits = 0
+	}
+	return b.buf
+}
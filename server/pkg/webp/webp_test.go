@@ -0,0 +1,138 @@
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+func TestEncodeWritesVP8LHeader(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 5, 7))
+	img.SetNRGBA(1, 1, color.NRGBA{R: 10, G: 20, B: 30, A: 128})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	data := buf.Bytes()
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" || string(data[12:16]) != "VP8L" {
+		t.Fatalf("unexpected container header: %q", data[:16])
+	}
+	if size := binary.LittleEndian.Uint32(data[4:8]); int(size) != len(data)-8 {
+		t.Fatalf("riff size = %d, want %d", size, len(data)-8)
+	}
+	if data[20] != vp8lSignature {
+		t.Fatalf("missing vp8l signature")
+	}
+	bits := binary.LittleEndian.Uint32(data[21:25])
+	width := int(bits&0x3fff) + 1
+	height := int((bits>>14)&0x3fff) + 1
+	if width != 5 || height != 7 {
+		t.Fatalf("dimensions = %dx%d, want 5x7", width, height)
+	}
+	if alpha := (bits >> 28) & 1; alpha != 1 {
+		t.Fatalf("alpha_is_used = %d, want 1", alpha)
+	}
+}
+
+func TestEncodeRejectsOversizedImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, maxDimension+1, 1))
+	if err := Encode(&bytes.Buffer{}, img); err != ErrTooLarge {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+}
+
+// testImage returns a deterministic w×h image. Smooth images are gradients
+// with mild noise, like product photos; the rest are uniform random noise.
+func testImage(w, h int, smooth, alpha bool) *image.NRGBA {
+	rng := rand.New(rand.NewSource(int64(w*31 + h)))
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{A: 0xff}
+			if smooth {
+				c.R = uint8(x*255/w + rng.Intn(3))
+				c.G = uint8(y*255/h + rng.Intn(3))
+				c.B = uint8((x+y)*127/(w+h) + 64)
+			} else {
+				c.R, c.G, c.B = uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256))
+			}
+			if alpha {
+				c.A = uint8(x * 255 / w)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	flat := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0x80
+	}
+	cases := map[string]*image.NRGBA{
+		"single pixel":      testImage(1, 1, false, false),
+		"single row":        testImage(37, 1, true, false),
+		"single column":     testImage(1, 23, true, false),
+		"partial tiles":     testImage(33, 19, true, false),
+		"smooth":            testImage(300, 200, true, false),
+		"smooth with alpha": testImage(120, 80, true, true),
+		"noise":             testImage(64, 48, false, true),
+		"flat":              flat,
+	}
+	for name, img := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, img); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			decoded, err := xwebp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if decoded.Bounds() != img.Bounds() {
+				t.Fatalf("bounds = %v, want %v", decoded.Bounds(), img.Bounds())
+			}
+			for y := 0; y < img.Bounds().Dy(); y++ {
+				for x := 0; x < img.Bounds().Dx(); x++ {
+					want := img.NRGBAAt(x, y)
+					if got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA); got != want {
+						t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeSmallerThanPNG(t *testing.T) {
+	flat := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0xff
+	}
+	for name, img := range map[string]*image.NRGBA{
+		"smooth": testImage(300, 200, true, false),
+		"flat":   flat,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var webpBuf, pngBuf bytes.Buffer
+			if err := Encode(&webpBuf, img); err != nil {
+				t.Fatalf("encode webp: %v", err)
+			}
+			if err := png.Encode(&pngBuf, img); err != nil {
+				t.Fatalf("encode png: %v", err)
+			}
+			if webpBuf.Len() > pngBuf.Len() {
+				t.Fatalf("webp = %d bytes, larger than png = %d bytes", webpBuf.Len(), pngBuf.Len())
+			}
+			t.Logf("webp %d bytes, png %d bytes", webpBuf.Len(), pngBuf.Len())
+		})
+	}
+}